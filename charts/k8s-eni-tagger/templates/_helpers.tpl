@@ -65,31 +65,85 @@ false
 {{- /* Required / always-present settings */}}
 {{- $_ := set $data "ENI_TAGGER_ANNOTATION_KEY" $c.annotationKey }}
 {{- $_ := set $data "ENI_TAGGER_MAX_CONCURRENT_RECONCILES" $c.maxConcurrentReconciles }}
+{{- $_ := set $data "ENI_TAGGER_MAX_CONCURRENT_RECONCILES_CEILING" (default 0 $c.maxConcurrentReconcilesCeiling) }}
+{{- $_ := set $data "ENI_TAGGER_NAMESPACE_FAIR_QUEUING" (default false $c.namespaceFairQueuing) }}
+{{- $_ := set $data "ENI_TAGGER_TRIGGER_AUDIT" (default false $c.triggerAudit) }}
+{{- $_ := set $data "ENI_TAGGER_METRICS_EXEMPLARS" (default false $c.metricsExemplars) }}
+{{- $_ := set $data "ENI_TAGGER_ADMIN_BIND_ADDRESS" (default "0" $c.adminBindAddress) }}
+{{- $_ := set $data "ENI_TAGGER_API_BIND_ADDRESS" (default "0" $c.apiBindAddress) }}
+{{- $_ := set $data "ENI_TAGGER_RECONCILE_HISTORY_SIZE" (default 0 $c.reconcileHistorySize) }}
 {{- $_ := set $data "ENI_TAGGER_DRY_RUN" $c.dryRun }}
+{{- $_ := set $data "ENI_TAGGER_READ_ONLY" (default false $c.readOnly) }}
+{{- if $c.auditLogPath }}
+{{- $_ := set $data "ENI_TAGGER_AUDIT_LOG_PATH" $c.auditLogPath }}
+{{- end }}
 {{- $_ := set $data "ENI_TAGGER_METRICS_BIND_ADDRESS" $c.metricsBindAddress }}
 {{- $_ := set $data "ENI_TAGGER_HEALTH_PROBE_BIND_ADDRESS" $c.healthProbeBindAddress }}
 {{- $_ := set $data "ENI_TAGGER_ALLOW_SHARED_ENI_TAGGING" $c.allowSharedENITagging }}
+{{- $_ := set $data "ENI_TAGGER_PAUSE_DELETION_HOLD" (default "10m" $c.pauseDeletionHold) }}
+{{- $_ := set $data "ENI_TAGGER_TAG_BURST_DELAY" (default "0" $c.tagBurstDelay) }}
+{{- $_ := set $data "ENI_TAGGER_POD_WRITE_COLLAPSE_WINDOW" (default "0" $c.podWriteCollapseWindow) }}
+{{- $_ := set $data "ENI_TAGGER_ENI_ATTACHMENT_REQUEUE_DELAY" (default "0" $c.eniAttachmentRequeueDelay) }}
 {{- $_ := set $data "ENI_TAGGER_ENABLE_ENI_CACHE" $c.enableENICache }}
+{{- $_ := set $data "ENI_TAGGER_ENI_CACHE_IP_CHECK" $c.eniCacheIPCheck }}
+{{- $_ := set $data "ENI_TAGGER_ENI_CACHE_WARMUP" $c.eniCacheWarmup }}
+{{- $_ := set $data "ENI_TAGGER_ENI_CACHE_RESYNC_INTERVAL" (default "0" $c.eniCacheResyncInterval) }}
 {{- $_ := set $data "ENI_TAGGER_ENABLE_CACHE_CONFIGMAP" $c.enableCacheConfigMap }}
 {{- $_ := set $data "ENI_TAGGER_CACHE_BATCH_INTERVAL" $c.cacheBatchInterval }}
 {{- $_ := set $data "ENI_TAGGER_CACHE_BATCH_SIZE" $c.cacheBatchSize }}
+{{- $_ := set $data "ENI_TAGGER_STANDBY_CACHE_REFRESH_INTERVAL" (default "1m" $c.standbyCacheRefreshInterval) }}
 {{- $_ := set $data "ENI_TAGGER_AWS_RATE_LIMIT_QPS" $c.awsRateLimitQPS }}
 {{- $_ := set $data "ENI_TAGGER_AWS_RATE_LIMIT_BURST" $c.awsRateLimitBurst }}
+{{- $_ := set $data "ENI_TAGGER_AWS_DEBUG_LOGGING" (default false $c.awsDebugLogging) }}
+{{- $_ := set $data "ENI_TAGGER_AWS_SESSION_TAGS" (ternary $c.awsSessionTags true (hasKey $c "awsSessionTags")) }}
 {{- $_ := set $data "ENI_TAGGER_PPROF_BIND_ADDRESS" $c.pprofBindAddress }}
+{{- $_ := set $data "ENI_TAGGER_OWNERSHIP_REPORT_INTERVAL" (default "1h" $c.ownershipReportInterval) }}
+{{- $_ := set $data "ENI_TAGGER_TAG_SOURCE_WEBHOOK_TIMEOUT" (default "2s" $c.tagSourceWebhookTimeout) }}
+{{- $_ := set $data "ENI_TAGGER_TAG_SOURCE_WEBHOOK_CACHE_TTL" (default "5m" $c.tagSourceWebhookCacheTTL) }}
+{{- $_ := set $data "ENI_TAGGER_AUX_SERVER_READ_HEADER_TIMEOUT" (default "10s" $c.auxServerReadHeaderTimeout) }}
+{{- $_ := set $data "ENI_TAGGER_AUX_SERVER_READ_TIMEOUT" (default "30s" $c.auxServerReadTimeout) }}
+{{- $_ := set $data "ENI_TAGGER_AUX_SERVER_IDLE_TIMEOUT" (default "90s" $c.auxServerIdleTimeout) }}
+{{- $_ := set $data "ENI_TAGGER_AUX_SERVER_MAX_HEADER_BYTES" (default 65536 $c.auxServerMaxHeaderBytes) }}
+{{- $_ := set $data "ENI_TAGGER_AUX_SERVER_SHUTDOWN_TIMEOUT" (default "10s" $c.auxServerShutdownTimeout) }}
 {{- $_ := set $data "ENI_TAGGER_POD_RATE_LIMIT_QPS" $c.podRateLimitQPS }}
 {{- $_ := set $data "ENI_TAGGER_POD_RATE_LIMIT_BURST" $c.podRateLimitBurst }}
+{{- $_ := set $data "ENI_TAGGER_POD_RATE_LIMIT_CONDITION" (default false $c.podRateLimitCondition) }}
 {{- $_ := set $data "ENI_TAGGER_RATE_LIMITER_CLEANUP_INTERVAL" $c.rateLimiterCleanupInterval }}
-{{- /* AWS health latch max successes: supports 0 to disable latching */}}
-{{- $awsHealthMax := 3 -}}
-{{- if kindIs "invalid" $c.awsHealthMaxSuccesses }}
-  {{- /* Value is not provided, use default of 3 */}}
-{{- else }}
-  {{- $awsHealthMax = $c.awsHealthMaxSuccesses -}}
-{{- end }}
-{{- if lt (int $awsHealthMax) 0 }}
-  {{- $awsHealthMax = 0 -}}
-{{- end }}
-{{- $_ := set $data "ENI_TAGGER_AWS_HEALTH_MAX_SUCCESSES" $awsHealthMax }}
+{{- $_ := set $data "ENI_TAGGER_POD_RATE_LIMITERS_MAX" $c.podRateLimitersMax }}
+{{- $_ := set $data "ENI_TAGGER_VERIFY_PERMISSIONS" (ternary $c.verifyPermissions true (hasKey $c "verifyPermissions")) }}
+{{- $_ := set $data "ENI_TAGGER_VERIFY_TAGGING_PERMISSIONS" (ternary $c.verifyTaggingPermissions true (hasKey $c "verifyTaggingPermissions")) }}
+{{- $_ := set $data "ENI_TAGGER_AWS_HEALTH_PROBE" (default "readyz" $c.awsHealthProbe) }}
+{{- $_ := set $data "ENI_TAGGER_TAG_KEY_CASE_CONFLICT" (default "allow" $c.tagKeyCaseConflict) }}
+{{- $_ := set $data "ENI_TAGGER_TAG_VALUE_TEMPLATES" (default "none" $c.tagValueTemplates) }}
+{{- $_ := set $data "ENI_TAGGER_ENABLE_SERVICE_TAGGING" (default false $c.enableServiceTagging) }}
+{{- $_ := set $data "ENI_TAGGER_ENABLE_ADMISSION_WEBHOOK" (default false .Values.webhook.enabled) }}
+{{- $_ := set $data "ENI_TAGGER_WEBHOOK_PORT" (default 9443 .Values.webhook.port) }}
+{{- $_ := set $data "ENI_TAGGER_WEBHOOK_CERT_DIR" "/etc/k8s-eni-tagger/webhook-certs" }}
+{{- if .Values.webhook.enabled }}
+{{- $defaultTags := default dict .Values.webhook.defaultTags }}
+{{- $_ := set $data "ENI_TAGGER_DEFAULT_TAGS" (default "" $defaultTags.tags) }}
+{{- $_ := set $data "ENI_TAGGER_DEFAULT_TAGS_SELECTOR" (default "" $defaultTags.selector) }}
+{{- $_ := set $data "ENI_TAGGER_DEFAULT_TAGS_CONFIGMAP" (default "" $defaultTags.configMap) }}
+{{- $canary := default dict $defaultTags.canary }}
+{{- $_ := set $data "ENI_TAGGER_DEFAULT_TAGS_CANARY_PERCENT" (default 10 $canary.percent) }}
+{{- $_ := set $data "ENI_TAGGER_DEFAULT_TAGS_CANARY_PODS" (default 5 $canary.pods) }}
+{{- $_ := set $data "ENI_TAGGER_DEFAULT_TAGS_CANARY_MAX_FAILURE_RATIO" (ternary $canary.maxFailureRatio 0.2 (hasKey $canary "maxFailureRatio")) }}
+{{- end }}
+{{- $_ := set $data "ENI_TAGGER_TAG_DIFF_SOURCE" (default "annotation" $c.tagDiffSource) }}
+{{- $_ := set $data "ENI_TAGGER_HOST_NETWORK_ENI" (default "pod-ip" $c.hostNetworkENI) }}
+{{- $_ := set $data "ENI_TAGGER_TAG_SECURITY_GROUPS" (default false $c.tagSecurityGroups) }}
+{{- $_ := set $data "ENI_TAGGER_TAG_NODE_VOLUMES" (default false $c.tagNodeVolumes) }}
+{{- $_ := set $data "ENI_TAGGER_TAG_HEADROOM_WARNING" (default 0 $c.tagHeadroomWarning) }}
+{{- $_ := set $data "ENI_TAGGER_AWS_TAG_BACKEND" (default "ec2" $c.awsTagBackend) }}
+{{- $_ := set $data "ENI_TAGGER_STARTUP_REPAIR_WINDOW" (default "0" $c.startupRepairWindow) }}
+{{- $_ := set $data "ENI_TAGGER_RESYNC_INTERVAL" (default "0" $c.resyncInterval) }}
+{{- $_ := set $data "ENI_TAGGER_INVALID_TAGS_POLICY" (default "keep" $c.invalidTagsPolicy) }}
+{{- $_ := set $data "ENI_TAGGER_TAG_HISTORY_SIZE" (default 0 $c.tagHistorySize) }}
+{{- $_ := set $data "ENI_TAGGER_STATE_STORE" (default "annotations" $c.stateStore) }}
+{{- $_ := set $data "ENI_TAGGER_AWS_HEALTH_CHECK_INTERVAL" (default "30s" $c.awsHealthCheckInterval) }}
+{{- $_ := set $data "ENI_TAGGER_KEY_DOMAIN" (default "eni-tagger.io" $c.keyDomain) }}
+{{- $_ := set $data "ENI_TAGGER_CONTROLLER_ID" (default (printf "%s/%s" $root.Release.Namespace (include "k8s-eni-tagger.fullname" $root)) $c.controllerID) }}
+{{- $_ := set $data "ENI_TAGGER_MANAGED_BY_TAG" (default false $c.managedByTag) }}
 
 {{- /* Derived leader election: only emit env when it would be true */}}
 {{- if $leader }}
@@ -100,12 +154,71 @@ false
 {{- if $c.subnetIDs }}
 {{- $_ := set $data "ENI_TAGGER_SUBNET_IDS" $c.subnetIDs }}
 {{- end }}
+{{- if $c.subnetConfigMap }}
+{{- $_ := set $data "ENI_TAGGER_SUBNET_CONFIGMAP" $c.subnetConfigMap }}
+{{- end }}
+{{- if $c.pauseConfigMap }}
+{{- $_ := set $data "ENI_TAGGER_PAUSE_CONFIGMAP" $c.pauseConfigMap }}
+{{- end }}
 {{- if $c.tagNamespace }}
 {{- $_ := set $data "ENI_TAGGER_TAG_NAMESPACE" $c.tagNamespace }}
 {{- end }}
 {{- if $c.watchNamespace }}
 {{- $_ := set $data "ENI_TAGGER_WATCH_NAMESPACE" $c.watchNamespace }}
 {{- end }}
+{{- if $c.maintenanceWindows }}
+{{- $_ := set $data "ENI_TAGGER_MAINTENANCE_WINDOWS" $c.maintenanceWindows }}
+{{- end }}
+{{- if $c.tagKeyRenames }}
+{{- $_ := set $data "ENI_TAGGER_TAG_KEY_RENAMES" $c.tagKeyRenames }}
+{{- end }}
+{{- if $c.tagFromLabels }}
+{{- $_ := set $data "ENI_TAGGER_TAG_FROM_LABELS" $c.tagFromLabels }}
+{{- end }}
+{{- if $c.criticalTagKeys }}
+{{- $_ := set $data "ENI_TAGGER_CRITICAL_TAG_KEYS" $c.criticalTagKeys }}
+{{- end }}
+{{- if $c.awsNamespaceBudgets }}
+{{- $_ := set $data "ENI_TAGGER_AWS_NAMESPACE_BUDGETS" $c.awsNamespaceBudgets }}
+{{- end }}
+{{- if $c.awsMutationBudget }}
+{{- $_ := set $data "ENI_TAGGER_AWS_MUTATION_BUDGET" $c.awsMutationBudget }}
+{{- $_ := set $data "ENI_TAGGER_AWS_MUTATION_BUDGET_WINDOW" (default "1h" $c.awsMutationBudgetWindow) }}
+{{- end }}
+{{- if $c.awsEC2Endpoint }}
+{{- $_ := set $data "ENI_TAGGER_AWS_EC2_ENDPOINT" $c.awsEC2Endpoint }}
+{{- end }}
+{{- if $c.awsAssumeRoleArn }}
+{{- $_ := set $data "ENI_TAGGER_AWS_ASSUME_ROLE_ARN" $c.awsAssumeRoleArn }}
+{{- end }}
+{{- if $c.awsAssumeRoleExternalId }}
+{{- $_ := set $data "ENI_TAGGER_AWS_ASSUME_ROLE_EXTERNAL_ID" $c.awsAssumeRoleExternalId }}
+{{- end }}
+{{- if $c.awsProfile }}
+{{- $_ := set $data "ENI_TAGGER_AWS_PROFILE" $c.awsProfile }}
+{{- end }}
+{{- if $c.awsHealthProfile }}
+{{- $_ := set $data "ENI_TAGGER_AWS_HEALTH_PROFILE" $c.awsHealthProfile }}
+{{- end }}
+{{- if $c.awsHealthRoleArn }}
+{{- $_ := set $data "ENI_TAGGER_AWS_HEALTH_ROLE_ARN" $c.awsHealthRoleArn }}
+{{- end }}
+{{- if $c.ownershipReportS3Bucket }}
+{{- $_ := set $data "ENI_TAGGER_OWNERSHIP_REPORT_S3_BUCKET" $c.ownershipReportS3Bucket }}
+{{- end }}
+{{- if $c.ownershipReportS3Prefix }}
+{{- $_ := set $data "ENI_TAGGER_OWNERSHIP_REPORT_S3_PREFIX" $c.ownershipReportS3Prefix }}
+{{- end }}
+{{- if $c.tagSourceWebhookUrl }}
+{{- $_ := set $data "ENI_TAGGER_TAG_SOURCE_WEBHOOK_URL" $c.tagSourceWebhookUrl }}
+{{- end }}
+{{- if $c.clusterName }}
+{{- $_ := set $data "ENI_TAGGER_CLUSTER_NAME" $c.clusterName }}
+{{- end }}
+{{- if $c.excludePodSelector }}
+{{- $_ := set $data "ENI_TAGGER_EXCLUDE_POD_SELECTOR" $c.excludePodSelector }}
+{{- end }}
+{{- $_ := set $data "ENI_TAGGER_DEBUG_PODS" (default "skip" $c.debugPods) }}
 
 {{- /* Merge user-provided extra env */}}
 {{- if .Values.env }}
@@ -13,22 +13,106 @@ with chart's standard _helpers.tpl.
 ENI_TAGGER_ANNOTATION_KEY: {{ $c.annotationKey | quote }}
 ENI_TAGGER_WATCH_NAMESPACE: {{ $c.watchNamespace | quote }}
 ENI_TAGGER_MAX_CONCURRENT_RECONCILES: {{ $c.maxConcurrentReconciles | quote }}
+ENI_TAGGER_MAX_CONCURRENT_RECONCILES_CEILING: {{ default 0 $c.maxConcurrentReconcilesCeiling | quote }}
+ENI_TAGGER_NAMESPACE_FAIR_QUEUING: {{ default false $c.namespaceFairQueuing | quote }}
+ENI_TAGGER_TRIGGER_AUDIT: {{ default false $c.triggerAudit | quote }}
+ENI_TAGGER_METRICS_EXEMPLARS: {{ default false $c.metricsExemplars | quote }}
+ENI_TAGGER_ADMIN_BIND_ADDRESS: {{ default "0" $c.adminBindAddress | quote }}
+ENI_TAGGER_API_BIND_ADDRESS: {{ default "0" $c.apiBindAddress | quote }}
+ENI_TAGGER_RECONCILE_HISTORY_SIZE: {{ default 0 $c.reconcileHistorySize | quote }}
 ENI_TAGGER_DRY_RUN: {{ $c.dryRun | quote }}
+ENI_TAGGER_READ_ONLY: {{ default false $c.readOnly | quote }}
+ENI_TAGGER_AUDIT_LOG_PATH: {{ default "" $c.auditLogPath | quote }}
 ENI_TAGGER_METRICS_BIND_ADDRESS: {{ $c.metricsBindAddress | quote }}
 ENI_TAGGER_HEALTH_PROBE_BIND_ADDRESS: {{ $c.healthProbeBindAddress | quote }}
 ENI_TAGGER_SUBNET_IDS: {{ $c.subnetIDs | quote }}
+ENI_TAGGER_SUBNET_CONFIGMAP: {{ default "" $c.subnetConfigMap | quote }}
+ENI_TAGGER_PAUSE_CONFIGMAP: {{ default "" $c.pauseConfigMap | quote }}
+ENI_TAGGER_PAUSE_DELETION_HOLD: {{ default "10m" $c.pauseDeletionHold | quote }}
 ENI_TAGGER_ALLOW_SHARED_ENI_TAGGING: {{ $c.allowSharedENITagging | quote }}
+ENI_TAGGER_TAG_BURST_DELAY: {{ default "0" $c.tagBurstDelay | quote }}
+ENI_TAGGER_POD_WRITE_COLLAPSE_WINDOW: {{ default "0" $c.podWriteCollapseWindow | quote }}
+ENI_TAGGER_ENI_ATTACHMENT_REQUEUE_DELAY: {{ default "0" $c.eniAttachmentRequeueDelay | quote }}
 ENI_TAGGER_ENABLE_ENI_CACHE: {{ $c.enableENICache | quote }}
+ENI_TAGGER_ENI_CACHE_IP_CHECK: {{ $c.eniCacheIPCheck | quote }}
+ENI_TAGGER_ENI_CACHE_WARMUP: {{ $c.eniCacheWarmup | quote }}
+ENI_TAGGER_ENI_CACHE_RESYNC_INTERVAL: {{ default "0" $c.eniCacheResyncInterval | quote }}
 ENI_TAGGER_ENABLE_CACHE_CONFIGMAP: {{ $c.enableCacheConfigMap | quote }}
 ENI_TAGGER_CACHE_BATCH_INTERVAL: {{ $c.cacheBatchInterval | quote }}
 ENI_TAGGER_CACHE_BATCH_SIZE: {{ $c.cacheBatchSize | quote }}
+ENI_TAGGER_STANDBY_CACHE_REFRESH_INTERVAL: {{ default "1m" $c.standbyCacheRefreshInterval | quote }}
 ENI_TAGGER_AWS_RATE_LIMIT_QPS: {{ $c.awsRateLimitQPS | quote }}
 ENI_TAGGER_AWS_RATE_LIMIT_BURST: {{ $c.awsRateLimitBurst | quote }}
+ENI_TAGGER_AWS_NAMESPACE_BUDGETS: {{ default "" $c.awsNamespaceBudgets | quote }}
+ENI_TAGGER_AWS_MUTATION_BUDGET: {{ default 0 $c.awsMutationBudget | quote }}
+ENI_TAGGER_AWS_MUTATION_BUDGET_WINDOW: {{ default "1h" $c.awsMutationBudgetWindow | quote }}
+ENI_TAGGER_AWS_EC2_ENDPOINT: {{ default "" $c.awsEC2Endpoint | quote }}
+ENI_TAGGER_AWS_ASSUME_ROLE_ARN: {{ default "" $c.awsAssumeRoleArn | quote }}
+ENI_TAGGER_AWS_ASSUME_ROLE_EXTERNAL_ID: {{ default "" $c.awsAssumeRoleExternalId | quote }}
+ENI_TAGGER_AWS_PROFILE: {{ default "" $c.awsProfile | quote }}
+ENI_TAGGER_AWS_HEALTH_PROFILE: {{ default "" $c.awsHealthProfile | quote }}
+ENI_TAGGER_AWS_HEALTH_ROLE_ARN: {{ default "" $c.awsHealthRoleArn | quote }}
+ENI_TAGGER_AWS_SESSION_TAGS: {{ ternary $c.awsSessionTags true (hasKey $c "awsSessionTags") | quote }}
+ENI_TAGGER_CLUSTER_NAME: {{ default "" $c.clusterName | quote }}
+ENI_TAGGER_AWS_DEBUG_LOGGING: {{ default false $c.awsDebugLogging | quote }}
 ENI_TAGGER_PPROF_BIND_ADDRESS: {{ $c.pprofBindAddress | quote }}
+ENI_TAGGER_OWNERSHIP_REPORT_S3_BUCKET: {{ default "" $c.ownershipReportS3Bucket | quote }}
+ENI_TAGGER_OWNERSHIP_REPORT_S3_PREFIX: {{ default "" $c.ownershipReportS3Prefix | quote }}
+ENI_TAGGER_OWNERSHIP_REPORT_INTERVAL: {{ default "1h" $c.ownershipReportInterval | quote }}
+ENI_TAGGER_TAG_SOURCE_WEBHOOK_URL: {{ default "" $c.tagSourceWebhookUrl | quote }}
+ENI_TAGGER_TAG_SOURCE_WEBHOOK_TIMEOUT: {{ default "2s" $c.tagSourceWebhookTimeout | quote }}
+ENI_TAGGER_TAG_SOURCE_WEBHOOK_CACHE_TTL: {{ default "5m" $c.tagSourceWebhookCacheTTL | quote }}
+ENI_TAGGER_AUX_SERVER_READ_HEADER_TIMEOUT: {{ default "10s" $c.auxServerReadHeaderTimeout | quote }}
+ENI_TAGGER_AUX_SERVER_READ_TIMEOUT: {{ default "30s" $c.auxServerReadTimeout | quote }}
+ENI_TAGGER_AUX_SERVER_IDLE_TIMEOUT: {{ default "90s" $c.auxServerIdleTimeout | quote }}
+ENI_TAGGER_AUX_SERVER_MAX_HEADER_BYTES: {{ default 65536 $c.auxServerMaxHeaderBytes | quote }}
+ENI_TAGGER_AUX_SERVER_SHUTDOWN_TIMEOUT: {{ default "10s" $c.auxServerShutdownTimeout | quote }}
 ENI_TAGGER_TAG_NAMESPACE: {{ $c.tagNamespace | quote }}
 ENI_TAGGER_POD_RATE_LIMIT_QPS: {{ $c.podRateLimitQPS | quote }}
 ENI_TAGGER_POD_RATE_LIMIT_BURST: {{ $c.podRateLimitBurst | quote }}
+ENI_TAGGER_POD_RATE_LIMIT_CONDITION: {{ default false $c.podRateLimitCondition | quote }}
 ENI_TAGGER_RATE_LIMITER_CLEANUP_INTERVAL: {{ $c.rateLimiterCleanupInterval | quote }}
+ENI_TAGGER_POD_RATE_LIMITERS_MAX: {{ $c.podRateLimitersMax | quote }}
+ENI_TAGGER_AWS_HEALTH_CHECK_INTERVAL: {{ $c.awsHealthCheckInterval | quote }}
+ENI_TAGGER_AWS_HEALTH_PROBE: {{ $c.awsHealthProbe | quote }}
+ENI_TAGGER_VERIFY_PERMISSIONS: {{ ternary $c.verifyPermissions true (hasKey $c "verifyPermissions") | quote }}
+ENI_TAGGER_VERIFY_TAGGING_PERMISSIONS: {{ ternary $c.verifyTaggingPermissions true (hasKey $c "verifyTaggingPermissions") | quote }}
+ENI_TAGGER_TAG_KEY_CASE_CONFLICT: {{ default "allow" $c.tagKeyCaseConflict | quote }}
+ENI_TAGGER_TAG_KEY_RENAMES: {{ default "" $c.tagKeyRenames | quote }}
+ENI_TAGGER_TAG_FROM_LABELS: {{ default "" $c.tagFromLabels | quote }}
+ENI_TAGGER_TAG_VALUE_TEMPLATES: {{ default "none" $c.tagValueTemplates | quote }}
+ENI_TAGGER_ENABLE_SERVICE_TAGGING: {{ default false $c.enableServiceTagging | quote }}
+ENI_TAGGER_ENABLE_ADMISSION_WEBHOOK: {{ default false .Values.webhook.enabled | quote }}
+ENI_TAGGER_WEBHOOK_PORT: {{ default 9443 .Values.webhook.port | quote }}
+ENI_TAGGER_WEBHOOK_CERT_DIR: "/etc/k8s-eni-tagger/webhook-certs"
+{{- if .Values.webhook.enabled }}
+{{- $defaultTags := default dict .Values.webhook.defaultTags }}
+ENI_TAGGER_DEFAULT_TAGS: {{ default "" $defaultTags.tags | quote }}
+ENI_TAGGER_DEFAULT_TAGS_SELECTOR: {{ default "" $defaultTags.selector | quote }}
+ENI_TAGGER_DEFAULT_TAGS_CONFIGMAP: {{ default "" $defaultTags.configMap | quote }}
+{{- $canary := default dict $defaultTags.canary }}
+ENI_TAGGER_DEFAULT_TAGS_CANARY_PERCENT: {{ default 10 $canary.percent | quote }}
+ENI_TAGGER_DEFAULT_TAGS_CANARY_PODS: {{ default 5 $canary.pods | quote }}
+ENI_TAGGER_DEFAULT_TAGS_CANARY_MAX_FAILURE_RATIO: {{ ternary $canary.maxFailureRatio 0.2 (hasKey $canary "maxFailureRatio") | quote }}
+{{- end }}
+ENI_TAGGER_CRITICAL_TAG_KEYS: {{ default "" $c.criticalTagKeys | quote }}
+ENI_TAGGER_TAG_DIFF_SOURCE: {{ default "annotation" $c.tagDiffSource | quote }}
+ENI_TAGGER_HOST_NETWORK_ENI: {{ default "pod-ip" $c.hostNetworkENI | quote }}
+ENI_TAGGER_TAG_SECURITY_GROUPS: {{ default false $c.tagSecurityGroups | quote }}
+ENI_TAGGER_TAG_NODE_VOLUMES: {{ default false $c.tagNodeVolumes | quote }}
+ENI_TAGGER_TAG_HEADROOM_WARNING: {{ default 0 $c.tagHeadroomWarning | quote }}
+ENI_TAGGER_AWS_TAG_BACKEND: {{ default "ec2" $c.awsTagBackend | quote }}
+ENI_TAGGER_STARTUP_REPAIR_WINDOW: {{ default "0" $c.startupRepairWindow | quote }}
+ENI_TAGGER_RESYNC_INTERVAL: {{ default "0" $c.resyncInterval | quote }}
+ENI_TAGGER_INVALID_TAGS_POLICY: {{ default "keep" $c.invalidTagsPolicy | quote }}
+ENI_TAGGER_TAG_HISTORY_SIZE: {{ default 0 $c.tagHistorySize | quote }}
+ENI_TAGGER_STATE_STORE: {{ default "annotations" $c.stateStore | quote }}
+ENI_TAGGER_MAINTENANCE_WINDOWS: {{ default "" $c.maintenanceWindows | quote }}
+ENI_TAGGER_EXCLUDE_POD_SELECTOR: {{ $c.excludePodSelector | quote }}
+ENI_TAGGER_DEBUG_PODS: {{ default "skip" $c.debugPods | quote }}
+ENI_TAGGER_KEY_DOMAIN: {{ default "eni-tagger.io" $c.keyDomain | quote }}
+ENI_TAGGER_CONTROLLER_ID: {{ default (printf "%s/%s" .Release.Namespace (include "k8s-eni-tagger.fullname" .)) $c.controllerID | quote }}
+ENI_TAGGER_MANAGED_BY_TAG: {{ default false $c.managedByTag | quote }}
 {{- if $e }}
 {{- range $key, $value := $e }}
 {{ $key }}: {{ $value | quote }}
@@ -0,0 +1,489 @@
+// Command loadgen measures the pod controller at cluster scale without a cluster.
+// It starts envtest (a real kube-apiserver and etcd), serves the EC2 mock on a
+// local listener, runs the controller in-process against both, creates thousands
+// of annotated pods and reports reconcile throughput, EC2 calls per pod and the
+// controller's memory use.
+//
+// envtest needs the kube-apiserver and etcd binaries; point KUBEBUILDER_ASSETS at
+// them, or run it through make:
+//
+//	make loadgen LOADGEN_ARGS="-pods 5000 -namespaces 20 -workers 8"
+//
+// -min-throughput and -max-calls-per-pod make it exit non-zero on regressions, so
+// it can gate releases in CI.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+	enicache "k8s-eni-tagger/pkg/cache"
+	"k8s-eni-tagger/pkg/controller"
+
+	"github.com/prabhu-mannu/k8s-eni-tagger/e2e-v2/mock/ec2mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// ec2Actions are the EC2 calls reported, in report order.
+var ec2Actions = []string{"DescribeNetworkInterfaces", "CreateTags", "DeleteTags"}
+
+type options struct {
+	pods              int
+	namespaces        int
+	workers           int
+	createConcurrency int
+	awsQPS            float64
+	awsBurst          int
+	podRateLimitQPS   float64
+	eniCache          bool
+	fairQueuing       bool
+	deletePods        bool
+	timeout           time.Duration
+	jsonOutput        bool
+	verbose           bool
+	minThroughput     float64
+	maxCallsPerPod    float64
+}
+
+// phase is the result of creating or deleting every pod.
+type phase struct {
+	Pods       int            `json:"pods"`
+	Seconds    float64        `json:"seconds"`
+	PodsPerSec float64        `json:"podsPerSecond"`
+	EC2Calls   map[string]int `json:"ec2Calls"`
+	// CallsPerPod is the number of EC2 calls per pod across all actions.
+	CallsPerPod float64 `json:"ec2CallsPerPod"`
+}
+
+// report is printed at the end of a run.
+type report struct {
+	Pods       int    `json:"pods"`
+	Namespaces int    `json:"namespaces"`
+	Workers    int    `json:"workers"`
+	Tagging    phase  `json:"tagging"`
+	Cleanup    *phase `json:"cleanup,omitempty"`
+	// PeakHeapMB is the highest in-use heap sampled during the run. The apiserver
+	// and etcd run as separate processes, so this is the controller plus loadgen's
+	// own client.
+	PeakHeapMB    float64 `json:"peakHeapMB"`
+	TotalAllocMB  float64 `json:"totalAllocMB"`
+	NumGC         uint32  `json:"numGC"`
+	PeakGoroutine int     `json:"peakGoroutines"`
+}
+
+func main() {
+	var opts options
+	flag.IntVar(&opts.pods, "pods", 2000, "Number of annotated pods to create.")
+	flag.IntVar(&opts.namespaces, "namespaces", 10, "Number of namespaces the pods are spread over.")
+	flag.IntVar(&opts.workers, "workers", 4, "Concurrent reconciles (--max-concurrent-reconciles).")
+	flag.IntVar(&opts.createConcurrency, "create-concurrency", 20, "Concurrent pod creations against the apiserver.")
+	flag.Float64Var(&opts.awsQPS, "aws-qps", 100, "AWS API rate limit (--aws-rate-limit-qps).")
+	flag.IntVar(&opts.awsBurst, "aws-burst", 200, "AWS API rate limit burst (--aws-rate-limit-burst).")
+	flag.Float64Var(&opts.podRateLimitQPS, "pod-rate-limit-qps", 0, "Per-pod reconcile rate limit (--pod-rate-limit-qps). 0 disables it.")
+	flag.BoolVar(&opts.eniCache, "enable-eni-cache", true, "Use the ENI cache (--enable-eni-cache).")
+	flag.BoolVar(&opts.fairQueuing, "namespace-fair-queuing", false, "Serve namespaces round-robin (--namespace-fair-queuing).")
+	flag.BoolVar(&opts.deletePods, "delete", true, "Delete the pods afterwards and measure tag cleanup.")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Minute, "Give up if a phase takes longer than this.")
+	flag.BoolVar(&opts.jsonOutput, "json", false, "Print the report as JSON.")
+	flag.BoolVar(&opts.verbose, "v", false, "Write controller logs to stderr.")
+	flag.Float64Var(&opts.minThroughput, "min-throughput", 0, "Exit 1 if tagging is slower than this many pods per second. 0 disables the check.")
+	flag.Float64Var(&opts.maxCallsPerPod, "max-calls-per-pod", 0, "Exit 1 if tagging makes more EC2 calls per pod than this. 0 disables the check.")
+	flag.Parse()
+
+	if err := validate(opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	rep, err := run(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "loadgen:", err)
+		os.Exit(1)
+	}
+	printReport(rep, opts.jsonOutput)
+
+	if failures := check(rep, opts); len(failures) > 0 {
+		for _, f := range failures {
+			fmt.Fprintln(os.Stderr, "loadgen: regression:", f)
+		}
+		os.Exit(1)
+	}
+}
+
+func validate(opts options) error {
+	switch {
+	case os.Getenv("KUBEBUILDER_ASSETS") == "":
+		return errors.New("KUBEBUILDER_ASSETS not set; run 'make loadgen' to install envtest binaries")
+	case opts.pods < 1 || opts.pods > 65000:
+		return fmt.Errorf("-pods must be between 1 and 65000, got %d", opts.pods)
+	case opts.namespaces < 1:
+		return fmt.Errorf("-namespaces must be at least 1, got %d", opts.namespaces)
+	case opts.workers < 1 || opts.createConcurrency < 1:
+		return errors.New("-workers and -create-concurrency must be at least 1")
+	}
+	return nil
+}
+
+func run(opts options) (*report, error) {
+	logOutput := io.Discard
+	if opts.verbose {
+		logOutput = os.Stderr
+	}
+	ctrl.SetLogger(zap.New(zap.WriteTo(logOutput)))
+
+	ec2 := ec2mock.NewServer()
+	ec2Listener := httptest.NewServer(ec2)
+	defer ec2Listener.Close()
+	for k, v := range map[string]string{
+		"AWS_ENDPOINT_URL":          ec2Listener.URL,
+		"AWS_REGION":                "us-east-1",
+		"AWS_ACCESS_KEY_ID":         "loadgen",
+		"AWS_SECRET_ACCESS_KEY":     "loadgen",
+		"AWS_EC2_METADATA_DISABLED": "true",
+	} {
+		if err := os.Setenv(k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	env := &envtest.Environment{}
+	cfg, err := env.Start()
+	if err != nil {
+		return nil, fmt.Errorf("start envtest: %w", err)
+	}
+	defer func() { _ = env.Stop() }()
+	// Creating thousands of pods is limited by client-side throttling otherwise
+	cfg.QPS = 500
+	cfg.Burst = 1000
+
+	scheme := k8sruntime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: "0"},
+		HealthProbeBindAddress: "0",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create manager: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	awsClient, err := aws.NewClientWithRateLimiter(ctx, aws.RateLimitConfig{QPS: opts.awsQPS, Burst: opts.awsBurst})
+	if err != nil {
+		return nil, fmt.Errorf("create AWS client: %w", err)
+	}
+	r := &controller.PodReconciler{
+		Client:            mgr.GetClient(),
+		Scheme:            mgr.GetScheme(),
+		AWSClient:         awsClient,
+		Recorder:          mgr.GetEventRecorderFor("k8s-eni-tagger"),
+		AnnotationKey:     controller.AnnotationKey,
+		PodRateLimiters:   &sync.Map{},
+		PodRateLimitQPS:   opts.podRateLimitQPS,
+		PodRateLimitBurst: 1,
+	}
+	if opts.eniCache {
+		r.ENICache = enicache.NewENICache(awsClient)
+	}
+	if opts.fairQueuing {
+		if r.FairQueue, err = controller.NewFairQueue(2 * opts.workers); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.SetupWithManager(mgr, opts.workers); err != nil {
+		return nil, fmt.Errorf("set up controller: %w", err)
+	}
+
+	k8sClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("create client: %w", err)
+	}
+
+	mgrErr := make(chan error, 1)
+	go func() { mgrErr <- mgr.Start(ctx) }()
+	defer func() {
+		cancel()
+		<-mgrErr
+	}()
+
+	sampler := newMemSampler()
+	go sampler.run(ctx)
+
+	namespaces := make([]string, opts.namespaces)
+	for i := range namespaces {
+		namespaces[i] = fmt.Sprintf("loadgen-%d", i)
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespaces[i]}}
+		if err := k8sClient.Create(ctx, ns); err != nil {
+			return nil, fmt.Errorf("create namespace %s: %w", ns.Name, err)
+		}
+	}
+
+	pods := make([]*corev1.Pod, opts.pods)
+	for i := range pods {
+		// One branch ENI per pod, on 10.<i/250>.<i%250>.10
+		eni := ec2mock.ENI{
+			ID:            fmt.Sprintf("eni-loadgen%05d", i),
+			PrivateIP:     fmt.Sprintf("10.%d.%d.10", i/250, i%250),
+			InterfaceType: "branch",
+			SubnetID:      "subnet-loadgen",
+		}
+		if err := ec2.SeedENI(eni); err != nil {
+			return nil, fmt.Errorf("seed ENI %s: %w", eni.ID, err)
+		}
+		pods[i] = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      fmt.Sprintf("pod-%05d", i),
+				Namespace: namespaces[i%len(namespaces)],
+				Annotations: map[string]string{
+					controller.AnnotationKey: fmt.Sprintf("Team=team-%d,CostCenter=%d", i%len(namespaces), 1000+i%7),
+				},
+			},
+			Spec:   corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "registry.k8s.io/pause:3.9"}}},
+			Status: corev1.PodStatus{PodIP: eni.PrivateIP, PodIPs: []corev1.PodIP{{IP: eni.PrivateIP}}},
+		}
+	}
+
+	rep := &report{Pods: opts.pods, Namespaces: opts.namespaces, Workers: opts.workers}
+
+	calls := ec2Counts(ec2)
+	start := time.Now()
+	if err := forEach(ctx, pods, opts.createConcurrency, func(pod *corev1.Pod) error {
+		status := pod.Status
+		if err := k8sClient.Create(ctx, pod); err != nil {
+			return fmt.Errorf("create pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+		// envtest runs no kubelet, so set the pod IP through the status subresource
+		pod.Status = status
+		return k8sClient.Status().Update(ctx, pod)
+	}); err != nil {
+		return nil, err
+	}
+	conditionType := corev1.PodConditionType(controller.NewKeys("").ConditionType)
+	if err := waitFor(ctx, k8sClient, opts.timeout, namespaces, "tagging", func(pods []corev1.Pod) int {
+		synced := 0
+		for _, pod := range pods {
+			for _, c := range pod.Status.Conditions {
+				if c.Type == conditionType && c.Reason == string(controller.ReasonSynced) {
+					synced++
+				}
+			}
+		}
+		return opts.pods - synced
+	}); err != nil {
+		return nil, err
+	}
+	rep.Tagging = newPhase(opts.pods, time.Since(start), calls, ec2Counts(ec2))
+
+	if opts.deletePods {
+		calls = ec2Counts(ec2)
+		start = time.Now()
+		if err := forEach(ctx, pods, opts.createConcurrency, func(pod *corev1.Pod) error {
+			return client.IgnoreNotFound(k8sClient.Delete(ctx, pod))
+		}); err != nil {
+			return nil, err
+		}
+		if err := waitFor(ctx, k8sClient, opts.timeout, namespaces, "cleanup", func(pods []corev1.Pod) int {
+			return len(pods)
+		}); err != nil {
+			return nil, err
+		}
+		cleanup := newPhase(opts.pods, time.Since(start), calls, ec2Counts(ec2))
+		rep.Cleanup = &cleanup
+	}
+
+	sampler.fill(rep)
+	return rep, nil
+}
+
+// forEach runs fn for every pod with at most concurrency calls in flight and
+// returns the first error.
+func forEach(ctx context.Context, pods []*corev1.Pod, concurrency int, fn func(*corev1.Pod) error) error {
+	var (
+		wg       sync.WaitGroup
+		firstErr atomic.Value
+		next     atomic.Int64
+	)
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= len(pods) || ctx.Err() != nil || firstErr.Load() != nil {
+					return
+				}
+				if err := fn(pods[i]); err != nil {
+					firstErr.CompareAndSwap(nil, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err, ok := firstErr.Load().(error); ok {
+		return err
+	}
+	return nil
+}
+
+// waitFor lists the pods in namespaces until remaining reports none left,
+// printing progress to stderr.
+func waitFor(ctx context.Context, c client.Client, timeout time.Duration, namespaces []string, name string, remaining func([]corev1.Pod) int) error {
+	deadline := time.Now().Add(timeout)
+	lastLeft := -1
+	for {
+		var pods []corev1.Pod
+		for _, ns := range namespaces {
+			list := &corev1.PodList{}
+			if err := c.List(ctx, list, client.InNamespace(ns)); err != nil {
+				return fmt.Errorf("list pods in %s: %w", ns, err)
+			}
+			pods = append(pods, list.Items...)
+		}
+		left := remaining(pods)
+		if left == 0 {
+			return nil
+		}
+		if left != lastLeft {
+			fmt.Fprintf(os.Stderr, "%s: %d pods remaining\n", name, left)
+			lastLeft = left
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s: %d pods still pending after %s", name, left, timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func ec2Counts(ec2 *ec2mock.Server) map[string]int {
+	counts := make(map[string]int, len(ec2Actions))
+	for _, action := range ec2Actions {
+		counts[action] = ec2.RequestCount(action)
+	}
+	return counts
+}
+
+func newPhase(pods int, elapsed time.Duration, before, after map[string]int) phase {
+	p := phase{
+		Pods:       pods,
+		Seconds:    elapsed.Seconds(),
+		PodsPerSec: float64(pods) / elapsed.Seconds(),
+		EC2Calls:   make(map[string]int, len(after)),
+	}
+	total := 0
+	for action, n := range after {
+		p.EC2Calls[action] = n - before[action]
+		total += n - before[action]
+	}
+	p.CallsPerPod = float64(total) / float64(pods)
+	return p
+}
+
+// memSampler records the peak heap and goroutine count while the run is in progress.
+type memSampler struct {
+	mu            sync.Mutex
+	peakHeap      uint64
+	peakGoroutine int
+}
+
+func newMemSampler() *memSampler {
+	return &memSampler{}
+}
+
+func (s *memSampler) run(ctx context.Context) {
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		s.sample()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *memSampler) sample() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	goroutines := runtime.NumGoroutine()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peakHeap = max(s.peakHeap, m.HeapInuse)
+	s.peakGoroutine = max(s.peakGoroutine, goroutines)
+}
+
+func (s *memSampler) fill(rep *report) {
+	s.sample()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rep.PeakHeapMB = float64(s.peakHeap) / (1 << 20)
+	rep.TotalAllocMB = float64(m.TotalAlloc) / (1 << 20)
+	rep.NumGC = m.NumGC
+	rep.PeakGoroutine = s.peakGoroutine
+}
+
+func printReport(rep *report, asJSON bool) {
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(rep)
+		return
+	}
+	fmt.Printf("pods=%d namespaces=%d workers=%d\n", rep.Pods, rep.Namespaces, rep.Workers)
+	printPhase("tagging", rep.Tagging)
+	if rep.Cleanup != nil {
+		printPhase("cleanup", *rep.Cleanup)
+	}
+	fmt.Printf("memory: peak heap %.1f MB, total allocated %.1f MB, %d GCs, peak goroutines %d\n",
+		rep.PeakHeapMB, rep.TotalAllocMB, rep.NumGC, rep.PeakGoroutine)
+}
+
+func printPhase(name string, p phase) {
+	fmt.Printf("%s: %.1fs, %.1f pods/s, %.2f EC2 calls/pod", name, p.Seconds, p.PodsPerSec, p.CallsPerPod)
+	for _, action := range ec2Actions {
+		fmt.Printf(", %s=%d", action, p.EC2Calls[action])
+	}
+	fmt.Println()
+}
+
+// check returns the regression thresholds the tagging phase missed.
+func check(rep *report, opts options) []string {
+	var failures []string
+	if opts.minThroughput > 0 && rep.Tagging.PodsPerSec < opts.minThroughput {
+		failures = append(failures, fmt.Sprintf("tagging throughput %.1f pods/s is below -min-throughput %.1f", rep.Tagging.PodsPerSec, opts.minThroughput))
+	}
+	if opts.maxCallsPerPod > 0 && rep.Tagging.CallsPerPod > opts.maxCallsPerPod {
+		failures = append(failures, fmt.Sprintf("%.2f EC2 calls per pod exceeds -max-calls-per-pod %.2f", rep.Tagging.CallsPerPod, opts.maxCallsPerPod))
+	}
+	return failures
+}
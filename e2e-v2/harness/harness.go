@@ -0,0 +1,294 @@
+// Package harness runs the pod controller end to end without a cluster: envtest
+// provides a real kube-apiserver and etcd, the EC2 mock serves the AWS API over a
+// local HTTP listener, and the controller runs in-process against both.
+//
+// envtest needs the kube-apiserver and etcd binaries; point KUBEBUILDER_ASSETS at
+// them (make e2e-harness does this). Start skips the test when it is unset.
+package harness
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/controller"
+
+	"github.com/prabhu-mannu/k8s-eni-tagger/e2e-v2/mock/ec2mock"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+const (
+	// DefaultTimeout bounds the Eventually* helpers.
+	DefaultTimeout = 30 * time.Second
+	// pollInterval is how often the Eventually* helpers re-check state.
+	pollInterval = 100 * time.Millisecond
+	// defaultNamespace is where CreatePod creates pods.
+	defaultNamespace = "default"
+)
+
+// Harness is a running apiserver, EC2 mock and pod controller.
+type Harness struct {
+	// Client talks to the envtest apiserver directly (no cache).
+	Client client.Client
+	// EC2 is the in-process EC2 mock the controller is pointed at.
+	EC2 *ec2mock.Server
+	// Reconciler is the controller under test, configured by Options.
+	Reconciler *controller.PodReconciler
+}
+
+// Option customizes the reconciler before the controller starts.
+type Option func(*controller.PodReconciler)
+
+// Start boots envtest, the EC2 mock and the controller, and registers cleanup on t.
+// AWS settings are passed through environment variables, so tests using the harness
+// must not run in parallel.
+func Start(t *testing.T, opts ...Option) *Harness {
+	t.Helper()
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS not set; run 'make e2e-harness' to install envtest binaries")
+	}
+
+	// Controller logs outlive individual tests (client-go goroutines), so they go to
+	// stderr with -v rather than through t.Log
+	logOutput := io.Discard
+	if testing.Verbose() {
+		logOutput = os.Stderr
+	}
+	ctrl.SetLogger(zap.New(zap.UseDevMode(true), zap.WriteTo(logOutput)))
+
+	ec2 := ec2mock.NewServer()
+	ec2Listener := httptest.NewServer(ec2)
+	t.Cleanup(ec2Listener.Close)
+
+	t.Setenv("AWS_ENDPOINT_URL", ec2Listener.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "harness")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "harness")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	env := &envtest.Environment{}
+	cfg, err := env.Start()
+	if err != nil {
+		t.Fatalf("start envtest: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := env.Stop(); err != nil {
+			t.Logf("stop envtest: %v", err)
+		}
+	})
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatalf("build scheme: %v", err)
+	}
+
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: "0"},
+		HealthProbeBindAddress: "0",
+	})
+	if err != nil {
+		t.Fatalf("create manager: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	awsClient, err := aws.NewClient(ctx)
+	if err != nil {
+		cancel()
+		t.Fatalf("create AWS client: %v", err)
+	}
+
+	r := &controller.PodReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		AWSClient:       awsClient,
+		Recorder:        mgr.GetEventRecorderFor("k8s-eni-tagger"),
+		AnnotationKey:   controller.AnnotationKey,
+		PodRateLimiters: &sync.Map{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if err := r.SetupWithManager(mgr, 1); err != nil {
+		cancel()
+		t.Fatalf("set up controller: %v", err)
+	}
+
+	k8sClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		cancel()
+		t.Fatalf("create client: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := mgr.Start(ctx); err != nil {
+			t.Errorf("manager stopped: %v", err)
+		}
+	}()
+	// Registered after env.Stop, so it runs first: stop the controller before the apiserver
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return &Harness{Client: k8sClient, EC2: ec2, Reconciler: r}
+}
+
+// SeedENI adds an ENI to the EC2 mock.
+func (h *Harness) SeedENI(t *testing.T, eni ec2mock.ENI) {
+	t.Helper()
+	if err := h.EC2.SeedENI(eni); err != nil {
+		t.Fatalf("seed ENI %s: %v", eni.ID, err)
+	}
+}
+
+// CreatePod creates a pod carrying annotations and, since envtest runs no kubelet,
+// sets its pod IP directly so the controller can resolve the ENI.
+func (h *Harness) CreatePod(t *testing.T, name, podIP string, annotations map[string]string) *corev1.Pod {
+	t.Helper()
+	ctx := context.Background()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   defaultNamespace,
+			Annotations: annotations,
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "app", Image: "registry.k8s.io/pause:3.9"}},
+		},
+	}
+	if err := h.Client.Create(ctx, pod); err != nil {
+		t.Fatalf("create pod %s: %v", name, err)
+	}
+
+	pod.Status.PodIP = podIP
+	pod.Status.PodIPs = []corev1.PodIP{{IP: podIP}}
+	if err := h.Client.Status().Update(ctx, pod); err != nil {
+		t.Fatalf("set pod %s IP: %v", name, err)
+	}
+	return pod
+}
+
+// CreateAnnotatedPod seeds eni in the EC2 mock and creates a pod on its IP with the
+// tag annotation set to tags.
+func (h *Harness) CreateAnnotatedPod(t *testing.T, name string, eni ec2mock.ENI, tags string) *corev1.Pod {
+	t.Helper()
+	h.SeedENI(t, eni)
+	return h.CreatePod(t, name, eni.PrivateIP, map[string]string{h.annotationKey(): tags})
+}
+
+// DeletePod deletes the pod and waits until the controller has released its
+// finalizer and the pod is gone.
+func (h *Harness) DeletePod(t *testing.T, pod *corev1.Pod) {
+	t.Helper()
+	ctx := context.Background()
+	if err := h.Client.Delete(ctx, pod); err != nil {
+		t.Fatalf("delete pod %s: %v", pod.Name, err)
+	}
+	h.eventually(t, func() string { return fmt.Sprintf("pod %s to be deleted", pod.Name) }, func() (bool, error) {
+		err := h.Client.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// EventuallyENITags waits until the ENI carries every tag in want. Other tags,
+// such as the controller's hash tag, are ignored.
+func (h *Harness) EventuallyENITags(t *testing.T, eniID string, want map[string]string) map[string]string {
+	t.Helper()
+	var got map[string]string
+	describe := func() string { return fmt.Sprintf("ENI %s to carry %v (last seen %v)", eniID, want, got) }
+	h.eventually(t, describe, func() (bool, error) {
+		tags, ok := h.EC2.Tags(eniID)
+		if !ok {
+			return false, fmt.Errorf("ENI %s not seeded", eniID)
+		}
+		got = tags
+		for k, v := range want {
+			if tags[k] != v {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	return got
+}
+
+// EventuallyENITagsAbsent waits until none of keys is set on the ENI.
+func (h *Harness) EventuallyENITagsAbsent(t *testing.T, eniID string, keys ...string) {
+	t.Helper()
+	h.eventually(t, func() string { return fmt.Sprintf("ENI %s to drop %v", eniID, keys) }, func() (bool, error) {
+		tags, ok := h.EC2.Tags(eniID)
+		if !ok {
+			return false, fmt.Errorf("ENI %s not seeded", eniID)
+		}
+		for _, k := range keys {
+			if _, present := tags[k]; present {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+// EventuallyCondition waits until the pod's tagged condition has the given reason
+// and returns the condition.
+func (h *Harness) EventuallyCondition(t *testing.T, pod *corev1.Pod, reason controller.ConditionReason) corev1.PodCondition {
+	t.Helper()
+	conditionType := corev1.PodConditionType(controller.NewKeys(h.Reconciler.KeyDomain).ConditionType)
+	var found corev1.PodCondition
+	h.eventually(t, func() string { return fmt.Sprintf("pod %s condition reason %s", pod.Name, reason) }, func() (bool, error) {
+		current := &corev1.Pod{}
+		if err := h.Client.Get(context.Background(), client.ObjectKeyFromObject(pod), current); err != nil {
+			return false, err
+		}
+		for _, c := range current.Status.Conditions {
+			if c.Type == conditionType && c.Reason == string(reason) {
+				found = c
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	return found
+}
+
+func (h *Harness) annotationKey() string {
+	if h.Reconciler.AnnotationKey != "" {
+		return h.Reconciler.AnnotationKey
+	}
+	return controller.AnnotationKey
+}
+
+// eventually polls cond until it returns true, failing the test after DefaultTimeout.
+// describe is evaluated only on failure so it can report the last observed state.
+func (h *Harness) eventually(t *testing.T, describe func() string, cond func() (bool, error)) {
+	t.Helper()
+	err := wait.PollUntilContextTimeout(context.Background(), pollInterval, DefaultTimeout, true, func(context.Context) (bool, error) {
+		return cond()
+	})
+	if err != nil {
+		t.Fatalf("timed out waiting for %s: %v", describe(), err)
+	}
+}
@@ -0,0 +1,77 @@
+package harness
+
+import (
+	"testing"
+
+	"k8s-eni-tagger/pkg/controller"
+
+	"github.com/prabhu-mannu/k8s-eni-tagger/e2e-v2/mock/ec2mock"
+)
+
+func TestTagAndCleanupFullLoop(t *testing.T) {
+	h := Start(t)
+
+	eni := ec2mock.ENI{ID: "eni-harness-1", PrivateIP: "10.0.1.10", InterfaceType: "branch", SubnetID: "subnet-harness"}
+	pod := h.CreateAnnotatedPod(t, "tagged", eni, "CostCenter=1234,Team=Platform")
+
+	tags := h.EventuallyENITags(t, eni.ID, map[string]string{"CostCenter": "1234", "Team": "Platform"})
+	if tags[controller.HashTagKey] == "" {
+		t.Errorf("expected %s tag on ENI, got %v", controller.HashTagKey, tags)
+	}
+
+	cond := h.EventuallyCondition(t, pod, controller.ReasonSynced)
+	details, err := controller.ParseConditionDetails(cond.Message)
+	if err != nil {
+		t.Fatalf("parse condition message %q: %v", cond.Message, err)
+	}
+	if details.ENIID != eni.ID || details.SubnetID != eni.SubnetID {
+		t.Errorf("condition details = %+v, want ENI %s in %s", details, eni.ID, eni.SubnetID)
+	}
+
+	h.DeletePod(t, pod)
+	h.EventuallyENITagsAbsent(t, eni.ID, "CostCenter", "Team", controller.HashTagKey)
+}
+
+func TestForeignControllerOwnership(t *testing.T) {
+	h := Start(t, func(r *controller.PodReconciler) {
+		r.ControllerID = "kube-system/eni-tagger-a"
+	})
+
+	eni := ec2mock.ENI{
+		ID: "eni-harness-2", PrivateIP: "10.0.1.11", InterfaceType: "branch", SubnetID: "subnet-harness",
+		Tags: map[string]string{controller.OwnerTagKey: "team-b/eni-tagger-b"},
+	}
+	pod := h.CreateAnnotatedPod(t, "foreign", eni, "Team=Platform")
+
+	h.EventuallyCondition(t, pod, controller.ReasonForeignController)
+	if tags, _ := h.EC2.Tags(eni.ID); tags["Team"] != "" {
+		t.Errorf("ENI owned by another controller was tagged: %v", tags)
+	}
+}
+
+func TestTrunkNodeENIs(t *testing.T) {
+	h := Start(t)
+	if _, err := h.EC2.LoadFixtureFile("../mock/fixtures/trunk-nodes.yaml"); err != nil {
+		t.Fatalf("load fixture: %v", err)
+	}
+	annotations := map[string]string{h.annotationKey(): "Team=Platform"}
+
+	// Branch ENIs are tagged whatever interface type or number of IPs they report
+	for name, ip := range map[string]string{"bottlerocket-branch": "10.0.2.12", "windows-branch": "10.0.2.25"} {
+		pod := h.CreatePod(t, name, ip, annotations)
+		h.EventuallyCondition(t, pod, controller.ReasonSynced)
+	}
+	h.EventuallyENITags(t, "eni-br-branch", map[string]string{"Team": "Platform"})
+	h.EventuallyENITags(t, "eni-win-branch", map[string]string{"Team": "Platform"})
+
+	// Trunk ENIs and the Windows primary ENI are shared by the node's pods
+	for name, ip := range map[string]string{"bottlerocket-trunk": "10.0.2.11", "windows-trunk": "10.0.2.23", "windows-primary": "10.0.2.21"} {
+		pod := h.CreatePod(t, name, ip, annotations)
+		h.EventuallyCondition(t, pod, controller.ReasonENIValidationFailed)
+	}
+	for _, eniID := range []string{"eni-br-trunk", "eni-win-trunk", "eni-win-primary"} {
+		if tags, _ := h.EC2.Tags(eniID); tags["Team"] != "" {
+			t.Errorf("shared ENI %s was tagged: %v", eniID, tags)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package ec2mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Edge-case modes. Each turns successful EC2 responses into valid-looking but
+// awkward ones, to harden the SDK-facing code paths.
+const (
+	// EdgeCaseEmptyTagItems adds <item/> and an item with an empty key and
+	// value to every resource tagSet.
+	EdgeCaseEmptyTagItems = "empty-tag-items"
+	// EdgeCaseUnicode adds a tag whose key and value are raw, unescaped UTF-8
+	// (plus a numeric character reference).
+	EdgeCaseUnicode = "unicode"
+	// EdgeCaseLargeValues adds a tag with a 128-character key and a
+	// 256-character value, the EC2 maximums.
+	EdgeCaseLargeValues = "large-values"
+	// EdgeCaseTruncatedXML cuts the response body in half, keeping the 200.
+	EdgeCaseTruncatedXML = "truncated-xml"
+)
+
+// tagSetActions are the actions whose responses carry per-resource tagSets
+// that the tag modes extend. DescribeTags is excluded: its items describe
+// tags on other resources and have a different shape.
+var tagSetActions = map[string]bool{
+	"DescribeNetworkInterfaces": true,
+	"DescribeInstances":         true,
+	"DescribeSubnets":           true,
+}
+
+// EdgeCases selects edge-case modes. It applies to every tenant.
+type EdgeCases struct {
+	// Modes to apply; see the EdgeCase constants.
+	Modes []string `json:"modes"`
+	// Actions limits the modes to these EC2 actions. Empty means all.
+	Actions []string `json:"actions,omitempty"`
+	// Count limits how many responses are altered. 0 means until cleared.
+	Count int `json:"count,omitempty"`
+}
+
+func (e EdgeCases) validate() error {
+	for _, m := range e.Modes {
+		switch m {
+		case EdgeCaseEmptyTagItems, EdgeCaseUnicode, EdgeCaseLargeValues, EdgeCaseTruncatedXML:
+		default:
+			return fmt.Errorf("unknown edge-case mode %q", m)
+		}
+	}
+	if e.Count < 0 {
+		return fmt.Errorf("count must not be negative")
+	}
+	return nil
+}
+
+// SetEdgeCases replaces the active edge-case modes, like PUT
+// /admin/edge-cases. An empty Modes list turns them off.
+func (s *Server) SetEdgeCases(e EdgeCases) error {
+	if err := e.validate(); err != nil {
+		return err
+	}
+	s.edgeCases.set(e)
+	return nil
+}
+
+type edgeCaseState struct {
+	mu        sync.Mutex
+	cfg       EdgeCases
+	remaining int
+}
+
+func (st *edgeCaseState) set(e EdgeCases) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.cfg = e
+	st.remaining = e.Count
+}
+
+func (st *edgeCaseState) get() EdgeCases {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.cfg
+}
+
+func (st *edgeCaseState) active() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return len(st.cfg.Modes) > 0
+}
+
+// take returns the modes that apply to a response for action, consuming one
+// use of a limited Count.
+func (st *edgeCaseState) take(action string) []string {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if len(st.cfg.Modes) == 0 {
+		return nil
+	}
+	if len(st.cfg.Actions) > 0 && !contains(st.cfg.Actions, action) {
+		return nil
+	}
+	var modes []string
+	for _, m := range st.cfg.Modes {
+		if m == EdgeCaseTruncatedXML || tagSetActions[action] {
+			modes = append(modes, m)
+		}
+	}
+	if len(modes) == 0 {
+		return nil
+	}
+	if st.cfg.Count > 0 {
+		st.remaining--
+		if st.remaining <= 0 {
+			st.cfg = EdgeCases{}
+		}
+	}
+	return modes
+}
+
+// applyEdgeCases rewrites a rendered response body.
+func applyEdgeCases(modes []string, body []byte) []byte {
+	out := string(body)
+	var extra strings.Builder
+	for _, m := range modes {
+		switch m {
+		case EdgeCaseEmptyTagItems:
+			extra.WriteString("        <item/>\n        <item>\n          <key></key>\n          <value/>\n        </item>\n")
+		case EdgeCaseUnicode:
+			extra.WriteString("        <item>\n          <key>edge/ключ-键</key>\n          <value>naïve café ☃ 日本語 🚀 caf&#233;</value>\n        </item>\n")
+		case EdgeCaseLargeValues:
+			key := "edge/" + strings.Repeat("k", 123)
+			extra.WriteString(fmt.Sprintf("        <item>\n          <key>%s</key>\n          <value>%s</value>\n        </item>\n", key, strings.Repeat("v", 256)))
+		}
+	}
+	if extra.Len() > 0 {
+		out = strings.ReplaceAll(out, "<tagSet>\n", "<tagSet>\n"+extra.String())
+	}
+	if contains(modes, EdgeCaseTruncatedXML) {
+		out = out[:len(out)/2]
+	}
+	return []byte(out)
+}
+
+// handleEdgeCases serves GET, PUT, and DELETE /admin/edge-cases.
+func handleEdgeCases(w http.ResponseWriter, r *http.Request, s *Server) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.edgeCases.get())
+	case http.MethodPut:
+		var req EdgeCases
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+			return
+		}
+		if err := s.SetEdgeCases(req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSONStatus(w, http.StatusOK, "edge cases updated")
+	case http.MethodDelete:
+		s.edgeCases.set(EdgeCases{})
+		writeJSONStatus(w, http.StatusOK, "edge cases cleared")
+	default:
+		methodNotAllowed(w)
+	}
+}
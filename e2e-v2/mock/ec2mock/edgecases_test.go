@@ -0,0 +1,136 @@
+package ec2mock
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestEdgeCases(t *testing.T) {
+	tests := []struct {
+		name      string
+		edgeCases EdgeCases
+		action    string
+		want      []string
+		wantValid bool
+	}{
+		{"off", EdgeCases{}, "DescribeNetworkInterfaces", nil, true},
+		{"empty tag items", EdgeCases{Modes: []string{EdgeCaseEmptyTagItems}}, "DescribeNetworkInterfaces", []string{"<item/>", "<key></key>"}, true},
+		{"unicode", EdgeCases{Modes: []string{EdgeCaseUnicode}}, "DescribeSubnets", []string{"edge/ключ-键", "☃"}, true},
+		{"large values", EdgeCases{Modes: []string{EdgeCaseLargeValues}}, "DescribeInstances", []string{strings.Repeat("v", 256)}, true},
+		{"truncated XML", EdgeCases{Modes: []string{EdgeCaseTruncatedXML}}, "DescribeTags", nil, false},
+		{"tag modes skip DescribeTags", EdgeCases{Modes: []string{EdgeCaseUnicode}}, "DescribeTags", nil, true},
+		{"other action", EdgeCases{Modes: []string{EdgeCaseTruncatedXML}, Actions: []string{"DescribeSubnets"}}, "DescribeNetworkInterfaces", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			seedInstanceFixture(t, s)
+			if err := s.SetEdgeCases(tt.edgeCases); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := query(s, nil, "Action", tt.action)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(rec.Body.String(), w) {
+					t.Errorf("response lacks %q: %s", w, rec.Body)
+				}
+			}
+			if valid := wellFormed(rec.Body.String()); valid != tt.wantValid {
+				t.Errorf("well-formed = %t, want %t: %s", valid, tt.wantValid, rec.Body)
+			}
+		})
+	}
+}
+
+func TestEdgeCasesCount(t *testing.T) {
+	s := NewServer()
+	seedInstanceFixture(t, s)
+	if err := s.SetEdgeCases(EdgeCases{Modes: []string{EdgeCaseTruncatedXML}, Count: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, wantValid := range []bool{false, false, true} {
+		rec := query(s, nil, "Action", "DescribeSubnets")
+		if valid := wellFormed(rec.Body.String()); valid != wantValid {
+			t.Errorf("response %d well-formed = %t, want %t", i, valid, wantValid)
+		}
+	}
+	// Errors do not use up the count
+	if err := s.SetEdgeCases(EdgeCases{Modes: []string{EdgeCaseTruncatedXML}, Count: 1}); err != nil {
+		t.Fatal(err)
+	}
+	query(s, nil, "Action", "DescribeSubnets", "SubnetId.1", "subnet-missing")
+	if rec := query(s, nil, "Action", "DescribeSubnets"); wellFormed(rec.Body.String()) {
+		t.Error("an error response used up the edge-case count")
+	}
+}
+
+func TestEdgeCasesAdminEndpoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantModes  int
+	}{
+		{"set", http.MethodPut, `{"modes":["unicode","truncated-xml"],"count":3}`, http.StatusOK, 2},
+		{"unknown mode", http.MethodPut, `{"modes":["explode"]}`, http.StatusBadRequest, 1},
+		{"negative count", http.MethodPut, `{"modes":["unicode"],"count":-1}`, http.StatusBadRequest, 1},
+		{"bad JSON", http.MethodPut, `{`, http.StatusBadRequest, 1},
+		{"clear", http.MethodDelete, "", http.StatusOK, 0},
+		{"wrong method", http.MethodPost, "", http.StatusMethodNotAllowed, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			if err := s.SetEdgeCases(EdgeCases{Modes: []string{EdgeCaseUnicode}}); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := admin(s, tt.method, "/admin/edge-cases", tt.body, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if got := len(s.edgeCases.get().Modes); got != tt.wantModes {
+				t.Errorf("active modes = %d, want %d", got, tt.wantModes)
+			}
+		})
+	}
+}
+
+// seedInstanceFixture seeds a subnet, an instance, and an attached ENI, all
+// tagged, so every Describe action returns a tagSet.
+func seedInstanceFixture(t *testing.T, s *Server) {
+	t.Helper()
+	tags := map[string]string{"team": "a"}
+	if err := s.Seed(&Fixture{
+		Subnets:   []Subnet{{ID: "subnet-1", VpcID: "vpc-1", CIDRBlock: "10.0.0.0/24", Tags: tags}},
+		Instances: []Instance{{ID: "i-1", SubnetID: "subnet-1", PrivateIP: "10.0.0.10", Tags: tags}},
+		ENIs:      []FixtureENI{{ENI: ENI{ID: "eni-a", PrivateIP: "10.0.0.10", InterfaceType: "interface", SubnetID: "subnet-1", InstanceID: "i-1"}, Tags: tags}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// wellFormed reports whether body parses as a complete XML document.
+func wellFormed(body string) bool {
+	d := xml.NewDecoder(strings.NewReader(body))
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return depth == 0 && err.Error() == "EOF"
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ec2mock
+
+import (
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Fixture is a bulk description of mock state, loaded from JSON or YAML:
+//
+//	subnets:
+//	  - {subnetId: subnet-1, vpcId: vpc-1, cidrBlock: 10.0.0.0/24}
+//	instances:
+//	  - {instanceId: i-1, subnetId: subnet-1, privateIp: 10.0.0.10}
+//	enis:
+//	  - {eniId: eni-1, privateIp: 10.0.0.11, interfaceType: branch, subnetId: subnet-1, tags: {team: a}}
+type Fixture struct {
+	ENIs      []FixtureENI `json:"enis,omitempty"`
+	Instances []Instance   `json:"instances,omitempty"`
+	Subnets   []Subnet     `json:"subnets,omitempty"`
+}
+
+// FixtureENI is an ENI in a fixture. Unlike POST /admin/enis, fixtures may set
+// pre-existing tags.
+type FixtureENI struct {
+	ENI  `json:",inline"`
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// ParseFixture decodes a JSON or YAML fixture. Unknown fields are rejected so
+// typos do not silently seed incomplete data.
+func ParseFixture(data []byte) (*Fixture, error) {
+	var f Fixture
+	if err := yaml.UnmarshalStrict(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid fixture: %w", err)
+	}
+	return &f, nil
+}
+
+// LoadFixtureFile reads and seeds a fixture file, returning what it seeded.
+func (s *Server) LoadFixtureFile(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := ParseFixture(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := s.Seed(f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Seed adds everything in the fixture. The whole fixture is validated first,
+// so an invalid entry seeds nothing.
+func (s *Server) Seed(f *Fixture) error {
+	for i, subnet := range f.Subnets {
+		if err := validateSubnet(subnet); err != nil {
+			return fmt.Errorf("subnets[%d]: %w", i, err)
+		}
+	}
+	for i, inst := range f.Instances {
+		if err := validateInstance(inst); err != nil {
+			return fmt.Errorf("instances[%d]: %w", i, err)
+		}
+	}
+	for i, eni := range f.ENIs {
+		if err := validateENI(eni.ENI); err != nil {
+			return fmt.Errorf("enis[%d]: %w", i, err)
+		}
+	}
+
+	for _, subnet := range f.Subnets {
+		s.store.upsertSubnet(subnet)
+	}
+	for _, inst := range f.Instances {
+		s.store.upsertInstance(inst)
+	}
+	for _, eni := range f.ENIs {
+		rec := eni.ENI
+		rec.Tags = eni.Tags
+		s.store.upsert(rec)
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package ec2mock
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseFixture(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+		check   func(t *testing.T, f *Fixture)
+	}{
+		{
+			name: "YAML",
+			data: `
+subnets:
+  - {subnetId: subnet-1, vpcId: vpc-1, cidrBlock: 10.0.0.0/24}
+instances:
+  - {instanceId: i-1, subnetId: subnet-1, privateIp: 10.0.0.10}
+enis:
+  - {eniId: eni-1, privateIp: 10.0.0.11, interfaceType: branch, subnetId: subnet-1, tags: {team: a}}
+`,
+			check: func(t *testing.T, f *Fixture) {
+				if len(f.Subnets) != 1 || len(f.Instances) != 1 || len(f.ENIs) != 1 {
+					t.Fatalf("fixture = %+v", f)
+				}
+				if f.ENIs[0].ID != "eni-1" || f.ENIs[0].Tags["team"] != "a" {
+					t.Errorf("ENI = %+v", f.ENIs[0])
+				}
+			},
+		},
+		{
+			name: "JSON",
+			data: `{"enis":[{"eniId":"eni-1","privateIp":"10.0.0.11","interfaceType":"branch","subnetId":"subnet-1","secondaryIps":["10.0.0.12"]}]}`,
+			check: func(t *testing.T, f *Fixture) {
+				if len(f.ENIs) != 1 || len(f.ENIs[0].SecondaryIPs) != 1 {
+					t.Errorf("fixture = %+v", f)
+				}
+			},
+		},
+		{name: "empty", data: "", check: func(t *testing.T, f *Fixture) {}},
+		{name: "unknown field", data: "enis:\n  - {eniId: eni-1, ipAddress: 10.0.0.11}\n", wantErr: "invalid fixture"},
+		{name: "unknown section", data: "volumes: []\n", wantErr: "invalid fixture"},
+		{name: "malformed", data: "enis: [", wantErr: "invalid fixture"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ParseFixture([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, f)
+		})
+	}
+}
+
+func TestSeedValidatesWholeFixture(t *testing.T) {
+	validENI := FixtureENI{ENI: ENI{ID: "eni-1", PrivateIP: "10.0.0.11", InterfaceType: "branch", SubnetID: "subnet-1"}}
+	tests := []struct {
+		name    string
+		fixture Fixture
+		wantErr string
+	}{
+		{"valid", Fixture{ENIs: []FixtureENI{validENI}}, ""},
+		{"subnet without CIDR", Fixture{ENIs: []FixtureENI{validENI}, Subnets: []Subnet{{ID: "subnet-1", VpcID: "vpc-1"}}}, "subnets[0]"},
+		{"instance with unknown state", Fixture{ENIs: []FixtureENI{validENI}, Instances: []Instance{{ID: "i-1", SubnetID: "subnet-1", PrivateIP: "10.0.0.10", State: "melting"}}}, "instances[0]"},
+		{"ENI without subnet", Fixture{ENIs: []FixtureENI{validENI, {ENI: ENI{ID: "eni-2", PrivateIP: "10.0.0.12", InterfaceType: "branch"}}}}, "enis[1]"},
+		{"secondary IP equal to primary", Fixture{ENIs: []FixtureENI{{ENI: ENI{ID: "eni-2", PrivateIP: "10.0.0.12", InterfaceType: "branch", SubnetID: "subnet-1", SecondaryIPs: []string{"10.0.0.12"}}}}}, "enis[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			err := s.Seed(&tt.fixture)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := s.Tags("eni-1"); !ok {
+					t.Error("valid fixture not seeded")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if _, ok := s.Tags("eni-1"); ok {
+				t.Error("invalid fixture was partly seeded")
+			}
+		})
+	}
+}
+
+func TestLoadFixtureFile(t *testing.T) {
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("enis:\n  - {eniId: eni-1}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"shipped fixture", "../fixtures/trunk-nodes.yaml", ""},
+		{"missing file", filepath.Join(dir, "missing.yaml"), "no such file"},
+		{"invalid ENI", invalid, "invalid.yaml: enis[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			f, err := s.LoadFixtureFile(tt.path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, eni := range f.ENIs {
+				tags, ok := s.Tags(eni.ID)
+				if !ok {
+					t.Fatalf("%s not seeded", eni.ID)
+				}
+				assertTags(t, tags, eni.Tags)
+			}
+		})
+	}
+}
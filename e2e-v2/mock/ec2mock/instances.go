@@ -0,0 +1,175 @@
+package ec2mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// instanceStateCodes are the EC2 state codes reported alongside state names.
+var instanceStateCodes = map[string]int{
+	"pending":       0,
+	"running":       16,
+	"shutting-down": 32,
+	"terminated":    48,
+	"stopping":      64,
+	"stopped":       80,
+}
+
+// instanceFilters maps the DescribeInstances filter names the mock supports to
+// a function reporting whether an instance matches one of the filter values.
+var instanceFilters = map[string]func(inst *Instance, enis []*ENI, values []string) bool{
+	"instance-id":         func(inst *Instance, _ []*ENI, v []string) bool { return contains(v, inst.ID) },
+	"instance-type":       func(inst *Instance, _ []*ENI, v []string) bool { return contains(v, inst.InstanceType) },
+	"instance-state-name": func(inst *Instance, _ []*ENI, v []string) bool { return contains(v, inst.State) },
+	"subnet-id":           func(inst *Instance, _ []*ENI, v []string) bool { return contains(v, inst.SubnetID) },
+	"private-ip-address":  func(inst *Instance, _ []*ENI, v []string) bool { return contains(v, inst.PrivateIP) },
+	"network-interface.network-interface-id": func(_ *Instance, enis []*ENI, v []string) bool {
+		return anyENI(enis, func(e *ENI) bool { return contains(v, e.ID) })
+	},
+	"network-interface.addresses.private-ip-address": func(_ *Instance, enis []*ENI, v []string) bool {
+		return anyENI(enis, func(e *ENI) bool { return containsAny(v, e.PrivateIPs()) })
+	},
+}
+
+// validateInstance checks the fields required to answer DescribeInstances.
+func validateInstance(inst Instance) error {
+	if inst.ID == "" || inst.SubnetID == "" || inst.PrivateIP == "" {
+		return fmt.Errorf("instanceId, subnetId, and privateIp are required")
+	}
+	if _, ok := instanceStateCodes[inst.State]; inst.State != "" && !ok {
+		return fmt.Errorf("unknown instance state %q", inst.State)
+	}
+	return nil
+}
+
+func handleSeedInstance(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	var req Instance
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+
+	if err := validateInstance(req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	store.upsertInstance(req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status":  "ok",
+		"message": "instance seeded",
+	})
+}
+
+func describeInstances(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	filters := extractFilters(r.Form)
+	for name := range filters {
+		if _, ok := instanceFilters[name]; !ok {
+			writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", fmt.Sprintf("The filter '%s' is invalid", name))
+			return
+		}
+	}
+	ids := extractIndexed(r.Form, "InstanceId")
+
+	instances := store.listInstances(func(inst *Instance, enis []*ENI) bool {
+		if len(ids) > 0 && !contains(ids, inst.ID) {
+			return false
+		}
+		for name, values := range filters {
+			if !instanceFilters[name](inst, enis, values) {
+				return false
+			}
+		}
+		return true
+	})
+
+	for _, id := range ids {
+		if !containsInstance(instances, id) {
+			writeXMLError(w, http.StatusBadRequest, "InvalidInstanceID.NotFound", fmt.Sprintf("The instance ID '%s' does not exist", id))
+			return
+		}
+	}
+
+	var items strings.Builder
+	for _, inst := range instances {
+		items.WriteString(buildReservation(inst))
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	response := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<DescribeInstancesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>%s</requestId>
+  <reservationSet>
+%s  </reservationSet>
+</DescribeInstancesResponse>`, requestID(), items.String())
+	_, _ = w.Write([]byte(response))
+}
+
+// buildReservation renders an instance as its own reservation, which is how
+// EC2 reports instances launched one at a time.
+func buildReservation(inst instanceView) string {
+	var nics strings.Builder
+	for _, e := range inst.ENIs {
+		nics.WriteString(fmt.Sprintf(`            <item>
+              <networkInterfaceId>%s</networkInterfaceId>
+              <subnetId>%s</subnetId>
+              <description>%s</description>
+              <interfaceType>%s</interfaceType>
+              <status>%s</status>
+              <privateIpAddress>%s</privateIpAddress>
+              <attachment>
+                <attachmentId>%s</attachmentId>
+                <deviceIndex>%d</deviceIndex>
+                <status>attached</status>
+              </attachment>
+            </item>
+`, xmlEscape(e.ID), xmlEscape(e.SubnetID), xmlEscape(e.Description), xmlEscape(e.InterfaceType), eniStatus(e),
+			xmlEscape(e.PrivateIP), xmlEscape(e.AttachmentID()), e.DeviceIndex))
+	}
+
+	return fmt.Sprintf(`    <item>
+      <reservationId>r-%s</reservationId>
+      <ownerId>000000000000</ownerId>
+      <instancesSet>
+        <item>
+          <instanceId>%s</instanceId>
+          <instanceType>%s</instanceType>
+          <instanceState>
+            <code>%d</code>
+            <name>%s</name>
+          </instanceState>
+          <subnetId>%s</subnetId>
+          <privateIpAddress>%s</privateIpAddress>
+          <networkInterfaceSet>
+%s          </networkInterfaceSet>
+          <tagSet>
+%s          </tagSet>
+        </item>
+      </instancesSet>
+    </item>
+`, xmlEscape(strings.TrimPrefix(inst.ID, "i-")), xmlEscape(inst.ID), xmlEscape(inst.InstanceType), instanceStateCodes[inst.State], xmlEscape(inst.State),
+		xmlEscape(inst.SubnetID), xmlEscape(inst.PrivateIP), nics.String(), buildTagSet(inst.Tags))
+}
+
+func anyENI(enis []*ENI, match func(*ENI) bool) bool {
+	for _, e := range enis {
+		if match(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInstance(instances []instanceView, id string) bool {
+	for _, inst := range instances {
+		if inst.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package ec2mock
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDescribeInstances(t *testing.T) {
+	s := NewServer()
+	if err := s.Seed(&Fixture{
+		Instances: []Instance{
+			{ID: "i-1", InstanceType: "m5.large", SubnetID: "subnet-1", PrivateIP: "10.0.0.10"},
+			{ID: "i-2", InstanceType: "c5.large", State: "stopped", SubnetID: "subnet-2", PrivateIP: "10.0.1.10"},
+		},
+		ENIs: []FixtureENI{
+			{ENI: ENI{ID: "eni-1b", PrivateIP: "10.0.0.11", SecondaryIPs: []string{"10.0.0.12"}, InterfaceType: "interface", SubnetID: "subnet-1", InstanceID: "i-1", DeviceIndex: 1}},
+			{ENI: ENI{ID: "eni-1a", PrivateIP: "10.0.0.10", InterfaceType: "interface", SubnetID: "subnet-1", InstanceID: "i-1"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		params     []string
+		wantStatus int
+		want       []string
+		notWant    []string
+	}{
+		{"all", nil, http.StatusOK, []string{"i-1", "i-2", "<code>16</code>", "<code>80</code>"}, nil},
+		{"by ID", []string{"InstanceId.1", "i-2"}, http.StatusOK, []string{"<name>stopped</name>"}, []string{"<instanceId>i-1<"}},
+		{"by state", []string{"Filter.1.Name", "instance-state-name", "Filter.1.Value.1", "running"}, http.StatusOK, []string{"<instanceId>i-1<"}, []string{"<instanceId>i-2<"}},
+		{"by ENI ID", []string{"Filter.1.Name", "network-interface.network-interface-id", "Filter.1.Value.1", "eni-1b"}, http.StatusOK, []string{"<instanceId>i-1<"}, []string{"<instanceId>i-2<"}},
+		{"by ENI secondary IP", []string{"Filter.1.Name", "network-interface.addresses.private-ip-address", "Filter.1.Value.1", "10.0.0.12"}, http.StatusOK, []string{"<instanceId>i-1<"}, []string{"<instanceId>i-2<"}},
+		{"no match", []string{"Filter.1.Name", "instance-type", "Filter.1.Value.1", "t3.micro"}, http.StatusOK, nil, []string{"i-1", "i-2"}},
+		{"unknown ID", []string{"InstanceId.1", "i-missing"}, http.StatusBadRequest, []string{"InvalidInstanceID.NotFound"}, nil},
+		{"unknown filter", []string{"Filter.1.Name", "tag:Name", "Filter.1.Value.1", "x"}, http.StatusBadRequest, []string{"InvalidParameterValue"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := query(s, nil, append([]string{"Action", "DescribeInstances"}, tt.params...)...)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(rec.Body.String(), w) {
+					t.Errorf("response lacks %q: %s", w, rec.Body)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(rec.Body.String(), w) {
+					t.Errorf("response contains %q: %s", w, rec.Body)
+				}
+			}
+		})
+	}
+
+	// Attached ENIs are listed by device index
+	rec := query(s, nil, "Action", "DescribeInstances", "InstanceId.1", "i-1")
+	body := rec.Body.String()
+	if strings.Index(body, "eni-1a") > strings.Index(body, "eni-1b") {
+		t.Errorf("ENIs not ordered by device index: %s", body)
+	}
+}
+
+func TestSeedInstance(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{"valid", http.MethodPost, `{"instanceId":"i-1","subnetId":"subnet-1","privateIp":"10.0.0.10"}`, http.StatusCreated},
+		{"missing private IP", http.MethodPost, `{"instanceId":"i-1","subnetId":"subnet-1"}`, http.StatusBadRequest},
+		{"unknown state", http.MethodPost, `{"instanceId":"i-1","subnetId":"subnet-1","privateIp":"10.0.0.10","state":"melting"}`, http.StatusBadRequest},
+		{"bad JSON", http.MethodPost, `{`, http.StatusBadRequest},
+		{"wrong method", http.MethodGet, "", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			rec := admin(s, tt.method, "/admin/instances", tt.body, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			seeded := strings.Contains(query(s, nil, "Action", "DescribeInstances").Body.String(), "<instanceId>i-1<")
+			if want := tt.wantStatus == http.StatusCreated; seeded != want {
+				t.Errorf("instance seeded = %t, want %t", seeded, want)
+			}
+		})
+	}
+}
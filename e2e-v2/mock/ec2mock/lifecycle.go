@@ -0,0 +1,82 @@
+package ec2mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DetachENI clears the ENI's attachment, like POST /admin/enis/<id>/detach.
+func (s *Server) DetachENI(eniID string) error {
+	return s.store.detach(eniID)
+}
+
+// DeleteENI removes the ENI, like DELETE /admin/enis/<id>.
+func (s *Server) DeleteENI(eniID string) error {
+	return s.store.remove(eniID)
+}
+
+// ReassignIP deletes the ENI and gives its private IP to a new one, like
+// POST /admin/enis/<id>/reassign-ip.
+func (s *Server) ReassignIP(oldENIID string, next ENI) error {
+	_, err := s.store.reassignIP(oldENIID, next)
+	return err
+}
+
+// handleENILifecycle serves the per-ENI admin endpoints:
+//
+//	DELETE /admin/enis/<id>              delete the ENI
+//	POST   /admin/enis/<id>/detach       detach it from its instance
+//	POST   /admin/enis/<id>/reassign-ip  move its IP to a new ENI (JSON body: ENI fields)
+func handleENILifecycle(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	eniID, op, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/admin/enis/"), "/")
+	if eniID == "" {
+		writeJSONError(w, http.StatusBadRequest, "eniId is required")
+		return
+	}
+
+	switch {
+	case op == "" && r.Method == http.MethodDelete:
+		if err := store.remove(eniID); err != nil {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSONStatus(w, http.StatusOK, "ENI deleted")
+	case op == "detach" && r.Method == http.MethodPost:
+		if err := store.detach(eniID); err != nil {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		writeJSONStatus(w, http.StatusOK, "ENI detached")
+	case op == "reassign-ip" && r.Method == http.MethodPost:
+		var next ENI
+		if err := json.NewDecoder(r.Body).Decode(&next); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+			return
+		}
+		if _, ok := store.byID(eniID); !ok {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("eni %s not found", eniID))
+			return
+		}
+		rec, err := store.reassignIP(eniID, next)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSONStatus(w, http.StatusOK, fmt.Sprintf("IP %s moved from %s to %s", rec.PrivateIP, eniID, rec.ID))
+	case op == "" || op == "detach" || op == "reassign-ip":
+		methodNotAllowed(w)
+	default:
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("unknown ENI operation %q", op))
+	}
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status":  "ok",
+		"message": message,
+	})
+}
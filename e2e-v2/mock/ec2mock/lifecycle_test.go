@@ -0,0 +1,123 @@
+package ec2mock
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestENILifecycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		check      func(t *testing.T, s *Server)
+	}{
+		{
+			name:       "delete",
+			method:     http.MethodDelete,
+			path:       "/admin/enis/eni-a",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, s *Server) {
+				if _, ok := s.Tags("eni-a"); ok {
+					t.Error("deleted ENI still exists")
+				}
+				rec := query(s, nil, "Action", "DescribeNetworkInterfaces", "Filter.1.Name", "private-ip-address", "Filter.1.Value.1", "10.0.0.1")
+				if !strings.Contains(rec.Body.String(), "InvalidNetworkInterfaceID.NotFound") {
+					t.Errorf("lookup by the deleted ENI's IP = %s", rec.Body)
+				}
+			},
+		},
+		{
+			name:       "detach",
+			method:     http.MethodPost,
+			path:       "/admin/enis/eni-a/detach",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, s *Server) {
+				rec := query(s, nil, "Action", "DescribeNetworkInterfaces", "NetworkInterfaceId.1", "eni-a")
+				body := rec.Body.String()
+				if !strings.Contains(body, "<status>available</status>") || strings.Contains(body, "<attachment>") {
+					t.Errorf("detached ENI still attached: %s", body)
+				}
+				if tags, _ := s.Tags("eni-a"); tags["team"] != "a" {
+					t.Errorf("detach dropped tags: %v", tags)
+				}
+			},
+		},
+		{
+			name:       "reassign IP",
+			method:     http.MethodPost,
+			path:       "/admin/enis/eni-a/reassign-ip",
+			body:       `{"eniId":"eni-b"}`,
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, s *Server) {
+				if _, ok := s.Tags("eni-a"); ok {
+					t.Error("old ENI still exists")
+				}
+				tags, ok := s.Tags("eni-b")
+				if !ok || len(tags) != 0 {
+					t.Errorf("new ENI tags = %v, %t, want empty", tags, ok)
+				}
+				rec := query(s, nil, "Action", "DescribeNetworkInterfaces", "Filter.1.Name", "private-ip-address", "Filter.1.Value.1", "10.0.0.1")
+				body := rec.Body.String()
+				if !strings.Contains(body, "eni-b") || !strings.Contains(body, "subnet-1") || strings.Contains(body, "<attachment>") {
+					t.Errorf("lookup by the reassigned IP = %s", body)
+				}
+			},
+		},
+		{"delete unknown ENI", http.MethodDelete, "/admin/enis/eni-missing", "", http.StatusNotFound, nil},
+		{"detach unknown ENI", http.MethodPost, "/admin/enis/eni-missing/detach", "", http.StatusNotFound, nil},
+		{"reassign unknown ENI", http.MethodPost, "/admin/enis/eni-missing/reassign-ip", `{"eniId":"eni-b"}`, http.StatusNotFound, nil},
+		{"reassign to same ID", http.MethodPost, "/admin/enis/eni-a/reassign-ip", `{"eniId":"eni-a"}`, http.StatusBadRequest, nil},
+		{"reassign to existing ENI", http.MethodPost, "/admin/enis/eni-a/reassign-ip", `{"eniId":"eni-other"}`, http.StatusBadRequest, nil},
+		{"reassign with bad JSON", http.MethodPost, "/admin/enis/eni-a/reassign-ip", `{`, http.StatusBadRequest, nil},
+		{"wrong method", http.MethodGet, "/admin/enis/eni-a/detach", "", http.StatusMethodNotAllowed, nil},
+		{"unknown operation", http.MethodPost, "/admin/enis/eni-a/explode", "", http.StatusNotFound, nil},
+		{"missing ID", http.MethodDelete, "/admin/enis/", "", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			if err := s.Seed(&Fixture{
+				Instances: []Instance{{ID: "i-1", SubnetID: "subnet-1", PrivateIP: "10.0.0.10"}},
+				ENIs: []FixtureENI{
+					{ENI: ENI{ID: "eni-a", PrivateIP: "10.0.0.1", InterfaceType: "interface", SubnetID: "subnet-1", InstanceID: "i-1", DeviceIndex: 1}, Tags: map[string]string{"team": "a"}},
+					{ENI: ENI{ID: "eni-other", PrivateIP: "10.0.0.2", InterfaceType: "interface", SubnetID: "subnet-1"}},
+				},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := admin(s, tt.method, tt.path, tt.body, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.check != nil {
+				tt.check(t, s)
+			}
+		})
+	}
+}
+
+func TestLifecycleMethods(t *testing.T) {
+	s := NewServer()
+	mustSeedENI(t, s, ENI{ID: "eni-a", PrivateIP: "10.0.0.1", InterfaceType: "branch", SubnetID: "subnet-1", InstanceID: "i-1"})
+
+	if err := s.DetachENI("eni-a"); err != nil {
+		t.Fatalf("DetachENI: %v", err)
+	}
+	if err := s.ReassignIP("eni-a", ENI{ID: "eni-b"}); err != nil {
+		t.Fatalf("ReassignIP: %v", err)
+	}
+	if err := s.DeleteENI("eni-a"); err == nil {
+		t.Error("DeleteENI of the replaced ENI succeeded")
+	}
+	if err := s.DeleteENI("eni-b"); err != nil {
+		t.Fatalf("DeleteENI: %v", err)
+	}
+	if err := s.DetachENI("eni-b"); err == nil {
+		t.Error("DetachENI of a deleted ENI succeeded")
+	}
+}
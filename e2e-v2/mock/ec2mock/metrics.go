@@ -0,0 +1,223 @@
+package ec2mock
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// actionNames maps the normalized Action parameter to the name used in
+// metrics and access logs. Anything else is counted as "Unsupported".
+var actionNames = map[string]string{
+	"Describeaccountattributes": "DescribeAccountAttributes",
+	"Describenetworkinterfaces": "DescribeNetworkInterfaces",
+	"Describeinstances":         "DescribeInstances",
+	"Describesubnets":           "DescribeSubnets",
+	"Describetags":              "DescribeTags",
+	"Createtags":                "CreateTags",
+	"Deletetags":                "DeleteTags",
+}
+
+// WithAccessLog writes one structured record per request to logger: method,
+// path, EC2 action, tenant, HTTP status, AWS error code, and duration.
+func WithAccessLog(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.accessLog = logger
+	}
+}
+
+type requestKey struct {
+	action    string
+	status    int
+	errorCode string
+}
+
+type faultKey struct {
+	action string
+	fault  string
+}
+
+type durationStat struct {
+	count uint64
+	sum   float64
+}
+
+// metrics counts EC2 Query API calls. It is exposed in the Prometheus text
+// format on /metrics; the mock has no client library dependency.
+type metrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	durations map[string]*durationStat
+	faults    map[faultKey]uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		requests:  make(map[requestKey]uint64),
+		durations: make(map[string]*durationStat),
+		faults:    make(map[faultKey]uint64),
+	}
+}
+
+func (m *metrics) observe(action string, status int, errorCode string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{action: action, status: status, errorCode: errorCode}]++
+	stat, ok := m.durations[action]
+	if !ok {
+		stat = &durationStat{}
+		m.durations[action] = stat
+	}
+	stat.count++
+	stat.sum += d.Seconds()
+}
+
+// fault records an injected fault, i.e. a response the mock deliberately
+// degraded or failed rather than one caused by the request.
+func (m *metrics) fault(action, fault string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.faults[faultKey{action: action, fault: fault}]++
+}
+
+func (m *metrics) requestCount(action string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n uint64
+	for k, v := range m.requests {
+		if k.action == action {
+			n += v
+		}
+	}
+	return int(n)
+}
+
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var lines []string
+	for k, v := range m.requests {
+		lines = append(lines, fmt.Sprintf("ec2mock_requests_total{action=%q,status=\"%d\",error_code=%q} %d", k.action, k.status, k.errorCode, v))
+	}
+	writeFamily(w, "ec2mock_requests_total", "counter", "EC2 Query API requests by action, HTTP status, and AWS error code.", lines)
+
+	lines = nil
+	for action, stat := range m.durations {
+		lines = append(lines,
+			fmt.Sprintf("ec2mock_request_duration_seconds_sum{action=%q} %g", action, stat.sum),
+			fmt.Sprintf("ec2mock_request_duration_seconds_count{action=%q} %d", action, stat.count))
+	}
+	writeFamily(w, "ec2mock_request_duration_seconds", "summary", "Time spent serving EC2 Query API requests.", lines)
+
+	lines = nil
+	for k, v := range m.faults {
+		lines = append(lines, fmt.Sprintf("ec2mock_injected_faults_total{action=%q,fault=%q} %d", k.action, k.fault, v))
+	}
+	writeFamily(w, "ec2mock_injected_faults_total", "counter", "Responses the mock deliberately failed or degraded.", lines)
+}
+
+func writeFamily(w io.Writer, name, kind, help string, lines []string) {
+	sort.Strings(lines)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	for _, l := range lines {
+		fmt.Fprintln(w, l)
+	}
+}
+
+// RequestCount returns how many EC2 requests with the given action (e.g.
+// "DescribeNetworkInterfaces") the mock has served, across all tenants.
+func (s *Server) RequestCount(action string) int {
+	return s.metrics.requestCount(action)
+}
+
+// requestRecorder captures what a handler did so ServeHTTP can count and log
+// the request. While edge cases are active it buffers the response so
+// ServeHTTP can rewrite it once the action is known.
+type requestRecorder struct {
+	http.ResponseWriter
+	status    int
+	action    string
+	errorCode string
+	buffer    bool
+	body      bytes.Buffer
+}
+
+func (rec *requestRecorder) WriteHeader(status int) {
+	rec.status = status
+	if !rec.buffer {
+		rec.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (rec *requestRecorder) Write(p []byte) (int, error) {
+	if rec.buffer {
+		return rec.body.Write(p)
+	}
+	return rec.ResponseWriter.Write(p)
+}
+
+// flush writes a buffered response, applying any edge cases that match the
+// action to successful EC2 responses.
+func (s *Server) flush(rec *requestRecorder) {
+	body := rec.body.Bytes()
+	if rec.action != "" && rec.status == http.StatusOK {
+		modes := s.edgeCases.take(rec.action)
+		for _, m := range modes {
+			s.metrics.fault(rec.action, m)
+		}
+		body = applyEdgeCases(modes, body)
+	}
+	rec.ResponseWriter.WriteHeader(rec.status)
+	_, _ = rec.ResponseWriter.Write(body)
+}
+
+// noteAction records the EC2 action being served.
+func noteAction(w http.ResponseWriter, normalized string) {
+	if rec, ok := w.(*requestRecorder); ok {
+		if name, known := actionNames[normalized]; known {
+			rec.action = name
+		} else {
+			rec.action = "Unsupported"
+		}
+	}
+}
+
+// noteErrorCode records the AWS error code returned.
+func noteErrorCode(w http.ResponseWriter, code string) {
+	if rec, ok := w.(*requestRecorder); ok {
+		rec.errorCode = code
+	}
+}
+
+func (s *Server) observe(rec *requestRecorder, r *http.Request, d time.Duration) {
+	if rec.action != "" {
+		s.metrics.observe(rec.action, rec.status, rec.errorCode, d)
+	}
+	if s.accessLog == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("method", r.Method),
+		slog.String("path", r.URL.Path),
+		slog.Int("status", rec.status),
+		slog.Float64("durationMs", float64(d.Microseconds())/1000),
+	}
+	if rec.action != "" {
+		attrs = append(attrs, slog.String("action", rec.action))
+	}
+	if tenant := s.tenants.requestTenant(r); tenant != "" {
+		attrs = append(attrs, slog.String("tenant", tenant))
+	}
+	if rec.errorCode != "" {
+		attrs = append(attrs, slog.String("errorCode", rec.errorCode))
+	}
+	s.accessLog.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+}
@@ -0,0 +1,98 @@
+package ec2mock
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	s := NewServer()
+	mustSeedENI(t, s, ENI{ID: "eni-a", PrivateIP: "10.0.0.1", InterfaceType: "branch", SubnetID: "subnet-1"})
+
+	query(s, nil, "Action", "DescribeNetworkInterfaces")
+	query(s, http.Header{TestIDHeader: {"test-1"}}, "Action", "describenetworkinterfaces")
+	query(s, nil, "Action", "CreateTags", "ResourceId.1", "eni-missing", "Tag.1.Key", "k", "Tag.1.Value", "v")
+	query(s, nil, "Action", "RunInstances")
+	admin(s, http.MethodGet, "/admin/tags/eni-a", "", nil)
+	if err := s.SetEdgeCases(EdgeCases{Modes: []string{EdgeCaseUnicode}, Count: 1}); err != nil {
+		t.Fatal(err)
+	}
+	query(s, nil, "Action", "DescribeNetworkInterfaces")
+
+	counts := []struct {
+		action string
+		want   int
+	}{
+		{"DescribeNetworkInterfaces", 3},
+		{"CreateTags", 1},
+		{"Unsupported", 1},
+		{"DeleteTags", 0},
+	}
+	for _, c := range counts {
+		if got := s.RequestCount(c.action); got != c.want {
+			t.Errorf("RequestCount(%s) = %d, want %d", c.action, got, c.want)
+		}
+	}
+
+	rec := admin(s, http.MethodGet, "/metrics", "", nil)
+	body := rec.Body.String()
+	for _, want := range []string{
+		`ec2mock_requests_total{action="DescribeNetworkInterfaces",status="200",error_code=""} 3`,
+		`ec2mock_requests_total{action="CreateTags",status="400",error_code="InvalidNetworkInterfaceID.NotFound"} 1`,
+		`ec2mock_request_duration_seconds_count{action="DescribeNetworkInterfaces"} 3`,
+		`ec2mock_injected_faults_total{action="DescribeNetworkInterfaces",fault="unicode"} 1`,
+		"# TYPE ec2mock_request_duration_seconds summary",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %q:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "/admin") {
+		t.Errorf("admin requests were counted:\n%s", body)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  http.Header
+		params  []string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "success",
+			params:  []string{"Action", "DescribeSubnets"},
+			want:    []string{"action=DescribeSubnets", "status=200", "method=POST"},
+			notWant: []string{"errorCode", "tenant"},
+		},
+		{
+			name:   "error with tenant",
+			header: http.Header{TestIDHeader: {"test-1"}},
+			params: []string{"Action", "DeleteTags", "ResourceId.1", "eni-missing", "Tag.1.Key", "k"},
+			want:   []string{"action=DeleteTags", "status=400", "errorCode=InvalidNetworkInterfaceID.NotFound", "tenant=test-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			s := NewServer(WithAccessLog(slog.New(slog.NewTextHandler(&buf, nil))))
+
+			query(s, tt.header, tt.params...)
+			line := buf.String()
+			for _, w := range tt.want {
+				if !strings.Contains(line, w) {
+					t.Errorf("access log lacks %q: %s", w, line)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(line, w) {
+					t.Errorf("access log contains %q: %s", w, line)
+				}
+			}
+		})
+	}
+}
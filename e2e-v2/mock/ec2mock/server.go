@@ -0,0 +1,654 @@
+// Package ec2mock implements a minimal EC2 Query API (DescribeNetworkInterfaces,
+// DescribeInstances, DescribeSubnets, DescribeTags, CreateTags, DeleteTags) backed by an
+// in-memory ENI, instance, and subnet store. It is served by the
+// aws-mock binary and can also be embedded in tests.
+package ec2mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Server is the EC2 mock. It serves the EC2 Query API on "/" and JSON admin
+// endpoints under "/admin" for seeding ENIs, instances, and subnets and reading
+// tags.
+//
+// Each request is served from the store of its tenant (see TestIDHeader and
+// WithAccessKeyTenancy), so parallel tests can share one mock. The Seed and
+// Tags methods act on the default store; use Tenant for another one.
+type Server struct {
+	store     *eniStore
+	tenants   *tenants
+	metrics   *metrics
+	accessLog *slog.Logger
+	ready     *atomic.Bool
+	edgeCases *edgeCaseState
+	mux       *http.ServeMux
+}
+
+// NewServer returns an empty EC2 mock.
+func NewServer(opts ...Option) *Server {
+	s := &Server{tenants: newTenants(), metrics: newMetrics(), ready: new(atomic.Bool), edgeCases: &edgeCaseState{}, mux: http.NewServeMux()}
+	s.ready.Store(true)
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.store = s.tenants.get("")
+	tenants := s.tenants
+
+	s.mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	s.mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !s.ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte("seeding"))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+	s.mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		s.metrics.writeTo(w)
+	})
+	s.mux.HandleFunc("/admin/enis", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w)
+			return
+		}
+		handleSeedENI(w, r, tenants.forRequest(r))
+	})
+	s.mux.HandleFunc("/admin/enis/", func(w http.ResponseWriter, r *http.Request) {
+		handleENILifecycle(w, r, tenants.forRequest(r))
+	})
+	s.mux.HandleFunc("/admin/instances", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w)
+			return
+		}
+		handleSeedInstance(w, r, tenants.forRequest(r))
+	})
+	s.mux.HandleFunc("/admin/subnets", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w)
+			return
+		}
+		handleSeedSubnet(w, r, tenants.forRequest(r))
+	})
+	s.mux.HandleFunc("/admin/tags/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w)
+			return
+		}
+		handleGetTags(w, r, tenants.forRequest(r))
+	})
+	s.mux.HandleFunc("/admin/edge-cases", func(w http.ResponseWriter, r *http.Request) {
+		handleEdgeCases(w, r, s)
+	})
+	s.mux.HandleFunc("/admin/reset", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w)
+			return
+		}
+		tenants.reset(tenants.requestTenant(r))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodGet:
+			handleQueryAPI(w, r, tenants.forRequest(r))
+		default:
+			methodNotAllowed(w)
+		}
+	})
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &requestRecorder{ResponseWriter: w, status: http.StatusOK, buffer: s.edgeCases.active()}
+	s.mux.ServeHTTP(rec, r)
+	if rec.buffer {
+		s.flush(rec)
+	}
+	s.observe(rec, r, time.Since(start))
+}
+
+// SetReady controls /readyz. A new Server is ready; callers that seed it in
+// the background mark it not ready until seeding completes, while /healthz
+// keeps passing.
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// Tenant returns a view of the mock whose Seed and Tags methods act on the
+// store of the given test ID, i.e. the one requests carrying that X-Test-Id
+// header (or, with WithAccessKeyTenancy, that access key) see. It serves
+// HTTP exactly like s.
+func (s *Server) Tenant(id string) *Server {
+	view := *s
+	view.store = s.tenants.get(id)
+	return &view
+}
+
+// SeedENI adds or replaces an ENI, like POST /admin/enis.
+func (s *Server) SeedENI(eni ENI) error {
+	if err := validateENI(eni); err != nil {
+		return err
+	}
+	s.store.upsert(eni)
+	return nil
+}
+
+// SeedInstance adds or replaces an instance, like POST /admin/instances. ENIs
+// are attached to it by seeding them with a matching InstanceID.
+func (s *Server) SeedInstance(inst Instance) error {
+	if err := validateInstance(inst); err != nil {
+		return err
+	}
+	s.store.upsertInstance(inst)
+	return nil
+}
+
+// SeedSubnet adds or replaces a subnet, like POST /admin/subnets.
+func (s *Server) SeedSubnet(subnet Subnet) error {
+	if err := validateSubnet(subnet); err != nil {
+		return err
+	}
+	s.store.upsertSubnet(subnet)
+	return nil
+}
+
+// Tags returns a copy of the ENI's tags, like GET /admin/tags/<id>.
+func (s *Server) Tags(eniID string) (map[string]string, bool) {
+	rec, ok := s.store.byID(eniID)
+	if !ok {
+		return nil, false
+	}
+	return rec.Tags, true
+}
+
+// validateENI checks the fields required to answer DescribeNetworkInterfaces.
+func validateENI(eni ENI) error {
+	if eni.ID == "" || eni.PrivateIP == "" || eni.InterfaceType == "" || eni.SubnetID == "" {
+		return fmt.Errorf("eniId, privateIp, interfaceType, and subnetId are required")
+	}
+	for _, ip := range eni.SecondaryIPs {
+		if ip == "" || ip == eni.PrivateIP {
+			return fmt.Errorf("secondaryIps must be non-empty and differ from privateIp")
+		}
+	}
+	return nil
+}
+
+func handleSeedENI(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	var req ENI
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+
+	if err := validateENI(req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	store.upsert(req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status":  "ok",
+		"message": "ENI seeded",
+	})
+}
+
+func handleGetTags(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	eniID := strings.TrimPrefix(r.URL.Path, "/admin/tags/")
+	if eniID == "" {
+		writeJSONError(w, http.StatusBadRequest, "eniId is required")
+		return
+	}
+
+	rec, ok := store.byID(eniID)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("eni %s not found", eniID))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(rec.Tags)
+}
+
+func handleQueryAPI(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	if err := r.ParseForm(); err != nil {
+		writeXMLError(w, http.StatusBadRequest, "InvalidRequest", fmt.Sprintf("cannot parse request: %v", err))
+		return
+	}
+
+	action := strings.Title(strings.ToLower(r.Form.Get("Action")))
+	noteAction(w, action)
+	switch action {
+	case "Describeaccountattributes":
+		describeAccountAttributes(w)
+	case "Describenetworkinterfaces":
+		describeNetworkInterfaces(w, r, store)
+	case "Describeinstances":
+		describeInstances(w, r, store)
+	case "Describesubnets":
+		describeSubnets(w, r, store)
+	case "Describetags":
+		describeTags(w, r, store)
+	case "Createtags":
+		if isDryRun(r) {
+			writeDryRunResponse(w)
+			return
+		}
+		createTags(w, r, store)
+	case "Deletetags":
+		if isDryRun(r) {
+			writeDryRunResponse(w)
+			return
+		}
+		deleteTags(w, r, store)
+	case "":
+		writeXMLError(w, http.StatusBadRequest, "InvalidAction", "Action is required")
+	default:
+		writeXMLError(w, http.StatusBadRequest, "InvalidAction", fmt.Sprintf("unsupported action %s", action))
+	}
+}
+
+func describeAccountAttributes(w http.ResponseWriter) {
+	reqID := requestID()
+	w.Header().Set("Content-Type", "text/xml")
+	response := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<DescribeAccountAttributesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>%s</requestId>
+  <accountAttributeSet>
+    <item>
+      <attributeName>supported-platforms</attributeName>
+      <attributeValueSet>
+        <item>
+          <attributeValue>VPC</attributeValue>
+        </item>
+      </attributeValueSet>
+    </item>
+  </accountAttributeSet>
+</DescribeAccountAttributesResponse>`, reqID)
+	_, _ = w.Write([]byte(response))
+}
+
+// eniFilters maps the DescribeNetworkInterfaces filter names the mock supports
+// to the ENI field each one matches.
+var eniFilters = map[string]func(*ENI) string{
+	"network-interface-id":    func(e *ENI) string { return e.ID },
+	"subnet-id":               func(e *ENI) string { return e.SubnetID },
+	"interface-type":          func(e *ENI) string { return e.InterfaceType },
+	"attachment.instance-id":  func(e *ENI) string { return e.InstanceID },
+	"attachment.device-index": func(e *ENI) string { return attachedOnly(e, strconv.Itoa(e.DeviceIndex)) },
+	"attachment.attachment-id": func(e *ENI) string {
+		return attachedOnly(e, e.AttachmentID())
+	},
+	"status": eniStatus,
+}
+
+// eniListFilters are the filters matching any of several values of an ENI.
+var eniListFilters = map[string]func(*ENI) []string{
+	"private-ip-address": func(e *ENI) []string { return e.PrivateIPs() },
+}
+
+func describeNetworkInterfaces(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	filters := extractFilters(r.Form)
+	for name := range filters {
+		_, ok := eniFilters[name]
+		_, listOK := eniListFilters[name]
+		if !ok && !listOK {
+			writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", fmt.Sprintf("The filter '%s' is invalid", name))
+			return
+		}
+	}
+	ids := extractIndexed(r.Form, "NetworkInterfaceId")
+
+	enis := store.listENIs(func(e *ENI) bool {
+		if len(ids) > 0 && !contains(ids, e.ID) {
+			return false
+		}
+		for name, values := range filters {
+			if field, ok := eniFilters[name]; ok && !contains(values, field(e)) {
+				return false
+			}
+			if field, ok := eniListFilters[name]; ok && !containsAny(values, field(e)) {
+				return false
+			}
+		}
+		return true
+	})
+
+	// Lookups by private IP have always answered NotFound for an unknown
+	// address; existing e2e scenarios rely on that.
+	if len(enis) == 0 {
+		if ips := filters["private-ip-address"]; len(ips) > 0 {
+			writeXMLError(w, http.StatusBadRequest, "InvalidNetworkInterfaceID.NotFound", fmt.Sprintf("no ENI for IP %s", ips[0]))
+			return
+		}
+	}
+	for _, id := range ids {
+		if !containsENI(enis, id) {
+			writeXMLError(w, http.StatusBadRequest, "InvalidNetworkInterfaceID.NotFound", fmt.Sprintf("The networkInterface ID '%s' does not exist", id))
+			return
+		}
+	}
+
+	var items strings.Builder
+	for _, rec := range enis {
+		items.WriteString(fmt.Sprintf(`    <item>
+      <networkInterfaceId>%s</networkInterfaceId>
+      <subnetId>%s</subnetId>
+      <description>%s</description>
+      <interfaceType>%s</interfaceType>
+      <status>%s</status>
+      <privateIpAddress>%s</privateIpAddress>
+      <privateIpAddressesSet>
+%s      </privateIpAddressesSet>
+%s      <tagSet>
+%s      </tagSet>
+    </item>
+`, xmlEscape(rec.ID), xmlEscape(rec.SubnetID), xmlEscape(rec.Description), xmlEscape(rec.InterfaceType), eniStatus(rec),
+			xmlEscape(rec.PrivateIP), buildPrivateIPSet(rec), buildAttachment(rec), buildTagSet(rec.Tags)))
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	response := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<DescribeNetworkInterfacesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>%s</requestId>
+  <networkInterfaceSet>
+%s  </networkInterfaceSet>
+</DescribeNetworkInterfacesResponse>`, requestID(), items.String())
+	_, _ = w.Write([]byte(response))
+}
+
+// buildAttachment renders the <attachment> element of an attached ENI, or
+// nothing for an available one.
+func buildAttachment(e *ENI) string {
+	if e.InstanceID == "" {
+		return ""
+	}
+	return fmt.Sprintf(`      <attachment>
+        <attachmentId>%s</attachmentId>
+        <instanceId>%s</instanceId>
+        <deviceIndex>%d</deviceIndex>
+        <status>attached</status>
+      </attachment>
+`, xmlEscape(e.AttachmentID()), xmlEscape(e.InstanceID), e.DeviceIndex)
+}
+
+// buildPrivateIPSet renders the items of an ENI's <privateIpAddressesSet>, its
+// primary IP first.
+func buildPrivateIPSet(e *ENI) string {
+	var items strings.Builder
+	for i, ip := range e.PrivateIPs() {
+		items.WriteString(fmt.Sprintf(`        <item>
+          <privateIpAddress>%s</privateIpAddress>
+          <primary>%t</primary>
+        </item>
+`, xmlEscape(ip), i == 0))
+	}
+	return items.String()
+}
+
+func eniStatus(e *ENI) string {
+	if e.InstanceID != "" {
+		return "in-use"
+	}
+	return "available"
+}
+
+func attachedOnly(e *ENI, value string) string {
+	if e.InstanceID == "" {
+		return ""
+	}
+	return value
+}
+
+func containsENI(enis []*ENI, id string) bool {
+	for _, e := range enis {
+		if e.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func createTags(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	eniID := firstNonEmpty(r.Form.Get("ResourceId.1"), r.Form.Get("ResourceId.0"), r.Form.Get("ResourceId"))
+	if eniID == "" {
+		writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", "ResourceId.1 is required")
+		return
+	}
+	tags := extractTags(r.Form)
+	if len(tags) == 0 {
+		writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", "at least one Tag.n.Key is required")
+		return
+	}
+
+	if _, err := store.mergeTags(eniID, tags); err != nil {
+		writeXMLError(w, http.StatusBadRequest, "InvalidNetworkInterfaceID.NotFound", err.Error())
+		return
+	}
+
+	writeSimpleResponse(w, "CreateTagsResponse", "CreateTagsResult")
+}
+
+func deleteTags(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	eniID := firstNonEmpty(r.Form.Get("ResourceId.1"), r.Form.Get("ResourceId.0"), r.Form.Get("ResourceId"))
+	if eniID == "" {
+		writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", "ResourceId.1 is required")
+		return
+	}
+	keys := extractTagKeys(r.Form)
+	if len(keys) == 0 {
+		writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", "at least one Tag.n.Key is required")
+		return
+	}
+
+	if _, err := store.deleteTags(eniID, keys); err != nil {
+		writeXMLError(w, http.StatusBadRequest, "InvalidNetworkInterfaceID.NotFound", err.Error())
+		return
+	}
+
+	writeSimpleResponse(w, "DeleteTagsResponse", "DeleteTagsResult")
+}
+
+// extractFilters collects Filter.N.Name / Filter.N.Value.M parameters into a
+// map from filter name to accepted values.
+func extractFilters(values map[string][]string) map[string][]string {
+	filters := make(map[string][]string)
+	for key, vals := range values {
+		parts := strings.Split(key, ".")
+		// Filter.<index>.Name
+		if len(parts) != 3 || !strings.EqualFold(parts[0], "filter") || !strings.EqualFold(parts[2], "name") || len(vals) == 0 {
+			continue
+		}
+		name := vals[0]
+		prefix := strings.ToLower("filter." + parts[1] + ".value.")
+		for valueKey, valueVals := range values {
+			if strings.HasPrefix(strings.ToLower(valueKey), prefix) {
+				filters[name] = append(filters[name], valueVals...)
+			}
+		}
+		if _, ok := filters[name]; !ok {
+			filters[name] = nil
+		}
+	}
+	return filters
+}
+
+// extractIndexed collects list parameters such as InstanceId.1, InstanceId.2.
+func extractIndexed(values map[string][]string, name string) []string {
+	var results []string
+	prefix := strings.ToLower(name + ".")
+	for key, vals := range values {
+		if strings.HasPrefix(strings.ToLower(key), prefix) {
+			results = append(results, vals...)
+		}
+	}
+	return results
+}
+
+// containsAny reports whether any of vs is in values.
+func containsAny(values []string, vs []string) bool {
+	for _, v := range vs {
+		if contains(values, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, candidate := range values {
+		if candidate == v {
+			return true
+		}
+	}
+	return false
+}
+
+func extractTags(values map[string][]string) map[string]string {
+	tags := make(map[string]string)
+	for key, vals := range values {
+		if !strings.HasPrefix(strings.ToLower(key), "tag.") {
+			continue
+		}
+		parts := strings.Split(key, ".")
+		if len(parts) != 3 || !strings.EqualFold(parts[2], "key") {
+			continue
+		}
+		if len(vals) == 0 {
+			continue
+		}
+		idx := parts[1]
+		valueKey := fmt.Sprintf("Tag.%s.Value", idx)
+		value := first(values[valueKey])
+		tags[vals[0]] = value
+	}
+	return tags
+}
+
+func extractTagKeys(values map[string][]string) []string {
+	var keys []string
+	for key, vals := range values {
+		if !strings.HasPrefix(strings.ToLower(key), "tag.") {
+			continue
+		}
+		parts := strings.Split(key, ".")
+		if len(parts) != 3 || !strings.EqualFold(parts[2], "key") {
+			continue
+		}
+		keys = append(keys, first(vals))
+	}
+	return keys
+}
+
+func buildTagSet(tags map[string]string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for k, v := range tags {
+		b.WriteString("        <item>\n")
+		b.WriteString(fmt.Sprintf("          <key>%s</key>\n", xmlEscape(k)))
+		b.WriteString(fmt.Sprintf("          <value>%s</value>\n", xmlEscape(v)))
+		b.WriteString("        </item>\n")
+	}
+	return b.String()
+}
+
+func writeSimpleResponse(w http.ResponseWriter, envelope, result string) {
+	w.Header().Set("Content-Type", "text/xml")
+	response := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<%s xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>%s</requestId>
+  <%s>
+    <return>true</return>
+  </%s>
+</%s>`, envelope, requestID(), result, result, envelope)
+	_, _ = w.Write([]byte(response))
+}
+
+// isDryRun reports whether the request sets the EC2 DryRun parameter.
+func isDryRun(r *http.Request) bool {
+	return strings.EqualFold(r.Form.Get("DryRun"), "true")
+}
+
+// writeDryRunResponse mimics EC2's reply to an authorized DryRun request.
+func writeDryRunResponse(w http.ResponseWriter) {
+	writeXMLError(w, http.StatusPreconditionFailed, "DryRunOperation", "Request would have succeeded, but DryRun flag is set.")
+}
+
+func writeXMLError(w http.ResponseWriter, status int, code, message string) {
+	noteErrorCode(w, code)
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(status)
+	errResp := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<Response>
+  <Errors>
+    <Error>
+      <Code>%s</Code>
+      <Message>%s</Message>
+    </Error>
+  </Errors>
+  <RequestID>%s</RequestID>
+</Response>`, xmlEscape(code), xmlEscape(message), requestID())
+	_, _ = w.Write([]byte(errResp))
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func requestID() string {
+	return fmt.Sprintf("req-%d", time.Now().UnixNano())
+}
+
+func xmlEscape(s string) string {
+	replacer := strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		"\"", "&quot;",
+		"'", "&apos;",
+	)
+	return replacer.Replace(s)
+}
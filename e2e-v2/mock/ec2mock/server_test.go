@@ -0,0 +1,208 @@
+package ec2mock
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// query sends an EC2 Query API request to h with the given parameters and
+// headers, e.g. query(h, nil, "Action", "DescribeTags", "Filter.1.Name", "key").
+func query(h http.Handler, header http.Header, params ...string) *httptest.ResponseRecorder {
+	form := url.Values{}
+	for i := 0; i+1 < len(params); i += 2 {
+		form.Add(params[i], params[i+1])
+	}
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// admin sends a request to an admin endpoint of h.
+func admin(h http.Handler, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func mustSeedENI(t *testing.T, s *Server, eni ENI) {
+	t.Helper()
+	if err := s.SeedENI(eni); err != nil {
+		t.Fatalf("SeedENI(%s): %v", eni.ID, err)
+	}
+}
+
+func TestDescribeNetworkInterfacesFilters(t *testing.T) {
+	s := NewServer()
+	mustSeedENI(t, s, ENI{ID: "eni-a", PrivateIP: "10.0.0.1", SecondaryIPs: []string{"10.0.0.2"}, InterfaceType: "branch", SubnetID: "subnet-1"})
+	mustSeedENI(t, s, ENI{ID: "eni-b", PrivateIP: "10.0.0.3", InterfaceType: "interface", SubnetID: "subnet-2", InstanceID: "i-1", DeviceIndex: 1})
+
+	tests := []struct {
+		name       string
+		params     []string
+		wantStatus int
+		want       []string
+		notWant    []string
+	}{
+		{"all", nil, http.StatusOK, []string{"eni-a", "eni-b"}, nil},
+		{"by secondary IP", []string{"Filter.1.Name", "private-ip-address", "Filter.1.Value.1", "10.0.0.2"}, http.StatusOK, []string{"eni-a"}, []string{"<networkInterfaceId>eni-b<"}},
+		{"by subnet", []string{"Filter.1.Name", "subnet-id", "Filter.1.Value.1", "subnet-2"}, http.StatusOK, []string{"eni-b", "<status>in-use</status>"}, []string{"<networkInterfaceId>eni-a<"}},
+		{"values are ORed", []string{"Filter.1.Name", "subnet-id", "Filter.1.Value.1", "subnet-1", "Filter.1.Value.2", "subnet-2"}, http.StatusOK, []string{"eni-a", "eni-b"}, nil},
+		{"filters are ANDed", []string{"Filter.1.Name", "subnet-id", "Filter.1.Value.1", "subnet-1", "Filter.2.Name", "interface-type", "Filter.2.Value.1", "interface"}, http.StatusOK, nil, []string{"<networkInterfaceId>"}},
+		{"unknown IP", []string{"Filter.1.Name", "private-ip-address", "Filter.1.Value.1", "10.9.9.9"}, http.StatusBadRequest, []string{"InvalidNetworkInterfaceID.NotFound"}, nil},
+		{"unknown ID", []string{"NetworkInterfaceId.1", "eni-missing"}, http.StatusBadRequest, []string{"InvalidNetworkInterfaceID.NotFound"}, nil},
+		{"unknown filter", []string{"Filter.1.Name", "owner-id", "Filter.1.Value.1", "x"}, http.StatusBadRequest, []string{"InvalidParameterValue"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := query(s, nil, append([]string{"Action", "DescribeNetworkInterfaces"}, tt.params...)...)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(rec.Body.String(), w) {
+					t.Errorf("response lacks %q: %s", w, rec.Body)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(rec.Body.String(), w) {
+					t.Errorf("response contains %q: %s", w, rec.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateAndDeleteTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     []string
+		wantStatus int
+		wantCode   string
+		wantTags   map[string]string
+	}{
+		{
+			name:       "create merges",
+			params:     []string{"Action", "CreateTags", "ResourceId.1", "eni-a", "Tag.1.Key", "team", "Tag.1.Value", "b"},
+			wantStatus: http.StatusOK,
+			wantTags:   map[string]string{"team": "b", "env": "dev"},
+		},
+		{
+			name:       "delete removes keys",
+			params:     []string{"Action", "DeleteTags", "ResourceId.1", "eni-a", "Tag.1.Key", "env"},
+			wantStatus: http.StatusOK,
+			wantTags:   map[string]string{"team": "a"},
+		},
+		{
+			name:       "dry run changes nothing",
+			params:     []string{"Action", "CreateTags", "DryRun", "true", "ResourceId.1", "eni-a", "Tag.1.Key", "team", "Tag.1.Value", "b"},
+			wantStatus: http.StatusPreconditionFailed,
+			wantCode:   "DryRunOperation",
+			wantTags:   map[string]string{"team": "a", "env": "dev"},
+		},
+		{
+			name:       "unknown ENI",
+			params:     []string{"Action", "CreateTags", "ResourceId.1", "eni-missing", "Tag.1.Key", "team", "Tag.1.Value", "b"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "InvalidNetworkInterfaceID.NotFound",
+			wantTags:   map[string]string{"team": "a", "env": "dev"},
+		},
+		{
+			name:       "no tags",
+			params:     []string{"Action", "CreateTags", "ResourceId.1", "eni-a"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "InvalidParameterValue",
+			wantTags:   map[string]string{"team": "a", "env": "dev"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			if err := s.Seed(&Fixture{ENIs: []FixtureENI{{
+				ENI:  ENI{ID: "eni-a", PrivateIP: "10.0.0.1", InterfaceType: "branch", SubnetID: "subnet-1"},
+				Tags: map[string]string{"team": "a", "env": "dev"},
+			}}}); err != nil {
+				t.Fatal(err)
+			}
+
+			rec := query(s, nil, tt.params...)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode != "" && !strings.Contains(rec.Body.String(), "<Code>"+tt.wantCode+"</Code>") {
+				t.Errorf("response lacks error code %s: %s", tt.wantCode, rec.Body)
+			}
+			tags, _ := s.Tags("eni-a")
+			assertTags(t, tags, tt.wantTags)
+		})
+	}
+}
+
+func TestQueryAPIRejectsBadRequests(t *testing.T) {
+	s := NewServer()
+	tests := []struct {
+		name       string
+		action     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"missing action", "", http.StatusBadRequest, "InvalidAction"},
+		{"unsupported action", "RunInstances", http.StatusBadRequest, "InvalidAction"},
+		{"account attributes", "DescribeAccountAttributes", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := query(s, nil, "Action", tt.action)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode != "" && !strings.Contains(rec.Body.String(), tt.wantCode) {
+				t.Errorf("response lacks %s: %s", tt.wantCode, rec.Body)
+			}
+		})
+	}
+
+	if rec := admin(s, http.MethodPut, "/", "", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT / status = %d, want 405", rec.Code)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	s := NewServer()
+	for _, ready := range []bool{false, true} {
+		s.SetReady(ready)
+		want := http.StatusOK
+		if !ready {
+			want = http.StatusServiceUnavailable
+		}
+		if rec := admin(s, http.MethodGet, "/readyz", "", nil); rec.Code != want {
+			t.Errorf("ready=%t: /readyz status = %d, want %d", ready, rec.Code, want)
+		}
+		if rec := admin(s, http.MethodGet, "/healthz", "", nil); rec.Code != http.StatusOK {
+			t.Errorf("ready=%t: /healthz status = %d, want 200", ready, rec.Code)
+		}
+	}
+}
+
+func assertTags(t *testing.T, got, want map[string]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("tags = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("tags = %v, want %v", got, want)
+		}
+	}
+}
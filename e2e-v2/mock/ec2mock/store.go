@@ -0,0 +1,393 @@
+package ec2mock
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ENI is a network interface held by the mock.
+type ENI struct {
+	ID            string `json:"eniId"`
+	PrivateIP     string `json:"privateIp"`
+	InterfaceType string `json:"interfaceType"`
+	SubnetID      string `json:"subnetId"`
+	Description   string `json:"description,omitempty"`
+	// SecondaryIPs are further private IPs of the ENI, such as the pod IPs of a
+	// node's primary ENI. Lookups by any of them find the ENI.
+	SecondaryIPs []string `json:"secondaryIps,omitempty"`
+	// InstanceID attaches the ENI to an instance. Empty means the ENI is
+	// available (unattached).
+	InstanceID  string            `json:"instanceId,omitempty"`
+	DeviceIndex int               `json:"deviceIndex,omitempty"`
+	Tags        map[string]string `json:"-"`
+}
+
+// PrivateIPs returns the primary private IP followed by the secondary ones.
+func (e ENI) PrivateIPs() []string {
+	return append([]string{e.PrivateIP}, e.SecondaryIPs...)
+}
+
+// AttachmentID returns the attachment ID EC2 would report for an attached ENI.
+func (e ENI) AttachmentID() string {
+	return "eni-attach-" + strings.TrimPrefix(e.ID, "eni-")
+}
+
+// Instance is an EC2 instance held by the mock. Its network interfaces are the
+// ENIs whose InstanceID points at it.
+type Instance struct {
+	ID           string            `json:"instanceId"`
+	InstanceType string            `json:"instanceType,omitempty"`
+	State        string            `json:"state,omitempty"`
+	SubnetID     string            `json:"subnetId"`
+	PrivateIP    string            `json:"privateIp"`
+	Tags         map[string]string `json:"tags,omitempty"`
+}
+
+// Subnet is a VPC subnet held by the mock.
+type Subnet struct {
+	ID               string            `json:"subnetId"`
+	VpcID            string            `json:"vpcId"`
+	CIDRBlock        string            `json:"cidrBlock"`
+	AvailabilityZone string            `json:"availabilityZone,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+}
+
+type eniStore struct {
+	mu        sync.RWMutex
+	enis      map[string]*ENI
+	ipIndex   map[string]string
+	instances map[string]*Instance
+	subnets   map[string]*Subnet
+}
+
+func newENIStore() *eniStore {
+	return &eniStore{
+		enis:      make(map[string]*ENI),
+		ipIndex:   make(map[string]string),
+		instances: make(map[string]*Instance),
+		subnets:   make(map[string]*Subnet),
+	}
+}
+
+// clear removes every ENI, instance, and subnet.
+func (s *eniStore) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.enis = make(map[string]*ENI)
+	s.ipIndex = make(map[string]string)
+	s.instances = make(map[string]*Instance)
+	s.subnets = make(map[string]*Subnet)
+}
+
+func (s *eniStore) upsert(eni ENI) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copy := eni
+	if copy.Tags == nil {
+		copy.Tags = make(map[string]string)
+	} else {
+		copy.Tags = cloneTags(copy.Tags)
+	}
+
+	if old, ok := s.enis[copy.ID]; ok {
+		s.unindexLocked(old)
+	}
+	s.enis[copy.ID] = &copy
+	s.indexLocked(&copy)
+}
+
+// indexLocked points the IP index at rec for each of its private IPs.
+func (s *eniStore) indexLocked(rec *ENI) {
+	for _, ip := range rec.PrivateIPs() {
+		s.ipIndex[ip] = rec.ID
+	}
+}
+
+// unindexLocked drops the private IPs of rec that still point at it.
+func (s *eniStore) unindexLocked(rec *ENI) {
+	for _, ip := range rec.PrivateIPs() {
+		if s.ipIndex[ip] == rec.ID {
+			delete(s.ipIndex, ip)
+		}
+	}
+}
+
+// detach clears the ENI's attachment, leaving it available.
+func (s *eniStore) detach(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.enis[id]
+	if !ok {
+		return fmt.Errorf("eni %s not found", id)
+	}
+	rec.InstanceID = ""
+	rec.DeviceIndex = 0
+	return nil
+}
+
+// remove deletes the ENI; later lookups answer NotFound.
+func (s *eniStore) remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.enis[id]
+	if !ok {
+		return fmt.Errorf("eni %s not found", id)
+	}
+	s.removeLocked(rec)
+	return nil
+}
+
+func (s *eniStore) removeLocked(rec *ENI) {
+	delete(s.enis, rec.ID)
+	s.unindexLocked(rec)
+}
+
+// reassignIP atomically deletes the ENI holding oldID's private IP and creates
+// next with that IP, the way a replaced branch or secondary ENI shows up to the
+// controller. Empty fields of next are copied from the old ENI, except tags and
+// attachment, which start fresh.
+func (s *eniStore) reassignIP(oldID string, next ENI) (*ENI, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.enis[oldID]
+	if !ok {
+		return nil, fmt.Errorf("eni %s not found", oldID)
+	}
+	if next.ID == "" || next.ID == oldID {
+		return nil, fmt.Errorf("a new eniId different from %s is required", oldID)
+	}
+	if _, exists := s.enis[next.ID]; exists {
+		return nil, fmt.Errorf("eni %s already exists", next.ID)
+	}
+
+	next.PrivateIP = old.PrivateIP
+	if next.SubnetID == "" {
+		next.SubnetID = old.SubnetID
+	}
+	if next.InterfaceType == "" {
+		next.InterfaceType = old.InterfaceType
+	}
+	if next.Description == "" {
+		next.Description = old.Description
+	}
+	next.Tags = cloneTags(next.Tags)
+	if next.Tags == nil {
+		next.Tags = make(map[string]string)
+	}
+
+	s.removeLocked(old)
+	s.enis[next.ID] = &next
+	s.indexLocked(&next)
+	return cloneENI(&next), nil
+}
+
+func (s *eniStore) byID(id string) (*ENI, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rec, ok := s.enis[id]
+	if !ok {
+		return nil, false
+	}
+	return cloneENI(rec), true
+}
+
+func (s *eniStore) byPrivateIP(ip string) (*ENI, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.ipIndex[ip]
+	if !ok {
+		return nil, false
+	}
+	rec, ok := s.enis[id]
+	if !ok {
+		return nil, false
+	}
+	return cloneENI(rec), true
+}
+
+// listENIs returns copies of all ENIs accepted by match, ordered by ID.
+func (s *eniStore) listENIs(match func(*ENI) bool) []*ENI {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*ENI
+	for _, rec := range s.enis {
+		if match(rec) {
+			out = append(out, cloneENI(rec))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+func (s *eniStore) upsertInstance(inst Instance) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copy := inst
+	copy.Tags = cloneTags(inst.Tags)
+	if copy.State == "" {
+		copy.State = "running"
+	}
+	s.instances[copy.ID] = &copy
+}
+
+// listInstances returns copies of all instances accepted by match, ordered by
+// ID, each with its attached ENIs ordered by device index.
+func (s *eniStore) listInstances(match func(*Instance, []*ENI) bool) []instanceView {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []instanceView
+	for _, inst := range s.instances {
+		var enis []*ENI
+		for _, rec := range s.enis {
+			if rec.InstanceID == inst.ID {
+				enis = append(enis, cloneENI(rec))
+			}
+		}
+		sort.Slice(enis, func(i, j int) bool { return enis[i].DeviceIndex < enis[j].DeviceIndex })
+		if !match(inst, enis) {
+			continue
+		}
+		copy := *inst
+		copy.Tags = cloneTags(inst.Tags)
+		out = append(out, instanceView{Instance: copy, ENIs: enis})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// instanceView is an instance together with its attached ENIs.
+type instanceView struct {
+	Instance
+	ENIs []*ENI
+}
+
+func (s *eniStore) upsertSubnet(subnet Subnet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copy := subnet
+	copy.Tags = cloneTags(subnet.Tags)
+	s.subnets[copy.ID] = &copy
+}
+
+// listSubnets returns copies of all subnets accepted by match, ordered by ID.
+func (s *eniStore) listSubnets(match func(*Subnet) bool) []*Subnet {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*Subnet
+	for _, rec := range s.subnets {
+		if match(rec) {
+			copy := *rec
+			copy.Tags = cloneTags(rec.Tags)
+			out = append(out, &copy)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// tagEntry is one tag on one resource, as reported by DescribeTags.
+type tagEntry struct {
+	ResourceID   string
+	ResourceType string
+	Key          string
+	Value        string
+}
+
+// listTags returns every tag on every ENI, instance, and subnet, ordered by
+// resource ID and then key.
+func (s *eniStore) listTags() []tagEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []tagEntry
+	add := func(id, resourceType string, tags map[string]string) {
+		for k, v := range tags {
+			out = append(out, tagEntry{ResourceID: id, ResourceType: resourceType, Key: k, Value: v})
+		}
+	}
+	for id, rec := range s.enis {
+		add(id, "network-interface", rec.Tags)
+	}
+	for id, rec := range s.instances {
+		add(id, "instance", rec.Tags)
+	}
+	for id, rec := range s.subnets {
+		add(id, "subnet", rec.Tags)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ResourceID != out[j].ResourceID {
+			return out[i].ResourceID < out[j].ResourceID
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+func (s *eniStore) mergeTags(id string, tags map[string]string) (*ENI, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.enis[id]
+	if !ok {
+		return nil, fmt.Errorf("eni %s not found", id)
+	}
+	if rec.Tags == nil {
+		rec.Tags = make(map[string]string)
+	}
+	for k, v := range tags {
+		rec.Tags[k] = v
+	}
+	return cloneENI(rec), nil
+}
+
+func (s *eniStore) deleteTags(id string, keys []string) (*ENI, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.enis[id]
+	if !ok {
+		return nil, fmt.Errorf("eni %s not found", id)
+	}
+	if len(rec.Tags) == 0 {
+		return cloneENI(rec), nil
+	}
+	for _, k := range keys {
+		delete(rec.Tags, k)
+	}
+	return cloneENI(rec), nil
+}
+
+func cloneENI(src *ENI) *ENI {
+	if src == nil {
+		return nil
+	}
+	copy := *src
+	copy.Tags = cloneTags(src.Tags)
+	copy.SecondaryIPs = append([]string(nil), src.SecondaryIPs...)
+	return &copy
+}
+
+func cloneTags(tags map[string]string) map[string]string {
+	if tags == nil {
+		return nil
+	}
+	dup := make(map[string]string, len(tags))
+	for k, v := range tags {
+		dup[k] = v
+	}
+	return dup
+}
@@ -0,0 +1,134 @@
+package ec2mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// subnetFilters maps the DescribeSubnets filter names the mock supports to the
+// subnet field each one matches. tag:<key> and tag-key filters are handled by
+// tagFilter.
+var subnetFilters = map[string]func(*Subnet) string{
+	"subnet-id":         func(s *Subnet) string { return s.ID },
+	"vpc-id":            func(s *Subnet) string { return s.VpcID },
+	"cidr-block":        func(s *Subnet) string { return s.CIDRBlock },
+	"availability-zone": func(s *Subnet) string { return s.AvailabilityZone },
+}
+
+// validateSubnet checks the fields required to answer DescribeSubnets.
+func validateSubnet(subnet Subnet) error {
+	if subnet.ID == "" || subnet.VpcID == "" || subnet.CIDRBlock == "" {
+		return fmt.Errorf("subnetId, vpcId, and cidrBlock are required")
+	}
+	return nil
+}
+
+func handleSeedSubnet(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	var req Subnet
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+
+	if err := validateSubnet(req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	store.upsertSubnet(req)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"status":  "ok",
+		"message": "subnet seeded",
+	})
+}
+
+func describeSubnets(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	filters := extractFilters(r.Form)
+	for name := range filters {
+		if _, ok := subnetFilters[name]; !ok && !isTagFilter(name) {
+			writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", fmt.Sprintf("The filter '%s' is invalid", name))
+			return
+		}
+	}
+	ids := extractIndexed(r.Form, "SubnetId")
+
+	subnets := store.listSubnets(func(s *Subnet) bool {
+		if len(ids) > 0 && !contains(ids, s.ID) {
+			return false
+		}
+		for name, values := range filters {
+			if field, ok := subnetFilters[name]; ok {
+				if !contains(values, field(s)) {
+					return false
+				}
+			} else if !tagFilter(name, values, s.Tags) {
+				return false
+			}
+		}
+		return true
+	})
+
+	for _, id := range ids {
+		if !containsSubnet(subnets, id) {
+			writeXMLError(w, http.StatusBadRequest, "InvalidSubnetID.NotFound", fmt.Sprintf("The subnet ID '%s' does not exist", id))
+			return
+		}
+	}
+
+	var items strings.Builder
+	for _, s := range subnets {
+		items.WriteString(fmt.Sprintf(`    <item>
+      <subnetId>%s</subnetId>
+      <vpcId>%s</vpcId>
+      <cidrBlock>%s</cidrBlock>
+      <availabilityZone>%s</availabilityZone>
+      <state>available</state>
+      <tagSet>
+%s      </tagSet>
+    </item>
+`, xmlEscape(s.ID), xmlEscape(s.VpcID), xmlEscape(s.CIDRBlock), xmlEscape(s.AvailabilityZone), buildTagSet(s.Tags)))
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	response := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<DescribeSubnetsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>%s</requestId>
+  <subnetSet>
+%s  </subnetSet>
+</DescribeSubnetsResponse>`, requestID(), items.String())
+	_, _ = w.Write([]byte(response))
+}
+
+// isTagFilter reports whether name is a tag:<key> or tag-key filter.
+func isTagFilter(name string) bool {
+	return name == "tag-key" || (strings.HasPrefix(name, "tag:") && len(name) > len("tag:"))
+}
+
+// tagFilter reports whether tags satisfy a tag:<key> filter (the key is present
+// with one of the values) or a tag-key filter (one of the keys is present).
+func tagFilter(name string, values []string, tags map[string]string) bool {
+	if name == "tag-key" {
+		for _, key := range values {
+			if _, ok := tags[key]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	value, ok := tags[strings.TrimPrefix(name, "tag:")]
+	return ok && contains(values, value)
+}
+
+func containsSubnet(subnets []*Subnet, id string) bool {
+	for _, s := range subnets {
+		if s.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package ec2mock
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDescribeSubnets(t *testing.T) {
+	s := NewServer()
+	if err := s.Seed(&Fixture{Subnets: []Subnet{
+		{ID: "subnet-1", VpcID: "vpc-1", CIDRBlock: "10.0.0.0/24", AvailabilityZone: "us-east-1a", Tags: map[string]string{"kubernetes.io/role/elb": "1", "tier": "public"}},
+		{ID: "subnet-2", VpcID: "vpc-1", CIDRBlock: "10.0.1.0/24", AvailabilityZone: "us-east-1b", Tags: map[string]string{"tier": "private"}},
+		{ID: "subnet-3", VpcID: "vpc-2", CIDRBlock: "10.1.0.0/24"},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		params     []string
+		wantStatus int
+		want       []string
+	}{
+		{"all", nil, http.StatusOK, []string{"subnet-1", "subnet-2", "subnet-3"}},
+		{"by ID", []string{"SubnetId.1", "subnet-2"}, http.StatusOK, []string{"subnet-2"}},
+		{"by VPC", []string{"Filter.1.Name", "vpc-id", "Filter.1.Value.1", "vpc-1"}, http.StatusOK, []string{"subnet-1", "subnet-2"}},
+		{"by tag value", []string{"Filter.1.Name", "tag:tier", "Filter.1.Value.1", "private"}, http.StatusOK, []string{"subnet-2"}},
+		{"tag values are ORed", []string{"Filter.1.Name", "tag:tier", "Filter.1.Value.1", "private", "Filter.1.Value.2", "public"}, http.StatusOK, []string{"subnet-1", "subnet-2"}},
+		{"by tag key", []string{"Filter.1.Name", "tag-key", "Filter.1.Value.1", "kubernetes.io/role/elb"}, http.StatusOK, []string{"subnet-1"}},
+		{"tag and field filters are ANDed", []string{"Filter.1.Name", "tag-key", "Filter.1.Value.1", "tier", "Filter.2.Name", "availability-zone", "Filter.2.Value.1", "us-east-1b"}, http.StatusOK, []string{"subnet-2"}},
+		{"no match", []string{"Filter.1.Name", "cidr-block", "Filter.1.Value.1", "192.168.0.0/24"}, http.StatusOK, nil},
+		{"unknown ID", []string{"SubnetId.1", "subnet-missing"}, http.StatusBadRequest, nil},
+		{"tag filter without key", []string{"Filter.1.Name", "tag:", "Filter.1.Value.1", "x"}, http.StatusBadRequest, nil},
+		{"unknown filter", []string{"Filter.1.Name", "state", "Filter.1.Value.1", "available"}, http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := query(s, nil, append([]string{"Action", "DescribeSubnets"}, tt.params...)...)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			for _, id := range []string{"subnet-1", "subnet-2", "subnet-3"} {
+				got := strings.Contains(rec.Body.String(), "<subnetId>"+id+"</subnetId>")
+				if want := contains(tt.want, id); got != want {
+					t.Errorf("%s returned = %t, want %t", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSeedSubnet(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"valid", `{"subnetId":"subnet-1","vpcId":"vpc-1","cidrBlock":"10.0.0.0/24","tags":{"tier":"public"}}`, http.StatusCreated},
+		{"missing VPC", `{"subnetId":"subnet-1","cidrBlock":"10.0.0.0/24"}`, http.StatusBadRequest},
+		{"bad JSON", `[]`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer()
+			rec := admin(s, http.MethodPost, "/admin/subnets", tt.body, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package ec2mock
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// tagFilters maps the DescribeTags filter names the mock supports to the tag
+// field each one matches.
+var tagFilters = map[string]func(tagEntry) string{
+	"resource-id":   func(t tagEntry) string { return t.ResourceID },
+	"resource-type": func(t tagEntry) string { return t.ResourceType },
+	"key":           func(t tagEntry) string { return t.Key },
+	"value":         func(t tagEntry) string { return t.Value },
+}
+
+// describeTags answers DescribeTags. NextToken is the offset of the next page,
+// which is enough for the SDK paginator.
+func describeTags(w http.ResponseWriter, r *http.Request, store *eniStore) {
+	filters := extractFilters(r.Form)
+	for name := range filters {
+		if _, ok := tagFilters[name]; !ok {
+			writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", fmt.Sprintf("The filter '%s' is invalid", name))
+			return
+		}
+	}
+
+	var matched []tagEntry
+	for _, t := range store.listTags() {
+		ok := true
+		for name, values := range filters {
+			if !contains(values, tagFilters[name](t)) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			matched = append(matched, t)
+		}
+	}
+
+	offset := 0
+	if token := r.Form.Get("NextToken"); token != "" {
+		n, err := strconv.Atoi(token)
+		if err != nil || n < 0 || n > len(matched) {
+			writeXMLError(w, http.StatusBadRequest, "InvalidNextToken", fmt.Sprintf("The token '%s' is invalid", token))
+			return
+		}
+		offset = n
+	}
+	end := len(matched)
+	if maxResults := r.Form.Get("MaxResults"); maxResults != "" {
+		n, err := strconv.Atoi(maxResults)
+		if err != nil || n < 1 {
+			writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", fmt.Sprintf("MaxResults must be a positive integer, got %q", maxResults))
+			return
+		}
+		if offset+n < end {
+			end = offset + n
+		}
+	}
+
+	var items strings.Builder
+	for _, t := range matched[offset:end] {
+		items.WriteString(fmt.Sprintf(`    <item>
+      <resourceId>%s</resourceId>
+      <resourceType>%s</resourceType>
+      <key>%s</key>
+      <value>%s</value>
+    </item>
+`, xmlEscape(t.ResourceID), xmlEscape(t.ResourceType), xmlEscape(t.Key), xmlEscape(t.Value)))
+	}
+	nextToken := ""
+	if end < len(matched) {
+		nextToken = fmt.Sprintf("\n  <nextToken>%d</nextToken>", end)
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	response := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<DescribeTagsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>%s</requestId>
+  <tagSet>
+%s  </tagSet>%s
+</DescribeTagsResponse>`, requestID(), items.String(), nextToken)
+	_, _ = w.Write([]byte(response))
+}
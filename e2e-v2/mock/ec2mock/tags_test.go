@@ -0,0 +1,183 @@
+package ec2mock
+
+import (
+	"encoding/xml"
+	"net/http"
+	"testing"
+)
+
+type describeTagsResponse struct {
+	Items []struct {
+		ResourceID   string `xml:"resourceId"`
+		ResourceType string `xml:"resourceType"`
+		Key          string `xml:"key"`
+		Value        string `xml:"value"`
+	} `xml:"tagSet>item"`
+	NextToken string `xml:"nextToken"`
+}
+
+func (r describeTagsResponse) pairs() []string {
+	var out []string
+	for _, item := range r.Items {
+		out = append(out, item.ResourceID+"/"+item.Key+"="+item.Value)
+	}
+	return out
+}
+
+func TestDescribeTags(t *testing.T) {
+	s := NewServer()
+	if err := s.Seed(&Fixture{
+		Subnets:   []Subnet{{ID: "subnet-1", VpcID: "vpc-1", CIDRBlock: "10.0.0.0/24", Tags: map[string]string{"team": "net"}}},
+		Instances: []Instance{{ID: "i-1", SubnetID: "subnet-1", PrivateIP: "10.0.0.10", Tags: map[string]string{"Name": "node"}}},
+		ENIs: []FixtureENI{
+			{ENI: ENI{ID: "eni-a", PrivateIP: "10.0.0.11", InterfaceType: "branch", SubnetID: "subnet-1"}, Tags: map[string]string{"team": "a", "env": "dev"}},
+			{ENI: ENI{ID: "eni-b", PrivateIP: "10.0.0.12", InterfaceType: "branch", SubnetID: "subnet-1"}, Tags: map[string]string{"team": "b"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		params        []string
+		wantStatus    int
+		wantCode      string
+		want          []string
+		wantNextToken string
+	}{
+		{
+			name:       "all, ordered by resource and key",
+			wantStatus: http.StatusOK,
+			want:       []string{"eni-a/env=dev", "eni-a/team=a", "eni-b/team=b", "i-1/Name=node", "subnet-1/team=net"},
+		},
+		{
+			name:       "by resource type",
+			params:     []string{"Filter.1.Name", "resource-type", "Filter.1.Value.1", "network-interface"},
+			wantStatus: http.StatusOK,
+			want:       []string{"eni-a/env=dev", "eni-a/team=a", "eni-b/team=b"},
+		},
+		{
+			name:       "key values are ORed",
+			params:     []string{"Filter.1.Name", "key", "Filter.1.Value.1", "env", "Filter.1.Value.2", "Name"},
+			wantStatus: http.StatusOK,
+			want:       []string{"eni-a/env=dev", "i-1/Name=node"},
+		},
+		{
+			name:       "filters are ANDed",
+			params:     []string{"Filter.1.Name", "key", "Filter.1.Value.1", "team", "Filter.2.Name", "resource-id", "Filter.2.Value.1", "eni-b", "Filter.2.Value.2", "subnet-1"},
+			wantStatus: http.StatusOK,
+			want:       []string{"eni-b/team=b", "subnet-1/team=net"},
+		},
+		{
+			name:       "by value",
+			params:     []string{"Filter.1.Name", "value", "Filter.1.Value.1", "a"},
+			wantStatus: http.StatusOK,
+			want:       []string{"eni-a/team=a"},
+		},
+		{
+			name:       "no match",
+			params:     []string{"Filter.1.Name", "key", "Filter.1.Value.1", "missing"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:          "first page",
+			params:        []string{"MaxResults", "2"},
+			wantStatus:    http.StatusOK,
+			want:          []string{"eni-a/env=dev", "eni-a/team=a"},
+			wantNextToken: "2",
+		},
+		{
+			name:       "last page",
+			params:     []string{"MaxResults", "2", "NextToken", "4"},
+			wantStatus: http.StatusOK,
+			want:       []string{"subnet-1/team=net"},
+		},
+		{
+			name:       "unknown filter",
+			params:     []string{"Filter.1.Name", "tag:team", "Filter.1.Value.1", "a"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "InvalidParameterValue",
+		},
+		{
+			name:       "invalid MaxResults",
+			params:     []string{"MaxResults", "0"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "InvalidParameterValue",
+		},
+		{
+			name:       "invalid NextToken",
+			params:     []string{"NextToken", "99"},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "InvalidNextToken",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := query(s, nil, append([]string{"Action", "DescribeTags"}, tt.params...)...)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode != "" {
+				var errResp struct {
+					Code string `xml:"Errors>Error>Code"`
+				}
+				if err := xml.Unmarshal(rec.Body.Bytes(), &errResp); err != nil || errResp.Code != tt.wantCode {
+					t.Errorf("error code = %q (%v), want %s", errResp.Code, err, tt.wantCode)
+				}
+				return
+			}
+
+			var resp describeTagsResponse
+			if err := xml.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v: %s", err, rec.Body)
+			}
+			got := resp.pairs()
+			if len(got) != len(tt.want) {
+				t.Fatalf("tags = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("tags = %v, want %v", got, tt.want)
+				}
+			}
+			if resp.NextToken != tt.wantNextToken {
+				t.Errorf("nextToken = %q, want %q", resp.NextToken, tt.wantNextToken)
+			}
+		})
+	}
+}
+
+func TestDescribeTagsPagesThroughEverything(t *testing.T) {
+	s := NewServer()
+	tags := map[string]string{}
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		tags[k] = k
+	}
+	if err := s.Seed(&Fixture{ENIs: []FixtureENI{{ENI: ENI{ID: "eni-a", PrivateIP: "10.0.0.11", InterfaceType: "branch", SubnetID: "subnet-1"}, Tags: tags}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]string{}
+	token := ""
+	for pages := 0; ; pages++ {
+		if pages > len(tags) {
+			t.Fatal("pagination does not terminate")
+		}
+		params := []string{"Action", "DescribeTags", "MaxResults", "3"}
+		if token != "" {
+			params = append(params, "NextToken", token)
+		}
+		var resp describeTagsResponse
+		if err := xml.Unmarshal(query(s, nil, params...).Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		for _, item := range resp.Items {
+			seen[item.Key] = item.Value
+		}
+		if resp.NextToken == "" {
+			break
+		}
+		token = resp.NextToken
+	}
+	assertTags(t, seen, tags)
+}
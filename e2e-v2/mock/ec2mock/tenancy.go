@@ -0,0 +1,85 @@
+package ec2mock
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// TestIDHeader selects the store a request reads and writes. Requests without
+// it share the default store.
+const TestIDHeader = "X-Test-Id"
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithAccessKeyTenancy makes requests without an X-Test-Id header use the
+// access key ID from their SigV4 Authorization header as the tenant, so each
+// controller under test can be isolated just by giving it its own
+// AWS_ACCESS_KEY_ID. Unsigned requests (curl, admin calls) still use the
+// default store unless they send X-Test-Id.
+func WithAccessKeyTenancy() Option {
+	return func(s *Server) {
+		s.tenants.accessKey = true
+	}
+}
+
+// tenants holds one store per test ID alongside the default store.
+type tenants struct {
+	mu        sync.Mutex
+	byID      map[string]*eniStore
+	fallback  *eniStore
+	accessKey bool
+}
+
+func newTenants() *tenants {
+	return &tenants{byID: make(map[string]*eniStore), fallback: newENIStore()}
+}
+
+// get returns the tenant's store, creating it on first use. The empty ID is
+// the default store.
+func (t *tenants) get(id string) *eniStore {
+	if id == "" {
+		return t.fallback
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	store, ok := t.byID[id]
+	if !ok {
+		store = newENIStore()
+		t.byID[id] = store
+	}
+	return store
+}
+
+// reset empties the tenant's store in place, so Tenant views stay valid.
+func (t *tenants) reset(id string) {
+	t.get(id).clear()
+}
+
+// forRequest returns the store selected by the request's tenant.
+func (t *tenants) forRequest(r *http.Request) *eniStore {
+	return t.get(t.requestTenant(r))
+}
+
+func (t *tenants) requestTenant(r *http.Request) string {
+	if id := strings.TrimSpace(r.Header.Get(TestIDHeader)); id != "" {
+		return id
+	}
+	if t.accessKey {
+		return accessKeyID(r.Header.Get("Authorization"))
+	}
+	return ""
+}
+
+// accessKeyID extracts the access key from a SigV4 Authorization header:
+// "AWS4-HMAC-SHA256 Credential=<key>/<date>/<region>/ec2/aws4_request, ...".
+func accessKeyID(authorization string) string {
+	_, rest, ok := strings.Cut(authorization, "Credential=")
+	if !ok {
+		return ""
+	}
+	key, _, _ := strings.Cut(rest, "/")
+	return strings.TrimSpace(key)
+}
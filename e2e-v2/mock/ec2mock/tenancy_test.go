@@ -0,0 +1,90 @@
+package ec2mock
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	eni := ENI{ID: "eni-a", PrivateIP: "10.0.0.1", InterfaceType: "branch", SubnetID: "subnet-1"}
+	signed := func(key string) http.Header {
+		return http.Header{"Authorization": {"AWS4-HMAC-SHA256 Credential=" + key + "/20240101/us-east-1/ec2/aws4_request, SignedHeaders=host, Signature=x"}}
+	}
+
+	tests := []struct {
+		name      string
+		opts      []Option
+		seedAs    string
+		header    http.Header
+		wantFound bool
+	}{
+		{"default store", nil, "", nil, true},
+		{"same test ID", nil, "test-1", http.Header{TestIDHeader: {"test-1"}}, true},
+		{"other test ID", nil, "test-1", http.Header{TestIDHeader: {"test-2"}}, false},
+		{"test ID does not see default store", nil, "", http.Header{TestIDHeader: {"test-1"}}, false},
+		{"default store does not see test ID", nil, "test-1", nil, false},
+		{"access key ignored without option", nil, "AKIA1", signed("AKIA1"), false},
+		{"access key tenancy", []Option{WithAccessKeyTenancy()}, "AKIA1", signed("AKIA1"), true},
+		{"other access key", []Option{WithAccessKeyTenancy()}, "AKIA1", signed("AKIA2"), false},
+		{"test ID wins over access key", []Option{WithAccessKeyTenancy()}, "test-1", http.Header{TestIDHeader: {"test-1"}, "Authorization": signed("AKIA1")["Authorization"]}, true},
+		{"unsigned request uses default store", []Option{WithAccessKeyTenancy()}, "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(tt.opts...)
+			mustSeedENI(t, s.Tenant(tt.seedAs), eni)
+
+			rec := query(s, tt.header, "Action", "DescribeNetworkInterfaces")
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+			}
+			if found := strings.Contains(rec.Body.String(), "eni-a"); found != tt.wantFound {
+				t.Errorf("found eni-a = %t, want %t", found, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestTenantReset(t *testing.T) {
+	s := NewServer()
+	eni := ENI{ID: "eni-a", PrivateIP: "10.0.0.1", InterfaceType: "branch", SubnetID: "subnet-1"}
+	tenant := s.Tenant("test-1")
+	mustSeedENI(t, tenant, eni)
+	mustSeedENI(t, s, eni)
+
+	rec := admin(s, http.MethodPost, "/admin/reset", "", http.Header{TestIDHeader: {"test-1"}})
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("reset status = %d", rec.Code)
+	}
+	if _, ok := tenant.Tags("eni-a"); ok {
+		t.Error("reset tenant still has eni-a")
+	}
+	if _, ok := s.Tags("eni-a"); !ok {
+		t.Error("reset of a tenant emptied the default store")
+	}
+
+	// The Tenant view stays valid after a reset
+	mustSeedENI(t, tenant, eni)
+	rec = query(s, http.Header{TestIDHeader: {"test-1"}}, "Action", "DescribeNetworkInterfaces")
+	if !strings.Contains(rec.Body.String(), "eni-a") {
+		t.Errorf("ENI seeded through the view after reset not served: %s", rec.Body)
+	}
+}
+
+func TestAccessKeyID(t *testing.T) {
+	tests := []struct {
+		authorization string
+		want          string
+	}{
+		{"AWS4-HMAC-SHA256 Credential=AKIA1/20240101/us-east-1/ec2/aws4_request, SignedHeaders=host", "AKIA1"},
+		{"AWS4-HMAC-SHA256 Credential=AKIA1", "AKIA1"},
+		{"", ""},
+		{"Bearer token", ""},
+	}
+	for _, tt := range tests {
+		if got := accessKeyID(tt.authorization); got != tt.want {
+			t.Errorf("accessKeyID(%q) = %q, want %q", tt.authorization, got, tt.want)
+		}
+	}
+}
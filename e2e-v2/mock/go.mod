@@ -1,3 +1,7 @@
 module github.com/prabhu-mannu/k8s-eni-tagger/e2e-v2/mock
 
 go 1.22
+
+require sigs.k8s.io/yaml v1.3.0
+
+require gopkg.in/yaml.v2 v2.4.0 // indirect
@@ -1,463 +1,180 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/tls"
+	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
-)
-
-type eniRecord struct {
-	ID            string            `json:"eniId"`
-	PrivateIP     string            `json:"privateIp"`
-	InterfaceType string            `json:"interfaceType"`
-	SubnetID      string            `json:"subnetId"`
-	Description   string            `json:"description,omitempty"`
-	Tags          map[string]string `json:"-"`
-}
-
-type eniStore struct {
-	mu      sync.RWMutex
-	enis    map[string]*eniRecord
-	ipIndex map[string]string
-}
-
-func newENIStore() *eniStore {
-	return &eniStore{
-		enis:    make(map[string]*eniRecord),
-		ipIndex: make(map[string]string),
-	}
-}
 
-func (s *eniStore) upsert(eni eniRecord) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	copy := eni
-	if copy.Tags == nil {
-		copy.Tags = make(map[string]string)
-	} else {
-		copy.Tags = cloneTags(copy.Tags)
-	}
-
-	s.enis[copy.ID] = &copy
-	s.ipIndex[copy.PrivateIP] = copy.ID
-}
-
-func (s *eniStore) byID(id string) (*eniRecord, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	rec, ok := s.enis[id]
-	if !ok {
-		return nil, false
-	}
-	return cloneENI(rec), true
-}
-
-func (s *eniStore) byPrivateIP(ip string) (*eniRecord, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	id, ok := s.ipIndex[ip]
-	if !ok {
-		return nil, false
-	}
-	rec, ok := s.enis[id]
-	if !ok {
-		return nil, false
-	}
-	return cloneENI(rec), true
-}
-
-func (s *eniStore) mergeTags(id string, tags map[string]string) (*eniRecord, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	rec, ok := s.enis[id]
-	if !ok {
-		return nil, fmt.Errorf("eni %s not found", id)
-	}
-	if rec.Tags == nil {
-		rec.Tags = make(map[string]string)
-	}
-	for k, v := range tags {
-		rec.Tags[k] = v
-	}
-	return cloneENI(rec), nil
-}
-
-func (s *eniStore) deleteTags(id string, keys []string) (*eniRecord, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	rec, ok := s.enis[id]
-	if !ok {
-		return nil, fmt.Errorf("eni %s not found", id)
-	}
-	if len(rec.Tags) == 0 {
-		return cloneENI(rec), nil
-	}
-	for _, k := range keys {
-		delete(rec.Tags, k)
-	}
-	return cloneENI(rec), nil
-}
-
-func cloneENI(src *eniRecord) *eniRecord {
-	if src == nil {
-		return nil
-	}
-	copy := *src
-	copy.Tags = cloneTags(src.Tags)
-	return &copy
-}
-
-func cloneTags(tags map[string]string) map[string]string {
-	if tags == nil {
-		return nil
-	}
-	dup := make(map[string]string, len(tags))
-	for k, v := range tags {
-		dup[k] = v
-	}
-	return dup
-}
+	"github.com/prabhu-mannu/k8s-eni-tagger/e2e-v2/mock/ec2mock"
+)
 
 func main() {
-	addr := ":" + envOrDefault("PORT", "4566")
-	store := newENIStore()
-
-	mux := http.NewServeMux()
-	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
-	mux.HandleFunc("/admin/enis", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			methodNotAllowed(w)
-			return
-		}
-		handleSeedENI(w, r, store)
-	})
-	mux.HandleFunc("/admin/tags/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodGet {
-			methodNotAllowed(w)
-			return
-		}
-		handleGetTags(w, r, store)
-	})
-	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		switch r.Method {
-		case http.MethodPost, http.MethodGet:
-			handleQueryAPI(w, r, store)
-		default:
-			methodNotAllowed(w)
-		}
-	})
-
-	srv := &http.Server{
-		Addr:    addr,
-		Handler: logRequests(mux),
-	}
+	seedFile := flag.String("seed-file", envOrDefault("SEED_FILE", ""), "JSON or YAML fixture of ENIs, instances, and subnets to seed at startup (env SEED_FILE)")
+	flag.Parse()
 
-	log.Printf("Starting AWS EC2 mock on %s", addr)
-	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("server error: %v", err)
-	}
-}
-
-func handleSeedENI(w http.ResponseWriter, r *http.Request, store *eniStore) {
-	var req eniRecord
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
-		return
-	}
-
-	if req.ID == "" || req.PrivateIP == "" || req.InterfaceType == "" || req.SubnetID == "" {
-		writeJSONError(w, http.StatusBadRequest, "eniId, privateIp, interfaceType, and subnetId are required")
-		return
-	}
-
-	store.upsert(req)
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	_ = json.NewEncoder(w).Encode(map[string]any{
-		"status":  "ok",
-		"message": "ENI seeded",
-	})
-}
-
-func handleGetTags(w http.ResponseWriter, r *http.Request, store *eniStore) {
-	eniID := strings.TrimPrefix(r.URL.Path, "/admin/tags/")
-	if eniID == "" {
-		writeJSONError(w, http.StatusBadRequest, "eniId is required")
-		return
-	}
+	addr := ":" + envOrDefault("PORT", "4566")
 
-	rec, ok := store.byID(eniID)
-	if !ok {
-		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("eni %s not found", eniID))
-		return
+	var opts []ec2mock.Option
+	tenancy, err := parseBoolEnv("ACCESS_KEY_TENANCY")
+	if err != nil {
+		log.Fatalf("config error: %v", err)
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(rec.Tags)
-}
-
-func handleQueryAPI(w http.ResponseWriter, r *http.Request, store *eniStore) {
-	if err := r.ParseForm(); err != nil {
-		writeXMLError(w, http.StatusBadRequest, "InvalidRequest", fmt.Sprintf("cannot parse request: %v", err))
-		return
+	if tenancy {
+		opts = append(opts, ec2mock.WithAccessKeyTenancy())
 	}
 
-	action := strings.Title(strings.ToLower(r.Form.Get("Action")))
-	switch action {
-	case "Describeaccountattributes":
-		describeAccountAttributes(w)
-	case "Describenetworkinterfaces":
-		describeNetworkInterfaces(w, r, store)
-	case "Createtags":
-		createTags(w, r, store)
-	case "Deletetags":
-		deleteTags(w, r, store)
-	case "":
-		writeXMLError(w, http.StatusBadRequest, "InvalidAction", "Action is required")
+	switch format := envOrDefault("LOG_FORMAT", "text"); format {
+	case "json":
+		opts = append(opts, ec2mock.WithAccessLog(slog.New(slog.NewJSONHandler(os.Stderr, nil))))
+	case "text":
+		opts = append(opts, ec2mock.WithAccessLog(slog.New(slog.NewTextHandler(os.Stderr, nil))))
 	default:
-		writeXMLError(w, http.StatusBadRequest, "InvalidAction", fmt.Sprintf("unsupported action %s", action))
-	}
-}
-
-func describeAccountAttributes(w http.ResponseWriter) {
-	reqID := requestID()
-	w.Header().Set("Content-Type", "text/xml")
-	response := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<DescribeAccountAttributesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
-  <requestId>%s</requestId>
-  <accountAttributeSet>
-    <item>
-      <attributeName>supported-platforms</attributeName>
-      <attributeValueSet>
-        <item>
-          <attributeValue>VPC</attributeValue>
-        </item>
-      </attributeValueSet>
-    </item>
-  </accountAttributeSet>
-</DescribeAccountAttributesResponse>`, reqID)
-	_, _ = w.Write([]byte(response))
-}
-
-func describeNetworkInterfaces(w http.ResponseWriter, r *http.Request, store *eniStore) {
-	privateIPs := extractFilterValues(r.Form, "private-ip-address")
-	if len(privateIPs) == 0 {
-		writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", "private-ip-address filter is required")
-		return
-	}
-
-	rec, ok := store.byPrivateIP(privateIPs[0])
-	if !ok {
-		writeXMLError(w, http.StatusBadRequest, "InvalidNetworkInterfaceID.NotFound", fmt.Sprintf("no ENI for IP %s", privateIPs[0]))
-		return
-	}
-
-	tagSet := buildTagSet(rec.Tags)
-	w.Header().Set("Content-Type", "text/xml")
-	response := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<DescribeNetworkInterfacesResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
-  <requestId>%s</requestId>
-  <networkInterfaceSet>
-    <item>
-      <networkInterfaceId>%s</networkInterfaceId>
-      <subnetId>%s</subnetId>
-      <description>%s</description>
-      <interfaceType>%s</interfaceType>
-      <privateIpAddressesSet>
-        <item>
-          <privateIpAddress>%s</privateIpAddress>
-        </item>
-      </privateIpAddressesSet>
-      <tagSet>
-%s      </tagSet>
-    </item>
-  </networkInterfaceSet>
-</DescribeNetworkInterfacesResponse>`, requestID(), xmlEscape(rec.ID), xmlEscape(rec.SubnetID), xmlEscape(rec.Description), xmlEscape(rec.InterfaceType), xmlEscape(rec.PrivateIP), tagSet)
-	_, _ = w.Write([]byte(response))
-}
-
-func createTags(w http.ResponseWriter, r *http.Request, store *eniStore) {
-	eniID := firstNonEmpty(r.Form.Get("ResourceId.1"), r.Form.Get("ResourceId.0"), r.Form.Get("ResourceId"))
-	if eniID == "" {
-		writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", "ResourceId.1 is required")
-		return
-	}
-	tags := extractTags(r.Form)
-	if len(tags) == 0 {
-		writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", "at least one Tag.n.Key is required")
-		return
-	}
-
-	if _, err := store.mergeTags(eniID, tags); err != nil {
-		writeXMLError(w, http.StatusBadRequest, "InvalidNetworkInterfaceID.NotFound", err.Error())
-		return
+		log.Fatalf("config error: LOG_FORMAT must be text or json, got %q", format)
 	}
 
-	writeSimpleResponse(w, "CreateTagsResponse", "CreateTagsResult")
-}
-
-func deleteTags(w http.ResponseWriter, r *http.Request, store *eniStore) {
-	eniID := firstNonEmpty(r.Form.Get("ResourceId.1"), r.Form.Get("ResourceId.0"), r.Form.Get("ResourceId"))
-	if eniID == "" {
-		writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", "ResourceId.1 is required")
-		return
+	tlsCfg, err := loadTLSSettings()
+	if err != nil {
+		log.Fatalf("config error: %v", err)
 	}
-	keys := extractTagKeys(r.Form)
-	if len(keys) == 0 {
-		writeXMLError(w, http.StatusBadRequest, "InvalidParameterValue", "at least one Tag.n.Key is required")
-		return
+	limits, err := loadServerLimits()
+	if err != nil {
+		log.Fatalf("config error: %v", err)
 	}
 
-	if _, err := store.deleteTags(eniID, keys); err != nil {
-		writeXMLError(w, http.StatusBadRequest, "InvalidNetworkInterfaceID.NotFound", err.Error())
-		return
+	mock := ec2mock.NewServer(opts...)
+	if modes := envOrDefault("EDGE_CASES", ""); modes != "" {
+		if err := mock.SetEdgeCases(ec2mock.EdgeCases{Modes: strings.Split(modes, ",")}); err != nil {
+			log.Fatalf("config error: EDGE_CASES: %v", err)
+		}
+		log.Printf("Edge-case responses enabled: %s", modes)
+	}
+	if *seedFile != "" {
+		// Seed after the listeners are up so /healthz passes during a long
+		// seed; /readyz holds orchestration back until it finishes.
+		mock.SetReady(false)
+		go func() {
+			f, err := mock.LoadFixtureFile(*seedFile)
+			if err != nil {
+				log.Fatalf("seed error: %v", err)
+			}
+			log.Printf("Seeded %d ENIs, %d instances, %d subnets from %s", len(f.ENIs), len(f.Instances), len(f.Subnets), *seedFile)
+			mock.SetReady(true)
+		}()
 	}
 
-	writeSimpleResponse(w, "DeleteTagsResponse", "DeleteTagsResult")
-}
+	mux := http.NewServeMux()
+	mux.Handle("/", mock)
+	var servers []*http.Server
 
-func extractFilterValues(values map[string][]string, filterName string) []string {
-	var results []string
-	for key, vals := range values {
-		if !strings.HasPrefix(strings.ToLower(key), "filter.") {
-			continue
+	if tlsCfg.enabled() {
+		cert, certPEM, err := tlsCfg.certificate()
+		if err != nil {
+			log.Fatalf("TLS certificate error: %v", err)
 		}
-
-		parts := strings.Split(key, ".")
-		if len(parts) < 3 {
-			continue
+		if tlsCfg.caOut != "" {
+			if err := os.WriteFile(tlsCfg.caOut, certPEM, 0o644); err != nil {
+				log.Fatalf("cannot write %s: %v", tlsCfg.caOut, err)
+			}
 		}
-		// filter.<index>.name or filter.<index>.value.<i>
-		if strings.EqualFold(parts[2], "name") && len(vals) > 0 && strings.EqualFold(vals[0], filterName) {
-			idx := parts[1]
-			// look for matching values
-			for valueKey, valueVals := range values {
-				if strings.HasPrefix(strings.ToLower(valueKey), fmt.Sprintf("filter.%s.value", idx)) {
-					results = append(results, valueVals...)
-				}
+		// Served over plain HTTP too, so orchestration can fetch the bundle
+		// before it trusts the HTTPS listener.
+		mux.HandleFunc("/admin/ca.pem", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/x-pem-file")
+			_, _ = w.Write(certPEM)
+		})
+
+		tlsSrv := limits.server(tlsCfg.addr, mux)
+		tlsSrv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		servers = append(servers, tlsSrv)
+		go func() {
+			log.Printf("Starting AWS EC2 mock (HTTPS) on %s", tlsCfg.addr)
+			if err := tlsSrv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("TLS server error: %v", err)
+			}
+		}()
+	}
+
+	srv := limits.server(addr, mux)
+	servers = append(servers, srv)
+
+	// Drain in-flight requests on SIGTERM (e.g. docker compose down) instead of
+	// cutting them off
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		log.Printf("Shutting down, waiting up to %s for in-flight requests", limits.shutdownTimeout)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), limits.shutdownTimeout)
+		defer cancel()
+		for _, s := range servers {
+			if err := s.Shutdown(shutdownCtx); err != nil {
+				log.Printf("shutdown error on %s: %v", s.Addr, err)
 			}
 		}
-	}
-	return results
-}
+	}()
 
-func extractTags(values map[string][]string) map[string]string {
-	tags := make(map[string]string)
-	for key, vals := range values {
-		if !strings.HasPrefix(strings.ToLower(key), "tag.") {
-			continue
-		}
-		parts := strings.Split(key, ".")
-		if len(parts) != 3 || !strings.EqualFold(parts[2], "key") {
-			continue
-		}
-		if len(vals) == 0 {
-			continue
-		}
-		idx := parts[1]
-		valueKey := fmt.Sprintf("Tag.%s.Value", idx)
-		value := first(values[valueKey])
-		tags[vals[0]] = value
+	log.Printf("Starting AWS EC2 mock on %s", addr)
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatalf("server error: %v", err)
 	}
-	return tags
-}
-
-func extractTagKeys(values map[string][]string) []string {
-	var keys []string
-	for key, vals := range values {
-		if !strings.HasPrefix(strings.ToLower(key), "tag.") {
-			continue
-		}
-		parts := strings.Split(key, ".")
-		if len(parts) != 3 || !strings.EqualFold(parts[2], "key") {
-			continue
+	<-shutdownDone
+}
+
+// serverLimits bounds how long clients may hold the mock's connections. There
+// is no write timeout, so latency edge cases can hold responses back.
+type serverLimits struct {
+	readHeaderTimeout time.Duration
+	readTimeout       time.Duration
+	idleTimeout       time.Duration
+	maxHeaderBytes    int
+	shutdownTimeout   time.Duration
+}
+
+func loadServerLimits() (serverLimits, error) {
+	var l serverLimits
+	var err error
+	for _, d := range []struct {
+		key string
+		def time.Duration
+		dst *time.Duration
+	}{
+		{"READ_HEADER_TIMEOUT", 10 * time.Second, &l.readHeaderTimeout},
+		{"READ_TIMEOUT", 30 * time.Second, &l.readTimeout},
+		{"IDLE_TIMEOUT", 90 * time.Second, &l.idleTimeout},
+		{"SHUTDOWN_TIMEOUT", 10 * time.Second, &l.shutdownTimeout},
+	} {
+		if *d.dst, err = parseDurationEnv(d.key, d.def); err != nil {
+			return l, err
 		}
-		keys = append(keys, first(vals))
 	}
-	return keys
-}
-
-func buildTagSet(tags map[string]string) string {
-	if len(tags) == 0 {
-		return ""
-	}
-	var b strings.Builder
-	for k, v := range tags {
-		b.WriteString("        <item>\n")
-		b.WriteString(fmt.Sprintf("          <key>%s</key>\n", xmlEscape(k)))
-		b.WriteString(fmt.Sprintf("          <value>%s</value>\n", xmlEscape(v)))
-		b.WriteString("        </item>\n")
-	}
-	return b.String()
-}
-
-func writeSimpleResponse(w http.ResponseWriter, envelope, result string) {
-	w.Header().Set("Content-Type", "text/xml")
-	response := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<%s xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
-  <requestId>%s</requestId>
-  <%s>
-    <return>true</return>
-  </%s>
-</%s>`, envelope, requestID(), result, result, envelope)
-	_, _ = w.Write([]byte(response))
-}
-
-func writeXMLError(w http.ResponseWriter, status int, code, message string) {
-	w.Header().Set("Content-Type", "text/xml")
-	w.WriteHeader(status)
-	errResp := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
-<Response>
-  <Errors>
-    <Error>
-      <Code>%s</Code>
-      <Message>%s</Message>
-    </Error>
-  </Errors>
-  <RequestID>%s</RequestID>
-</Response>`, xmlEscape(code), xmlEscape(message), requestID())
-	_, _ = w.Write([]byte(errResp))
-}
-
-func writeJSONError(w http.ResponseWriter, status int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
-}
-
-func methodNotAllowed(w http.ResponseWriter) {
-	w.WriteHeader(http.StatusMethodNotAllowed)
-}
-
-func first(values []string) string {
-	if len(values) == 0 {
-		return ""
+	v := envOrDefault("MAX_HEADER_BYTES", "65536")
+	if l.maxHeaderBytes, err = strconv.Atoi(v); err != nil || l.maxHeaderBytes < 1024 {
+		return l, fmt.Errorf("MAX_HEADER_BYTES: must be an integer of at least 1024, got %q", v)
 	}
-	return values[0]
+	return l, nil
 }
 
-func firstNonEmpty(values ...string) string {
-	for _, v := range values {
-		if strings.TrimSpace(v) != "" {
-			return v
-		}
+func (l serverLimits) server(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: l.readHeaderTimeout,
+		ReadTimeout:       l.readTimeout,
+		IdleTimeout:       l.idleTimeout,
+		MaxHeaderBytes:    l.maxHeaderBytes,
 	}
-	return ""
 }
 
 func envOrDefault(key, def string) string {
@@ -467,29 +184,22 @@ func envOrDefault(key, def string) string {
 	return def
 }
 
-func requestID() string {
-	return fmt.Sprintf("req-%d", time.Now().UnixNano())
-}
-
-func xmlEscape(s string) string {
-	replacer := strings.NewReplacer(
-		"&", "&amp;",
-		"<", "&lt;",
-		">", "&gt;",
-		"\"", "&quot;",
-		"'", "&apos;",
-	)
-	return replacer.Replace(s)
+// parseDurationEnv reads an optional positive duration environment variable.
+func parseDurationEnv(key string, def time.Duration) (time.Duration, error) {
+	v := envOrDefault(key, def.String())
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s: invalid positive duration %q", key, v)
+	}
+	return d, nil
 }
 
-func logRequests(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s (%s)", r.Method, r.URL.Path, r.URL.RawQuery, time.Since(start).Round(time.Millisecond))
-	})
+// parseBoolEnv reads an optional boolean environment variable.
+func parseBoolEnv(key string) (bool, error) {
+	v := envOrDefault(key, "false")
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s: invalid boolean %q", key, v)
+	}
+	return b, nil
 }
-
-// Ensure strconv is referenced for completeness where form parsing may need ints later.
-// Avoids accidental unused import removal when adding future fields.
-var _ = strconv.IntSize
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// tlsSettings describes the optional HTTPS listener.
+type tlsSettings struct {
+	addr       string
+	certFile   string
+	keyFile    string
+	selfSigned bool
+	hosts      []string
+	caOut      string
+}
+
+func (s tlsSettings) enabled() bool {
+	return s.selfSigned || s.certFile != ""
+}
+
+// loadTLSSettings reads TLS_* environment variables.
+func loadTLSSettings() (tlsSettings, error) {
+	s := tlsSettings{
+		addr:     ":" + envOrDefault("TLS_PORT", "4443"),
+		certFile: envOrDefault("TLS_CERT_FILE", ""),
+		keyFile:  envOrDefault("TLS_KEY_FILE", ""),
+		caOut:    envOrDefault("TLS_CA_OUT", ""),
+	}
+	for _, h := range strings.Split(envOrDefault("TLS_HOSTS", "localhost,127.0.0.1,aws-mock"), ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			s.hosts = append(s.hosts, h)
+		}
+	}
+	selfSigned, err := parseBoolEnv("TLS_SELF_SIGNED")
+	if err != nil {
+		return s, err
+	}
+	s.selfSigned = selfSigned
+
+	if (s.certFile == "") != (s.keyFile == "") {
+		return s, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if s.selfSigned && s.certFile != "" {
+		return s, fmt.Errorf("TLS_SELF_SIGNED cannot be combined with TLS_CERT_FILE")
+	}
+	return s, nil
+}
+
+// certificate returns the serving certificate and its PEM encoding, which
+// clients use as their CA bundle.
+func (s tlsSettings) certificate() (tls.Certificate, []byte, error) {
+	if !s.selfSigned {
+		certPEM, err := os.ReadFile(s.certFile)
+		if err != nil {
+			return tls.Certificate{}, nil, err
+		}
+		cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		return cert, certPEM, err
+	}
+	return selfSignedCertificate(s.hosts)
+}
+
+// selfSignedCertificate generates a CA-flagged certificate for hosts, so the
+// same PEM works as the server certificate and as the client's CA bundle.
+func selfSignedCertificate(hosts []string) (tls.Certificate, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 62))
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "aws-mock"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return tls.Certificate{}, nil, err
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	return cert, certPEM, err
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/config"
+	"k8s-eni-tagger/pkg/controller"
+	"k8s-eni-tagger/pkg/tagsource"
+
+	"github.com/go-logr/logr"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// inspectUsage is printed for a missing or malformed pod argument.
+const inspectUsage = "usage: k8s-eni-tagger inspect [-n namespace] [-o text|json] [controller flags] <pod>"
+
+// Exit codes of the inspect subcommand, as diff(1) uses them.
+const (
+	inspectInSync  = 0
+	inspectDiffers = 1
+	inspectFailed  = 2
+)
+
+// runInspect is the inspect subcommand: it resolves a pod's ENI and prints the
+// tags the controller would add to or remove from it, computed with the same
+// flags and environment as the controller. It reads the pod from the cluster
+// in the current kubeconfig context and the ENI from AWS, and writes nothing.
+func runInspect(ctx context.Context, stdout io.Writer) int {
+	namespace := pflag.StringP("namespace", "n", corev1.NamespaceDefault, "Namespace of the pod to inspect.")
+	output := pflag.StringP("output", "o", "text", "Output format: text or json.")
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		return inspectFailed
+	}
+	if pflag.NArg() != 1 || (*output != "text" && *output != "json") {
+		fmt.Fprintln(os.Stderr, inspectUsage)
+		return inspectFailed
+	}
+	key := types.NamespacedName{Namespace: *namespace, Name: pflag.Arg(0)}
+	if ns, name, ok := strings.Cut(pflag.Arg(0), "/"); ok {
+		key = types.NamespacedName{Namespace: ns, Name: name}
+	}
+	ctrl.SetLogger(logr.Discard())
+
+	r, err := newInspectReconciler(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return inspectFailed
+	}
+	pod := &corev1.Pod{}
+	if err := r.Get(ctx, key, pod); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to get pod %s: %v\n", key, err)
+		return inspectFailed
+	}
+	inspection := r.Inspect(ctx, pod)
+
+	if *output == "json" {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(inspection); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return inspectFailed
+		}
+	} else {
+		printInspection(stdout, inspection)
+	}
+	switch {
+	case inspection.ENIError != "":
+		return inspectFailed
+	case inspection.InSync():
+		return inspectInSync
+	default:
+		return inspectDiffers
+	}
+}
+
+// newInspectReconciler returns a reconciler computing tags as the controller
+// would with cfg, with a read-only AWS client and no ENI cache.
+func newInspectReconciler(ctx context.Context, cfg *config.Config) (*controller.PodReconciler, error) {
+	restConfig, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		return nil, err
+	}
+	k8sClient, err := client.New(restConfig, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	awsClient, err := aws.NewClientWithOptions(ctx, aws.ClientOptions{
+		RateLimit:    aws.RateLimitConfig{QPS: cfg.AWSRateLimitQPS, Burst: cfg.AWSRateLimitBurst},
+		DebugLogging: cfg.AWSDebugLogging,
+		EC2Endpoint:  cfg.AWSEC2Endpoint,
+		Profile:      cfg.AWSProfile,
+		AssumeRole: aws.AssumeRoleConfig{
+			RoleARN:     cfg.AWSAssumeRoleARN,
+			ExternalID:  cfg.AWSAssumeRoleExternalID,
+			SessionTags: cfg.AWSSessionTags,
+			ClusterName: cfg.ClusterName,
+		},
+		TagBackend: cfg.AWSTagBackend,
+		ReadOnly:   true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS client: %w", err)
+	}
+
+	excludeSelector, err := labels.Parse(cfg.ExcludePodSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exclude pod selector: %w", err)
+	}
+	var managedByTag string
+	if cfg.ManagedByTag {
+		managedByTag = controller.ManagedByTagValue(cfg.ClusterName)
+	}
+	// A nil *tagsource.Webhook must not become a non-nil controller.TagSource
+	var tagSource controller.TagSource
+	if cfg.TagSourceWebhookURL != "" {
+		webhook, err := tagsource.NewWebhook(cfg.TagSourceWebhookURL, cfg.TagSourceWebhookTimeout, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create tag source webhook: %w", err)
+		}
+		tagSource = webhook
+	}
+	tagMacros := tagValueMacros(cfg, awsClient)
+	var primaryENIs aws.PrimaryENIFinder
+	if cfg.HostNetworkENI == config.HostNetworkENIPrimary {
+		primaryENIs, _ = awsClient.(aws.PrimaryENIFinder)
+	}
+
+	return &controller.PodReconciler{
+		Client:                k8sClient,
+		Scheme:                scheme,
+		AWSClient:             awsClient,
+		AnnotationKey:         cfg.AnnotationKey,
+		SubnetIDs:             cfg.SubnetIDs,
+		AllowSharedENITagging: cfg.AllowSharedENITagging,
+		TagNamespace:          cfg.TagNamespace,
+		TagKeyCase:            controller.TagKeyCasePolicy(cfg.TagKeyCaseConflict),
+		TagKeyRenames:         cfg.TagKeyRenames,
+		TagFromLabels:         cfg.TagFromLabels,
+		TagValueTemplates:     controller.TagValueTemplateMode(cfg.TagValueTemplates),
+		TagMacros:             tagMacros,
+		HostNetworkENI:        controller.HostNetworkENIMode(cfg.HostNetworkENI),
+		PrimaryENIs:           primaryENIs,
+		ExcludePodSelector:    excludeSelector,
+		DebugPods:             controller.DebugPodPolicy(cfg.DebugPods),
+		KeyDomain:             cfg.KeyDomain,
+		ControllerID:          cfg.ControllerID,
+		ManagedByTag:          managedByTag,
+		TagSource:             tagSource,
+	}, nil
+}
+
+// printInspection writes inspection for people, one line per tag change.
+func printInspection(w io.Writer, i controller.Inspection) {
+	plan := i.Plan
+	fmt.Fprintf(w, "Pod:        %s\n", plan.Pod)
+	switch {
+	case i.ENIError != "":
+		fmt.Fprintf(w, "ENI:        unknown (%s)\n", i.ENIError)
+	default:
+		shared := ""
+		if i.Shared {
+			shared = ", shared"
+		}
+		fmt.Fprintf(w, "ENI:        %s (%s, %s%s)\n", i.ENIID, i.SubnetID, i.ENIClass, shared)
+	}
+	if i.Ineligible != "" {
+		fmt.Fprintf(w, "Not tagged: %s\n", i.Ineligible)
+	}
+	switch {
+	case plan.Skipped != "":
+		fmt.Fprintf(w, "Skipped:    %s\n", plan.Skipped)
+	case plan.Error != "":
+		fmt.Fprintf(w, "Invalid:    %s: %s\n", plan.Reason, plan.Error)
+	default:
+		fmt.Fprintf(w, "Desired:    %s\n", formatTags(plan.Tags))
+		fmt.Fprintf(w, "Bookkeeping: %s\n", formatTags(plan.Bookkeeping))
+	}
+	if i.ENIError != "" {
+		return
+	}
+	fmt.Fprintf(w, "On ENI:     %s\n", formatTags(i.ENITags))
+	if len(i.Foreign) > 0 {
+		fmt.Fprintf(w, "Foreign:    %s\n", strings.Join(i.Foreign, ", "))
+	}
+	if i.InSync() {
+		fmt.Fprintln(w, "In sync")
+		return
+	}
+	for _, k := range slices.Sorted(maps.Keys(i.ToAdd)) {
+		if current, ok := i.ENITags[k]; ok {
+			fmt.Fprintf(w, "~ %s: %s -> %s\n", k, current, i.ToAdd[k])
+		} else {
+			fmt.Fprintf(w, "+ %s=%s\n", k, i.ToAdd[k])
+		}
+	}
+	for _, k := range i.ToRemove {
+		fmt.Fprintf(w, "- %s=%s\n", k, i.ENITags[k])
+	}
+}
+
+// formatTags renders tags as sorted key=value pairs.
+func formatTags(tags map[string]string) string {
+	if len(tags) == 0 {
+		return "(none)"
+	}
+	pairs := make([]string, 0, len(tags))
+	for _, k := range slices.Sorted(maps.Keys(tags)) {
+		pairs = append(pairs, k+"="+tags[k])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// inspectTimeout bounds the inspect subcommand's Kubernetes and AWS calls.
+const inspectTimeout = time.Minute
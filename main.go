@@ -1,34 +1,50 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"net/http"
-	_ "net/http/pprof" // Register pprof handlers
+	"net/http/pprof"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
+	"k8s-eni-tagger/pkg/audit"
 	"k8s-eni-tagger/pkg/aws"
 	enicache "k8s-eni-tagger/pkg/cache"
 	"k8s-eni-tagger/pkg/config"
 	"k8s-eni-tagger/pkg/controller"
 	"k8s-eni-tagger/pkg/health"
+	"k8s-eni-tagger/pkg/httpserver"
+	"k8s-eni-tagger/pkg/metrics"
+	"k8s-eni-tagger/pkg/tagsource"
+	buildversion "k8s-eni-tagger/pkg/version"
+	podwebhook "k8s-eni-tagger/pkg/webhook"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 var (
 	scheme   = runtime.NewScheme()
 	setupLog = ctrl.Log.WithName("setup")
+	// errMissingPermissions is logged when the permission self-check fails
+	errMissingPermissions = errors.New("missing permissions")
 	// Version information set by ldflags
 	version = "dev"
 	commit  = "none"
@@ -59,18 +75,191 @@ func getControllerNamespace() string {
 	return "default"
 }
 
-func startPprof(addr string) {
-	if addr != "0" {
-		go func() {
-			setupLog.Info("Starting pprof server", "addr", addr)
-			if err := http.ListenAndServe(addr, nil); err != nil {
-				setupLog.Error(err, "Failed to start pprof server")
+// configMapKey resolves a ConfigMap flag such as --subnet-configmap ("name" or
+// "namespace/name"), defaulting the namespace to the controller's own.
+func configMapKey(ref string) types.NamespacedName {
+	if namespace, name, ok := strings.Cut(ref, "/"); ok {
+		return types.NamespacedName{Namespace: namespace, Name: name}
+	}
+	return types.NamespacedName{Namespace: getControllerNamespace(), Name: ref}
+}
+
+// pprofHandler serves the runtime profiles under /debug/pprof/.
+func pprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// adminHandler serves runtime admin endpoints, kept off the metrics port because
+// they are unauthenticated and mutate controller state. eniCache may be nil.
+func adminHandler(concurrency, plan, pause, eniCache http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/concurrency", concurrency)
+	mux.Handle("/plan", plan)
+	mux.Handle("/pause", pause)
+	if eniCache != nil {
+		mux.Handle("/eni-cache", eniCache)
+	}
+	return mux
+}
+
+// addAuxServer runs an auxiliary listener with the manager, on every replica.
+// An address of "0" disables it.
+func addAuxServer(mgr ctrl.Manager, opts httpserver.Options, name, addr string, handler http.Handler) error {
+	if addr == "0" {
+		return nil
+	}
+	return mgr.Add(&httpserver.Server{
+		Name:    name,
+		Addr:    addr,
+		Handler: handler,
+		Options: opts,
+		Log:     ctrl.Log.WithName("http"),
+	})
+}
+
+// addAWSHealthCheck attaches the AWS connectivity check to the probe selected by mode.
+func addAWSHealthCheck(probes *health.Probes, mode string, check healthz.Checker) error {
+	switch mode {
+	case config.AWSHealthProbeReadyz:
+		return probes.AddReadyzCheck("aws", check)
+	case config.AWSHealthProbeHealthz:
+		return probes.AddHealthzCheck("aws", check)
+	case config.AWSHealthProbeNone:
+		setupLog.Info("AWS health check disabled")
+		return nil
+	default:
+		return fmt.Errorf("unknown aws health probe mode %q", mode)
+	}
+}
+
+// verifyPermissions checks every Kubernetes permission and EC2 action the enabled
+// features use, logs a single summary and exits if a required one is missing.
+// Checks that could not be completed (e.g. network errors) are reported as
+// unverified and do not stop startup.
+func verifyPermissions(ctx context.Context, cfg *config.Config, c client.Client, awsClient aws.Client, healthAPI aws.HealthPermissionAPI, subnetConfigMap, pauseConfigMap, defaultTagsConfigMap types.NamespacedName) {
+	checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var missing, missingOptional, unverified []string
+	rbacChecks := controller.CheckRBAC(checkCtx, c, controller.RBACRequirements(controller.RBACOptions{
+		WatchNamespace:        cfg.WatchNamespace,
+		ControllerNamespace:   getControllerNamespace(),
+		LeaderElection:        cfg.EnableLeaderElection,
+		StateStore:            cfg.StateStore == config.StateStoreConfigMap,
+		CacheConfigMap:        cfg.EnableENICache && cfg.EnableCacheConfigMap,
+		SubnetConfigMap:       subnetConfigMap,
+		PauseConfigMap:        pauseConfigMap,
+		DefaultTagsConfigMap:  defaultTagsConfigMap,
+		NodeTemplates:         cfg.TagValueTemplates == config.TagValueTemplatesNode,
+		ServiceTagging:        cfg.EnableServiceTagging,
+		HostNetworkPrimaryENI: cfg.HostNetworkENI == config.HostNetworkENIPrimary,
+		NodeVolumes:           cfg.TagNodeVolumes,
+		ReadOnly:              cfg.ReadOnly,
+	}))
+	checked := len(rbacChecks)
+	for _, check := range rbacChecks {
+		switch {
+		case check.Err != nil:
+			unverified = append(unverified, check.Err.Error())
+		case check.Allowed:
+		case check.Optional:
+			missingOptional = append(missingOptional, fmt.Sprintf("%s (%s)", check, check.Purpose))
+		default:
+			missing = append(missing, fmt.Sprintf("%s (%s)", check, check.Purpose))
+		}
+	}
+
+	// DescribeAccountAttributes is only called by the health check
+	if cfg.AWSHealthProbe == config.AWSHealthProbeNone {
+		healthAPI = nil
+	}
+	if ec2Client := awsClient.GetEC2Client(); ec2Client != nil {
+		// Tagging is not needed in dry-run and read-only modes
+		tagging := !cfg.DryRun && !cfg.ReadOnly
+		awsOpts := aws.PermissionOptions{
+			Tagging:        tagging,
+			NodeVolumes:    cfg.TagNodeVolumes,
+			SecurityGroups: cfg.TagSecurityGroups,
+			TaggingAPI:     tagging && cfg.AWSTagBackend == config.AWSTagBackendResourceGroupsTagging,
+			S3:             cfg.OwnershipReportS3Bucket != "",
+			AssumeRole:     cfg.AWSAssumeRoleARN != "",
+			SessionTags:    cfg.AWSAssumeRoleARN != "" && cfg.AWSSessionTags,
+		}
+		// With a role the account is read from its ARN, and STS is never called
+		if finder, ok := awsClient.(aws.IdentityFinder); ok && cfg.AWSAssumeRoleARN == "" {
+			awsOpts.Identity = finder
+		}
+		awsChecks := aws.CheckPermissions(checkCtx, ec2Client, healthAPI, awsOpts)
+		checked += len(awsChecks)
+		for _, check := range awsChecks {
+			switch {
+			case check.Allowed:
+			case check.Denied:
+				missing = append(missing, check.Action)
+			default:
+				unverified = append(unverified, check.Err.Error())
 			}
-		}()
+		}
+	}
+
+	summary := []any{"checked", checked, "missing", missing, "missingOptional", missingOptional, "unverified", unverified}
+	if len(missing) > 0 {
+		setupLog.Error(errMissingPermissions, "Permission self-check failed; grant the missing permissions (see the chart's RBAC templates and iam-policy.json) or disable the check with --verify-permissions=false", summary...)
+		os.Exit(1)
+	}
+	setupLog.Info("Permission self-check passed", summary...)
+}
+
+// diagnoseAWSCredentials logs how credentials and region were resolved and exits
+// on misconfiguration (e.g. broken IRSA). Other failures are logged and startup
+// continues, since they may be transient.
+func diagnoseAWSCredentials(ctx context.Context, profile string) {
+	checkCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	diag, err := aws.DiagnoseCredentials(checkCtx, profile)
+	setupLog.Info("AWS credentials",
+		"provider", diag.Provider, "profile", diag.Profile, "chain", diag.Chain,
+		"region", diag.Region, "regionSource", diag.RegionSource, "partition", diag.Partition,
+		"roleARN", diag.RoleARN, "tokenSubject", diag.TokenSubject, "stsEndpoint", diag.STSEndpoint.String())
+	for _, w := range diag.Warnings {
+		setupLog.Info("WARNING: " + w)
+	}
+	if err != nil {
+		if errors.Is(err, aws.ErrCredentialsMisconfigured) {
+			setupLog.Error(err, "AWS credential self-check failed")
+			os.Exit(1)
+		}
+		setupLog.Error(err, "Unable to verify AWS credentials, continuing")
 	}
 }
 
+// tagValueMacros returns the values of the $cluster, $region and $account tag
+// value macros. The account is only looked up, with an STS call unless the
+// assumed role's ARN names it, when a tag first uses $account.
+func tagValueMacros(cfg *config.Config, awsClient aws.Client) controller.TagValueMacros {
+	macros := controller.TagValueMacros{Cluster: cfg.ClusterName}
+	if finder, ok := awsClient.(aws.IdentityFinder); ok {
+		macros.Region = finder.Region()
+		macros.AccountLookup = controller.NewAccountLookup(finder.AccountID)
+	}
+	return macros
+}
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "inspect" {
+		os.Args = append(os.Args[:1:1], os.Args[2:]...)
+		ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+		code := runInspect(ctx, os.Stdout)
+		cancel()
+		os.Exit(code)
+	}
+
 	opts := zap.Options{
 		Development: true,
 	}
@@ -85,7 +274,17 @@ func main() {
 
 	if cfg.PrintVersion {
 		// Use fmt.Printf for version info when requested directly
-		fmt.Printf("k8s-eni-tagger version=%s commit=%s date=%s\n", version, commit, date)
+		info := buildversion.Get(version, commit, date, cfg.EnabledFeatures())
+		if cfg.VersionFormat == config.VersionFormatJSON {
+			data, err := info.JSON()
+			if err != nil {
+				fmt.Printf("Error encoding version information: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+		} else {
+			fmt.Println(info.String())
+		}
 		os.Exit(0)
 	}
 
@@ -97,19 +296,173 @@ func main() {
 		setupLog.Info("Subnet filtering enabled", "subnets", cfg.SubnetIDs)
 	}
 
+	if len(cfg.TagKeyRenames) > 0 {
+		if err := controller.ValidateTagKeyRenames(cfg.TagKeyRenames); err != nil {
+			setupLog.Error(err, "invalid --tag-key-renames target")
+			os.Exit(1)
+		}
+		setupLog.Info("Tag key renames enabled", "renames", cfg.TagKeyRenames)
+	}
+
+	if len(cfg.TagFromLabels) > 0 {
+		if err := controller.ValidateTagFromLabels(cfg.TagFromLabels); err != nil {
+			setupLog.Error(err, "invalid --tag-from-labels tag key")
+			os.Exit(1)
+		}
+		setupLog.Info("Tags from pod labels enabled", "labels", cfg.TagFromLabels)
+	}
+
+	if len(cfg.CriticalTagKeys) > 0 {
+		setupLog.Info("Tags outside the critical keys are best-effort", "criticalTagKeys", cfg.CriticalTagKeys)
+	}
+
 	if cfg.AllowSharedENITagging {
 		setupLog.Info("WARNING: Shared ENI tagging is enabled. This may cause tag thrashing on standard EKS nodes.")
 	}
 
-	// Start pprof server
-	startPprof(cfg.PprofBindAddress)
+	ctx := ctrl.SetupSignalHandler()
+
+	// Create AWS client with rate limiting
+	rlConfig := aws.RateLimitConfig{
+		QPS:              cfg.AWSRateLimitQPS,
+		Burst:            cfg.AWSRateLimitBurst,
+		NamespaceBudgets: cfg.AWSNamespaceBudgets,
+	}
+	diagnoseAWSCredentials(ctx, cfg.AWSProfile)
 
+	ec2Endpoint, err := aws.ResolveEndpoint("EC2", cfg.AWSEC2Endpoint)
+	if err != nil {
+		setupLog.Error(err, "invalid AWS endpoint configuration")
+		os.Exit(1)
+	}
+	setupLog.Info("AWS EC2 endpoint", "endpoint", ec2Endpoint.String(), "source", ec2Endpoint.Source)
+
+	mutationBudget, err := aws.NewMutationBudget(cfg.AWSMutationBudget, cfg.AWSMutationBudgetWindow)
+	if err != nil {
+		setupLog.Error(err, "invalid AWS mutation budget")
+		os.Exit(1)
+	}
+	auditLog, err := audit.Open(cfg.AuditLogPath)
+	if err != nil {
+		setupLog.Error(err, "unable to open audit log", "path", cfg.AuditLogPath)
+		os.Exit(1)
+	}
+	defer func() {
+		if err := auditLog.Close(); err != nil {
+			setupLog.Error(err, "failed to close audit log")
+		}
+	}()
+	awsClient, err := aws.NewClientWithOptions(ctx, aws.ClientOptions{
+		RateLimit:    rlConfig,
+		DebugLogging: cfg.AWSDebugLogging,
+		Faults:       aws.FaultInjection{ErrorRate: cfg.FaultAWSErrorRate},
+		EC2Endpoint:  cfg.AWSEC2Endpoint,
+		Profile:      cfg.AWSProfile,
+		AssumeRole: aws.AssumeRoleConfig{
+			RoleARN:     cfg.AWSAssumeRoleARN,
+			ExternalID:  cfg.AWSAssumeRoleExternalID,
+			SessionTags: cfg.AWSSessionTags,
+			ClusterName: cfg.ClusterName,
+		},
+		MutationBudget: mutationBudget,
+		AuditLog:       auditLog,
+		TagBackend:     cfg.AWSTagBackend,
+		ReadOnly:       cfg.ReadOnly,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to create AWS client")
+		os.Exit(1)
+	}
+	setupLog.Info("AWS client initialized with rate limiting", "qps", cfg.AWSRateLimitQPS, "burst", cfg.AWSRateLimitBurst)
+	if len(cfg.AWSNamespaceBudgets) > 0 {
+		setupLog.Info("Per-namespace AWS rate limit budgets enabled", "budgets", cfg.AWSNamespaceBudgets)
+	}
+	if auditLog != nil {
+		setupLog.Info("Tag change audit log enabled", "path", cfg.AuditLogPath)
+	}
+	if mutationBudget != nil {
+		setupLog.Info("AWS mutation budget enabled", "calls", cfg.AWSMutationBudget, "window", cfg.AWSMutationBudgetWindow)
+	}
+	if cfg.AWSDebugLogging {
+		setupLog.Info("AWS request debug logging enabled; every EC2 call is logged")
+	}
+	if cfg.FaultAWSErrorRate > 0 || cfg.FaultCacheCorruptionRate > 0 || cfg.FaultCacheConflictRate > 0 {
+		setupLog.Info("FAULT INJECTION ENABLED: failures are induced on purpose, do not use in production",
+			"awsErrorRate", cfg.FaultAWSErrorRate, "cacheCorruptionRate", cfg.FaultCacheCorruptionRate, "cacheConflictRate", cfg.FaultCacheConflictRate)
+	}
+	if cfg.AWSAssumeRoleARN != "" {
+		setupLog.Info("Assuming IAM role for EC2 calls", "roleARN", cfg.AWSAssumeRoleARN, "sessionTags", cfg.AWSSessionTags, "cluster", cfg.ClusterName)
+	}
+
+	// AWS connectivity check. By default it gates readiness only, so an AWS outage
+	// takes the controller out of service without restarting it; liveness keeps
+	// reflecting the process itself.
+	ec2HealthClient := &health.EC2HealthClient{EC2: awsClient.GetEC2Client()}
+	if cfg.AWSHealthProfile != "" || cfg.AWSHealthRoleARN != "" {
+		healthProfile := cfg.AWSHealthProfile
+		if healthProfile == "" {
+			healthProfile = cfg.AWSProfile
+		}
+		ec2HealthClient.EC2, err = aws.NewHealthEC2Client(ctx, aws.HealthClientOptions{
+			Profile:     healthProfile,
+			RoleARN:     cfg.AWSHealthRoleARN,
+			EC2Endpoint: cfg.AWSEC2Endpoint,
+		})
+		if err != nil {
+			setupLog.Error(err, "unable to create AWS health check client")
+			os.Exit(1)
+		}
+		setupLog.Info("AWS health checks use separate credentials", "profile", healthProfile, "roleARN", cfg.AWSHealthRoleARN)
+	}
+	if err := ec2HealthClient.Validate(); err != nil {
+		setupLog.Error(err, "unable to initialize EC2 health client")
+		os.Exit(1)
+	}
+	awsChecker := health.NewAWSChecker(ec2HealthClient)
+	awsChecker.SetMetrics(metrics.AWSHealthMetrics{})
+
+	// Exemplars are only carried by the OpenMetrics format, which controller-runtime's
+	// /metrics handler does not negotiate
+	metricsHandlers := map[string]http.Handler{"/aws-health": awsChecker}
+	if cfg.MetricsExemplars {
+		metrics.EnableExemplars(true)
+		metricsHandlers[metrics.OpenMetricsPath] = metrics.OpenMetricsHandler()
+		setupLog.Info("AWS latency exemplars enabled", "path", metrics.OpenMetricsPath)
+	}
+
+	// Scoped by key domain so independent installations never share a lease
+	leaderElectionID := "k8s-eni-tagger." + cfg.KeyDomain
 	mgrOptions := ctrl.Options{
-		Scheme:                 scheme,
-		Metrics:                server.Options{BindAddress: cfg.MetricsBindAddress},
-		HealthProbeBindAddress: cfg.HealthProbeBindAddress,
-		LeaderElection:         cfg.EnableLeaderElection,
-		LeaderElectionID:       "k8s-eni-tagger.eni-tagger.io",
+		Scheme: scheme,
+		Metrics: server.Options{
+			BindAddress: cfg.MetricsBindAddress,
+			// Last AWS health check result as JSON, for dashboards and debugging
+			ExtraHandlers: metricsHandlers,
+		},
+		LeaderElection:   cfg.EnableLeaderElection,
+		LeaderElectionID: leaderElectionID,
+		// The namespace ENI cache ownership reads the lease from
+		LeaderElectionNamespace: getControllerNamespace(),
+	}
+
+	if cfg.EnableAdmissionWebhook {
+		mgrOptions.WebhookServer = webhook.NewServer(webhook.Options{
+			Port:    cfg.WebhookPort,
+			CertDir: cfg.WebhookCertDir,
+		})
+	}
+
+	var subnetConfigMap types.NamespacedName
+	if cfg.SubnetConfigMap != "" {
+		subnetConfigMap = configMapKey(cfg.SubnetConfigMap)
+	}
+	var pauseConfigMap types.NamespacedName
+	if cfg.PauseConfigMap != "" {
+		pauseConfigMap = configMapKey(cfg.PauseConfigMap)
+	}
+	var defaultTagsConfigMap types.NamespacedName
+	if cfg.DefaultTagsConfigMap != "" {
+		defaultTagsConfigMap = configMapKey(cfg.DefaultTagsConfigMap)
 	}
 
 	if cfg.WatchNamespace != "" {
@@ -118,6 +471,18 @@ func main() {
 				cfg.WatchNamespace: {},
 			},
 		}
+		// The subnet, pause and default tags ConfigMaps may live outside the watched namespace
+		configMapNamespaces := map[string]cache.Config{cfg.WatchNamespace: {}}
+		for _, cm := range []types.NamespacedName{subnetConfigMap, pauseConfigMap, defaultTagsConfigMap} {
+			if cm.Name != "" {
+				configMapNamespaces[cm.Namespace] = cache.Config{}
+			}
+		}
+		if len(configMapNamespaces) > 1 {
+			mgrOptions.Cache.ByObject = map[client.Object]cache.ByObject{
+				&corev1.ConfigMap{}: {Namespaces: configMapNamespaces},
+			}
+		}
 	}
 
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOptions)
@@ -126,58 +491,282 @@ func main() {
 		os.Exit(1)
 	}
 
-	ctx := ctrl.SetupSignalHandler()
+	// Report every missing RBAC permission and IAM action at once, before any of
+	// them fails a reconcile
+	if cfg.VerifyPermissions {
+		verifyPermissions(ctx, cfg, mgr.GetClient(), awsClient, ec2HealthClient.EC2, subnetConfigMap, pauseConfigMap, defaultTagsConfigMap)
+	}
 
-	// Create AWS client with rate limiting
-	rlConfig := aws.RateLimitConfig{
-		QPS:   cfg.AWSRateLimitQPS,
-		Burst: cfg.AWSRateLimitBurst,
+	// Health probes, pprof and the admin endpoint share hardened listener settings
+	auxServerOptions := httpserver.Options{
+		ReadHeaderTimeout: cfg.AuxServerReadHeaderTimeout,
+		ReadTimeout:       cfg.AuxServerReadTimeout,
+		IdleTimeout:       cfg.AuxServerIdleTimeout,
+		MaxHeaderBytes:    cfg.AuxServerMaxHeaderBytes,
+		ShutdownTimeout:   cfg.AuxServerShutdownTimeout,
 	}
-	awsClient, err := aws.NewClientWithRateLimiter(ctx, rlConfig)
-	if err != nil {
-		setupLog.Error(err, "unable to create AWS client")
+	if err := addAuxServer(mgr, auxServerOptions, "pprof", cfg.PprofBindAddress, pprofHandler()); err != nil {
+		setupLog.Error(err, "unable to add pprof server")
 		os.Exit(1)
 	}
-	setupLog.Info("AWS client initialized with rate limiting", "qps", cfg.AWSRateLimitQPS, "burst", cfg.AWSRateLimitBurst)
 
-	// Add AWS connectivity check for startup validation only
-	// This runs once at startup to verify AWS permissions
-	// Moving from readyz to healthz prevents continuous AWS API calls from readiness probes
-	ec2HealthClient := &health.EC2HealthClient{EC2: awsClient.GetEC2Client()}
-	if err := ec2HealthClient.Validate(); err != nil {
-		setupLog.Error(err, "unable to initialize EC2 health client")
-		os.Exit(1)
-	}
-	awsChecker := health.NewAWSChecker(ec2HealthClient)
-	// Configure latch threshold from config (validated to be >= 0)
-	// 0 = disable latching, positive = latch after N successes
-	awsChecker.SetMaxSuccesses(cfg.AWSHealthMaxSuccesses)
-	if err := mgr.AddHealthzCheck("aws", awsChecker.Check); err != nil {
+	probes := health.NewProbes()
+	if err := addAWSHealthCheck(probes, cfg.AWSHealthProbe, awsChecker.Check); err != nil {
 		setupLog.Error(err, "unable to add AWS health check")
 		os.Exit(1)
 	}
+	// Probes only read the cached result; the AWS call runs on its own interval
+	if cfg.AWSHealthProbe != config.AWSHealthProbeNone {
+		awsChecker.Start(ctx, cfg.AWSHealthCheckInterval)
+	}
 
 	// Initialize ENI cache if enabled
 	var eniCache *enicache.ENICache
+	var standbyWarmer *enicache.StandbyWarmer
 	if cfg.EnableENICache {
 		eniCache = enicache.NewENICache(awsClient)
 		// Apply batch settings before enabling persistence
 		eniCache.SetBatchConfig(cfg.CacheBatchInterval, cfg.CacheBatchSize)
+		if cfg.ENICacheIPCheck {
+			if err := eniCache.EnableIPCheck(); err != nil {
+				setupLog.Error(err, "unable to enable ENI cache IP check")
+				os.Exit(1)
+			}
+			setupLog.Info("ENI cache hits are checked against the pod IP")
+		}
+		if cfg.ENICacheResyncInterval > 0 {
+			if err := mgr.Add(&enicache.Resyncer{Cache: eniCache, Interval: cfg.ENICacheResyncInterval}); err != nil {
+				setupLog.Error(err, "unable to add ENI cache resync")
+				os.Exit(1)
+			}
+			setupLog.Info("ENI cache resync enabled", "interval", cfg.ENICacheResyncInterval)
+		}
 
 		// Add ConfigMap persistence if enabled
 		if cfg.EnableCacheConfigMap {
 			namespace := getControllerNamespace()
-			cmPersister := enicache.NewConfigMapPersister(mgr.GetClient(), namespace)
+			cmPersister := enicache.NewConfigMapPersisterWithFaults(mgr.GetClient(), mgr.GetAPIReader(), namespace, enicache.FaultInjection{
+				CorruptionRate: cfg.FaultCacheCorruptionRate,
+				ConflictRate:   cfg.FaultCacheConflictRate,
+			})
 			eniCache.WithConfigMapPersister(cmPersister)
+			if cfg.EnableLeaderElection {
+				// Only the leader writes the ConfigMap, fenced by its lease term
+				eniCache.SetOwner(nil)
+				if err := mgr.Add(&enicache.Ownership{
+					Cache:  eniCache,
+					Reader: mgr.GetAPIReader(),
+					Lease:  types.NamespacedName{Namespace: namespace, Name: leaderElectionID},
+				}); err != nil {
+					setupLog.Error(err, "unable to add ENI cache ownership")
+					os.Exit(1)
+				}
+			}
 			if err := eniCache.LoadFromConfigMap(ctx); err != nil {
 				setupLog.Error(err, "Failed to load cache from ConfigMap, starting fresh")
 			}
 			setupLog.Info("ENI cache ConfigMap persistence enabled", "namespace", namespace)
+
+			// Replicas waiting for the lease follow the leader's cache so failover starts warm
+			if cfg.EnableLeaderElection && cfg.StandbyCacheRefreshInterval > 0 {
+				standbyWarmer = &enicache.StandbyWarmer{
+					Cache:    eniCache,
+					Interval: cfg.StandbyCacheRefreshInterval,
+					Elected:  mgr.Elected(),
+				}
+				if err := mgr.Add(standbyWarmer); err != nil {
+					setupLog.Error(err, "unable to add standby cache warmer")
+					os.Exit(1)
+				}
+				setupLog.Info("Standby ENI cache refresh enabled", "interval", cfg.StandbyCacheRefreshInterval)
+			}
 		}
 
 		setupLog.Info("ENI caching enabled (lifecycle-based)", "configMapPersistence", cfg.EnableCacheConfigMap)
 	}
 
+	excludeSelector, err := labels.Parse(cfg.ExcludePodSelector)
+	if err != nil {
+		setupLog.Error(err, "invalid exclude pod selector")
+		os.Exit(1)
+	}
+	if !excludeSelector.Empty() {
+		setupLog.Info("Pod exclusion selector enabled", "selector", excludeSelector.String())
+	}
+
+	// Workers are started at the ceiling; the limiter holds effective concurrency at
+	// --max-concurrent-reconciles until changed through the admin endpoint.
+	concurrency, err := controller.NewConcurrencyLimiter(cfg.MaxConcurrentReconciles, cfg.MaxConcurrentReconcilesCeiling)
+	if err != nil {
+		setupLog.Error(err, "invalid reconcile concurrency")
+		os.Exit(1)
+	}
+
+	// Twice the worker count keeps workers busy between fair queue top-ups while
+	// leaving the backlog, and the ordering, in the fair queue.
+	var fairQueue *controller.FairQueue
+	if cfg.NamespaceFairQueuing {
+		fairQueue, err = controller.NewFairQueue(2 * cfg.MaxConcurrentReconcilesCeiling)
+		if err != nil {
+			setupLog.Error(err, "invalid fair queue depth")
+			os.Exit(1)
+		}
+		setupLog.Info("Namespace fair queuing enabled")
+	}
+
+	var triggerAudit *controller.TriggerAudit
+	if cfg.TriggerAudit {
+		triggerAudit = controller.NewTriggerAudit(ctrl.Log.WithName("trigger-audit"))
+		setupLog.Info("Reconcile trigger audit enabled")
+	}
+
+	var stateStore *controller.StateStore
+	if cfg.StateStore == config.StateStoreConfigMap {
+		stateStore = controller.NewStateStore(mgr.GetClient(), mgr.GetAPIReader(), getControllerNamespace())
+		setupLog.Info("Keeping last-applied state in a ConfigMap instead of pod annotations", "configMap", getControllerNamespace()+"/"+controller.StateConfigMapName)
+	}
+
+	var tagBurst *controller.TagBurstBuffer
+	if cfg.TagBurstDelay > 0 {
+		tagBurst, err = controller.NewTagBurstBuffer(awsClient, cfg.TagBurstDelay)
+		if err != nil {
+			setupLog.Error(err, "unable to create tag burst buffer")
+			os.Exit(1)
+		}
+		setupLog.Info("Merging CreateTags calls for shared ENIs", "delay", cfg.TagBurstDelay)
+		if !cfg.AllowSharedENITagging {
+			// Shared ENIs are skipped without it, so nothing is ever buffered
+			setupLog.Info("--tag-burst-delay has no effect without --allow-shared-eni-tagging")
+		}
+	}
+	// Only pods sharing an ENI can race on its tags
+	var eniLocks *controller.ENILocks
+	if cfg.AllowSharedENITagging || cfg.HostNetworkENI == config.HostNetworkENIPrimary {
+		eniLocks = controller.NewENILocks()
+	}
+	var podWrites *controller.PodWriteBuffer
+	if cfg.PodWriteCollapseWindow > 0 {
+		podWrites, err = controller.NewPodWriteBuffer(mgr.GetClient(), cfg.PodWriteCollapseWindow)
+		if err != nil {
+			setupLog.Error(err, "unable to create pod write buffer")
+			os.Exit(1)
+		}
+		setupLog.Info("Collapsing pod annotation and status writes", "window", cfg.PodWriteCollapseWindow)
+	}
+	// A nil *tagsource.Webhook must not become a non-nil controller.TagSource
+	var tagSource controller.TagSource
+	if cfg.TagSourceWebhookURL != "" {
+		webhook, err := tagsource.NewWebhook(cfg.TagSourceWebhookURL, cfg.TagSourceWebhookTimeout, cfg.TagSourceWebhookCacheTTL)
+		if err != nil {
+			setupLog.Error(err, "unable to create tag source webhook")
+			os.Exit(1)
+		}
+		tagSource = webhook
+		setupLog.Info("Merging tags from external tag source webhook", "url", cfg.TagSourceWebhookURL, "timeout", cfg.TagSourceWebhookTimeout, "cacheTTL", cfg.TagSourceWebhookCacheTTL)
+	}
+	if cfg.ENIAttachmentRequeueDelay > 0 {
+		setupLog.Info("Waiting for ENIs to be attached before tagging", "requeueDelay", cfg.ENIAttachmentRequeueDelay)
+	}
+
+	maintenanceWindows, err := controller.ParseMaintenanceWindows(cfg.MaintenanceWindows)
+	if err != nil {
+		setupLog.Error(err, "invalid maintenance windows")
+		os.Exit(1)
+	}
+	if len(maintenanceWindows) > 0 {
+		setupLog.Info("Deferring tag repairs to maintenance windows", "windows", maintenanceWindows.String())
+	}
+
+	var repairUntil time.Time
+	if cfg.StartupRepairWindow > 0 {
+		repairUntil = time.Now().Add(cfg.StartupRepairWindow)
+		setupLog.Info("Rebuilding bookkeeping annotations from ENI tags during startup", "until", repairUntil)
+	}
+
+	// Read-only mode requeues audited pods itself
+	var driftResync *controller.DriftResync
+	if cfg.ResyncInterval > 0 && !cfg.ReadOnly {
+		driftResync = controller.NewDriftResync(cfg.ResyncInterval)
+		setupLog.Info("Drift resync enabled", "interval", cfg.ResyncInterval)
+	}
+
+	var subnetAllowList *controller.SubnetAllowList
+	if subnetConfigMap.Name != "" {
+		subnetAllowList = controller.NewSubnetAllowList(cfg.SubnetIDs)
+		subnetReconciler := &controller.SubnetAllowListReconciler{
+			Client:        mgr.GetClient(),
+			ConfigMap:     subnetConfigMap,
+			StaticIDs:     cfg.SubnetIDs,
+			AllowList:     subnetAllowList,
+			ConditionType: controller.NewKeys(cfg.KeyDomain).ConditionType,
+		}
+		if err := subnetReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", controller.SubnetAllowListControllerName)
+			os.Exit(1)
+		}
+		setupLog.Info("Subnet allow-list ConfigMap watch enabled", "configMap", subnetConfigMap)
+	}
+
+	// The admin endpoint can pause tagging even without a pause ConfigMap
+	pauseSwitch := controller.NewPauseSwitch(mgr.GetClient(), controller.NewKeys(cfg.KeyDomain))
+	if pauseConfigMap.Name != "" {
+		pauseReconciler := &controller.PauseConfigMapReconciler{
+			Client:    mgr.GetClient(),
+			ConfigMap: pauseConfigMap,
+			Switch:    pauseSwitch,
+		}
+		// Read the state before the first reconcile; the watch keeps it current
+		if err := pauseReconciler.Load(ctx, mgr.GetAPIReader()); err != nil {
+			setupLog.Error(err, "unable to read pause ConfigMap, starting unpaused", "configMap", pauseConfigMap)
+		}
+		if err := pauseReconciler.SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", controller.PauseConfigMapControllerName)
+			os.Exit(1)
+		}
+		setupLog.Info("Pause ConfigMap watch enabled", "configMap", pauseConfigMap, "paused", pauseSwitch.Paused())
+	}
+
+	var managedByTag string
+	if cfg.ManagedByTag {
+		managedByTag = controller.ManagedByTagValue(cfg.ClusterName)
+		setupLog.Info("Writing managed-by tag to ENIs", "key", controller.ManagedByTagKey, "value", managedByTag)
+	}
+
+	tagMacros := tagValueMacros(cfg, awsClient)
+
+	var primaryENIs aws.PrimaryENIFinder
+	if cfg.HostNetworkENI == config.HostNetworkENIPrimary {
+		finder, ok := awsClient.(aws.PrimaryENIFinder)
+		if !ok {
+			setupLog.Error(nil, "AWS client cannot find primary ENIs, host network pods are looked up by pod IP")
+		} else {
+			primaryENIs = finder
+			setupLog.Info("Tagging node primary ENIs for host network pods")
+		}
+	}
+
+	var securityGroups aws.SecurityGroupTagger
+	if cfg.TagSecurityGroups {
+		tagger, ok := awsClient.(aws.SecurityGroupTagger)
+		if !ok {
+			setupLog.Error(nil, "AWS client cannot tag security groups, security group tagging disabled")
+		} else {
+			securityGroups = tagger
+			setupLog.Info("Tagging the security groups of pod ENIs")
+		}
+	}
+
+	var volumes aws.VolumeTagger
+	if cfg.TagNodeVolumes {
+		tagger, ok := awsClient.(aws.VolumeTagger)
+		if !ok {
+			setupLog.Error(nil, "AWS client cannot tag volumes, node volume tagging disabled")
+		} else {
+			volumes = tagger
+			setupLog.Info("Tagging the EBS volumes of pod nodes")
+		}
+	}
+
 	podReconciler := &controller.PodReconciler{
 		Client:                      mgr.GetClient(),
 		Scheme:                      mgr.GetScheme(),
@@ -187,32 +776,197 @@ func main() {
 		AnnotationKey:               cfg.AnnotationKey,
 		DryRun:                      cfg.DryRun,
 		SubnetIDs:                   cfg.SubnetIDs,
+		SubnetAllowList:             subnetAllowList,
 		AllowSharedENITagging:       cfg.AllowSharedENITagging,
 		TagNamespace:                cfg.TagNamespace,
+		TagKeyCase:                  controller.TagKeyCasePolicy(cfg.TagKeyCaseConflict),
+		TagKeyRenames:               cfg.TagKeyRenames,
+		TagFromLabels:               cfg.TagFromLabels,
+		TagValueTemplates:           controller.TagValueTemplateMode(cfg.TagValueTemplates),
+		TagMacros:                   tagMacros,
+		CriticalTagKeys:             cfg.CriticalTagKeys,
+		DiffSource:                  controller.TagDiffSource(cfg.TagDiffSource),
+		HostNetworkENI:              controller.HostNetworkENIMode(cfg.HostNetworkENI),
+		PrimaryENIs:                 primaryENIs,
+		SecurityGroups:              securityGroups,
+		Volumes:                     volumes,
+		TagHeadroomWarning:          cfg.TagHeadroomWarning,
+		RepairUntil:                 repairUntil,
+		InvalidTags:                 controller.InvalidTagsPolicy(cfg.InvalidTagsPolicy),
+		TagHistorySize:              cfg.TagHistorySize,
+		Pause:                       pauseSwitch,
+		PauseDeletionHold:           cfg.PauseDeletionHold,
+		MaintenanceWindows:          maintenanceWindows,
+		MutationBudget:              mutationBudget,
+		AuditLog:                    auditLog,
+		ExcludePodSelector:          excludeSelector,
+		DebugPods:                   controller.DebugPodPolicy(cfg.DebugPods),
+		KeyDomain:                   cfg.KeyDomain,
+		ControllerID:                cfg.ControllerID,
+		ManagedByTag:                managedByTag,
+		TagSource:                   tagSource,
+		TagBurst:                    tagBurst,
+		ENILocks:                    eniLocks,
+		PodWrites:                   podWrites,
+		DriftResync:                 driftResync,
+		Stats:                       controller.NewTaggingStats(),
+		ENIAttachmentRequeueDelay:   cfg.ENIAttachmentRequeueDelay,
+		Concurrency:                 concurrency,
+		FairQueue:                   fairQueue,
+		TriggerAudit:                triggerAudit,
+		StateStore:                  stateStore,
 		PodRateLimiters:             &sync.Map{},
 		PodRateLimitQPS:             cfg.PodRateLimitQPS,
 		PodRateLimitBurst:           cfg.PodRateLimitBurst,
+		PodRateLimitersMax:          cfg.PodRateLimitersMax,
+		ReportRateLimited:           cfg.PodRateLimitCondition,
 		RateLimiterCleanupThreshold: cfg.RateLimiterCleanupInterval * 5,
 	}
+	if cfg.ReconcileHistorySize > 0 {
+		podReconciler.History = controller.NewReconcileHistory(cfg.ReconcileHistorySize)
+	}
+	if cfg.ReadOnly {
+		podReconciler.ReadOnly = controller.NewReadOnlyAudit(cfg.ResyncInterval)
+		setupLog.Info("Read-only mode enabled, pods and ENIs are audited but never written", "interval", cfg.ResyncInterval)
+	}
 
-	if err = podReconciler.SetupWithManager(mgr, cfg.MaxConcurrentReconciles); err != nil {
+	if err = podReconciler.SetupWithManager(mgr, cfg.MaxConcurrentReconcilesCeiling); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Pod")
 		os.Exit(1)
 	}
 
+	// Reconciles fall back to per-pod lookups, so a failed warm-up only costs calls
+	if cfg.ENICacheWarmup {
+		if err := podReconciler.WarmENICache(ctx, mgr.GetAPIReader(), cfg.WatchNamespace); err != nil {
+			setupLog.Error(err, "ENI cache warm-up failed, continuing with a partially filled cache")
+		}
+	}
+
+	if cfg.EnableServiceTagging {
+		finder, ok := awsClient.(aws.LoadBalancerENIFinder)
+		if !ok {
+			setupLog.Error(nil, "AWS client cannot find load balancer ENIs, Service tagging disabled")
+		} else {
+			serviceReconciler := &controller.ServiceReconciler{
+				Client:        mgr.GetClient(),
+				Recorder:      mgr.GetEventRecorderFor("k8s-eni-tagger"),
+				AWSClient:     awsClient,
+				Finder:        finder,
+				AnnotationKey: cfg.AnnotationKey,
+				KeyDomain:     cfg.KeyDomain,
+				TagNamespace:  cfg.TagNamespace,
+				TagKeyRenames: cfg.TagKeyRenames,
+				TagKeyCase:    controller.TagKeyCasePolicy(cfg.TagKeyCaseConflict),
+				DryRun:        cfg.DryRun || cfg.ReadOnly,
+				Pause:         pauseSwitch,
+				AuditLog:      auditLog,
+			}
+			if err = serviceReconciler.SetupWithManager(mgr); err != nil {
+				setupLog.Error(err, "unable to create controller", "controller", "Service")
+				os.Exit(1)
+			}
+			setupLog.Info("Service load balancer ENI tagging enabled")
+		}
+	}
+
+	// Served by every replica, leader or not
+	if cfg.EnableAdmissionWebhook {
+		tagValidator := controller.TagAnnotationValidator{
+			TagKeyRenames:     cfg.TagKeyRenames,
+			TagKeyCase:        controller.TagKeyCasePolicy(cfg.TagKeyCaseConflict),
+			TagValueTemplates: controller.TagValueTemplateMode(cfg.TagValueTemplates),
+			TagMacros:         tagMacros,
+		}
+		validator := podwebhook.NewPodValidator(scheme, cfg.AnnotationKey, tagValidator)
+		mgr.GetWebhookServer().Register(podwebhook.ValidatePath, &webhook.Admission{Handler: validator})
+		setupLog.Info("Admission webhook enabled", "path", podwebhook.ValidatePath, "port", cfg.WebhookPort)
+
+		if cfg.DefaultTags != "" || defaultTagsConfigMap.Name != "" {
+			defaults, err := podwebhook.ParseDefaultTags(cfg.DefaultTags, cfg.DefaultTagsSelector, tagValidator)
+			if err != nil {
+				setupLog.Error(err, "invalid --default-tags")
+				os.Exit(1)
+			}
+			defaulter := podwebhook.NewPodDefaulter(scheme, cfg.AnnotationKey, defaults, tagValidator)
+			if defaultTagsConfigMap.Name != "" {
+				defaulter.WithConfigMap(mgr.GetClient(), defaultTagsConfigMap)
+			}
+			// Canaries need the leader to promote or roll them back, which read-only mode cannot
+			if defaultTagsConfigMap.Name != "" && !cfg.ReadOnly {
+				keys := controller.NewKeys(cfg.KeyDomain)
+				defaulter.WithCanary(keys.DefaultTagsCanary, cfg.DefaultTagsCanaryPercent)
+				canaryReconciler := &podwebhook.DefaultTagsCanaryReconciler{
+					Client:          mgr.GetClient(),
+					ConfigMap:       defaultTagsConfigMap,
+					Keys:            keys,
+					Validator:       tagValidator,
+					Pods:            cfg.DefaultTagsCanaryPods,
+					MaxFailureRatio: cfg.DefaultTagsCanaryMaxFailureRatio,
+				}
+				if err := canaryReconciler.SetupWithManager(mgr); err != nil {
+					setupLog.Error(err, "unable to create controller", "controller", podwebhook.DefaultTagsCanaryControllerName)
+					os.Exit(1)
+				}
+			}
+			mgr.GetWebhookServer().Register(podwebhook.DefaultPath, &webhook.Admission{Handler: defaulter})
+			setupLog.Info("Default tags webhook enabled", "path", podwebhook.DefaultPath, "tags", len(defaults.Tags), "configMap", defaultTagsConfigMap)
+		}
+	}
+
+	if cfg.OwnershipReportS3Bucket != "" {
+		writer, ok := awsClient.(aws.ObjectWriter)
+		if !ok {
+			setupLog.Error(nil, "AWS client cannot write to S3, ownership report disabled")
+		} else {
+			report := &controller.OwnershipReport{
+				Client:     mgr.GetClient(),
+				Writer:     writer,
+				Bucket:     cfg.OwnershipReportS3Bucket,
+				Prefix:     cfg.OwnershipReportS3Prefix,
+				Interval:   cfg.OwnershipReportInterval,
+				Keys:       controller.NewKeys(cfg.KeyDomain),
+				StateStore: stateStore,
+			}
+			if err := mgr.Add(report); err != nil {
+				setupLog.Error(err, "unable to add ownership report")
+				os.Exit(1)
+			}
+			setupLog.Info("ENI ownership report enabled", "bucket", cfg.OwnershipReportS3Bucket, "prefix", cfg.OwnershipReportS3Prefix, "interval", cfg.OwnershipReportInterval)
+		}
+	}
+
+	// A nil *StandbyWarmer must not become a non-nil http.Handler
+	var eniCacheStatus http.Handler
+	if standbyWarmer != nil {
+		eniCacheStatus = standbyWarmer
+	}
+	if err := addAuxServer(mgr, auxServerOptions, "admin", cfg.AdminBindAddress, adminHandler(concurrency, podReconciler.PlanHandler(), pauseSwitch, eniCacheStatus)); err != nil {
+		setupLog.Error(err, "unable to add admin server")
+		os.Exit(1)
+	}
+
+	if err := addAuxServer(mgr, auxServerOptions, "api", cfg.APIBindAddress, podReconciler.APIHandler()); err != nil {
+		setupLog.Error(err, "unable to add API server")
+		os.Exit(1)
+	}
+
 	// Start rate limiter cleanup goroutine
 	podReconciler.StartRateLimiterCleanup(ctx, cfg.RateLimiterCleanupInterval)
 
-	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+	if err := probes.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	// Readiness check: simple ping, no AWS API calls
-	// This ensures the controller manager is ready to process events
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	// Readiness check: the manager is up; AWS reachability is added separately per --aws-health-probe
+	if err := probes.AddReadyzCheck("readyz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	// Served by the controller rather than the manager so its timeouts are configurable
+	if err := addAuxServer(mgr, auxServerOptions, "health probe", cfg.HealthProbeBindAddress, probes.Handler()); err != nil {
+		setupLog.Error(err, "unable to add health probe server")
+		os.Exit(1)
+	}
 
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctx); err != nil {
@@ -0,0 +1,151 @@
+// Package audit writes a structured record of every ENI tag change, as one JSON
+// object per line, for evidence of who tagged what.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/metrics"
+)
+
+// Operations recorded in the audit log, named after the EC2 API calls.
+const (
+	OperationCreateTags = "CreateTags"
+	OperationDeleteTags = "DeleteTags"
+)
+
+// Results recorded in the audit log.
+const (
+	ResultSuccess = "success"
+	ResultError   = "error"
+	// ResultSkipped marks a change computed in dry-run mode and not applied.
+	ResultSkipped = "skipped"
+)
+
+// Entry is one audit record.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	// Pod and Service are "namespace/name" of the object the change was made
+	// for. Both are empty for calls made outside a reconcile.
+	Pod     string `json:"pod,omitempty"`
+	Service string `json:"service,omitempty"`
+	// ENIID is the ENI changed, SecurityGroupIDs the security groups or
+	// VolumeIDs the EBS volumes.
+	ENIID            string            `json:"eniID,omitempty"`
+	SecurityGroupIDs []string          `json:"securityGroupIDs,omitempty"`
+	VolumeIDs        []string          `json:"volumeIDs,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	TagKeys          []string          `json:"tagKeys,omitempty"`
+	Result           string            `json:"result"`
+	Error            string            `json:"error,omitempty"`
+	DryRun           bool              `json:"dryRun"`
+}
+
+// Log appends entries to a writer. A nil Log records nothing.
+type Log struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	closer io.Closer
+	now    func() time.Time
+}
+
+// Open returns a Log appending to the file at path, created if missing, or to
+// standard output if path is "-". It returns nil, a disabled log, if path is empty.
+func Open(path string) (*Log, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case "-":
+		return NewLog(os.Stdout), nil
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l := NewLog(f)
+	l.closer = f
+	return l, nil
+}
+
+// NewLog returns a Log writing to w.
+func NewLog(w io.Writer) *Log {
+	return &Log{enc: json.NewEncoder(w), now: time.Now}
+}
+
+// Record writes e, filling in its time and subject from ctx. Write failures are
+// logged and counted but never fail the tag change itself.
+func (l *Log) Record(ctx context.Context, e Entry) {
+	if l == nil {
+		return
+	}
+	if e.Service == "" {
+		e.Service = serviceFrom(ctx)
+	}
+	if e.TagKeys != nil {
+		e.TagKeys = append([]string(nil), e.TagKeys...)
+		sort.Strings(e.TagKeys)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e.Time.IsZero() {
+		e.Time = l.now().UTC()
+	}
+	if err := l.enc.Encode(e); err != nil {
+		metrics.AuditLogErrorsTotal.Inc()
+		log.Printf("[Audit] failed to write %s audit record: %v", e.Operation, err)
+	}
+}
+
+// RecordCall records the outcome of a CreateTags or DeleteTags call.
+func (l *Log) RecordCall(ctx context.Context, e Entry, err error) {
+	e.Result = ResultSuccess
+	if err != nil {
+		e.Result = ResultError
+		e.Error = err.Error()
+	}
+	l.Record(ctx, e)
+}
+
+// RecordDryRun records the tag changes a dry run would have made to an ENI, one
+// entry per operation.
+func (l *Log) RecordDryRun(ctx context.Context, pod, eniID string, toAdd map[string]string, toRemove []string) {
+	if len(toAdd) > 0 {
+		l.Record(ctx, Entry{Operation: OperationCreateTags, Pod: pod, ENIID: eniID, Tags: toAdd, Result: ResultSkipped, DryRun: true})
+	}
+	if len(toRemove) > 0 {
+		l.Record(ctx, Entry{Operation: OperationDeleteTags, Pod: pod, ENIID: eniID, TagKeys: toRemove, Result: ResultSkipped, DryRun: true})
+	}
+}
+
+// Close closes the underlying file, if Open created one.
+func (l *Log) Close() error {
+	if l == nil || l.closer == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.closer.Close()
+}
+
+type serviceKey struct{}
+
+// WithService records the Service a call is made for, so its tag changes are
+// attributed to it in the audit log.
+func WithService(ctx context.Context, namespace, name string) context.Context {
+	return context.WithValue(ctx, serviceKey{}, namespace+"/"+name)
+}
+
+func serviceFrom(ctx context.Context) string {
+	s, _ := ctx.Value(serviceKey{}).(string)
+	return s
+}
@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decode(t *testing.T, buf *bytes.Buffer) []Entry {
+	t.Helper()
+	var entries []Entry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e Entry
+		require.NoError(t, json.Unmarshal([]byte(line), &e), line)
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestLogRecordCall(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLog(&buf)
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	l.RecordCall(context.Background(), Entry{Operation: OperationCreateTags, Pod: "shop/web", ENIID: "eni-1", Tags: map[string]string{"team": "a"}}, nil)
+	ctx := WithService(context.Background(), "shop", "lb")
+	l.RecordCall(ctx, Entry{Operation: OperationDeleteTags, ENIID: "eni-2", TagKeys: []string{"z", "a"}}, errors.New("denied"))
+
+	entries := decode(t, &buf)
+	require.Len(t, entries, 2)
+	assert.Equal(t, Entry{Time: now, Operation: OperationCreateTags, Pod: "shop/web", ENIID: "eni-1", Tags: map[string]string{"team": "a"}, Result: ResultSuccess}, entries[0])
+	assert.Equal(t, Entry{Time: now, Operation: OperationDeleteTags, Service: "shop/lb", ENIID: "eni-2", TagKeys: []string{"a", "z"}, Result: ResultError, Error: "denied"}, entries[1])
+}
+
+func TestLogRecordDryRun(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLog(&buf)
+
+	l.RecordDryRun(context.Background(), "shop/web", "eni-1", map[string]string{"team": "a"}, []string{"env"})
+	entries := decode(t, &buf)
+	require.Len(t, entries, 2)
+	for _, e := range entries {
+		assert.True(t, e.DryRun)
+		assert.Equal(t, ResultSkipped, e.Result)
+		assert.Equal(t, "shop/web", e.Pod)
+	}
+	assert.Equal(t, OperationCreateTags, entries[0].Operation)
+	assert.Equal(t, []string{"env"}, entries[1].TagKeys)
+
+	buf.Reset()
+	l.RecordDryRun(context.Background(), "shop/web", "eni-1", nil, nil)
+	assert.Empty(t, buf.String())
+}
+
+func TestOpen(t *testing.T) {
+	l, err := Open("")
+	require.NoError(t, err)
+	assert.Nil(t, l)
+	// A disabled log records nothing
+	l.RecordCall(context.Background(), Entry{ENIID: "eni-1"}, nil)
+	assert.NoError(t, l.Close())
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < 2; i++ {
+		l, err = Open(path)
+		require.NoError(t, err)
+		l.RecordCall(context.Background(), Entry{Operation: OperationCreateTags, ENIID: "eni-1"}, nil)
+		require.NoError(t, l.Close())
+	}
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Len(t, decode(t, bytes.NewBuffer(data)), 2, "reopening appends")
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing", "audit.log"))
+	assert.Error(t, err)
+}
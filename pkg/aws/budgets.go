@@ -0,0 +1,76 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// AnyNamespace is the NamespaceBudgets key applying to namespaces without their own entry.
+const AnyNamespace = "*"
+
+// namespaceLimiters gives namespaces their own share of the EC2 rate limit, so one
+// busy namespace cannot use up the whole budget. Each budgeted namespace waits on
+// its own limiter before the shared one; unbudgeted namespaces and calls made
+// without a pod (health and permission checks) only use the shared limiter.
+type namespaceLimiters struct {
+	qps    float64
+	burst  int
+	shares map[string]float64
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// newNamespaceLimiters returns limiters for shares, fractions (0, 1] of qps and
+// burst keyed by namespace or AnyNamespace. It returns nil if shares is empty.
+func newNamespaceLimiters(qps float64, burst int, shares map[string]float64) (*namespaceLimiters, error) {
+	if len(shares) == 0 {
+		return nil, nil
+	}
+	for ns, share := range shares {
+		if share <= 0 || share > 1 {
+			return nil, fmt.Errorf("rate limit share for namespace %q must be in (0, 1]: %v", ns, share)
+		}
+	}
+	return &namespaceLimiters{qps: qps, burst: burst, shares: shares, limiters: make(map[string]*rate.Limiter)}, nil
+}
+
+// limiter returns the limiter for namespace, or nil if it has no budget.
+func (n *namespaceLimiters) limiter(namespace string) *rate.Limiter {
+	share, ok := n.shares[namespace]
+	if !ok {
+		if share, ok = n.shares[AnyNamespace]; !ok {
+			return nil
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	l, ok := n.limiters[namespace]
+	if !ok {
+		burst := int(float64(n.burst) * share)
+		if burst < 1 {
+			burst = 1
+		}
+		l = rate.NewLimiter(rate.Limit(n.qps*share), burst)
+		n.limiters[namespace] = l
+	}
+	return l
+}
+
+// wait blocks until the call's namespace budget, if any, and then the shared limit allow it.
+func (c *defaultClient) wait(ctx context.Context) error {
+	if c.budgets != nil {
+		if id, ok := podIdentityFrom(ctx); ok && id.Namespace != "" {
+			if l := c.budgets.limiter(id.Namespace); l != nil {
+				if err := l.Wait(ctx); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return c.rateLimiter.Wait(ctx)
+}
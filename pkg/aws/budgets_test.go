@@ -0,0 +1,60 @@
+package aws
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestNewNamespaceLimiters(t *testing.T) {
+	n, err := newNamespaceLimiters(10, 20, nil)
+	require.NoError(t, err)
+	assert.Nil(t, n)
+
+	_, err = newNamespaceLimiters(10, 20, map[string]float64{"batch": 1.5})
+	assert.Error(t, err)
+
+	n, err = newNamespaceLimiters(10, 20, map[string]float64{"batch": 0.2, AnyNamespace: 0.01})
+	require.NoError(t, err)
+
+	batch := n.limiter("batch")
+	require.NotNil(t, batch)
+	assert.Equal(t, rate.Limit(2), batch.Limit())
+	assert.Equal(t, 4, batch.Burst())
+	assert.Same(t, batch, n.limiter("batch"))
+
+	// Unlisted namespaces each get their own AnyNamespace share, with at least one token.
+	other := n.limiter("web")
+	require.NotNil(t, other)
+	assert.Equal(t, 1, other.Burst())
+	assert.NotSame(t, other, n.limiter("api"))
+
+	n, err = newNamespaceLimiters(10, 20, map[string]float64{"batch": 0.2})
+	require.NoError(t, err)
+	assert.Nil(t, n.limiter("web"))
+}
+
+func TestWaitUsesNamespaceBudget(t *testing.T) {
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+	budgets, err := newNamespaceLimiters(10, 20, map[string]float64{"batch": 0.1})
+	require.NoError(t, err)
+	c := &defaultClient{rateLimiter: rl, budgets: budgets}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	batch := WithPodIdentity(ctx, "batch", "job-1")
+
+	// The batch namespace gets 2 tokens of burst at 1 QPS.
+	require.NoError(t, c.wait(batch))
+	require.NoError(t, c.wait(batch))
+	assert.Error(t, c.wait(batch))
+
+	// Other namespaces and pod-less calls still have the shared budget.
+	assert.NoError(t, c.wait(WithPodIdentity(ctx, "web", "api-1")))
+	assert.NoError(t, c.wait(ctx))
+}
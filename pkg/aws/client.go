@@ -6,10 +6,11 @@ import (
 	"fmt"
 	"log"
 	"net"
-	"os"
+	"sort"
 	"strings"
 	"time"
 
+	"k8s-eni-tagger/pkg/audit"
 	"k8s-eni-tagger/pkg/metrics"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -17,6 +18,8 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/aws/smithy-go"
 	"golang.org/x/time/rate"
 	"math/rand/v2"
@@ -28,21 +31,64 @@ type EC2API interface {
 	DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error)
 	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
 	DeleteTags(ctx context.Context, params *ec2.DeleteTagsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteTagsOutput, error)
+	DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error)
 }
 
 // ENIInfo contains details about an Elastic Network Interface
 type ENIInfo struct {
-	ID            string
-	SubnetID      string
-	InterfaceType string
-	IsShared      bool
-	Description   string
-	Tags          map[string]string
+	ID       string
+	SubnetID string
+	// AvailabilityZone is empty when unknown, e.g. in cache entries persisted by
+	// older versions.
+	AvailabilityZone string
+	InterfaceType    string
+	IsShared         bool
+	Description      string
+	Tags             map[string]string
+	// Status is the ENI's status (available, attaching, in-use, detaching) and
+	// AttachmentStatus that of its attachment (attaching, attached, ...). Both are
+	// empty when unknown, e.g. in cache entries persisted by older versions.
+	Status           string
+	AttachmentStatus string
+	// SecurityGroupIDs are the security groups attached to the ENI. Empty in cache
+	// entries persisted by older versions.
+	SecurityGroupIDs []string
+	// Class is what the ENI is used for (ENIClassBranch, ...), which decides
+	// IsShared; see classifyENI.
+	Class string
+}
+
+// Attached reports whether the ENI is in use and attached, or its state is unknown.
+func (e *ENIInfo) Attached() bool {
+	if e.Status != "" && e.Status != string(types.NetworkInterfaceStatusInUse) {
+		return false
+	}
+	return e.AttachmentStatus == "" || e.AttachmentStatus == string(types.AttachmentStatusAttached)
+}
+
+// ForeignTagKeys returns the sorted keys of tags on the ENI that are not present
+// in managed. These are tags applied by other tools or users and still count
+// against the per-ENI tag quota.
+func (e *ENIInfo) ForeignTagKeys(managed map[string]string) []string {
+	if e == nil {
+		return nil
+	}
+	var foreign []string
+	for k := range e.Tags {
+		if _, ok := managed[k]; !ok {
+			foreign = append(foreign, k)
+		}
+	}
+	sort.Strings(foreign)
+	return foreign
 }
 
 // Client defines the interface for AWS operations
 type Client interface {
 	GetENIInfoByIP(ctx context.Context, ip string) (*ENIInfo, error)
+	GetENIInfoByIPs(ctx context.Context, ips []string) (map[string]*ENIInfo, error)
 	TagENI(ctx context.Context, eniID string, tags map[string]string) error
 	UntagENI(ctx context.Context, eniID string, tagKeys []string) error
 	GetEC2Client() *ec2.Client
@@ -64,6 +110,9 @@ const (
 	AWSErrorTemporary
 	// AWSErrorInvalidInput - Invalid input parameters (permanent)
 	AWSErrorInvalidInput
+	// AWSErrorTagPolicy - Tags rejected by an Organizations tag policy (permanent
+	// until the tags or the policy change)
+	AWSErrorTagPolicy
 )
 
 // AWSErrorInfo contains categorized error information
@@ -74,6 +123,16 @@ type AWSErrorInfo struct {
 	IsRetryable bool
 }
 
+// ErrorCode returns the AWS API error code wrapped in err (e.g. "UnauthorizedOperation"),
+// or an empty string if err does not wrap an AWS API error.
+func ErrorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
+
 // categorizeAWSError analyzes an AWS error and returns categorized information
 func categorizeAWSError(err error) AWSErrorInfo {
 	if err == nil {
@@ -113,7 +172,7 @@ func categorizeAWSError(err error) AWSErrorInfo {
 		}
 
 	// Permission errors (permanent)
-	case "UnauthorizedOperation", "AccessDenied", "Forbidden":
+	case "UnauthorizedOperation", "AccessDenied", "AccessDeniedException", "Forbidden":
 		return AWSErrorInfo{
 			Category:    AWSErrorPermission,
 			ErrorCode:   errorCode,
@@ -122,7 +181,7 @@ func categorizeAWSError(err error) AWSErrorInfo {
 		}
 
 	// Rate limiting errors (transient, retry)
-	case "RequestLimitExceeded", "ThrottlingException", "Throttling", "TooManyRequestsException":
+	case "RequestLimitExceeded", "ThrottlingException", "ThrottledException", "Throttling", "TooManyRequestsException", "SlowDown":
 		return AWSErrorInfo{
 			Category:    AWSErrorRateLimit,
 			ErrorCode:   errorCode,
@@ -131,7 +190,7 @@ func categorizeAWSError(err error) AWSErrorInfo {
 		}
 
 	// Invalid input errors (permanent)
-	case "InvalidParameterValue", "InvalidParameterCombination", "ValidationException":
+	case "InvalidParameterValue", "InvalidParameterCombination", "InvalidParameterException", "ValidationException":
 		return AWSErrorInfo{
 			Category:    AWSErrorInvalidInput,
 			ErrorCode:   errorCode,
@@ -139,8 +198,17 @@ func categorizeAWSError(err error) AWSErrorInfo {
 			IsRetryable: false,
 		}
 
+	// Tag policy violations (permanent)
+	case tagPolicyViolationCode:
+		return AWSErrorInfo{
+			Category:    AWSErrorTagPolicy,
+			ErrorCode:   errorCode,
+			Message:     message,
+			IsRetryable: false,
+		}
+
 	// Temporary/transient errors (retry)
-	case "InternalError", "ServiceUnavailable", "InternalFailure", "Unavailable":
+	case "InternalError", "ServiceUnavailable", "InternalFailure", "InternalServiceException", "Unavailable":
 		return AWSErrorInfo{
 			Category:    AWSErrorTemporary,
 			ErrorCode:   errorCode,
@@ -177,6 +245,9 @@ type RateLimitConfig struct {
 	QPS float64
 	// Burst is the maximum burst size
 	Burst int
+	// NamespaceBudgets caps namespaces at a fraction (0, 1] of QPS and Burst, keyed
+	// by namespace or AnyNamespace. Calls are attributed with WithPodIdentity.
+	NamespaceBudgets map[string]float64
 }
 
 // DefaultRateLimitConfig returns sensible defaults for AWS API rate limiting
@@ -202,6 +273,26 @@ func newRateLimiter(qps float64, burst int) (*rate.Limiter, error) {
 type defaultClient struct {
 	ec2Client   EC2API
 	rateLimiter *rate.Limiter
+	// budgets holds per-namespace shares of rateLimiter; nil without budgets.
+	budgets *namespaceLimiters
+	// debug tags each call with its retry attempt for the debug log.
+	debug bool
+	// sessions selects per-pod assumed-role credentials; nil without role assumption.
+	sessions *roleSessions
+	// s3 serves PutObject with the client's base credentials.
+	s3 *s3Target
+	// mutations counts tag changing calls; nil without a budget.
+	mutations *MutationBudget
+	// auditLog records every tag change; nil without an audit log.
+	auditLog *audit.Log
+	// tagBackend makes the calls changing tags; nil means EC2 (see backend).
+	tagBackend TagBackend
+	// readOnly refuses every call changing AWS resources with ErrReadOnly.
+	readOnly bool
+	// region, roleARN and sts serve Identity.
+	region  string
+	roleARN string
+	sts     callerIdentityAPI
 }
 
 const (
@@ -211,6 +302,38 @@ const (
 	awsAPIDelayDivisor = 2 // delay range is [backoff/2, backoff]
 )
 
+// ClientOptions configures NewClientWithOptions.
+type ClientOptions struct {
+	// RateLimit bounds the rate of EC2 API calls.
+	RateLimit RateLimitConfig
+	// DebugLogging logs every EC2 HTTP exchange (operation, attempt,
+	// latency, status, request ID, parameters, and headers with credentials
+	// redacted).
+	DebugLogging bool
+	// EC2Endpoint overrides the EC2 endpoint. Empty falls back to
+	// AWS_ENDPOINT_URL_EC2, then AWS_ENDPOINT_URL (see ResolveEndpoint).
+	EC2Endpoint string
+	// AssumeRole makes EC2 calls with credentials from sts:AssumeRole,
+	// e.g. for tagging ENIs in another account.
+	AssumeRole AssumeRoleConfig
+	// Profile selects a named profile from the shared config and credentials
+	// files. Empty uses AWS_PROFILE or the default chain.
+	Profile string
+	// Faults injects EC2 errors for resilience tests.
+	Faults FaultInjection
+	// MutationBudget, if set, counts every CreateTags and DeleteTags call.
+	MutationBudget *MutationBudget
+	// AuditLog, if set, records every TagENI and UntagENI call and its result.
+	AuditLog *audit.Log
+	// TagBackend selects the API changing tags: TagBackendEC2 (default) or
+	// TagBackendResourceGroupsTagging. Reads always use EC2.
+	TagBackend string
+	// ReadOnly refuses every call changing tags or writing to S3 with
+	// ErrReadOnly, without sending it, for auditing from an account that must
+	// not be modified.
+	ReadOnly bool
+}
+
 // NewClient creates a new AWS client with default rate limiting
 func NewClient(ctx context.Context) (Client, error) {
 	return NewClientWithRateLimiter(ctx, DefaultRateLimitConfig())
@@ -218,7 +341,12 @@ func NewClient(ctx context.Context) (Client, error) {
 
 // NewClientWithRateLimiter creates a new AWS client with custom rate limiting
 func NewClientWithRateLimiter(ctx context.Context, rlConfig RateLimitConfig) (Client, error) {
-	cfg, err := config.LoadDefaultConfig(ctx)
+	return NewClientWithOptions(ctx, ClientOptions{RateLimit: rlConfig})
+}
+
+// NewClientWithOptions creates a new AWS client from opts
+func NewClientWithOptions(ctx context.Context, opts ClientOptions) (Client, error) {
+	cfg, err := loadSDKConfig(ctx, opts.Profile)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load SDK config: %w", err)
 	}
@@ -226,13 +354,16 @@ func NewClientWithRateLimiter(ctx context.Context, rlConfig RateLimitConfig) (Cl
 	// Set custom User-Agent
 	cfg.AppID = "k8s-eni-tagger"
 
-	limiter, err := newRateLimiter(rlConfig.QPS, rlConfig.Burst)
+	limiter, err := newRateLimiter(opts.RateLimit.QPS, opts.RateLimit.Burst)
+	if err != nil {
+		return nil, err
+	}
+	budgets, err := newNamespaceLimiters(opts.RateLimit.QPS, opts.RateLimit.Burst, opts.RateLimit.NamespaceBudgets)
 	if err != nil {
 		return nil, err
 	}
 
-	// Support custom AWS endpoint for testing/mocking
-	// Check AWS_ENDPOINT_URL environment variable
+	// Support custom AWS endpoints for testing/mocking, private endpoints and proxies
 	ec2Options := []func(*ec2.Options){}
 	// We implement our own retry loop that re-enters the rate limiter on each attempt.
 	// To avoid multiplicative retries (SDK retries inside our manual retries), disable SDK retries here.
@@ -241,18 +372,113 @@ func NewClientWithRateLimiter(ctx context.Context, rlConfig RateLimitConfig) (Cl
 			so.MaxAttempts = 1
 		})
 	})
-	if endpoint := os.Getenv("AWS_ENDPOINT_URL"); endpoint != "" {
+	endpoint, err := ResolveEndpoint(ec2.ServiceID, opts.EC2Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if endpoint.URL != "" {
+		ec2Options = append(ec2Options, func(o *ec2.Options) {
+			o.BaseEndpoint = aws.String(endpoint.URL)
+		})
+	}
+	ec2Options = append(ec2Options, func(o *ec2.Options) {
+		o.APIOptions = append(o.APIOptions, addRequestLog)
+	})
+	if opts.DebugLogging {
 		ec2Options = append(ec2Options, func(o *ec2.Options) {
-			o.BaseEndpoint = aws.String(endpoint)
+			o.APIOptions = append(o.APIOptions, addDebugLogging)
 		})
 	}
+	if opts.Faults.ErrorRate > 0 {
+		ec2Options = append(ec2Options, func(o *ec2.Options) {
+			o.APIOptions = append(o.APIOptions, faultInjectionMiddleware(opts.Faults.ErrorRate))
+		})
+	}
+
+	var sessions *roleSessions
+	if opts.AssumeRole.RoleARN != "" {
+		if cfg.Region != "" {
+			if err := CheckARNPartition(opts.AssumeRole.RoleARN, cfg.Region); err != nil {
+				return nil, fmt.Errorf("assume role: %w", err)
+			}
+		}
+		sessions, err = newRoleSessions(sts.NewFromConfig(cfg), opts.AssumeRole)
+		if err != nil {
+			return nil, err
+		}
+		// The shared EC2 client (health and permission checks) uses the base session.
+		cfg.Credentials = sessions.base
+	}
+
+	s3, err := newS3Target(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var tagBackend TagBackend
+	switch opts.TagBackend {
+	case "", TagBackendEC2:
+	case TagBackendResourceGroupsTagging:
+		tagBackend, err = newTaggingAPIBackendFromConfig(ctx, cfg, sessions, opts)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown tag backend %q", opts.TagBackend)
+	}
 
 	return &defaultClient{
 		ec2Client:   ec2.NewFromConfig(cfg, ec2Options...),
 		rateLimiter: limiter,
+		budgets:     budgets,
+		debug:       opts.DebugLogging,
+		sessions:    sessions,
+		s3:          s3,
+		mutations:   opts.MutationBudget,
+		auditLog:    opts.AuditLog,
+		tagBackend:  tagBackend,
+		readOnly:    opts.ReadOnly,
+		region:      cfg.Region,
+		roleARN:     opts.AssumeRole.RoleARN,
+		sts:         sts.NewFromConfig(cfg),
 	}, nil
 }
 
+// newTaggingAPIBackendFromConfig builds the Tagging API client with the same
+// retry, endpoint, debug and fault injection settings as the EC2 client.
+func newTaggingAPIBackendFromConfig(ctx context.Context, cfg aws.Config, sessions *roleSessions, opts ClientOptions) (*taggingAPIBackend, error) {
+	endpoint, err := ResolveEndpoint(resourcegroupstaggingapi.ServiceID, "")
+	if err != nil {
+		return nil, err
+	}
+	client := resourcegroupstaggingapi.NewFromConfig(cfg, func(o *resourcegroupstaggingapi.Options) {
+		o.Retryer = retry.NewStandard(func(so *retry.StandardOptions) {
+			so.MaxAttempts = 1
+		})
+		if endpoint.URL != "" {
+			o.BaseEndpoint = aws.String(endpoint.URL)
+		}
+		o.APIOptions = append(o.APIOptions, addRequestLog)
+		if opts.DebugLogging {
+			o.APIOptions = append(o.APIOptions, addDebugLogging)
+		}
+		if opts.Faults.ErrorRate > 0 {
+			o.APIOptions = append(o.APIOptions, faultInjectionMiddleware(opts.Faults.ErrorRate))
+		}
+	})
+	return newTaggingAPIBackend(ctx, cfg, client, sessions, opts.AssumeRole.RoleARN)
+}
+
+// loadSDKConfig loads the SDK config from the default chain, or from the named
+// shared config profile when profile is set.
+func loadSDKConfig(ctx context.Context, profile string) (aws.Config, error) {
+	var opts []func(*config.LoadOptions) error
+	if profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
+	}
+	return config.LoadDefaultConfig(ctx, opts...)
+}
+
 // GetEC2Client returns the underlying EC2 client for sharing with other components
 // Note: This now returns an interface, callers may need to type assert if they need the specific struct
 // but for general usage the interface should suffice if extended.
@@ -272,7 +498,7 @@ func (c *defaultClient) GetENIInfoByIP(ctx context.Context, ip string) (*ENIInfo
 	status := "success"
 	defer func() {
 		duration := time.Since(start).Seconds()
-		metrics.AWSAPILatency.WithLabelValues("DescribeNetworkInterfaces", status).Observe(duration)
+		metrics.ObserveAWSAPILatency(ctx, "DescribeNetworkInterfaces", status, duration)
 	}()
 
 	input := &ec2.DescribeNetworkInterfacesInput{
@@ -286,11 +512,11 @@ func (c *defaultClient) GetENIInfoByIP(ctx context.Context, ip string) (*ENIInfo
 
 	var result *ec2.DescribeNetworkInterfacesOutput
 	err := c.doWithRetry(ctx, "DescribeNetworkInterfaces", awsAPIMaxAttempts, func(ctx context.Context) error {
-		if err := c.rateLimiter.Wait(ctx); err != nil {
+		if err := c.wait(ctx); err != nil {
 			return fmt.Errorf("rate limiter wait: %w", err)
 		}
 		var callErr error
-		result, callErr = c.ec2Client.DescribeNetworkInterfaces(ctx, input)
+		result, callErr = c.ec2Client.DescribeNetworkInterfaces(ctx, input, c.sessions.ec2Options(ctx)...)
 		return callErr
 	})
 	if err != nil {
@@ -313,46 +539,41 @@ func (c *defaultClient) GetENIInfoByIP(ctx context.Context, ip string) (*ENIInfo
 	}
 
 	// In case of multiple matches (unlikely for private IP in same VPC), return the first one
-	eni := result.NetworkInterfaces[0]
+	return newENIInfo(result.NetworkInterfaces[0]), nil
+}
 
+// newENIInfo builds the ENIInfo of a described network interface.
+func newENIInfo(eni types.NetworkInterface) *ENIInfo {
 	tags := make(map[string]string)
 	for _, t := range eni.TagSet {
-		if t.Key != nil && t.Value != nil {
+		// EC2 never stores an empty key; skip malformed items rather than
+		// treating "" as a tag the controller could diff against.
+		if aws.ToString(t.Key) != "" && t.Value != nil {
 			tags[*t.Key] = *t.Value
 		}
 	}
 
 	info := &ENIInfo{
-		ID:            aws.ToString(eni.NetworkInterfaceId),
-		SubnetID:      aws.ToString(eni.SubnetId),
-		InterfaceType: string(eni.InterfaceType),
-		Description:   aws.ToString(eni.Description),
-		Tags:          tags,
-	}
-
-	// Determine if ENI is shared using improved heuristics
-	// Check description for AWS VPC CNI patterns
-	isVPCCNI := strings.Contains(aws.ToString(eni.Description), "aws-K8S-")
-
-	switch {
-	case string(eni.InterfaceType) == "branch":
-		// EKS Fargate/trunk-based - branch ENIs are pod-exclusive
-		info.IsShared = false
-	case string(eni.InterfaceType) == "trunk":
-		// Trunk ENIs host multiple branch ENIs
-		info.IsShared = true
-	case isVPCCNI && len(eni.PrivateIpAddresses) == 1:
-		// VPC CNI secondary ENI with single IP - likely pod exclusive (prefix delegation)
-		info.IsShared = false
-	case len(eni.PrivateIpAddresses) > 1:
-		// Multiple IPs on same ENI - definitely shared
-		info.IsShared = true
-	default:
-		// Single IP, standard interface - could be either, assume not shared
-		info.IsShared = false
+		ID:               aws.ToString(eni.NetworkInterfaceId),
+		SubnetID:         aws.ToString(eni.SubnetId),
+		AvailabilityZone: aws.ToString(eni.AvailabilityZone),
+		InterfaceType:    string(eni.InterfaceType),
+		Description:      aws.ToString(eni.Description),
+		Tags:             tags,
+		Status:           string(eni.Status),
 	}
+	if eni.Attachment != nil {
+		info.AttachmentStatus = string(eni.Attachment.Status)
+	}
+	for _, g := range eni.Groups {
+		if id := aws.ToString(g.GroupId); id != "" {
+			info.SecurityGroupIDs = append(info.SecurityGroupIDs, id)
+		}
+	}
+
+	info.Class, info.IsShared = classifyENI(eni)
 
-	return info, nil
+	return info
 }
 
 // TagENI adds tags to an ENI
@@ -361,33 +582,23 @@ func (c *defaultClient) TagENI(ctx context.Context, eniID string, tags map[strin
 		return nil
 	}
 
+	backend := c.backend()
+	op := backend.TagOperation()
 	start := time.Now()
 	status := "success"
 	defer func() {
 		duration := time.Since(start).Seconds()
-		metrics.AWSAPILatency.WithLabelValues("CreateTags", status).Observe(duration)
+		metrics.ObserveAWSAPILatency(ctx, op, status, duration)
 	}()
 
-	var ec2Tags []types.Tag
-	for k, v := range tags {
-		ec2Tags = append(ec2Tags, types.Tag{
-			Key:   aws.String(k),
-			Value: aws.String(v),
-		})
-	}
-
-	input := &ec2.CreateTagsInput{
-		Resources: []string{eniID},
-		Tags:      ec2Tags,
-	}
-
-	err := c.doWithRetry(ctx, "CreateTags", awsAPIMaxAttempts, func(ctx context.Context) error {
-		if err := c.rateLimiter.Wait(ctx); err != nil {
+	err := c.doWithRetry(ctx, op, awsAPIMaxAttempts, func(ctx context.Context) error {
+		if err := c.wait(ctx); err != nil {
 			return fmt.Errorf("rate limiter wait: %w", err)
 		}
-		_, callErr := c.ec2Client.CreateTags(ctx, input)
-		return callErr
+		c.mutations.Record()
+		return backend.TagResources(ctx, resourceTypeNetworkInterface, []string{eniID}, tags)
 	})
+	c.recordAudit(ctx, audit.Entry{Operation: audit.OperationCreateTags, ENIID: eniID, Tags: tags}, err)
 	if err != nil {
 		status = "error"
 		awsErr := categorizeAWSError(err)
@@ -395,9 +606,13 @@ func (c *defaultClient) TagENI(ctx context.Context, eniID string, tags map[strin
 		case AWSErrorNotFound:
 			return fmt.Errorf("ENI %s not found (may have been deleted): %w", eniID, err)
 		case AWSErrorPermission:
-			return fmt.Errorf("insufficient permissions to tag ENI %s (check ec2:CreateTags): %w", eniID, err)
+			return fmt.Errorf("insufficient permissions to tag ENI %s (check %s): %w", eniID, requiredPermissions(op), err)
 		case AWSErrorInvalidInput:
 			return fmt.Errorf("invalid tag request for ENI %s: %w", eniID, err)
+		case AWSErrorTagPolicy:
+			var apiErr smithy.APIError
+			errors.As(err, &apiErr)
+			return newTagPolicyViolationError(eniID, apiErr.ErrorMessage(), err)
 		default:
 			return fmt.Errorf("failed to tag ENI %s: %w", eniID, err)
 		}
@@ -412,32 +627,23 @@ func (c *defaultClient) UntagENI(ctx context.Context, eniID string, tagKeys []st
 		return nil
 	}
 
+	backend := c.backend()
+	op := backend.UntagOperation()
 	start := time.Now()
 	status := "success"
 	defer func() {
 		duration := time.Since(start).Seconds()
-		metrics.AWSAPILatency.WithLabelValues("DeleteTags", status).Observe(duration)
+		metrics.ObserveAWSAPILatency(ctx, op, status, duration)
 	}()
 
-	var ec2Tags []types.Tag
-	for _, k := range tagKeys {
-		ec2Tags = append(ec2Tags, types.Tag{
-			Key: aws.String(k),
-		})
-	}
-
-	input := &ec2.DeleteTagsInput{
-		Resources: []string{eniID},
-		Tags:      ec2Tags,
-	}
-
-	err := c.doWithRetry(ctx, "DeleteTags", awsAPIMaxAttempts, func(ctx context.Context) error {
-		if err := c.rateLimiter.Wait(ctx); err != nil {
+	err := c.doWithRetry(ctx, op, awsAPIMaxAttempts, func(ctx context.Context) error {
+		if err := c.wait(ctx); err != nil {
 			return fmt.Errorf("rate limiter wait: %w", err)
 		}
-		_, callErr := c.ec2Client.DeleteTags(ctx, input)
-		return callErr
+		c.mutations.Record()
+		return backend.UntagResources(ctx, resourceTypeNetworkInterface, []string{eniID}, tagKeys)
 	})
+	c.recordAudit(ctx, audit.Entry{Operation: audit.OperationDeleteTags, ENIID: eniID, TagKeys: tagKeys}, err)
 	if err != nil {
 		status = "error"
 		awsErr := categorizeAWSError(err)
@@ -445,7 +651,7 @@ func (c *defaultClient) UntagENI(ctx context.Context, eniID string, tagKeys []st
 		case AWSErrorNotFound:
 			return fmt.Errorf("ENI %s not found (may have been deleted): %w", eniID, err)
 		case AWSErrorPermission:
-			return fmt.Errorf("insufficient permissions to untag ENI %s (check ec2:DeleteTags): %w", eniID, err)
+			return fmt.Errorf("insufficient permissions to untag ENI %s (check %s): %w", eniID, requiredPermissions(op), err)
 		case AWSErrorInvalidInput:
 			return fmt.Errorf("invalid untag request for ENI %s: %w", eniID, err)
 		default:
@@ -456,13 +662,39 @@ func (c *defaultClient) UntagENI(ctx context.Context, eniID string, tagKeys []st
 	return nil
 }
 
+// backend returns the tag backend, EC2 unless ClientOptions.TagBackend chose
+// another, refusing every call when the client is read-only.
+func (c *defaultClient) backend() TagBackend {
+	var backend TagBackend = ec2TagBackend{client: c.ec2Client, sessions: c.sessions}
+	if c.tagBackend != nil {
+		backend = c.tagBackend
+	}
+	if c.readOnly {
+		return readOnlyBackend{TagBackend: backend}
+	}
+	return backend
+}
+
+// recordAudit writes a tag change to the audit log, attributed to the pod the
+// call is made for.
+func (c *defaultClient) recordAudit(ctx context.Context, e audit.Entry, err error) {
+	if id, ok := podIdentityFrom(ctx); ok {
+		e.Pod = id.Namespace + "/" + id.Name
+	}
+	c.auditLog.RecordCall(ctx, e, err)
+}
+
 func (c *defaultClient) doWithRetry(ctx context.Context, op string, maxAttempts int, call func(context.Context) error) error {
 	if maxAttempts < 1 {
 		maxAttempts = 1
 	}
 	var lastErr error
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		callErr := call(ctx)
+		callCtx := ctx
+		if c.debug {
+			callCtx = withAttempt(ctx, attempt+1)
+		}
+		callErr := call(callCtx)
 		if callErr == nil {
 			return nil
 		}
@@ -1,12 +1,18 @@
 package aws
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
+	"k8s-eni-tagger/pkg/audit"
+
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
@@ -45,6 +51,30 @@ func (m *mockEC2Client) DeleteTags(ctx context.Context, params *ec2.DeleteTagsIn
 	return args.Get(0).(*ec2.DeleteTagsOutput), args.Error(1)
 }
 
+func (m *mockEC2Client) DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error) {
+	args := m.Called(ctx, params, optFns)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ec2.DescribeSecurityGroupsOutput), args.Error(1)
+}
+
+func (m *mockEC2Client) DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error) {
+	args := m.Called(ctx, params, optFns)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ec2.DescribeInstancesOutput), args.Error(1)
+}
+
+func (m *mockEC2Client) DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error) {
+	args := m.Called(ctx, params, optFns)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ec2.DescribeVolumesOutput), args.Error(1)
+}
+
 type throttlingAPIError struct{}
 
 func (th throttlingAPIError) ErrorCode() string    { return "Throttling" }
@@ -115,6 +145,28 @@ func TestGetENIInfoByIP(t *testing.T) {
 			},
 			expectedError: "no ENI found for IP 10.0.0.3",
 		},
+		{
+			name: "Success - Skips Empty Tag Items",
+			ip:   "10.0.0.6",
+			mockSetup: func(m *mockEC2Client) {
+				m.On("DescribeNetworkInterfaces", ctx, mock.Anything, mock.Anything).Return(&ec2.DescribeNetworkInterfacesOutput{
+					NetworkInterfaces: []types.NetworkInterface{
+						{
+							NetworkInterfaceId: aws.String("eni-edge"),
+							TagSet: []types.Tag{
+								{},
+								{Key: aws.String(""), Value: aws.String("")},
+								{Key: aws.String("Name"), Value: aws.String("")},
+							},
+							PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{
+								{PrivateIpAddress: aws.String("10.0.0.6")},
+							},
+						},
+					},
+				}, nil)
+			},
+			expectedInfo: &ENIInfo{ID: "eni-edge", Tags: map[string]string{"Name": ""}},
+		},
 		{
 			name: "Success - Shared ENI (Multiple IPs)",
 			ip:   "10.0.0.4",
@@ -250,6 +302,38 @@ func TestTagENI_RetryOnThrottling(t *testing.T) {
 	mockClient.AssertExpectations(t)
 }
 
+func TestTagENI_AuditLog(t *testing.T) {
+	mockClient := new(mockEC2Client)
+	mockClient.On("CreateTags", mock.Anything, mock.Anything, mock.Anything).Return(&ec2.CreateTagsOutput{}, nil).Once()
+	mockClient.On("DeleteTags", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("boom")).Once()
+
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	c := &defaultClient{
+		ec2Client:   mockClient,
+		rateLimiter: rl,
+		auditLog:    audit.NewLog(&buf),
+	}
+
+	ctx := WithPodIdentity(context.Background(), "shop", "web")
+	require.NoError(t, c.TagENI(ctx, "eni-abc", map[string]string{"k": "v"}))
+	require.Error(t, c.UntagENI(ctx, "eni-abc", []string{"k"}))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2, "one record per call, after retries")
+	var created, deleted audit.Entry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &created))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &deleted))
+	assert.Equal(t, audit.OperationCreateTags, created.Operation)
+	assert.Equal(t, "shop/web", created.Pod)
+	assert.Equal(t, map[string]string{"k": "v"}, created.Tags)
+	assert.Equal(t, audit.ResultSuccess, created.Result)
+	assert.Equal(t, audit.OperationDeleteTags, deleted.Operation)
+	assert.Equal(t, audit.ResultError, deleted.Result)
+	assert.Contains(t, deleted.Error, "boom")
+}
+
 func TestTagENI_RetryStopsOnContextDeadline(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
 	defer cancel()
@@ -456,3 +540,237 @@ func TestNewClientWithEndpointOverride(t *testing.T) {
 		})
 	}
 }
+
+func TestENIInfoForeignTagKeys(t *testing.T) {
+	info := &ENIInfo{Tags: map[string]string{"b": "1", "a": "2", "managed": "3"}}
+	assert.Equal(t, []string{"a", "b"}, info.ForeignTagKeys(map[string]string{"managed": ""}))
+	assert.Empty(t, info.ForeignTagKeys(map[string]string{"a": "", "b": "", "managed": ""}))
+
+	var nilInfo *ENIInfo
+	assert.Nil(t, nilInfo.ForeignTagKeys(nil))
+}
+
+func TestENIInfoAttached(t *testing.T) {
+	assert.True(t, (&ENIInfo{}).Attached(), "unknown state is not gated")
+	assert.True(t, (&ENIInfo{Status: "in-use", AttachmentStatus: "attached"}).Attached())
+	assert.True(t, (&ENIInfo{Status: "in-use"}).Attached())
+	assert.False(t, (&ENIInfo{Status: "attaching", AttachmentStatus: "attaching"}).Attached())
+	assert.False(t, (&ENIInfo{Status: "in-use", AttachmentStatus: "attaching"}).Attached())
+	assert.False(t, (&ENIInfo{Status: "available"}).Attached())
+}
+
+func TestErrorCode(t *testing.T) {
+	apiErr := &smithy.GenericAPIError{Code: "UnauthorizedOperation", Message: "denied"}
+
+	assert.Equal(t, "UnauthorizedOperation", ErrorCode(fmt.Errorf("tag ENI eni-1: %w", apiErr)))
+	assert.Equal(t, "", ErrorCode(errors.New("plain error")))
+	assert.Equal(t, "", ErrorCode(nil))
+}
+
+type tagPolicyAPIError struct{}
+
+func (tagPolicyAPIError) ErrorCode() string { return "TagPolicyViolation" }
+func (tagPolicyAPIError) ErrorMessage() string {
+	return "The tag policy does not allow the specified value for the following tag key: 'CostCenter'. Policy p-95ouootqx1"
+}
+func (tagPolicyAPIError) ErrorFault() smithy.ErrorFault { return smithy.FaultClient }
+func (e tagPolicyAPIError) Error() string               { return e.ErrorCode() + ": " + e.ErrorMessage() }
+
+func TestTagENI_TagPolicyViolation(t *testing.T) {
+	ctx := context.Background()
+	mockClient := new(mockEC2Client)
+	mockClient.On("CreateTags", mock.Anything, mock.Anything, mock.Anything).Return(nil, tagPolicyAPIError{})
+
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+
+	c := &defaultClient{
+		ec2Client:   mockClient,
+		rateLimiter: rl,
+	}
+
+	err = c.TagENI(ctx, "eni-abc", map[string]string{"CostCenter": "nope"})
+	require.Error(t, err)
+	// Not retryable: a second attempt would be rejected the same way
+	mockClient.AssertNumberOfCalls(t, "CreateTags", 1)
+
+	var policyErr *TagPolicyViolationError
+	require.ErrorAs(t, err, &policyErr)
+	assert.Equal(t, "eni-abc", policyErr.ENIID)
+	assert.Equal(t, []string{"CostCenter"}, policyErr.Keys)
+	assert.Equal(t, "p-95ouootqx1", policyErr.PolicyID)
+	assert.Equal(t, "TagPolicyViolation", ErrorCode(err))
+}
+
+func TestLocateIPs(t *testing.T) {
+	ctx := context.TODO()
+	mockClient := new(mockEC2Client)
+	mockClient.On("DescribeNetworkInterfaces", ctx, mock.MatchedBy(func(input *ec2.DescribeNetworkInterfacesInput) bool {
+		return len(input.Filters) == 1 && len(input.Filters[0].Values) == 3
+	}), mock.Anything).Return(&ec2.DescribeNetworkInterfacesOutput{
+		NetworkInterfaces: []types.NetworkInterface{
+			{
+				NetworkInterfaceId: aws.String("eni-a"),
+				PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{
+					{PrivateIpAddress: aws.String("10.0.0.1")},
+					{PrivateIpAddress: aws.String("10.0.0.9")},
+				},
+			},
+			{
+				NetworkInterfaceId: aws.String("eni-b"),
+				PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String("10.0.0.2")}},
+			},
+		},
+	}, nil).Once()
+
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+	c := &defaultClient{ec2Client: mockClient, rateLimiter: rl}
+
+	located, err := c.LocateIPs(ctx, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"10.0.0.1": "eni-a", "10.0.0.2": "eni-b"}, located)
+	mockClient.AssertExpectations(t)
+
+	_, err = c.LocateIPs(ctx, make([]string, MaxLocateIPs+1))
+	assert.Error(t, err)
+}
+
+func TestGetENIInfoByIPs(t *testing.T) {
+	ctx := context.TODO()
+	mockClient := new(mockEC2Client)
+	firstPage := mock.MatchedBy(func(input *ec2.DescribeNetworkInterfacesInput) bool {
+		return input.NextToken == nil && len(input.Filters[0].Values) == 4
+	})
+	mockClient.On("DescribeNetworkInterfaces", ctx, firstPage, mock.Anything).Return(&ec2.DescribeNetworkInterfacesOutput{
+		NetworkInterfaces: []types.NetworkInterface{{
+			NetworkInterfaceId: aws.String("eni-a"),
+			SubnetId:           aws.String("subnet-1"),
+			TagSet:             []types.Tag{{Key: aws.String("team"), Value: aws.String("a")}},
+			PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{
+				{PrivateIpAddress: aws.String("10.0.0.1")},
+				{PrivateIpAddress: aws.String("10.0.0.2")},
+			},
+		}},
+		NextToken: aws.String("page-2"),
+	}, nil).Once()
+	mockClient.On("DescribeNetworkInterfaces", ctx, mock.MatchedBy(func(input *ec2.DescribeNetworkInterfacesInput) bool {
+		return aws.ToString(input.NextToken) == "page-2"
+	}), mock.Anything).Return(&ec2.DescribeNetworkInterfacesOutput{
+		NetworkInterfaces: []types.NetworkInterface{{
+			NetworkInterfaceId: aws.String("eni-b"),
+			InterfaceType:      types.NetworkInterfaceTypeBranch,
+			PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{{PrivateIpAddress: aws.String("10.0.0.3")}},
+		}},
+	}, nil).Once()
+
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+	c := &defaultClient{ec2Client: mockClient, rateLimiter: rl}
+
+	infos, err := c.GetENIInfoByIPs(ctx, []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"})
+	require.NoError(t, err)
+	mockClient.AssertExpectations(t)
+	require.Len(t, infos, 3)
+	assert.Same(t, infos["10.0.0.1"], infos["10.0.0.2"])
+	assert.Equal(t, "eni-a", infos["10.0.0.1"].ID)
+	assert.Equal(t, map[string]string{"team": "a"}, infos["10.0.0.1"].Tags)
+	assert.True(t, infos["10.0.0.1"].IsShared)
+	assert.Equal(t, "eni-b", infos["10.0.0.3"].ID)
+	assert.False(t, infos["10.0.0.3"].IsShared)
+	assert.NotContains(t, infos, "10.0.0.4")
+
+	_, err = c.GetENIInfoByIPs(ctx, make([]string, MaxLocateIPs+1))
+	assert.Error(t, err)
+}
+
+func TestLoadBalancerENIDescription(t *testing.T) {
+	tests := []struct {
+		hostname string
+		want     string
+		wantErr  bool
+	}{
+		{hostname: "k8s-shop-web-3a1b2c3d4e-0123456789abcdef.elb.us-west-2.amazonaws.com", want: "ELB net/k8s-shop-web-3a1b2c3d4e/*"},
+		{hostname: "a1b2c3d4e5f6-1234567890.us-west-2.elb.amazonaws.com", want: "ELB a1b2c3d4e5f6"},
+		{hostname: "internal-a1b2c3d4e5f6-1234567890.us-west-2.elb.amazonaws.com", want: "ELB a1b2c3d4e5f6"},
+		{hostname: "a1b2c3d4e5f6-0123456789abcdef.elb.cn-north-1.amazonaws.com.cn", want: "ELB net/a1b2c3d4e5f6/*"},
+		{hostname: "web.example.com", wantErr: true},
+		{hostname: "10.0.0.1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.hostname, func(t *testing.T) {
+			got, err := LoadBalancerENIDescription(tt.hostname)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFindLoadBalancerENIs(t *testing.T) {
+	ctx := context.TODO()
+	mockClient := new(mockEC2Client)
+	mockClient.On("DescribeNetworkInterfaces", ctx, mock.MatchedBy(func(input *ec2.DescribeNetworkInterfacesInput) bool {
+		return aws.ToString(input.Filters[0].Name) == "description" && input.Filters[0].Values[0] == "ELB net/k8s-shop-web-3a1b2c3d4e/*"
+	}), mock.Anything).Return(&ec2.DescribeNetworkInterfacesOutput{
+		NetworkInterfaces: []types.NetworkInterface{
+			{NetworkInterfaceId: aws.String("eni-az1"), Description: aws.String("ELB net/k8s-shop-web-3a1b2c3d4e/0123456789abcdef")},
+			{NetworkInterfaceId: aws.String("eni-az2"), Description: aws.String("ELB net/k8s-shop-web-3a1b2c3d4e/0123456789abcdef")},
+		},
+	}, nil).Once()
+
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+	c := &defaultClient{ec2Client: mockClient, rateLimiter: rl}
+
+	infos, err := c.FindLoadBalancerENIs(ctx, "k8s-shop-web-3a1b2c3d4e-0123456789abcdef.elb.us-west-2.amazonaws.com")
+	require.NoError(t, err)
+	mockClient.AssertExpectations(t)
+	require.Len(t, infos, 2)
+	assert.Equal(t, "eni-az1", infos[0].ID)
+	assert.Equal(t, "eni-az2", infos[1].ID)
+}
+
+func TestInstanceIDFromProviderID(t *testing.T) {
+	id, err := InstanceIDFromProviderID("aws:///us-west-2a/i-0123456789abcdef0")
+	require.NoError(t, err)
+	assert.Equal(t, "i-0123456789abcdef0", id)
+
+	for _, providerID := range []string{"", "aws:///us-west-2a/", "gce://project/zone/node-1", "i-0123456789abcdef0"} {
+		_, err := InstanceIDFromProviderID(providerID)
+		assert.Error(t, err, providerID)
+	}
+}
+
+func TestGetPrimaryENIInfo(t *testing.T) {
+	ctx := context.TODO()
+	mockClient := new(mockEC2Client)
+	mockClient.On("DescribeNetworkInterfaces", ctx, mock.MatchedBy(func(input *ec2.DescribeNetworkInterfacesInput) bool {
+		return input.Filters[0].Values[0] == "i-0123456789abcdef0" && aws.ToString(input.Filters[1].Name) == "attachment.device-index"
+	}), mock.Anything).Return(&ec2.DescribeNetworkInterfacesOutput{
+		NetworkInterfaces: []types.NetworkInterface{{
+			NetworkInterfaceId: aws.String("eni-primary"),
+			PrivateIpAddresses: []types.NetworkInterfacePrivateIpAddress{
+				{PrivateIpAddress: aws.String("10.0.0.10")},
+				{PrivateIpAddress: aws.String("10.0.0.11")},
+			},
+		}},
+	}, nil).Once()
+	mockClient.On("DescribeNetworkInterfaces", ctx, mock.Anything, mock.Anything).Return(&ec2.DescribeNetworkInterfacesOutput{}, nil).Once()
+
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+	c := &defaultClient{ec2Client: mockClient, rateLimiter: rl}
+
+	info, err := c.GetPrimaryENIInfo(ctx, "i-0123456789abcdef0")
+	require.NoError(t, err)
+	assert.Equal(t, "eni-primary", info.ID)
+	assert.True(t, info.IsShared, "the primary ENI's secondary IPs make it shared; callers decide ownership")
+
+	_, err = c.GetPrimaryENIInfo(ctx, "i-terminated")
+	assert.Error(t, err)
+	mockClient.AssertExpectations(t)
+}
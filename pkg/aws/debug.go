@@ -0,0 +1,126 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// redactedHeaders are never written to debug logs.
+var redactedHeaders = map[string]bool{
+	"Authorization":        true,
+	"X-Amz-Security-Token": true,
+	"Cookie":               true,
+}
+
+// attemptKey carries the doWithRetry attempt number into the SDK middleware.
+type attemptKey struct{}
+
+// paramsKey carries the serialized operation input from the Initialize step to
+// the Deserialize step of the same call.
+type paramsKey struct{}
+
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+func attemptFrom(ctx context.Context) int {
+	if n, ok := ctx.Value(attemptKey{}).(int); ok {
+		return n
+	}
+	return 1
+}
+
+// addDebugLogging registers middleware that logs one line per HTTP exchange:
+// operation, retry attempt, endpoint, status, request ID, latency, error code,
+// the operation parameters, and the request headers with credentials redacted.
+func addDebugLogging(stack *middleware.Stack) error {
+	err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc("ENITaggerDebugParams",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			ctx = middleware.WithStackValue(ctx, paramsKey{}, compactParams(in.Parameters))
+			return next.HandleInitialize(ctx, in)
+		}), middleware.After)
+	if err != nil {
+		return err
+	}
+
+	return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("ENITaggerDebugHTTP",
+		func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+			start := time.Now()
+			out, metadata, err := next.HandleDeserialize(ctx, in)
+
+			var b strings.Builder
+			fmt.Fprintf(&b, "[AWSClient] debug op=%s attempt=%d", awsmiddleware.GetOperationName(ctx), attemptFrom(ctx))
+			if req, ok := in.Request.(*smithyhttp.Request); ok {
+				fmt.Fprintf(&b, " method=%s url=%s://%s%s", req.Method, req.URL.Scheme, req.URL.Host, req.URL.Path)
+			}
+			status := 0
+			if resp, ok := out.RawResponse.(*smithyhttp.Response); ok {
+				status = resp.StatusCode
+			}
+			requestID, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+			fmt.Fprintf(&b, " status=%d requestID=%s latency=%s", status, requestID, time.Since(start).Round(time.Microsecond))
+			if err != nil {
+				fmt.Fprintf(&b, " code=%s err=%q", ErrorCode(err), err.Error())
+			}
+			if params, ok := middleware.GetStackValue(ctx, paramsKey{}).(string); ok {
+				fmt.Fprintf(&b, " params=%s", params)
+			}
+			if req, ok := in.Request.(*smithyhttp.Request); ok {
+				fmt.Fprintf(&b, " headers=%s", sanitizeHeaders(req.Header))
+			}
+			log.Print(b.String())
+
+			return out, metadata, err
+		}), middleware.Before)
+}
+
+// compactParams renders an operation input as JSON without the unset fields.
+func compactParams(params any) string {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Sprintf("%q", fmt.Sprintf("unserializable input: %v", err))
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return string(raw)
+	}
+	for k, v := range fields {
+		if v == nil {
+			delete(fields, k)
+		}
+	}
+	compact, err := json.Marshal(fields)
+	if err != nil {
+		return string(raw)
+	}
+	return string(compact)
+}
+
+// sanitizeHeaders renders headers in a stable order with credentials redacted.
+func sanitizeHeaders(h http.Header) string {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := strings.Join(h[k], ",")
+		if redactedHeaders[http.CanonicalHeaderKey(k)] {
+			v = "REDACTED"
+		}
+		parts = append(parts, fmt.Sprintf("%s=%q", k, v))
+	}
+	return "{" + strings.Join(parts, " ") + "}"
+}
@@ -0,0 +1,75 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugLogging(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`<Response><Errors><Error><Code>RequestLimitExceeded</Code><Message>slow down</Message></Error></Errors><RequestID>req-1</RequestID></Response>`))
+			return
+		}
+		w.Header().Set("X-Amzn-Requestid", "req-2")
+		_, _ = w.Write([]byte(`<DescribeNetworkInterfacesResponse><requestId>req-2</requestId><networkInterfaceSet><item><networkInterfaceId>eni-1</networkInterfaceId></item></networkInterfaceSet></DescribeNetworkInterfacesResponse>`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("AWS_ENDPOINT_URL", srv.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "session-token-value")
+
+	var buf bytes.Buffer
+	orig := log.Writer()
+	log.SetOutput(&buf)
+	defer log.SetOutput(orig)
+
+	c, err := NewClientWithOptions(context.Background(), ClientOptions{RateLimit: DefaultRateLimitConfig(), DebugLogging: true})
+	require.NoError(t, err)
+	info, err := c.GetENIInfoByIP(context.Background(), "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "eni-1", info.ID)
+
+	var debugLines []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if strings.Contains(line, "[AWSClient] debug") {
+			debugLines = append(debugLines, line)
+		}
+	}
+	require.Len(t, debugLines, 2)
+
+	assert.Contains(t, debugLines[0], "op=DescribeNetworkInterfaces attempt=1")
+	assert.Contains(t, debugLines[0], "status=503 requestID=req-1")
+	assert.Contains(t, debugLines[0], "code=RequestLimitExceeded")
+	assert.Contains(t, debugLines[1], "attempt=2")
+	assert.Contains(t, debugLines[1], "status=200 requestID=req-2")
+	assert.Contains(t, debugLines[1], `params={"Filters":[{"Name":"private-ip-address","Values":["10.0.0.1"]}]}`)
+	assert.Contains(t, debugLines[1], `Authorization="REDACTED"`)
+	assert.Contains(t, debugLines[1], `X-Amz-Security-Token="REDACTED"`)
+	assert.NotContains(t, buf.String(), "session-token-value")
+	assert.NotContains(t, buf.String(), "Signature=")
+}
+
+func TestSanitizeHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "AWS4-HMAC-SHA256 Credential=AKID/...")
+	h.Set("Content-Type", "application/x-www-form-urlencoded")
+	h.Set("X-Amz-Security-Token", "token")
+
+	assert.Equal(t, `{Authorization="REDACTED" Content-Type="application/x-www-form-urlencoded" X-Amz-Security-Token="REDACTED"}`, sanitizeHeaders(h))
+}
@@ -0,0 +1,328 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// ErrCredentialsMisconfigured marks credential or region problems that will
+// not resolve on retry (e.g. a broken IRSA setup), so startup should fail.
+var ErrCredentialsMisconfigured = errors.New("AWS credentials misconfigured")
+
+// Credential providers reported by DiagnoseCredentials.
+const (
+	CredentialProviderIRSA            = "irsa"
+	CredentialProviderStatic          = "static"
+	CredentialProviderContainer       = "container"
+	CredentialProviderInstanceProfile = "instance-profile"
+	CredentialProviderSSO             = "sso"
+	CredentialProviderProcess         = "process"
+	CredentialProviderUnknown         = "unknown"
+)
+
+// Environment variables set by the EKS pod identity webhook for IRSA.
+const (
+	roleARNEnv              = "AWS_ROLE_ARN"
+	webIdentityTokenFileEnv = "AWS_WEB_IDENTITY_TOKEN_FILE"
+)
+
+// irsaAudience is the audience the EKS pod identity webhook requests by
+// default; some China-region setups use the partition's STS host instead.
+const (
+	irsaAudience      = "sts.amazonaws.com"
+	irsaAudienceChina = "sts.amazonaws.com.cn"
+)
+
+// credentialSourceNames names the SDK credential sources for logs.
+var credentialSourceNames = map[aws.CredentialSource]string{
+	aws.CredentialSourceCode:                 "code",
+	aws.CredentialSourceEnvVars:              "env",
+	aws.CredentialSourceEnvVarsSTSWebIDToken: "env-web-identity-token",
+	aws.CredentialSourceSTSAssumeRole:        "sts-assume-role",
+	aws.CredentialSourceSTSAssumeRoleWebID:   "sts-assume-role-web-identity",
+	aws.CredentialSourceProfile:              "profile",
+	aws.CredentialSourceProfileSourceProfile: "profile-source-profile",
+	aws.CredentialSourceProfileNamedProvider: "profile-credential-source",
+	aws.CredentialSourceProfileSTSWebIDToken: "profile-web-identity-token",
+	aws.CredentialSourceProfileSSO:           "profile-sso",
+	aws.CredentialSourceSSO:                  "sso",
+	aws.CredentialSourceProfileSSOLegacy:     "profile-sso-legacy",
+	aws.CredentialSourceSSOLegacy:            "sso-legacy",
+	aws.CredentialSourceProfileProcess:       "profile-process",
+	aws.CredentialSourceProcess:              "process",
+	aws.CredentialSourceHTTP:                 "container-endpoint",
+	aws.CredentialSourceIMDS:                 "imds",
+	aws.CredentialSourceProfileLogin:         "profile-login",
+	aws.CredentialSourceLogin:                "login",
+	aws.CredentialSourceSTSAssumeRoleSaml:    "sts-assume-role-saml",
+	aws.CredentialSourceSTSFederationToken:   "sts-federation-token",
+	aws.CredentialSourceSTSSessionToken:      "sts-session-token",
+	aws.CredentialSourceUndefined:            "undefined",
+}
+
+// CredentialDiagnostics describes how the SDK resolved credentials and region.
+type CredentialDiagnostics struct {
+	// Provider is the effective credential provider (see CredentialProvider*).
+	Provider string
+	// Profile is the shared config profile requested, if any.
+	Profile string
+	// Chain lists the SDK credential sources in resolution order.
+	Chain  []string
+	Region string
+	// RegionSource is AWS_REGION, AWS_DEFAULT_REGION, "shared config" or "unset".
+	RegionSource string
+	Partition    string
+	// RoleARN, TokenFile and TokenSubject are set when IRSA variables are present.
+	RoleARN      string
+	TokenFile    string
+	TokenSubject string
+	// STSEndpoint is where web identity credentials are exchanged.
+	STSEndpoint ResolvedEndpoint
+	// Warnings are suspicious but non-fatal findings.
+	Warnings []string
+}
+
+// DiagnoseCredentials resolves the SDK credential chain and region the same way
+// the EC2 client does and retrieves credentials once, so a broken IRSA setup is
+// reported at startup instead of on the first Describe call. Errors wrapping
+// ErrCredentialsMisconfigured carry a hint on how to fix the setup; other
+// errors (e.g. STS unreachable) may be transient. The returned diagnostics are
+// populated as far as resolution got, even on error. A non-empty profile is
+// resolved from the shared config files, as with ClientOptions.Profile.
+func DiagnoseCredentials(ctx context.Context, profile string) (*CredentialDiagnostics, error) {
+	d := &CredentialDiagnostics{
+		Profile:   profile,
+		RoleARN:   strings.TrimSpace(os.Getenv(roleARNEnv)),
+		TokenFile: strings.TrimSpace(os.Getenv(webIdentityTokenFileEnv)),
+	}
+	irsa := d.RoleARN != "" || d.TokenFile != ""
+
+	if irsa {
+		if os.Getenv("AWS_ACCESS_KEY_ID") != "" {
+			d.Warnings = append(d.Warnings, "AWS_ACCESS_KEY_ID is set and takes precedence over IRSA; the service account role is not used")
+		} else if err := d.checkWebIdentity(time.Now()); err != nil {
+			return d, err
+		}
+	}
+	if strings.EqualFold(os.Getenv("AWS_STS_REGIONAL_ENDPOINTS"), "legacy") {
+		d.Warnings = append(d.Warnings, "AWS_STS_REGIONAL_ENDPOINTS=legacy is ignored; the regional STS endpoint is always used")
+	}
+
+	cfg, err := loadSDKConfig(ctx, profile)
+	if err != nil {
+		if irsa {
+			return d, fmt.Errorf("%w: loading SDK config: %v", ErrCredentialsMisconfigured, err)
+		}
+		return d, fmt.Errorf("unable to load SDK config: %w", err)
+	}
+
+	d.Region = cfg.Region
+	d.RegionSource = regionSource(cfg.Region)
+	if d.Region == "" {
+		return d, fmt.Errorf("%w: no AWS region configured; set AWS_REGION (or a region in the shared config profile)", ErrCredentialsMisconfigured)
+	}
+	d.Partition = PartitionForRegion(d.Region)
+
+	if d.RoleARN != "" {
+		if err := CheckARNPartition(d.RoleARN, d.Region); err != nil {
+			return d, fmt.Errorf("%w: %v; use a role from the region's partition or fix AWS_REGION", ErrCredentialsMisconfigured, err)
+		}
+	}
+
+	d.STSEndpoint, err = ResolveEndpoint("STS", "")
+	if err != nil {
+		return d, fmt.Errorf("%w: %v", ErrCredentialsMisconfigured, err)
+	}
+	if d.STSEndpoint.URL == "" {
+		d.STSEndpoint.URL = fmt.Sprintf("https://sts.%s.%s", d.Region, PartitionDNSSuffix(d.Partition))
+	}
+
+	var sources []aws.CredentialSource
+	if p, ok := cfg.Credentials.(aws.CredentialProviderSource); ok {
+		sources = p.ProviderSources()
+	}
+	d.Provider, d.Chain = describeCredentialSources(sources)
+
+	if cfg.Credentials == nil {
+		return d, fmt.Errorf("%w: no credential provider resolved", ErrCredentialsMisconfigured)
+	}
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		if d.Provider == CredentialProviderIRSA {
+			if hint := webIdentityErrorHint(ErrorCode(err), d); hint != "" {
+				return d, fmt.Errorf("%w: %s: %v", ErrCredentialsMisconfigured, hint, err)
+			}
+		}
+		return d, fmt.Errorf("unable to retrieve %s credentials: %w", d.Provider, err)
+	}
+	return d, nil
+}
+
+// checkWebIdentity validates the IRSA variables and projected token without
+// calling STS.
+func (d *CredentialDiagnostics) checkWebIdentity(now time.Time) error {
+	switch {
+	case d.RoleARN == "":
+		return fmt.Errorf("%w: %s is set but %s is not; annotate the service account with eks.amazonaws.com/role-arn and recreate the pod",
+			ErrCredentialsMisconfigured, webIdentityTokenFileEnv, roleARNEnv)
+	case d.TokenFile == "":
+		return fmt.Errorf("%w: %s is set but %s is not; check that the EKS pod identity webhook mutated the pod (it must be recreated after annotating the service account)",
+			ErrCredentialsMisconfigured, roleARNEnv, webIdentityTokenFileEnv)
+	}
+
+	roleARN, err := arn.Parse(d.RoleARN)
+	if err != nil || roleARN.Service != "iam" || !strings.HasPrefix(roleARN.Resource, "role/") {
+		return fmt.Errorf("%w: %s=%q is not an IAM role ARN (arn:<partition>:iam::<account>:role/<name>); fix the eks.amazonaws.com/role-arn annotation",
+			ErrCredentialsMisconfigured, roleARNEnv, d.RoleARN)
+	}
+
+	raw, err := os.ReadFile(d.TokenFile)
+	if err != nil {
+		return fmt.Errorf("%w: cannot read web identity token %s: %v; check the projected service account token volume (aws-iam-token) is mounted",
+			ErrCredentialsMisconfigured, d.TokenFile, err)
+	}
+	token := strings.TrimSpace(string(raw))
+	if token == "" {
+		return fmt.Errorf("%w: web identity token %s is empty", ErrCredentialsMisconfigured, d.TokenFile)
+	}
+
+	claims, err := parseTokenClaims(token)
+	if err != nil {
+		return fmt.Errorf("%w: web identity token %s is not a valid JWT: %v", ErrCredentialsMisconfigured, d.TokenFile, err)
+	}
+	d.TokenSubject = claims.Subject
+	if claims.Expiry > 0 {
+		if exp := time.Unix(claims.Expiry, 0); now.After(exp) {
+			return fmt.Errorf("%w: web identity token %s expired at %s; the kubelet refreshes projected tokens, so the file is likely a stale copy",
+				ErrCredentialsMisconfigured, d.TokenFile, exp.UTC().Format(time.RFC3339))
+		}
+	}
+	if !claims.hasAudience(irsaAudience) && !claims.hasAudience(irsaAudienceChina) {
+		d.Warnings = append(d.Warnings, fmt.Sprintf("web identity token audience %v does not include %s; the IAM OIDC provider must list the token's audience", []string(claims.Audience), irsaAudience))
+	}
+	return nil
+}
+
+// webIdentityErrorHint explains STS errors that point at IRSA setup rather than
+// a transient failure. It returns "" for errors worth retrying.
+func webIdentityErrorHint(code string, d *CredentialDiagnostics) string {
+	subject := d.TokenSubject
+	if subject == "" {
+		subject = "system:serviceaccount:<namespace>:<name>"
+	}
+	switch code {
+	case "AccessDenied":
+		return fmt.Sprintf("STS denied AssumeRoleWithWebIdentity for %s; the role trust policy must allow the cluster OIDC provider with condition sub=%s", d.RoleARN, subject)
+	case "InvalidIdentityToken":
+		return "STS rejected the web identity token; register the cluster OIDC issuer as an IAM identity provider with audience " + irsaAudience
+	case "ExpiredTokenException":
+		return "STS reports the web identity token as expired; check the projected token is being refreshed"
+	default:
+		return ""
+	}
+}
+
+// tokenClaims holds the JWT claims relevant to IRSA.
+type tokenClaims struct {
+	Subject  string   `json:"sub"`
+	Audience audience `json:"aud"`
+	Expiry   int64    `json:"exp"`
+}
+
+func (c tokenClaims) hasAudience(want string) bool {
+	for _, a := range c.Audience {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
+
+// audience accepts the JWT "aud" claim as either a string or a list.
+type audience []string
+
+func (a *audience) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(b, &list); err != nil {
+		return err
+	}
+	*a = list
+	return nil
+}
+
+// parseTokenClaims decodes the JWT payload without verifying the signature;
+// STS does the verification.
+func parseTokenClaims(token string) (tokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return tokenClaims{}, fmt.Errorf("expected 3 dot-separated parts, got %d", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return tokenClaims{}, fmt.Errorf("decoding payload: %w", err)
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return tokenClaims{}, fmt.Errorf("decoding claims: %w", err)
+	}
+	return claims, nil
+}
+
+// describeCredentialSources maps the SDK credential sources to a provider
+// name and a printable chain.
+func describeCredentialSources(sources []aws.CredentialSource) (string, []string) {
+	chain := make([]string, 0, len(sources))
+	provider := CredentialProviderUnknown
+	for _, s := range sources {
+		name, ok := credentialSourceNames[s]
+		if !ok {
+			name = fmt.Sprintf("source-%d", s)
+		}
+		chain = append(chain, name)
+
+		switch s {
+		case aws.CredentialSourceEnvVarsSTSWebIDToken, aws.CredentialSourceProfileSTSWebIDToken:
+			provider = CredentialProviderIRSA
+		case aws.CredentialSourceHTTP:
+			provider = CredentialProviderContainer
+		case aws.CredentialSourceIMDS:
+			provider = CredentialProviderInstanceProfile
+		case aws.CredentialSourceSSO, aws.CredentialSourceSSOLegacy:
+			provider = CredentialProviderSSO
+		case aws.CredentialSourceProcess:
+			provider = CredentialProviderProcess
+		case aws.CredentialSourceEnvVars, aws.CredentialSourceProfile, aws.CredentialSourceCode:
+			if provider == CredentialProviderUnknown {
+				provider = CredentialProviderStatic
+			}
+		}
+	}
+	return provider, chain
+}
+
+// regionSource reports where the SDK most likely took region from.
+func regionSource(region string) string {
+	switch {
+	case os.Getenv("AWS_REGION") != "":
+		return "AWS_REGION"
+	case os.Getenv("AWS_DEFAULT_REGION") != "":
+		return "AWS_DEFAULT_REGION"
+	case region != "":
+		return "shared config"
+	default:
+		return "unset"
+	}
+}
@@ -0,0 +1,192 @@
+package aws
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// isolateAWSEnv clears the AWS variables the SDK reads so tests don't pick up
+// the developer's credentials, profiles or IMDS.
+func isolateAWSEnv(t *testing.T) {
+	t.Helper()
+	for _, key := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE",
+		"AWS_REGION", "AWS_DEFAULT_REGION", roleARNEnv, webIdentityTokenFileEnv,
+		"AWS_ENDPOINT_URL", "AWS_ENDPOINT_URL_STS", "AWS_STS_REGIONAL_ENDPOINTS",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "AWS_CONTAINER_CREDENTIALS_FULL_URI",
+	} {
+		t.Setenv(key, "")
+	}
+	dir := t.TempDir()
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+}
+
+// writeToken writes an unsigned JWT with the given claims JSON.
+func writeToken(t *testing.T, claims string) string {
+	t.Helper()
+	enc := base64.RawURLEncoding
+	token := enc.EncodeToString([]byte(`{"alg":"RS256"}`)) + "." + enc.EncodeToString([]byte(claims)) + ".sig"
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte(token), 0o600))
+	return path
+}
+
+func TestDiagnoseCredentialsStatic(t *testing.T) {
+	isolateAWSEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "cn-north-1")
+
+	d, err := DiagnoseCredentials(context.Background(), "")
+	require.NoError(t, err)
+	assert.Equal(t, CredentialProviderStatic, d.Provider)
+	assert.Equal(t, []string{"env"}, d.Chain)
+	assert.Equal(t, "cn-north-1", d.Region)
+	assert.Equal(t, "AWS_REGION", d.RegionSource)
+	assert.Equal(t, "aws-cn", d.Partition)
+	assert.Equal(t, "https://sts.cn-north-1.amazonaws.com.cn", d.STSEndpoint.URL)
+	assert.Empty(t, d.Warnings)
+}
+
+func TestDiagnoseCredentialsNoRegion(t *testing.T) {
+	isolateAWSEnv(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	d, err := DiagnoseCredentials(context.Background(), "")
+	require.ErrorIs(t, err, ErrCredentialsMisconfigured)
+	assert.Contains(t, err.Error(), "no AWS region configured")
+	assert.Equal(t, "unset", d.RegionSource)
+}
+
+func TestDiagnoseCredentialsRolePartitionMismatch(t *testing.T) {
+	isolateAWSEnv(t)
+	t.Setenv("AWS_REGION", "cn-north-1")
+	t.Setenv(roleARNEnv, "arn:aws:iam::123456789012:role/eni-tagger")
+	t.Setenv(webIdentityTokenFileEnv, writeToken(t, `{"aud":"sts.amazonaws.com"}`))
+
+	d, err := DiagnoseCredentials(context.Background(), "")
+	require.ErrorIs(t, err, ErrCredentialsMisconfigured)
+	assert.Contains(t, err.Error(), "region cn-north-1 is in partition aws-cn")
+	assert.Equal(t, PartitionChina, d.Partition)
+}
+
+func TestDiagnoseCredentialsIRSA(t *testing.T) {
+	validClaims := fmt.Sprintf(`{"sub":"system:serviceaccount:kube-system:k8s-eni-tagger","aud":["sts.amazonaws.com"],"exp":%d}`, time.Now().Add(time.Hour).Unix())
+
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		wantError error
+		wantText  string
+	}{
+		{
+			name:   "credentials retrieved",
+			status: http.StatusOK,
+			body: `<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>
+<AccessKeyId>ASIAEXAMPLE</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><SessionToken>token</SessionToken>
+<Expiration>2099-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleWithWebIdentityResult></AssumeRoleWithWebIdentityResponse>`,
+		},
+		{
+			name:      "trust policy rejects the service account",
+			status:    http.StatusForbidden,
+			body:      `<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code><Message>Not authorized to perform sts:AssumeRoleWithWebIdentity</Message></Error><RequestId>r1</RequestId></ErrorResponse>`,
+			wantError: ErrCredentialsMisconfigured,
+			wantText:  "sub=system:serviceaccount:kube-system:k8s-eni-tagger",
+		},
+		{
+			name:      "OIDC provider not registered",
+			status:    http.StatusBadRequest,
+			body:      `<ErrorResponse><Error><Type>Sender</Type><Code>InvalidIdentityToken</Code><Message>No OpenIDConnect provider found</Message></Error><RequestId>r2</RequestId></ErrorResponse>`,
+			wantError: ErrCredentialsMisconfigured,
+			wantText:  "register the cluster OIDC issuer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/xml")
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			isolateAWSEnv(t)
+			t.Setenv("AWS_REGION", "us-east-1")
+			t.Setenv("AWS_ENDPOINT_URL_STS", srv.URL)
+			t.Setenv(roleARNEnv, "arn:aws:iam::123456789012:role/eni-tagger")
+			t.Setenv(webIdentityTokenFileEnv, writeToken(t, validClaims))
+
+			d, err := DiagnoseCredentials(context.Background(), "")
+			assert.Equal(t, CredentialProviderIRSA, d.Provider)
+			assert.Equal(t, srv.URL, d.STSEndpoint.URL)
+			assert.Equal(t, "system:serviceaccount:kube-system:k8s-eni-tagger", d.TokenSubject)
+			if tt.wantError == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.True(t, errors.Is(err, tt.wantError), "got %v", err)
+			assert.Contains(t, err.Error(), tt.wantText)
+		})
+	}
+}
+
+func TestCheckWebIdentity(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	valid := writeToken(t, fmt.Sprintf(`{"sub":"system:serviceaccount:ns:sa","aud":"sts.amazonaws.com","exp":%d}`, now.Add(time.Hour).Unix()))
+
+	tests := []struct {
+		name        string
+		roleARN     string
+		tokenFile   string
+		wantError   string
+		wantWarning string
+	}{
+		{name: "valid", roleARN: "arn:aws:iam::123456789012:role/r", tokenFile: valid},
+		{name: "role missing", tokenFile: valid, wantError: "AWS_ROLE_ARN is not"},
+		{name: "token file missing", roleARN: "arn:aws:iam::123456789012:role/r", wantError: "AWS_WEB_IDENTITY_TOKEN_FILE is not"},
+		{name: "not a role ARN", roleARN: "arn:aws:iam::123456789012:user/u", tokenFile: valid, wantError: "is not an IAM role ARN"},
+		{name: "unreadable token", roleARN: "arn:aws:iam::123456789012:role/r", tokenFile: "/nonexistent/token", wantError: "cannot read web identity token"},
+		{name: "not a JWT", roleARN: "arn:aws:iam::123456789012:role/r", tokenFile: func() string {
+			path := filepath.Join(t.TempDir(), "token")
+			require.NoError(t, os.WriteFile(path, []byte("opaque"), 0o600))
+			return path
+		}(), wantError: "not a valid JWT"},
+		{name: "expired", roleARN: "arn:aws:iam::123456789012:role/r", tokenFile: writeToken(t, fmt.Sprintf(`{"exp":%d}`, now.Add(-time.Minute).Unix())), wantError: "expired at"},
+		{name: "unexpected audience", roleARN: "arn:aws:iam::123456789012:role/r", tokenFile: writeToken(t, `{"aud":["vault"]}`), wantWarning: "does not include sts.amazonaws.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &CredentialDiagnostics{RoleARN: tt.roleARN, TokenFile: tt.tokenFile}
+			err := d.checkWebIdentity(now)
+			if tt.wantError != "" {
+				require.ErrorIs(t, err, ErrCredentialsMisconfigured)
+				assert.Contains(t, err.Error(), tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			if tt.wantWarning != "" {
+				require.Len(t, d.Warnings, 1)
+				assert.Contains(t, d.Warnings[0], tt.wantWarning)
+			} else {
+				assert.Empty(t, d.Warnings)
+			}
+		})
+	}
+}
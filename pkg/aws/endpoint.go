@@ -0,0 +1,97 @@
+package aws
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Endpoint override sources, in order of precedence.
+const (
+	EndpointSourceFlag       = "flag"
+	EndpointSourceServiceEnv = "service env"
+	EndpointSourceGlobalEnv  = "AWS_ENDPOINT_URL"
+	EndpointSourceDefault    = "default"
+)
+
+// ResolvedEndpoint is the endpoint a service client will use. URL is empty when
+// the SDK resolves the regional endpoint itself.
+type ResolvedEndpoint struct {
+	URL    string
+	Source string
+	// EnvVar names the variable the URL came from, if any.
+	EnvVar string
+}
+
+// String describes the endpoint for logs.
+func (e ResolvedEndpoint) String() string {
+	if e.URL == "" {
+		return "SDK default for region"
+	}
+	if e.EnvVar != "" {
+		return fmt.Sprintf("%s (from %s)", e.URL, e.EnvVar)
+	}
+	return fmt.Sprintf("%s (from %s)", e.URL, e.Source)
+}
+
+// ServiceEndpointEnvVar returns the SDK's service-specific endpoint variable
+// for a service ID, e.g. "EC2" -> AWS_ENDPOINT_URL_EC2.
+func ServiceEndpointEnvVar(serviceID string) string {
+	return "AWS_ENDPOINT_URL_" + strings.ToUpper(strings.ReplaceAll(serviceID, " ", "_"))
+}
+
+// ResolveEndpoint picks the endpoint for a service: an explicit override (from
+// a flag), then AWS_ENDPOINT_URL_<SERVICE>, then AWS_ENDPOINT_URL. Environment
+// overrides are skipped when AWS_IGNORE_CONFIGURED_ENDPOINT_URLS is true, as
+// in the SDK. Every URL is validated so a typo fails at startup instead of on
+// the first API call.
+func ResolveEndpoint(serviceID, override string) (ResolvedEndpoint, error) {
+	if override != "" {
+		if err := ValidateEndpoint(override); err != nil {
+			return ResolvedEndpoint{}, fmt.Errorf("invalid %s endpoint override: %w", serviceID, err)
+		}
+		return ResolvedEndpoint{URL: override, Source: EndpointSourceFlag}, nil
+	}
+
+	if ignore, _ := strconv.ParseBool(os.Getenv("AWS_IGNORE_CONFIGURED_ENDPOINT_URLS")); ignore {
+		return ResolvedEndpoint{Source: EndpointSourceDefault}, nil
+	}
+
+	serviceVar := ServiceEndpointEnvVar(serviceID)
+	for _, candidate := range []struct{ envVar, source string }{
+		{serviceVar, EndpointSourceServiceEnv},
+		{"AWS_ENDPOINT_URL", EndpointSourceGlobalEnv},
+	} {
+		value := strings.TrimSpace(os.Getenv(candidate.envVar))
+		if value == "" {
+			continue
+		}
+		if err := ValidateEndpoint(value); err != nil {
+			return ResolvedEndpoint{}, fmt.Errorf("invalid %s: %w", candidate.envVar, err)
+		}
+		return ResolvedEndpoint{URL: value, Source: candidate.source, EnvVar: candidate.envVar}, nil
+	}
+
+	return ResolvedEndpoint{Source: EndpointSourceDefault}, nil
+}
+
+// ValidateEndpoint checks that raw is an absolute http(s) URL without query or
+// fragment, which is what the SDK expects as a base endpoint.
+func ValidateEndpoint(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("%q is not a URL: %w", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q must use http or https", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("%q must not have a query or fragment", raw)
+	}
+	return nil
+}
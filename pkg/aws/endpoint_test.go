@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveEndpoint(t *testing.T) {
+	tests := []struct {
+		name      string
+		override  string
+		env       map[string]string
+		want      ResolvedEndpoint
+		wantError string
+	}{
+		{
+			name: "SDK default when nothing is set",
+			want: ResolvedEndpoint{Source: EndpointSourceDefault},
+		},
+		{
+			name: "global env",
+			env:  map[string]string{"AWS_ENDPOINT_URL": "http://localhost:4566"},
+			want: ResolvedEndpoint{URL: "http://localhost:4566", Source: EndpointSourceGlobalEnv, EnvVar: "AWS_ENDPOINT_URL"},
+		},
+		{
+			name: "service env wins over global env",
+			env: map[string]string{
+				"AWS_ENDPOINT_URL":     "http://localhost:4566",
+				"AWS_ENDPOINT_URL_EC2": "https://ec2.vpce.example.com",
+			},
+			want: ResolvedEndpoint{URL: "https://ec2.vpce.example.com", Source: EndpointSourceServiceEnv, EnvVar: "AWS_ENDPOINT_URL_EC2"},
+		},
+		{
+			name:     "flag wins over env",
+			override: "https://flag.example.com",
+			env:      map[string]string{"AWS_ENDPOINT_URL_EC2": "https://ec2.vpce.example.com"},
+			want:     ResolvedEndpoint{URL: "https://flag.example.com", Source: EndpointSourceFlag},
+		},
+		{
+			name: "env ignored when configured endpoints are disabled",
+			env: map[string]string{
+				"AWS_ENDPOINT_URL_EC2":                "https://ec2.vpce.example.com",
+				"AWS_IGNORE_CONFIGURED_ENDPOINT_URLS": "true",
+			},
+			want: ResolvedEndpoint{Source: EndpointSourceDefault},
+		},
+		{
+			name:      "invalid flag",
+			override:  "ec2.example.com",
+			wantError: "invalid EC2 endpoint override",
+		},
+		{
+			name:      "invalid env names the variable",
+			env:       map[string]string{"AWS_ENDPOINT_URL_EC2": "https://ec2.example.com/?x=1"},
+			wantError: "invalid AWS_ENDPOINT_URL_EC2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"AWS_ENDPOINT_URL", "AWS_ENDPOINT_URL_EC2", "AWS_IGNORE_CONFIGURED_ENDPOINT_URLS"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			got, err := ResolveEndpoint("EC2", tt.override)
+			if tt.wantError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	assert.NoError(t, ValidateEndpoint("https://ec2.us-east-1.amazonaws.com"))
+	assert.NoError(t, ValidateEndpoint("http://aws-mock:4566/"))
+	assert.ErrorContains(t, ValidateEndpoint("ftp://example.com"), "http or https")
+	assert.ErrorContains(t, ValidateEndpoint("https://"), "no host")
+	assert.ErrorContains(t, ValidateEndpoint("https://example.com#frag"), "query or fragment")
+}
+
+func TestServiceEndpointEnvVar(t *testing.T) {
+	assert.Equal(t, "AWS_ENDPOINT_URL_EC2", ServiceEndpointEnvVar("EC2"))
+	assert.Equal(t, "AWS_ENDPOINT_URL_ELASTIC_LOAD_BALANCING", ServiceEndpointEnvVar("Elastic Load Balancing"))
+}
@@ -0,0 +1,73 @@
+package aws
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// ENI classes, recorded in ENIInfo.Class. Empty in cache entries persisted by
+// older versions.
+const (
+	// ENIClassBranch is a pod's own ENI attached through a trunk ENI (security
+	// groups for pods), exclusive to the pod however many addresses it holds.
+	ENIClassBranch = "branch"
+	// ENIClassTrunk is a node's trunk ENI, carrying the branch ENIs of its pods.
+	ENIClassTrunk = "trunk"
+	// ENIClassVPCCNI is a secondary ENI created by the VPC CNI for pod IPs.
+	ENIClassVPCCNI = "vpc-cni"
+	// ENIClassStandard is any other ENI, such as a node's primary ENI.
+	ENIClassStandard = "standard"
+)
+
+// Descriptions and tags the VPC CNI and the VPC resource controller give the
+// ENIs they create.
+const (
+	vpcCNIDescriptionPrefix = "aws-k8s-"
+	branchENIDescription    = "aws-k8s-branch-eni"
+	trunkENIDescription     = "trunk-eni"
+	branchENITrunkTagKey    = "vpcresources.k8s.aws/trunk-eni-id"
+	branchENIVLANTagKey     = "vpcresources.k8s.aws/vlan-id"
+	vpcCNIInstanceIDTagKey  = "node.k8s.amazonaws.com/instance_id"
+)
+
+// classifyENI returns the class of eni and whether it is shared by several pods.
+//
+// The interface type alone is not enough: Windows nodes and some Bottlerocket
+// configurations report the branch and trunk ENIs of the VPC resource controller
+// with interface type "interface", and a branch ENI may hold more than one
+// address. Those are recognized by the description and tags the controller gives
+// them. Descriptions are compared case-insensitively, since VPC CNI builds differ
+// in the case of their "aws-K8S-" prefix.
+func classifyENI(eni types.NetworkInterface) (class string, shared bool) {
+	description := strings.ToLower(aws.ToString(eni.Description))
+	multipleIPs := len(eni.PrivateIpAddresses) > 1
+
+	switch {
+	case eni.InterfaceType == types.NetworkInterfaceTypeTrunk, description == trunkENIDescription:
+		return ENIClassTrunk, true
+	case eni.InterfaceType == types.NetworkInterfaceTypeBranch,
+		strings.HasPrefix(description, branchENIDescription),
+		hasTagKey(eni.TagSet, branchENITrunkTagKey),
+		hasTagKey(eni.TagSet, branchENIVLANTagKey):
+		return ENIClassBranch, false
+	case strings.HasPrefix(description, vpcCNIDescriptionPrefix), hasTagKey(eni.TagSet, vpcCNIInstanceIDTagKey):
+		// A secondary ENI with a single IP is a pod's own (prefix delegation);
+		// with several, pods share it
+		return ENIClassVPCCNI, multipleIPs
+	default:
+		// Multiple IPs on the same ENI are shared; a single IP on a standard
+		// interface could be either, assume not shared
+		return ENIClassStandard, multipleIPs
+	}
+}
+
+func hasTagKey(tags []types.Tag, key string) bool {
+	for _, t := range tags {
+		if aws.ToString(t.Key) == key {
+			return true
+		}
+	}
+	return false
+}
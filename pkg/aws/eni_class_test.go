@@ -0,0 +1,48 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyENI(t *testing.T) {
+	ips := func(n int) []types.NetworkInterfacePrivateIpAddress {
+		return make([]types.NetworkInterfacePrivateIpAddress, n)
+	}
+	tags := func(keys ...string) []types.Tag {
+		var tags []types.Tag
+		for _, k := range keys {
+			tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String("x")})
+		}
+		return tags
+	}
+
+	tests := []struct {
+		name       string
+		eni        types.NetworkInterface
+		wantClass  string
+		wantShared bool
+	}{
+		{"branch type", types.NetworkInterface{InterfaceType: types.NetworkInterfaceTypeBranch, PrivateIpAddresses: ips(1)}, ENIClassBranch, false},
+		{"trunk type", types.NetworkInterface{InterfaceType: types.NetworkInterfaceTypeTrunk, PrivateIpAddresses: ips(1)}, ENIClassTrunk, true},
+		{"trunk reported as interface (Bottlerocket)", types.NetworkInterface{InterfaceType: types.NetworkInterfaceTypeInterface, Description: aws.String("trunk-eni"), PrivateIpAddresses: ips(1)}, ENIClassTrunk, true},
+		{"branch by description with several IPs (Windows)", types.NetworkInterface{InterfaceType: types.NetworkInterfaceTypeInterface, Description: aws.String("aws-k8s-branch-eni"), PrivateIpAddresses: ips(2)}, ENIClassBranch, false},
+		{"branch by resource controller tags", types.NetworkInterface{InterfaceType: types.NetworkInterfaceTypeInterface, TagSet: tags(branchENITrunkTagKey), PrivateIpAddresses: ips(2)}, ENIClassBranch, false},
+		{"VPC CNI secondary ENI with one IP", types.NetworkInterface{InterfaceType: types.NetworkInterfaceTypeInterface, Description: aws.String("aws-K8S-i-0abc"), PrivateIpAddresses: ips(1)}, ENIClassVPCCNI, false},
+		{"VPC CNI secondary ENI in lower case with several IPs", types.NetworkInterface{InterfaceType: types.NetworkInterfaceTypeInterface, Description: aws.String("aws-k8s-i-0abc"), PrivateIpAddresses: ips(3)}, ENIClassVPCCNI, true},
+		{"VPC CNI secondary ENI by tag", types.NetworkInterface{InterfaceType: types.NetworkInterfaceTypeInterface, TagSet: tags(vpcCNIInstanceIDTagKey), PrivateIpAddresses: ips(1)}, ENIClassVPCCNI, false},
+		{"primary ENI holding pod IPs (Windows)", types.NetworkInterface{InterfaceType: types.NetworkInterfaceTypeInterface, PrivateIpAddresses: ips(3)}, ENIClassStandard, true},
+		{"standard with one IP", types.NetworkInterface{InterfaceType: types.NetworkInterfaceTypeInterface, PrivateIpAddresses: ips(1)}, ENIClassStandard, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, shared := classifyENI(tt.eni)
+			assert.Equal(t, tt.wantClass, class)
+			assert.Equal(t, tt.wantShared, shared)
+			assert.Equal(t, tt.wantClass, newENIInfo(tt.eni).Class)
+		})
+	}
+}
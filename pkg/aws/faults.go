@@ -0,0 +1,11 @@
+package aws
+
+// FaultInjection makes EC2 calls fail on purpose, so resilience tests can run
+// the real controller against AWS or a local endpoint under induced failure.
+// It only has an effect in binaries built with the faultinject build tag (see
+// FaultInjectionAvailable); never enable it in production.
+type FaultInjection struct {
+	// ErrorRate is the fraction of EC2 calls, from 0 to 1, that fail with a
+	// retryable throttling or server error before being sent.
+	ErrorRate float64
+}
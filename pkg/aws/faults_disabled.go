@@ -0,0 +1,15 @@
+//go:build !faultinject
+
+package aws
+
+import "github.com/aws/smithy-go/middleware"
+
+// FaultInjectionAvailable reports whether the binary was built with the
+// faultinject build tag, without which FaultInjection has no effect.
+const FaultInjectionAvailable = false
+
+// faultInjectionMiddleware injects nothing: production builds carry no fault
+// injection code.
+func faultInjectionMiddleware(float64) func(*middleware.Stack) error {
+	return func(*middleware.Stack) error { return nil }
+}
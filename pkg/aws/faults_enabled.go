@@ -0,0 +1,37 @@
+//go:build faultinject
+
+package aws
+
+import (
+	"context"
+	"math/rand/v2"
+
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// FaultInjectionAvailable reports whether the binary was built with the
+// faultinject build tag, without which FaultInjection has no effect.
+const FaultInjectionAvailable = true
+
+// injectedErrorCodes are the errors returned by injected faults: throttling and
+// server errors, which the controller is expected to retry.
+var injectedErrorCodes = []string{"RequestLimitExceeded", "InternalError", "Unavailable"}
+
+// faultInjectionMiddleware fails a call with a random retryable API error at
+// rate, before it is signed and sent.
+func faultInjectionMiddleware(rate float64) func(*middleware.Stack) error {
+	return func(stack *middleware.Stack) error {
+		return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("ENITaggerFaultInjection",
+			func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+				if rand.Float64() < rate {
+					return middleware.FinalizeOutput{}, middleware.Metadata{}, &smithy.GenericAPIError{
+						Code:    injectedErrorCodes[rand.IntN(len(injectedErrorCodes))],
+						Message: "fault injected by k8s-eni-tagger",
+						Fault:   smithy.FaultServer,
+					}
+				}
+				return next.HandleFinalize(ctx, in)
+			}), middleware.Before)
+	}
+}
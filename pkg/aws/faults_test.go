@@ -0,0 +1,43 @@
+//go:build faultinject
+
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultInjection(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Content-Type", "text/xml")
+		_, _ = w.Write([]byte(`<DescribeNetworkInterfacesResponse><networkInterfaceSet><item><networkInterfaceId>eni-1</networkInterfaceId></item></networkInterfaceSet></DescribeNetworkInterfacesResponse>`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("AWS_ENDPOINT_URL", srv.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	c, err := NewClientWithOptions(context.Background(), ClientOptions{RateLimit: DefaultRateLimitConfig(), Faults: FaultInjection{ErrorRate: 1}})
+	require.NoError(t, err)
+	_, err = c.GetENIInfoByIP(context.Background(), "10.0.0.1")
+	require.Error(t, err)
+	assert.Contains(t, injectedErrorCodes, ErrorCode(err))
+	assert.True(t, categorizeAWSError(err).IsRetryable, "injected faults take the retry path")
+	assert.Zero(t, calls.Load(), "failed calls are never sent")
+
+	c, err = NewClientWithOptions(context.Background(), ClientOptions{RateLimit: DefaultRateLimitConfig()})
+	require.NoError(t, err)
+	info, err := c.GetENIInfoByIP(context.Background(), "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "eni-1", info.ID)
+}
@@ -0,0 +1,58 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// healthSessionName is the STS session name used by HealthClientOptions.RoleARN.
+const healthSessionName = "k8s-eni-tagger-health"
+
+// HealthClientOptions configures NewHealthEC2Client.
+type HealthClientOptions struct {
+	// Profile selects a named shared config profile. Empty uses AWS_PROFILE or
+	// the default chain.
+	Profile string
+	// RoleARN is assumed for health check calls, e.g. a read-only role, so
+	// probing AWS never uses the credentials allowed to tag.
+	RoleARN string
+	// EC2Endpoint overrides the EC2 endpoint, as ClientOptions.EC2Endpoint.
+	EC2Endpoint string
+}
+
+// NewHealthEC2Client creates an EC2 client for the AWS health checker with its
+// own credentials, independent of the tagging client. It is not rate limited:
+// the health checker makes one call per interval.
+func NewHealthEC2Client(ctx context.Context, opts HealthClientOptions) (*ec2.Client, error) {
+	cfg, err := loadSDKConfig(ctx, opts.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SDK config for health checks: %w", err)
+	}
+	cfg.AppID = "k8s-eni-tagger"
+
+	if opts.RoleARN != "" {
+		if cfg.Region != "" {
+			if err := CheckARNPartition(opts.RoleARN, cfg.Region); err != nil {
+				return nil, fmt.Errorf("health check role: %w", err)
+			}
+		}
+		cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), opts.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = healthSessionName
+		}))
+	}
+
+	endpoint, err := ResolveEndpoint(ec2.ServiceID, opts.EC2Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return ec2.NewFromConfig(cfg, func(o *ec2.Options) {
+		if endpoint.URL != "" {
+			o.BaseEndpoint = aws.String(endpoint.URL)
+		}
+	}), nil
+}
@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// withSharedConfig points the SDK at a shared config file with a "readonly"
+// profile in eu-west-1 and no other configuration.
+func withSharedConfig(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(configFile, []byte("[profile readonly]\nregion = eu-west-1\naws_access_key_id = AKIDREADONLY\naws_secret_access_key = secret\n"), 0o600))
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+	for _, env := range []string{"AWS_PROFILE", "AWS_REGION", "AWS_DEFAULT_REGION", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_ENDPOINT_URL", "AWS_ENDPOINT_URL_EC2"} {
+		t.Setenv(env, "")
+		os.Unsetenv(env)
+	}
+}
+
+func TestNewHealthEC2Client_Profile(t *testing.T) {
+	withSharedConfig(t)
+	ctx := context.Background()
+
+	client, err := NewHealthEC2Client(ctx, HealthClientOptions{Profile: "readonly"})
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", client.Options().Region)
+	creds, err := client.Options().Credentials.Retrieve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "AKIDREADONLY", creds.AccessKeyID)
+
+	_, err = NewHealthEC2Client(ctx, HealthClientOptions{Profile: "missing"})
+	assert.Error(t, err)
+}
+
+func TestNewHealthEC2Client_RolePartition(t *testing.T) {
+	withSharedConfig(t)
+
+	_, err := NewHealthEC2Client(context.Background(), HealthClientOptions{Profile: "readonly", RoleARN: "arn:aws-cn:iam::123456789012:role/read-only"})
+	assert.ErrorContains(t, err, "partition")
+
+	_, err = NewHealthEC2Client(context.Background(), HealthClientOptions{Profile: "readonly", RoleARN: "arn:aws:iam::123456789012:role/read-only"})
+	assert.NoError(t, err)
+}
+
+func TestDiagnoseCredentials_Profile(t *testing.T) {
+	withSharedConfig(t)
+	t.Setenv("AWS_ROLE_ARN", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+
+	d, err := DiagnoseCredentials(context.Background(), "readonly")
+	require.NoError(t, err)
+	assert.Equal(t, "readonly", d.Profile)
+	assert.Equal(t, "eu-west-1", d.Region)
+}
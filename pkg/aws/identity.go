@@ -0,0 +1,56 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// IdentityFinder returns the AWS region and account the client tags ENIs in.
+// The client returned by NewClientWithOptions implements it.
+type IdentityFinder interface {
+	// Region returns the client's region.
+	Region() string
+	// AccountID looks up the client's account.
+	AccountID(ctx context.Context) (string, error)
+}
+
+var _ IdentityFinder = (*defaultClient)(nil)
+
+// callerIdentityAPI is the STS call used to find the account of the client's
+// credentials.
+type callerIdentityAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// Region returns the client's region.
+func (c *defaultClient) Region() string {
+	return c.region
+}
+
+// AccountID returns the client's account: the account of the assumed role, or
+// of the base credentials, which costs an STS GetCallerIdentity call (allowed
+// for every identity).
+func (c *defaultClient) AccountID(ctx context.Context) (string, error) {
+	return lookupAccountID(ctx, c.sts, c.roleARN)
+}
+
+// lookupAccountID returns the account of roleARN, or of the credentials of
+// client when roleARN is empty.
+func lookupAccountID(ctx context.Context, client callerIdentityAPI, roleARN string) (string, error) {
+	if roleARN != "" {
+		parsed, err := arn.Parse(roleARN)
+		if err != nil {
+			return "", fmt.Errorf("invalid assume role ARN %q: %w", roleARN, err)
+		}
+		return parsed.AccountID, nil
+	}
+	identity, err := client.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up the AWS account: %w", err)
+	}
+	return aws.ToString(identity.Account), nil
+}
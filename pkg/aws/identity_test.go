@@ -0,0 +1,47 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCallerIdentity answers GetCallerIdentity with account, or err.
+type fakeCallerIdentity struct {
+	account string
+	err     error
+	calls   int
+}
+
+func (f *fakeCallerIdentity) GetCallerIdentity(context.Context, *sts.GetCallerIdentityInput, ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &sts.GetCallerIdentityOutput{Account: aws.String(f.account)}, nil
+}
+
+func TestIdentity(t *testing.T) {
+	api := &fakeCallerIdentity{account: "111111111111"}
+	c := &defaultClient{region: "eu-west-1", sts: api}
+	assert.Equal(t, "eu-west-1", c.Region())
+	assert.Zero(t, api.calls, "the region needs no call")
+	account, err := c.AccountID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "111111111111", account)
+
+	c.roleARN = "arn:aws:iam::222222222222:role/tagger"
+	account, err = c.AccountID(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "222222222222", account)
+	assert.Equal(t, 1, api.calls, "the account of an assumed role is read from its ARN")
+
+	c = &defaultClient{region: "eu-west-1", sts: &fakeCallerIdentity{err: errors.New("no network")}}
+	_, err = c.AccountID(context.Background())
+	assert.ErrorContains(t, err, "no network")
+}
@@ -0,0 +1,62 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// LoadBalancerENIFinder finds the ENIs of an Elastic Load Balancer. The client
+// returned by NewClientWithOptions implements it with the tagging client's rate
+// limiter and retries.
+type LoadBalancerENIFinder interface {
+	FindLoadBalancerENIs(ctx context.Context, hostname string) ([]*ENIInfo, error)
+}
+
+var _ LoadBalancerENIFinder = (*defaultClient)(nil)
+
+// LoadBalancerENIDescription returns the description ELB gives the ENIs of the
+// load balancer whose DNS name is hostname, as a DescribeNetworkInterfaces
+// filter value: "ELB net/<name>/*" for a Network Load Balancer
+// (<name>-<id>.elb.<region>.amazonaws.com) and "ELB <name>" for a Classic Load
+// Balancer (<name>-<id>.<region>.elb.amazonaws.com). The "internal-" prefix of
+// internal load balancers is not part of the name.
+func LoadBalancerENIDescription(hostname string) (string, error) {
+	label, domain, ok := strings.Cut(strings.TrimSuffix(hostname, "."), ".")
+	dash := strings.LastIndex(label, "-")
+	if !ok || dash <= 0 {
+		return "", fmt.Errorf("%q is not a load balancer DNS name", hostname)
+	}
+	name := strings.TrimPrefix(label[:dash], "internal-")
+	domain = strings.TrimSuffix(strings.ToLower(domain), ".cn")
+	switch {
+	case strings.HasPrefix(domain, "elb.") && strings.HasSuffix(domain, ".amazonaws.com"):
+		return "ELB net/" + name + "/*", nil
+	case strings.HasSuffix(domain, ".elb.amazonaws.com"):
+		return "ELB " + name, nil
+	default:
+		return "", fmt.Errorf("%q is not a load balancer DNS name", hostname)
+	}
+}
+
+// FindLoadBalancerENIs returns the ENIs of the load balancer whose DNS name is
+// hostname, one per Availability Zone it is enabled in, matched by the ENI
+// description (see LoadBalancerENIDescription). None are returned while the
+// load balancer is still being provisioned.
+func (c *defaultClient) FindLoadBalancerENIs(ctx context.Context, hostname string) ([]*ENIInfo, error) {
+	description, err := LoadBalancerENIDescription(hostname)
+	if err != nil {
+		return nil, err
+	}
+	var infos []*ENIInfo
+	err = c.describeENIs(ctx, []types.Filter{{Name: aws.String("description"), Values: []string{description}}}, func(eni types.NetworkInterface) {
+		infos = append(infos, newENIInfo(eni))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
@@ -0,0 +1,123 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s-eni-tagger/pkg/metrics"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// MaxLocateIPs is the most IPs GetENIInfoByIPs and LocateIPs accept in one
+// call, the EC2 limit on filter values.
+const MaxLocateIPs = 200
+
+// IPLocator finds the ENIs private IPs are assigned to. The client returned by
+// NewClientWithOptions implements it with the tagging client's rate limiter and
+// retries.
+type IPLocator interface {
+	LocateIPs(ctx context.Context, ips []string) (map[string]string, error)
+}
+
+var _ IPLocator = (*defaultClient)(nil)
+
+// GetENIInfoByIPs returns the ENI each IP is assigned to, with one
+// DescribeNetworkInterfaces call (and its further pages) for up to MaxLocateIPs
+// IPs. IPs on the same ENI share one ENIInfo. IPs not assigned to any ENI, e.g.
+// of host network pods, are left out of the result.
+func (c *defaultClient) GetENIInfoByIPs(ctx context.Context, ips []string) (map[string]*ENIInfo, error) {
+	infos := make(map[string]*ENIInfo, len(ips))
+	byID := make(map[string]*ENIInfo)
+	err := c.describeByIPs(ctx, ips, func(ip string, eni types.NetworkInterface) {
+		id := aws.ToString(eni.NetworkInterfaceId)
+		info, ok := byID[id]
+		if !ok {
+			info = newENIInfo(eni)
+			byID[id] = info
+		}
+		infos[ip] = info
+	})
+	if err != nil {
+		return nil, err
+	}
+	return infos, nil
+}
+
+// LocateIPs returns the ID of the ENI each IP is assigned to, with one
+// DescribeNetworkInterfaces call for up to MaxLocateIPs IPs. IPs not assigned
+// to any ENI are left out of the result.
+func (c *defaultClient) LocateIPs(ctx context.Context, ips []string) (map[string]string, error) {
+	located := make(map[string]string, len(ips))
+	err := c.describeByIPs(ctx, ips, func(ip string, eni types.NetworkInterface) {
+		located[ip] = aws.ToString(eni.NetworkInterfaceId)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return located, nil
+}
+
+// describeByIPs describes the ENIs holding ips, following pagination, and calls
+// found for each of ips that one of them holds.
+func (c *defaultClient) describeByIPs(ctx context.Context, ips []string, found func(ip string, eni types.NetworkInterface)) error {
+	if len(ips) == 0 {
+		return nil
+	}
+	if len(ips) > MaxLocateIPs {
+		return fmt.Errorf("cannot look up %d IPs at once, at most %d", len(ips), MaxLocateIPs)
+	}
+
+	wanted := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		wanted[ip] = true
+	}
+	return c.describeENIs(ctx, []types.Filter{{Name: aws.String("private-ip-address"), Values: ips}}, func(eni types.NetworkInterface) {
+		for _, addr := range eni.PrivateIpAddresses {
+			if ip := aws.ToString(addr.PrivateIpAddress); wanted[ip] {
+				found(ip, eni)
+			}
+		}
+	})
+}
+
+// describeENIs describes the ENIs matching filters, following pagination, and
+// calls found for each.
+func (c *defaultClient) describeENIs(ctx context.Context, filters []types.Filter, found func(eni types.NetworkInterface)) error {
+	start := time.Now()
+	status := "success"
+	defer func() {
+		metrics.ObserveAWSAPILatency(ctx, "DescribeNetworkInterfaces", status, time.Since(start).Seconds())
+	}()
+
+	input := &ec2.DescribeNetworkInterfacesInput{Filters: filters}
+	for {
+		var result *ec2.DescribeNetworkInterfacesOutput
+		err := c.doWithRetry(ctx, "DescribeNetworkInterfaces", awsAPIMaxAttempts, func(ctx context.Context) error {
+			if err := c.wait(ctx); err != nil {
+				return fmt.Errorf("rate limiter wait: %w", err)
+			}
+			var callErr error
+			result, callErr = c.ec2Client.DescribeNetworkInterfaces(ctx, input, c.sessions.ec2Options(ctx)...)
+			return callErr
+		})
+		if err != nil {
+			status = "error"
+			if categorizeAWSError(err).Category == AWSErrorPermission {
+				return fmt.Errorf("insufficient permissions to describe network interfaces (check ec2:DescribeNetworkInterfaces): %w", err)
+			}
+			return fmt.Errorf("failed to describe network interfaces: %w", err)
+		}
+
+		for _, eni := range result.NetworkInterfaces {
+			found(eni)
+		}
+		if aws.ToString(result.NextToken) == "" {
+			return nil
+		}
+		input.NextToken = result.NextToken
+	}
+}
@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/metrics"
+)
+
+// MutationBudget counts the EC2 calls that change tags (CreateTags and DeleteTags,
+// retries included) in fixed windows aligned to the UTC epoch, so an hourly
+// window starts on the hour and a daily one at midnight UTC. Calls are never
+// refused by the budget itself: callers ask Exhausted before work that can wait,
+// and hold it back to the next window. Counts are kept in memory and start again
+// at zero when the process restarts.
+type MutationBudget struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	start time.Time
+	used  int
+}
+
+// NewMutationBudget returns a budget of limit calls per window. It returns nil,
+// an unlimited budget, if limit is 0.
+func NewMutationBudget(limit int, window time.Duration) (*MutationBudget, error) {
+	if limit == 0 {
+		return nil, nil
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("mutation budget must not be negative: %d", limit)
+	}
+	if window < time.Minute {
+		return nil, fmt.Errorf("mutation budget window must be at least a minute: %s", window)
+	}
+	metrics.AWSMutationBudgetLimit.Set(float64(limit))
+	return &MutationBudget{limit: limit, window: window, now: time.Now}, nil
+}
+
+// roll starts a new window if the current one has ended. b.mu must be held.
+func (b *MutationBudget) roll(now time.Time) {
+	if start := now.UTC().Truncate(b.window); !start.Equal(b.start) {
+		b.start = start
+		b.used = 0
+		metrics.AWSMutationBudgetUsed.Set(0)
+	}
+}
+
+// Record counts one call changing tags. Clients given the budget in ClientOptions
+// record their own calls.
+func (b *MutationBudget) Record() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(b.now())
+	b.used++
+	metrics.AWSMutationBudgetUsed.Set(float64(b.used))
+}
+
+// Exhausted reports whether the current window's calls have used up the budget,
+// and if so when the next window starts. A nil budget is never exhausted.
+func (b *MutationBudget) Exhausted() (bool, time.Time) {
+	if b == nil {
+		return false, time.Time{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(b.now())
+	if b.used < b.limit {
+		return false, time.Time{}
+	}
+	return true, b.start.Add(b.window)
+}
+
+// Limit returns the number of calls allowed per window.
+func (b *MutationBudget) Limit() int {
+	return b.limit
+}
@@ -0,0 +1,49 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMutationBudget(t *testing.T) {
+	b, err := NewMutationBudget(0, time.Hour)
+	require.NoError(t, err)
+	assert.Nil(t, b)
+	exhausted, _ := b.Exhausted()
+	assert.False(t, exhausted, "a nil budget is unlimited")
+	b.Record()
+
+	_, err = NewMutationBudget(-1, time.Hour)
+	assert.Error(t, err)
+	_, err = NewMutationBudget(100, time.Second)
+	assert.Error(t, err)
+}
+
+func TestMutationBudgetWindows(t *testing.T) {
+	b, err := NewMutationBudget(2, time.Hour)
+	require.NoError(t, err)
+	now := time.Date(2026, 10, 16, 9, 59, 0, 0, time.UTC)
+	b.now = func() time.Time { return now }
+
+	b.Record()
+	exhausted, _ := b.Exhausted()
+	assert.False(t, exhausted)
+
+	b.Record()
+	exhausted, next := b.Exhausted()
+	assert.True(t, exhausted)
+	assert.Equal(t, time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC), next)
+
+	// Calls past the limit are still counted
+	b.Record()
+	assert.Equal(t, 3, b.used)
+
+	// The next window starts on the hour with nothing used
+	now = now.Add(time.Minute)
+	exhausted, _ = b.Exhausted()
+	assert.False(t, exhausted)
+	assert.Equal(t, 0, b.used)
+}
@@ -0,0 +1,71 @@
+package aws
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+)
+
+// AWS partitions. ARNs, endpoint DNS suffixes and IAM principals differ per
+// partition, so a role or endpoint from one partition never works in another.
+const (
+	PartitionAWS      = "aws"
+	PartitionChina    = "aws-cn"
+	PartitionGovCloud = "aws-us-gov"
+	PartitionISO      = "aws-iso"
+	PartitionISOB     = "aws-iso-b"
+	PartitionISOE     = "aws-iso-e"
+	PartitionISOF     = "aws-iso-f"
+)
+
+// PartitionForRegion returns the AWS partition a region belongs to.
+func PartitionForRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "cn-"):
+		return PartitionChina
+	case strings.HasPrefix(region, "us-gov-"):
+		return PartitionGovCloud
+	case strings.HasPrefix(region, "us-isob-"):
+		return PartitionISOB
+	case strings.HasPrefix(region, "us-isof-"):
+		return PartitionISOF
+	case strings.HasPrefix(region, "us-iso-"):
+		return PartitionISO
+	case strings.HasPrefix(region, "eu-isoe-"):
+		return PartitionISOE
+	default:
+		return PartitionAWS
+	}
+}
+
+// PartitionDNSSuffix returns the endpoint DNS suffix of a partition.
+func PartitionDNSSuffix(partition string) string {
+	switch partition {
+	case PartitionChina:
+		return "amazonaws.com.cn"
+	case PartitionISO:
+		return "c2s.ic.gov"
+	case PartitionISOB:
+		return "sc2s.sgov.gov"
+	case PartitionISOE:
+		return "cloud.adc-e.uk"
+	case PartitionISOF:
+		return "csp.hci.ic.gov"
+	default:
+		return "amazonaws.com"
+	}
+}
+
+// CheckARNPartition returns an error if resourceARN is malformed or belongs to
+// a different partition than region, e.g. an arn:aws: role used in cn-north-1.
+func CheckARNPartition(resourceARN, region string) error {
+	parsed, err := arn.Parse(resourceARN)
+	if err != nil {
+		return fmt.Errorf("invalid ARN %q: %w", resourceARN, err)
+	}
+	if want := PartitionForRegion(region); parsed.Partition != want {
+		return fmt.Errorf("%s is in partition %s but region %s is in partition %s", resourceARN, parsed.Partition, region, want)
+	}
+	return nil
+}
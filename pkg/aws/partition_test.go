@@ -0,0 +1,39 @@
+package aws
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartitionForRegion(t *testing.T) {
+	tests := map[string]string{
+		"us-east-1":       PartitionAWS,
+		"eu-west-1":       PartitionAWS,
+		"cn-north-1":      PartitionChina,
+		"cn-northwest-1":  PartitionChina,
+		"us-gov-west-1":   PartitionGovCloud,
+		"us-gov-east-1":   PartitionGovCloud,
+		"us-iso-east-1":   PartitionISO,
+		"us-isob-east-1":  PartitionISOB,
+		"us-isof-south-1": PartitionISOF,
+		"eu-isoe-west-1":  PartitionISOE,
+	}
+	for region, want := range tests {
+		assert.Equal(t, want, PartitionForRegion(region), region)
+	}
+}
+
+func TestPartitionDNSSuffix(t *testing.T) {
+	assert.Equal(t, "amazonaws.com", PartitionDNSSuffix(PartitionAWS))
+	assert.Equal(t, "amazonaws.com", PartitionDNSSuffix(PartitionGovCloud))
+	assert.Equal(t, "amazonaws.com.cn", PartitionDNSSuffix(PartitionChina))
+}
+
+func TestCheckARNPartition(t *testing.T) {
+	assert.NoError(t, CheckARNPartition("arn:aws:iam::123456789012:role/r", "us-east-1"))
+	assert.NoError(t, CheckARNPartition("arn:aws-cn:iam::123456789012:role/r", "cn-north-1"))
+	assert.NoError(t, CheckARNPartition("arn:aws-us-gov:iam::123456789012:role/r", "us-gov-west-1"))
+	assert.ErrorContains(t, CheckARNPartition("arn:aws:iam::123456789012:role/r", "cn-north-1"), "partition aws but region cn-north-1 is in partition aws-cn")
+	assert.ErrorContains(t, CheckARNPartition("role/r", "us-east-1"), "invalid ARN")
+}
@@ -0,0 +1,252 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// permissionProbeResourceID is a syntactically valid ENI ID that does not exist.
+	// Dry-run requests are authorized before the resource is looked up, so nothing is ever modified.
+	permissionProbeResourceID = "eni-00000000000000000"
+
+	// permissionProbeInstanceID, permissionProbeVolumeID and
+	// permissionProbeSecurityGroupID are the instance, volume and security group
+	// counterparts of permissionProbeResourceID.
+	permissionProbeInstanceID      = "i-00000000000000000"
+	permissionProbeVolumeID        = "vol-00000000000000000"
+	permissionProbeSecurityGroupID = "sg-00000000000000000"
+
+	// permissionProbeTagKey is the tag key used in dry-run permission probes.
+	permissionProbeTagKey = "eni-tagger.io/permission-check"
+
+	// dryRunOperationCode is returned by EC2 when a DryRun request would have succeeded.
+	dryRunOperationCode = "DryRunOperation"
+)
+
+// TaggingPermissionAPI is the subset of EC2API needed to verify tagging permissions.
+type TaggingPermissionAPI interface {
+	CreateTags(ctx context.Context, params *ec2.CreateTagsInput, optFns ...func(*ec2.Options)) (*ec2.CreateTagsOutput, error)
+	DeleteTags(ctx context.Context, params *ec2.DeleteTagsInput, optFns ...func(*ec2.Options)) (*ec2.DeleteTagsOutput, error)
+}
+
+// ErrPermissionDenied is wrapped by PermissionCheck.Err when IAM denies an action.
+var ErrPermissionDenied = errors.New("permission denied")
+
+// ErrTaggingPermissionDenied is returned by VerifyTaggingPermissions when IAM
+// denies ec2:CreateTags or ec2:DeleteTags. It wraps ErrPermissionDenied.
+var ErrTaggingPermissionDenied = fmt.Errorf("tagging %w", ErrPermissionDenied)
+
+// ErrPermissionUnverifiable is wrapped by PermissionCheck.Err for actions that
+// cannot be probed without side effects.
+var ErrPermissionUnverifiable = errors.New("cannot be checked without side effects")
+
+// VerifyTaggingPermissions issues DryRun CreateTags and DeleteTags requests to
+// confirm the caller may tag ENIs. DescribeAccountAttributes succeeding does not
+// prove ec2:CreateTags is allowed, so this closes that gap at startup.
+//
+// It returns nil when both dry runs report DryRunOperation, an error wrapping
+// ErrTaggingPermissionDenied on an authorization failure, and any other error
+// unchanged so callers can decide whether it is fatal.
+func VerifyTaggingPermissions(ctx context.Context, api TaggingPermissionAPI) error {
+	_, err := api.CreateTags(ctx, &ec2.CreateTagsInput{
+		DryRun:    aws.Bool(true),
+		Resources: []string{permissionProbeResourceID},
+		Tags:      []types.Tag{{Key: aws.String(permissionProbeTagKey), Value: aws.String("dry-run")}},
+	})
+	if err := dryRunResult("ec2:CreateTags", err); err != nil {
+		return err
+	}
+
+	_, err = api.DeleteTags(ctx, &ec2.DeleteTagsInput{
+		DryRun:    aws.Bool(true),
+		Resources: []string{permissionProbeResourceID},
+		Tags:      []types.Tag{{Key: aws.String(permissionProbeTagKey)}},
+	})
+	return dryRunResult("ec2:DeleteTags", err)
+}
+
+// dryRunResult interprets the error from a DryRun tagging request.
+func dryRunResult(action string, err error) error {
+	return dryRunError(action, ErrTaggingPermissionDenied, err)
+}
+
+// dryRunError interprets the error from a DryRun request, wrapping denied on an
+// authorization failure.
+func dryRunError(action string, denied, err error) error {
+	if err == nil {
+		// EC2 always fails DryRun requests; a nil error means the endpoint ignored DryRun.
+		return fmt.Errorf("%s dry run unexpectedly succeeded (endpoint may not support DryRun)", action)
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == dryRunOperationCode {
+		return nil
+	}
+	if categorizeAWSError(err).Category == AWSErrorPermission {
+		return fmt.Errorf("%w: %s (check the IAM policy): %v", denied, action, err)
+	}
+	return fmt.Errorf("%s dry run failed: %w", action, err)
+}
+
+// PermissionAPI is the subset of the EC2 API probed by CheckPermissions.
+type PermissionAPI interface {
+	TaggingPermissionAPI
+	DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error)
+	DescribeInstances(ctx context.Context, params *ec2.DescribeInstancesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeInstancesOutput, error)
+	DescribeVolumes(ctx context.Context, params *ec2.DescribeVolumesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeVolumesOutput, error)
+	DescribeSecurityGroups(ctx context.Context, params *ec2.DescribeSecurityGroupsInput, optFns ...func(*ec2.Options)) (*ec2.DescribeSecurityGroupsOutput, error)
+}
+
+// PermissionOptions describes the features whose EC2 actions CheckPermissions probes.
+type PermissionOptions struct {
+	// Tagging probes the tagging actions, which dry-run and read-only modes never call.
+	Tagging bool
+	// NodeVolumes probes the lookups of node instances and their EBS volumes.
+	NodeVolumes bool
+	// SecurityGroups probes the lookup of ENI security groups.
+	SecurityGroups bool
+	// TaggingAPI lists tag:TagResources and tag:UntagResources, which change
+	// tags with the Resource Groups Tagging API backend.
+	TaggingAPI bool
+	// S3 lists s3:PutObject, which writes the ownership report.
+	S3 bool
+	// AssumeRole lists sts:AssumeRole, and sts:TagSession with SessionTags.
+	AssumeRole  bool
+	SessionTags bool
+	// Identity, if set, is asked for the account, which calls sts:GetCallerIdentity.
+	Identity IdentityFinder
+}
+
+// HealthPermissionAPI is the EC2 API used by the AWS health check.
+type HealthPermissionAPI interface {
+	DescribeAccountAttributes(ctx context.Context, params *ec2.DescribeAccountAttributesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAccountAttributesOutput, error)
+}
+
+// PermissionCheck is the outcome of probing one IAM action with a DryRun request.
+type PermissionCheck struct {
+	// Action is the IAM action, e.g. "ec2:CreateTags".
+	Action string
+	// Allowed is true when EC2 reported the request would have succeeded.
+	Allowed bool
+	// Denied is true when IAM refused the action.
+	Denied bool
+	// Err explains a denied or unverifiable action. It is set whenever Allowed is false.
+	Err error
+}
+
+// CheckPermissions probes every EC2 action the controller uses with DryRun
+// requests and reports each outcome, rather than stopping at the first denial,
+// so all missing IAM permissions can be fixed at once. Actions of features off in
+// opts are skipped, and ec2:DescribeAccountAttributes is probed on health, which
+// may use other credentials, unless it is nil.
+//
+// DryRun authorizes against the real IAM policy, including conditions, unlike
+// iam:SimulatePrincipalPolicy, which also needs IAM permissions of its own.
+// sts:GetCallerIdentity is called as is, as it changes nothing. The Tagging API,
+// S3 and STS role actions support no DryRun and are reported unverified, with
+// ErrPermissionUnverifiable, so they are listed rather than silently assumed.
+func CheckPermissions(ctx context.Context, api PermissionAPI, health HealthPermissionAPI, opts PermissionOptions) []PermissionCheck {
+	var checks []PermissionCheck
+	_, err := api.DescribeNetworkInterfaces(ctx, &ec2.DescribeNetworkInterfacesInput{
+		DryRun:              aws.Bool(true),
+		NetworkInterfaceIds: []string{permissionProbeResourceID},
+	})
+	checks = append(checks, permissionCheck("ec2:DescribeNetworkInterfaces", ErrPermissionDenied, err))
+
+	if opts.Tagging {
+		_, err = api.CreateTags(ctx, &ec2.CreateTagsInput{
+			DryRun:    aws.Bool(true),
+			Resources: []string{permissionProbeResourceID},
+			Tags:      []types.Tag{{Key: aws.String(permissionProbeTagKey), Value: aws.String("dry-run")}},
+		})
+		checks = append(checks, permissionCheck("ec2:CreateTags", ErrTaggingPermissionDenied, err))
+
+		_, err = api.DeleteTags(ctx, &ec2.DeleteTagsInput{
+			DryRun:    aws.Bool(true),
+			Resources: []string{permissionProbeResourceID},
+			Tags:      []types.Tag{{Key: aws.String(permissionProbeTagKey)}},
+		})
+		checks = append(checks, permissionCheck("ec2:DeleteTags", ErrTaggingPermissionDenied, err))
+	}
+
+	if opts.NodeVolumes {
+		_, err = api.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+			DryRun:      aws.Bool(true),
+			InstanceIds: []string{permissionProbeInstanceID},
+		})
+		checks = append(checks, permissionCheck("ec2:DescribeInstances", ErrPermissionDenied, err))
+
+		_, err = api.DescribeVolumes(ctx, &ec2.DescribeVolumesInput{
+			DryRun:    aws.Bool(true),
+			VolumeIds: []string{permissionProbeVolumeID},
+		})
+		checks = append(checks, permissionCheck("ec2:DescribeVolumes", ErrPermissionDenied, err))
+	}
+
+	if opts.SecurityGroups {
+		_, err = api.DescribeSecurityGroups(ctx, &ec2.DescribeSecurityGroupsInput{
+			DryRun:   aws.Bool(true),
+			GroupIds: []string{permissionProbeSecurityGroupID},
+		})
+		checks = append(checks, permissionCheck("ec2:DescribeSecurityGroups", ErrPermissionDenied, err))
+	}
+
+	if opts.Identity != nil {
+		_, err = opts.Identity.AccountID(ctx)
+		checks = append(checks, callCheck("sts:GetCallerIdentity", err))
+	}
+
+	var unverifiable []string
+	if opts.TaggingAPI {
+		unverifiable = append(unverifiable, "tag:TagResources", "tag:UntagResources")
+	}
+	if opts.S3 {
+		unverifiable = append(unverifiable, "s3:PutObject")
+	}
+	if opts.AssumeRole {
+		unverifiable = append(unverifiable, "sts:AssumeRole")
+		if opts.SessionTags {
+			unverifiable = append(unverifiable, "sts:TagSession")
+		}
+	}
+	for _, action := range unverifiable {
+		checks = append(checks, PermissionCheck{Action: action, Err: fmt.Errorf("%s %w; check the IAM policy", action, ErrPermissionUnverifiable)})
+	}
+
+	if health != nil {
+		_, err = health.DescribeAccountAttributes(ctx, &ec2.DescribeAccountAttributesInput{DryRun: aws.Bool(true)})
+		checks = append(checks, permissionCheck("ec2:DescribeAccountAttributes", ErrPermissionDenied, err))
+	}
+	return checks
+}
+
+// permissionCheck interprets the error from a DryRun request for action.
+func permissionCheck(action string, denied, err error) PermissionCheck {
+	err = dryRunError(action, denied, err)
+	return PermissionCheck{
+		Action:  action,
+		Allowed: err == nil,
+		Denied:  errors.Is(err, ErrPermissionDenied),
+		Err:     err,
+	}
+}
+
+// callCheck interprets the error from a request for action sent without DryRun.
+func callCheck(action string, err error) PermissionCheck {
+	check := PermissionCheck{Action: action, Allowed: err == nil}
+	switch {
+	case err == nil:
+	case categorizeAWSError(err).Category == AWSErrorPermission:
+		check.Denied = true
+		check.Err = fmt.Errorf("%w: %s (check the IAM policy): %v", ErrPermissionDenied, action, err)
+	default:
+		check.Err = fmt.Errorf("%s failed: %w", action, err)
+	}
+	return check
+}
@@ -0,0 +1,163 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyTaggingPermissions(t *testing.T) {
+	dryRunOK := &smithy.GenericAPIError{Code: "DryRunOperation", Message: "Request would have succeeded"}
+	denied := &smithy.GenericAPIError{Code: "UnauthorizedOperation", Message: "not authorized"}
+
+	isDryRunCreate := mock.MatchedBy(func(in *ec2.CreateTagsInput) bool {
+		return in.DryRun != nil && *in.DryRun && in.Resources[0] == permissionProbeResourceID
+	})
+	isDryRunDelete := mock.MatchedBy(func(in *ec2.DeleteTagsInput) bool {
+		return in.DryRun != nil && *in.DryRun && in.Resources[0] == permissionProbeResourceID
+	})
+
+	tests := []struct {
+		name       string
+		createErr  error
+		deleteErr  error
+		callDelete bool
+		wantErr    bool
+		wantDenied bool
+	}{
+		{name: "Allowed", createErr: dryRunOK, deleteErr: dryRunOK, callDelete: true},
+		{name: "CreateTags denied", createErr: denied, wantErr: true, wantDenied: true},
+		{name: "DeleteTags denied", createErr: dryRunOK, deleteErr: denied, callDelete: true, wantErr: true, wantDenied: true},
+		{name: "Endpoint ignores DryRun", createErr: nil, wantErr: true},
+		{name: "Network error", createErr: errors.New("dial tcp: connection refused"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(mockEC2Client)
+			if tt.createErr == nil {
+				m.On("CreateTags", mock.Anything, isDryRunCreate, mock.Anything).Return(&ec2.CreateTagsOutput{}, nil)
+			} else {
+				m.On("CreateTags", mock.Anything, isDryRunCreate, mock.Anything).Return(nil, tt.createErr)
+			}
+			if tt.callDelete {
+				m.On("DeleteTags", mock.Anything, isDryRunDelete, mock.Anything).Return(nil, tt.deleteErr)
+			}
+
+			err := VerifyTaggingPermissions(context.Background(), m)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantDenied, errors.Is(err, ErrTaggingPermissionDenied))
+			m.AssertExpectations(t)
+		})
+	}
+}
+
+type mockHealthEC2Client struct {
+	mock.Mock
+}
+
+func (m *mockHealthEC2Client) DescribeAccountAttributes(ctx context.Context, params *ec2.DescribeAccountAttributesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeAccountAttributesOutput, error) {
+	args := m.Called(ctx, params, optFns)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ec2.DescribeAccountAttributesOutput), args.Error(1)
+}
+
+func TestCheckPermissions(t *testing.T) {
+	dryRunOK := &smithy.GenericAPIError{Code: "DryRunOperation", Message: "Request would have succeeded"}
+	denied := &smithy.GenericAPIError{Code: "UnauthorizedOperation", Message: "not authorized"}
+
+	m := new(mockEC2Client)
+	m.On("DescribeNetworkInterfaces", mock.Anything, mock.MatchedBy(func(in *ec2.DescribeNetworkInterfacesInput) bool {
+		return in.DryRun != nil && *in.DryRun
+	}), mock.Anything).Return(nil, dryRunOK)
+	m.On("CreateTags", mock.Anything, mock.Anything, mock.Anything).Return(nil, denied)
+	m.On("DeleteTags", mock.Anything, mock.Anything, mock.Anything).Return(nil, denied)
+	h := new(mockHealthEC2Client)
+	h.On("DescribeAccountAttributes", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("dial tcp: i/o timeout"))
+
+	// Every action is reported, not only the first denial
+	checks := CheckPermissions(context.Background(), m, h, PermissionOptions{Tagging: true})
+	assert.Len(t, checks, 4)
+	byAction := make(map[string]PermissionCheck)
+	for _, c := range checks {
+		byAction[c.Action] = c
+	}
+	assert.True(t, byAction["ec2:DescribeNetworkInterfaces"].Allowed)
+	assert.NoError(t, byAction["ec2:DescribeNetworkInterfaces"].Err)
+	assert.True(t, byAction["ec2:CreateTags"].Denied)
+	assert.ErrorIs(t, byAction["ec2:DeleteTags"].Err, ErrTaggingPermissionDenied)
+	assert.False(t, byAction["ec2:DescribeAccountAttributes"].Allowed)
+	assert.False(t, byAction["ec2:DescribeAccountAttributes"].Denied, "network errors leave the action unverified")
+	m.AssertExpectations(t)
+
+	// Without tagging or a health client only the lookup is probed
+	m = new(mockEC2Client)
+	m.On("DescribeNetworkInterfaces", mock.Anything, mock.Anything, mock.Anything).Return(nil, denied)
+	checks = CheckPermissions(context.Background(), m, nil, PermissionOptions{})
+	assert.Len(t, checks, 1)
+	assert.ErrorIs(t, checks[0].Err, ErrPermissionDenied)
+	assert.NotErrorIs(t, checks[0].Err, ErrTaggingPermissionDenied)
+
+	// Node volume tagging looks up instances and volumes
+	m = new(mockEC2Client)
+	m.On("DescribeNetworkInterfaces", mock.Anything, mock.Anything, mock.Anything).Return(nil, dryRunOK)
+	m.On("DescribeInstances", mock.Anything, mock.MatchedBy(func(in *ec2.DescribeInstancesInput) bool {
+		return in.DryRun != nil && *in.DryRun
+	}), mock.Anything).Return(nil, dryRunOK)
+	m.On("DescribeVolumes", mock.Anything, mock.MatchedBy(func(in *ec2.DescribeVolumesInput) bool {
+		return in.DryRun != nil && *in.DryRun
+	}), mock.Anything).Return(nil, denied)
+	checks = CheckPermissions(context.Background(), m, nil, PermissionOptions{NodeVolumes: true})
+	require.Len(t, checks, 3)
+	assert.Equal(t, "ec2:DescribeInstances", checks[1].Action)
+	assert.True(t, checks[1].Allowed)
+	assert.Equal(t, "ec2:DescribeVolumes", checks[2].Action)
+	assert.True(t, checks[2].Denied)
+	m.AssertExpectations(t)
+
+	// Actions without DryRun are called, or listed as unverified
+	m = new(mockEC2Client)
+	m.On("DescribeNetworkInterfaces", mock.Anything, mock.Anything, mock.Anything).Return(nil, dryRunOK)
+	m.On("DescribeSecurityGroups", mock.Anything, mock.MatchedBy(func(in *ec2.DescribeSecurityGroupsInput) bool {
+		return in.DryRun != nil && *in.DryRun
+	}), mock.Anything).Return(nil, dryRunOK)
+	identity := &defaultClient{sts: &fakeCallerIdentity{err: denied}}
+	checks = CheckPermissions(context.Background(), m, nil, PermissionOptions{
+		SecurityGroups: true,
+		TaggingAPI:     true,
+		S3:             true,
+		AssumeRole:     true,
+		SessionTags:    true,
+		Identity:       identity,
+	})
+	byAction = make(map[string]PermissionCheck)
+	for _, c := range checks {
+		byAction[c.Action] = c
+	}
+	assert.Len(t, checks, 8)
+	assert.True(t, byAction["ec2:DescribeSecurityGroups"].Allowed)
+	assert.True(t, byAction["sts:GetCallerIdentity"].Denied)
+	for _, action := range []string{"tag:TagResources", "tag:UntagResources", "s3:PutObject", "sts:AssumeRole", "sts:TagSession"} {
+		assert.False(t, byAction[action].Allowed, action)
+		assert.False(t, byAction[action].Denied, action)
+		assert.ErrorIs(t, byAction[action].Err, ErrPermissionUnverifiable, action)
+	}
+	m.AssertExpectations(t)
+
+	identity.sts = &fakeCallerIdentity{account: "111111111111"}
+	checks = CheckPermissions(context.Background(), m, nil, PermissionOptions{Identity: identity})
+	require.Len(t, checks, 2)
+	assert.True(t, checks[1].Allowed)
+}
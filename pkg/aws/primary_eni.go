@@ -0,0 +1,48 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// PrimaryENIFinder finds the primary ENI (device index 0) of an EC2 instance.
+// The client returned by NewClientWithOptions implements it with the tagging
+// client's rate limiter and retries.
+type PrimaryENIFinder interface {
+	GetPrimaryENIInfo(ctx context.Context, instanceID string) (*ENIInfo, error)
+}
+
+var _ PrimaryENIFinder = (*defaultClient)(nil)
+
+// InstanceIDFromProviderID returns the EC2 instance ID in a Node's provider ID,
+// "aws:///<zone>/<instance-id>".
+func InstanceIDFromProviderID(providerID string) (string, error) {
+	rest, ok := strings.CutPrefix(providerID, "aws://")
+	id := rest[strings.LastIndex(rest, "/")+1:]
+	if !ok || !strings.HasPrefix(id, "i-") {
+		return "", fmt.Errorf("provider ID %q does not name an EC2 instance", providerID)
+	}
+	return id, nil
+}
+
+// GetPrimaryENIInfo returns the primary ENI of the instance instanceID.
+func (c *defaultClient) GetPrimaryENIInfo(ctx context.Context, instanceID string) (*ENIInfo, error) {
+	var info *ENIInfo
+	err := c.describeENIs(ctx, []types.Filter{
+		{Name: aws.String("attachment.instance-id"), Values: []string{instanceID}},
+		{Name: aws.String("attachment.device-index"), Values: []string{"0"}},
+	}, func(eni types.NetworkInterface) {
+		info = newENIInfo(eni)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return nil, fmt.Errorf("no primary ENI found for instance %s", instanceID)
+	}
+	return info, nil
+}
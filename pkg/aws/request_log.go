@@ -0,0 +1,75 @@
+package aws
+
+import (
+	"context"
+	"sync"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// maxLoggedRequests bounds the requests a RequestLog keeps; later ones are
+// only counted.
+const maxLoggedRequests = 32
+
+// AWSRequest is one AWS API call, with the request ID AWS support asks for.
+type AWSRequest struct {
+	Operation string `json:"operation"`
+	RequestID string `json:"requestID,omitempty"`
+	// ErrorCode is the AWS error code of a failed call.
+	ErrorCode string `json:"errorCode,omitempty"`
+}
+
+// RequestLog collects the AWS calls made with a context, see WithRequestLog.
+type RequestLog struct {
+	mu       sync.Mutex
+	requests []AWSRequest
+	dropped  int
+}
+
+type requestLogKey struct{}
+
+// WithRequestLog returns a context whose EC2 and tagging API calls are recorded
+// in the returned log, including each retry attempt. Calls made with another
+// context are not; a merged CreateTags call is recorded in the log of the
+// first caller whose call was merged.
+func WithRequestLog(ctx context.Context) (context.Context, *RequestLog) {
+	l := &RequestLog{}
+	return context.WithValue(ctx, requestLogKey{}, l), l
+}
+
+// Requests returns the recorded calls, oldest first, and how many more were
+// made beyond the ones kept.
+func (l *RequestLog) Requests() ([]AWSRequest, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]AWSRequest(nil), l.requests...), l.dropped
+}
+
+func (l *RequestLog) add(r AWSRequest) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.requests) >= maxLoggedRequests {
+		l.dropped++
+		return
+	}
+	l.requests = append(l.requests, r)
+}
+
+// addRequestLog registers middleware recording each HTTP exchange in the
+// context's RequestLog, if any.
+func addRequestLog(stack *middleware.Stack) error {
+	return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc("ENITaggerRequestLog",
+		func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+			out, metadata, err := next.HandleDeserialize(ctx, in)
+			if l, ok := ctx.Value(requestLogKey{}).(*RequestLog); ok {
+				r := AWSRequest{Operation: awsmiddleware.GetOperationName(ctx)}
+				r.RequestID, _ = awsmiddleware.GetRequestIDMetadata(metadata)
+				if err != nil {
+					r.ErrorCode = ErrorCode(err)
+				}
+				l.add(r)
+			}
+			return out, metadata, err
+		}), middleware.Before)
+}
@@ -0,0 +1,56 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLog(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`<Response><Errors><Error><Code>RequestLimitExceeded</Code><Message>slow down</Message></Error></Errors><RequestID>req-1</RequestID></Response>`))
+			return
+		}
+		w.Header().Set("X-Amzn-Requestid", "req-2")
+		_, _ = w.Write([]byte(`<DescribeNetworkInterfacesResponse><requestId>req-2</requestId><networkInterfaceSet><item><networkInterfaceId>eni-1</networkInterfaceId></item></networkInterfaceSet></DescribeNetworkInterfacesResponse>`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("AWS_ENDPOINT_URL", srv.URL)
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	c, err := NewClientWithOptions(context.Background(), ClientOptions{RateLimit: DefaultRateLimitConfig()})
+	require.NoError(t, err)
+
+	ctx, requestLog := WithRequestLog(context.Background())
+	_, err = c.GetENIInfoByIP(ctx, "10.0.0.1")
+	require.NoError(t, err)
+
+	requests, dropped := requestLog.Requests()
+	assert.Zero(t, dropped)
+	assert.Equal(t, []AWSRequest{
+		{Operation: "DescribeNetworkInterfaces", RequestID: "req-1", ErrorCode: "RequestLimitExceeded"},
+		{Operation: "DescribeNetworkInterfaces", RequestID: "req-2"},
+	}, requests)
+}
+
+func TestRequestLogLimit(t *testing.T) {
+	_, l := WithRequestLog(context.Background())
+	for range maxLoggedRequests + 3 {
+		l.add(AWSRequest{Operation: "CreateTags"})
+	}
+	requests, dropped := l.Requests()
+	assert.Len(t, requests, maxLoggedRequests)
+	assert.Equal(t, 3, dropped)
+}
@@ -0,0 +1,164 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"k8s-eni-tagger/pkg/metrics"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/smithy-go"
+)
+
+// s3ServiceID is the S3 service ID, for AWS_ENDPOINT_URL_S3.
+const s3ServiceID = "S3"
+
+// ObjectWriter uploads objects to S3. The client returned by
+// NewClientWithOptions implements it with the tagging client's credentials,
+// rate limiter and retries.
+type ObjectWriter interface {
+	PutObject(ctx context.Context, bucket, key, contentType string, body []byte) error
+}
+
+var _ ObjectWriter = (*defaultClient)(nil)
+
+// s3Target is what PutObject needs to sign and send requests. The EC2 SDK module
+// has no S3 client, and a single PutObject does not justify another SDK module,
+// so requests are signed directly.
+type s3Target struct {
+	httpClient  aws.HTTPClient
+	credentials aws.CredentialsProvider
+	region      string
+	// endpoint overrides the regional endpoint and switches to path-style URLs.
+	endpoint string
+	signer   *v4.Signer
+}
+
+func newS3Target(cfg aws.Config) (*s3Target, error) {
+	endpoint, err := ResolveEndpoint(s3ServiceID, "")
+	if err != nil {
+		return nil, err
+	}
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &s3Target{
+		httpClient:  httpClient,
+		credentials: cfg.Credentials,
+		region:      cfg.Region,
+		endpoint:    endpoint.URL,
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			// S3 signs the path as sent instead of escaping it twice.
+			o.DisableURIPathEscaping = true
+		}),
+	}, nil
+}
+
+// objectURL returns the URL of an object: virtual-hosted style on the regional
+// endpoint, or path style for endpoint overrides and bucket names with dots,
+// which do not match the endpoint's TLS certificate.
+func (t *s3Target) objectURL(bucket, key string) string {
+	path := (&url.URL{Path: "/" + key}).EscapedPath()
+	if t.endpoint != "" {
+		return strings.TrimSuffix(t.endpoint, "/") + "/" + bucket + path
+	}
+	host := fmt.Sprintf("s3.%s.%s", t.region, PartitionDNSSuffix(PartitionForRegion(t.region)))
+	if strings.Contains(bucket, ".") {
+		return "https://" + host + "/" + bucket + path
+	}
+	return "https://" + bucket + "." + host + path
+}
+
+// s3Error is the XML error body returned by S3.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (t *s3Target) put(ctx context.Context, bucket, key, contentType string, body []byte) error {
+	if t.region == "" {
+		return fmt.Errorf("no AWS region configured for S3")
+	}
+	creds, err := t.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve AWS credentials: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, t.objectURL(bucket, key), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := t.signer.SignHTTP(ctx, creds, req, payloadHash, "s3", t.region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign S3 request: %w", err)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	// Map S3 errors to smithy API errors so they are categorized like EC2's.
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var s3Err s3Error
+	if xml.Unmarshal(respBody, &s3Err) != nil || s3Err.Code == "" {
+		s3Err = s3Error{Code: http.StatusText(resp.StatusCode), Message: strings.TrimSpace(string(respBody))}
+	}
+	fault := smithy.FaultClient
+	if resp.StatusCode >= 500 {
+		fault = smithy.FaultServer
+	}
+	return &smithy.GenericAPIError{Code: s3Err.Code, Message: s3Err.Message, Fault: fault}
+}
+
+// PutObject uploads body to bucket/key. It waits for the AWS rate limiter like
+// EC2 calls do and retries throttling and transient errors.
+func (c *defaultClient) PutObject(ctx context.Context, bucket, key, contentType string, body []byte) error {
+	if c.s3 == nil {
+		return fmt.Errorf("S3 uploads are not configured")
+	}
+	if c.readOnly {
+		return fmt.Errorf("not writing s3://%s/%s: %w", bucket, key, ErrReadOnly)
+	}
+
+	start := time.Now()
+	status := "success"
+	defer func() {
+		metrics.ObserveAWSAPILatency(ctx, "PutObject", status, time.Since(start).Seconds())
+	}()
+
+	err := c.doWithRetry(ctx, "PutObject", awsAPIMaxAttempts, func(ctx context.Context) error {
+		if err := c.wait(ctx); err != nil {
+			return fmt.Errorf("rate limiter wait: %w", err)
+		}
+		return c.s3.put(ctx, bucket, key, contentType, body)
+	})
+	if err != nil {
+		status = "error"
+		if categorizeAWSError(err).Category == AWSErrorPermission {
+			return fmt.Errorf("insufficient permissions to write s3://%s/%s (check s3:PutObject): %w", bucket, key, err)
+		}
+		return fmt.Errorf("failed to write s3://%s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package aws
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3ObjectURL(t *testing.T) {
+	target := &s3Target{region: "eu-west-1"}
+	assert.Equal(t, "https://reports.s3.eu-west-1.amazonaws.com/finops/dt=2024-01-02/a.csv", target.objectURL("reports", "finops/dt=2024-01-02/a.csv"))
+	assert.Equal(t, "https://s3.eu-west-1.amazonaws.com/my.reports/a.csv", target.objectURL("my.reports", "a.csv"))
+
+	target = &s3Target{region: "cn-north-1"}
+	assert.Equal(t, "https://reports.s3.cn-north-1.amazonaws.com.cn/a.csv", target.objectURL("reports", "a.csv"))
+
+	target = &s3Target{region: "us-east-1", endpoint: "http://localhost:4566/"}
+	assert.Equal(t, "http://localhost:4566/reports/a.csv", target.objectURL("reports", "a.csv"))
+}
+
+func TestPutObject(t *testing.T) {
+	newClient := func(t *testing.T, handler http.HandlerFunc) *defaultClient {
+		srv := httptest.NewServer(handler)
+		t.Cleanup(srv.Close)
+		rl, err := newRateLimiter(10, 20)
+		require.NoError(t, err)
+		return &defaultClient{
+			rateLimiter: rl,
+			s3: &s3Target{
+				httpClient:  srv.Client(),
+				credentials: credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+				region:      "us-east-1",
+				endpoint:    srv.URL,
+				signer:      v4.NewSigner(),
+			},
+		}
+	}
+
+	t.Run("uploads a signed object", func(t *testing.T) {
+		var gotBody string
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPut, r.Method)
+			assert.Equal(t, "/reports/dt=2024-01-02/a.csv", r.URL.Path)
+			assert.Equal(t, "text/csv", r.Header.Get("Content-Type"))
+			assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+			assert.Contains(t, r.Header.Get("Authorization"), "/us-east-1/s3/aws4_request")
+			assert.NotEmpty(t, r.Header.Get("X-Amz-Content-Sha256"))
+			body, _ := io.ReadAll(r.Body)
+			gotBody = string(body)
+		})
+
+		err := c.PutObject(context.Background(), "reports", "dt=2024-01-02/a.csv", "text/csv", []byte("a,b\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "a,b\n", gotBody)
+	})
+
+	t.Run("access denied is not retried", func(t *testing.T) {
+		var calls atomic.Int32
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+		})
+
+		err := c.PutObject(context.Background(), "reports", "a.csv", "text/csv", []byte("a"))
+		require.ErrorContains(t, err, "s3:PutObject")
+		assert.Equal(t, "AccessDenied", ErrorCode(err))
+		assert.Equal(t, int32(1), calls.Load())
+	})
+
+	t.Run("slow down is retried", func(t *testing.T) {
+		var calls atomic.Int32
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = io.WriteString(w, `<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`)
+			}
+		})
+
+		require.NoError(t, c.PutObject(context.Background(), "reports", "a.csv", "text/csv", []byte("a")))
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("no region", func(t *testing.T) {
+		c := newClient(t, func(w http.ResponseWriter, r *http.Request) {})
+		c.s3.region = ""
+		assert.ErrorContains(t, c.PutObject(context.Background(), "reports", "a.csv", "text/csv", nil), "region")
+	})
+}
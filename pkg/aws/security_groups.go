@@ -0,0 +1,157 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"k8s-eni-tagger/pkg/audit"
+	"k8s-eni-tagger/pkg/metrics"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+// SecurityGroupTagger reads and changes the tags of the security groups attached
+// to ENIs. The client returned by NewClientWithOptions implements it with the
+// tagging client's rate limiter, retries, mutation budget and audit log.
+type SecurityGroupTagger interface {
+	// GetSecurityGroupTags returns the tags of each of groupIDs, keyed by group ID.
+	GetSecurityGroupTags(ctx context.Context, groupIDs []string) (map[string]map[string]string, error)
+	TagSecurityGroups(ctx context.Context, groupIDs []string, tags map[string]string) error
+	UntagSecurityGroups(ctx context.Context, groupIDs []string, tagKeys []string) error
+	// FindSecurityGroupENIs returns the IDs of the ENIs attached to groupID that
+	// carry the tag key=value.
+	FindSecurityGroupENIs(ctx context.Context, groupID, key, value string) ([]string, error)
+}
+
+var _ SecurityGroupTagger = (*defaultClient)(nil)
+
+// GetSecurityGroupTags describes groupIDs and returns their tags.
+func (c *defaultClient) GetSecurityGroupTags(ctx context.Context, groupIDs []string) (map[string]map[string]string, error) {
+	tags := make(map[string]map[string]string, len(groupIDs))
+	if len(groupIDs) == 0 {
+		return tags, nil
+	}
+
+	start := time.Now()
+	status := "success"
+	defer func() {
+		metrics.ObserveAWSAPILatency(ctx, "DescribeSecurityGroups", status, time.Since(start).Seconds())
+	}()
+
+	input := &ec2.DescribeSecurityGroupsInput{GroupIds: groupIDs}
+	for {
+		var result *ec2.DescribeSecurityGroupsOutput
+		err := c.doWithRetry(ctx, "DescribeSecurityGroups", awsAPIMaxAttempts, func(ctx context.Context) error {
+			if err := c.wait(ctx); err != nil {
+				return fmt.Errorf("rate limiter wait: %w", err)
+			}
+			var callErr error
+			result, callErr = c.ec2Client.DescribeSecurityGroups(ctx, input, c.sessions.ec2Options(ctx)...)
+			return callErr
+		})
+		if err != nil {
+			status = "error"
+			if categorizeAWSError(err).Category == AWSErrorPermission {
+				return nil, fmt.Errorf("insufficient permissions to describe security groups (check ec2:DescribeSecurityGroups): %w", err)
+			}
+			return nil, fmt.Errorf("failed to describe security groups %v: %w", groupIDs, err)
+		}
+		for _, sg := range result.SecurityGroups {
+			groupTags := make(map[string]string, len(sg.Tags))
+			for _, t := range sg.Tags {
+				if aws.ToString(t.Key) != "" && t.Value != nil {
+					groupTags[*t.Key] = *t.Value
+				}
+			}
+			tags[aws.ToString(sg.GroupId)] = groupTags
+		}
+		if aws.ToString(result.NextToken) == "" {
+			return tags, nil
+		}
+		input.NextToken = result.NextToken
+	}
+}
+
+// TagSecurityGroups adds tags to every group in groupIDs with as few calls as
+// the tag backend allows: one with EC2.
+func (c *defaultClient) TagSecurityGroups(ctx context.Context, groupIDs []string, tags map[string]string) error {
+	if len(groupIDs) == 0 || len(tags) == 0 {
+		return nil
+	}
+	backend := c.backend()
+	err := c.changeResourceTags(ctx, backend.TagOperation(), resourceTypeSecurityGroup, groupIDs, func(ctx context.Context, ids []string) error {
+		return backend.TagResources(ctx, resourceTypeSecurityGroup, ids, tags)
+	})
+	c.recordAudit(ctx, audit.Entry{Operation: audit.OperationCreateTags, SecurityGroupIDs: groupIDs, Tags: tags}, err)
+	return err
+}
+
+// UntagSecurityGroups removes tagKeys from every group in groupIDs with as few
+// calls as the tag backend allows.
+func (c *defaultClient) UntagSecurityGroups(ctx context.Context, groupIDs []string, tagKeys []string) error {
+	if len(groupIDs) == 0 || len(tagKeys) == 0 {
+		return nil
+	}
+	backend := c.backend()
+	err := c.changeResourceTags(ctx, backend.UntagOperation(), resourceTypeSecurityGroup, groupIDs, func(ctx context.Context, ids []string) error {
+		return backend.UntagResources(ctx, resourceTypeSecurityGroup, ids, tagKeys)
+	})
+	c.recordAudit(ctx, audit.Entry{Operation: audit.OperationDeleteTags, SecurityGroupIDs: groupIDs, TagKeys: tagKeys}, err)
+	return err
+}
+
+// changeResourceTags makes the tag backend call op on resourceIDs, EC2 resources
+// of type resourceType (e.g. "security-group"), in batches of at most the
+// backend's MaxResources, with retries, counting each attempt against the
+// mutation budget. It stops at the first batch that fails.
+func (c *defaultClient) changeResourceTags(ctx context.Context, op, resourceType string, resourceIDs []string, call func(ctx context.Context, ids []string) error) error {
+	start := time.Now()
+	status := "success"
+	defer func() {
+		metrics.ObserveAWSAPILatency(ctx, op, status, time.Since(start).Seconds())
+	}()
+
+	var err error
+	for batch := range slices.Chunk(resourceIDs, c.backend().MaxResources()) {
+		err = c.doWithRetry(ctx, op, awsAPIMaxAttempts, func(ctx context.Context) error {
+			if err := c.wait(ctx); err != nil {
+				return fmt.Errorf("rate limiter wait: %w", err)
+			}
+			c.mutations.Record()
+			return call(ctx, batch)
+		})
+		if err != nil {
+			break
+		}
+	}
+	if err != nil {
+		status = "error"
+		if categorizeAWSError(err).Category == AWSErrorPermission {
+			return fmt.Errorf("insufficient permissions to change tags of %s resources %v (check %s): %w", resourceType, resourceIDs, requiredPermissions(op), err)
+		}
+		return fmt.Errorf("failed to change tags of %s resources %v: %w", resourceType, resourceIDs, err)
+	}
+	return nil
+}
+
+// FindSecurityGroupENIs returns the sorted IDs of the ENIs attached to groupID
+// that carry the tag key=value.
+func (c *defaultClient) FindSecurityGroupENIs(ctx context.Context, groupID, key, value string) ([]string, error) {
+	var ids []string
+	err := c.describeENIs(ctx, []types.Filter{
+		{Name: aws.String("group-id"), Values: []string{groupID}},
+		{Name: aws.String("tag:" + key), Values: []string{value}},
+	}, func(eni types.NetworkInterface) {
+		ids = append(ids, aws.ToString(eni.NetworkInterfaceId))
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSecurityGroupTags(t *testing.T) {
+	mockClient := new(mockEC2Client)
+	mockClient.On("DescribeSecurityGroups", mock.Anything, mock.MatchedBy(func(input *ec2.DescribeSecurityGroupsInput) bool {
+		return len(input.GroupIds) == 2
+	}), mock.Anything).Return(&ec2.DescribeSecurityGroupsOutput{SecurityGroups: []types.SecurityGroup{
+		{GroupId: aws.String("sg-1"), Tags: []types.Tag{{Key: aws.String("team"), Value: aws.String("a")}}},
+		{GroupId: aws.String("sg-2")},
+	}}, nil)
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+	c := &defaultClient{ec2Client: mockClient, rateLimiter: rl}
+
+	tags, err := c.GetSecurityGroupTags(context.Background(), []string{"sg-1", "sg-2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"sg-1": {"team": "a"}, "sg-2": {}}, tags)
+}
+
+func TestTagSecurityGroups(t *testing.T) {
+	mockClient := new(mockEC2Client)
+	mockClient.On("CreateTags", mock.Anything, mock.MatchedBy(func(input *ec2.CreateTagsInput) bool {
+		return assert.ObjectsAreEqual([]string{"sg-1", "sg-2"}, input.Resources) && len(input.Tags) == 1
+	}), mock.Anything).Return(&ec2.CreateTagsOutput{}, nil).Once()
+	mockClient.On("DeleteTags", mock.Anything, mock.MatchedBy(func(input *ec2.DeleteTagsInput) bool {
+		return assert.ObjectsAreEqual([]string{"sg-1"}, input.Resources) && aws.ToString(input.Tags[0].Key) == "team"
+	}), mock.Anything).Return(&ec2.DeleteTagsOutput{}, nil).Once()
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+	c := &defaultClient{ec2Client: mockClient, rateLimiter: rl}
+
+	require.NoError(t, c.TagSecurityGroups(context.Background(), []string{"sg-1", "sg-2"}, map[string]string{"team": "a"}))
+	require.NoError(t, c.UntagSecurityGroups(context.Background(), []string{"sg-1"}, []string{"team"}))
+	// Nothing to change makes no call
+	require.NoError(t, c.TagSecurityGroups(context.Background(), nil, map[string]string{"team": "a"}))
+	mockClient.AssertExpectations(t)
+}
+
+func TestNewENIInfoSecurityGroups(t *testing.T) {
+	info := newENIInfo(types.NetworkInterface{
+		NetworkInterfaceId: aws.String("eni-1"),
+		Groups:             []types.GroupIdentifier{{GroupId: aws.String("sg-1")}, {GroupId: aws.String("sg-2")}},
+	})
+	assert.Equal(t, []string{"sg-1", "sg-2"}, info.SecurityGroupIDs)
+}
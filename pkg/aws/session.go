@@ -0,0 +1,201 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+// Session tag keys attached to assumed-role sessions. They show up in the
+// target account's CloudTrail records under sessionContext.
+const (
+	SessionTagCluster   = "kubernetes-cluster"
+	SessionTagNamespace = "kubernetes-namespace"
+	SessionTagPod       = "kubernetes-pod"
+)
+
+const (
+	baseSessionName = "k8s-eni-tagger"
+	// sessionIdleTTL drops per-pod sessions nobody used for a while; pods
+	// that come back simply assume the role again.
+	sessionIdleTTL = time.Hour
+	// sessionPruneInterval bounds how often the idle sweep runs.
+	sessionPruneInterval = time.Minute
+)
+
+// invalidSessionNameChars matches characters STS rejects in RoleSessionName.
+var invalidSessionNameChars = regexp.MustCompile(`[^\w+=,.@-]`)
+
+// AssumeRoleConfig configures cross-account role assumption for EC2 calls.
+type AssumeRoleConfig struct {
+	// RoleARN is the role to assume. Empty disables role assumption.
+	RoleARN string
+	// ExternalID is passed to sts:AssumeRole when set.
+	ExternalID string
+	// SessionTags attaches cluster, namespace and pod session tags, assuming
+	// the role once per source pod. The role trust policy must allow
+	// sts:TagSession.
+	SessionTags bool
+	// ClusterName is the value of the cluster session tag (omitted if empty).
+	ClusterName string
+}
+
+// PodIdentity identifies the pod an AWS call is made on behalf of.
+type PodIdentity struct {
+	Namespace string
+	Name      string
+}
+
+type podIdentityKey struct{}
+
+// WithPodIdentity records the pod a call is made for, so assumed-role sessions
+// can be tagged with it.
+func WithPodIdentity(ctx context.Context, namespace, name string) context.Context {
+	return context.WithValue(ctx, podIdentityKey{}, PodIdentity{Namespace: namespace, Name: name})
+}
+
+func podIdentityFrom(ctx context.Context) (PodIdentity, bool) {
+	id, ok := ctx.Value(podIdentityKey{}).(PodIdentity)
+	return id, ok
+}
+
+// roleSessions hands out assumed-role credentials: one base session for calls
+// without a pod (health checks, permission checks) and, with session tags, one
+// session per source pod.
+type roleSessions struct {
+	client stscreds.AssumeRoleAPIClient
+	cfg    AssumeRoleConfig
+	base   *aws.CredentialsCache
+
+	mu        sync.Mutex
+	sessions  map[PodIdentity]*roleSession
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type roleSession struct {
+	creds    *aws.CredentialsCache
+	lastUsed time.Time
+}
+
+func newRoleSessions(client stscreds.AssumeRoleAPIClient, cfg AssumeRoleConfig) (*roleSessions, error) {
+	roleARN, err := arn.Parse(cfg.RoleARN)
+	if err != nil || roleARN.Service != "iam" {
+		return nil, fmt.Errorf("invalid assume role ARN %q", cfg.RoleARN)
+	}
+	s := &roleSessions{
+		client:   client,
+		cfg:      cfg,
+		sessions: make(map[PodIdentity]*roleSession),
+		now:      time.Now,
+	}
+	s.base = s.provider(PodIdentity{})
+	return s, nil
+}
+
+// provider builds a cached AssumeRole provider for id. The zero PodIdentity
+// only carries the cluster tag.
+func (s *roleSessions) provider(id PodIdentity) *aws.CredentialsCache {
+	return aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(s.client, s.cfg.RoleARN, func(o *stscreds.AssumeRoleOptions) {
+		o.RoleSessionName = sessionName(id)
+		if s.cfg.ExternalID != "" {
+			o.ExternalID = aws.String(s.cfg.ExternalID)
+		}
+		if s.cfg.SessionTags {
+			o.Tags = sessionTags(s.cfg.ClusterName, id)
+		}
+	}))
+}
+
+// credentialsFor returns the provider for the pod in ctx, or nil when the base
+// session applies.
+func (s *roleSessions) credentialsFor(ctx context.Context) aws.CredentialsProvider {
+	if !s.cfg.SessionTags {
+		return nil
+	}
+	id, ok := podIdentityFrom(ctx)
+	if !ok {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.lastPrune) >= sessionPruneInterval {
+		for key, sess := range s.sessions {
+			if now.Sub(sess.lastUsed) > sessionIdleTTL {
+				delete(s.sessions, key)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	sess, ok := s.sessions[id]
+	if !ok {
+		sess = &roleSession{creds: s.provider(id)}
+		s.sessions[id] = sess
+	}
+	sess.lastUsed = now
+	return sess.creds
+}
+
+// ec2Options returns per-call options selecting the session for the pod in
+// ctx, or nil when the client's default credentials apply.
+func (s *roleSessions) ec2Options(ctx context.Context) []func(*ec2.Options) {
+	if s == nil {
+		return nil
+	}
+	creds := s.credentialsFor(ctx)
+	if creds == nil {
+		return nil
+	}
+	return []func(*ec2.Options){func(o *ec2.Options) { o.Credentials = creds }}
+}
+
+// taggingOptions is ec2Options for Resource Groups Tagging API calls.
+func (s *roleSessions) taggingOptions(ctx context.Context) []func(*resourcegroupstaggingapi.Options) {
+	if s == nil {
+		return nil
+	}
+	creds := s.credentialsFor(ctx)
+	if creds == nil {
+		return nil
+	}
+	return []func(*resourcegroupstaggingapi.Options){func(o *resourcegroupstaggingapi.Options) { o.Credentials = creds }}
+}
+
+func sessionTags(cluster string, id PodIdentity) []ststypes.Tag {
+	var tags []ststypes.Tag
+	add := func(k, v string) {
+		if v != "" {
+			tags = append(tags, ststypes.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+	}
+	add(SessionTagCluster, cluster)
+	add(SessionTagNamespace, id.Namespace)
+	add(SessionTagPod, id.Name)
+	return tags
+}
+
+// sessionName names the STS session after the pod so it is visible in the
+// assumed-role ARN. STS limits names to 64 characters of [\w+=,.@-]; the
+// session tags carry the untruncated values.
+func sessionName(id PodIdentity) string {
+	if id == (PodIdentity{}) {
+		return baseSessionName
+	}
+	name := invalidSessionNameChars.ReplaceAllString(fmt.Sprintf("eni-tagger@%s.%s", id.Namespace, id.Name), "-")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
@@ -0,0 +1,146 @@
+package aws
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSTS records AssumeRole calls and hands out credentials named after the session.
+type fakeSTS struct {
+	mu    sync.Mutex
+	calls []*sts.AssumeRoleInput
+}
+
+func (f *fakeSTS) AssumeRole(_ context.Context, in *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, in)
+	return &sts.AssumeRoleOutput{Credentials: &ststypes.Credentials{
+		AccessKeyId:     in.RoleSessionName,
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+func tagMap(tags []ststypes.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	return m
+}
+
+func TestRoleSessionsPerPod(t *testing.T) {
+	fake := &fakeSTS{}
+	s, err := newRoleSessions(fake, AssumeRoleConfig{
+		RoleARN:     "arn:aws:iam::210987654321:role/eni-tagger",
+		ExternalID:  "ext-id",
+		SessionTags: true,
+		ClusterName: "prod",
+	})
+	require.NoError(t, err)
+
+	ctx := WithPodIdentity(context.Background(), "team-a", "web-0")
+	creds, err := s.credentialsFor(ctx).Retrieve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "eni-tagger@team-a.web-0", creds.AccessKeyID)
+
+	// The session is reused for the same pod.
+	_, err = s.credentialsFor(ctx).Retrieve(ctx)
+	require.NoError(t, err)
+	require.Len(t, fake.calls, 1)
+
+	call := fake.calls[0]
+	assert.Equal(t, "arn:aws:iam::210987654321:role/eni-tagger", aws.ToString(call.RoleArn))
+	assert.Equal(t, "ext-id", aws.ToString(call.ExternalId))
+	assert.Equal(t, map[string]string{
+		SessionTagCluster:   "prod",
+		SessionTagNamespace: "team-a",
+		SessionTagPod:       "web-0",
+	}, tagMap(call.Tags))
+
+	// Calls without a pod use the base session, tagged with the cluster only.
+	assert.Nil(t, s.credentialsFor(context.Background()))
+	_, err = s.base.Retrieve(context.Background())
+	require.NoError(t, err)
+	require.Len(t, fake.calls, 2)
+	assert.Equal(t, baseSessionName, aws.ToString(fake.calls[1].RoleSessionName))
+	assert.Equal(t, map[string]string{SessionTagCluster: "prod"}, tagMap(fake.calls[1].Tags))
+}
+
+func TestRoleSessionsWithoutTags(t *testing.T) {
+	fake := &fakeSTS{}
+	s, err := newRoleSessions(fake, AssumeRoleConfig{RoleARN: "arn:aws:iam::210987654321:role/eni-tagger", ClusterName: "prod"})
+	require.NoError(t, err)
+
+	ctx := WithPodIdentity(context.Background(), "team-a", "web-0")
+	assert.Nil(t, s.credentialsFor(ctx))
+	assert.Nil(t, s.ec2Options(ctx))
+
+	_, err = s.base.Retrieve(ctx)
+	require.NoError(t, err)
+	require.Len(t, fake.calls, 1)
+	assert.Empty(t, fake.calls[0].Tags)
+}
+
+func TestRoleSessionsPruneIdle(t *testing.T) {
+	s, err := newRoleSessions(&fakeSTS{}, AssumeRoleConfig{RoleARN: "arn:aws:iam::210987654321:role/eni-tagger", SessionTags: true})
+	require.NoError(t, err)
+	now := time.Unix(1_700_000_000, 0)
+	s.now = func() time.Time { return now }
+
+	s.credentialsFor(WithPodIdentity(context.Background(), "ns", "old"))
+	now = now.Add(sessionIdleTTL + time.Minute)
+	s.credentialsFor(WithPodIdentity(context.Background(), "ns", "new"))
+
+	assert.Len(t, s.sessions, 1)
+	assert.Contains(t, s.sessions, PodIdentity{Namespace: "ns", Name: "new"})
+}
+
+func TestNewRoleSessionsInvalidARN(t *testing.T) {
+	_, err := newRoleSessions(&fakeSTS{}, AssumeRoleConfig{RoleARN: "eni-tagger"})
+	assert.ErrorContains(t, err, "invalid assume role ARN")
+}
+
+func TestSessionName(t *testing.T) {
+	assert.Equal(t, baseSessionName, sessionName(PodIdentity{}))
+	assert.Equal(t, "eni-tagger@ns.pod-1", sessionName(PodIdentity{Namespace: "ns", Name: "pod-1"}))
+	assert.Equal(t, "eni-tagger@ns.a-b", sessionName(PodIdentity{Namespace: "ns", Name: "a:b"}))
+	assert.Len(t, sessionName(PodIdentity{Namespace: "ns", Name: strings.Repeat("x", 100)}), 64)
+}
+
+func TestTagENIUsesPodSession(t *testing.T) {
+	mockEC2 := new(mockEC2Client)
+	s, err := newRoleSessions(&fakeSTS{}, AssumeRoleConfig{RoleARN: "arn:aws:iam::210987654321:role/eni-tagger", SessionTags: true})
+	require.NoError(t, err)
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+	c := &defaultClient{ec2Client: mockEC2, rateLimiter: rl, sessions: s}
+
+	var got []func(*ec2.Options)
+	mockEC2.On("CreateTags", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { got = args.Get(2).([]func(*ec2.Options)) }).
+		Return(&ec2.CreateTagsOutput{}, nil)
+
+	ctx := WithPodIdentity(context.Background(), "team-a", "web-0")
+	require.NoError(t, c.TagENI(ctx, "eni-1", map[string]string{"k": "v"}))
+
+	require.Len(t, got, 1)
+	var opts ec2.Options
+	got[0](&opts)
+	creds, err := opts.Credentials.Retrieve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "eni-tagger@team-a.web-0", creds.AccessKeyID)
+}
@@ -0,0 +1,222 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	taggingtypes "github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+)
+
+// Tag backends, selected with ClientOptions.TagBackend.
+const (
+	// TagBackendEC2 changes tags with EC2 CreateTags and DeleteTags (default).
+	TagBackendEC2 = "ec2"
+	// TagBackendResourceGroupsTagging changes tags with the Resource Groups
+	// Tagging API's TagResources and UntagResources, addressing resources by ARN.
+	TagBackendResourceGroupsTagging = "resourcegroupstaggingapi"
+)
+
+// EC2 resource types tagged by the client, as named in their ARNs.
+const (
+	resourceTypeNetworkInterface = "network-interface"
+	resourceTypeSecurityGroup    = "security-group"
+	resourceTypeVolume           = "volume"
+)
+
+const (
+	// ec2MaxTagResources bounds the resources of one CreateTags or DeleteTags call.
+	ec2MaxTagResources = 1000
+	// taggingAPIMaxResources is the most ARNs TagResources and UntagResources take.
+	taggingAPIMaxResources = 20
+)
+
+// TagBackend makes the API calls that change the tags of EC2 resources. It makes
+// a single attempt per call: the client adds rate limiting, retries, the mutation
+// budget, latency metrics and the audit log around it.
+type TagBackend interface {
+	// TagOperation and UntagOperation name the calls made by TagResources and
+	// UntagResources, e.g. "CreateTags", in metrics, logs and error messages.
+	TagOperation() string
+	UntagOperation() string
+	// MaxResources is the most resources one call can change.
+	MaxResources() int
+	// TagResources adds tags to resourceIDs, EC2 resources of resourceType
+	// (e.g. "network-interface"), with one call.
+	TagResources(ctx context.Context, resourceType string, resourceIDs []string, tags map[string]string) error
+	// UntagResources removes tagKeys from resourceIDs with one call.
+	UntagResources(ctx context.Context, resourceType string, resourceIDs []string, tagKeys []string) error
+}
+
+// ec2TagBackend changes tags with EC2 CreateTags and DeleteTags.
+type ec2TagBackend struct {
+	client   EC2API
+	sessions *roleSessions
+}
+
+var _ TagBackend = ec2TagBackend{}
+
+func (ec2TagBackend) TagOperation() string   { return "CreateTags" }
+func (ec2TagBackend) UntagOperation() string { return "DeleteTags" }
+func (ec2TagBackend) MaxResources() int      { return ec2MaxTagResources }
+
+func (b ec2TagBackend) TagResources(ctx context.Context, _ string, resourceIDs []string, tags map[string]string) error {
+	ec2Tags := make([]types.Tag, 0, len(tags))
+	for k, v := range tags {
+		ec2Tags = append(ec2Tags, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	_, err := b.client.CreateTags(ctx, &ec2.CreateTagsInput{Resources: resourceIDs, Tags: ec2Tags}, b.sessions.ec2Options(ctx)...)
+	return err
+}
+
+func (b ec2TagBackend) UntagResources(ctx context.Context, _ string, resourceIDs []string, tagKeys []string) error {
+	ec2Tags := make([]types.Tag, 0, len(tagKeys))
+	for _, k := range tagKeys {
+		ec2Tags = append(ec2Tags, types.Tag{Key: aws.String(k)})
+	}
+	_, err := b.client.DeleteTags(ctx, &ec2.DeleteTagsInput{Resources: resourceIDs, Tags: ec2Tags}, b.sessions.ec2Options(ctx)...)
+	return err
+}
+
+// ErrReadOnly is returned by the calls of a read-only client that would change
+// AWS resources (see ClientOptions.ReadOnly).
+var ErrReadOnly = errors.New("AWS client is read-only")
+
+// readOnlyBackend refuses every tag change of the backend it wraps, which still
+// names the operations in metrics and errors.
+type readOnlyBackend struct {
+	TagBackend
+}
+
+func (readOnlyBackend) TagResources(context.Context, string, []string, map[string]string) error {
+	return ErrReadOnly
+}
+
+func (readOnlyBackend) UntagResources(context.Context, string, []string, []string) error {
+	return ErrReadOnly
+}
+
+// TaggingAPI defines the Resource Groups Tagging API operations used by this
+// package. This allows for mocking in tests.
+type TaggingAPI interface {
+	TagResources(ctx context.Context, params *resourcegroupstaggingapi.TagResourcesInput, optFns ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.TagResourcesOutput, error)
+	UntagResources(ctx context.Context, params *resourcegroupstaggingapi.UntagResourcesInput, optFns ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.UntagResourcesOutput, error)
+}
+
+// taggingAPIBackend changes tags with the Resource Groups Tagging API, which
+// takes up to 20 resource ARNs per call. Resources are addressed in the region
+// and account the client's credentials belong to.
+type taggingAPIBackend struct {
+	client    TaggingAPI
+	sessions  *roleSessions
+	partition string
+	region    string
+	accountID string
+}
+
+var _ TagBackend = (*taggingAPIBackend)(nil)
+
+func (*taggingAPIBackend) TagOperation() string   { return "TagResources" }
+func (*taggingAPIBackend) UntagOperation() string { return "UntagResources" }
+func (*taggingAPIBackend) MaxResources() int      { return taggingAPIMaxResources }
+
+func (b *taggingAPIBackend) TagResources(ctx context.Context, resourceType string, resourceIDs []string, tags map[string]string) error {
+	out, err := b.client.TagResources(ctx, &resourcegroupstaggingapi.TagResourcesInput{
+		ResourceARNList: b.arns(resourceType, resourceIDs),
+		Tags:            tags,
+	}, b.sessions.taggingOptions(ctx)...)
+	if err != nil {
+		return err
+	}
+	return failedResourcesError(out.FailedResourcesMap, len(resourceIDs))
+}
+
+func (b *taggingAPIBackend) UntagResources(ctx context.Context, resourceType string, resourceIDs []string, tagKeys []string) error {
+	out, err := b.client.UntagResources(ctx, &resourcegroupstaggingapi.UntagResourcesInput{
+		ResourceARNList: b.arns(resourceType, resourceIDs),
+		TagKeys:         tagKeys,
+	}, b.sessions.taggingOptions(ctx)...)
+	if err != nil {
+		return err
+	}
+	return failedResourcesError(out.FailedResourcesMap, len(resourceIDs))
+}
+
+// arns returns the ARNs of resourceIDs, e.g.
+// arn:aws:ec2:us-east-1:123456789012:network-interface/eni-0abc.
+func (b *taggingAPIBackend) arns(resourceType string, resourceIDs []string) []string {
+	arns := make([]string, len(resourceIDs))
+	for i, id := range resourceIDs {
+		arns[i] = arn.ARN{
+			Partition: b.partition,
+			Service:   "ec2",
+			Region:    b.region,
+			AccountID: b.accountID,
+			Resource:  resourceType + "/" + id,
+		}.String()
+	}
+	return arns
+}
+
+// failedResourcesError turns the per-resource failures the Tagging API reports
+// in a successful response into an error carrying the code of the first failed
+// ARN, so it is categorized and retried like a failed EC2 call. Retrying the
+// whole call is safe: tag changes are idempotent.
+func failedResourcesError(failed map[string]taggingtypes.FailureInfo, total int) error {
+	if len(failed) == 0 {
+		return nil
+	}
+	arns := make([]string, 0, len(failed))
+	for a := range failed {
+		arns = append(arns, a)
+	}
+	slices.Sort(arns)
+	first := failed[arns[0]]
+	code := string(first.ErrorCode)
+	message := aws.ToString(first.ErrorMessage)
+	if strings.Contains(strings.ToLower(message), "tag polic") {
+		code = tagPolicyViolationCode
+	}
+	return fmt.Errorf("%d of %d resources failed, first %s: %w", len(failed), total, arns[0], &smithy.GenericAPIError{Code: code, Message: message})
+}
+
+// newTaggingAPIBackend returns a Tagging API backend addressing resources in
+// cfg's region and in the account of roleARN, or of cfg's credentials when
+// roleARN is empty.
+func newTaggingAPIBackend(ctx context.Context, cfg aws.Config, client TaggingAPI, sessions *roleSessions, roleARN string) (*taggingAPIBackend, error) {
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("the %s tag backend needs an AWS region", TagBackendResourceGroupsTagging)
+	}
+	accountID, err := lookupAccountID(ctx, sts.NewFromConfig(cfg), roleARN)
+	if err != nil {
+		return nil, fmt.Errorf("resource ARNs: %w", err)
+	}
+	return &taggingAPIBackend{
+		client:    client,
+		sessions:  sessions,
+		partition: PartitionForRegion(cfg.Region),
+		region:    cfg.Region,
+		accountID: accountID,
+	}, nil
+}
+
+// requiredPermissions names the IAM actions a tag backend call op needs.
+func requiredPermissions(op string) string {
+	switch op {
+	case "TagResources":
+		return "tag:TagResources and ec2:CreateTags"
+	case "UntagResources":
+		return "tag:UntagResources and ec2:DeleteTags"
+	default:
+		return "ec2:" + op
+	}
+}
@@ -0,0 +1,137 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi"
+	taggingtypes "github.com/aws/aws-sdk-go-v2/service/resourcegroupstaggingapi/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTaggingAPI records the ARNs of each call and fails the ARNs in failed.
+type fakeTaggingAPI struct {
+	tagCalls   [][]string
+	untagCalls [][]string
+	tags       map[string]string
+	tagKeys    []string
+	failed     map[string]taggingtypes.FailureInfo
+}
+
+func (f *fakeTaggingAPI) TagResources(_ context.Context, params *resourcegroupstaggingapi.TagResourcesInput, _ ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.TagResourcesOutput, error) {
+	f.tagCalls = append(f.tagCalls, params.ResourceARNList)
+	f.tags = params.Tags
+	return &resourcegroupstaggingapi.TagResourcesOutput{FailedResourcesMap: f.failed}, nil
+}
+
+func (f *fakeTaggingAPI) UntagResources(_ context.Context, params *resourcegroupstaggingapi.UntagResourcesInput, _ ...func(*resourcegroupstaggingapi.Options)) (*resourcegroupstaggingapi.UntagResourcesOutput, error) {
+	f.untagCalls = append(f.untagCalls, params.ResourceARNList)
+	f.tagKeys = params.TagKeys
+	return &resourcegroupstaggingapi.UntagResourcesOutput{FailedResourcesMap: f.failed}, nil
+}
+
+func newTaggingAPITestClient(t *testing.T, api *fakeTaggingAPI) *defaultClient {
+	t.Helper()
+	rl, err := newRateLimiter(100, 100)
+	require.NoError(t, err)
+	return &defaultClient{
+		ec2Client:   new(mockEC2Client),
+		rateLimiter: rl,
+		tagBackend:  &taggingAPIBackend{client: api, partition: "aws", region: "us-east-1", accountID: "123456789012"},
+	}
+}
+
+func TestTaggingAPIBackendTagENI(t *testing.T) {
+	api := &fakeTaggingAPI{}
+	c := newTaggingAPITestClient(t, api)
+
+	require.NoError(t, c.TagENI(context.Background(), "eni-0abc", map[string]string{"team": "a"}))
+	require.NoError(t, c.UntagENI(context.Background(), "eni-0abc", []string{"team"}))
+
+	arn := "arn:aws:ec2:us-east-1:123456789012:network-interface/eni-0abc"
+	assert.Equal(t, [][]string{{arn}}, api.tagCalls)
+	assert.Equal(t, [][]string{{arn}}, api.untagCalls)
+	assert.Equal(t, map[string]string{"team": "a"}, api.tags)
+	assert.Equal(t, []string{"team"}, api.tagKeys)
+}
+
+func TestTaggingAPIBackendBatchesResources(t *testing.T) {
+	api := &fakeTaggingAPI{}
+	c := newTaggingAPITestClient(t, api)
+
+	groupIDs := make([]string, 45)
+	for i := range groupIDs {
+		groupIDs[i] = fmt.Sprintf("sg-%d", i)
+	}
+	require.NoError(t, c.TagSecurityGroups(context.Background(), groupIDs, map[string]string{"team": "a"}))
+	require.NoError(t, c.UntagVolumes(context.Background(), []string{"vol-1"}, []string{"team"}))
+
+	require.Len(t, api.tagCalls, 3)
+	assert.Len(t, api.tagCalls[0], 20)
+	assert.Len(t, api.tagCalls[1], 20)
+	assert.Len(t, api.tagCalls[2], 5)
+	assert.Equal(t, "arn:aws:ec2:us-east-1:123456789012:security-group/sg-44", api.tagCalls[2][4])
+	assert.Equal(t, [][]string{{"arn:aws:ec2:us-east-1:123456789012:volume/vol-1"}}, api.untagCalls)
+}
+
+func TestTaggingAPIBackendFailedResources(t *testing.T) {
+	arn := "arn:aws:ec2:us-east-1:123456789012:network-interface/eni-0abc"
+
+	t.Run("Permission", func(t *testing.T) {
+		api := &fakeTaggingAPI{failed: map[string]taggingtypes.FailureInfo{
+			arn: {ErrorCode: "AccessDeniedException", ErrorMessage: aws.String("not authorized to perform ec2:CreateTags")},
+		}}
+		c := newTaggingAPITestClient(t, api)
+
+		err := c.TagENI(context.Background(), "eni-0abc", map[string]string{"team": "a"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "check tag:TagResources and ec2:CreateTags")
+		assert.Equal(t, AWSErrorPermission, categorizeAWSError(err).Category)
+		assert.Len(t, api.tagCalls, 1, "permission errors are not retried")
+	})
+
+	t.Run("TagPolicy", func(t *testing.T) {
+		api := &fakeTaggingAPI{failed: map[string]taggingtypes.FailureInfo{
+			arn: {ErrorCode: taggingtypes.ErrorCodeInvalidParameterException, ErrorMessage: aws.String("The tag policy does not allow the specified value for the following tag key: CostCenter")},
+		}}
+		c := newTaggingAPITestClient(t, api)
+
+		err := c.TagENI(context.Background(), "eni-0abc", map[string]string{"CostCenter": "x"})
+		var policyErr *TagPolicyViolationError
+		require.True(t, errors.As(err, &policyErr))
+	})
+}
+
+func TestNewTaggingAPIBackend(t *testing.T) {
+	_, err := newTaggingAPIBackend(context.Background(), aws.Config{}, &fakeTaggingAPI{}, nil, "")
+	require.ErrorContains(t, err, "needs an AWS region")
+
+	b, err := newTaggingAPIBackend(context.Background(), aws.Config{Region: "cn-north-1"}, &fakeTaggingAPI{}, nil, "arn:aws-cn:iam::111111111111:role/tagger")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"arn:aws-cn:ec2:cn-north-1:111111111111:volume/vol-1"}, b.arns(resourceTypeVolume, []string{"vol-1"}))
+}
+
+func TestRequiredPermissions(t *testing.T) {
+	assert.Equal(t, "ec2:CreateTags", requiredPermissions("CreateTags"))
+	assert.Equal(t, "tag:UntagResources and ec2:DeleteTags", requiredPermissions("UntagResources"))
+}
+
+func TestReadOnlyClient(t *testing.T) {
+	api := &fakeTaggingAPI{}
+	c := newTaggingAPITestClient(t, api)
+	c.readOnly = true
+
+	require.ErrorIs(t, c.TagENI(context.Background(), "eni-0abc", map[string]string{"team": "a"}), ErrReadOnly)
+	require.ErrorIs(t, c.UntagSecurityGroups(context.Background(), []string{"sg-1"}, []string{"team"}), ErrReadOnly)
+	assert.Empty(t, api.tagCalls)
+	assert.Empty(t, api.untagCalls)
+
+	// The EC2 backend is refused too; the mock fails on any CreateTags call
+	c.tagBackend = nil
+	require.ErrorIs(t, c.TagVolumes(context.Background(), []string{"vol-1"}, map[string]string{"team": "a"}), ErrReadOnly)
+	assert.Equal(t, AWSErrorUnknown, categorizeAWSError(ErrReadOnly).Category, "not retried")
+}
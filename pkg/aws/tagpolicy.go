@@ -0,0 +1,64 @@
+package aws
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tagPolicyViolationCode is the EC2 error code for tags rejected by an AWS
+// Organizations tag policy.
+const tagPolicyViolationCode = "TagPolicyViolation"
+
+var (
+	// tagPolicyKeysPattern captures the quoted keys after "tag key(s)" in a
+	// violation message, e.g. "... for the following tag key: 'CostCenter'".
+	tagPolicyKeysPattern = regexp.MustCompile(`(?i)tag keys?:?\s*((?:'[^']*'(?:\s*,\s*)?)+)`)
+	tagPolicyKeyPattern  = regexp.MustCompile(`'([^']*)'`)
+	// tagPolicyIDPattern matches an Organizations policy ID, e.g. p-95ouootqx1.
+	tagPolicyIDPattern = regexp.MustCompile(`\bp-[0-9a-z]{8,128}\b`)
+)
+
+// TagPolicyViolationError is returned by TagENI when an Organizations tag policy
+// rejects the tags. Retrying cannot succeed until the tag values (or the policy)
+// change.
+type TagPolicyViolationError struct {
+	ENIID string
+	// Keys are the tag keys AWS reported as non-compliant, if it named them.
+	Keys []string
+	// PolicyID is the violated policy, if AWS named it.
+	PolicyID string
+	// Message is the AWS error message.
+	Message string
+	Err     error
+}
+
+func (e *TagPolicyViolationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "tags for ENI %s violate an AWS Organizations tag policy", e.ENIID)
+	if e.PolicyID != "" {
+		fmt.Fprintf(&b, " (%s)", e.PolicyID)
+	}
+	if len(e.Keys) > 0 {
+		fmt.Fprintf(&b, ", non-compliant keys %q", e.Keys)
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	return b.String()
+}
+
+func (e *TagPolicyViolationError) Unwrap() error {
+	return e.Err
+}
+
+// newTagPolicyViolationError extracts what it can about the violation from the
+// AWS message, whose wording is not part of the API.
+func newTagPolicyViolationError(eniID, message string, err error) *TagPolicyViolationError {
+	e := &TagPolicyViolationError{ENIID: eniID, Message: message, Err: err}
+	if m := tagPolicyKeysPattern.FindStringSubmatch(message); m != nil {
+		for _, k := range tagPolicyKeyPattern.FindAllStringSubmatch(m[1], -1) {
+			e.Keys = append(e.Keys, k[1])
+		}
+	}
+	e.PolicyID = tagPolicyIDPattern.FindString(message)
+	return e
+}
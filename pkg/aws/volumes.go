@@ -0,0 +1,145 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s-eni-tagger/pkg/audit"
+	"k8s-eni-tagger/pkg/metrics"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+)
+
+// VolumeTagger reads and changes the tags of the EBS volumes backing EC2
+// instances. The client returned by NewClientWithOptions implements it with the
+// tagging client's rate limiter, retries, mutation budget and audit log.
+type VolumeTagger interface {
+	// GetInstanceVolumeTags returns the tags of each EBS volume backing
+	// instanceID, keyed by volume ID. Volumes that outlive the instance, such as
+	// persistent volumes attached by the EBS CSI driver, are not included.
+	GetInstanceVolumeTags(ctx context.Context, instanceID string) (map[string]map[string]string, error)
+	TagVolumes(ctx context.Context, volumeIDs []string, tags map[string]string) error
+	UntagVolumes(ctx context.Context, volumeIDs []string, tagKeys []string) error
+}
+
+var _ VolumeTagger = (*defaultClient)(nil)
+
+// GetInstanceVolumeTags finds the EBS volumes of instanceID and describes them
+// for their tags.
+func (c *defaultClient) GetInstanceVolumeTags(ctx context.Context, instanceID string) (map[string]map[string]string, error) {
+	volumeIDs, err := c.instanceVolumeIDs(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]map[string]string, len(volumeIDs))
+	if len(volumeIDs) == 0 {
+		return tags, nil
+	}
+
+	start := time.Now()
+	status := "success"
+	defer func() {
+		metrics.ObserveAWSAPILatency(ctx, "DescribeVolumes", status, time.Since(start).Seconds())
+	}()
+
+	input := &ec2.DescribeVolumesInput{VolumeIds: volumeIDs}
+	for {
+		var result *ec2.DescribeVolumesOutput
+		err := c.doWithRetry(ctx, "DescribeVolumes", awsAPIMaxAttempts, func(ctx context.Context) error {
+			if err := c.wait(ctx); err != nil {
+				return fmt.Errorf("rate limiter wait: %w", err)
+			}
+			var callErr error
+			result, callErr = c.ec2Client.DescribeVolumes(ctx, input, c.sessions.ec2Options(ctx)...)
+			return callErr
+		})
+		if err != nil {
+			status = "error"
+			if categorizeAWSError(err).Category == AWSErrorPermission {
+				return nil, fmt.Errorf("insufficient permissions to describe volumes (check ec2:DescribeVolumes): %w", err)
+			}
+			return nil, fmt.Errorf("failed to describe volumes %v of instance %s: %w", volumeIDs, instanceID, err)
+		}
+		for _, vol := range result.Volumes {
+			volumeTags := make(map[string]string, len(vol.Tags))
+			for _, t := range vol.Tags {
+				if aws.ToString(t.Key) != "" && t.Value != nil {
+					volumeTags[*t.Key] = *t.Value
+				}
+			}
+			tags[aws.ToString(vol.VolumeId)] = volumeTags
+		}
+		if aws.ToString(result.NextToken) == "" {
+			return tags, nil
+		}
+		input.NextToken = result.NextToken
+	}
+}
+
+// instanceVolumeIDs returns the IDs of the EBS volumes in the block device
+// mappings of instanceID that are deleted on termination, in device order.
+func (c *defaultClient) instanceVolumeIDs(ctx context.Context, instanceID string) ([]string, error) {
+	start := time.Now()
+	status := "success"
+	defer func() {
+		metrics.ObserveAWSAPILatency(ctx, "DescribeInstances", status, time.Since(start).Seconds())
+	}()
+
+	var result *ec2.DescribeInstancesOutput
+	err := c.doWithRetry(ctx, "DescribeInstances", awsAPIMaxAttempts, func(ctx context.Context) error {
+		if err := c.wait(ctx); err != nil {
+			return fmt.Errorf("rate limiter wait: %w", err)
+		}
+		var callErr error
+		result, callErr = c.ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}}, c.sessions.ec2Options(ctx)...)
+		return callErr
+	})
+	if err != nil {
+		status = "error"
+		if categorizeAWSError(err).Category == AWSErrorPermission {
+			return nil, fmt.Errorf("insufficient permissions to describe instances (check ec2:DescribeInstances): %w", err)
+		}
+		return nil, fmt.Errorf("failed to describe instance %s: %w", instanceID, err)
+	}
+	var ids []string
+	for _, reservation := range result.Reservations {
+		for _, instance := range reservation.Instances {
+			for _, mapping := range instance.BlockDeviceMappings {
+				if mapping.Ebs != nil && aws.ToBool(mapping.Ebs.DeleteOnTermination) && aws.ToString(mapping.Ebs.VolumeId) != "" {
+					ids = append(ids, *mapping.Ebs.VolumeId)
+				}
+			}
+		}
+	}
+	return ids, nil
+}
+
+// TagVolumes adds tags to every volume in volumeIDs with as few calls as the
+// tag backend allows: one with EC2.
+func (c *defaultClient) TagVolumes(ctx context.Context, volumeIDs []string, tags map[string]string) error {
+	if len(volumeIDs) == 0 || len(tags) == 0 {
+		return nil
+	}
+	backend := c.backend()
+	err := c.changeResourceTags(ctx, backend.TagOperation(), resourceTypeVolume, volumeIDs, func(ctx context.Context, ids []string) error {
+		return backend.TagResources(ctx, resourceTypeVolume, ids, tags)
+	})
+	c.recordAudit(ctx, audit.Entry{Operation: audit.OperationCreateTags, VolumeIDs: volumeIDs, Tags: tags}, err)
+	return err
+}
+
+// UntagVolumes removes tagKeys from every volume in volumeIDs with as few calls
+// as the tag backend allows.
+func (c *defaultClient) UntagVolumes(ctx context.Context, volumeIDs []string, tagKeys []string) error {
+	if len(volumeIDs) == 0 || len(tagKeys) == 0 {
+		return nil
+	}
+	backend := c.backend()
+	err := c.changeResourceTags(ctx, backend.UntagOperation(), resourceTypeVolume, volumeIDs, func(ctx context.Context, ids []string) error {
+		return backend.UntagResources(ctx, resourceTypeVolume, ids, tagKeys)
+	})
+	c.recordAudit(ctx, audit.Entry{Operation: audit.OperationDeleteTags, VolumeIDs: volumeIDs, TagKeys: tagKeys}, err)
+	return err
+}
@@ -0,0 +1,59 @@
+package aws
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetInstanceVolumeTags(t *testing.T) {
+	mockClient := new(mockEC2Client)
+	mockClient.On("DescribeInstances", mock.Anything, mock.MatchedBy(func(input *ec2.DescribeInstancesInput) bool {
+		return assert.ObjectsAreEqual([]string{"i-1"}, input.InstanceIds)
+	}), mock.Anything).Return(&ec2.DescribeInstancesOutput{Reservations: []types.Reservation{{Instances: []types.Instance{{
+		BlockDeviceMappings: []types.InstanceBlockDeviceMapping{
+			{DeviceName: aws.String("/dev/xvda"), Ebs: &types.EbsInstanceBlockDevice{VolumeId: aws.String("vol-root"), DeleteOnTermination: aws.Bool(true)}},
+			{DeviceName: aws.String("/dev/xvdb"), Ebs: &types.EbsInstanceBlockDevice{VolumeId: aws.String("vol-data"), DeleteOnTermination: aws.Bool(true)}},
+			// A persistent volume attached by the EBS CSI driver
+			{DeviceName: aws.String("/dev/xvdba"), Ebs: &types.EbsInstanceBlockDevice{VolumeId: aws.String("vol-pv"), DeleteOnTermination: aws.Bool(false)}},
+			{DeviceName: aws.String("/dev/sdc")},
+		},
+	}}}}}, nil)
+	mockClient.On("DescribeVolumes", mock.Anything, mock.MatchedBy(func(input *ec2.DescribeVolumesInput) bool {
+		return assert.ObjectsAreEqual([]string{"vol-root", "vol-data"}, input.VolumeIds)
+	}), mock.Anything).Return(&ec2.DescribeVolumesOutput{Volumes: []types.Volume{
+		{VolumeId: aws.String("vol-root"), Tags: []types.Tag{{Key: aws.String("team"), Value: aws.String("a")}}},
+		{VolumeId: aws.String("vol-data")},
+	}}, nil)
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+	c := &defaultClient{ec2Client: mockClient, rateLimiter: rl}
+
+	tags, err := c.GetInstanceVolumeTags(context.Background(), "i-1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{"vol-root": {"team": "a"}, "vol-data": {}}, tags)
+}
+
+func TestTagVolumes(t *testing.T) {
+	mockClient := new(mockEC2Client)
+	mockClient.On("CreateTags", mock.Anything, mock.MatchedBy(func(input *ec2.CreateTagsInput) bool {
+		return assert.ObjectsAreEqual([]string{"vol-1", "vol-2"}, input.Resources) && len(input.Tags) == 1
+	}), mock.Anything).Return(&ec2.CreateTagsOutput{}, nil).Once()
+	mockClient.On("DeleteTags", mock.Anything, mock.MatchedBy(func(input *ec2.DeleteTagsInput) bool {
+		return assert.ObjectsAreEqual([]string{"vol-1"}, input.Resources) && aws.ToString(input.Tags[0].Key) == "team"
+	}), mock.Anything).Return(&ec2.DeleteTagsOutput{}, nil).Once()
+	rl, err := newRateLimiter(10, 20)
+	require.NoError(t, err)
+	c := &defaultClient{ec2Client: mockClient, rateLimiter: rl}
+
+	require.NoError(t, c.TagVolumes(context.Background(), []string{"vol-1", "vol-2"}, map[string]string{"team": "a"}))
+	require.NoError(t, c.UntagVolumes(context.Background(), []string{"vol-1"}, []string{"team"}))
+	require.NoError(t, c.UntagVolumes(context.Background(), []string{"vol-1"}, nil))
+	mockClient.AssertExpectations(t)
+}
@@ -2,6 +2,8 @@ package cache
 
 import (
 	"context"
+	"errors"
+	"maps"
 	"sync"
 	"time"
 
@@ -31,6 +33,9 @@ type cacheUpdate struct {
 // GetENIInfoByIP and Invalidate is the requesting pod's UID; cache entries are
 // only returned (or deleted) when the cached PodUID matches, which prevents
 // stale results when an IP is reassigned to a different pod.
+//
+// Returned ENIInfos are shared by every pod on the same ENI and must not be
+// modified.
 type Cache interface {
 	GetENIInfoByIP(ctx context.Context, ip string, podUID string) (*aws.ENIInfo, error)
 	Invalidate(ctx context.Context, ip string, podUID string)
@@ -47,9 +52,15 @@ type ENICache struct {
 	cache     map[string]CachedEntry
 	awsClient aws.Client
 
+	// enis interns ENIInfos by ENI ID so IPs on the same ENI share one copy
+	enis map[string]*internedENI
+
 	// ConfigMap persistence (optional)
 	cmPersister ConfigMapPersister
 
+	// ipCheck, when set, checks cache hits against AWS (see EnableIPCheck)
+	ipCheck *ipChecker
+
 	// Batching/rate limiting
 	updateQueue   chan cacheUpdate
 	stopWorker    chan struct{}
@@ -65,11 +76,18 @@ type ConfigMapPersister interface {
 	Delete(ctx context.Context, ip string) error
 }
 
+// deltaLoader is implemented by persisters that can report what changed since
+// their previous read, as configMapPersister does.
+type deltaLoader interface {
+	LoadDelta(ctx context.Context) (changed map[string]CachedEntry, removed []string, err error)
+}
+
 // NewENICache creates a new ENI cache
 func NewENICache(awsClient aws.Client) *ENICache {
 	c := &ENICache{
 		cache:         make(map[string]CachedEntry),
 		awsClient:     awsClient,
+		enis:          make(map[string]*internedENI),
 		updateQueue:   make(chan cacheUpdate, 1000),
 		stopWorker:    make(chan struct{}),
 		batchInterval: 2 * time.Second, // configurable
@@ -97,6 +115,24 @@ func (c *ENICache) WithConfigMapPersister(persister ConfigMapPersister) *ENICach
 	return c
 }
 
+// SetOwner sets the leader term ConfigMap writes are made under, when the
+// persister fences writes by owner. A nil owner makes this replica a follower:
+// its writes are refused until it claims the ConfigMap (see Ownership).
+func (c *ENICache) SetOwner(owner *Owner) {
+	if p, ok := c.cmPersister.(ownedPersister); ok {
+		p.SetOwner(owner)
+	}
+}
+
+// Claim makes owner the writer of the persisted cache, refusing writes of
+// earlier terms from then on.
+func (c *ENICache) Claim(ctx context.Context, owner Owner) error {
+	if p, ok := c.cmPersister.(ownedPersister); ok {
+		return p.Claim(ctx, owner)
+	}
+	return nil
+}
+
 // LoadFromConfigMap loads cached entries from ConfigMap on startup
 func (c *ENICache) LoadFromConfigMap(ctx context.Context) error {
 	if c.cmPersister == nil {
@@ -113,31 +149,102 @@ func (c *ENICache) LoadFromConfigMap(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	for ip, entry := range entries {
+		if old, ok := c.cache[ip]; ok {
+			c.releaseLocked(ip, old.Info)
+		}
+		entry.Info = c.internLocked(ip, entry.Info)
 		c.cache[ip] = entry
 	}
 	logger.Info("Loaded ENI cache from ConfigMap", "entries", len(entries))
 	return nil
 }
 
+// SyncFromConfigMap replaces the cache contents with the ConfigMap's, dropping
+// entries the ConfigMap no longer has. Standby replicas use it to follow the
+// leader's cache; nothing is written back. When the persister supports it, only
+// the entries the leader changed or removed since the previous sync are applied,
+// and entries cached by this replica alone are kept.
+func (c *ENICache) SyncFromConfigMap(ctx context.Context) error {
+	if c.cmPersister == nil {
+		return nil
+	}
+	if loader, ok := c.cmPersister.(deltaLoader); ok {
+		return c.syncDelta(ctx, loader)
+	}
+
+	entries, err := c.cmPersister.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for ip, old := range c.cache {
+		if _, ok := entries[ip]; !ok {
+			delete(c.cache, ip)
+			c.releaseLocked(ip, old.Info)
+		}
+	}
+	for ip, entry := range entries {
+		if old, ok := c.cache[ip]; ok {
+			c.releaseLocked(ip, old.Info)
+		}
+		entry.Info = c.internLocked(ip, entry.Info)
+		c.cache[ip] = entry
+	}
+	return nil
+}
+
+// syncDelta applies the ConfigMap changes reported by loader.
+func (c *ENICache) syncDelta(ctx context.Context, loader deltaLoader) error {
+	changed, removed, err := loader.LoadDelta(ctx)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ip := range removed {
+		if old, ok := c.cache[ip]; ok {
+			delete(c.cache, ip)
+			c.releaseLocked(ip, old.Info)
+		}
+	}
+	for ip, entry := range changed {
+		if old, ok := c.cache[ip]; ok {
+			c.releaseLocked(ip, old.Info)
+		}
+		entry.Info = c.internLocked(ip, entry.Info)
+		c.cache[ip] = entry
+	}
+	log.FromContext(ctx).V(1).Info("Applied ENI cache changes from ConfigMap", "changed", len(changed), "removed", len(removed))
+	return nil
+}
+
 // GetENIInfoByIP returns ENI info for an IP, using cache if available.
 // It requires the expected PodUID to validate the cache entry.
 func (c *ENICache) GetENIInfoByIP(ctx context.Context, ip string, podUID string) (*aws.ENIInfo, error) {
 	// Try in-memory cache first
 	if info, ok := c.get(ctx, ip, podUID); ok {
-		metrics.CacheHitsTotal.Inc()
-		return info, nil
+		if c.ipCheck == nil || c.stillAssigned(ctx, ip, info) {
+			metrics.CacheHitsTotal.Inc()
+			return info, nil
+		}
+		c.Invalidate(ctx, ip, podUID)
 	}
 	metrics.CacheMissesTotal.Inc()
 
-	// Cache miss, UID mismatch, or legacy migrated entry - call AWS API
+	// Cache miss, UID mismatch, legacy migrated entry or moved IP - call AWS API
 	info, err := c.awsClient.GetENIInfoByIP(ctx, ip)
 	if err != nil {
 		return nil, err
 	}
 
 	// Store in cache (persists until pod deletion)
-	c.set(ctx, ip, info, podUID)
-	return info, nil
+	return c.set(ctx, ip, info, podUID), nil
 }
 
 // get retrieves from in-memory cache with validation. An empty cached PodUID
@@ -165,11 +272,15 @@ func (c *ENICache) get(ctx context.Context, ip string, podUID string) (*aws.ENII
 	return entry.Info, true
 }
 
-// set stores in in-memory cache and optionally persists to ConfigMap
-func (c *ENICache) set(ctx context.Context, ip string, info *aws.ENIInfo, podUID string) {
+// set stores in in-memory cache and optionally persists to ConfigMap. It returns
+// the interned ENIInfo stored for ip.
+func (c *ENICache) set(ctx context.Context, ip string, info *aws.ENIInfo, podUID string) *aws.ENIInfo {
 	c.mu.Lock()
+	if old, ok := c.cache[ip]; ok {
+		c.releaseLocked(ip, old.Info)
+	}
 	entry := CachedEntry{
-		Info:   info,
+		Info:   c.internLocked(ip, info),
 		PodUID: podUID,
 	}
 	c.cache[ip] = entry
@@ -188,6 +299,7 @@ func (c *ENICache) set(ctx context.Context, ip string, info *aws.ENIInfo, podUID
 			log.FromContext(ctx).Info("ConfigMap update queue full, dropping update", "ip", ip)
 		}
 	}
+	return entry.Info
 }
 
 // Invalidate removes an entry from the cache when the pod UID matches.
@@ -206,10 +318,14 @@ func (c *ENICache) Invalidate(ctx context.Context, ip string, podUID string) {
 		return
 	}
 	delete(c.cache, ip)
+	c.releaseLocked(ip, entry.Info)
 	c.mu.Unlock()
 
 	if c.cmPersister != nil {
-		if err := c.cmPersister.Delete(ctx, ip); err != nil {
+		if err := c.cmPersister.Delete(ctx, ip); errors.Is(err, ErrNotOwner) {
+			metrics.CachePersistFencedTotal.Inc()
+			logger.V(1).Info("Skipped ConfigMap delete, this replica does not own the ENI cache", "ip", ip)
+		} else if err != nil {
 			logger.Error(err, "Failed to delete ENI from ConfigMap, cache may grow unbounded", "ip", ip)
 		}
 	}
@@ -265,7 +381,10 @@ func (c *ENICache) flushBatch(batch []cacheUpdate, logger logr.Logger) {
 
 	// Apply sets
 	for _, upd := range batch {
-		if err := c.cmPersister.Save(ctx, upd.ip, upd.entry); err != nil {
+		if err := c.cmPersister.Save(ctx, upd.ip, upd.entry); errors.Is(err, ErrNotOwner) {
+			metrics.CachePersistFencedTotal.Inc()
+			logger.V(1).Info("Skipped ConfigMap persist, this replica does not own the ENI cache", "ip", upd.ip)
+		} else if err != nil {
 			logger.Error(err, "Batch persist ENI to ConfigMap failed", "ip", upd.ip)
 		}
 	}
@@ -277,3 +396,11 @@ func (c *ENICache) Size() int {
 	defer c.mu.RUnlock()
 	return len(c.cache)
 }
+
+// Entries returns a copy of the cached entries by IP. The ENIInfos are shared
+// with the cache and must not be modified.
+func (c *ENICache) Entries() map[string]CachedEntry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return maps.Clone(c.cache)
+}
@@ -16,12 +16,16 @@ import (
 
 // MockAWSClient implements aws.Client for testing
 type MockAWSClient struct {
-	GetENIInfoByIPFunc func(ctx context.Context, ip string) (*aws.ENIInfo, error)
+	GetENIInfoByIPFunc  func(ctx context.Context, ip string) (*aws.ENIInfo, error)
+	GetENIInfoByIPsFunc func(ctx context.Context, ips []string) (map[string]*aws.ENIInfo, error)
 }
 
 func (m *MockAWSClient) GetENIInfoByIP(ctx context.Context, ip string) (*aws.ENIInfo, error) {
 	return m.GetENIInfoByIPFunc(ctx, ip)
 }
+func (m *MockAWSClient) GetENIInfoByIPs(ctx context.Context, ips []string) (map[string]*aws.ENIInfo, error) {
+	return m.GetENIInfoByIPsFunc(ctx, ips)
+}
 func (m *MockAWSClient) TagENI(ctx context.Context, eniID string, tags map[string]string) error {
 	return nil
 }
@@ -46,6 +50,8 @@ type MockConfigMapPersister struct {
 }
 
 func (m *MockConfigMapPersister) Load(ctx context.Context) (map[string]CachedEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	if m.loadError != nil {
 		return nil, m.loadError
 	}
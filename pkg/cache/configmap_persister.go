@@ -3,7 +3,9 @@ package cache
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"k8s-eni-tagger/pkg/aws"
@@ -16,32 +18,105 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-const (
-	configMapName = "eni-tagger-cache"
-)
+// ConfigMapName is the ConfigMap the ENI cache is persisted to, in the controller's namespace.
+const ConfigMapName = "eni-tagger-cache"
 
 // configMapPersister implements ConfigMapPersister interface
 type configMapPersister struct {
-	client    client.Client
+	client client.Client
+	// reader reads the ConfigMap uncached: the manager's cache is not started when
+	// the cache is first loaded, and would need a ConfigMap watch.
+	reader    client.Reader
 	namespace string
+
+	// mu guards the ConfigMap state seen by the previous LoadDelta.
+	mu              sync.Mutex
+	resourceVersion string
+	seen            map[string]string
+
+	// ownerMu guards the term writes are fenced by. Writes are not fenced until
+	// SetOwner is first called; after that a nil owner refuses them.
+	ownerMu sync.Mutex
+	fenced  bool
+	owner   *Owner
 }
 
-// NewConfigMapPersister creates a new ConfigMap-based persister
-func NewConfigMapPersister(client client.Client, namespace string) ConfigMapPersister {
+// NewConfigMapPersister creates a new ConfigMap-based persister. Writes go
+// through c and reads through reader, normally the manager's API reader.
+func NewConfigMapPersister(c client.Client, reader client.Reader, namespace string) ConfigMapPersister {
 	return &configMapPersister{
-		client:    client,
+		client:    c,
+		reader:    reader,
 		namespace: namespace,
 	}
 }
 
+// SetOwner implements ownedPersister.
+func (p *configMapPersister) SetOwner(owner *Owner) {
+	p.ownerMu.Lock()
+	defer p.ownerMu.Unlock()
+	p.fenced = true
+	p.owner = owner
+}
+
+// Claim implements ownedPersister. A ConfigMap that does not exist yet is
+// stamped when first created.
+func (p *configMapPersister) Claim(ctx context.Context, owner Owner) error {
+	p.SetOwner(&owner)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm := &corev1.ConfigMap{}
+		err := p.reader.Get(ctx, client.ObjectKey{Namespace: p.namespace, Name: ConfigMapName}, cm)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return err
+		}
+		if err := p.fence(cm); err != nil {
+			return err
+		}
+		return p.client.Update(ctx, cm)
+	})
+}
+
+// fence checks that this replica may write cm and stamps its owner on it. A
+// replica refused by the ConfigMap's labels drops its owner, so its later
+// writes fail without an API call.
+func (p *configMapPersister) fence(cm *corev1.ConfigMap) error {
+	p.ownerMu.Lock()
+	defer p.ownerMu.Unlock()
+	if !p.fenced {
+		return nil
+	}
+	if p.owner == nil {
+		return ErrNotOwner
+	}
+	if err := fenceConfigMap(cm, *p.owner); err != nil {
+		p.owner = nil
+		return err
+	}
+	return nil
+}
+
+// checkOwner returns ErrNotOwner when writes are fenced and this replica owns
+// no term.
+func (p *configMapPersister) checkOwner() error {
+	p.ownerMu.Lock()
+	defer p.ownerMu.Unlock()
+	if p.fenced && p.owner == nil {
+		return ErrNotOwner
+	}
+	return nil
+}
+
 // Load loads all cached ENI entries from the ConfigMap
 func (p *configMapPersister) Load(ctx context.Context) (map[string]CachedEntry, error) {
 	logger := log.FromContext(ctx)
 
 	cm := &corev1.ConfigMap{}
-	err := p.client.Get(ctx, client.ObjectKey{
+	err := p.reader.Get(ctx, client.ObjectKey{
 		Namespace: p.namespace,
-		Name:      configMapName,
+		Name:      ConfigMapName,
 	}, cm)
 
 	if err != nil {
@@ -86,6 +161,53 @@ func (p *configMapPersister) Load(ctx context.Context) (map[string]CachedEntry,
 	return result, nil
 }
 
+// LoadDelta returns the entries added or changed in the ConfigMap since the
+// previous LoadDelta, and the IPs removed from it. An unchanged resourceVersion
+// is reported as no change without decoding anything, and otherwise only values
+// that differ are decoded, so a standby replica following the leader only pays
+// for what the leader wrote. Corrupted entries are reported as removed and left
+// for Load to clean up.
+func (p *configMapPersister) LoadDelta(ctx context.Context) (map[string]CachedEntry, []string, error) {
+	cm := &corev1.ConfigMap{}
+	err := p.reader.Get(ctx, client.ObjectKey{Namespace: p.namespace, Name: ConfigMapName}, cm)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, nil, fmt.Errorf("failed to get ConfigMap: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.seen != nil && cm.ResourceVersion == p.resourceVersion {
+		return nil, nil, nil
+	}
+
+	changed := make(map[string]CachedEntry)
+	var removed []string
+	seen := make(map[string]string, len(cm.Data))
+	for ip, data := range cm.Data {
+		if old, ok := p.seen[ip]; ok && old == data {
+			seen[ip] = data
+			continue
+		}
+		entry, _, ok := parseCacheEntry([]byte(data))
+		if !ok {
+			if _, had := p.seen[ip]; had {
+				removed = append(removed, ip)
+			}
+			continue
+		}
+		changed[ip] = entry
+		seen[ip] = data
+	}
+	for ip := range p.seen {
+		if _, ok := cm.Data[ip]; !ok {
+			removed = append(removed, ip)
+		}
+	}
+	p.seen = seen
+	p.resourceVersion = cm.ResourceVersion
+	return changed, removed, nil
+}
+
 // parseCacheEntry decodes a single ConfigMap value into a CachedEntry.
 // It accepts both the current format ({"info":{...},"pod_uid":"..."}) and the
 // legacy format from the pre-UID release (a top-level aws.ENIInfo JSON).
@@ -112,6 +234,11 @@ func (p *configMapPersister) cleanupEntries(ips []string) {
 	defer cancel()
 	for _, ip := range ips {
 		if err := p.Delete(ctx, ip); err != nil {
+			if errors.Is(err, ErrNotOwner) {
+				// The leader cleans up when it loads the ConfigMap
+				logger.V(1).Info("Left corrupted ConfigMap entries to the leader", "ips", ips)
+				return
+			}
 			logger.Error(err, "Failed to clean up corrupted ConfigMap entry", "ip", ip)
 			continue
 		}
@@ -123,6 +250,9 @@ func (p *configMapPersister) cleanupEntries(ips []string) {
 func (p *configMapPersister) Save(ctx context.Context, ip string, entry CachedEntry) error {
 	logger := log.FromContext(ctx)
 
+	if err := p.checkOwner(); err != nil {
+		return err
+	}
 	data, err := json.Marshal(entry)
 	if err != nil {
 		return fmt.Errorf("failed to marshal cache entry: %w", err)
@@ -138,9 +268,9 @@ func (p *configMapPersister) Save(ctx context.Context, ip string, entry CachedEn
 		}
 
 		cm := &corev1.ConfigMap{}
-		err := p.client.Get(ctx, client.ObjectKey{
+		err := p.reader.Get(ctx, client.ObjectKey{
 			Namespace: p.namespace,
-			Name:      configMapName,
+			Name:      ConfigMapName,
 		}, cm)
 
 		if err != nil {
@@ -148,13 +278,16 @@ func (p *configMapPersister) Save(ctx context.Context, ip string, entry CachedEn
 				// Create new ConfigMap
 				cm = &corev1.ConfigMap{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      configMapName,
+						Name:      ConfigMapName,
 						Namespace: p.namespace,
 					},
 					Data: map[string]string{
 						ip: string(data),
 					},
 				}
+				if err := p.fence(cm); err != nil {
+					return err
+				}
 				if err := p.client.Create(ctx, cm); err != nil {
 					lastErr = err
 					return fmt.Errorf("failed to create ConfigMap: %w", err)
@@ -166,6 +299,10 @@ func (p *configMapPersister) Save(ctx context.Context, ip string, entry CachedEn
 			return err
 		}
 
+		if err := p.fence(cm); err != nil {
+			return err
+		}
+
 		// Update with resource version check
 		if cm.Data == nil {
 			cm.Data = make(map[string]string)
@@ -188,11 +325,14 @@ func (p *configMapPersister) Save(ctx context.Context, ip string, entry CachedEn
 
 // Delete removes a single ENI entry from the ConfigMap
 func (p *configMapPersister) Delete(ctx context.Context, ip string) error {
+	if err := p.checkOwner(); err != nil {
+		return err
+	}
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		cm := &corev1.ConfigMap{}
-		err := p.client.Get(ctx, client.ObjectKey{
+		err := p.reader.Get(ctx, client.ObjectKey{
 			Namespace: p.namespace,
-			Name:      configMapName,
+			Name:      ConfigMapName,
 		}, cm)
 
 		if err != nil {
@@ -202,9 +342,12 @@ func (p *configMapPersister) Delete(ctx context.Context, ip string) error {
 			return err
 		}
 
-		if cm.Data == nil {
+		if _, ok := cm.Data[ip]; !ok {
 			return nil
 		}
+		if err := p.fence(cm); err != nil {
+			return err
+		}
 
 		delete(cm.Data, ip)
 
@@ -54,7 +54,7 @@ func TestNewConfigMapPersister(t *testing.T) {
 	require.NoError(t, err)
 	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
 
-	p := NewConfigMapPersister(k8sClient, "default")
+	p := NewConfigMapPersister(k8sClient, k8sClient, "default")
 	assert.NotNil(t, p)
 }
 
@@ -83,7 +83,7 @@ func TestLoad(t *testing.T) {
 			existingObjs: []client.Object{
 				&corev1.ConfigMap{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      configMapName,
+						Name:      ConfigMapName,
 						Namespace: "default",
 					},
 					Data: map[string]string{
@@ -100,7 +100,7 @@ func TestLoad(t *testing.T) {
 			existingObjs: []client.Object{
 				&corev1.ConfigMap{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      configMapName,
+						Name:      ConfigMapName,
 						Namespace: "default",
 					},
 					Data: map[string]string{
@@ -119,7 +119,7 @@ func TestLoad(t *testing.T) {
 			existingObjs: []client.Object{
 				&corev1.ConfigMap{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      configMapName,
+						Name:      ConfigMapName,
 						Namespace: "default",
 					},
 					Data: map[string]string{
@@ -140,7 +140,7 @@ func TestLoad(t *testing.T) {
 			existingObjs: []client.Object{
 				&corev1.ConfigMap{
 					ObjectMeta: metav1.ObjectMeta{
-						Name:      configMapName,
+						Name:      ConfigMapName,
 						Namespace: "default",
 					},
 					Data: map[string]string{
@@ -158,7 +158,7 @@ func TestLoad(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.existingObjs...).Build()
-			p := NewConfigMapPersister(k8sClient, "default")
+			p := NewConfigMapPersister(k8sClient, k8sClient, "default")
 
 			items, err := p.Load(context.TODO())
 
@@ -186,14 +186,14 @@ func TestSave(t *testing.T) {
 
 	t.Run("Create New ConfigMap", func(t *testing.T) {
 		k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
-		p := NewConfigMapPersister(k8sClient, "default")
+		p := NewConfigMapPersister(k8sClient, k8sClient, "default")
 
 		err := p.Save(context.TODO(), "10.0.0.1", entry)
 		assert.NoError(t, err)
 
 		// Verify created
 		cm := &corev1.ConfigMap{}
-		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: configMapName, Namespace: "default"}, cm)
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: ConfigMapName, Namespace: "default"}, cm)
 		assert.NoError(t, err)
 		assert.Contains(t, cm.Data, "10.0.0.1")
 		// Verify content contains PodUID
@@ -202,18 +202,18 @@ func TestSave(t *testing.T) {
 
 	t.Run("Update Existing ConfigMap", func(t *testing.T) {
 		existing := &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{Name: configMapName, Namespace: "default"},
+			ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: "default"},
 			Data:       map[string]string{"10.0.0.2": "{}"},
 		}
 		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
-		p := NewConfigMapPersister(k8sClient, "default")
+		p := NewConfigMapPersister(k8sClient, k8sClient, "default")
 
 		err := p.Save(context.TODO(), "10.0.0.1", entry)
 		assert.NoError(t, err)
 
 		// Verify updated
 		cm := &corev1.ConfigMap{}
-		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: configMapName, Namespace: "default"}, cm)
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: ConfigMapName, Namespace: "default"}, cm)
 		assert.NoError(t, err)
 		assert.Contains(t, cm.Data, "10.0.0.1")
 		assert.Contains(t, cm.Data, "10.0.0.2")
@@ -227,7 +227,7 @@ func TestDelete(t *testing.T) {
 
 	t.Run("ConfigMap Not Found", func(t *testing.T) {
 		k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
-		p := NewConfigMapPersister(k8sClient, "default")
+		p := NewConfigMapPersister(k8sClient, k8sClient, "default")
 
 		err := p.Delete(context.TODO(), "10.0.0.1")
 		assert.NoError(t, err)
@@ -235,17 +235,17 @@ func TestDelete(t *testing.T) {
 
 	t.Run("Delete Item", func(t *testing.T) {
 		existing := &corev1.ConfigMap{
-			ObjectMeta: metav1.ObjectMeta{Name: configMapName, Namespace: "default"},
+			ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: "default"},
 			Data:       map[string]string{"10.0.0.1": "{}", "10.0.0.2": "{}"},
 		}
 		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
-		p := NewConfigMapPersister(k8sClient, "default")
+		p := NewConfigMapPersister(k8sClient, k8sClient, "default")
 
 		err := p.Delete(context.TODO(), "10.0.0.1")
 		assert.NoError(t, err)
 
 		cm := &corev1.ConfigMap{}
-		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: configMapName, Namespace: "default"}, cm)
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: ConfigMapName, Namespace: "default"}, cm)
 		assert.NoError(t, err)
 		assert.NotContains(t, cm.Data, "10.0.0.1")
 		assert.Contains(t, cm.Data, "10.0.0.2")
@@ -265,21 +265,21 @@ func TestSaveRetryOnConflict(t *testing.T) {
 	t.Run("Save with retry succeeds", func(t *testing.T) {
 		existing := &corev1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            configMapName,
+				Name:            ConfigMapName,
 				Namespace:       "default",
 				ResourceVersion: "1",
 			},
 			Data: map[string]string{"10.0.0.2": "{}"},
 		}
 		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
-		p := NewConfigMapPersister(k8sClient, "default")
+		p := NewConfigMapPersister(k8sClient, k8sClient, "default")
 
 		err := p.Save(context.TODO(), "10.0.0.1", entry)
 		assert.NoError(t, err)
 
 		// Verify saved
 		cm := &corev1.ConfigMap{}
-		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: configMapName, Namespace: "default"}, cm)
+		err = k8sClient.Get(context.TODO(), client.ObjectKey{Name: ConfigMapName, Namespace: "default"}, cm)
 		assert.NoError(t, err)
 		assert.Contains(t, cm.Data, "10.0.0.1")
 		assert.Contains(t, cm.Data, "10.0.0.2")
@@ -342,13 +342,13 @@ func TestLoadCorruptionScenarios(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			existing := &corev1.ConfigMap{
 				ObjectMeta: metav1.ObjectMeta{
-					Name:      configMapName,
+					Name:      ConfigMapName,
 					Namespace: "default",
 				},
 				Data: tt.configMapData,
 			}
 			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
-			p := NewConfigMapPersister(k8sClient, "default")
+			p := NewConfigMapPersister(k8sClient, k8sClient, "default")
 
 			items, err := p.Load(context.TODO())
 			assert.NoError(t, err)
@@ -372,7 +372,7 @@ func TestDeleteRetryOnConflict(t *testing.T) {
 	t.Run("Delete with retry succeeds", func(t *testing.T) {
 		existing := &corev1.ConfigMap{
 			ObjectMeta: metav1.ObjectMeta{
-				Name:            configMapName,
+				Name:            ConfigMapName,
 				Namespace:       "default",
 				ResourceVersion: "1",
 			},
@@ -383,16 +383,53 @@ func TestDeleteRetryOnConflict(t *testing.T) {
 		}
 		baseClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
 		conflictClient := &conflictOnceClient{Client: baseClient}
-		p := NewConfigMapPersister(conflictClient, "default")
+		p := NewConfigMapPersister(conflictClient, conflictClient, "default")
 
 		err := p.Delete(context.TODO(), "10.0.0.1")
 		assert.NoError(t, err)
 		assert.GreaterOrEqual(t, conflictClient.updateCalls, 2, "expected retry after injected conflict")
 
 		cm := &corev1.ConfigMap{}
-		err = baseClient.Get(context.TODO(), client.ObjectKey{Name: configMapName, Namespace: "default"}, cm)
+		err = baseClient.Get(context.TODO(), client.ObjectKey{Name: ConfigMapName, Namespace: "default"}, cm)
 		assert.NoError(t, err)
 		assert.NotContains(t, cm.Data, "10.0.0.1")
 		assert.Contains(t, cm.Data, "10.0.0.2")
 	})
 }
+
+func TestLoadDelta(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	p := NewConfigMapPersister(k8sClient, k8sClient, "default").(*configMapPersister)
+	ctx := context.TODO()
+
+	entry := func(id, uid string) CachedEntry { return CachedEntry{Info: &aws.ENIInfo{ID: id}, PodUID: uid} }
+	require.NoError(t, p.Save(ctx, "10.0.0.1", entry("eni-1", "pod-1")))
+	require.NoError(t, p.Save(ctx, "10.0.0.2", entry("eni-1", "pod-2")))
+
+	changed, removed, err := p.LoadDelta(ctx)
+	require.NoError(t, err)
+	assert.Len(t, changed, 2)
+	assert.Empty(t, removed)
+
+	// Nothing written since the previous read
+	changed, removed, err = p.LoadDelta(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	assert.Empty(t, removed)
+
+	require.NoError(t, p.Delete(ctx, "10.0.0.1"))
+	require.NoError(t, p.Save(ctx, "10.0.0.3", entry("eni-2", "pod-3")))
+	changed, removed, err = p.LoadDelta(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]CachedEntry{"10.0.0.3": entry("eni-2", "pod-3")}, changed)
+	assert.Equal(t, []string{"10.0.0.1"}, removed)
+
+	// A ConfigMap deleted by hand empties the cache
+	require.NoError(t, k8sClient.Delete(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: "default"}}))
+	changed, removed, err = p.LoadDelta(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+	assert.ElementsMatch(t, []string{"10.0.0.2", "10.0.0.3"}, removed)
+}
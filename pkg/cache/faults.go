@@ -0,0 +1,21 @@
+package cache
+
+import "sigs.k8s.io/controller-runtime/pkg/client"
+
+// FaultInjection makes ConfigMap persistence misbehave on purpose, so resilience
+// tests can run the real controller under induced failure. Rates are fractions
+// from 0 to 1. Like aws.FaultInjection it only has an effect in binaries built
+// with the faultinject build tag; never enable it in production.
+type FaultInjection struct {
+	// CorruptionRate is the fraction of saved entries written unreadable, as
+	// found after a crash or a manual edit; Load drops and cleans them up.
+	CorruptionRate float64
+	// ConflictRate is the fraction of ConfigMap updates failing with a conflict,
+	// as when another replica wrote first; writes retry on conflicts.
+	ConflictRate float64
+}
+
+// NewConfigMapPersisterWithFaults is NewConfigMapPersister with faults injected.
+func NewConfigMapPersisterWithFaults(c client.Client, reader client.Reader, namespace string, faults FaultInjection) ConfigMapPersister {
+	return injectFaults(&configMapPersister{client: c, reader: reader, namespace: namespace}, faults)
+}
@@ -0,0 +1,9 @@
+//go:build !faultinject
+
+package cache
+
+// injectFaults injects nothing: production builds carry no fault injection
+// code.
+func injectFaults(p *configMapPersister, _ FaultInjection) ConfigMapPersister {
+	return p
+}
@@ -0,0 +1,53 @@
+//go:build faultinject
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// injectFaults wraps p's client and p itself with the configured faults.
+func injectFaults(p *configMapPersister, faults FaultInjection) ConfigMapPersister {
+	if faults.ConflictRate > 0 {
+		p.client = &conflictingClient{Client: p.client, rate: faults.ConflictRate}
+	}
+	if faults.CorruptionRate > 0 {
+		return &corruptingPersister{configMapPersister: p, rate: faults.CorruptionRate}
+	}
+	return p
+}
+
+// corruptingPersister saves a fraction of entries without their ENI ID, which
+// parseCacheEntry rejects.
+type corruptingPersister struct {
+	*configMapPersister
+	rate float64
+}
+
+func (p *corruptingPersister) Save(ctx context.Context, ip string, entry CachedEntry) error {
+	if rand.Float64() < p.rate {
+		entry = CachedEntry{Info: &aws.ENIInfo{SubnetID: "corrupted"}, PodUID: entry.PodUID}
+	}
+	return p.configMapPersister.Save(ctx, ip, entry)
+}
+
+// conflictingClient fails a fraction of updates with a conflict error.
+type conflictingClient struct {
+	client.Client
+	rate float64
+}
+
+func (c *conflictingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if rand.Float64() < c.rate {
+		return apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, obj.GetName(), errors.New("fault injected by k8s-eni-tagger"))
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
@@ -0,0 +1,53 @@
+//go:build faultinject
+
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestConfigMapPersisterWithFaults(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	entry := CachedEntry{Info: &aws.ENIInfo{ID: "eni-1"}, PodUID: "pod-1"}
+	existing := func() *corev1.ConfigMap {
+		return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: ConfigMapName, Namespace: "default"}}
+	}
+
+	t.Run("corrupted entries are dropped on load", func(t *testing.T) {
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing()).Build()
+		p := NewConfigMapPersisterWithFaults(k8sClient, k8sClient, "default", FaultInjection{CorruptionRate: 1})
+		require.NoError(t, p.Save(context.Background(), "10.0.0.1", entry))
+
+		loaded, err := NewConfigMapPersister(k8sClient, k8sClient, "default").Load(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, loaded)
+	})
+
+	t.Run("conflicts exhaust the retries", func(t *testing.T) {
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing()).Build()
+		p := NewConfigMapPersisterWithFaults(k8sClient, k8sClient, "default", FaultInjection{ConflictRate: 1})
+		err := p.Save(context.Background(), "10.0.0.1", entry)
+		assert.True(t, apierrors.IsConflict(err))
+	})
+
+	t.Run("no faults", func(t *testing.T) {
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing()).Build()
+		p := NewConfigMapPersisterWithFaults(k8sClient, k8sClient, "default", FaultInjection{})
+		require.NoError(t, p.Save(context.Background(), "10.0.0.1", entry))
+		loaded, err := p.Load(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "eni-1", loaded["10.0.0.1"].Info.ID)
+	})
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"maps"
+	"slices"
+
+	"k8s-eni-tagger/pkg/aws"
+)
+
+// internedENI is the single ENIInfo kept for an ENI and the cached IPs sharing it.
+type internedENI struct {
+	info *aws.ENIInfo
+	ips  map[string]struct{}
+}
+
+// internLocked returns the shared ENIInfo for info.ID and records ip as one of
+// its users. With prefix delegation or shared ENIs many IPs resolve to the same
+// ENI, and keeping one ENIInfo (and tag map) per ENI instead of per IP avoids
+// holding thousands of identical copies.
+//
+// Interned ENIInfos are never modified. When a lookup returns different data for
+// an ENI (e.g. its tags changed), a new ENIInfo replaces the old one for every IP
+// sharing the ENI, since the newer lookup is the more accurate one; readers
+// still holding the old pointer keep a consistent snapshot.
+//
+// c.mu must be held for writing.
+func (c *ENICache) internLocked(ip string, info *aws.ENIInfo) *aws.ENIInfo {
+	if info == nil || info.ID == "" {
+		return info
+	}
+	shared, ok := c.enis[info.ID]
+	if !ok {
+		shared = &internedENI{info: info, ips: make(map[string]struct{})}
+		c.enis[info.ID] = shared
+	} else if !sameENIInfo(shared.info, info) {
+		shared.info = info
+		for other := range shared.ips {
+			if entry, ok := c.cache[other]; ok {
+				entry.Info = info
+				c.cache[other] = entry
+			}
+		}
+	}
+	shared.ips[ip] = struct{}{}
+	return shared.info
+}
+
+// releaseLocked drops ip from the users of the ENIInfo it was cached with,
+// forgetting the ENI once no cached IP refers to it. c.mu must be held for writing.
+func (c *ENICache) releaseLocked(ip string, info *aws.ENIInfo) {
+	if info == nil {
+		return
+	}
+	shared, ok := c.enis[info.ID]
+	if !ok {
+		return
+	}
+	delete(shared.ips, ip)
+	if len(shared.ips) == 0 {
+		delete(c.enis, info.ID)
+	}
+}
+
+func sameENIInfo(a, b *aws.ENIInfo) bool {
+	return a.ID == b.ID &&
+		a.SubnetID == b.SubnetID &&
+		a.AvailabilityZone == b.AvailabilityZone &&
+		a.InterfaceType == b.InterfaceType &&
+		a.Class == b.Class &&
+		a.IsShared == b.IsShared &&
+		a.Description == b.Description &&
+		a.Status == b.Status &&
+		a.AttachmentStatus == b.AttachmentStatus &&
+		slices.Equal(a.SecurityGroupIDs, b.SecurityGroupIDs) &&
+		maps.Equal(a.Tags, b.Tags)
+}
+
+// ENICount returns the number of distinct ENIs held by the cache (for testing/metrics).
+func (c *ENICache) ENICount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.enis)
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+)
+
+func TestENICache_InternsENIInfoByID(t *testing.T) {
+	ctx := context.Background()
+	lookups := 0
+	tags := map[string]string{"Team": "platform"}
+	mockAWS := &MockAWSClient{
+		GetENIInfoByIPFunc: func(ctx context.Context, ip string) (*aws.ENIInfo, error) {
+			lookups++
+			// Every lookup returns a fresh copy, as the AWS client does
+			copied := make(map[string]string, len(tags))
+			for k, v := range tags {
+				copied[k] = v
+			}
+			return &aws.ENIInfo{ID: "eni-shared", SubnetID: "subnet-1", Tags: copied}, nil
+		},
+	}
+	c := NewENICache(mockAWS)
+
+	first, err := c.GetENIInfoByIP(ctx, "10.0.0.1", "uid-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := c.GetENIInfoByIP(ctx, "10.0.0.2", "uid-2")
+	if first != second {
+		t.Error("expected IPs on the same ENI to share one ENIInfo")
+	}
+	if c.Size() != 2 || c.ENICount() != 1 {
+		t.Errorf("expected 2 IPs on 1 ENI, got %d IPs on %d ENIs", c.Size(), c.ENICount())
+	}
+
+	// A lookup with changed tags replaces the shared copy for every IP on the ENI,
+	// leaving the old snapshot untouched.
+	tags["Team"] = "payments"
+	third, _ := c.GetENIInfoByIP(ctx, "10.0.0.3", "uid-3")
+	if third == first {
+		t.Fatal("expected changed ENI data to get a new ENIInfo")
+	}
+	if first.Tags["Team"] != "platform" {
+		t.Errorf("old ENIInfo was modified: %v", first.Tags)
+	}
+	refreshed, _ := c.GetENIInfoByIP(ctx, "10.0.0.1", "uid-1")
+	if refreshed != third {
+		t.Error("expected existing IPs to be repointed to the newer ENIInfo")
+	}
+	if lookups != 3 {
+		t.Errorf("expected 3 AWS lookups, got %d", lookups)
+	}
+
+	// The ENI is forgotten once no cached IP refers to it.
+	c.Invalidate(ctx, "10.0.0.1", "uid-1")
+	c.Invalidate(ctx, "10.0.0.2", "uid-2")
+	if c.ENICount() != 1 {
+		t.Errorf("expected ENI kept while 10.0.0.3 is cached, got %d", c.ENICount())
+	}
+	c.Invalidate(ctx, "10.0.0.3", "uid-3")
+	if c.ENICount() != 0 {
+		t.Errorf("expected no interned ENIs, got %d", c.ENICount())
+	}
+}
+
+func TestENICache_LoadFromConfigMapInterns(t *testing.T) {
+	persister := &MockConfigMapPersister{store: map[string]CachedEntry{
+		"10.0.0.1": {Info: &aws.ENIInfo{ID: "eni-1", Tags: map[string]string{"a": "1"}}, PodUID: "uid-1"},
+		"10.0.0.2": {Info: &aws.ENIInfo{ID: "eni-1", Tags: map[string]string{"a": "1"}}, PodUID: "uid-2"},
+	}}
+	c := NewENICache(&MockAWSClient{}).WithConfigMapPersister(persister)
+	if err := c.LoadFromConfigMap(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.ENICount() != 1 {
+		t.Errorf("expected entries loaded for one ENI to share an ENIInfo, got %d ENIs", c.ENICount())
+	}
+}
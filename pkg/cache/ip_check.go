@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/metrics"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ipCheckWindow is how long a cache hit waits for others to share its IP check.
+const ipCheckWindow = 50 * time.Millisecond
+
+// ipChecker looks up the ENIs of IPs found in the cache, batching the IPs of
+// hits within ipCheckWindow (up to aws.MaxLocateIPs) into one AWS call.
+type ipChecker struct {
+	locator aws.IPLocator
+	window  time.Duration
+
+	mu    sync.Mutex
+	batch *ipCheckBatch
+}
+
+// ipCheckBatch is one AWS call shared by the checks that joined it.
+type ipCheckBatch struct {
+	ctx  context.Context
+	ips  []string
+	once sync.Once
+	done chan struct{}
+
+	located map[string]string
+	err     error
+}
+
+// eniOf returns the ID of the ENI ip is assigned to now, or "" if none.
+func (c *ipChecker) eniOf(ctx context.Context, ip string) (string, error) {
+	c.mu.Lock()
+	b := c.batch
+	if b == nil {
+		b = &ipCheckBatch{ctx: context.WithoutCancel(ctx), done: make(chan struct{})}
+		c.batch = b
+		time.AfterFunc(c.window, func() { c.run(b) })
+	}
+	b.ips = append(b.ips, ip)
+	full := len(b.ips) >= aws.MaxLocateIPs
+	if full {
+		c.batch = nil
+	}
+	c.mu.Unlock()
+
+	if full {
+		go c.run(b)
+	}
+	select {
+	case <-b.done:
+		if b.err != nil {
+			return "", b.err
+		}
+		return b.located[ip], nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// run closes the batch to new checks and makes its AWS call, once.
+func (c *ipChecker) run(b *ipCheckBatch) {
+	b.once.Do(func() {
+		c.mu.Lock()
+		if c.batch == b {
+			c.batch = nil
+		}
+		ips := b.ips
+		c.mu.Unlock()
+
+		b.located, b.err = c.locator.LocateIPs(b.ctx, ips)
+		close(b.done)
+	})
+}
+
+// EnableIPCheck makes every cache hit check that the IP is still assigned to the
+// cached ENI before returning it, to catch IPs moved to another ENI while their
+// pod lives (e.g. by the CNI after an ENI was detached). Checks of hits made at
+// about the same time share one DescribeNetworkInterfaces call. On a mismatch
+// the entry is dropped and the ENI looked up again; if the check itself fails,
+// the cached ENI is used. The cache's AWS client must implement aws.IPLocator.
+func (c *ENICache) EnableIPCheck() error {
+	locator, ok := c.awsClient.(aws.IPLocator)
+	if !ok {
+		return fmt.Errorf("AWS client %T cannot locate IPs", c.awsClient)
+	}
+	c.ipCheck = &ipChecker{locator: locator, window: ipCheckWindow}
+	return nil
+}
+
+// stillAssigned reports whether ip is still on the cached ENI, or its check failed.
+func (c *ENICache) stillAssigned(ctx context.Context, ip string, info *aws.ENIInfo) bool {
+	logger := log.FromContext(ctx)
+	eniID, err := c.ipCheck.eniOf(ctx, ip)
+	switch {
+	case err != nil:
+		metrics.CacheIPChecksTotal.WithLabelValues("error").Inc()
+		logger.V(1).Info("ENI cache IP check failed, using the cached ENI", "ip", ip, "eni", info.ID, "error", err.Error())
+		return true
+	case eniID == info.ID:
+		metrics.CacheIPChecksTotal.WithLabelValues("match").Inc()
+		return true
+	default:
+		metrics.CacheIPChecksTotal.WithLabelValues("mismatch").Inc()
+		logger.Info("IP is no longer on the cached ENI, looking it up again", "ip", ip, "cachedENI", info.ID, "currentENI", eniID)
+		return false
+	}
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// locatingAWSClient adds aws.IPLocator to MockAWSClient.
+type locatingAWSClient struct {
+	MockAWSClient
+	LocateIPsFunc func(ctx context.Context, ips []string) (map[string]string, error)
+}
+
+func (m *locatingAWSClient) LocateIPs(ctx context.Context, ips []string) (map[string]string, error) {
+	return m.LocateIPsFunc(ctx, ips)
+}
+
+func TestENICache_IPCheck(t *testing.T) {
+	ctx := context.Background()
+	require.Error(t, NewENICache(&MockAWSClient{}).EnableIPCheck())
+
+	var lookups, locateCalls atomic.Int32
+	var mu sync.Mutex
+	located := map[string]string{"10.0.0.1": "eni-a", "10.0.0.2": "eni-a"}
+	var locateErr error
+	client := &locatingAWSClient{
+		MockAWSClient: MockAWSClient{GetENIInfoByIPFunc: func(ctx context.Context, ip string) (*aws.ENIInfo, error) {
+			lookups.Add(1)
+			mu.Lock()
+			defer mu.Unlock()
+			return &aws.ENIInfo{ID: located[ip]}, nil
+		}},
+		LocateIPsFunc: func(ctx context.Context, ips []string) (map[string]string, error) {
+			locateCalls.Add(1)
+			mu.Lock()
+			defer mu.Unlock()
+			return located, locateErr
+		},
+	}
+	c := NewENICache(client)
+	require.NoError(t, c.EnableIPCheck())
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		_, err := c.GetENIInfoByIP(ctx, ip, "uid-"+ip)
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(2), lookups.Load())
+	require.Equal(t, int32(0), locateCalls.Load())
+
+	// Concurrent hits share one check
+	var wg sync.WaitGroup
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info, err := c.GetENIInfoByIP(ctx, ip, "uid-"+ip)
+			assert.NoError(t, err)
+			assert.Equal(t, "eni-a", info.ID)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), locateCalls.Load())
+	assert.Equal(t, int32(2), lookups.Load())
+
+	// A moved IP is looked up again
+	mu.Lock()
+	located = map[string]string{"10.0.0.1": "eni-b"}
+	mu.Unlock()
+	info, err := c.GetENIInfoByIP(ctx, "10.0.0.1", "uid-10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "eni-b", info.ID)
+	assert.Equal(t, int32(3), lookups.Load())
+
+	// A failed check uses the cached ENI
+	mu.Lock()
+	locateErr = errors.New("throttled")
+	mu.Unlock()
+	info, err = c.GetENIInfoByIP(ctx, "10.0.0.1", "uid-10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "eni-b", info.ID)
+	assert.Equal(t, int32(3), lookups.Load())
+}
@@ -0,0 +1,176 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Labels the owning leader stamps on the cache ConfigMap with every write.
+const (
+	// OwnerLabel holds the leader election identity of the replica that owns the
+	// ConfigMap, hashed when it is not a valid label value.
+	OwnerLabel = "eni-tagger.io/cache-owner"
+	// EpochLabel holds the lease transitions of the owner's term, the fencing
+	// token: a write is refused once the ConfigMap carries a higher epoch.
+	EpochLabel = "eni-tagger.io/cache-epoch"
+)
+
+// ErrNotOwner is returned by persister writes from a replica that does not own
+// the cache ConfigMap: a replica waiting for the lease, or a stale leader whose
+// successor already claimed the ConfigMap.
+var ErrNotOwner = errors.New("replica does not own the ENI cache ConfigMap")
+
+// Owner is a leader's term: the lease holder identity and the lease transitions
+// when it was elected, which only grow.
+type Owner struct {
+	Identity string
+	Epoch    int64
+}
+
+// ownedPersister is implemented by persisters that fence writes by owner, as
+// configMapPersister does.
+type ownedPersister interface {
+	// SetOwner sets the term writes are made under; nil refuses all writes.
+	SetOwner(owner *Owner)
+	// Claim sets owner and stamps it on the stored cache, so writes of earlier
+	// terms are refused from then on.
+	Claim(ctx context.Context, owner Owner) error
+}
+
+// ownerLabelValue returns identity as a label value. Identities are usually the
+// hostname and a UUID, which may be longer than a label value can be.
+func ownerLabelValue(identity string) string {
+	if len(validation.IsValidLabelValue(identity)) == 0 {
+		return identity
+	}
+	sum := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(sum[:16])
+}
+
+// fenceConfigMap checks that owner may write cm and stamps owner on it. A
+// ConfigMap claimed at a later epoch, or by another replica at the same epoch,
+// is not written.
+func fenceConfigMap(cm *corev1.ConfigMap, owner Owner) error {
+	ownerValue := ownerLabelValue(owner.Identity)
+	if epochValue, ok := cm.Labels[EpochLabel]; ok {
+		epoch, err := strconv.ParseInt(epochValue, 10, 64)
+		if err == nil && (epoch > owner.Epoch || (epoch == owner.Epoch && cm.Labels[OwnerLabel] != ownerValue)) {
+			return fmt.Errorf("%w: claimed by %s at epoch %d, this replica's epoch is %d", ErrNotOwner, cm.Labels[OwnerLabel], epoch, owner.Epoch)
+		}
+	}
+	if cm.Labels == nil {
+		cm.Labels = make(map[string]string, 2)
+	}
+	cm.Labels[OwnerLabel] = ownerValue
+	cm.Labels[EpochLabel] = strconv.FormatInt(owner.Epoch, 10)
+	return nil
+}
+
+// Ownership makes the elected replica the only writer of the ENI cache
+// ConfigMap. On election it claims the ConfigMap under the leader lease's
+// holder and transitions, then loads the cache the previous leader persisted.
+// It watches the lease and gives up ownership as soon as it changes hands, so
+// batched writes still queued on a deposed leader are dropped instead of
+// overwriting its successor's entries.
+//
+// Replicas start without ownership (ENICache.SetOwner(nil)) and Ownership only
+// runs on the leader.
+type Ownership struct {
+	Cache *ENICache
+	// Reader reads the lease uncached, normally the manager's API reader.
+	Reader client.Reader
+	// Lease is the leader election lease of the manager.
+	Lease types.NamespacedName
+	// CheckInterval is the time between lease reads. Defaults to 5s.
+	CheckInterval time.Duration
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (o *Ownership) NeedLeaderElection() bool {
+	return true
+}
+
+// Start implements manager.Runnable.
+func (o *Ownership) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("cache-ownership")
+	interval := o.CheckInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer o.Cache.SetOwner(nil)
+
+	var owner Owner
+	for {
+		var err error
+		if owner, err = o.readOwner(ctx); err == nil {
+			break
+		}
+		logger.Error(err, "Failed to read leader lease, ENI cache writes stay disabled")
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+
+	if err := o.Cache.Claim(ctx, owner); err != nil {
+		logger.Error(err, "Failed to claim ENI cache ConfigMap", "identity", owner.Identity, "epoch", owner.Epoch)
+	} else {
+		logger.Info("Claimed ENI cache ConfigMap", "identity", owner.Identity, "epoch", owner.Epoch)
+	}
+	// Pick up what the previous leader wrote while this replica was waiting
+	if err := o.Cache.LoadFromConfigMap(ctx); err != nil {
+		logger.Error(err, "Failed to load ENI cache from ConfigMap on election")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			current, err := o.readOwner(ctx)
+			if err != nil {
+				logger.V(1).Info("Failed to read leader lease", "error", err.Error())
+				continue
+			}
+			if current != owner {
+				logger.Info("Leader lease changed hands, giving up ENI cache ownership",
+					"identity", owner.Identity, "epoch", owner.Epoch, "holder", current.Identity, "holderEpoch", current.Epoch)
+				return nil
+			}
+		}
+	}
+}
+
+// readOwner returns the current term of the lease.
+func (o *Ownership) readOwner(ctx context.Context) (Owner, error) {
+	lease := &coordinationv1.Lease{}
+	if err := o.Reader.Get(ctx, o.Lease, lease); err != nil {
+		return Owner{}, fmt.Errorf("failed to get lease %s: %w", o.Lease, err)
+	}
+	owner := Owner{}
+	if lease.Spec.HolderIdentity != nil {
+		owner.Identity = *lease.Spec.HolderIdentity
+	}
+	if lease.Spec.LeaseTransitions != nil {
+		owner.Epoch = int64(*lease.Spec.LeaseTransitions)
+	}
+	if owner.Identity == "" {
+		return Owner{}, fmt.Errorf("lease %s has no holder", o.Lease)
+	}
+	return owner, nil
+}
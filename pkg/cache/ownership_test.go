@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func ownershipTestScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	require.NoError(t, coordinationv1.AddToScheme(scheme))
+	return scheme
+}
+
+func getCacheConfigMap(t *testing.T, c client.Client) *corev1.ConfigMap {
+	t.Helper()
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, c.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: ConfigMapName}, cm))
+	return cm
+}
+
+func TestConfigMapPersisterFencing(t *testing.T) {
+	ctx := context.Background()
+	entry := CachedEntry{Info: &aws.ENIInfo{ID: "eni-1"}, PodUID: "pod-1"}
+
+	t.Run("Follower", func(t *testing.T) {
+		k8sClient := fake.NewClientBuilder().WithScheme(ownershipTestScheme(t)).Build()
+		p := NewConfigMapPersister(k8sClient, k8sClient, "default").(*configMapPersister)
+		p.SetOwner(nil)
+
+		assert.ErrorIs(t, p.Save(ctx, "10.0.0.1", entry), ErrNotOwner)
+		assert.ErrorIs(t, p.Delete(ctx, "10.0.0.1"), ErrNotOwner)
+		cms := &corev1.ConfigMapList{}
+		require.NoError(t, k8sClient.List(ctx, cms))
+		assert.Empty(t, cms.Items, "a follower makes no writes")
+	})
+
+	t.Run("StaleLeader", func(t *testing.T) {
+		k8sClient := fake.NewClientBuilder().WithScheme(ownershipTestScheme(t)).Build()
+		stale := NewConfigMapPersister(k8sClient, k8sClient, "default").(*configMapPersister)
+		stale.SetOwner(&Owner{Identity: "replica-a", Epoch: 1})
+		require.NoError(t, stale.Save(ctx, "10.0.0.1", entry))
+		cm := getCacheConfigMap(t, k8sClient)
+		assert.Equal(t, "replica-a", cm.Labels[OwnerLabel])
+		assert.Equal(t, "1", cm.Labels[EpochLabel])
+
+		leader := NewConfigMapPersister(k8sClient, k8sClient, "default").(*configMapPersister)
+		require.NoError(t, leader.Claim(ctx, Owner{Identity: "replica-b", Epoch: 2}))
+		cm = getCacheConfigMap(t, k8sClient)
+		assert.Equal(t, "replica-b", cm.Labels[OwnerLabel])
+		assert.Equal(t, "2", cm.Labels[EpochLabel])
+
+		err := stale.Save(ctx, "10.0.0.2", entry)
+		require.ErrorIs(t, err, ErrNotOwner)
+		assert.Contains(t, err.Error(), "claimed by replica-b at epoch 2")
+		assert.ErrorIs(t, stale.Delete(ctx, "10.0.0.1"), ErrNotOwner, "the refused leader drops its term")
+		assert.NotContains(t, getCacheConfigMap(t, k8sClient).Data, "10.0.0.2")
+
+		require.NoError(t, leader.Save(ctx, "10.0.0.2", entry))
+		require.NoError(t, leader.Delete(ctx, "10.0.0.1"))
+		assert.Equal(t, []string{"10.0.0.2"}, mapKeys(getCacheConfigMap(t, k8sClient).Data))
+	})
+
+	t.Run("Unfenced", func(t *testing.T) {
+		existing := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+			Name: ConfigMapName, Namespace: "default",
+			Labels: map[string]string{OwnerLabel: "replica-b", EpochLabel: "2"},
+		}}
+		k8sClient := fake.NewClientBuilder().WithScheme(ownershipTestScheme(t)).WithObjects(existing).Build()
+		p := NewConfigMapPersister(k8sClient, k8sClient, "default")
+
+		require.NoError(t, p.Save(ctx, "10.0.0.1", entry), "without leader election writes are not fenced")
+	})
+}
+
+func mapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestOwnerLabelValue(t *testing.T) {
+	assert.Equal(t, "node-1_0f1e2d3c", ownerLabelValue("node-1_0f1e2d3c"))
+
+	long := ownerLabelValue(strings.Repeat("ip-10-0-0-1.ec2.internal", 3) + "_0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0")
+	assert.Empty(t, validation.IsValidLabelValue(long))
+	assert.Len(t, long, 32)
+}
+
+func TestOwnership(t *testing.T) {
+	holder, transitions := "replica-b", int32(3)
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "k8s-eni-tagger.eni-tagger.io", Namespace: "default"},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:   &holder,
+			LeaseTransitions: &transitions,
+		},
+	}
+	existing := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: ConfigMapName, Namespace: "default",
+			Labels: map[string]string{OwnerLabel: "replica-a", EpochLabel: "2"},
+		},
+		Data: map[string]string{"10.0.0.1": `{"info":{"id":"eni-1"},"pod_uid":"pod-1"}`},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(ownershipTestScheme(t)).WithObjects(lease, existing).Build()
+
+	c := NewENICache(&MockAWSClient{})
+	c.WithConfigMapPersister(NewConfigMapPersister(k8sClient, k8sClient, "default"))
+	c.SetOwner(nil)
+	o := &Ownership{Cache: c, Reader: k8sClient, Lease: types.NamespacedName{Namespace: "default", Name: lease.Name}, CheckInterval: 10 * time.Millisecond}
+	assert.True(t, o.NeedLeaderElection())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- o.Start(ctx) }()
+
+	// Claimed and loaded on election
+	require.Eventually(t, func() bool { return c.Size() == 1 }, time.Second, 5*time.Millisecond)
+	cm := getCacheConfigMap(t, k8sClient)
+	assert.Equal(t, "replica-b", cm.Labels[OwnerLabel])
+	assert.Equal(t, "3", cm.Labels[EpochLabel])
+
+	// Lease taken over: ownership is given up and writes are refused
+	newHolder, newTransitions := "replica-c", int32(4)
+	lease.Spec.HolderIdentity = &newHolder
+	lease.Spec.LeaseTransitions = &newTransitions
+	require.NoError(t, k8sClient.Update(context.Background(), lease))
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("ownership did not stop after the lease changed hands")
+	}
+	err := c.cmPersister.Save(context.Background(), "10.0.0.2", CachedEntry{Info: &aws.ENIInfo{ID: "eni-2"}})
+	assert.ErrorIs(t, err, ErrNotOwner)
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Warm looks up the ENIs of pods not cached yet, keyed by IP with the pod's UID
+// as value, with one batched AWS call per aws.MaxLocateIPs IPs instead of one
+// per pod. It returns how many entries were added. IPs without an ENI are left
+// for the pod's reconcile to report.
+func (c *ENICache) Warm(ctx context.Context, pods map[string]string) (int, error) {
+	var ips []string
+	for ip, podUID := range pods {
+		if _, ok := c.get(ctx, ip, podUID); !ok {
+			ips = append(ips, ip)
+		}
+	}
+	slices.Sort(ips)
+
+	warmed := 0
+	for batch := range slices.Chunk(ips, aws.MaxLocateIPs) {
+		infos, err := c.awsClient.GetENIInfoByIPs(ctx, batch)
+		if err != nil {
+			return warmed, err
+		}
+		for ip, info := range infos {
+			c.set(ctx, ip, info, pods[ip])
+			warmed++
+		}
+	}
+	return warmed, nil
+}
+
+// Resync looks up the ENIs of every cached IP again, in batches of
+// aws.MaxLocateIPs, so entries stay current without a per-pod call: entries
+// whose ENI changed (e.g. its status, or tags written outside the controller)
+// are updated, and entries whose IP is no longer on any ENI are dropped. It
+// returns the number of entries updated and dropped.
+func (c *ENICache) Resync(ctx context.Context) (updated, dropped int, err error) {
+	c.mu.RLock()
+	entries := make(map[string]CachedEntry, len(c.cache))
+	for ip, entry := range c.cache {
+		// Legacy entries are refreshed by their pod's next lookup
+		if entry.PodUID != "" {
+			entries[ip] = entry
+		}
+	}
+	c.mu.RUnlock()
+	ips := slices.Sorted(maps.Keys(entries))
+
+	for batch := range slices.Chunk(ips, aws.MaxLocateIPs) {
+		infos, err := c.awsClient.GetENIInfoByIPs(ctx, batch)
+		if err != nil {
+			return updated, dropped, err
+		}
+		for _, ip := range batch {
+			entry := entries[ip]
+			info, ok := infos[ip]
+			switch {
+			case !ok:
+				c.Invalidate(ctx, ip, entry.PodUID)
+				dropped++
+			case !sameENIInfo(info, entry.Info) && c.unchanged(ip, entry):
+				c.set(ctx, ip, info, entry.PodUID)
+				updated++
+			}
+		}
+	}
+	return updated, dropped, nil
+}
+
+// unchanged reports whether ip is still cached as entry, i.e. no reconcile
+// replaced it while its batch was being looked up.
+func (c *ENICache) unchanged(ip string, entry CachedEntry) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	current, ok := c.cache[ip]
+	return ok && current.PodUID == entry.PodUID && current.Info == entry.Info
+}
+
+// Resyncer runs Resync every Interval on the leader.
+type Resyncer struct {
+	Cache    *ENICache
+	Interval time.Duration
+}
+
+// Start implements manager.Runnable. Without NeedLeaderElection the manager
+// only runs it on the leader, whose cache the reconciles use.
+func (r *Resyncer) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("eni-cache-resync")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			start := time.Now()
+			updated, dropped, err := r.Cache.Resync(ctx)
+			if err != nil {
+				logger.Error(err, "Failed to resync ENI cache", "updated", updated, "dropped", dropped)
+				continue
+			}
+			logger.V(1).Info("Resynced ENI cache", "entries", r.Cache.Size(), "updated", updated, "dropped", dropped, "duration", time.Since(start))
+		}
+	}
+}
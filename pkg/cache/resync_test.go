@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestENICache_Warm(t *testing.T) {
+	var batches [][]string
+	mockAWS := &MockAWSClient{
+		GetENIInfoByIPsFunc: func(ctx context.Context, ips []string) (map[string]*aws.ENIInfo, error) {
+			batches = append(batches, ips)
+			infos := make(map[string]*aws.ENIInfo)
+			for _, ip := range ips {
+				if ip != "10.0.9.9" {
+					infos[ip] = &aws.ENIInfo{ID: "eni-" + ip}
+				}
+			}
+			return infos, nil
+		},
+	}
+	c := NewENICache(mockAWS)
+	ctx := context.Background()
+	c.set(ctx, "10.0.0.0", &aws.ENIInfo{ID: "eni-cached"}, "uid-0")
+
+	pods := map[string]string{"10.0.9.9": "uid-host"}
+	for i := 0; i < aws.MaxLocateIPs+10; i++ {
+		pods[fmt.Sprintf("10.0.%d.%d", i/100, i%100)] = fmt.Sprintf("uid-%d", i)
+	}
+	warmed, err := c.Warm(ctx, pods)
+	require.NoError(t, err)
+
+	// The cached pod is not looked up again; the rest take two calls
+	require.Len(t, batches, 2)
+	assert.Len(t, batches[0], aws.MaxLocateIPs)
+	assert.Len(t, batches[1], 10)
+	assert.Equal(t, aws.MaxLocateIPs+9, warmed)
+	info, ok := c.get(ctx, "10.0.1.5", "uid-105")
+	require.True(t, ok)
+	assert.Equal(t, "eni-10.0.1.5", info.ID)
+	info, ok = c.get(ctx, "10.0.0.0", "uid-0")
+	require.True(t, ok)
+	assert.Equal(t, "eni-cached", info.ID)
+}
+
+func TestENICache_Resync(t *testing.T) {
+	current := map[string]*aws.ENIInfo{
+		"10.0.0.1": {ID: "eni-a", Status: "in-use"},
+		"10.0.0.2": {ID: "eni-b", Status: "in-use", Tags: map[string]string{"team": "edited"}},
+	}
+	mockAWS := &MockAWSClient{
+		GetENIInfoByIPsFunc: func(ctx context.Context, ips []string) (map[string]*aws.ENIInfo, error) {
+			return current, nil
+		},
+	}
+	c := NewENICache(mockAWS)
+	ctx := context.Background()
+	c.set(ctx, "10.0.0.1", &aws.ENIInfo{ID: "eni-a", Status: "in-use"}, "uid-1")
+	c.set(ctx, "10.0.0.2", &aws.ENIInfo{ID: "eni-b", Status: "in-use", Tags: map[string]string{"team": "a"}}, "uid-2")
+	c.set(ctx, "10.0.0.3", &aws.ENIInfo{ID: "eni-c"}, "uid-3")
+
+	updated, dropped, err := c.Resync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+	assert.Equal(t, 1, dropped)
+	assert.Equal(t, 2, c.Size())
+	info, ok := c.get(ctx, "10.0.0.2", "uid-2")
+	require.True(t, ok)
+	assert.Equal(t, "edited", info.Tags["team"])
+	_, ok = c.get(ctx, "10.0.0.3", "uid-3")
+	assert.False(t, ok)
+}
@@ -0,0 +1,105 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// StandbyWarmer keeps a non-leader replica's ENI cache in step with the
+// ConfigMap the leader persists to, so a replica that takes over after leader
+// loss starts with a warm cache instead of one AWS call per pod. It runs on
+// every replica and stops refreshing once its replica is elected.
+type StandbyWarmer struct {
+	Cache *ENICache
+	// Interval is the time between ConfigMap reads while on standby.
+	Interval time.Duration
+	// Elected is closed when this replica becomes the leader (mgr.Elected()).
+	Elected <-chan struct{}
+
+	mu          sync.Mutex
+	leader      bool
+	lastRefresh time.Time
+	lastErr     error
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable: the warmer is
+// only useful on replicas that are not the leader.
+func (w *StandbyWarmer) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable.
+func (w *StandbyWarmer) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("standby-cache")
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.Elected:
+			// Ownership loads what the previous leader wrote after the last
+			// refresh once it has claimed the ConfigMap
+			w.mu.Lock()
+			w.leader = true
+			w.mu.Unlock()
+			logger.Info("Elected leader, stopped standby cache refresh", "entries", w.Cache.Size())
+			return nil
+		case <-ticker.C:
+			err := w.Cache.SyncFromConfigMap(ctx)
+			w.record(err)
+			if err != nil {
+				logger.Error(err, "Failed to refresh standby ENI cache from ConfigMap")
+			}
+		}
+	}
+}
+
+func (w *StandbyWarmer) record(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.lastErr = err
+	if err == nil {
+		w.lastRefresh = time.Now()
+	}
+}
+
+// standbyStatus is the JSON body served by StandbyWarmer.ServeHTTP.
+type standbyStatus struct {
+	Leader      bool       `json:"leader"`
+	Entries     int        `json:"entries"`
+	ENIs        int        `json:"enis"`
+	LastRefresh *time.Time `json:"lastRefresh,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+}
+
+// ServeHTTP reports whether this replica leads and how warm its cache is.
+func (w *StandbyWarmer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		rw.Header().Set("Allow", "GET")
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.mu.Lock()
+	status := standbyStatus{Leader: w.leader}
+	if !w.lastRefresh.IsZero() {
+		t := w.lastRefresh
+		status.LastRefresh = &t
+	}
+	if w.lastErr != nil {
+		status.LastError = w.lastErr.Error()
+	}
+	w.mu.Unlock()
+	status.Entries = w.Cache.Size()
+	status.ENIs = w.Cache.ENICount()
+
+	rw.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(rw).Encode(status)
+}
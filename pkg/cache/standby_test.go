@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestENICache_SyncFromConfigMap(t *testing.T) {
+	persister := &MockConfigMapPersister{store: map[string]CachedEntry{
+		"10.0.0.1": {Info: &aws.ENIInfo{ID: "eni-1"}, PodUID: "pod-1"},
+		"10.0.0.2": {Info: &aws.ENIInfo{ID: "eni-1"}, PodUID: "pod-2"},
+	}}
+	c := NewENICache(&MockAWSClient{})
+	c.WithConfigMapPersister(persister)
+	ctx := context.Background()
+
+	require.NoError(t, c.SyncFromConfigMap(ctx))
+	assert.Equal(t, 2, c.Size())
+	assert.Equal(t, 1, c.ENICount())
+
+	// The leader invalidated one pod and cached another
+	persister.mu.Lock()
+	delete(persister.store, "10.0.0.1")
+	persister.store["10.0.0.3"] = CachedEntry{Info: &aws.ENIInfo{ID: "eni-2"}, PodUID: "pod-3"}
+	persister.mu.Unlock()
+
+	require.NoError(t, c.SyncFromConfigMap(ctx))
+	assert.Equal(t, 2, c.Size())
+	assert.Equal(t, 2, c.ENICount())
+	info, ok := c.get(ctx, "10.0.0.3", "pod-3")
+	require.True(t, ok)
+	assert.Equal(t, "eni-2", info.ID)
+	_, ok = c.get(ctx, "10.0.0.1", "pod-1")
+	assert.False(t, ok, "entries the ConfigMap no longer has are dropped")
+	assert.False(t, persister.saveCalled, "a sync never writes back")
+}
+
+func TestENICache_SyncFromConfigMapDelta(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	leader := NewConfigMapPersister(k8sClient, k8sClient, "default")
+	ctx := context.Background()
+	require.NoError(t, leader.Save(ctx, "10.0.0.1", CachedEntry{Info: &aws.ENIInfo{ID: "eni-1"}, PodUID: "pod-1"}))
+
+	c := NewENICache(&MockAWSClient{})
+	c.WithConfigMapPersister(NewConfigMapPersister(k8sClient, k8sClient, "default"))
+	require.NoError(t, c.SyncFromConfigMap(ctx))
+	assert.Equal(t, 1, c.Size())
+
+	// Entries this replica cached itself survive syncs that did not touch them
+	c.set(ctx, "10.0.0.9", &aws.ENIInfo{ID: "eni-9"}, "pod-9")
+	require.NoError(t, leader.Save(ctx, "10.0.0.2", CachedEntry{Info: &aws.ENIInfo{ID: "eni-1"}, PodUID: "pod-2"}))
+	require.NoError(t, leader.Delete(ctx, "10.0.0.1"))
+	require.NoError(t, c.SyncFromConfigMap(ctx))
+
+	_, ok := c.get(ctx, "10.0.0.1", "pod-1")
+	assert.False(t, ok)
+	info, ok := c.get(ctx, "10.0.0.2", "pod-2")
+	require.True(t, ok)
+	assert.Equal(t, "eni-1", info.ID)
+	_, ok = c.get(ctx, "10.0.0.9", "pod-9")
+	assert.True(t, ok)
+}
+
+func TestStandbyWarmer(t *testing.T) {
+	persister := &MockConfigMapPersister{store: map[string]CachedEntry{
+		"10.0.0.1": {Info: &aws.ENIInfo{ID: "eni-1"}, PodUID: "pod-1"},
+	}}
+	c := NewENICache(&MockAWSClient{})
+	c.WithConfigMapPersister(persister)
+	elected := make(chan struct{})
+	w := &StandbyWarmer{Cache: c, Interval: 10 * time.Millisecond, Elected: elected}
+	assert.False(t, w.NeedLeaderElection())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- w.Start(ctx) }()
+
+	require.Eventually(t, func() bool { return c.Size() == 1 }, time.Second, 5*time.Millisecond)
+
+	status := func() standbyStatus {
+		rec := httptest.NewRecorder()
+		w.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/eni-cache", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var s standbyStatus
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &s))
+		return s
+	}
+	s := status()
+	assert.False(t, s.Leader)
+	assert.Equal(t, 1, s.Entries)
+	assert.NotNil(t, s.LastRefresh)
+
+	close(elected)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("warmer did not stop after election")
+	}
+	assert.True(t, status().Leader)
+
+	rec := httptest.NewRecorder()
+	w.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/eni-cache", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
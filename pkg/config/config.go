@@ -1,15 +1,97 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
+// DefaultKeyDomain is the default key-domain; it matches controller.DefaultKeyDomain.
+const DefaultKeyDomain = "eni-tagger.io"
+
+// Valid values for the aws-health-probe setting
+const (
+	AWSHealthProbeReadyz  = "readyz"
+	AWSHealthProbeHealthz = "healthz"
+	AWSHealthProbeNone    = "none"
+)
+
+// Valid values for the tag-key-case-conflict setting; they match controller.TagKeyCase*.
+const (
+	TagKeyCaseConflictAllow     = "allow"
+	TagKeyCaseConflictReject    = "reject"
+	TagKeyCaseConflictNormalize = "normalize"
+)
+
+// Valid values for the tag-value-templates setting; they match controller.TagValueTemplates*.
+const (
+	TagValueTemplatesNone = "none"
+	TagValueTemplatesPod  = "pod"
+	TagValueTemplatesNode = "node"
+)
+
+// Valid values for the aws-tag-backend setting; they match aws.TagBackend*.
+const (
+	AWSTagBackendEC2                   = "ec2"
+	AWSTagBackendResourceGroupsTagging = "resourcegroupstaggingapi"
+)
+
+// Valid values for the tag-diff-source setting; they match controller.TagDiffSource*.
+const (
+	TagDiffSourceAnnotation = "annotation"
+	TagDiffSourceENI        = "eni"
+)
+
+// Valid values for the host-network-eni setting; they match controller.HostNetworkENI*.
+const (
+	HostNetworkENIPodIP   = "pod-ip"
+	HostNetworkENIPrimary = "primary-eni"
+)
+
+// Valid values for the version-format setting.
+const (
+	VersionFormatText = "text"
+	VersionFormatJSON = "json"
+)
+
+// Valid values for the debug-pods setting; they match controller.DebugPods*.
+const (
+	DebugPodsSkip = "skip"
+	DebugPodsTag  = "tag"
+)
+
+// Valid values for the invalid-tags-policy setting; they match controller.InvalidTags*.
+const (
+	InvalidTagsPolicyKeep     = "keep"
+	InvalidTagsPolicyRollback = "rollback"
+	InvalidTagsPolicyRemove   = "remove"
+)
+
+// Valid values for the state-store setting.
+const (
+	StateStoreAnnotations = "annotations"
+	StateStoreConfigMap   = "configmap"
+)
+
+// MaxTagHistorySize is the largest accepted tag-history-size; it matches controller.MaxTagHistorySize.
+const MaxTagHistorySize = 20
+
+// maxTagBurstDelay caps TagBurstDelay: callers block a reconcile worker while
+// they wait.
+const maxTagBurstDelay = 30 * time.Second
+
+// maxPodWriteCollapseWindow caps PodWriteCollapseWindow: pod annotations and
+// conditions lag behind the ENI for up to a window.
+const maxPodWriteCollapseWindow = 10 * time.Second
+
 // Config holds all application configuration
 type Config struct {
 	MetricsBindAddress      string        `mapstructure:"metrics-bind-address"`
@@ -18,28 +100,270 @@ type Config struct {
 	AnnotationKey           string        `mapstructure:"annotation-key"`
 	MaxConcurrentReconciles int           `mapstructure:"max-concurrent-reconciles"`
 	DryRun                  bool          `mapstructure:"dry-run"`
+	AuditLogPath            string        `mapstructure:"audit-log-path"`
 	WatchNamespace          string        `mapstructure:"watch-namespace"`
 	PrintVersion            bool          `mapstructure:"version"`
+	VersionFormat           string        `mapstructure:"version-format"`
 	SubnetIDs               []string      `mapstructure:"subnet-ids"`
 	AllowSharedENITagging   bool          `mapstructure:"allow-shared-eni-tagging"`
 	EnableENICache          bool          `mapstructure:"enable-eni-cache"`
 	EnableCacheConfigMap    bool          `mapstructure:"enable-cache-configmap"`
 	CacheBatchInterval      time.Duration `mapstructure:"cache-batch-interval"`
 	CacheBatchSize          int           `mapstructure:"cache-batch-size"`
-	AWSRateLimitQPS         float64       `mapstructure:"aws-rate-limit-qps"`
-	AWSRateLimitBurst       int           `mapstructure:"aws-rate-limit-burst"`
-	PprofBindAddress        string        `mapstructure:"pprof-bind-address"`
-	TagNamespace            string        `mapstructure:"tag-namespace"`
-	PodRateLimitQPS         float64       `mapstructure:"pod-rate-limit-qps"`
-	PodRateLimitBurst       int           `mapstructure:"pod-rate-limit-burst"`
+	// ENICacheIPCheck checks on every ENI cache hit that the pod's IP is still on
+	// the cached ENI, batching the checks of concurrent hits into one AWS call.
+	ENICacheIPCheck bool `mapstructure:"eni-cache-ip-check"`
+	// ENICacheWarmup fills the ENI cache for existing pods at startup with
+	// batched lookups instead of one per pod.
+	ENICacheWarmup bool `mapstructure:"eni-cache-warmup"`
+	// ENICacheResyncInterval is how often the leader looks up every cached ENI
+	// again with batched calls; 0 disables it.
+	ENICacheResyncInterval time.Duration `mapstructure:"eni-cache-resync-interval"`
+	AWSRateLimitQPS        float64       `mapstructure:"aws-rate-limit-qps"`
+	AWSRateLimitBurst      int           `mapstructure:"aws-rate-limit-burst"`
+	PprofBindAddress       string        `mapstructure:"pprof-bind-address"`
+	TagNamespace           string        `mapstructure:"tag-namespace"`
+	PodRateLimitQPS        float64       `mapstructure:"pod-rate-limit-qps"`
+	PodRateLimitBurst      int           `mapstructure:"pod-rate-limit-burst"`
+	// AWSNamespaceBudgets caps namespaces at a fraction of the AWS rate limit, keyed by
+	// namespace or "*" for every other namespace. Parsed from aws-namespace-budgets.
+	AWSNamespaceBudgets map[string]float64 `mapstructure:"-"`
+	// AWSMutationBudget caps the EC2 CreateTags and DeleteTags calls per
+	// AWSMutationBudgetWindow. Once used up, drift repair and best-effort tag changes
+	// wait for the next window. 0 disables the budget.
+	AWSMutationBudget       int           `mapstructure:"aws-mutation-budget"`
+	AWSMutationBudgetWindow time.Duration `mapstructure:"aws-mutation-budget-window"`
+	// TagKeyRenames maps annotation tag keys to the keys written to ENIs (e.g.
+	// team -> CostTeam). Parsed from tag-key-renames.
+	TagKeyRenames map[string]string `mapstructure:"-"`
+	// TagFromLabels maps pod labels to the ENI tag keys their values are written
+	// to (e.g. cost-center -> CostCenter). Parsed from tag-from-labels.
+	TagFromLabels map[string]string `mapstructure:"-"`
+	// CriticalTagKeys are the ENI tag keys, or prefixes ending in "*", whose failure
+	// to apply fails the pod condition. Other tags are best-effort. Empty makes
+	// every tag critical. Parsed from critical-tag-keys.
+	CriticalTagKeys []string `mapstructure:"-"`
 	// RateLimiterCleanupInterval defines how often to run cleanup of stale per-pod rate limiters.
 	// The cleanup threshold is automatically set to 5x this interval (threshold = interval * 5).
 	// For example, with a 1m interval, rate limiters unused for 5+ minutes will be cleaned up.
 	RateLimiterCleanupInterval time.Duration `mapstructure:"rate-limiter-cleanup-interval"`
-	// AWSHealthMaxSuccesses controls after how many successful AWS health checks
-	// the checker will latch and stop making further AWS API calls for subsequent probes.
-	// Set to 0 to disable latching (always call AWS API). Must be >= 0.
-	AWSHealthMaxSuccesses int `mapstructure:"aws-health-max-successes"`
+	// PodRateLimitCondition sets the RateLimited condition, with the next attempt
+	// time, on pods whose reconcile the per-pod rate limit deferred.
+	PodRateLimitCondition bool `mapstructure:"pod-rate-limit-condition"`
+	// PodRateLimitersMax caps the number of per-pod rate limiters kept between
+	// cleanups; the least recently used are evicted past it. 0 means no cap.
+	PodRateLimitersMax int `mapstructure:"pod-rate-limiters-max"`
+	// AWSHealthCheckInterval is how often the background AWS health check runs.
+	// Probes only read the cached result, so they never trigger AWS API calls.
+	AWSHealthCheckInterval time.Duration `mapstructure:"aws-health-check-interval"`
+	// AWSHealthProbe selects which probe endpoint the AWS connectivity check is attached to.
+	// "readyz" (default) marks the controller not-ready during an AWS outage without restarting it,
+	// "healthz" restores the legacy liveness behavior, and "none" disables the check.
+	AWSHealthProbe string `mapstructure:"aws-health-probe"`
+	// VerifyPermissions checks at startup that every Kubernetes permission (with
+	// SelfSubjectAccessReviews) and EC2 action (with DryRun requests) the enabled
+	// features need is granted, reports all missing ones in one summary and
+	// refuses to start if a required one is missing.
+	VerifyPermissions bool `mapstructure:"verify-permissions"`
+	// VerifyTaggingPermissions is deprecated; false disables VerifyPermissions.
+	VerifyTaggingPermissions bool `mapstructure:"verify-tagging-permissions"`
+	// ExcludePodSelector is a label selector for pods that must never be tagged,
+	// even when they carry the tag annotation (e.g. CI runners in a shared namespace).
+	ExcludePodSelector string `mapstructure:"exclude-pod-selector"`
+	// DebugPods decides whether pods created by kubectl debug (copies with a debug
+	// container, node debugging pods) are tagged: "skip" (default) or "tag".
+	DebugPods string `mapstructure:"debug-pods"`
+	// KeyDomain is the domain used for the finalizer, pod condition type, ENI hash tag
+	// and last-applied annotations. Independent installations in one cluster must use
+	// different domains (and different annotation keys) so they never share state.
+	KeyDomain string `mapstructure:"key-domain"`
+	// ControllerID identifies this installation in the owner tag written to each ENI.
+	// ENIs owned by another ID are reported with a ForeignController condition and left
+	// untouched. Empty disables owner tracking.
+	ControllerID string `mapstructure:"controller-id"`
+	// ManagedByTag writes a managed-by=k8s-eni-tagger/<ClusterName> tag to each
+	// tagged ENI so people browsing the EC2 console can see where the tags come from.
+	ManagedByTag bool `mapstructure:"managed-by-tag"`
+	// MaxConcurrentReconcilesCeiling is the number of controller workers started. The
+	// effective concurrency starts at MaxConcurrentReconciles and can be changed at runtime
+	// through the admin endpoint up to this ceiling. 0 means MaxConcurrentReconciles.
+	MaxConcurrentReconcilesCeiling int `mapstructure:"max-concurrent-reconciles-ceiling"`
+	// NamespaceFairQueuing queues pod events per namespace and hands them to the
+	// workers round-robin, so a namespace creating many pods cannot starve the others.
+	NamespaceFairQueuing bool `mapstructure:"namespace-fair-queuing"`
+	// TriggerAudit logs why each reconcile was triggered and counts filtered and
+	// triggering pod events by reason, to find noisy event sources.
+	TriggerAudit bool `mapstructure:"trigger-audit"`
+	// MetricsExemplars attaches the reconcile ID to AWS API latency observations as
+	// an exemplar, served in the OpenMetrics format on /metrics/openmetrics.
+	MetricsExemplars bool `mapstructure:"metrics-exemplars"`
+	// AdminBindAddress serves runtime admin endpoints (/concurrency, /plan, /pause, /eni-cache). "0" disables it.
+	// It is unauthenticated, so bind it to localhost and use kubectl port-forward.
+	AdminBindAddress string `mapstructure:"admin-bind-address"`
+	// APIBindAddress serves the read-only debugging API (/api/v1/enis,
+	// /api/v1/pods/{namespace}/{name}/tags). "0" disables it. It is
+	// unauthenticated and exposes tags, so bind it to localhost as well.
+	APIBindAddress string `mapstructure:"api-bind-address"`
+	// ReconcileHistorySize is how many recent pod reconciles, with the request IDs
+	// of their AWS calls, are kept in memory for the API. 0 disables the history.
+	ReconcileHistorySize int `mapstructure:"reconcile-history-size"`
+	// AWSDebugLogging logs every EC2 HTTP exchange with its retry attempt, latency,
+	// status, request ID and parameters. Credentials and signatures are redacted.
+	AWSDebugLogging bool `mapstructure:"aws-debug-logging"`
+	// FaultAWSErrorRate, FaultCacheCorruptionRate and FaultCacheConflictRate inject
+	// failures for resilience tests (see aws.FaultInjection, cache.FaultInjection).
+	// They are hidden flags, normally set through the environment. 0 disables them;
+	// other values need a binary built with the faultinject tag.
+	FaultAWSErrorRate        float64 `mapstructure:"fault-aws-error-rate"`
+	FaultCacheCorruptionRate float64 `mapstructure:"fault-cache-corruption-rate"`
+	FaultCacheConflictRate   float64 `mapstructure:"fault-cache-conflict-rate"`
+	// AWSEC2Endpoint overrides the EC2 endpoint (e.g. a VPC interface endpoint).
+	// Empty falls back to AWS_ENDPOINT_URL_EC2, then AWS_ENDPOINT_URL.
+	AWSEC2Endpoint string `mapstructure:"aws-ec2-endpoint"`
+	// AWSAssumeRoleARN is a role (typically in another account) assumed for EC2
+	// calls. Empty uses the pod's own credentials.
+	AWSAssumeRoleARN        string `mapstructure:"aws-assume-role-arn"`
+	AWSAssumeRoleExternalID string `mapstructure:"aws-assume-role-external-id"`
+	// AWSProfile selects a named shared config profile for the tagging client.
+	// Empty uses AWS_PROFILE or the default credential chain.
+	AWSProfile string `mapstructure:"aws-profile"`
+	// AWSTagBackend is the API that changes tags: "ec2" (default) for CreateTags
+	// and DeleteTags, or "resourcegroupstaggingapi" for TagResources and
+	// UntagResources. Tags are read with EC2 either way.
+	AWSTagBackend string `mapstructure:"aws-tag-backend"`
+	// AWSHealthProfile and AWSHealthRoleARN give the AWS health checker its own
+	// credentials (e.g. a read-only role). When both are empty it shares the
+	// tagging client's credentials. The role is assumed from the health profile,
+	// or from AWSProfile when no health profile is set.
+	AWSHealthProfile string `mapstructure:"aws-health-profile"`
+	AWSHealthRoleARN string `mapstructure:"aws-health-role-arn"`
+	// AWSSessionTags tags assumed-role sessions with the cluster, namespace and
+	// pod behind each call so the target account's CloudTrail shows the workload.
+	AWSSessionTags bool `mapstructure:"aws-session-tags"`
+	// ClusterName is the cluster session tag value, also used in the managed-by tag.
+	ClusterName string `mapstructure:"cluster-name"`
+	// TagKeyCaseConflict decides what happens to tag keys that differ only by case,
+	// which EC2 stores as separate tags: "allow" (default) applies them as given,
+	// "reject" refuses them and "normalize" merges them into one spelling.
+	TagKeyCaseConflict string `mapstructure:"tag-key-case-conflict"`
+	// TagValueTemplates decides whether annotation tag values are rendered as Go
+	// templates: "none" (default), "pod" (pod fields and node name) or "node"
+	// (also node labels, which needs read access to nodes).
+	TagValueTemplates string `mapstructure:"tag-value-templates"`
+	// EnableServiceTagging runs a second controller that tags the ENIs of the
+	// NLBs and CLBs of annotated type LoadBalancer Services.
+	EnableServiceTagging bool `mapstructure:"enable-service-tagging"`
+	// EnableAdmissionWebhook serves a validating webhook on WebhookPort, with the
+	// tls.crt and tls.key in WebhookCertDir, that denies pods with invalid tag
+	// annotations at admission.
+	EnableAdmissionWebhook bool   `mapstructure:"enable-admission-webhook"`
+	WebhookPort            int    `mapstructure:"webhook-port"`
+	WebhookCertDir         string `mapstructure:"webhook-cert-dir"`
+	// DefaultTags, in either tag annotation format, are added by a mutating webhook
+	// to the tag annotation of new pods matching DefaultTagsSelector (all pods when
+	// empty). DefaultTagsConfigMap ("name" or "namespace/name") overrides them
+	// from its "tags" and "selector" keys. They need EnableAdmissionWebhook.
+	DefaultTags          string `mapstructure:"default-tags"`
+	DefaultTagsSelector  string `mapstructure:"default-tags-selector"`
+	DefaultTagsConfigMap string `mapstructure:"default-tags-configmap"`
+	// DefaultTagsCanaryPercent of new pods get the "canaryTags" of the
+	// DefaultTagsConfigMap instead of its "tags", until DefaultTagsCanaryPods of
+	// them are tagged and it is promoted, or more than
+	// DefaultTagsCanaryMaxFailureRatio of that many fail and it is rolled back.
+	DefaultTagsCanaryPercent         int     `mapstructure:"default-tags-canary-percent"`
+	DefaultTagsCanaryPods            int     `mapstructure:"default-tags-canary-pods"`
+	DefaultTagsCanaryMaxFailureRatio float64 `mapstructure:"default-tags-canary-max-failure-ratio"`
+	// TagDiffSource is what desired tags are diffed against: "annotation" (default)
+	// uses the last-applied pod annotation, "eni" uses the tags on the ENI so
+	// out-of-band edits and lost annotations are repaired. "eni" bypasses the ENI cache.
+	TagDiffSource string `mapstructure:"tag-diff-source"`
+	// HostNetworkENI is the ENI tagged for hostNetwork pods: "pod-ip" (default)
+	// looks it up by the pod IP like for other pods, "primary-eni" tags the primary
+	// ENI of the node's instance, found from the Node's provider ID.
+	HostNetworkENI string `mapstructure:"host-network-eni"`
+	// TagSecurityGroups also applies each pod's tags to the security groups of its
+	// ENI, for per-team security group cost attribution. Groups are owned through
+	// their own hash tag.
+	TagSecurityGroups bool `mapstructure:"tag-security-groups"`
+	// TagNodeVolumes also applies each pod's tags to the EBS volumes of the
+	// instance running it, for storage cost attribution. Volumes are owned through
+	// their own hash tag.
+	TagNodeVolumes bool `mapstructure:"tag-node-volumes"`
+	// TagHeadroomWarning emits a TagQuotaLow event when fewer tags than this can
+	// still be added to a pod's ENI before the limit of 50. 0 disables it.
+	TagHeadroomWarning int `mapstructure:"tag-headroom-warning"`
+	// InvalidTagsPolicy decides what happens to previously applied tags when a pod's
+	// annotation is edited into an invalid state: "keep" (default) leaves them,
+	// "rollback" restores them on the ENI and "remove" deletes them.
+	InvalidTagsPolicy string `mapstructure:"invalid-tags-policy"`
+	// TagHistorySize is how many applied tag sets, with timestamps, are kept in a pod
+	// annotation so past changes can be looked up on the pod. 0 disables the history.
+	TagHistorySize int `mapstructure:"tag-history-size"`
+	// ReadOnly audits pods instead of tagging them: no AWS call changes
+	// anything and pods get no finalizer, annotations or condition, while the
+	// tag changes each ENI needs are exported as metrics.
+	ReadOnly bool `mapstructure:"read-only"`
+	// StateStore is where last-applied state is kept: "annotations" (default) on
+	// the pod, with a finalizer for cleanup, or "configmap" in the controller's own
+	// ConfigMap so the controller needs no update or patch permission on pods.
+	StateStore string `mapstructure:"state-store"`
+	// MaintenanceWindows are comma-separated "HH:MM-HH:MM" UTC ranges outside which
+	// drift repair on already tagged ENIs is deferred. Empty never defers.
+	MaintenanceWindows string `mapstructure:"maintenance-windows"`
+	// StartupRepairWindow is how long after startup last-applied and hash annotations
+	// that disagree with the ENI are rebuilt from its tags instead of being reported
+	// as hash conflicts (e.g. after restoring pods from backup). 0 disables repair.
+	StartupRepairWindow time.Duration `mapstructure:"startup-repair-window"`
+	// ResyncInterval is how often the leader compares the tags on the ENIs of all
+	// tagged pods with their last applied tags and repairs drift. 0 disables it.
+	ResyncInterval time.Duration `mapstructure:"resync-interval"`
+	// SubnetConfigMap names a ConfigMap ("name" in the controller's namespace, or
+	// "namespace/name") whose "subnet-ids" key extends SubnetIDs. It is watched, so
+	// the allow-list changes without a restart. Empty disables it.
+	SubnetConfigMap string `mapstructure:"subnet-configmap"`
+	// PauseConfigMap names a ConfigMap ("name" or "namespace/name") whose "paused"
+	// key pauses all ENI tag changes while "true". It is watched by every replica.
+	// Empty disables it; the admin endpoint can still pause tagging.
+	PauseConfigMap string `mapstructure:"pause-configmap"`
+	// PauseDeletionHold is how long a deleted pod keeps its finalizer while tagging
+	// is paused, waiting for resume to clean up its tags. It is then released with
+	// its tags left on the ENI. 0 releases deleted pods at once while paused.
+	PauseDeletionHold time.Duration `mapstructure:"pause-deletion-hold"`
+	// StandbyCacheRefreshInterval is how often replicas that are not the leader
+	// reload the ENI cache from its ConfigMap, so a failover starts with a warm
+	// cache. It applies with leader election and the cache ConfigMap; 0 disables it.
+	StandbyCacheRefreshInterval time.Duration `mapstructure:"standby-cache-refresh-interval"`
+	// TagBurstDelay is how long a CreateTags call for a shared ENI waits for calls
+	// from other pods on the same ENI, so they are sent as one (e.g. when a node
+	// joins). 0 disables merging.
+	TagBurstDelay time.Duration `mapstructure:"tag-burst-delay"`
+	// PodWriteCollapseWindow is how long annotation and status writes to a pod are
+	// held, so successive writes within it are sent as one patch. 0 writes at once.
+	PodWriteCollapseWindow time.Duration `mapstructure:"pod-write-collapse-window"`
+	// ENIAttachmentRequeueDelay, when positive, holds off tagging ENIs that are not
+	// yet attached and in use, retrying after this delay. 0 tags them regardless.
+	ENIAttachmentRequeueDelay time.Duration `mapstructure:"eni-attachment-requeue-delay"`
+	// OwnershipReportS3Bucket, when set, receives a CSV report of which pod put
+	// which tags on which ENI every OwnershipReportInterval, written by the leader
+	// under OwnershipReportS3Prefix with the tagging client's credentials.
+	OwnershipReportS3Bucket string        `mapstructure:"ownership-report-s3-bucket"`
+	OwnershipReportS3Prefix string        `mapstructure:"ownership-report-s3-prefix"`
+	OwnershipReportInterval time.Duration `mapstructure:"ownership-report-interval"`
+	// TagSourceWebhookURL, when set, is POSTed each annotated pod's metadata and
+	// answers with tags to merge under the annotation's. Answers are cached per pod
+	// for TagSourceWebhookCacheTTL; each call times out after TagSourceWebhookTimeout.
+	TagSourceWebhookURL      string        `mapstructure:"tag-source-webhook-url"`
+	TagSourceWebhookTimeout  time.Duration `mapstructure:"tag-source-webhook-timeout"`
+	TagSourceWebhookCacheTTL time.Duration `mapstructure:"tag-source-webhook-cache-ttl"`
+	// AuxServer* bound the health probe, pprof and admin listeners: how long a
+	// client may take to send headers and the whole request, how long idle
+	// keep-alive connections stay open, the header size limit, and how long
+	// in-flight requests may take to finish on shutdown.
+	AuxServerReadHeaderTimeout time.Duration `mapstructure:"aux-server-read-header-timeout"`
+	AuxServerReadTimeout       time.Duration `mapstructure:"aux-server-read-timeout"`
+	AuxServerIdleTimeout       time.Duration `mapstructure:"aux-server-idle-timeout"`
+	AuxServerMaxHeaderBytes    int           `mapstructure:"aux-server-max-header-bytes"`
+	AuxServerShutdownTimeout   time.Duration `mapstructure:"aux-server-shutdown-timeout"`
 }
 
 // Load parses flags and environment variables to create a Config
@@ -50,7 +374,7 @@ func Load() (*Config, error) {
 	v := viper.New()
 
 	// Set environment variable prefix and automatic env binding
-	v.SetEnvPrefix("ENI_TAGGER")
+	v.SetEnvPrefix(envPrefix)
 	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 	v.AutomaticEnv()
 
@@ -75,7 +399,7 @@ func Load() (*Config, error) {
 			trimmed := strings.TrimSpace(p)
 			if trimmed != "" {
 				if !strings.HasPrefix(trimmed, "subnet-") {
-					return nil, fmt.Errorf("invalid subnet ID format: %s", trimmed)
+					return nil, invalidValue(v, "subnet-ids", fmt.Errorf("invalid subnet ID format: %s", trimmed))
 				}
 				cfg.SubnetIDs = append(cfg.SubnetIDs, trimmed)
 			}
@@ -83,12 +407,20 @@ func Load() (*Config, error) {
 	}
 
 	// Unmarshal config
+	if err := checkEnvTypes(v); err != nil {
+		return nil, err
+	}
 	if err := v.Unmarshal(cfg); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
 	// Early return for version flag
 	if cfg.PrintVersion {
+		switch cfg.VersionFormat {
+		case VersionFormatText, VersionFormatJSON:
+		default:
+			return nil, invalidValue(v, "version-format", fmt.Errorf("must be %q or %q", VersionFormatText, VersionFormatJSON))
+		}
 		return cfg, nil
 	}
 
@@ -96,20 +428,31 @@ func Load() (*Config, error) {
 	var err error
 	cfg.MetricsBindAddress, err = normalizeBindAddress(cfg.MetricsBindAddress)
 	if err != nil {
-		return nil, fmt.Errorf("invalid metrics bind address: %w", err)
+		return nil, invalidValue(v, "metrics-bind-address", err)
 	}
 	cfg.HealthProbeBindAddress, err = normalizeBindAddress(cfg.HealthProbeBindAddress)
 	if err != nil {
-		return nil, fmt.Errorf("invalid health probe bind address: %w", err)
+		return nil, invalidValue(v, "health-probe-bind-address", err)
 	}
 	cfg.PprofBindAddress, err = normalizeBindAddress(cfg.PprofBindAddress)
 	if err != nil {
-		return nil, fmt.Errorf("invalid pprof bind address: %w", err)
+		return nil, invalidValue(v, "pprof-bind-address", err)
+	}
+	cfg.AdminBindAddress, err = normalizeBindAddress(cfg.AdminBindAddress)
+	if err != nil {
+		return nil, invalidValue(v, "admin-bind-address", err)
+	}
+	cfg.APIBindAddress, err = normalizeBindAddress(cfg.APIBindAddress)
+	if err != nil {
+		return nil, invalidValue(v, "api-bind-address", err)
+	}
+	if cfg.ReconcileHistorySize < 0 {
+		return nil, invalidValue(v, "reconcile-history-size", errors.New("must not be negative"))
 	}
 
 	// Validate annotation key
 	if cfg.AnnotationKey == "" {
-		return nil, fmt.Errorf("annotation-key cannot be empty")
+		return nil, invalidValue(v, "annotation-key", errors.New("cannot be empty"))
 	}
 
 	// Validate tag namespace
@@ -120,28 +463,306 @@ func Load() (*Config, error) {
 
 	// Validate rate limiting configuration
 	if cfg.PodRateLimitQPS < 0 {
-		return nil, fmt.Errorf("pod-rate-limit-qps cannot be negative: %f", cfg.PodRateLimitQPS)
+		return nil, invalidValue(v, "pod-rate-limit-qps", errors.New("cannot be negative"))
 	}
 	if cfg.PodRateLimitQPS > 0 && cfg.PodRateLimitBurst < 1 {
-		return nil, fmt.Errorf("pod-rate-limit-burst must be at least 1 when rate limiting enabled (got %d)", cfg.PodRateLimitBurst)
+		return nil, invalidValue(v, "pod-rate-limit-burst", errors.New("must be at least 1 when rate limiting is enabled"))
 	}
 	if cfg.RateLimiterCleanupInterval < 0 {
-		return nil, fmt.Errorf("rate-limiter-cleanup-interval cannot be negative: %v", cfg.RateLimiterCleanupInterval)
+		return nil, invalidValue(v, "rate-limiter-cleanup-interval", errors.New("cannot be negative"))
+	}
+	if cfg.PodRateLimitersMax < 0 {
+		return nil, invalidValue(v, "pod-rate-limiters-max", errors.New("cannot be negative"))
 	}
 	if cfg.AWSRateLimitQPS <= 0 {
-		return nil, fmt.Errorf("aws-rate-limit-qps must be positive: %f", cfg.AWSRateLimitQPS)
+		return nil, invalidValue(v, "aws-rate-limit-qps", errors.New("must be positive"))
 	}
 	if cfg.AWSRateLimitBurst < 1 {
-		return nil, fmt.Errorf("aws-rate-limit-burst must be at least 1: %d", cfg.AWSRateLimitBurst)
+		return nil, invalidValue(v, "aws-rate-limit-burst", errors.New("must be at least 1"))
+	}
+	cfg.AWSNamespaceBudgets, err = parseNamespaceBudgets(v.GetString("aws-namespace-budgets"))
+	if err != nil {
+		return nil, invalidValue(v, "aws-namespace-budgets", err)
+	}
+	if cfg.TagHeadroomWarning < 0 || cfg.TagHeadroomWarning > 50 {
+		return nil, invalidValue(v, "tag-headroom-warning", errors.New("must be between 0 and 50"))
+	}
+	if cfg.AWSMutationBudget < 0 {
+		return nil, invalidValue(v, "aws-mutation-budget", errors.New("cannot be negative"))
+	}
+	if cfg.AWSMutationBudget > 0 && cfg.AWSMutationBudgetWindow < time.Minute {
+		return nil, invalidValue(v, "aws-mutation-budget-window", errors.New("must be at least 1m"))
+	}
+	cfg.TagKeyRenames, err = parseTagKeyRenames(v.GetString("tag-key-renames"))
+	if err != nil {
+		return nil, invalidValue(v, "tag-key-renames", err)
+	}
+	cfg.TagFromLabels, err = parseTagFromLabels(v.GetString("tag-from-labels"))
+	if err != nil {
+		return nil, invalidValue(v, "tag-from-labels", err)
+	}
+	cfg.CriticalTagKeys, err = parseCriticalTagKeys(v.GetString("critical-tag-keys"))
+	if err != nil {
+		return nil, invalidValue(v, "critical-tag-keys", err)
+	}
+	// Validate reconcile concurrency
+	if cfg.EnableAdmissionWebhook {
+		if cfg.WebhookPort < 1 || cfg.WebhookPort > 65535 {
+			return nil, invalidValue(v, "webhook-port", errors.New("must be between 1 and 65535"))
+		}
+		if cfg.WebhookCertDir == "" {
+			return nil, invalidValue(v, "webhook-cert-dir", errors.New("is required with --enable-admission-webhook"))
+		}
+	} else {
+		for _, opt := range []struct{ key, value string }{
+			{"default-tags", cfg.DefaultTags},
+			{"default-tags-selector", cfg.DefaultTagsSelector},
+			{"default-tags-configmap", cfg.DefaultTagsConfigMap},
+		} {
+			if opt.value != "" {
+				return nil, invalidValue(v, opt.key, errors.New("requires --enable-admission-webhook"))
+			}
+		}
+	}
+	if _, err := labels.Parse(cfg.DefaultTagsSelector); err != nil {
+		return nil, invalidValue(v, "default-tags-selector", err)
+	}
+	if cfg.DefaultTagsCanaryPercent < 1 || cfg.DefaultTagsCanaryPercent > 100 {
+		return nil, invalidValue(v, "default-tags-canary-percent", errors.New("must be between 1 and 100"))
+	}
+	if cfg.DefaultTagsCanaryPods < 1 {
+		return nil, invalidValue(v, "default-tags-canary-pods", errors.New("must be at least 1"))
+	}
+	if cfg.DefaultTagsCanaryMaxFailureRatio < 0 || cfg.DefaultTagsCanaryMaxFailureRatio >= 1 {
+		return nil, invalidValue(v, "default-tags-canary-max-failure-ratio", errors.New("must be at least 0 and below 1"))
+	}
+	if cfg.MaxConcurrentReconciles < 1 {
+		return nil, invalidValue(v, "max-concurrent-reconciles", errors.New("must be at least 1"))
+	}
+	if cfg.MaxConcurrentReconcilesCeiling == 0 {
+		cfg.MaxConcurrentReconcilesCeiling = cfg.MaxConcurrentReconciles
+	}
+	if cfg.MaxConcurrentReconcilesCeiling < cfg.MaxConcurrentReconciles {
+		return nil, invalidValue(v, "max-concurrent-reconciles-ceiling", fmt.Errorf("cannot be lower than max-concurrent-reconciles (%d)", cfg.MaxConcurrentReconciles))
 	}
-	// Validate AWS health check latch threshold
-	if cfg.AWSHealthMaxSuccesses < 0 {
-		return nil, fmt.Errorf("aws-health-max-successes cannot be negative (got %d). Set to 0 to disable latching, or a positive value to enable", cfg.AWSHealthMaxSuccesses)
+	if !cfg.VerifyTaggingPermissions {
+		cfg.VerifyPermissions = false
+	}
+	if cfg.AWSAssumeRoleExternalID != "" && cfg.AWSAssumeRoleARN == "" {
+		return nil, invalidValue(v, "aws-assume-role-external-id", errors.New("requires aws-assume-role-arn"))
+	}
+
+	// Validate AWS health check interval
+	if cfg.AWSHealthCheckInterval <= 0 {
+		return nil, invalidValue(v, "aws-health-check-interval", errors.New("must be positive"))
+	}
+	// Validate AWS health probe placement
+	switch cfg.AWSHealthProbe {
+	case AWSHealthProbeReadyz, AWSHealthProbeHealthz, AWSHealthProbeNone:
+	default:
+		return nil, invalidValue(v, "aws-health-probe", fmt.Errorf("must be one of %q, %q, %q", AWSHealthProbeReadyz, AWSHealthProbeHealthz, AWSHealthProbeNone))
+	}
+	switch cfg.AWSTagBackend {
+	case AWSTagBackendEC2, AWSTagBackendResourceGroupsTagging:
+	default:
+		return nil, invalidValue(v, "aws-tag-backend", fmt.Errorf("must be one of %q, %q", AWSTagBackendEC2, AWSTagBackendResourceGroupsTagging))
+	}
+	switch cfg.TagKeyCaseConflict {
+	case TagKeyCaseConflictAllow, TagKeyCaseConflictReject, TagKeyCaseConflictNormalize:
+	default:
+		return nil, invalidValue(v, "tag-key-case-conflict", fmt.Errorf("must be one of %q, %q, %q", TagKeyCaseConflictAllow, TagKeyCaseConflictReject, TagKeyCaseConflictNormalize))
+	}
+	switch cfg.TagValueTemplates {
+	case TagValueTemplatesNone, TagValueTemplatesPod, TagValueTemplatesNode:
+	default:
+		return nil, invalidValue(v, "tag-value-templates", fmt.Errorf("must be one of %q, %q, %q", TagValueTemplatesNone, TagValueTemplatesPod, TagValueTemplatesNode))
+	}
+	// Validate the ConfigMap references: "name" or "namespace/name"
+	for _, ref := range []struct{ key, value string }{
+		{"subnet-configmap", cfg.SubnetConfigMap},
+		{"pause-configmap", cfg.PauseConfigMap},
+		{"default-tags-configmap", cfg.DefaultTagsConfigMap},
+	} {
+		if ref.value == "" {
+			continue
+		}
+		for _, part := range strings.SplitN(ref.value, "/", 2) {
+			if errs := validation.IsDNS1123Subdomain(part); len(errs) > 0 {
+				return nil, invalidValue(v, ref.key, errors.New(strings.Join(errs, "; ")))
+			}
+		}
+	}
+	for _, timeout := range []struct {
+		key string
+		d   time.Duration
+	}{
+		{"aux-server-read-header-timeout", cfg.AuxServerReadHeaderTimeout},
+		{"aux-server-read-timeout", cfg.AuxServerReadTimeout},
+		{"aux-server-idle-timeout", cfg.AuxServerIdleTimeout},
+		{"aux-server-shutdown-timeout", cfg.AuxServerShutdownTimeout},
+	} {
+		if timeout.d <= 0 {
+			return nil, invalidValue(v, timeout.key, errors.New("must be positive"))
+		}
+	}
+	if cfg.AuxServerMaxHeaderBytes < 1024 {
+		return nil, invalidValue(v, "aux-server-max-header-bytes", errors.New("must be at least 1024"))
+	}
+	if cfg.ENICacheResyncInterval < 0 {
+		return nil, invalidValue(v, "eni-cache-resync-interval", errors.New("cannot be negative"))
+	}
+	if cfg.StandbyCacheRefreshInterval < 0 {
+		return nil, invalidValue(v, "standby-cache-refresh-interval", errors.New("cannot be negative"))
+	}
+	if cfg.TagBurstDelay < 0 || cfg.TagBurstDelay > maxTagBurstDelay {
+		return nil, invalidValue(v, "tag-burst-delay", fmt.Errorf("must be between 0 and %s", maxTagBurstDelay))
+	}
+	// Merged calls run in one pod's session, which would misattribute the others
+	if cfg.TagBurstDelay > 0 && cfg.AWSAssumeRoleARN != "" && cfg.AWSSessionTags {
+		return nil, invalidValue(v, "tag-burst-delay", errors.New("cannot be used with per-pod session tags (aws-assume-role-arn with aws-session-tags)"))
+	}
+	if cfg.PodWriteCollapseWindow < 0 || cfg.PodWriteCollapseWindow > maxPodWriteCollapseWindow {
+		return nil, invalidValue(v, "pod-write-collapse-window", fmt.Errorf("must be between 0 and %s", maxPodWriteCollapseWindow))
+	}
+	if cfg.OwnershipReportS3Bucket != "" {
+		if l := len(cfg.OwnershipReportS3Bucket); l < 3 || l > 63 {
+			return nil, invalidValue(v, "ownership-report-s3-bucket", errors.New("must be 3 to 63 characters long"))
+		}
+		if errs := validation.IsDNS1123Subdomain(cfg.OwnershipReportS3Bucket); len(errs) > 0 {
+			return nil, invalidValue(v, "ownership-report-s3-bucket", errors.New(strings.Join(errs, "; ")))
+		}
+		if cfg.OwnershipReportInterval < time.Minute {
+			return nil, invalidValue(v, "ownership-report-interval", errors.New("must be at least 1m"))
+		}
+	}
+	if cfg.TagSourceWebhookURL != "" {
+		if u, err := url.Parse(cfg.TagSourceWebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, invalidValue(v, "tag-source-webhook-url", errors.New("must be an http or https URL"))
+		}
+		if cfg.TagSourceWebhookTimeout <= 0 {
+			return nil, invalidValue(v, "tag-source-webhook-timeout", errors.New("must be positive"))
+		}
+		if cfg.TagSourceWebhookCacheTTL < 0 {
+			return nil, invalidValue(v, "tag-source-webhook-cache-ttl", errors.New("cannot be negative"))
+		}
+	}
+	if cfg.ENIAttachmentRequeueDelay < 0 {
+		return nil, invalidValue(v, "eni-attachment-requeue-delay", errors.New("cannot be negative"))
+	}
+	if cfg.StartupRepairWindow < 0 {
+		return nil, invalidValue(v, "startup-repair-window", errors.New("cannot be negative"))
+	}
+	for key, rate := range map[string]float64{
+		"fault-aws-error-rate":        cfg.FaultAWSErrorRate,
+		"fault-cache-corruption-rate": cfg.FaultCacheCorruptionRate,
+		"fault-cache-conflict-rate":   cfg.FaultCacheConflictRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return nil, invalidValue(v, key, errors.New("must be between 0 and 1"))
+		}
+		if rate > 0 && !faultInjectionAvailable {
+			return nil, invalidValue(v, key, errors.New("needs a binary built with -tags faultinject"))
+		}
+	}
+	if cfg.ResyncInterval < 0 {
+		return nil, invalidValue(v, "resync-interval", errors.New("cannot be negative"))
+	}
+	if cfg.PauseDeletionHold < 0 {
+		return nil, invalidValue(v, "pause-deletion-hold", errors.New("cannot be negative"))
+	}
+	switch cfg.TagDiffSource {
+	case TagDiffSourceAnnotation, TagDiffSourceENI:
+	default:
+		return nil, invalidValue(v, "tag-diff-source", fmt.Errorf("must be %q or %q", TagDiffSourceAnnotation, TagDiffSourceENI))
+	}
+	switch cfg.HostNetworkENI {
+	case HostNetworkENIPodIP, HostNetworkENIPrimary:
+	default:
+		return nil, invalidValue(v, "host-network-eni", fmt.Errorf("must be %q or %q", HostNetworkENIPodIP, HostNetworkENIPrimary))
+	}
+	switch cfg.InvalidTagsPolicy {
+	case InvalidTagsPolicyKeep, InvalidTagsPolicyRollback, InvalidTagsPolicyRemove:
+	default:
+		return nil, invalidValue(v, "invalid-tags-policy", fmt.Errorf("must be %q, %q or %q", InvalidTagsPolicyKeep, InvalidTagsPolicyRollback, InvalidTagsPolicyRemove))
+	}
+	if cfg.TagHistorySize < 0 || cfg.TagHistorySize > MaxTagHistorySize {
+		return nil, invalidValue(v, "tag-history-size", fmt.Errorf("must be between 0 and %d", MaxTagHistorySize))
+	}
+	switch cfg.StateStore {
+	case StateStoreAnnotations, StateStoreConfigMap:
+	default:
+		return nil, invalidValue(v, "state-store", fmt.Errorf("must be %q or %q", StateStoreAnnotations, StateStoreConfigMap))
+	}
+	if cfg.StateStore == StateStoreConfigMap && cfg.TagHistorySize > 0 {
+		return nil, invalidValue(v, "tag-history-size", fmt.Errorf("tag history is kept in a pod annotation and cannot be used with --state-store=%s", StateStoreConfigMap))
+	}
+	// Read-only mode writes nothing; features that only exist to write are refused
+	if cfg.ReadOnly {
+		if cfg.StateStore == StateStoreConfigMap {
+			return nil, invalidValue(v, "state-store", fmt.Errorf("--state-store=%s writes a ConfigMap and cannot be used with --read-only", StateStoreConfigMap))
+		}
+		if cfg.OwnershipReportS3Bucket != "" {
+			return nil, invalidValue(v, "ownership-report-s3-bucket", errors.New("writes to S3 and cannot be used with --read-only"))
+		}
+	}
+	// Validate exclusion selector syntax early so a typo fails startup instead of silently matching nothing
+	if _, err := labels.Parse(cfg.ExcludePodSelector); err != nil {
+		return nil, invalidValue(v, "exclude-pod-selector", err)
+	}
+	switch cfg.DebugPods {
+	case DebugPodsSkip, DebugPodsTag:
+	default:
+		return nil, invalidValue(v, "debug-pods", fmt.Errorf("must be %q or %q", DebugPodsSkip, DebugPodsTag))
+	}
+	// Validate key domain: it becomes the prefix of finalizer, condition and annotation names
+	if errs := validation.IsDNS1123Subdomain(cfg.KeyDomain); len(errs) > 0 {
+		return nil, invalidValue(v, "key-domain", errors.New(strings.Join(errs, "; ")))
 	}
 
 	return cfg, nil
 }
 
+// EnabledFeatures returns the flags of the optional features switched on in c,
+// in a fixed order, e.g. for the --version output. It only reads settings that
+// are set before Load returns early for --version.
+func (c *Config) EnabledFeatures() []string {
+	features := []struct {
+		flag    string
+		enabled bool
+	}{
+		{"leader-elect", c.EnableLeaderElection},
+		{"dry-run", c.DryRun},
+		{"read-only", c.ReadOnly},
+		{"audit-log-path", c.AuditLogPath != ""},
+		{"reconcile-history-size", c.ReconcileHistorySize > 0},
+		{"allow-shared-eni-tagging", c.AllowSharedENITagging},
+		{"enable-eni-cache", c.EnableENICache},
+		{"enable-cache-configmap", c.EnableCacheConfigMap},
+		{"eni-cache-ip-check", c.ENICacheIPCheck},
+		{"eni-cache-warmup", c.ENICacheWarmup},
+		{"aws-mutation-budget", c.AWSMutationBudget > 0},
+		{"aws-assume-role-arn", c.AWSAssumeRoleARN != ""},
+		{"aws-session-tags", c.AWSSessionTags},
+		{"aws-tag-backend", c.AWSTagBackend != AWSTagBackendEC2},
+		{"namespace-fair-queuing", c.NamespaceFairQueuing},
+		{"managed-by-tag", c.ManagedByTag},
+		{"controller-id", c.ControllerID != ""},
+		{"tag-security-groups", c.TagSecurityGroups},
+		{"tag-node-volumes", c.TagNodeVolumes},
+		{"enable-service-tagging", c.EnableServiceTagging},
+		{"enable-admission-webhook", c.EnableAdmissionWebhook},
+		{"default-tags", c.DefaultTags != "" || c.DefaultTagsConfigMap != ""},
+		{"tag-source-webhook-url", c.TagSourceWebhookURL != ""},
+	}
+	var enabled []string
+	for _, f := range features {
+		if f.enabled {
+			enabled = append(enabled, f.flag)
+		}
+	}
+	return enabled
+}
+
 func defineFlags(v *viper.Viper) {
 	pflag.String("metrics-bind-address", "8090", "Port (or address) the metrics endpoint binds to. Use plain port (e.g., 8090) or address:port (e.g., 0.0.0.0:8090).")
 	pflag.String("health-probe-bind-address", "8081", "Port (or address) the health probe endpoint binds to. Use plain port (e.g., 8081) or address:port.")
@@ -150,24 +771,78 @@ func defineFlags(v *viper.Viper) {
 			"Enabling this will ensure there is only one active controller manager.")
 	pflag.String("annotation-key", "eni-tagger.io/tags", "The annotation key to watch for tags.")
 	pflag.Int("max-concurrent-reconciles", 1, "Maximum number of concurrent reconciles.")
+	pflag.Int("max-concurrent-reconciles-ceiling", 0, "Number of reconcile workers started; concurrency can be raised at runtime up to this value via the admin endpoint. 0 means max-concurrent-reconciles.")
+	pflag.Bool("namespace-fair-queuing", false, "Queue pod events per namespace and hand them to the workers round-robin, so a namespace creating many pods cannot delay the others.")
+	pflag.Bool("trigger-audit", false, "Log why each reconcile was triggered and count pod events by trigger and filter reason.")
+	pflag.Bool("metrics-exemplars", false, "Attach the reconcile ID to AWS API latency observations as an exemplar, served in the OpenMetrics format on /metrics/openmetrics.")
 	pflag.Bool("dry-run", false, "Enable dry-run mode (no AWS changes).")
+	pflag.Bool("read-only", false, "Audit instead of tagging: make no AWS changes and no pod writes (finalizers, annotations, conditions), and export the tag changes each ENI needs as metrics. Pods are audited again every --resync-interval.")
+	pflag.String("audit-log-path", "", "File to append a JSON audit record of every ENI tag change to (pod, ENI, tags, result, dry-run), or '-' for standard output. Empty disables the audit log.")
 	pflag.String("watch-namespace", "", "Namespace to watch for Pods. If empty, watches all namespaces.")
 	pflag.Bool("version", false, "Print version information and exit.")
+	pflag.String("version-format", VersionFormatText, "Format of --version: 'text' prints one line, 'json' also lists the Go and dependency versions and the features enabled by the other flags.")
 	pflag.String("subnet-ids", "", "Comma-separated list of allowed Subnet IDs. If empty, all subnets are allowed (subject to safety checks). Can also be set via ENI_TAGGER_SUBNET_IDS env var.")
+	pflag.String("subnet-configmap", "", "ConfigMap ('name' in the controller namespace, or 'namespace/name') whose 'subnet-ids' key adds allowed Subnet IDs. Watched for changes, so no restart is needed.")
+	pflag.String("pause-configmap", "", "ConfigMap ('name' in the controller namespace, or 'namespace/name') whose 'paused' key pauses all ENI tag changes while 'true'. Watched for changes by every replica.")
+	pflag.Duration("pause-deletion-hold", 10*time.Minute, "How long deleted pods stay Terminating while tagging is paused, so their tags are removed on resume. Pods held longer are released with their tags left on the ENI. 0 releases them at once.")
 	pflag.Bool("allow-shared-eni-tagging", false, "Allow tagging of shared ENIs (e.g. standard EKS nodes). WARNING: This can cause tag thrashing.")
+	pflag.Duration("pod-write-collapse-window", 0, "How long pod annotation and status writes are held so successive writes to the same pod are sent as one patch (e.g. 1s). Reduces API server writes when many pods are reconciled per second; annotations and conditions lag by up to the window. 0 disables it.")
+	pflag.Duration("tag-burst-delay", 0, "How long CreateTags calls for a shared ENI wait for calls from other pods on it, to send them as one (e.g. 2s). Helps when many pods start on a node at once; needs --max-concurrent-reconciles above 1. 0 disables it.")
+	pflag.Duration("eni-attachment-requeue-delay", 0, "Wait for ENIs to be attached and in use before tagging, retrying after this delay (e.g. 5s), since CreateTags can race with CNI setup. 0 tags regardless of attachment state.")
 
 	// ENI Cache flags
 	pflag.Bool("enable-eni-cache", true, "Enable in-memory ENI caching (cached until pod deletion).")
 	pflag.Bool("enable-cache-configmap", false, "Enable ConfigMap persistence for ENI cache (survives restarts).")
 	pflag.Duration("cache-batch-interval", 2*time.Second, "Batch interval for ConfigMap cache persistence (e.g., 2s).")
 	pflag.Int("cache-batch-size", 20, "Batch size for ConfigMap cache persistence.")
+	pflag.Bool("eni-cache-ip-check", false, "On every ENI cache hit, check that the pod's IP is still on the cached ENI, dropping the entry and looking the ENI up again if it moved. Checks of concurrent hits share one DescribeNetworkInterfaces call.")
+	pflag.Bool("eni-cache-warmup", false, "At startup, look up the ENIs of existing pods to tag with one DescribeNetworkInterfaces call per 200 IPs, before reconciles would look them up one by one.")
+	pflag.Duration("eni-cache-resync-interval", 0, "How often the leader looks up every cached ENI again, 200 IPs per DescribeNetworkInterfaces call, updating changed entries and dropping IPs no longer on an ENI (e.g. 30m). 0 disables it.")
+	pflag.Duration("standby-cache-refresh-interval", time.Minute, "How often non-leader replicas reload the ENI cache from its ConfigMap so failover starts warm. Requires --leader-elect and --enable-cache-configmap. 0 disables it.")
 
 	// Rate limiting flags
 	pflag.Float64("aws-rate-limit-qps", 10, "AWS API rate limit (requests per second).")
 	pflag.Int("aws-rate-limit-burst", 20, "AWS API rate limit burst size.")
+	pflag.String("aws-namespace-budgets", "", "Comma-separated namespace=fraction caps on the AWS rate limit (e.g. 'batch=0.2,*=0.5'); '*' applies to each namespace without its own entry. Empty disables namespace budgets.")
+	pflag.Int("aws-mutation-budget", 0, "EC2 CreateTags and DeleteTags calls allowed per --aws-mutation-budget-window. Once used up, drift repair, owner tag rollouts and changes to best-effort tags are deferred to the next window; critical tags requested by pods are still applied. 0 disables the budget.")
+	pflag.Duration("aws-mutation-budget-window", time.Hour, "Length of the --aws-mutation-budget windows, aligned to UTC (e.g. 1h starts on the hour, 24h at midnight UTC).")
+	pflag.String("aws-ec2-endpoint", "", "EC2 endpoint URL override (e.g. a VPC interface endpoint). Empty uses AWS_ENDPOINT_URL_EC2, then AWS_ENDPOINT_URL, then the regional default.")
+	pflag.String("aws-assume-role-arn", "", "IAM role to assume for EC2 calls, e.g. to tag ENIs in another account. Empty uses the controller's own credentials.")
+	pflag.String("aws-assume-role-external-id", "", "External ID passed when assuming --aws-assume-role-arn.")
+	pflag.String("aws-profile", "", "Named profile from the shared AWS config/credentials files for the tagging client. Empty uses AWS_PROFILE or the default credential chain.")
+	pflag.String("aws-tag-backend", "ec2", "API used to change tags: ec2 (CreateTags/DeleteTags) or resourcegroupstaggingapi (TagResources/UntagResources, up to 20 resources per call). The Tagging API backend needs tag:TagResources and tag:UntagResources in addition to the EC2 tagging permissions, and an AWS region.")
+	pflag.String("aws-health-profile", "", "Named profile for the AWS health checker, so it can use credentials separate from tagging. Empty shares the tagging client's credentials unless --aws-health-role-arn is set.")
+	pflag.String("aws-health-role-arn", "", "IAM role (e.g. read-only) assumed for AWS health checks instead of the tagging credentials.")
+	pflag.Bool("aws-session-tags", true, "Tag assumed-role sessions with the cluster, namespace and pod behind each call (one session per pod). The role trust policy must allow sts:TagSession.")
+	pflag.String("cluster-name", "", "Cluster name used as the kubernetes-cluster session tag and in the managed-by ENI tag.")
+	pflag.Float64("fault-aws-error-rate", 0, "Testing only, in binaries built with -tags faultinject: fraction of EC2 calls failing with an injected throttling or server error.")
+	pflag.Float64("fault-cache-corruption-rate", 0, "Testing only, in binaries built with -tags faultinject: fraction of ENI cache entries written to the cache ConfigMap unreadable.")
+	pflag.Float64("fault-cache-conflict-rate", 0, "Testing only, in binaries built with -tags faultinject: fraction of ENI cache ConfigMap updates failing with a conflict.")
+	for _, name := range []string{"fault-aws-error-rate", "fault-cache-corruption-rate", "fault-cache-conflict-rate"} {
+		_ = pflag.CommandLine.MarkHidden(name)
+	}
+	pflag.Bool("aws-debug-logging", false, "Log every EC2 request (operation, retry attempt, latency, status, request ID, parameters) with credentials redacted. Verbose; meant for diagnosing a single account.")
+
+	// ENI ownership report flags
+	pflag.String("ownership-report-s3-bucket", "", "S3 bucket that receives a periodic CSV report of ENI, pod and tags for FinOps ingestion, written by the leader with the tagging credentials. Empty disables the report.")
+	pflag.String("ownership-report-s3-prefix", "", "Key prefix for ownership reports, e.g. 'eni-tagger/prod/'. Reports are written under <prefix>dt=YYYY-MM-DD/.")
+	pflag.Duration("ownership-report-interval", time.Hour, "How often the ownership report is written (at least 1m).")
+	pflag.String("tag-source-webhook-url", "", "HTTP endpoint POSTed each annotated pod's metadata, answering with tags to merge; annotation tags win on conflicts. Empty disables the external tag source.")
+	pflag.Duration("tag-source-webhook-timeout", 2*time.Second, "Timeout for each tag source webhook call.")
+	pflag.Duration("tag-source-webhook-cache-ttl", 5*time.Minute, "How long a pod's tag source webhook answer is reused (0 calls the webhook on every reconcile).")
 
 	// Pprof flag
 	pflag.String("pprof-bind-address", "0", "The address the pprof endpoint binds to. Set to '0' to disable.")
+	pflag.Duration("aux-server-read-header-timeout", 10*time.Second, "How long clients of the health probe, pprof and admin servers may take to send request headers.")
+	pflag.Duration("aux-server-read-timeout", 30*time.Second, "How long clients of the health probe, pprof and admin servers may take to send a whole request.")
+	pflag.Duration("aux-server-idle-timeout", 90*time.Second, "How long idle keep-alive connections to the health probe, pprof and admin servers stay open.")
+	pflag.Int("aux-server-max-header-bytes", 64<<10, "Maximum request header size accepted by the health probe, pprof and admin servers.")
+	pflag.Duration("aux-server-shutdown-timeout", 10*time.Second, "How long in-flight requests to the health probe, pprof and admin servers may take to finish on shutdown.")
+
+	// Admin endpoint flag
+	pflag.String("admin-bind-address", "0", "The address the unauthenticated admin endpoint (/concurrency, /plan, /pause, /eni-cache) binds to, e.g. 127.0.0.1:8082. Set to '0' to disable.")
+	pflag.Int("reconcile-history-size", 0, "Number of recent pod reconciles, with their outcome and AWS request IDs, kept in memory and served by the API on --api-bind-address. 0 disables the history.")
+	pflag.String("api-bind-address", "0", "The address the unauthenticated read-only API (/api/v1/enis, /api/v1/pods/{namespace}/{name}/tags) binds to, e.g. 127.0.0.1:8083. Set to '0' to disable.")
 
 	// Tag namespace flag
 	pflag.String("tag-namespace", "", "Control automatic pod namespace-based tag namespacing. Set to 'enable' to use the pod's Kubernetes namespace as tag prefix. Any other value (including empty) disables namespacing.")
@@ -176,8 +851,50 @@ func defineFlags(v *viper.Viper) {
 	pflag.Float64("pod-rate-limit-qps", 0.1, "Per-pod reconciliation rate limit (requests per second). Default 0.1 = 1 reconciliation every 10 seconds per pod.")
 	pflag.Int("pod-rate-limit-burst", 1, "Per-pod rate limit burst size (allows brief bursts above QPS).")
 	pflag.Duration("rate-limiter-cleanup-interval", 1*time.Minute, "Interval for cleaning up stale pod rate limiters (e.g., 1m).")
-	// AWS health check latch successes before skipping AWS calls
-	pflag.Int("aws-health-max-successes", 3, "Number of successful AWS health checks before latching and skipping further AWS API calls for probes. Set to 0 to disable latching.")
+	pflag.Bool("pod-rate-limit-condition", false, "Set the RateLimited condition, with the next attempt time, on pods whose reconcile the per-pod rate limit deferred. Costs a pod status write per deferral.")
+	pflag.Int("pod-rate-limiters-max", 50000, "Maximum number of per-pod rate limiters kept between cleanups; the least recently used are evicted past it. 0 means no cap.")
+	// AWS health check runs in the background; probes read the cached result
+	pflag.Duration("aws-health-check-interval", 30*time.Second, "Interval between background AWS connectivity checks (e.g., 30s). Probes serve the cached result.")
+	// Deprecated: the latch was replaced by background checks. Kept so existing deployments still start.
+	pflag.Int("aws-health-max-successes", 3, "Deprecated and ignored.")
+	_ = pflag.CommandLine.MarkDeprecated("aws-health-max-successes", "AWS health checks now run in the background; use --aws-health-check-interval instead")
+	pflag.String("aws-health-probe", AWSHealthProbeReadyz, "Probe the AWS connectivity check is attached to: 'readyz' (AWS outages mark the pod not-ready), 'healthz' (AWS outages restart the pod) or 'none'.")
+	pflag.Bool("verify-permissions", true, "Check at startup that the Kubernetes RBAC permissions and IAM actions used by the enabled features are granted, report all missing ones at once, and fail startup if a required one is missing.")
+	pflag.Bool("verify-tagging-permissions", true, "Deprecated: use --verify-permissions. false disables the startup permission check.")
+	_ = pflag.CommandLine.MarkDeprecated("verify-tagging-permissions", "use --verify-permissions instead")
+	pflag.String("critical-tag-keys", "", "Comma-separated ENI tag keys, or prefixes ending in '*', that must be applied (e.g. 'CostCenter,billing:*'). Failing to apply them sets the TaggingFailed condition; failures affecting only other tags are logged and retried with the condition left True. Empty makes every tag critical.")
+	pflag.String("tag-key-renames", "", "Comma-separated from=to renames applied to annotation tag keys before tagging (e.g. 'team=CostTeam,env=Environment'). Empty disables renaming.")
+	pflag.String("tag-from-labels", "", "Comma-separated pod labels whose values are written to ENI tags, as label or label=TagKey (e.g. 'team,cost-center=CostCenter'). Pods with a listed label are tagged even without the annotation, whose tags win on conflicting keys. Empty disables label tags.")
+	pflag.String("tag-value-templates", TagValueTemplatesNone, "Render annotation tag values containing '{{' as Go templates, e.g. '{{ .Pod.Namespace }}': 'none' uses values as written, 'pod' exposes .Pod (Name, Namespace, UID, ServiceAccountName, Labels) and .Node.Name, 'node' also exposes .Node.Labels and needs read access to nodes.")
+	pflag.Bool("enable-service-tagging", false, "Also tag the ENIs of the Network and Classic Load Balancers of type LoadBalancer Services carrying the tag annotation. Needs read and patch access to Services.")
+	pflag.Bool("enable-admission-webhook", false, "Serve a validating admission webhook that denies pod creates and updates with invalid tag annotations. Needs a ValidatingWebhookConfiguration pointing at it, as the Helm chart creates.")
+	pflag.Int("webhook-port", 9443, "Port the admission webhook listens on.")
+	pflag.String("webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs", "Directory holding the admission webhook's serving certificate as tls.crt and tls.key.")
+	pflag.String("default-tags", "", "Tags, as JSON or key=value pairs, a mutating admission webhook adds to the tag annotation of new pods matching --default-tags-selector. Tags the pod sets win. Requires --enable-admission-webhook and a MutatingWebhookConfiguration, as the Helm chart creates.")
+	pflag.String("default-tags-selector", "", "Label selector (e.g. 'app.kubernetes.io/part-of=shop,tier!=test') of the pods default tags are added to. Empty selects all pods.")
+	pflag.String("default-tags-configmap", "", "ConfigMap ('name' in the controller namespace, or 'namespace/name') whose 'tags' and 'selector' keys override --default-tags and --default-tags-selector. Read on every admission, so no restart is needed.")
+	pflag.Int("default-tags-canary-percent", 10, "Percentage of new pods given the 'canaryTags' of --default-tags-configmap instead of its 'tags', unless its 'canaryPercent' key is set.")
+	pflag.Int("default-tags-canary-pods", 5, "Canary pods whose tags must be applied or refused before the canary default tags are promoted to the 'tags' key.")
+	pflag.Float64("default-tags-canary-max-failure-ratio", 0.2, "Share of --default-tags-canary-pods whose tags may be refused (invalid, AWS error or tag policy violation) before the canary default tags are rolled back.")
+	pflag.String("tag-key-case-conflict", TagKeyCaseConflictAllow, "Handling of tag keys that differ only by case (e.g. 'Team' and 'team'): 'allow' applies both, 'reject' refuses them, 'normalize' merges them into one spelling.")
+	pflag.String("tag-diff-source", TagDiffSourceAnnotation, "State desired tags are diffed against: 'annotation' (last-applied pod annotation) or 'eni' (tags currently on the ENI; repairs out-of-band changes and lost annotations, bypasses the ENI cache).")
+	pflag.Bool("tag-node-volumes", false, "Also apply each pod's tags to the EBS volumes of the EC2 instance running it, and remove them with the last pod on the node with the same tags. Volumes carrying other pods' tags are left untouched. Needs ec2:DescribeInstances, ec2:DescribeVolumes, tagging permissions on volumes and read access to nodes.")
+	pflag.Int("tag-headroom-warning", 5, "Emit a TagQuotaLow Warning event on a pod when fewer than this many tags can still be added to its ENI before the EC2 limit of 50. 0 disables the warning; k8s_eni_tagger_eni_tag_headroom is exported either way.")
+	pflag.Bool("tag-security-groups", false, "Also apply each pod's tags to the security groups attached to its ENI, and remove them with the last pod with the same tags. Pods sharing a security group must have the same tags; groups carrying other pods' tags are left untouched. Needs ec2:DescribeSecurityGroups and tagging permissions on security groups.")
+	pflag.String("host-network-eni", HostNetworkENIPodIP, "ENI tagged for hostNetwork pods: 'pod-ip' looks it up by the pod IP like for other pods, 'primary-eni' tags the primary ENI of the node's instance (found from the Node's provider ID; needs read access to nodes). Host network pods of a node share its tags.")
+	pflag.Duration("startup-repair-window", 0, "For this long after startup, rebuild last-applied and hash annotations that disagree with the ENI from its tags instead of reporting hash conflicts (e.g. 10m after restoring pods from backup). 0 disables repair.")
+	pflag.Duration("resync-interval", 0, "How often the leader re-reads the ENI tags of all tagged pods from AWS, 200 IPs per DescribeNetworkInterfaces call, and repairs tags deleted or changed outside the controller (e.g. 1h). 0 disables it.")
+	pflag.String("invalid-tags-policy", InvalidTagsPolicyKeep, "What happens to previously applied tags when a pod's annotation becomes invalid: 'keep' leaves them, 'rollback' restores them on the ENI (undoing out-of-band edits), 'remove' deletes them as on pod deletion.")
+	pflag.Int("tag-history-size", 0, "Number of applied tag sets (with timestamps) kept in the pod's tag-history annotation, up to 20. 0 disables the history.")
+	pflag.String("state-store", StateStoreAnnotations, "Where last-applied state is kept: 'annotations' (on the pod, with a finalizer for cleanup) or 'configmap' (in the controller's eni-tagger-state ConfigMap, no pod update/patch permission needed; cleanup relies on delete events and a startup sweep).")
+	pflag.String("maintenance-windows", "", "Comma-separated daily UTC windows (e.g. '22:00-06:00') outside which repairs of already applied tags (drift, owner tag rollout) are deferred. Tagging requested by pods is never deferred. Empty disables deferral.")
+	// Pod exclusion selector
+	pflag.String("exclude-pod-selector", "", "Label selector for pods that are never tagged even if annotated (e.g. 'ci-runner=true'). Empty excludes nothing.")
+	pflag.String("debug-pods", DebugPodsSkip, "Tagging of pods created by kubectl debug, which copy the annotations of the pod they debug: 'skip' leaves unowned pods with a 'debugger-*' container and 'node-debugger-*' pods untagged, 'tag' tags them like other pods.")
+	// Bookkeeping key domain
+	pflag.String("controller-id", "", "Identity of this installation (e.g. '<namespace>/<release>'), written to an owner tag on each ENI. ENIs owned by a different ID are not modified. Empty disables owner tracking.")
+	pflag.Bool("managed-by-tag", false, "Write a managed-by=k8s-eni-tagger/<cluster-name> tag to each tagged ENI, alongside the hash tag, so the EC2 console shows which system and cluster own its tags.")
+	pflag.String("key-domain", DefaultKeyDomain, "Domain for the finalizer, pod condition type, ENI hash tag and last-applied annotations. Use a different value per installation to run several controllers in one cluster.")
 }
 
 func setDefaults(v *viper.Viper) {
@@ -186,21 +903,104 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("leader-elect", false)
 	v.SetDefault("annotation-key", "eni-tagger.io/tags")
 	v.SetDefault("max-concurrent-reconciles", 1)
+	v.SetDefault("max-concurrent-reconciles-ceiling", 0)
+	v.SetDefault("namespace-fair-queuing", false)
+	v.SetDefault("trigger-audit", false)
+	v.SetDefault("metrics-exemplars", false)
 	v.SetDefault("dry-run", false)
+	v.SetDefault("read-only", false)
+	v.SetDefault("audit-log-path", "")
 	v.SetDefault("watch-namespace", "")
 	v.SetDefault("version", false)
+	v.SetDefault("version-format", VersionFormatText)
 	v.SetDefault("subnet-ids", "")
+	v.SetDefault("subnet-configmap", "")
+	v.SetDefault("pause-configmap", "")
+	v.SetDefault("pause-deletion-hold", 10*time.Minute)
 	v.SetDefault("allow-shared-eni-tagging", false)
 	v.SetDefault("enable-eni-cache", true)
 	v.SetDefault("enable-cache-configmap", false)
 	v.SetDefault("cache-batch-interval", 2*time.Second)
 	v.SetDefault("cache-batch-size", 20)
+	v.SetDefault("tag-burst-delay", time.Duration(0))
+	v.SetDefault("pod-write-collapse-window", time.Duration(0))
+	v.SetDefault("eni-attachment-requeue-delay", time.Duration(0))
+	v.SetDefault("standby-cache-refresh-interval", time.Minute)
+	v.SetDefault("eni-cache-ip-check", false)
+	v.SetDefault("eni-cache-warmup", false)
+	v.SetDefault("eni-cache-resync-interval", time.Duration(0))
 	v.SetDefault("aws-rate-limit-qps", 10.0)
 	v.SetDefault("aws-rate-limit-burst", 20)
+	v.SetDefault("aws-namespace-budgets", "")
+	v.SetDefault("aws-mutation-budget", 0)
+	v.SetDefault("aws-mutation-budget-window", time.Hour)
+	v.SetDefault("tag-key-renames", "")
+	v.SetDefault("tag-from-labels", "")
+	v.SetDefault("critical-tag-keys", "")
+	v.SetDefault("aws-debug-logging", false)
+	v.SetDefault("fault-aws-error-rate", 0.0)
+	v.SetDefault("fault-cache-corruption-rate", 0.0)
+	v.SetDefault("fault-cache-conflict-rate", 0.0)
+	v.SetDefault("aws-ec2-endpoint", "")
+	v.SetDefault("aws-assume-role-arn", "")
+	v.SetDefault("aws-assume-role-external-id", "")
+	v.SetDefault("aws-profile", "")
+	v.SetDefault("aws-tag-backend", AWSTagBackendEC2)
+	v.SetDefault("aws-health-profile", "")
+	v.SetDefault("aws-health-role-arn", "")
+	v.SetDefault("aws-session-tags", true)
+	v.SetDefault("cluster-name", "")
+	v.SetDefault("ownership-report-s3-bucket", "")
+	v.SetDefault("ownership-report-s3-prefix", "")
+	v.SetDefault("ownership-report-interval", time.Hour)
+	v.SetDefault("tag-source-webhook-url", "")
+	v.SetDefault("tag-source-webhook-timeout", 2*time.Second)
+	v.SetDefault("tag-source-webhook-cache-ttl", 5*time.Minute)
 	v.SetDefault("pprof-bind-address", "0")
+	v.SetDefault("aux-server-read-header-timeout", 10*time.Second)
+	v.SetDefault("aux-server-read-timeout", 30*time.Second)
+	v.SetDefault("aux-server-idle-timeout", 90*time.Second)
+	v.SetDefault("aux-server-max-header-bytes", 64<<10)
+	v.SetDefault("aux-server-shutdown-timeout", 10*time.Second)
+	v.SetDefault("admin-bind-address", "0")
+	v.SetDefault("api-bind-address", "0")
+	v.SetDefault("reconcile-history-size", 0)
 	v.SetDefault("tag-namespace", "")
 	v.SetDefault("pod-rate-limit-qps", 0.1)
 	v.SetDefault("pod-rate-limit-burst", 1)
 	v.SetDefault("rate-limiter-cleanup-interval", 1*time.Minute)
-	v.SetDefault("aws-health-max-successes", 3)
+	v.SetDefault("pod-rate-limit-condition", false)
+	v.SetDefault("pod-rate-limiters-max", 50000)
+	v.SetDefault("aws-health-check-interval", 30*time.Second)
+	v.SetDefault("aws-health-probe", AWSHealthProbeReadyz)
+	v.SetDefault("verify-permissions", true)
+	v.SetDefault("verify-tagging-permissions", true)
+	v.SetDefault("tag-key-case-conflict", TagKeyCaseConflictAllow)
+	v.SetDefault("tag-value-templates", TagValueTemplatesNone)
+	v.SetDefault("enable-service-tagging", false)
+	v.SetDefault("enable-admission-webhook", false)
+	v.SetDefault("webhook-port", 9443)
+	v.SetDefault("webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs")
+	v.SetDefault("default-tags", "")
+	v.SetDefault("default-tags-selector", "")
+	v.SetDefault("default-tags-configmap", "")
+	v.SetDefault("default-tags-canary-percent", 10)
+	v.SetDefault("default-tags-canary-pods", 5)
+	v.SetDefault("default-tags-canary-max-failure-ratio", 0.2)
+	v.SetDefault("tag-diff-source", TagDiffSourceAnnotation)
+	v.SetDefault("host-network-eni", HostNetworkENIPodIP)
+	v.SetDefault("tag-security-groups", false)
+	v.SetDefault("tag-node-volumes", false)
+	v.SetDefault("tag-headroom-warning", 5)
+	v.SetDefault("startup-repair-window", time.Duration(0))
+	v.SetDefault("resync-interval", time.Duration(0))
+	v.SetDefault("invalid-tags-policy", InvalidTagsPolicyKeep)
+	v.SetDefault("tag-history-size", 0)
+	v.SetDefault("state-store", StateStoreAnnotations)
+	v.SetDefault("maintenance-windows", "")
+	v.SetDefault("exclude-pod-selector", "")
+	v.SetDefault("debug-pods", DebugPodsSkip)
+	v.SetDefault("key-domain", DefaultKeyDomain)
+	v.SetDefault("controller-id", "")
+	v.SetDefault("managed-by-tag", false)
 }
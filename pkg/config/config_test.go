@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
@@ -170,3 +171,957 @@ func TestLoad_CLI_Precedence_OverEnv(t *testing.T) {
 		t.Errorf("Expected DryRun=true from CLI precedence, got false")
 	}
 }
+
+func TestLoad_ExcludePodSelector(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--exclude-pod-selector", "ci-runner=true,tier!=prod"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "ci-runner=true,tier!=prod", cfg.ExcludePodSelector)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--exclude-pod-selector", "in (broken"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_AWSHealthProbe(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, AWSHealthProbeReadyz, cfg.AWSHealthProbe)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aws-health-probe", "healthz"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, AWSHealthProbeHealthz, cfg.AWSHealthProbe)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aws-health-probe", "sometimes"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_TagKeyCaseConflict(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, TagKeyCaseConflictAllow, cfg.TagKeyCaseConflict)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-key-case-conflict", "normalize"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, TagKeyCaseConflictNormalize, cfg.TagKeyCaseConflict)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-key-case-conflict", "lower"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_TagValueTemplates(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, TagValueTemplatesNone, cfg.TagValueTemplates)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-value-templates", "node"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, TagValueTemplatesNode, cfg.TagValueTemplates)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-value-templates", "cluster"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_FaultInjection(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+	t.Setenv("ENI_TAGGER_FAULT_AWS_ERROR_RATE", "0.25")
+
+	cfg, err := Load()
+	if !faultInjectionAvailable {
+		require.ErrorContains(t, err, "faultinject")
+		return
+	}
+	require.NoError(t, err)
+	require.Equal(t, 0.25, cfg.FaultAWSErrorRate)
+	require.Zero(t, cfg.FaultCacheConflictRate)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--fault-cache-conflict-rate", "1.5"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_TagDiffSource(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, TagDiffSourceAnnotation, cfg.TagDiffSource)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-diff-source", "eni"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, TagDiffSourceENI, cfg.TagDiffSource)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-diff-source", "cache"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_AdmissionWebhook(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.False(t, cfg.EnableAdmissionWebhook)
+	require.Equal(t, 9443, cfg.WebhookPort)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--enable-admission-webhook", "--webhook-port", "10250", "--webhook-cert-dir", "/certs"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.True(t, cfg.EnableAdmissionWebhook)
+	require.Equal(t, 10250, cfg.WebhookPort)
+	require.Equal(t, "/certs", cfg.WebhookCertDir)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--enable-admission-webhook", "--webhook-port", "0"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_DefaultTags(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--enable-admission-webhook", "--default-tags", "team=platform", "--default-tags-selector", "tier in (web,api)", "--default-tags-configmap", "ops/default-tags"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "team=platform", cfg.DefaultTags)
+	require.Equal(t, "tier in (web,api)", cfg.DefaultTagsSelector)
+	require.Equal(t, "ops/default-tags", cfg.DefaultTagsConfigMap)
+	require.Equal(t, 10, cfg.DefaultTagsCanaryPercent)
+	require.Equal(t, 5, cfg.DefaultTagsCanaryPods)
+	require.Equal(t, 0.2, cfg.DefaultTagsCanaryMaxFailureRatio)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--enable-admission-webhook", "--default-tags-configmap", "default-tags", "--default-tags-canary-percent", "25", "--default-tags-canary-pods", "20", "--default-tags-canary-max-failure-ratio", "0"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, 25, cfg.DefaultTagsCanaryPercent)
+	require.Equal(t, 20, cfg.DefaultTagsCanaryPods)
+	require.Zero(t, cfg.DefaultTagsCanaryMaxFailureRatio)
+
+	for _, args := range [][]string{
+		{"--default-tags", "team=platform"},
+		{"--enable-admission-webhook", "--default-tags-selector", "tier in (web"},
+		{"--enable-admission-webhook", "--default-tags-configmap", "Default_Tags"},
+		{"--default-tags-canary-percent", "0"},
+		{"--default-tags-canary-percent", "101"},
+		{"--default-tags-canary-pods", "0"},
+		{"--default-tags-canary-max-failure-ratio", "1"},
+	} {
+		pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+		os.Args = append([]string{"cmd"}, args...)
+
+		_, err = Load()
+		require.Error(t, err, args)
+	}
+}
+
+func TestLoad_HostNetworkENI(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, HostNetworkENIPodIP, cfg.HostNetworkENI)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--host-network-eni", "primary-eni"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, HostNetworkENIPrimary, cfg.HostNetworkENI)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--host-network-eni", "instance"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_StartupRepairWindow(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Zero(t, cfg.StartupRepairWindow)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--startup-repair-window", "10m"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Minute, cfg.StartupRepairWindow)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--startup-repair-window", "-1m"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_StandbyCacheRefreshInterval(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, time.Minute, cfg.StandbyCacheRefreshInterval)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--standby-cache-refresh-interval", "-1s"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_AuxServerOptions(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Second, cfg.AuxServerReadHeaderTimeout)
+	require.Equal(t, 64<<10, cfg.AuxServerMaxHeaderBytes)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aux-server-read-header-timeout", "0"}
+
+	_, err = Load()
+	require.Error(t, err)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aux-server-max-header-bytes", "100"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_InvalidTagsPolicy(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, InvalidTagsPolicyKeep, cfg.InvalidTagsPolicy)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--invalid-tags-policy", "rollback"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, InvalidTagsPolicyRollback, cfg.InvalidTagsPolicy)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--invalid-tags-policy", "delete"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_TagHistorySize(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Zero(t, cfg.TagHistorySize)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-history-size", "10"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, 10, cfg.TagHistorySize)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-history-size", "21"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_AWSNamespaceBudgets(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aws-namespace-budgets", "batch=0.25"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, map[string]float64{"batch": 0.25}, cfg.AWSNamespaceBudgets)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aws-namespace-budgets", "batch=2"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_MaintenanceWindows(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Empty(t, cfg.MaintenanceWindows)
+
+	t.Setenv("ENI_TAGGER_MAINTENANCE_WINDOWS", "22:00-06:00")
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, "22:00-06:00", cfg.MaintenanceWindows)
+}
+
+func TestLoad_SubnetConfigMap(t *testing.T) {
+	for _, tt := range []struct {
+		value   string
+		wantErr bool
+	}{
+		{value: "eni-tagger-subnets"},
+		{value: "network/eni-tagger-subnets"},
+		{value: "Network/subnets", wantErr: true},
+		{value: "network/", wantErr: true},
+	} {
+		pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+		os.Args = []string{"cmd", "--subnet-configmap", tt.value, "--pause-configmap", tt.value}
+
+		cfg, err := Load()
+		if tt.wantErr {
+			require.Error(t, err, tt.value)
+			continue
+		}
+		require.NoError(t, err, tt.value)
+		require.Equal(t, tt.value, cfg.SubnetConfigMap)
+		require.Equal(t, tt.value, cfg.PauseConfigMap)
+	}
+}
+
+func TestLoad_PauseDeletionHold(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, 10*time.Minute, cfg.PauseDeletionHold)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--pause-deletion-hold", "0"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Zero(t, cfg.PauseDeletionHold)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--pause-deletion-hold", "-1m"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_AWSHealthCheckInterval(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	// The deprecated latch flag must still be accepted
+	os.Args = []string{"cmd", "--aws-health-check-interval", "45s", "--aws-health-max-successes", "5"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, 45*time.Second, cfg.AWSHealthCheckInterval)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aws-health-check-interval", "0s"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_KeyDomain(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, DefaultKeyDomain, cfg.KeyDomain)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--key-domain", "platform.example.com"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, "platform.example.com", cfg.KeyDomain)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--key-domain", "Not_A/Domain"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_ControllerID(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+	t.Setenv("ENI_TAGGER_CONTROLLER_ID", "kube-system/eni-tagger")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "kube-system/eni-tagger", cfg.ControllerID)
+}
+
+func TestLoad_AssumeRole(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Empty(t, cfg.AWSAssumeRoleARN)
+	require.True(t, cfg.AWSSessionTags)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aws-assume-role-arn", "arn:aws:iam::123456789012:role/eni-tagger", "--aws-assume-role-external-id", "ext", "--cluster-name", "prod"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, "arn:aws:iam::123456789012:role/eni-tagger", cfg.AWSAssumeRoleARN)
+	require.Equal(t, "ext", cfg.AWSAssumeRoleExternalID)
+	require.Equal(t, "prod", cfg.ClusterName)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aws-assume-role-external-id", "ext"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_NamespaceFairQueuing(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.False(t, cfg.NamespaceFairQueuing)
+
+	t.Setenv("ENI_TAGGER_NAMESPACE_FAIR_QUEUING", "true")
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.True(t, cfg.NamespaceFairQueuing)
+}
+
+func TestLoad_MetricsExemplars(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+	t.Setenv("ENI_TAGGER_METRICS_EXEMPLARS", "true")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.True(t, cfg.MetricsExemplars)
+}
+
+func TestLoad_AWSProfiles(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aws-profile", "tagger"}
+	t.Setenv("ENI_TAGGER_AWS_HEALTH_ROLE_ARN", "arn:aws:iam::111111111111:role/readonly")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "tagger", cfg.AWSProfile)
+	require.Empty(t, cfg.AWSHealthProfile)
+	require.Equal(t, "arn:aws:iam::111111111111:role/readonly", cfg.AWSHealthRoleARN)
+}
+
+func TestLoad_TriggerAudit(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--trigger-audit"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.True(t, cfg.TriggerAudit)
+}
+
+func TestLoad_VerifyPermissions(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.True(t, cfg.VerifyPermissions)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--verify-permissions=false"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.False(t, cfg.VerifyPermissions)
+
+	// The deprecated flag still disables the check
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--verify-tagging-permissions=false"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.False(t, cfg.VerifyPermissions)
+}
+
+func TestLoad_StateStore(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, StateStoreAnnotations, cfg.StateStore)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--state-store", "configmap"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, StateStoreConfigMap, cfg.StateStore)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--state-store", "crd"}
+
+	_, err = Load()
+	require.ErrorContains(t, err, "--state-store")
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--state-store", "configmap", "--tag-history-size", "5"}
+
+	_, err = Load()
+	require.ErrorContains(t, err, "--tag-history-size")
+}
+
+func TestLoad_MaxConcurrentReconcilesCeiling(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--max-concurrent-reconciles", "2"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, 2, cfg.MaxConcurrentReconcilesCeiling)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--max-concurrent-reconciles", "2", "--max-concurrent-reconciles-ceiling", "8", "--admin-bind-address", "8082"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, 8, cfg.MaxConcurrentReconcilesCeiling)
+	require.Equal(t, "0.0.0.0:8082", cfg.AdminBindAddress)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--max-concurrent-reconciles", "4", "--max-concurrent-reconciles-ceiling", "2"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_APIBindAddress(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "0", cfg.APIBindAddress)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--api-bind-address", "8083"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, "0.0.0.0:8083", cfg.APIBindAddress)
+}
+
+func TestLoad_ReconcileHistorySize(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--reconcile-history-size", "500"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, 500, cfg.ReconcileHistorySize)
+	require.Contains(t, cfg.EnabledFeatures(), "reconcile-history-size")
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--reconcile-history-size", "-1"}
+
+	_, err = Load()
+	require.ErrorContains(t, err, "--reconcile-history-size")
+}
+
+func TestLoad_ValidationErrorProvenance(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		env          map[string]string
+		key          string
+		source       Source
+		variable     string
+		errorMessage string
+	}{
+		{
+			name:         "bad duration from environment",
+			env:          map[string]string{"ENI_TAGGER_AWS_HEALTH_CHECK_INTERVAL": "5 minutes"},
+			key:          "aws-health-check-interval",
+			source:       SourceEnv,
+			variable:     "ENI_TAGGER_AWS_HEALTH_CHECK_INTERVAL",
+			errorMessage: `invalid ENI_TAGGER_AWS_HEALTH_CHECK_INTERVAL="5 minutes" (environment variable for --aws-health-check-interval): not a valid duration (e.g. 30s, 5m, 1h)`,
+		},
+		{
+			name:         "bad subnet from flag",
+			args:         []string{"--subnet-ids", "subnet-1,sbunet-2"},
+			key:          "subnet-ids",
+			source:       SourceFlag,
+			variable:     "--subnet-ids",
+			errorMessage: `invalid --subnet-ids="subnet-1,sbunet-2" (flag): invalid subnet ID format: sbunet-2`,
+		},
+		{
+			name:     "flag wins over environment",
+			args:     []string{"--aws-rate-limit-qps", "0"},
+			env:      map[string]string{"ENI_TAGGER_AWS_RATE_LIMIT_QPS": "5"},
+			key:      "aws-rate-limit-qps",
+			source:   SourceFlag,
+			variable: "--aws-rate-limit-qps",
+		},
+		{
+			name:     "bad enum from environment",
+			env:      map[string]string{"ENI_TAGGER_TAG_DIFF_SOURCE": "api"},
+			key:      "tag-diff-source",
+			source:   SourceEnv,
+			variable: "ENI_TAGGER_TAG_DIFF_SOURCE",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+			os.Args = append([]string{"cmd"}, tt.args...)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load()
+			var verr *ValidationError
+			require.ErrorAs(t, err, &verr)
+			require.Equal(t, tt.key, verr.Key)
+			require.Equal(t, tt.source, verr.Source)
+			require.Equal(t, tt.variable, verr.Variable())
+			if tt.errorMessage != "" {
+				require.EqualError(t, err, tt.errorMessage)
+			}
+		})
+	}
+}
+
+func TestLoad_TagBurstDelay(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-burst-delay", "2s"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, cfg.TagBurstDelay)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-burst-delay", "1m"}
+
+	_, err = Load()
+	require.Error(t, err)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-burst-delay", "2s", "--aws-assume-role-arn", "arn:aws:iam::123456789012:role/tagger"}
+
+	_, err = Load()
+	require.ErrorContains(t, err, "session tags")
+}
+
+func TestLoad_ENIAttachmentRequeueDelay(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--eni-attachment-requeue-delay", "5s"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Second, cfg.ENIAttachmentRequeueDelay)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--eni-attachment-requeue-delay", "-1s"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_OwnershipReport(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--ownership-report-s3-bucket", "finops-reports", "--ownership-report-s3-prefix", "eni/"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "finops-reports", cfg.OwnershipReportS3Bucket)
+	require.Equal(t, time.Hour, cfg.OwnershipReportInterval)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--ownership-report-s3-bucket", "Not_A_Bucket"}
+
+	_, err = Load()
+	require.Error(t, err)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--ownership-report-s3-bucket", "finops-reports", "--ownership-report-interval", "10s"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_TagSourceWebhook(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-source-webhook-url", "https://tags.example.com/v1/pod-tags"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "https://tags.example.com/v1/pod-tags", cfg.TagSourceWebhookURL)
+	require.Equal(t, 2*time.Second, cfg.TagSourceWebhookTimeout)
+	require.Equal(t, 5*time.Minute, cfg.TagSourceWebhookCacheTTL)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-source-webhook-url", "tags.example.com"}
+
+	_, err = Load()
+	require.Error(t, err)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-source-webhook-url", "http://tags:8080", "--tag-source-webhook-timeout", "0s"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_PodRateLimitersMax(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, 50000, cfg.PodRateLimitersMax)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--pod-rate-limiters-max", "-1"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_AWSMutationBudget(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Zero(t, cfg.AWSMutationBudget)
+	require.Equal(t, time.Hour, cfg.AWSMutationBudgetWindow)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aws-mutation-budget", "5000", "--aws-mutation-budget-window", "24h"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, 5000, cfg.AWSMutationBudget)
+	require.Equal(t, 24*time.Hour, cfg.AWSMutationBudgetWindow)
+
+	for _, args := range [][]string{
+		{"--aws-mutation-budget", "-1"},
+		{"--aws-mutation-budget", "100", "--aws-mutation-budget-window", "30s"},
+	} {
+		pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+		os.Args = append([]string{"cmd"}, args...)
+
+		_, err = Load()
+		require.Error(t, err, args)
+	}
+}
+
+func TestLoad_DebugPods(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, DebugPodsSkip, cfg.DebugPods)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--debug-pods", "tag"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, DebugPodsTag, cfg.DebugPods)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--debug-pods", "ignore"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_AuditLogPath(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Empty(t, cfg.AuditLogPath)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--audit-log-path", "/var/log/eni-tagger/audit.log"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, "/var/log/eni-tagger/audit.log", cfg.AuditLogPath)
+}
+
+func TestLoad_VersionFormat(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--version", "--version-format", "json", "--enable-eni-cache", "--aws-session-tags=false"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, VersionFormatJSON, cfg.VersionFormat)
+	require.Equal(t, []string{"enable-eni-cache"}, cfg.EnabledFeatures())
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--version", "--version-format", "yaml"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_TagSecurityGroups(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.False(t, cfg.TagSecurityGroups)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-security-groups"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.True(t, cfg.TagSecurityGroups)
+}
+
+func TestLoad_TagNodeVolumes(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-node-volumes"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.True(t, cfg.TagNodeVolumes)
+	require.Contains(t, cfg.EnabledFeatures(), "tag-node-volumes")
+}
+
+func TestLoad_AWSTagBackend(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, AWSTagBackendEC2, cfg.AWSTagBackend)
+	require.NotContains(t, cfg.EnabledFeatures(), "aws-tag-backend")
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aws-tag-backend", "resourcegroupstaggingapi"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Equal(t, AWSTagBackendResourceGroupsTagging, cfg.AWSTagBackend)
+	require.Contains(t, cfg.EnabledFeatures(), "aws-tag-backend")
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--aws-tag-backend", "s3"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_TagHeadroomWarning(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, 5, cfg.TagHeadroomWarning)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-headroom-warning", "0"}
+
+	cfg, err = Load()
+	require.NoError(t, err)
+	require.Zero(t, cfg.TagHeadroomWarning)
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--tag-headroom-warning", "-1"}
+
+	_, err = Load()
+	require.Error(t, err)
+}
+
+func TestLoad_ReadOnly(t *testing.T) {
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--read-only"}
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.True(t, cfg.ReadOnly)
+	require.Contains(t, cfg.EnabledFeatures(), "read-only")
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--read-only", "--state-store", "configmap"}
+
+	_, err = Load()
+	require.ErrorContains(t, err, "cannot be used with --read-only")
+
+	pflag.CommandLine = pflag.NewFlagSet(os.Args[0], pflag.ExitOnError)
+	os.Args = []string{"cmd", "--read-only", "--ownership-report-s3-bucket", "finops-reports"}
+
+	_, err = Load()
+	require.ErrorContains(t, err, "cannot be used with --read-only")
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// envPrefix is prepended to every environment variable read by Load.
+const envPrefix = "ENI_TAGGER"
+
+// Source is where a configuration value came from.
+type Source string
+
+const (
+	SourceFlag    Source = "flag"
+	SourceEnv     Source = "environment variable"
+	SourceDefault Source = "default"
+)
+
+// ValidationError reports a configuration value rejected by Load, together with
+// where it was set, so the right flag or environment variable can be fixed.
+type ValidationError struct {
+	// Key is the configuration key, which is also the flag name without "--".
+	Key string
+	// Value is the rejected value as given.
+	Value string
+	// Source is where Value came from.
+	Source Source
+	// Err describes what is wrong with Value.
+	Err error
+}
+
+// Variable returns the exact name the value was set by: "--<key>" for flags and
+// defaults, or the environment variable name.
+func (e *ValidationError) Variable() string {
+	if e.Source == SourceEnv {
+		return EnvVar(e.Key)
+	}
+	return "--" + e.Key
+}
+
+func (e *ValidationError) Error() string {
+	switch e.Source {
+	case SourceEnv:
+		return fmt.Sprintf("invalid %s=%q (environment variable for --%s): %v", e.Variable(), e.Value, e.Key, e.Err)
+	case SourceDefault:
+		return fmt.Sprintf("invalid default %s=%q: %v", e.Variable(), e.Value, e.Err)
+	default:
+		return fmt.Sprintf("invalid %s=%q (flag): %v", e.Variable(), e.Value, e.Err)
+	}
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// EnvVar returns the environment variable Load reads key from.
+func EnvVar(key string) string {
+	return envPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+}
+
+// valueSource returns where the value of key comes from, following viper's
+// precedence: flags set on the command line, then environment, then defaults.
+func valueSource(key string) Source {
+	if f := pflag.CommandLine.Lookup(key); f != nil && f.Changed {
+		return SourceFlag
+	}
+	if _, ok := os.LookupEnv(EnvVar(key)); ok {
+		return SourceEnv
+	}
+	return SourceDefault
+}
+
+// invalidValue returns a ValidationError for the current value of key.
+func invalidValue(v *viper.Viper, key string, err error) error {
+	return &ValidationError{Key: key, Value: v.GetString(key), Source: valueSource(key), Err: err}
+}
+
+// checkEnvTypes rejects environment variables that cannot be parsed as the type
+// of their flag. pflag already rejects bad flag values, but environment values
+// only fail when the config is decoded, with an error that does not name the
+// variable.
+func checkEnvTypes(v *viper.Viper) error {
+	var err error
+	pflag.CommandLine.VisitAll(func(f *pflag.Flag) {
+		if err != nil || valueSource(f.Name) != SourceEnv {
+			return
+		}
+		value := v.GetString(f.Name)
+		var parseErr error
+		switch f.Value.Type() {
+		case "duration":
+			_, parseErr = time.ParseDuration(value)
+		case "int":
+			if value != "" {
+				_, parseErr = strconv.ParseInt(value, 0, 0)
+			}
+		case "float64":
+			if value != "" {
+				_, parseErr = strconv.ParseFloat(value, 64)
+			}
+		case "bool":
+			if value != "" {
+				_, parseErr = strconv.ParseBool(value)
+			}
+		default:
+			return
+		}
+		if parseErr != nil {
+			msg := "not a valid " + f.Value.Type()
+			if f.Value.Type() == "duration" {
+				msg += " (e.g. 30s, 5m, 1h)"
+			}
+			err = invalidValue(v, f.Name, errors.New(msg))
+		}
+	})
+	return err
+}
@@ -0,0 +1,7 @@
+//go:build faultinject
+
+package config
+
+// faultInjectionAvailable reports whether the binary was built with the
+// faultinject build tag, without which the fault-* rates must be 0.
+const faultInjectionAvailable = true
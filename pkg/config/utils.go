@@ -5,6 +5,8 @@ import (
 	"net"
 	"strconv"
 	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // normalizeBindAddress ensures the controller-runtime bind addresses are valid:
@@ -30,3 +32,125 @@ func normalizeBindAddress(value string) (string, error) {
 	// Use net.JoinHostPort for robust formatting (handles edge cases consistently)
 	return net.JoinHostPort("0.0.0.0", v), nil
 }
+
+// parseTagKeyRenames parses "from=to" tag key renames separated by commas. Each
+// key may be renamed once and each target used once. Whether the targets are
+// valid tag keys is checked by the controller.
+func parseTagKeyRenames(value string) (map[string]string, error) {
+	renames := make(map[string]string)
+	targets := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(pair, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("expected from=to, got %q", pair)
+		}
+		if _, dup := renames[from]; dup {
+			return nil, fmt.Errorf("key %q renamed more than once", from)
+		}
+		if other, dup := targets[to]; dup {
+			return nil, fmt.Errorf("keys %q and %q both renamed to %q", other, from, to)
+		}
+		renames[from] = to
+		targets[to] = from
+	}
+	if len(renames) == 0 {
+		return nil, nil
+	}
+	return renames, nil
+}
+
+// parseTagFromLabels parses comma-separated pod label keys, each optionally
+// followed by "=TagKey"; without one the label key is used as the tag key. Each
+// label may be listed once and each tag key used once. Whether the tag keys are
+// valid is checked by the controller.
+func parseTagFromLabels(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	tagKeys := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		label, tagKey, ok := strings.Cut(entry, "=")
+		label, tagKey = strings.TrimSpace(label), strings.TrimSpace(tagKey)
+		if !ok {
+			tagKey = label
+		}
+		if label == "" || tagKey == "" {
+			return nil, fmt.Errorf("expected label or label=TagKey, got %q", entry)
+		}
+		if errs := validation.IsQualifiedName(label); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label key %q: %s", label, strings.Join(errs, "; "))
+		}
+		if _, dup := mapping[label]; dup {
+			return nil, fmt.Errorf("label %q listed more than once", label)
+		}
+		if other, dup := tagKeys[tagKey]; dup {
+			return nil, fmt.Errorf("labels %q and %q both mapped to tag key %q", other, label, tagKey)
+		}
+		mapping[label] = tagKey
+		tagKeys[tagKey] = label
+	}
+	if len(mapping) == 0 {
+		return nil, nil
+	}
+	return mapping, nil
+}
+
+// parseCriticalTagKeys parses comma-separated tag keys, each optionally ending in
+// "*" to match a prefix. Duplicates are dropped.
+func parseCriticalTagKeys(value string) ([]string, error) {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		if i := strings.Index(key, "*"); i >= 0 && i != len(key)-1 {
+			return nil, fmt.Errorf("%q: \"*\" is only allowed at the end of a key", key)
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// parseNamespaceBudgets parses "namespace=fraction" pairs separated by commas,
+// where namespace is a namespace name or "*" and fraction is in (0, 1].
+func parseNamespaceBudgets(value string) (map[string]float64, error) {
+	budgets := make(map[string]float64)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		ns, share, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("expected namespace=fraction, got %q", pair)
+		}
+		ns = strings.TrimSpace(ns)
+		if ns != "*" {
+			if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+				return nil, fmt.Errorf("invalid namespace %q: %s", ns, strings.Join(errs, "; "))
+			}
+		}
+		if _, dup := budgets[ns]; dup {
+			return nil, fmt.Errorf("namespace %q listed more than once", ns)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(share), 64)
+		if err != nil || f <= 0 || f > 1 {
+			return nil, fmt.Errorf("fraction for namespace %q must be a number in (0, 1], got %q", ns, share)
+		}
+		budgets[ns] = f
+	}
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+	return budgets, nil
+}
@@ -141,3 +141,61 @@ func TestNormalizeBindAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTagKeyRenames(t *testing.T) {
+	renames, err := parseTagKeyRenames(" team = CostTeam, env=Environment ,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"team": "CostTeam", "env": "Environment"}, renames)
+
+	renames, err = parseTagKeyRenames("")
+	require.NoError(t, err)
+	require.Nil(t, renames)
+
+	for _, value := range []string{"team", "team=", "=CostTeam", "team=a,team=b", "team=CostTeam,squad=CostTeam"} {
+		_, err := parseTagKeyRenames(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestParseTagFromLabels(t *testing.T) {
+	mapping, err := parseTagFromLabels(" team, example.com/cost-center = CostCenter ,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"team": "team", "example.com/cost-center": "CostCenter"}, mapping)
+
+	mapping, err = parseTagFromLabels("")
+	require.NoError(t, err)
+	require.Nil(t, mapping)
+
+	for _, value := range []string{"team=", "=Team", "bad label", "team,team=Team", "team=Owner,squad=Owner", "team,squad=team"} {
+		_, err := parseTagFromLabels(value)
+		require.Error(t, err, value)
+	}
+}
+
+func TestParseCriticalTagKeys(t *testing.T) {
+	keys, err := parseCriticalTagKeys(" CostCenter, billing:* ,CostCenter,")
+	require.NoError(t, err)
+	require.Equal(t, []string{"CostCenter", "billing:*"}, keys)
+
+	keys, err = parseCriticalTagKeys("")
+	require.NoError(t, err)
+	require.Nil(t, keys)
+
+	_, err = parseCriticalTagKeys("billing:*:project")
+	require.Error(t, err)
+}
+
+func TestParseNamespaceBudgets(t *testing.T) {
+	budgets, err := parseNamespaceBudgets(" batch=0.2, *=0.5 ,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]float64{"batch": 0.2, "*": 0.5}, budgets)
+
+	budgets, err = parseNamespaceBudgets("")
+	require.NoError(t, err)
+	require.Nil(t, budgets)
+
+	for _, value := range []string{"batch", "batch=0", "batch=1.5", "batch=x", "Batch=0.2", "batch=0.2,batch=0.3"} {
+		_, err := parseNamespaceBudgets(value)
+		require.Error(t, err, value)
+	}
+}
@@ -1,48 +1,139 @@
 package controller
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"sort"
 
 	corev1 "k8s.io/api/core/v1"
-	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 // updatePodAnnotations updates the pod's last-applied-tags and last-applied-hash annotations.
 // These annotations track the state of tags that were successfully applied to the ENI,
 // enabling the controller to calculate diffs on subsequent reconciliations.
-// If currentTags is empty, the annotations are removed from the pod.
-// Uses retry on conflict to handle concurrent updates.
+// If currentTags is empty, the annotations are removed from the pod. Changes are also
+// recorded in the tag history annotation when TagHistorySize is set.
+//
+// While tags are applied the pod also needs the finalizer, so they are cleaned up on
+// deletion. Unless the change also removes tags (see recordPendingTransition), it
+// is added in the same write as the annotations rather than in a write of its own
+// before tagging. A pod deleted before that write lands never gets it; see
+// rollbackUnprotectedTags.
+//
+// Only the changed fields are sent, as a strategic merge patch without a
+// resourceVersion, so concurrent edits to other annotations or finalizers cannot
+// conflict. Nothing is written when the pod is already current. pod is updated in
+// place from the response. With PodWrites the patch may be collapsed with later
+// ones instead.
+//
+// With a StateStore the state is saved there instead and the pod is not written.
 func updatePodAnnotations(ctx context.Context, r *PodReconciler, pod *corev1.Pod, currentTags map[string]string, desiredHash string) error {
 	logger := log.FromContext(ctx)
 
-	newLastApplied, err := json.Marshal(currentTags)
+	newLastApplied, err := marshalLastApplied(currentTags)
 	if err != nil {
 		logger.Error(err, "Failed to marshal current tags")
 		return err
 	}
 
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		// Re-fetch pod to get latest version
-		currentPod := &corev1.Pod{}
-		if err := r.Get(ctx, client.ObjectKeyFromObject(pod), currentPod); err != nil {
-			return err
+	if r.StateStore != nil {
+		return r.saveStoredState(ctx, pod, currentTags, newLastApplied, desiredHash)
+	}
+
+	keys := r.keys()
+	base := pod.DeepCopy()
+
+	if len(currentTags) > 0 {
+		controllerutil.AddFinalizer(pod, keys.Finalizer)
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	if r.TagHistorySize > 0 && pod.Annotations[keys.LastAppliedHash] != desiredHash {
+		history, err := appendTagHistory(pod.Annotations[keys.TagHistory], currentTags, desiredHash, r.TagHistorySize)
+		if err != nil {
+			logger.Error(err, "Failed to parse tag history, starting a new one")
 		}
+		pod.Annotations[keys.TagHistory] = history
+	}
+	// The tags are now described by the last-applied annotations
+	delete(pod.Annotations, keys.PendingTransition)
+	if len(currentTags) == 0 {
+		delete(pod.Annotations, keys.LastAppliedTags)
+		delete(pod.Annotations, keys.LastAppliedHash)
+	} else {
+		pod.Annotations[keys.LastAppliedTags] = newLastApplied
+		pod.Annotations[keys.LastAppliedHash] = desiredHash
+	}
+
+	if r.PodWrites != nil {
+		return r.PodWrites.patch(ctx, base, pod)
+	}
+	return patchIfChanged(ctx, r.Client, pod, client.StrategicMergeFrom(base))
+}
+
+// marshalLastApplied encodes tags for the last-applied annotation canonically:
+// compact, keys sorted by byte value and no HTML escaping, so the same tags always
+// give the same bytes and external tools can diff annotations textually.
+func marshalLastApplied(tags map[string]string) (string, error) {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
-		// Apply annotation updates
-		if currentPod.Annotations == nil {
-			currentPod.Annotations = make(map[string]string)
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	buf.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
 		}
-		if len(currentTags) == 0 {
-			delete(currentPod.Annotations, LastAppliedAnnotationKey)
-			delete(currentPod.Annotations, LastAppliedHashKey)
-		} else {
-			currentPod.Annotations[LastAppliedAnnotationKey] = string(newLastApplied)
-			currentPod.Annotations[LastAppliedHashKey] = desiredHash
+		// Encode appends a newline after each value
+		if err := enc.Encode(k); err != nil {
+			return "", err
 		}
+		buf.Truncate(buf.Len() - 1)
+		buf.WriteByte(':')
+		if err := enc.Encode(tags[k]); err != nil {
+			return "", err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	buf.WriteByte('}')
+	return buf.String(), nil
+}
 
-		return r.Update(ctx, currentPod)
-	})
+// parseLastApplied decodes a last-applied annotation. Any JSON encoding of the
+// object is accepted, including the HTML-escaped and whitespace variants written
+// by older versions, and "" or "null" mean no tags.
+func parseLastApplied(value string) (map[string]string, error) {
+	tags := make(map[string]string)
+	if value == "" {
+		return tags, nil
+	}
+	if err := checkAnnotationJSON(value, maxBookkeepingAnnotationBytes, 1); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(value), &tags); err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	return tags, nil
+}
+
+// patchIfChanged sends patch for obj unless it is empty, saving an API write on
+// reconciles that change nothing.
+func patchIfChanged(ctx context.Context, c client.Client, obj client.Object, patch client.Patch) error {
+	if changed, err := patchChanges(obj, patch); err != nil || !changed {
+		return err
+	}
+	return c.Patch(ctx, obj, patch)
 }
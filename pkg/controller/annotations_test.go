@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalLastApplied(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		want string
+	}{
+		{name: "Empty", tags: nil, want: `{}`},
+		{name: "Sorted and compact", tags: map[string]string{"team": "a", "Env": "prod", "cost-center": "42"}, want: `{"Env":"prod","cost-center":"42","team":"a"}`},
+		{name: "No HTML escaping", tags: map[string]string{"owner": "R&D <ops>"}, want: `{"owner":"R&D <ops>"}`},
+		{name: "Quotes and control characters escaped", tags: map[string]string{"note": "a \"b\"\n"}, want: `{"note":"a \"b\"\n"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := marshalLastApplied(tt.tags)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			parsed, err := parseLastApplied(got)
+			require.NoError(t, err)
+			assert.Equal(t, len(tt.tags), len(parsed))
+			for k, v := range tt.tags {
+				assert.Equal(t, v, parsed[k])
+			}
+		})
+	}
+}
+
+func TestParseLastApplied_LegacyEncodings(t *testing.T) {
+	want := map[string]string{"owner": "R&D", "team": "a"}
+	for _, value := range []string{
+		`{"owner":"R\u0026D","team":"a"}`,
+		`{"team":"a","owner":"R&D"}`,
+		"{\n  \"owner\": \"R&D\",\n  \"team\": \"a\"\n}",
+	} {
+		got, err := parseLastApplied(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	for _, value := range []string{"", "null", "{}"} {
+		got, err := parseLastApplied(value)
+		require.NoError(t, err, value)
+		assert.NotNil(t, got)
+		assert.Empty(t, got)
+	}
+
+	_, err := parseLastApplied("team=a")
+	assert.Error(t, err)
+}
@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"k8s-eni-tagger/pkg/aws"
+	enicache "k8s-eni-tagger/pkg/cache"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// CachedENI is an ENI held by the ENI cache, as served by /api/v1/enis.
+type CachedENI struct {
+	ID               string            `json:"id"`
+	SubnetID         string            `json:"subnetID,omitempty"`
+	AvailabilityZone string            `json:"availabilityZone,omitempty"`
+	InterfaceType    string            `json:"interfaceType,omitempty"`
+	Class            string            `json:"class,omitempty"`
+	Shared           bool              `json:"shared"`
+	Status           string            `json:"status,omitempty"`
+	AttachmentStatus string            `json:"attachmentStatus,omitempty"`
+	SecurityGroupIDs []string          `json:"securityGroupIDs,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty"`
+	// IPs maps each cached IP on the ENI to the UID of the pod it was looked up
+	// for, empty for entries persisted by older versions.
+	IPs map[string]string `json:"ips"`
+}
+
+// PodTagState is the controller's view of a pod, as served by
+// /api/v1/pods/{namespace}/{name}/tags.
+type PodTagState struct {
+	// Pod is the pod's "namespace/name".
+	Pod string `json:"pod"`
+	IP  string `json:"ip,omitempty"`
+
+	// Annotation is the raw tag annotation, empty when the pod has none.
+	Annotation string `json:"annotation,omitempty"`
+	// LastApplied and LastAppliedHash are the tags last written to the pod's
+	// ENI, from the pod's annotations or the state store.
+	LastApplied     map[string]string `json:"lastApplied,omitempty"`
+	LastAppliedHash string            `json:"lastAppliedHash,omitempty"`
+	// PendingTransition is set while a tag change is in progress.
+	PendingTransition bool `json:"pendingTransition,omitempty"`
+	// History is the tag history annotation, oldest first.
+	History []TagHistoryEntry `json:"history,omitempty"`
+
+	// Condition is the pod's tagging condition, once a reconcile has set it.
+	Condition *PodTagCondition `json:"condition,omitempty"`
+	// ENI is the pod's ENI as held by the ENI cache. Missing when the cache is
+	// disabled or has no entry for this pod.
+	ENI *CachedENI `json:"eni,omitempty"`
+
+	// Errors lists bookkeeping that could not be parsed.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// PodTagCondition is the tagging condition of a pod with its decoded message.
+type PodTagCondition struct {
+	Status             corev1.ConditionStatus `json:"status"`
+	Reason             string                 `json:"reason,omitempty"`
+	Details            *ConditionDetails      `json:"details,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime,omitempty"`
+}
+
+// APIHandler serves the controller's state for debugging, read-only and from
+// memory or the informer cache, so it makes no AWS calls:
+//
+//	GET /api/v1/enis                                 ENIs in the ENI cache
+//	GET /api/v1/enis/{id}                            one cached ENI
+//	GET /api/v1/enis/{id}/reconciles                 recent reconciles that resolved the ENI
+//	GET /api/v1/pods/{namespace}/{name}/tags         a pod's tags, bookkeeping and condition
+//	GET /api/v1/pods/{namespace}/{name}/reconciles   a pod's recent reconciles
+//
+// Reconciles are listed newest first and need History.
+func (r *PodReconciler) APIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/enis", r.serveENIs)
+	mux.HandleFunc("GET /api/v1/enis/{id}", r.serveENIs)
+	mux.HandleFunc("GET /api/v1/enis/{id}/reconciles", r.serveReconciles)
+	mux.HandleFunc("GET /api/v1/pods/{namespace}/{name}/tags", r.servePodTags)
+	mux.HandleFunc("GET /api/v1/pods/{namespace}/{name}/reconciles", r.serveReconciles)
+	return mux
+}
+
+func (r *PodReconciler) serveReconciles(w http.ResponseWriter, req *http.Request) {
+	if r.History == nil {
+		http.Error(w, "reconcile history is disabled", http.StatusNotFound)
+		return
+	}
+	if id := req.PathValue("id"); id != "" {
+		writeJSON(w, r.History.ForENI(id))
+		return
+	}
+	writeJSON(w, r.History.ForPod(types.NamespacedName{Namespace: req.PathValue("namespace"), Name: req.PathValue("name")}))
+}
+
+func (r *PodReconciler) serveENIs(w http.ResponseWriter, req *http.Request) {
+	if r.ENICache == nil {
+		http.Error(w, "ENI cache is disabled", http.StatusNotFound)
+		return
+	}
+	enis := cachedENIs(r.ENICache.Entries())
+	id := req.PathValue("id")
+	if id == "" {
+		writeJSON(w, enis)
+		return
+	}
+	for _, eni := range enis {
+		if eni.ID == id {
+			writeJSON(w, eni)
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf("ENI %s is not cached", id), http.StatusNotFound)
+}
+
+func (r *PodReconciler) servePodTags(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	key := types.NamespacedName{Namespace: req.PathValue("namespace"), Name: req.PathValue("name")}
+	pod := &corev1.Pod{}
+	if err := r.getPod(ctx, key, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			http.Error(w, fmt.Sprintf("pod %s not found", key), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if r.StateStore != nil {
+		// Not loadStoredState, which cleans up the state of an earlier pod
+		stored, ok, err := r.StateStore.get(ctx, key)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		keys := r.keys()
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		delete(pod.Annotations, keys.LastAppliedTags)
+		delete(pod.Annotations, keys.LastAppliedHash)
+		delete(pod.Annotations, keys.PendingTransition)
+		if ok && stored.UID == pod.UID {
+			pod.Annotations[keys.LastAppliedTags] = stored.Tags
+			pod.Annotations[keys.LastAppliedHash] = stored.Hash
+			pod.Annotations[keys.PendingTransition] = stored.Pending
+		}
+	}
+	writeJSON(w, r.podTagState(pod))
+}
+
+// podTagState reads the bookkeeping of pod, with stored state already put into
+// its annotations.
+func (r *PodReconciler) podTagState(pod *corev1.Pod) PodTagState {
+	keys := r.keys()
+	state := PodTagState{
+		Pod:               pod.Namespace + "/" + pod.Name,
+		IP:                pod.Status.PodIP,
+		Annotation:        pod.Annotations[r.annotationKey()],
+		LastAppliedHash:   pod.Annotations[keys.LastAppliedHash],
+		PendingTransition: pod.Annotations[keys.PendingTransition] != "",
+	}
+
+	var err error
+	if state.LastApplied, err = parseLastApplied(pod.Annotations[keys.LastAppliedTags]); err != nil {
+		state.Errors = append(state.Errors, err.Error())
+	}
+	if state.History, err = ParseTagHistory(pod.Annotations[keys.TagHistory]); err != nil {
+		state.Errors = append(state.Errors, err.Error())
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type != corev1.PodConditionType(keys.ConditionType) {
+			continue
+		}
+		condition := &PodTagCondition{
+			Status:             c.Status,
+			Reason:             c.Reason,
+			LastTransitionTime: c.LastTransitionTime,
+		}
+		if details, err := ParseConditionDetails(c.Message); err == nil {
+			condition.Details = &details
+		} else {
+			condition.Message = c.Message
+		}
+		state.Condition = condition
+	}
+
+	if r.ENICache != nil && pod.Status.PodIP != "" {
+		entries := r.ENICache.Entries()
+		if entry, ok := entries[pod.Status.PodIP]; ok && entry.Info != nil && (entry.PodUID == "" || entry.PodUID == string(pod.UID)) {
+			state.ENI = newCachedENI(entry.Info)
+			for ip, other := range entries {
+				if other.Info != nil && other.Info.ID == entry.Info.ID {
+					state.ENI.IPs[ip] = other.PodUID
+				}
+			}
+		}
+	}
+	return state
+}
+
+// cachedENIs groups cache entries by ENI, sorted by ENI ID.
+func cachedENIs(entries map[string]enicache.CachedEntry) []CachedENI {
+	byID := make(map[string]*CachedENI)
+	for ip, entry := range entries {
+		if entry.Info == nil {
+			continue
+		}
+		eni, ok := byID[entry.Info.ID]
+		if !ok {
+			eni = newCachedENI(entry.Info)
+			byID[entry.Info.ID] = eni
+		}
+		eni.IPs[ip] = entry.PodUID
+	}
+	enis := make([]CachedENI, 0, len(byID))
+	for _, eni := range byID {
+		enis = append(enis, *eni)
+	}
+	sort.Slice(enis, func(i, j int) bool { return enis[i].ID < enis[j].ID })
+	return enis
+}
+
+func newCachedENI(info *aws.ENIInfo) *CachedENI {
+	return &CachedENI{
+		ID:               info.ID,
+		SubnetID:         info.SubnetID,
+		AvailabilityZone: info.AvailabilityZone,
+		InterfaceType:    info.InterfaceType,
+		Class:            info.Class,
+		Shared:           info.IsShared,
+		Status:           info.Status,
+		AttachmentStatus: info.AttachmentStatus,
+		SecurityGroupIDs: info.SecurityGroupIDs,
+		Tags:             info.Tags,
+		IPs:              make(map[string]string),
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
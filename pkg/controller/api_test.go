@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+	enicache "k8s-eni-tagger/pkg/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAPIHandler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "default",
+			UID:       "pod-1",
+			Annotations: map[string]string{
+				AnnotationKey:            `{"team":"platform"}`,
+				LastAppliedAnnotationKey: `{"team":"platform"}`,
+				LastAppliedHashKey:       computeHash(map[string]string{"team": "platform"}),
+			},
+		},
+		Status: corev1.PodStatus{
+			PodIP: "10.0.0.1",
+			Conditions: []corev1.PodCondition{{
+				Type:    corev1.PodConditionType(NewKeys("").ConditionType),
+				Status:  corev1.ConditionTrue,
+				Reason:  string(ReasonSynced),
+				Message: ConditionDetails{Message: "Tags applied", ENIID: "eni-1"}.String(),
+			}},
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.1").Return(&aws.ENIInfo{ID: "eni-1", SubnetID: "subnet-1", Tags: map[string]string{"team": "platform"}}, nil)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.2").Return(&aws.ENIInfo{ID: "eni-1", SubnetID: "subnet-1", Tags: map[string]string{"team": "platform"}}, nil)
+	eniCache := enicache.NewENICache(mockAWS)
+	_, err := eniCache.GetENIInfoByIP(context.Background(), "10.0.0.1", "pod-1")
+	require.NoError(t, err)
+	_, err = eniCache.GetENIInfoByIP(context.Background(), "10.0.0.2", "pod-2")
+	require.NoError(t, err)
+
+	r := &PodReconciler{Client: k8sClient, Scheme: scheme, AWSClient: mockAWS, ENICache: eniCache}
+	h := r.APIHandler()
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/api/v1/enis")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var enis []CachedENI
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &enis))
+	require.Len(t, enis, 1)
+	assert.Equal(t, "eni-1", enis[0].ID)
+	assert.Equal(t, map[string]string{"10.0.0.1": "pod-1", "10.0.0.2": "pod-2"}, enis[0].IPs)
+
+	assert.Equal(t, http.StatusOK, get("/api/v1/enis/eni-1").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/enis/eni-2").Code)
+
+	rec = get("/api/v1/pods/default/app/tags")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var state PodTagState
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+	assert.Equal(t, "default/app", state.Pod)
+	assert.Equal(t, map[string]string{"team": "platform"}, state.LastApplied)
+	require.NotNil(t, state.Condition)
+	assert.Equal(t, corev1.ConditionTrue, state.Condition.Status)
+	require.NotNil(t, state.Condition.Details)
+	assert.Equal(t, "eni-1", state.Condition.Details.ENIID)
+	require.NotNil(t, state.ENI)
+	assert.Equal(t, "subnet-1", state.ENI.SubnetID)
+	assert.Empty(t, state.Errors)
+
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/pods/default/missing/tags").Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/enis", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	r.ENICache = nil
+	assert.Equal(t, http.StatusNotFound, get("/api/v1/enis").Code)
+	mockAWS.AssertNumberOfCalls(t, "GetENIInfoByIP", 2)
+}
@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/metrics"
+)
+
+// TagBurstBuffer merges CreateTags calls for the same ENI that arrive within
+// Delay of each other into one call. When a node joins, dozens of pods whose IPs
+// live on the same few shared ENIs are reconciled at once; without the buffer
+// each of them issues its own CreateTags against the same ENI, one after another
+// under the AWS rate limit.
+//
+// The first call for an ENI opens a burst and waits Delay for others to join.
+// Keys set by several calls take the value of the last one, as they would if the
+// calls ran in arrival order. Calls whose context is done by the time the burst
+// is flushed are left out. The merged call carries the values of the first
+// remaining caller's context, so its namespace budget is charged, but not its
+// cancellation: it is bounded by tagBurstFlushTimeout instead, so one pod giving
+// up does not fail the others' tags. If it fails, every call is retried on its
+// own so each pod gets the error its own tags cause.
+//
+// It wraps the AWS client here rather than in pkg/aws because only the
+// reconciler knows whether an ENI is shared, and pod-exclusive ENIs must not
+// wait for a burst that can never form.
+type TagBurstBuffer struct {
+	client aws.Client
+	delay  time.Duration
+
+	mu     sync.Mutex
+	bursts map[string]*tagBurst
+}
+
+// tagBurstFlushTimeout bounds the merged CreateTags call of a burst.
+const tagBurstFlushTimeout = 30 * time.Second
+
+// tagBurst collects the calls for one ENI until it is flushed.
+type tagBurst struct {
+	calls []*burstCall
+}
+
+// burstCall is one TagENI call waiting for its burst to be flushed.
+type burstCall struct {
+	ctx  context.Context
+	tags map[string]string
+	done chan error
+}
+
+// NewTagBurstBuffer returns a buffer that holds CreateTags calls for delay
+// before sending them through client.
+func NewTagBurstBuffer(client aws.Client, delay time.Duration) (*TagBurstBuffer, error) {
+	if delay <= 0 {
+		return nil, fmt.Errorf("tag burst delay must be positive, got %s", delay)
+	}
+	return &TagBurstBuffer{
+		client: client,
+		delay:  delay,
+		bursts: make(map[string]*tagBurst),
+	}, nil
+}
+
+// TagENI adds tags to the ENI, together with any other calls for it made within
+// the buffer's delay. It returns once the tags are applied or ctx is done; in the
+// latter case the tags are left out of the burst unless it was already being
+// sent.
+func (b *TagBurstBuffer) TagENI(ctx context.Context, eniID string, tags map[string]string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+	call := &burstCall{ctx: ctx, tags: maps.Clone(tags), done: make(chan error, 1)}
+
+	b.mu.Lock()
+	burst, ok := b.bursts[eniID]
+	if !ok {
+		burst = &tagBurst{}
+		b.bursts[eniID] = burst
+		time.AfterFunc(b.delay, func() { b.flush(eniID, burst) })
+	}
+	burst.calls = append(burst.calls, call)
+	b.mu.Unlock()
+
+	select {
+	case err := <-call.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush closes the burst to new calls and applies its tags.
+func (b *TagBurstBuffer) flush(eniID string, burst *tagBurst) {
+	b.mu.Lock()
+	delete(b.bursts, eniID)
+	calls := burst.calls
+	b.mu.Unlock()
+
+	live := calls[:0]
+	for _, call := range calls {
+		if err := call.ctx.Err(); err != nil {
+			call.done <- err
+			continue
+		}
+		live = append(live, call)
+	}
+	switch len(live) {
+	case 0:
+		return
+	case 1:
+		live[0].done <- b.client.TagENI(live[0].ctx, eniID, live[0].tags)
+		return
+	}
+
+	merged := make(map[string]string)
+	for _, call := range live {
+		maps.Copy(merged, call.tags)
+	}
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(live[0].ctx), tagBurstFlushTimeout)
+	err := b.client.TagENI(ctx, eniID, merged)
+	cancel()
+	if err == nil {
+		metrics.TagBurstCallsSavedTotal.Add(float64(len(live) - 1))
+		for _, call := range live {
+			call.done <- nil
+		}
+		return
+	}
+
+	for _, call := range live {
+		call.done <- b.client.TagENI(call.ctx, eniID, call.tags)
+	}
+}
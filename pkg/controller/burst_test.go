@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTagBurstBuffer(t *testing.T) {
+	_, err := NewTagBurstBuffer(new(MockAWSClient), 0)
+	assert.Error(t, err)
+}
+
+func TestTagBurstBuffer(t *testing.T) {
+	tagConcurrently := func(b *TagBurstBuffer, calls ...map[string]string) []error {
+		errs := make([]error, len(calls))
+		var wg sync.WaitGroup
+		for i, tags := range calls {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[i] = b.TagENI(context.Background(), "eni-shared", tags)
+			}()
+		}
+		wg.Wait()
+		return errs
+	}
+
+	t.Run("calls within the delay are merged", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("TagENI", mock.Anything, "eni-shared", map[string]string{"a": "1", "b": "2", "c": "3"}).Return(nil).Once()
+		b, err := NewTagBurstBuffer(mockAWS, 50*time.Millisecond)
+		require.NoError(t, err)
+
+		errs := tagConcurrently(b, map[string]string{"a": "1"}, map[string]string{"b": "2"}, map[string]string{"c": "3"})
+		assert.Equal(t, []error{nil, nil, nil}, errs)
+		mockAWS.AssertExpectations(t)
+	})
+
+	t.Run("a failed merged call is retried per caller", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("TagENI", mock.Anything, "eni-shared", map[string]string{"a": "1", "bad": "x"}).Return(errors.New("rejected")).Once()
+		mockAWS.On("TagENI", mock.Anything, "eni-shared", map[string]string{"a": "1"}).Return(nil).Once()
+		mockAWS.On("TagENI", mock.Anything, "eni-shared", map[string]string{"bad": "x"}).Return(errors.New("rejected")).Once()
+		b, err := NewTagBurstBuffer(mockAWS, 50*time.Millisecond)
+		require.NoError(t, err)
+
+		errs := tagConcurrently(b, map[string]string{"a": "1"}, map[string]string{"bad": "x"})
+		assert.NoError(t, errs[0])
+		assert.EqualError(t, errs[1], "rejected")
+		mockAWS.AssertExpectations(t)
+	})
+
+	t.Run("a cancelled caller is left out of the merged call", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("TagENI", mock.MatchedBy(func(ctx context.Context) bool {
+			_, hasDeadline := ctx.Deadline()
+			return ctx.Err() == nil && hasDeadline
+		}), "eni-shared", map[string]string{"b": "2", "c": "3"}).Return(nil).Once()
+		b, err := NewTagBurstBuffer(mockAWS, 50*time.Millisecond)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancelled := make(chan error, 1)
+		go func() { cancelled <- b.TagENI(ctx, "eni-shared", map[string]string{"a": "1"}) }()
+		require.Eventually(t, func() bool {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			return b.bursts["eni-shared"] != nil
+		}, time.Second, time.Millisecond)
+		cancel()
+		assert.ErrorIs(t, <-cancelled, context.Canceled)
+
+		errs := tagConcurrently(b, map[string]string{"b": "2"}, map[string]string{"c": "3"})
+		assert.Equal(t, []error{nil, nil}, errs)
+		mockAWS.AssertExpectations(t)
+	})
+
+	t.Run("calls after a flush start a new burst", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("TagENI", mock.Anything, "eni-shared", mock.Anything).Return(nil).Twice()
+		b, err := NewTagBurstBuffer(mockAWS, time.Millisecond)
+		require.NoError(t, err)
+
+		require.NoError(t, b.TagENI(context.Background(), "eni-shared", map[string]string{"a": "1"}))
+		require.NoError(t, b.TagENI(context.Background(), "eni-shared", map[string]string{"a": "2"}))
+		mockAWS.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// WarmENICache fills the ENI cache for the pods the controller would tag, read
+// through reader from namespace ("" for all), with batched AWS lookups. Called
+// before the manager starts, it saves the startup reconciles one AWS call per
+// pod. It does nothing without an ENI cache or when the cache is bypassed
+// (TagDiffSourceENI).
+func (r *PodReconciler) WarmENICache(ctx context.Context, reader client.Reader, namespace string) error {
+	if r.ENICache == nil || r.DiffSource == TagDiffSourceENI {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	pods := &corev1.PodList{}
+	if err := reader.List(ctx, pods, client.InNamespace(namespace)); err != nil {
+		return fmt.Errorf("failed to list pods to warm the ENI cache: %w", err)
+	}
+	wanted := make(map[string]string)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.PodIP == "" || pod.Spec.HostNetwork || !pod.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if _, wantsTags := r.tagAnnotation(pod); !wantsTags || r.isPodExcluded(pod) {
+			continue
+		}
+		wanted[pod.Status.PodIP] = string(pod.UID)
+	}
+
+	warmed, err := r.ENICache.Warm(ctx, wanted)
+	if err != nil {
+		return fmt.Errorf("failed to warm the ENI cache (%d of %d pods done): %w", warmed, len(wanted), err)
+	}
+	logger.Info("Warmed ENI cache", "pods", len(wanted), "added", warmed)
+	return nil
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+	enicache "k8s-eni-tagger/pkg/cache"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWarmENICache(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	annotated := map[string]string{AnnotationKey: "team=a"}
+	pod := func(name, ip string, annotations map[string]string, hostNetwork bool) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name), Annotations: annotations},
+			Spec:       corev1.PodSpec{HostNetwork: hostNetwork},
+			Status:     corev1.PodStatus{PodIP: ip},
+		}
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		pod("tagged", "10.0.0.1", annotated, false),
+		pod("plain", "10.0.0.2", nil, false),
+		pod("host", "10.0.0.3", annotated, true),
+		pod("pending", "", annotated, false),
+	).Build()
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIPs", context.Background(), []string{"10.0.0.1"}).
+		Return(map[string]*aws.ENIInfo{"10.0.0.1": {ID: "eni-tagged"}}, nil).Once()
+	cache := enicache.NewENICache(mockAWS)
+	r := &PodReconciler{Client: k8sClient, AWSClient: mockAWS, ENICache: cache, AnnotationKey: AnnotationKey}
+
+	require.NoError(t, r.WarmENICache(context.Background(), k8sClient, ""))
+	mockAWS.AssertExpectations(t)
+
+	// The reconcile of the warmed pod finds its ENI without another call
+	info, err := cache.GetENIInfoByIP(context.Background(), "10.0.0.1", "uid-tagged")
+	require.NoError(t, err)
+	assert.Equal(t, "eni-tagged", info.ID)
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"k8s-eni-tagger/pkg/metrics"
+)
+
+// ConcurrencyLimiter bounds how many reconciles run at once and lets the bound be
+// changed at runtime. controller-runtime fixes its worker count when the controller
+// starts, so the controller is started with Max workers and each reconcile first
+// takes a slot here; lowering the limit parks the surplus workers instead of
+// requiring a restart.
+type ConcurrencyLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	max     int
+	inUse   int
+	changed chan struct{} // closed and replaced whenever a slot may have become available
+}
+
+// NewConcurrencyLimiter returns a limiter allowing limit concurrent reconciles,
+// adjustable between 1 and max.
+func NewConcurrencyLimiter(limit, max int) (*ConcurrencyLimiter, error) {
+	if max < 1 {
+		return nil, fmt.Errorf("max concurrency must be at least 1, got %d", max)
+	}
+	if limit < 1 || limit > max {
+		return nil, fmt.Errorf("concurrency limit must be between 1 and %d, got %d", max, limit)
+	}
+	metrics.ReconcileConcurrencyLimit.Set(float64(limit))
+	return &ConcurrencyLimiter{limit: limit, max: max, changed: make(chan struct{})}, nil
+}
+
+// Acquire blocks until a slot is free or ctx is done.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.inUse < l.limit {
+			l.inUse++
+			l.mu.Unlock()
+			return nil
+		}
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Release returns a slot taken by Acquire.
+func (l *ConcurrencyLimiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inUse--
+	l.notifyLocked()
+}
+
+// SetLimit changes the concurrency limit. Lowering it does not interrupt running
+// reconciles; new ones wait until the number in flight drops below the limit.
+func (l *ConcurrencyLimiter) SetLimit(limit int) error {
+	if limit < 1 || limit > l.max {
+		return fmt.Errorf("concurrency limit must be between 1 and %d, got %d", l.max, limit)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	metrics.ReconcileConcurrencyLimit.Set(float64(limit))
+	l.notifyLocked()
+	return nil
+}
+
+// Limit returns the current concurrency limit.
+func (l *ConcurrencyLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+func (l *ConcurrencyLimiter) notifyLocked() {
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// concurrencyStatus is the JSON body served by ConcurrencyLimiter.ServeHTTP.
+type concurrencyStatus struct {
+	Limit int `json:"limit"`
+	Max   int `json:"max"`
+	InUse int `json:"inUse"`
+}
+
+// ServeHTTP reports the limiter state as JSON on GET and changes the limit on
+// PUT or POST with a "limit" query parameter, e.g. PUT /concurrency?limit=4.
+func (l *ConcurrencyLimiter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		limit, err := strconv.Atoi(req.URL.Query().Get("limit"))
+		if err != nil {
+			http.Error(w, "limit query parameter must be an integer", http.StatusBadRequest)
+			return
+		}
+		if err := l.SetLimit(limit); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	l.mu.Lock()
+	status := concurrencyStatus{Limit: l.limit, Max: l.max, InUse: l.inUse}
+	l.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConcurrencyLimiter_Validation(t *testing.T) {
+	_, err := NewConcurrencyLimiter(1, 0)
+	assert.Error(t, err)
+	_, err = NewConcurrencyLimiter(0, 4)
+	assert.Error(t, err)
+	_, err = NewConcurrencyLimiter(5, 4)
+	assert.Error(t, err)
+
+	l, err := NewConcurrencyLimiter(2, 4)
+	require.NoError(t, err)
+	assert.Equal(t, 2, l.Limit())
+}
+
+func TestConcurrencyLimiter_AcquireBlocksAtLimit(t *testing.T) {
+	l, err := NewConcurrencyLimiter(1, 2)
+	require.NoError(t, err)
+
+	require.NoError(t, l.Acquire(context.Background()))
+
+	// A second acquire times out while the only slot is held
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.Acquire(ctx), context.DeadlineExceeded)
+
+	// Raising the limit unblocks a waiting acquire
+	acquired := make(chan error, 1)
+	go func() { acquired <- l.Acquire(context.Background()) }()
+	require.NoError(t, l.SetLimit(2))
+	select {
+	case err := <-acquired:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("acquire did not unblock after raising the limit")
+	}
+
+	// Lowering the limit holds new work until enough slots are released
+	require.NoError(t, l.SetLimit(1))
+	go func() { acquired <- l.Acquire(context.Background()) }()
+	l.Release()
+	select {
+	case <-acquired:
+		t.Fatal("acquire succeeded while in-flight work still exceeds the limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+	l.Release()
+	select {
+	case err := <-acquired:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("acquire did not unblock after releases")
+	}
+}
+
+func TestConcurrencyLimiter_SetLimitBounds(t *testing.T) {
+	l, err := NewConcurrencyLimiter(1, 3)
+	require.NoError(t, err)
+
+	assert.Error(t, l.SetLimit(0))
+	assert.Error(t, l.SetLimit(4))
+	assert.NoError(t, l.SetLimit(3))
+	assert.Equal(t, 3, l.Limit())
+}
+
+func TestConcurrencyLimiter_ServeHTTP(t *testing.T) {
+	l, err := NewConcurrencyLimiter(1, 8)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	l.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/concurrency?limit=4", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var status concurrencyStatus
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	assert.Equal(t, concurrencyStatus{Limit: 4, Max: 8}, status)
+
+	rec = httptest.NewRecorder()
+	l.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/concurrency?limit=9", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	l.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/concurrency?limit=many", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	l.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/concurrency", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	assert.Equal(t, 4, l.Limit())
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"encoding/json"
+)
+
+// ConditionReason is the machine-readable reason set on the ENI tagged pod condition.
+// Automation should switch on these values rather than on the condition message.
+type ConditionReason string
+
+const (
+	// ReasonSynced means the ENI carries the tags requested by the pod annotation.
+	ReasonSynced ConditionReason = "Synced"
+	// ReasonInvalidTags means the tag annotation could not be parsed or failed validation.
+	ReasonInvalidTags ConditionReason = "InvalidTags"
+	// ReasonENILookupFailed means the pod's ENI could not be resolved from its IP.
+	ReasonENILookupFailed ConditionReason = "ENILookupFailed"
+	// ReasonENIValidationFailed means the ENI is not eligible for tagging (subnet filter, shared ENI).
+	ReasonENIValidationFailed ConditionReason = "ENIValidationFailed"
+	// ReasonTaggingFailed means the EC2 tag calls or the hash conflict check failed.
+	ReasonTaggingFailed ConditionReason = "TaggingFailed"
+	// ReasonForeignController means another controller installation owns the pod's ENI.
+	ReasonForeignController ConditionReason = "ForeignController"
+	// ReasonDeferred means a tag change that can wait, such as a drift repair, waits
+	// for a maintenance window or the next AWS mutation budget window.
+	ReasonDeferred ConditionReason = "Deferred"
+	// ReasonTagPolicyViolation means an AWS Organizations tag policy rejected the tags.
+	ReasonTagPolicyViolation ConditionReason = "TagPolicyViolation"
+	// ReasonENIAttaching means tagging waits for the ENI to be attached and in use.
+	ReasonENIAttaching ConditionReason = "ENIAttaching"
+	// ReasonPaused means tagging is paused cluster-wide and the ENI's tags are not changed.
+	ReasonPaused ConditionReason = "Paused"
+	// ReasonRateLimited means the per-pod rate limit deferred the reconcile to NextAttempt.
+	ReasonRateLimited ConditionReason = "RateLimited"
+	// ReasonBestEffortTaggingFailed means only best-effort tags failed to apply; the
+	// condition stays True since the critical tags are in place. See CriticalTagKeys.
+	ReasonBestEffortTaggingFailed ConditionReason = "BestEffortTaggingFailed"
+)
+
+// ConditionDetails is the structured payload stored as JSON in the condition message.
+// Fields that do not apply to a given reason are omitted.
+type ConditionDetails struct {
+	// Message is a human-readable description.
+	Message string `json:"message"`
+	// ENIID is the pod's ENI, once it has been resolved.
+	ENIID string `json:"eniID,omitempty"`
+	// SubnetID is the ENI's subnet, once the ENI has been resolved.
+	SubnetID string `json:"subnetID,omitempty"`
+	// ErrorCode is the AWS API error code behind a failure, if any.
+	ErrorCode string `json:"errorCode,omitempty"`
+	// Owner is the controller ID owning the ENI (ReasonForeignController only).
+	Owner string `json:"owner,omitempty"`
+	// InvalidTagsPolicy is what happened to previously applied tags (ReasonInvalidTags only).
+	InvalidTagsPolicy InvalidTagsPolicy `json:"invalidTagsPolicy,omitempty"`
+	// TagPolicyID is the violated Organizations tag policy, when AWS names it
+	// (ReasonTagPolicyViolation only).
+	TagPolicyID string `json:"tagPolicyID,omitempty"`
+	// TagPolicyKeys are the tag keys AWS reported as non-compliant
+	// (ReasonTagPolicyViolation only).
+	TagPolicyKeys []string `json:"tagPolicyKeys,omitempty"`
+	// NextAttempt is when the deferred reconcile runs again, in RFC 3339
+	// (ReasonRateLimited only).
+	NextAttempt string `json:"nextAttempt,omitempty"`
+}
+
+// ParseConditionDetails decodes the JSON payload of an ENI tagged condition message.
+func ParseConditionDetails(message string) (ConditionDetails, error) {
+	var details ConditionDetails
+	err := json.Unmarshal([]byte(message), &details)
+	return details, err
+}
+
+// String encodes the details as the JSON condition message.
+func (d ConditionDetails) String() string {
+	// Marshalling a struct of strings cannot fail
+	data, _ := json.Marshal(d)
+	return string(data)
+}
@@ -0,0 +1,31 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionDetailsRoundTrip(t *testing.T) {
+	details := ConditionDetails{
+		Message:   "failed to tag ENI eni-1",
+		ENIID:     "eni-1",
+		SubnetID:  "subnet-1",
+		ErrorCode: "UnauthorizedOperation",
+	}
+
+	parsed, err := ParseConditionDetails(details.String())
+	require.NoError(t, err)
+	assert.Equal(t, details, parsed)
+}
+
+func TestConditionDetailsOmitsEmptyFields(t *testing.T) {
+	assert.Equal(t, `{"message":"invalid tags"}`, ConditionDetails{Message: "invalid tags"}.String())
+}
+
+func TestParseConditionDetails_FreeFormMessage(t *testing.T) {
+	// Conditions written by older versions carry plain English messages
+	_, err := ParseConditionDetails("Successfully tagged ENI eni-1")
+	assert.Error(t, err)
+}
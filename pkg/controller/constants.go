@@ -4,33 +4,81 @@ package controller
 
 import (
 	"context"
-	"regexp"
 	"time"
 )
 
 const (
+	// DefaultKeyDomain is the default domain for the controller's finalizer, condition
+	// type, hash tag and bookkeeping annotations. See Keys.
+	DefaultKeyDomain = "eni-tagger.io"
+
+	// ControllerName is the name of the pod controller. It is the "name" label on the
+	// workqueue_* metrics and the "controller" label on controller_runtime_* metrics,
+	// so changing it breaks dashboards and alerts.
+	ControllerName = "pod"
+
 	// AnnotationKey is the default annotation key that the controller watches for tag specifications.
 	// Pods with this annotation will have their ENIs tagged accordingly.
 	AnnotationKey = "eni-tagger.io/tags"
 
 	// LastAppliedAnnotationKey stores the last successfully applied tags as a JSON string.
 	// This is used to calculate the diff between desired and current state.
-	LastAppliedAnnotationKey = "eni-tagger.io/last-applied-tags"
+	LastAppliedAnnotationKey = DefaultKeyDomain + "/last-applied-tags"
 
 	// finalizerName is the finalizer added to pods to ensure cleanup of ENI tags on deletion.
-	finalizerName = "eni-tagger.io/finalizer"
+	finalizerName = DefaultKeyDomain + "/finalizer"
 
 	// ConditionTypeEniTagged is the pod condition type that indicates ENI tagging status.
 	// The condition status will be True when tags are successfully applied.
-	ConditionTypeEniTagged = "eni-tagger.io/tagged"
+	ConditionTypeEniTagged = DefaultKeyDomain + "/tagged"
 
 	// HashTagKey is the tag key used for optimistic locking to prevent tag thrashing.
 	// The hash value represents the state of all managed tags on the ENI.
-	HashTagKey = "eni-tagger.io/hash"
+	HashTagKey = DefaultKeyDomain + "/hash"
+
+	// OwnerTagKey is the ENI tag key recording which controller installation owns the tags.
+	// See PodReconciler.ControllerID.
+	OwnerTagKey = DefaultKeyDomain + "/owner"
+
+	// SecurityGroupHashTagKey is the security group tag key holding the hash of the
+	// tags applied to it. See PodReconciler.SecurityGroups.
+	SecurityGroupHashTagKey = DefaultKeyDomain + "/sg-hash"
+
+	// VolumeHashTagKey is the EBS volume tag key holding the hash of the tags
+	// applied to it. See PodReconciler.Volumes.
+	VolumeHashTagKey = DefaultKeyDomain + "/volume-hash"
+
+	// ManagedByTagKey is the ENI tag key naming the system and cluster behind the
+	// tags, for people browsing the EC2 console. It is not under DefaultKeyDomain so
+	// it reads like the managed-by tags other tools write. See PodReconciler.ManagedByTag.
+	ManagedByTagKey = "managed-by"
+
+	// ManagedByTagPrefix starts every managed-by tag value; see ManagedByTagValue.
+	ManagedByTagPrefix = "k8s-eni-tagger"
 
 	// LastAppliedHashKey stores the last hash value that was successfully applied.
 	// This is used to detect conflicts when multiple controllers manage the same ENI.
-	LastAppliedHashKey = "eni-tagger.io/last-applied-hash"
+	LastAppliedHashKey = DefaultKeyDomain + "/last-applied-hash"
+
+	// PendingTransitionAnnotationKey records a tag change whose CreateTags and
+	// DeleteTags calls may not both have completed. See pendingTransition.
+	PendingTransitionAnnotationKey = DefaultKeyDomain + "/pending-tags"
+
+	// TagHistoryAnnotationKey stores the most recently applied tag sets, oldest first.
+	// See PodReconciler.TagHistorySize.
+	TagHistoryAnnotationKey = DefaultKeyDomain + "/tag-history"
+
+	// DefaultTagsCanaryAnnotationKey marks pods given canary default tags at
+	// admission, with the canary's revision as value.
+	DefaultTagsCanaryAnnotationKey = DefaultKeyDomain + "/default-tags-canary"
+
+	// DefaultTagsCanaryResultAnnotationKey records on the default tags ConfigMap
+	// whether its last canary was promoted or rolled back.
+	DefaultTagsCanaryResultAnnotationKey = DefaultKeyDomain + "/default-tags-canary-result"
+
+	// MaxTagHistorySize bounds the tag history kept per pod. With up to 50 tags per
+	// set this keeps the annotation well below the 256KiB limit on pod annotations.
+	MaxTagHistorySize = 20
 
 	// MaxTagKeyLength is the maximum length for AWS tag keys (127 characters).
 	MaxTagKeyLength = 127
@@ -41,6 +89,30 @@ const (
 	// MaxTagsPerENI is the maximum number of tags allowed per ENI by AWS (50 tags).
 	MaxTagsPerENI = 50
 
+	// foreignControllerRequeueDelay is how long to wait before re-checking an ENI owned by
+	// another controller. Retrying sooner cannot help until that controller releases it.
+	foreignControllerRequeueDelay = 5 * time.Minute
+
+	// caseConflictRequeueDelay is how long to wait before re-checking a desired tag key
+	// that clashes by case with a foreign ENI tag, in case that tag is removed.
+	caseConflictRequeueDelay = 5 * time.Minute
+
+	// tagPolicyRequeueDelay is how long to wait before retrying tags rejected by an
+	// Organizations tag policy. Retrying sooner cannot help; an annotation edit is
+	// reconciled at once, this only catches policy changes.
+	tagPolicyRequeueDelay = time.Hour
+
+	// bestEffortRequeueDelay is how long to wait before retrying a failed change to
+	// best-effort tags, instead of error backoff.
+	bestEffortRequeueDelay = time.Minute
+
+	// maxForeignKeysInMessage caps how many foreign tag keys are listed in a condition message.
+	maxForeignKeysInMessage = 10
+
+	// maxTagPreviewLength caps the TagsPlanned event message, within the 1 KiB
+	// limit of the events API.
+	maxTagPreviewLength = 1000
+
 	// Retry configuration for untag operations
 	// These constants define the exponential backoff retry strategy for AWS untag operations.
 
@@ -100,16 +172,90 @@ const (
 	LogKeyOperation     = "operation"
 )
 
+// TagKeyCasePolicy controls tag keys that differ only by case (e.g. Team and
+// team). EC2 stores them as distinct tags, but many cost and inventory tools
+// fold case and then see duplicates.
+type TagKeyCasePolicy string
+
+const (
+	// TagKeyCaseAllow applies such keys as given (the default).
+	TagKeyCaseAllow TagKeyCasePolicy = "allow"
+	// TagKeyCaseReject refuses tag sets with such keys, and keys that clash with
+	// a foreign ENI tag, with an InvalidTags condition.
+	TagKeyCaseReject TagKeyCasePolicy = "reject"
+	// TagKeyCaseNormalize folds such keys into one spelling: the first in sort
+	// order within the annotation, or the spelling already on the ENI. Folded
+	// keys must have the same value.
+	TagKeyCaseNormalize TagKeyCasePolicy = "normalize"
+)
+
+// InvalidTagsPolicy decides what happens to the tags previously applied to a pod's
+// ENI when its annotation is edited into something invalid.
+type InvalidTagsPolicy string
+
+const (
+	// InvalidTagsKeep leaves the ENI untouched (the default).
+	InvalidTagsKeep InvalidTagsPolicy = "keep"
+	// InvalidTagsRollback restores the last applied tags on the ENI, undoing any
+	// out-of-band changes to them, and keeps them until the annotation is fixed.
+	InvalidTagsRollback InvalidTagsPolicy = "rollback"
+	// InvalidTagsRemove removes the managed tags from the ENI, as on pod deletion.
+	InvalidTagsRemove InvalidTagsPolicy = "remove"
+)
+
+// TagDiffSource selects the state desired tags are diffed against.
+type TagDiffSource string
+
+const (
+	// TagDiffSourceAnnotation diffs against the last-applied annotation (the
+	// default). Changes made to the ENI outside the controller go unnoticed.
+	TagDiffSourceAnnotation TagDiffSource = "annotation"
+	// TagDiffSourceENI diffs against the tags currently on the ENI, so tags that
+	// were changed or deleted out of band, or lost annotations, are repaired.
+	// ENI info is always read from AWS, bypassing the ENI cache.
+	TagDiffSourceENI TagDiffSource = "eni"
+)
+
+// HostNetworkENIMode selects the ENI tagged for pods with hostNetwork, which
+// share the node's IP rather than having their own.
+type HostNetworkENIMode string
+
+const (
+	// HostNetworkENIPodIP looks up the ENI by the pod's IP like for other pods
+	// (the default). The node's primary ENI usually carries secondary IPs too and
+	// is then rejected as shared.
+	HostNetworkENIPodIP HostNetworkENIMode = "pod-ip"
+	// HostNetworkENIPrimary tags the primary ENI of the node's instance, found
+	// from the Node's provider ID. The ENI is treated as owned by the node's host
+	// network pods rather than as shared.
+	HostNetworkENIPrimary HostNetworkENIMode = "primary-eni"
+)
+
+// DebugPodPolicy decides whether pods created by kubectl debug (see IsDebugPod)
+// are tagged.
+type DebugPodPolicy string
+
+const (
+	// DebugPodsSkip leaves debug pods untagged, like pods matching
+	// ExcludePodSelector (the default).
+	DebugPodsSkip DebugPodPolicy = "skip"
+	// DebugPodsTag tags debug pods like any other annotated pod.
+	DebugPodsTag DebugPodPolicy = "tag"
+)
+
 var (
 	// reservedPrefixes contains AWS reserved tag key prefixes that cannot be used.
+	// AWS reserves "aws:" in every letter case and in every partition (aws-cn and
+	// aws-us-gov included), so matching is case-insensitive and partition-independent.
 	reservedPrefixes = []string{"aws:", "kubernetes.io/cluster/"}
 
-	// tagKeyPattern is the regex pattern for valid AWS tag keys.
-	// AWS allows alphanumeric characters, spaces, and the following: ._-:/=+@
-	tagKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9 +\=._:/@-]{1,127}$`)
-
-	// tagValuePattern is the regex pattern for valid AWS tag values.
-	// AWS allows alphanumeric characters, spaces, and the following: ._-:/=+@
-	// Empty values are allowed (0-255 characters from the allowed character set)
-	tagValuePattern = regexp.MustCompile(`^[a-zA-Z0-9 +\=._:/@-]{0,255}$`)
+	// tagCharAllowed marks the bytes allowed in AWS tag keys and values:
+	// alphanumeric characters, spaces, and the following: ._-:/=+@
+	// A lookup table rather than a regex, since every tag is checked on every reconcile.
+	tagCharAllowed = func() (allowed [256]bool) {
+		for _, c := range "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 ._-:/=+@" {
+			allowed[c] = true
+		}
+		return allowed
+	}()
 )
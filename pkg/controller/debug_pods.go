@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Names kubectl debug gives the containers it adds and the pods it creates to
+// debug a node.
+const (
+	debugContainerPrefix = "debugger-"
+	nodeDebugPodPrefix   = "node-debugger-"
+)
+
+// IsDebugPod reports whether pod looks created by kubectl debug: a copy of a pod
+// made with --copy-to and a debug container image, or a pod debugging a node.
+// Both are unowned. Copies keep the original's annotations, tag annotation
+// included, so without this they would be tagged like the pod they copy.
+//
+// Copies made with --set-image alone carry no debug container and cannot be told
+// apart from other unowned pods; neither can pods debugged in place with ephemeral
+// containers, which keep their ENI and tags.
+func IsDebugPod(pod *corev1.Pod) bool {
+	if len(pod.OwnerReferences) > 0 {
+		return false
+	}
+	if strings.HasPrefix(pod.Name, nodeDebugPodPrefix) {
+		return true
+	}
+	for _, c := range pod.Spec.Containers {
+		if strings.HasPrefix(c.Name, debugContainerPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipsDebugPod reports whether pod is a debug pod left untagged by DebugPods.
+func (r *PodReconciler) skipsDebugPod(pod *corev1.Pod) bool {
+	return r.DebugPods != DebugPodsTag && IsDebugPod(pod)
+}
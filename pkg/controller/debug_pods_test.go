@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestIsDebugPod(t *testing.T) {
+	owned := []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "web-6d4b"}}
+	tests := []struct {
+		name string
+		pod  corev1.Pod
+		want bool
+	}{
+		{
+			name: "copy with a debug container",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-copy"},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web"}, {Name: "debugger-x7k2p"}}},
+			},
+			want: true,
+		},
+		{
+			name: "node debugging pod",
+			pod:  corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "node-debugger-ip-10-0-1-5-abcde"}},
+			want: true,
+		},
+		{
+			name: "owned pod with a debugger-named container",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-6d4b-xyz", OwnerReferences: owned},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "debugger-sidecar"}}},
+			},
+		},
+		{
+			name: "pod debugged with an ephemeral container",
+			pod: corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web"},
+				Spec: corev1.PodSpec{
+					Containers:          []corev1.Container{{Name: "web"}},
+					EphemeralContainers: []corev1.EphemeralContainer{{EphemeralContainerCommon: corev1.EphemeralContainerCommon{Name: "debugger-q2w3e"}}},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsDebugPod(&tt.pod))
+		})
+	}
+}
+
+func TestDebugPodPolicy(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-copy", Namespace: "default", Annotations: map[string]string{AnnotationKey: "team=platform"}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "web"}, {Name: "debugger-x7k2p"}}},
+		Status:     corev1.PodStatus{PodIP: "10.0.0.9"},
+	}
+
+	r := &PodReconciler{AnnotationKey: AnnotationKey}
+	trigger, ok := r.createTrigger(pod)
+	assert.False(t, ok)
+	assert.Equal(t, FilterExcluded, trigger)
+	assert.Equal(t, "pod was created by kubectl debug", r.Plan(context.Background(), pod).Skipped)
+
+	r.DebugPods = DebugPodsTag
+	trigger, ok = r.createTrigger(pod)
+	assert.True(t, ok)
+	assert.Equal(t, TriggerCreated, trigger)
+}
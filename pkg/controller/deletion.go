@@ -2,13 +2,15 @@ package controller
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
+	"time"
 
 	"k8s-eni-tagger/pkg/aws"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
@@ -19,7 +21,15 @@ func (r *PodReconciler) cleanupTagsForPod(ctx context.Context, logger logr.Logge
 	// Safety check for deletion
 	// Only delete if we own the hash (or if hash is missing/empty?)
 	// If hash on ENI matches our last applied hash, we own it.
-	eniHash := eniInfo.Tags[HashTagKey]
+	keys := r.keys()
+
+	// Never clean up tags owned by another installation, even in shared mode
+	if owner := eniInfo.Tags[keys.OwnerTag]; r.ControllerID != "" && owner != "" && owner != r.ControllerID {
+		logger.Info("Skipping cleanup: ENI owned by another controller", "eniID", eniInfo.ID, "owner", owner)
+		return
+	}
+
+	eniHash := eniInfo.Tags[keys.HashTag]
 	shouldDelete := false
 
 	if eniHash == lastAppliedHash {
@@ -37,16 +47,76 @@ func (r *PodReconciler) cleanupTagsForPod(ctx context.Context, logger logr.Logge
 	for k := range lastAppliedTags {
 		tagKeys = append(tagKeys, k)
 	}
-	// Also remove the hash tag
-	tagKeys = append(tagKeys, HashTagKey)
+	// Also remove the hash, owner and managed-by tags
+	tagKeys = append(tagKeys, keys.HashTag)
+	if r.ControllerID != "" {
+		tagKeys = append(tagKeys, keys.OwnerTag)
+	}
+	if r.removeManagedByTag(eniInfo, lastAppliedTags) {
+		tagKeys = append(tagKeys, ManagedByTagKey)
+	}
 
-	if err := r.retryUntagENI(ctx, eniInfo.ID, tagKeys); err != nil {
+	unlock := r.ENILocks.lock(eniInfo.ID, true)
+	err := r.retryUntagENI(ctx, eniInfo.ID, tagKeys)
+	unlock()
+	if err != nil {
 		logger.Error(err, "Failed to cleanup tags, continuing with finalizer removal")
 	} else {
 		logger.Info("Cleaned up tags on pod deletion", "eniID", eniInfo.ID, "tags", tagKeys)
+		r.cleanupSecurityGroupTags(ctx, logger, eniInfo, lastAppliedTags, lastAppliedHash)
 	}
 }
 
+// cleanupRecordedTags removes the tags recorded in the pod's last-applied and
+// pending transition annotations from its ENI, with cleanupTagsForPod's ownership
+// checks. Only a failed ENI lookup is returned; other failures are logged.
+func (r *PodReconciler) cleanupRecordedTags(ctx context.Context, logger logr.Logger, pod *corev1.Pod) error {
+	keys := r.keys()
+	lastAppliedValue := pod.Annotations[keys.LastAppliedTags]
+	lastAppliedHash := pod.Annotations[keys.LastAppliedHash]
+	// Tags of a change interrupted between CreateTags and DeleteTags are cleaned up too
+	pending, err := parsePendingTransition(pod.Annotations[keys.PendingTransition])
+	if err != nil {
+		logger.Error(err, "Failed to parse pending transition annotation, ignoring it", "annotation", keys.PendingTransition)
+	}
+	if (lastAppliedValue == "" && pending == nil) || pod.Status.PodIP == "" {
+		return nil
+	}
+
+	lastAppliedTags, err := parseLastApplied(lastAppliedValue)
+	if err != nil {
+		logger.Error(err, "Failed to unmarshal last-applied-tags annotation, skipping cleanup", "annotation", keys.LastAppliedTags)
+		return nil
+	}
+	if len(lastAppliedTags) == 0 && pending == nil {
+		return nil
+	}
+	var eniInfo *aws.ENIInfo
+	if r.usesPrimaryENI(pod) {
+		eniInfo, err = r.getPrimaryENIInfo(ctx, pod)
+	} else {
+		eniInfo, err = r.AWSClient.GetENIInfoByIP(ctx, pod.Status.PodIP)
+	}
+	if err != nil {
+		return err
+	}
+	tags, hash := pending.cleanupState(lastAppliedTags, lastAppliedHash, eniInfo.Tags[keys.HashTag])
+	if r.usesPrimaryENI(pod) {
+		// Host network pods of a node with the same tags share the primary ENI's tags
+		inUse, err := r.primaryENIHashInUse(ctx, pod, hash)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			logger.Info("Skipping cleanup: tags still applied by other host network pods", "eniID", eniInfo.ID, "hash", hash)
+			return nil
+		}
+	}
+	r.cleanupTagsForPod(ctx, logger, eniInfo, tags, hash)
+	r.cleanupVolumeTags(ctx, logger, pod, tags, hash)
+	return nil
+}
+
 // handlePodDeletion handles cleanup when a pod is being deleted.
 // It removes tags from the ENI if the pod has last-applied-tags and the hash matches,
 // ensuring we only clean up tags that we own. The finalizer is then removed to allow
@@ -58,39 +128,37 @@ func (r *PodReconciler) cleanupTagsForPod(ctx context.Context, logger logr.Logge
 //   - If hash doesn't match and AllowSharedENITagging is false, we skip cleanup
 //
 // The function continues with finalizer removal even if tag cleanup fails to prevent
-// pods from being stuck in terminating state.
+// pods from being stuck in terminating state. While tagging is paused, the finalizer
+// is kept until resume so the pod's tags are still cleaned up, but for no longer
+// than PauseDeletionHold after the pod's deletion; the pod is then released with
+// its tags left on the ENI.
 func (r *PodReconciler) handlePodDeletion(ctx context.Context, pod *corev1.Pod) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
+	keys := r.keys()
 
-	if !controllerutil.ContainsFinalizer(pod, finalizerName) {
+	if !controllerutil.ContainsFinalizer(pod, keys.Finalizer) {
 		return ctrl.Result{}, nil
 	}
-
-	// Clean up tags if we have last-applied-tags
-	lastAppliedValue := pod.Annotations[LastAppliedAnnotationKey]
-	lastAppliedHash := pod.Annotations[LastAppliedHashKey]
-
-	if lastAppliedValue != "" && pod.Status.PodIP != "" {
-		var lastAppliedTags map[string]string
-		if err := json.Unmarshal([]byte(lastAppliedValue), &lastAppliedTags); err != nil {
-			logger.Error(err, "Failed to unmarshal last-applied-tags annotation, skipping cleanup", "annotation", LastAppliedAnnotationKey)
-		} else {
-			if len(lastAppliedTags) > 0 {
-				eniInfo, err := r.AWSClient.GetENIInfoByIP(ctx, pod.Status.PodIP)
-				if err != nil {
-					logger.Error(err, "Failed to get ENI for cleanup, continuing with finalizer removal")
-				} else {
-					r.cleanupTagsForPod(ctx, logger, eniInfo, lastAppliedTags, lastAppliedHash)
-				}
-			}
+	if r.paused() {
+		if remaining := r.PauseDeletionHold - time.Since(pod.DeletionTimestamp.Time); remaining > 0 {
+			logger.Info("Tagging paused, delaying tag cleanup of deleted pod", "releaseIn", remaining.Round(time.Second))
+			return ctrl.Result{RequeueAfter: min(remaining, pausedRequeueDelay)}, nil
 		}
+		logger.Info("Tagging paused past the deletion hold, releasing pod without removing its tags", "tags", pod.Annotations[keys.LastAppliedTags])
+		r.Recorder.Event(pod, corev1.EventTypeWarning, "TagCleanupSkipped", fmt.Sprintf("Tagging paused for longer than %s after deletion, tags were left on the ENI", r.PauseDeletionHold))
+	} else if err := r.cleanupRecordedTags(ctx, logger, pod); err != nil {
+		logger.Error(err, "Failed to get ENI for cleanup, continuing with finalizer removal")
 	}
 
 	// Remove finalizer
-	controllerutil.RemoveFinalizer(pod, finalizerName)
+	controllerutil.RemoveFinalizer(pod, keys.Finalizer)
 	if err := r.Update(ctx, pod); err != nil {
 		return ctrl.Result{}, err
 	}
+	if r.PodWrites != nil {
+		// The update carried any pending writes
+		r.PodWrites.discard(client.ObjectKeyFromObject(pod))
+	}
 
 	// Invalidate cache entry for this pod's IP
 	if r.ENICache != nil && pod.Status.PodIP != "" {
@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// DriftResync periodically compares the tags on the ENIs of all tagged pods with
+// the pods' last applied tags, reading the ENIs from AWS in batches of
+// aws.MaxLocateIPs IPs. Pods whose ENI lost a tag or carries another value, e.g.
+// after an edit in the console or by other tooling, are reconciled again and
+// their ENI is diffed against its live tags, as with TagDiffSourceENI, so the
+// drift is repaired. Repairs are subject to maintenance windows and pausing.
+//
+// Shared ENIs are skipped, since each of their pods applied its own tags.
+type DriftResync struct {
+	interval time.Duration
+	// detect returns the drifted pods; set by PodReconciler.SetupWithManager.
+	detect func(ctx context.Context) ([]*corev1.Pod, error)
+
+	mu      sync.Mutex
+	drifted map[types.NamespacedName]bool
+
+	// events requeues drifted pods.
+	events chan event.GenericEvent
+}
+
+// NewDriftResync returns a drift resync running every interval.
+func NewDriftResync(interval time.Duration) *DriftResync {
+	return &DriftResync{
+		interval: interval,
+		drifted:  make(map[types.NamespacedName]bool),
+		events:   make(chan event.GenericEvent, 100),
+	}
+}
+
+// Start implements manager.Runnable. Without NeedLeaderElection the manager
+// only runs it on the leader, whose controller consumes the events.
+func (d *DriftResync) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("drift-resync")
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			start := time.Now()
+			pods, err := d.detect(ctx)
+			if err != nil {
+				logger.Error(err, "Failed to check ENIs for tag drift")
+				continue
+			}
+			d.requeue(ctx, pods)
+			logger.Info("Checked ENIs for tag drift", "drifted", len(pods), "duration", time.Since(start))
+		}
+	}
+}
+
+// requeue marks pods as drifted, replacing the previous scan's marks, and sends
+// them to the pod controller.
+func (d *DriftResync) requeue(ctx context.Context, pods []*corev1.Pod) {
+	drifted := make(map[types.NamespacedName]bool, len(pods))
+	for _, pod := range pods {
+		drifted[client.ObjectKeyFromObject(pod)] = true
+	}
+	d.mu.Lock()
+	d.drifted = drifted
+	d.mu.Unlock()
+
+	for _, pod := range pods {
+		select {
+		case d.events <- event.GenericEvent{Object: pod}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pending reports whether the pod's ENI drifted and has not been repaired yet.
+func (d *DriftResync) pending(key types.NamespacedName) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.drifted[key]
+}
+
+// done clears the pod's drift mark once its reconcile succeeded.
+func (d *DriftResync) done(key types.NamespacedName) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.drifted, key)
+}
+
+// source returns the channel of drifted pods to requeue.
+func (d *DriftResync) source() source.Source {
+	return &source.Channel{Source: d.events}
+}
+
+// repairingDrift reports whether the pod is reconciled to repair drift found by
+// DriftResync, in which case its ENI is diffed against its live tags.
+func (r *PodReconciler) repairingDrift(pod *corev1.Pod) bool {
+	return r.DriftResync != nil && r.DriftResync.pending(client.ObjectKeyFromObject(pod))
+}
+
+// detectDrift returns the tagged pods whose ENI tags differ from their last
+// applied tags, looked up from AWS rather than the ENI cache.
+func (r *PodReconciler) detectDrift(ctx context.Context) ([]*corev1.Pod, error) {
+	logger := log.FromContext(ctx)
+	keys := r.keys()
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods); err != nil {
+		return nil, fmt.Errorf("failed to list pods to check for tag drift: %w", err)
+	}
+	tagged := make(map[string]*corev1.Pod)
+	lastApplied := make(map[string]map[string]string)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.PodIP == "" || pod.Spec.HostNetwork || pod.DeletionTimestamp != nil {
+			continue
+		}
+		if _, wantsTags := r.tagAnnotation(pod); !wantsTags || r.isPodExcluded(pod) {
+			continue
+		}
+		value := pod.Annotations[keys.LastAppliedTags]
+		if r.StateStore != nil {
+			state, ok, err := r.StateStore.get(ctx, client.ObjectKeyFromObject(pod))
+			if err != nil {
+				return nil, err
+			}
+			if !ok || state.UID != pod.UID {
+				continue
+			}
+			value = state.Tags
+		}
+		tags, err := parseLastApplied(value)
+		if err != nil || len(tags) == 0 {
+			continue
+		}
+		tagged[pod.Status.PodIP] = pod
+		lastApplied[pod.Status.PodIP] = tags
+	}
+
+	var drifted []*corev1.Pod
+	for batch := range slices.Chunk(slices.Sorted(maps.Keys(tagged)), aws.MaxLocateIPs) {
+		infos, err := r.AWSClient.GetENIInfoByIPs(ctx, batch)
+		if err != nil {
+			return drifted, fmt.Errorf("failed to look up ENIs to check for tag drift: %w", err)
+		}
+		for _, ip := range batch {
+			info, ok := infos[ip]
+			if !ok || info.IsShared {
+				continue
+			}
+			missing, changed := tagDrift(lastApplied[ip], info.Tags)
+			if len(missing) == 0 && len(changed) == 0 {
+				continue
+			}
+			pod := tagged[ip]
+			metrics.DriftDetectedTotal.Inc()
+			logger.Info("ENI tags drifted from last applied tags", LogKeyPod, client.ObjectKeyFromObject(pod), LogKeyENIID, info.ID, "missing", missing, "changed", changed)
+			drifted = append(drifted, pod)
+		}
+	}
+	return drifted, nil
+}
+
+// tagDrift returns the keys of applied tags missing from the ENI, and those
+// whose value on the ENI differs, in sorted order.
+func tagDrift(applied, eniTags map[string]string) (missing, changed []string) {
+	for _, k := range slices.Sorted(maps.Keys(applied)) {
+		v, ok := eniTags[k]
+		switch {
+		case !ok:
+			missing = append(missing, k)
+		case v != applied[k]:
+			changed = append(changed, k)
+		}
+	}
+	return missing, changed
+}
@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTagDrift(t *testing.T) {
+	applied := map[string]string{"team": "platform", "env": "prod", "owner": "alice"}
+	missing, changed := tagDrift(applied, map[string]string{"team": "platform", "env": "dev", "other": "x"})
+	assert.Equal(t, []string{"owner"}, missing)
+	assert.Equal(t, []string{"env"}, changed)
+
+	missing, changed = tagDrift(applied, map[string]string{"team": "platform", "env": "prod", "owner": "alice"})
+	assert.Empty(t, missing)
+	assert.Empty(t, changed)
+}
+
+func TestDriftResync(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	desiredHash := computeHash(map[string]string{"team": "platform"})
+	pod := func(name, ip string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+				Annotations: map[string]string{
+					AnnotationKey:            `{"team":"platform"}`,
+					LastAppliedAnnotationKey: `{"team":"platform"}`,
+					LastAppliedHashKey:       desiredHash,
+				},
+				Finalizers: []string{finalizerName},
+			},
+			Status: corev1.PodStatus{PodIP: ip},
+		}
+	}
+	drifted, synced := pod("drifted", "10.0.0.1"), pod("synced", "10.0.0.2")
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(drifted, synced).WithStatusSubresource(drifted, synced).Build()
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIPs", mock.Anything, []string{"10.0.0.1", "10.0.0.2"}).Return(map[string]*aws.ENIInfo{
+		"10.0.0.1": {ID: "eni-drifted", Tags: map[string]string{HashTagKey: desiredHash}},
+		"10.0.0.2": {ID: "eni-synced", Tags: map[string]string{"team": "platform", HashTagKey: desiredHash}},
+	}, nil)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.1").Return(&aws.ENIInfo{
+		ID:   "eni-drifted",
+		Tags: map[string]string{HashTagKey: desiredHash},
+	}, nil)
+	mockAWS.On("TagENI", mock.Anything, "eni-drifted", map[string]string{"team": "platform", HashTagKey: desiredHash}).Return(nil).Once()
+
+	r := &PodReconciler{
+		Client:        k8sClient,
+		Scheme:        scheme,
+		Recorder:      record.NewFakeRecorder(10),
+		AWSClient:     mockAWS,
+		AnnotationKey: AnnotationKey,
+		DriftResync:   NewDriftResync(0),
+	}
+	found, err := r.detectDrift(context.Background())
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "drifted", found[0].Name)
+
+	r.DriftResync.requeue(context.Background(), found)
+	key := client.ObjectKeyFromObject(drifted)
+	assert.True(t, r.DriftResync.pending(key))
+
+	// The requeued reconcile diffs against the live tags, which the annotation
+	// diff would have reported as in sync
+	_, err = r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+	mockAWS.AssertExpectations(t)
+	assert.False(t, r.DriftResync.pending(key))
+}
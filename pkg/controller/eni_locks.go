@@ -0,0 +1,69 @@
+package controller
+
+import "sync"
+
+// ENILocks serializes tag changes per ENI, so two pods on the same shared ENI
+// cannot interleave their CreateTags and DeleteTags calls and leave the ENI with
+// one pod's hash tag and the other's tags. Changes that only add tags hold an
+// ENI's lock shared, since concurrent CreateTags calls commute and the
+// TagBurstBuffer must still be able to merge them; changes that remove tags hold
+// it exclusively. Locks are dropped once no change holds or waits for them.
+//
+// The lock only orders calls made by this replica; other installations are kept
+// out by the owner tag.
+type ENILocks struct {
+	mu    sync.Mutex
+	locks map[string]*eniLock
+}
+
+// eniLock is the lock of one ENI and the number of changes holding or waiting for it.
+type eniLock struct {
+	sync.RWMutex
+	refs int
+}
+
+// NewENILocks returns an empty set of per-ENI locks.
+func NewENILocks() *ENILocks {
+	return &ENILocks{locks: make(map[string]*eniLock)}
+}
+
+// lock locks the ENI, exclusively when the change removes tags, and returns the
+// function unlocking it. A nil ENILocks does not lock.
+func (l *ENILocks) lock(eniID string, exclusive bool) (unlock func()) {
+	if l == nil {
+		return func() {}
+	}
+	l.mu.Lock()
+	el, ok := l.locks[eniID]
+	if !ok {
+		el = &eniLock{}
+		l.locks[eniID] = el
+	}
+	el.refs++
+	l.mu.Unlock()
+
+	if exclusive {
+		el.Lock()
+	} else {
+		el.RLock()
+	}
+	return func() {
+		if exclusive {
+			el.Unlock()
+		} else {
+			el.RUnlock()
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if el.refs--; el.refs == 0 {
+			delete(l.locks, eniID)
+		}
+	}
+}
+
+// size returns the number of ENIs with a change holding or waiting for their lock.
+func (l *ENILocks) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestENILocks(t *testing.T) {
+	l := NewENILocks()
+
+	// Adds to the same ENI run together
+	unlockA := l.lock("eni-1", false)
+	unlockB := l.lock("eni-1", false)
+
+	// A removal waits for them, while other ENIs are not held up
+	removed := make(chan struct{})
+	go func() {
+		unlock := l.lock("eni-1", true)
+		close(removed)
+		unlock()
+	}()
+	l.lock("eni-2", true)()
+
+	unlockA()
+	select {
+	case <-removed:
+		t.Fatal("removal ran while an add still held the ENI")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlockB()
+	select {
+	case <-removed:
+	case <-time.After(time.Second):
+		t.Fatal("removal did not run after the adds finished")
+	}
+	assert.Eventually(t, func() bool { return l.size() == 0 }, time.Second, time.Millisecond)
+
+	var nilLocks *ENILocks
+	nilLocks.lock("eni-1", true)()
+}
+
+func TestENILocksSerializeRemovals(t *testing.T) {
+	l := NewENILocks()
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := l.lock("eni-1", true)
+			defer unlock()
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, maxRunning)
+	assert.Zero(t, l.size())
+}
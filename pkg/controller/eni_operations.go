@@ -3,14 +3,42 @@ package controller
 import (
 	"context"
 	"fmt"
+	"maps"
 	"strings"
+	"time"
 
 	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/metrics"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// foreignControllerError reports that another controller installation owns the ENI's tags.
+type foreignControllerError struct {
+	eniID string
+	owner string
+}
+
+func (e *foreignControllerError) Error() string {
+	return fmt.Sprintf("ENI %s is managed by another controller (owner %q); give each installation its own --key-domain and --annotation-key", e.eniID, e.owner)
+}
+
+// syncedDetails builds the condition payload for a successfully synced ENI.
+func syncedDetails(eniInfo *aws.ENIInfo, message string) ConditionDetails {
+	return ConditionDetails{Message: message, ENIID: eniInfo.ID, SubnetID: eniInfo.SubnetID}
+}
+
+// changeSummary describes an applied change for the Synced condition message, e.g.
+// " (applied 3, removed 1, 120ms)", so application teams can follow tagging with
+// kubectl describe pod. added counts the pod's tags only, not bookkeeping tags.
+func changeSummary(added, removed int, elapsed time.Duration) string {
+	return fmt.Sprintf(" (applied %d, removed %d, %s)", added, removed, elapsed.Round(time.Millisecond))
+}
+
 // retryUntagENI retries untag operations with exponential backoff and context cancellation support
 func (r *PodReconciler) retryUntagENI(ctx context.Context, eniID string, tags []string) error {
 	return retryWithBackoff(ctx, maxUntagRetries, initialRetryBackoff, retryBackoffMultiplier, func() error {
@@ -18,11 +46,26 @@ func (r *PodReconciler) retryUntagENI(ctx context.Context, eniID string, tags []
 	})
 }
 
+// tagENI adds tags to the ENI, through the burst buffer when the ENI is shared
+// and may be tagged by other pods at the same time.
+func (r *PodReconciler) tagENI(ctx context.Context, eniInfo *aws.ENIInfo, tags map[string]string) error {
+	if r.TagBurst != nil && eniInfo.IsShared {
+		return r.TagBurst.TagENI(ctx, eniInfo.ID, tags)
+	}
+	return r.AWSClient.TagENI(ctx, eniInfo.ID, tags)
+}
+
 // getENIInfo retrieves ENI information for a given IP address.
-// Uses cache if available, otherwise queries AWS API.
+// Uses cache if available, otherwise queries AWS API. Diffing against the ENI
+// needs its current tags, so the cache is bypassed with TagDiffSourceENI, when
+// repairing drift and in read-only mode. Host network pods may use their node's primary ENI
+// instead (see HostNetworkENIPrimary).
 func (r *PodReconciler) getENIInfo(ctx context.Context, pod *corev1.Pod) (*aws.ENIInfo, error) {
+	if r.usesPrimaryENI(pod) {
+		return r.getPrimaryENIInfo(ctx, pod)
+	}
 	ip := pod.Status.PodIP
-	if r.ENICache != nil {
+	if r.ENICache != nil && r.DiffSource != TagDiffSourceENI && !r.repairingDrift(pod) && r.ReadOnly == nil {
 		// Use Pod UID for smart cache validation
 		eniInfo, err := r.ENICache.GetENIInfoByIP(ctx, ip, string(pod.UID))
 		if err != nil {
@@ -37,6 +80,33 @@ func (r *PodReconciler) getENIInfo(ctx context.Context, pod *corev1.Pod) (*aws.E
 	return eniInfo, nil
 }
 
+// waitForENIAttachment reports that the ENI is not attached yet and requeues the
+// pod. The ENI is dropped from the cache so the retry sees its current state.
+func (r *PodReconciler) waitForENIAttachment(ctx context.Context, pod *corev1.Pod, eniInfo *aws.ENIInfo) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	logger.V(1).Info("ENI not attached yet, delaying tagging", LogKeyENIID, eniInfo.ID, "status", eniInfo.Status, "attachmentStatus", eniInfo.AttachmentStatus, LogKeyRequeueAfter, r.ENIAttachmentRequeueDelay)
+	if r.ENICache != nil {
+		r.ENICache.Invalidate(ctx, pod.Status.PodIP, string(pod.UID))
+	}
+	message := fmt.Sprintf("ENI %s is not attached yet (status %q, attachment %q), retrying in %s", eniInfo.ID, eniInfo.Status, eniInfo.AttachmentStatus, r.ENIAttachmentRequeueDelay)
+	details := ConditionDetails{Message: message, ENIID: eniInfo.ID, SubnetID: eniInfo.SubnetID}
+	if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, ReasonENIAttaching, details); err != nil {
+		logger.Error(err, "Failed to update status")
+	}
+	return ctrl.Result{RequeueAfter: r.ENIAttachmentRequeueDelay}, nil
+}
+
+// inRepairWindow reports whether bookkeeping annotations are still being rebuilt
+// from ENI tags. See PodReconciler.RepairUntil.
+func (r *PodReconciler) inRepairWindow() bool {
+	return !r.RepairUntil.IsZero() && time.Now().Before(r.RepairUntil)
+}
+
+// paused reports whether ENI tag changes are paused.
+func (r *PodReconciler) paused() bool {
+	return r.Pause != nil && r.Pause.Paused()
+}
+
 // validateENI performs validation checks on the ENI.
 // It checks:
 // - Subnet ID filtering (if configured)
@@ -45,16 +115,20 @@ func (r *PodReconciler) validateENI(ctx context.Context, eniInfo *aws.ENIInfo) e
 	logger := log.FromContext(ctx)
 
 	// Check subnet filtering
-	if len(r.SubnetIDs) > 0 {
+	subnetIDs := r.SubnetIDs
+	if r.SubnetAllowList != nil {
+		subnetIDs = r.SubnetAllowList.IDs()
+	}
+	if len(subnetIDs) > 0 {
 		allowed := false
-		for _, subnet := range r.SubnetIDs {
+		for _, subnet := range subnetIDs {
 			if eniInfo.SubnetID == subnet {
 				allowed = true
 				break
 			}
 		}
 		if !allowed {
-			return fmt.Errorf("ENI %s subnet %s is not in allowed subnet list [%s]", eniInfo.ID, eniInfo.SubnetID, strings.Join(r.SubnetIDs, ", "))
+			return fmt.Errorf("ENI %s subnet %s is not in allowed subnet list [%s]", eniInfo.ID, eniInfo.SubnetID, strings.Join(subnetIDs, ", "))
 		}
 	}
 
@@ -63,8 +137,13 @@ func (r *PodReconciler) validateENI(ctx context.Context, eniInfo *aws.ENIInfo) e
 		logger.Info("Skipping shared ENI (use --allow-shared-eni-tagging to override)",
 			"eniID", eniInfo.ID,
 			"interfaceType", eniInfo.InterfaceType,
+			"class", eniInfo.Class,
 			"description", eniInfo.Description)
-		return fmt.Errorf("ENI %s is shared (multiple IPs), tagging would affect other pods (use --allow-shared-eni-tagging to override)", eniInfo.ID)
+		reason := "multiple IPs"
+		if eniInfo.Class == aws.ENIClassTrunk {
+			reason = "trunk interface"
+		}
+		return fmt.Errorf("ENI %s is shared (%s), tagging would affect other pods (use --allow-shared-eni-tagging to override)", eniInfo.ID, reason)
 	}
 
 	return nil
@@ -74,72 +153,256 @@ func (r *PodReconciler) validateENI(ctx context.Context, eniInfo *aws.ENIInfo) e
 // It calculates the diff between current and desired state and applies only the necessary changes.
 func (r *PodReconciler) applyENITags(ctx context.Context, pod *corev1.Pod, eniInfo *aws.ENIInfo, annotationValue string) error {
 	logger := log.FromContext(ctx)
+	start := time.Now()
+
+	keys := r.keys()
 
 	// Get last applied tags
-	lastAppliedValue := pod.Annotations[LastAppliedAnnotationKey]
-	lastAppliedHash := pod.Annotations[LastAppliedHashKey]
+	lastAppliedValue := pod.Annotations[keys.LastAppliedTags]
+	lastAppliedHash := pod.Annotations[keys.LastAppliedHash]
 
 	// Parse and compare tags
-	currentTags, _, diff, err := r.parseAndCompareTags(ctx, pod, annotationValue, lastAppliedValue)
+	currentTags, lastAppliedTags, diff, err := r.parseAndCompareTags(ctx, pod, annotationValue, lastAppliedValue)
 	if err != nil {
 		return fmt.Errorf("failed to parse and compare tags for pod %s: %w", pod.Name, err)
 	}
 
+	// A change interrupted between CreateTags and DeleteTags may have left tags and
+	// a hash on the ENI that the last-applied annotations do not describe. Its tags
+	// are treated as applied, so unwanted ones are removed, and all desired tags are
+	// written again below since some may never have been.
+	pending, err := parsePendingTransition(pod.Annotations[keys.PendingTransition])
+	if err != nil {
+		logger.Error(err, "Failed to parse pending transition, ignoring it", "value", pod.Annotations[keys.PendingTransition])
+	}
+	if pending != nil {
+		logger.Info("Resuming interrupted tag change", "eniID", eniInfo.ID, "pendingHashes", pending.Hashes)
+		pending.include(lastAppliedTags)
+		diff = computeTagDiff(currentTags, lastAppliedTags)
+	}
+
+	// Refuse to touch ENIs owned by another installation: with identical keys the two
+	// controllers would otherwise overwrite each other's tags indefinitely.
+	eniOwner := eniInfo.Tags[keys.OwnerTag]
+	if r.ControllerID != "" && eniOwner != "" && eniOwner != r.ControllerID {
+		return &foreignControllerError{eniID: eniInfo.ID, owner: eniOwner}
+	}
+	needsIdentityTags := r.ControllerID != "" && eniOwner == ""
+
+	// Keys that differ only by case from a foreign ENI tag would leave both on the ENI.
+	aligned, err := alignKeyCaseWithENI(currentTags, eniInfo.Tags, func(k string) bool {
+		_, ours := lastAppliedTags[k]
+		return ours || k == keys.HashTag || k == keys.OwnerTag || (k == ManagedByTagKey && r.ManagedByTag != "")
+	}, r.TagKeyCase)
+	if err != nil {
+		return err
+	}
+	if !sameKeys(aligned, currentTags) {
+		currentTags = aligned
+		diff = computeTagDiff(currentTags, lastAppliedTags)
+	}
+	if pending != nil {
+		diff.toAdd = maps.Clone(currentTags)
+	}
+
 	// Calculate desired hash
 	desiredHash := computeHash(currentTags)
 
+	// Like the owner tag, the managed-by tag is not part of the hash
+	managedBy := r.managedByTag(currentTags)
+	if managedBy != "" && eniInfo.Tags[ManagedByTagKey] != managedBy {
+		needsIdentityTags = true
+	}
+
+	// With the ENI as the source of truth, out-of-band changes are repaired rather
+	// than reported as hash conflicts; the owner tag still guards other installations.
+	// Drift found by DriftResync is repaired the same way.
+	repairingDrift := r.repairingDrift(pod)
+	liveDiff := r.DiffSource == TagDiffSourceENI || repairingDrift
+	eniInSync := false
+	if liveDiff {
+		diff = computeLiveTagDiff(currentTags, lastAppliedTags, eniInfo.Tags)
+		eniInSync = len(diff.toAdd) == 0 && len(diff.toRemove) == 0 && !needsIdentityTags && eniInfo.Tags[keys.HashTag] == desiredHash
+		if len(diff.toAdd) > 0 || len(diff.toRemove) > 0 {
+			logger.V(1).Info("ENI tags differ from desired state", "eniID", eniInfo.ID, "toAdd", diff.toAdd, "toRemove", diff.toRemove)
+		}
+	}
+
+	// During the startup repair window, rebuild bookkeeping annotations that disagree
+	// with the ENI (e.g. pods restored from backup) instead of reporting a hash
+	// conflict, so only tags that really differ are rewritten.
+	repaired := false
+	if !liveDiff && pending == nil && r.inRepairWindow() {
+		adopted, stale := adoptENITags(eniInfo, keys.HashTag, currentTags, lastAppliedTags, lastAppliedHash)
+		if stale {
+			logger.Info("Rebuilding last-applied annotations from ENI tags", "eniID", eniInfo.ID, "adoptedTags", len(adopted))
+			r.Recorder.Event(pod, corev1.EventTypeNormal, "AnnotationsRepaired", fmt.Sprintf("Adopted %d existing tags from ENI %s", len(adopted), eniInfo.ID))
+			lastAppliedTags = adopted
+			lastAppliedHash = eniInfo.Tags[keys.HashTag]
+			diff = computeTagDiff(currentTags, lastAppliedTags)
+			repaired = true
+		}
+	}
+
 	// Check for hash conflicts
-	if checkHashConflict(eniInfo, desiredHash, lastAppliedHash, r.AllowSharedENITagging) {
-		eniHash := eniInfo.Tags[HashTagKey]
+	if !liveDiff && !pending.owns(eniInfo.Tags[keys.HashTag]) && checkHashConflict(eniInfo, keys.HashTag, desiredHash, lastAppliedHash, r.AllowSharedENITagging) {
+		eniHash := eniInfo.Tags[keys.HashTag]
 		return fmt.Errorf("hash conflict detected on ENI %s: current hash=%s, our last hash=%s (another controller may be managing this ENI)", eniInfo.ID, eniHash, lastAppliedHash)
 	}
 
+	// Report tags owned by someone else so users can see quota pressure on the ENI
+	foreign := foreignTagSummary(eniInfo, keys, managedBy != "", currentTags, lastAppliedTags)
+	if foreign != "" {
+		logger.V(1).Info("ENI carries foreign tags", "eniID", eniInfo.ID, "foreignTags", foreign)
+	}
+
 	// If already synced, nothing to do
-	if desiredHash == lastAppliedHash && len(diff.toAdd) == 0 && len(diff.toRemove) == 0 {
+	if desiredHash == lastAppliedHash && len(diff.toAdd) == 0 && len(diff.toRemove) == 0 && !needsIdentityTags && (!liveDiff || eniInSync) {
+		if repaired || pending != nil {
+			if err := updatePodAnnotations(ctx, r, pod, currentTags, desiredHash); err != nil {
+				return fmt.Errorf("failed to update pod %s annotations after repair: %w", pod.Name, err)
+			}
+		}
 		logger.Info("Tags already in sync", "eniID", eniInfo.ID)
-		if err := r.updateStatus(ctx, pod, corev1.ConditionTrue, "Synced", fmt.Sprintf("ENI %s tags are up to date", eniInfo.ID)); err != nil {
+		if !r.DryRun {
+			r.observeTagHeadroom(ctx, pod, eniInfo.ID, len(eniInfo.Tags))
+		}
+		if err := r.updateStatus(ctx, pod, corev1.ConditionTrue, ReasonSynced, syncedDetails(eniInfo, fmt.Sprintf("ENI %s tags are up to date%s", eniInfo.ID, foreign))); err != nil {
 			return err
 		}
 		return nil
 	}
 
+	// Outside maintenance windows only changes the pod asked for are made; repairs of
+	// tags the pod already had wait for the next window.
+	repairOnly := pending == nil && lastAppliedValue != "" && desiredHash == lastAppliedHash
+	if !r.DryRun && !eniInSync && repairOnly {
+		if now := time.Now(); !r.MaintenanceWindows.Open(now) {
+			return &deferredError{eniID: eniInfo.ID, until: r.MaintenanceWindows.NextOpen(now)}
+		}
+	}
+
+	// Once the EC2 mutation budget is used up, repairs and changes to best-effort
+	// tags only wait for the next budget window.
+	if !r.DryRun && !eniInSync && (repairOnly || r.changePriority(diff) == TagPriorityBestEffort) {
+		if exhausted, next := r.MutationBudget.Exhausted(); exhausted {
+			metrics.AWSMutationBudgetDeferredTotal.Inc()
+			return &deferredError{eniID: eniInfo.ID, until: next, budget: true}
+		}
+	}
+
+	// While paused the diff is still computed and reported, but not applied
+	if !r.DryRun && !eniInSync && r.paused() {
+		return &pausedError{eniID: eniInfo.ID, toAdd: len(diff.toAdd), toRemove: len(diff.toRemove)}
+	}
+
 	// Apply changes
+	summary := ""
+	tagCount := len(eniInfo.Tags)
 	if r.DryRun {
 		logger.Info("DRY RUN: Would apply tags", "eniID", eniInfo.ID, "toAdd", diff.toAdd, "toRemove", diff.toRemove)
+		r.AuditLog.RecordDryRun(ctx, pod.Namespace+"/"+pod.Name, eniInfo.ID, diff.toAdd, diff.toRemove)
+		r.recordDryRunDiff(pod, eniInfo.ID, diff.toAdd, diff.toRemove)
+	} else if eniInSync {
+		// The ENI is correct but the pod's bookkeeping annotations are missing or stale.
+		logger.Info("ENI tags already match, restoring pod annotations", "eniID", eniInfo.ID)
 	} else {
 		// Add hash to tags
 		tagsWithHash := make(map[string]string)
 		for k, v := range diff.toAdd {
 			tagsWithHash[k] = v
 		}
-		tagsWithHash[HashTagKey] = desiredHash
+		tagsWithHash[keys.HashTag] = desiredHash
+		if r.ControllerID != "" {
+			tagsWithHash[keys.OwnerTag] = r.ControllerID
+		}
+		if managedBy != "" {
+			tagsWithHash[ManagedByTagKey] = managedBy
+		}
+
+		// Record the change first when it takes two calls, so a crash between
+		// them is resumed on the next reconcile. That write also adds the
+		// finalizer; otherwise it goes with the annotations below, so a first
+		// sync writes the pod's metadata once.
+		if len(diff.toRemove) > 0 {
+			if err := r.recordPendingTransition(ctx, pod, pending, currentTags, desiredHash); err != nil {
+				return fmt.Errorf("failed to record pending tag change on pod %s: %w", pod.Name, err)
+			}
+		}
 
-		// Apply tag changes
+		// Show a new pod's tags as they will be written, after namespacing, renames
+		// and defaults, before the first call
+		if lastAppliedValue == "" && pending == nil {
+			r.Recorder.Event(pod, corev1.EventTypeNormal, "TagsPlanned", fmt.Sprintf("Applying %d tags to ENI %s: %s", len(tagsWithHash), eniInfo.ID, tagPreview(tagsWithHash)))
+		}
+
+		// Apply tag changes; changes by other pods on the ENI wait for both calls
+		unlock := r.ENILocks.lock(eniInfo.ID, len(diff.toRemove) > 0)
 		if len(tagsWithHash) > 0 {
-			if err := r.AWSClient.TagENI(ctx, eniInfo.ID, tagsWithHash); err != nil {
-				return fmt.Errorf("failed to tag ENI %s with %d tags: %w", eniInfo.ID, len(tagsWithHash), err)
+			if err := r.tagENI(ctx, eniInfo, tagsWithHash); err != nil {
+				unlock()
+				return r.tagChangeError(eniInfo, diff, fmt.Errorf("failed to tag ENI %s with %d tags: %w", eniInfo.ID, len(tagsWithHash), err))
 			}
 		}
 
 		if len(diff.toRemove) > 0 {
 			if err := r.retryUntagENI(ctx, eniInfo.ID, diff.toRemove); err != nil {
-				return fmt.Errorf("failed to untag ENI %s after %d attempts (removed %d tags): %w", eniInfo.ID, maxUntagRetries, len(diff.toRemove), err)
+				unlock()
+				return r.tagChangeError(eniInfo, diff, fmt.Errorf("failed to untag ENI %s after %d attempts (removed %d tags): %w", eniInfo.ID, maxUntagRetries, len(diff.toRemove), err))
 			}
 		}
+		unlock()
 
 		logger.Info("Applied tags to ENI", "eniID", eniInfo.ID, "added", len(tagsWithHash), "removed", len(diff.toRemove))
+		tagCount = tagCountAfter(eniInfo.Tags, tagsWithHash, diff.toRemove)
+		r.syncSecurityGroupTags(ctx, pod, eniInfo, currentTags, lastAppliedTags, desiredHash, lastAppliedHash)
+		r.syncVolumeTags(ctx, pod, currentTags, lastAppliedTags, desiredHash, lastAppliedHash)
+		if repairingDrift {
+			metrics.DriftRepairedTotal.Inc()
+		}
+		summary = changeSummary(len(diff.toAdd), len(diff.toRemove), time.Since(start))
 		r.Recorder.Event(pod, corev1.EventTypeNormal, "TagsApplied", fmt.Sprintf("Applied %d tags to ENI %s", len(currentTags), eniInfo.ID))
 	}
 
-	// Update pod annotations
+	if !r.DryRun {
+		r.observeTagHeadroom(ctx, pod, eniInfo.ID, tagCount)
+	}
+
+	// Update pod annotations (and add the finalizer on the first sync)
+	protected := r.StateStore != nil || controllerutil.ContainsFinalizer(pod, keys.Finalizer)
 	if err := updatePodAnnotations(ctx, r, pod, currentTags, desiredHash); err != nil {
+		if !protected && !r.DryRun && !eniInSync {
+			r.rollbackUnprotectedTags(ctx, err, eniInfo, currentTags, desiredHash)
+		}
 		return fmt.Errorf("failed to update pod %s annotations after successful tagging: %w", pod.Name, err)
 	}
 
 	// Update status
-	if err := r.updateStatus(ctx, pod, corev1.ConditionTrue, "Synced", fmt.Sprintf("Successfully tagged ENI %s", eniInfo.ID)); err != nil {
+	if err := r.updateStatus(ctx, pod, corev1.ConditionTrue, ReasonSynced, syncedDetails(eniInfo, fmt.Sprintf("Successfully tagged ENI %s%s%s", eniInfo.ID, summary, foreign))); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// rollbackUnprotectedTags removes tags just applied to a pod that never got the
+// finalizer, because it was deleted (or started terminating, when no new finalizers
+// may be added) before the annotation write. Nothing else would clean them up.
+// Other write errors are retried by the next reconcile and are left alone.
+func (r *PodReconciler) rollbackUnprotectedTags(ctx context.Context, writeErr error, eniInfo *aws.ENIInfo, appliedTags map[string]string, appliedHash string) {
+	if !apierrors.IsNotFound(writeErr) && !apierrors.IsInvalid(writeErr) {
+		return
+	}
+	logger := log.FromContext(ctx)
+	logger.Info("Pod went away before its finalizer was added, removing the tags just applied", "eniID", eniInfo.ID)
+
+	// eniInfo predates the tagging and may be shared with the cache; cleanup checks
+	// the hash tag written above.
+	applied := *eniInfo
+	applied.Tags = maps.Clone(eniInfo.Tags)
+	if applied.Tags == nil {
+		applied.Tags = make(map[string]string)
+	}
+	applied.Tags[r.keys().HashTag] = appliedHash
+	r.cleanupTagsForPod(ctx, logger, &applied, appliedTags, appliedHash)
+}
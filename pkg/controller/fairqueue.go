@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/metrics"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// fairQueuePollInterval is how often the fair queue tops up the controller's
+// workqueue as workers drain it.
+const fairQueuePollInterval = 50 * time.Millisecond
+
+// FairQueue holds pod events in per-namespace queues and releases them to the
+// controller's workqueue round-robin by namespace, keeping at most Depth pods
+// waiting there. A namespace creating hundreds of pods then delays every other
+// namespace by at most one pod per turn instead of its whole backlog.
+//
+// controller-runtime does not let the workqueue itself be replaced, so FairQueue
+// is the event handler for pods and runs as a manager Runnable that feeds the
+// workqueue. Requeues (errors, RequeueAfter) go straight to the workqueue.
+type FairQueue struct {
+	mu      sync.Mutex
+	depth   int
+	target  workqueue.RateLimitingInterface
+	pending map[string][]reconcile.Request
+	queued  map[types.NamespacedName]struct{}
+	order   []string // namespaces with pending pods, next turn first
+	added   chan struct{}
+}
+
+// NewFairQueue returns a fair queue keeping at most depth pods in the workqueue.
+func NewFairQueue(depth int) (*FairQueue, error) {
+	if depth < 1 {
+		return nil, fmt.Errorf("fair queue depth must be at least 1, got %d", depth)
+	}
+	return &FairQueue{
+		depth:   depth,
+		pending: make(map[string][]reconcile.Request),
+		queued:  make(map[types.NamespacedName]struct{}),
+		added:   make(chan struct{}, 1),
+	}, nil
+}
+
+// Create implements handler.EventHandler.
+func (q *FairQueue) Create(_ context.Context, evt event.CreateEvent, wq workqueue.RateLimitingInterface) {
+	q.enqueue(evt.Object, wq)
+}
+
+// Update implements handler.EventHandler.
+func (q *FairQueue) Update(_ context.Context, evt event.UpdateEvent, wq workqueue.RateLimitingInterface) {
+	if evt.ObjectNew != nil {
+		q.enqueue(evt.ObjectNew, wq)
+		return
+	}
+	q.enqueue(evt.ObjectOld, wq)
+}
+
+// Delete implements handler.EventHandler.
+func (q *FairQueue) Delete(_ context.Context, evt event.DeleteEvent, wq workqueue.RateLimitingInterface) {
+	q.enqueue(evt.Object, wq)
+}
+
+// Generic implements handler.EventHandler.
+func (q *FairQueue) Generic(_ context.Context, evt event.GenericEvent, wq workqueue.RateLimitingInterface) {
+	q.enqueue(evt.Object, wq)
+}
+
+func (q *FairQueue) enqueue(obj client.Object, wq workqueue.RateLimitingInterface) {
+	if obj == nil {
+		return
+	}
+	q.Add(wq, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()}})
+}
+
+// Add queues req behind the other pods of its namespace and records wq as the
+// workqueue to feed. Pods already pending are not queued twice.
+func (q *FairQueue) Add(wq workqueue.RateLimitingInterface, req reconcile.Request) {
+	q.mu.Lock()
+	q.target = wq
+	if _, ok := q.queued[req.NamespacedName]; !ok {
+		q.queued[req.NamespacedName] = struct{}{}
+		if len(q.pending[req.Namespace]) == 0 {
+			q.order = append(q.order, req.Namespace)
+		}
+		q.pending[req.Namespace] = append(q.pending[req.Namespace], req)
+	}
+	q.fillLocked()
+	q.mu.Unlock()
+
+	select {
+	case q.added <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of pods waiting in the fair queue.
+func (q *FairQueue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.queued)
+}
+
+// Start tops up the workqueue until ctx is done. It implements manager.Runnable.
+func (q *FairQueue) Start(ctx context.Context) error {
+	ticker := time.NewTicker(fairQueuePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-q.added:
+		}
+		q.fill()
+	}
+}
+
+func (q *FairQueue) fill() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.fillLocked()
+}
+
+// fillLocked moves pods to the workqueue, one namespace at a time, until it holds
+// depth pods or nothing is pending.
+func (q *FairQueue) fillLocked() {
+	if q.target != nil {
+		for len(q.order) > 0 && q.target.Len() < q.depth {
+			ns := q.order[0]
+			q.order = q.order[1:]
+			reqs := q.pending[ns]
+			req := reqs[0]
+			if len(reqs) > 1 {
+				q.pending[ns] = reqs[1:]
+				q.order = append(q.order, ns)
+			} else {
+				delete(q.pending, ns)
+			}
+			delete(q.queued, req.NamespacedName)
+			q.target.Add(req)
+		}
+	}
+	metrics.FairQueuePending.Set(float64(len(q.queued)))
+}
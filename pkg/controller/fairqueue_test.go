@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func fairReq(namespace, name string) reconcile.Request {
+	return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: namespace, Name: name}}
+}
+
+// drain takes n items from wq, marking each done.
+func drain(t *testing.T, wq workqueue.RateLimitingInterface, n int) []reconcile.Request {
+	t.Helper()
+	var got []reconcile.Request
+	for i := 0; i < n; i++ {
+		item, shutdown := wq.Get()
+		require.False(t, shutdown)
+		got = append(got, item.(reconcile.Request))
+		wq.Done(item)
+	}
+	return got
+}
+
+func TestNewFairQueue_Validation(t *testing.T) {
+	_, err := NewFairQueue(0)
+	assert.Error(t, err)
+}
+
+func TestFairQueue_RoundRobinByNamespace(t *testing.T) {
+	q, err := NewFairQueue(1)
+	require.NoError(t, err)
+	wq := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer wq.ShutDown()
+
+	// A flooding namespace queues first, then two quieter ones arrive.
+	for _, name := range []string{"a", "b", "c", "d"} {
+		q.Add(wq, fairReq("batch", name))
+	}
+	q.Add(wq, fairReq("web", "x"))
+	q.Add(wq, fairReq("api", "y"))
+	q.Add(wq, fairReq("web", "z"))
+	// Duplicates of pending pods are dropped.
+	q.Add(wq, fairReq("batch", "d"))
+
+	assert.Equal(t, 1, wq.Len())
+	assert.Equal(t, 6, q.Len())
+
+	var got []reconcile.Request
+	for q.Len() > 0 || wq.Len() > 0 {
+		got = append(got, drain(t, wq, 1)...)
+		q.fill()
+	}
+	assert.Equal(t, []reconcile.Request{
+		fairReq("batch", "a"),
+		fairReq("batch", "b"),
+		fairReq("web", "x"),
+		fairReq("api", "y"),
+		fairReq("batch", "c"),
+		fairReq("web", "z"),
+		fairReq("batch", "d"),
+	}, got)
+}
+
+func TestFairQueue_EventHandlerAndStart(t *testing.T) {
+	q, err := NewFairQueue(2)
+	require.NoError(t, err)
+	wq := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer wq.ShutDown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = q.Start(ctx) }()
+
+	for _, name := range []string{"p1", "p2", "p3"} {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+		q.Create(ctx, event.CreateEvent{Object: pod}, wq)
+	}
+	assert.Equal(t, 2, wq.Len())
+
+	// Start tops the workqueue up once a worker takes a pod.
+	drain(t, wq, 2)
+	assert.Eventually(t, func() bool { return q.Len() == 0 && wq.Len() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, []reconcile.Request{fairReq("default", "p3")}, drain(t, wq, 1))
+}
@@ -2,75 +2,178 @@ package controller
 
 import (
 	"context"
+	"sync"
 	"testing"
 
+	"k8s-eni-tagger/pkg/aws"
+
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
-func TestEnsureFinalizer(t *testing.T) {
+func TestUpdatePodAnnotations_AddsFinalizer(t *testing.T) {
 	scheme := runtime.NewScheme()
-	err := corev1.AddToScheme(scheme)
-	require.NoError(t, err)
+	require.NoError(t, corev1.AddToScheme(scheme))
 
 	tests := []struct {
-		name          string
-		pod           *corev1.Pod
-		expectUpdated bool
-		expectError   bool
+		name             string
+		keyDomain        string
+		finalizers       []string
+		tags             map[string]string
+		expectFinalizers []string
 	}{
 		{
-			name: "Add finalizer when missing",
-			pod: &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "test-pod",
-					Namespace: "default",
-				},
-			},
-			expectUpdated: true,
-			expectError:   false,
+			name:             "Added with the first tags",
+			tags:             map[string]string{"team": "a"},
+			expectFinalizers: []string{finalizerName},
 		},
 		{
-			name: "Finalizer already present",
-			pod: &corev1.Pod{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:       "test-pod",
-					Namespace:  "default",
-					Finalizers: []string{finalizerName},
-				},
-			},
-			expectUpdated: false,
-			expectError:   false,
+			name:             "Not added without tags",
+			expectFinalizers: nil,
+		},
+		{
+			name:             "Other finalizers are kept",
+			keyDomain:        "team-b.example.com",
+			finalizers:       []string{finalizerName},
+			tags:             map[string]string{"team": "a"},
+			expectFinalizers: []string{finalizerName, "team-b.example.com/finalizer"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tt.pod).Build()
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default", Finalizers: tt.finalizers}}
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+			r := &PodReconciler{Client: k8sClient, Scheme: scheme, KeyDomain: tt.keyDomain}
+			ctx := context.Background()
 
-			r := &PodReconciler{
-				Client: k8sClient,
-				Scheme: scheme,
-			}
+			require.NoError(t, updatePodAnnotations(ctx, r, pod, tt.tags, computeHash(tt.tags)))
 
-			ctx := context.Background()
-			updated, err := r.ensureFinalizer(ctx, tt.pod)
-
-			if tt.expectError {
-				assert.Error(t, err)
-			} else {
-				assert.NoError(t, err)
-				assert.Equal(t, tt.expectUpdated, updated)
-
-				// Verify finalizer was added if expected
-				if tt.expectUpdated {
-					assert.Contains(t, tt.pod.Finalizers, finalizerName)
-				}
-			}
+			stored := &corev1.Pod{}
+			require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+			assert.ElementsMatch(t, tt.expectFinalizers, stored.Finalizers)
 		})
 	}
 }
+
+func TestReconcile_FirstSyncWritesPodTwice(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-new",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationKey: "team=platform"},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	var patches, statusPatches int
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patches++
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+		SubResourcePatch: func(ctx context.Context, c client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			statusPatches++
+			return c.SubResource(subResource).Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+	ctx := context.Background()
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.1").Return(&aws.ENIInfo{ID: "eni-1"}, nil)
+	mockAWS.On("TagENI", mock.Anything, "eni-1", mock.Anything).Return(nil)
+
+	r := &PodReconciler{
+		Client:          k8sClient,
+		Scheme:          scheme,
+		Recorder:        record.NewFakeRecorder(10),
+		AWSClient:       mockAWS,
+		AnnotationKey:   AnnotationKey,
+		PodRateLimiters: &sync.Map{},
+	}
+	res, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+	require.NoError(t, err)
+	assert.False(t, res.Requeue, "tagging should not wait for a separate finalizer write")
+
+	// The finalizer goes in the annotation patch, the condition in the status patch
+	assert.Equal(t, 1, patches)
+	assert.Equal(t, 1, statusPatches)
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+	assert.Contains(t, stored.Finalizers, finalizerName)
+	assert.Equal(t, `{"team":"platform"}`, stored.Annotations[LastAppliedAnnotationKey])
+	assert.True(t, isConditionTrue(stored.Status.Conditions, ConditionTypeEniTagged))
+	mockAWS.AssertExpectations(t)
+}
+
+func TestApplyENITags_RollsBackWhenPodGone(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	// The pod was deleted after it was read, so the annotation write finds nothing.
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-gone",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationKey: "team=platform"},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	eniInfo := &aws.ENIInfo{ID: "eni-1", Tags: map[string]string{"Name": "node"}}
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("TagENI", mock.Anything, "eni-1", mock.Anything).Return(nil)
+	mockAWS.On("UntagENI", mock.Anything, "eni-1", mock.MatchedBy(func(keys []string) bool {
+		return assert.ElementsMatch(t, []string{"team", HashTagKey}, keys)
+	})).Return(nil)
+
+	r := &PodReconciler{Client: k8sClient, Scheme: scheme, Recorder: record.NewFakeRecorder(10), AWSClient: mockAWS}
+	err := r.applyENITags(context.Background(), pod, eniInfo, "team=platform")
+	assert.Error(t, err)
+	assert.NotContains(t, eniInfo.Tags, HashTagKey, "the cached ENIInfo must not be modified")
+	mockAWS.AssertExpectations(t)
+}
+
+func TestApplyENITags_FinalizerInPendingTransitionWrite(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	// Tags applied before the finalizer was kept: the removal is recorded as a
+	// pending transition, and the finalizer goes in that write.
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-change",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationKey:            "team=platform",
+				LastAppliedAnnotationKey: `{"team":"platform","env":"dev"}`,
+				LastAppliedHashKey:       computeHash(map[string]string{"team": "platform", "env": "dev"}),
+			},
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+	ctx := context.Background()
+	eniInfo := &aws.ENIInfo{ID: "eni-1", Tags: map[string]string{"team": "platform", "env": "dev", HashTagKey: computeHash(map[string]string{"team": "platform", "env": "dev"})}}
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("TagENI", mock.Anything, "eni-1", mock.Anything).Run(func(mock.Arguments) {
+		stored := &corev1.Pod{}
+		require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+		assert.Contains(t, stored.Finalizers, finalizerName)
+		assert.NotEmpty(t, stored.Annotations[PendingTransitionAnnotationKey])
+	}).Return(nil)
+	mockAWS.On("UntagENI", mock.Anything, "eni-1", []string{"env"}).Return(nil)
+
+	r := &PodReconciler{Client: k8sClient, Scheme: scheme, Recorder: record.NewFakeRecorder(10), AWSClient: mockAWS}
+	require.NoError(t, r.applyENITags(ctx, pod, eniInfo, "team=platform"))
+	mockAWS.AssertExpectations(t)
+}
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TagHistoryEntry is one tag set applied to a pod's ENI, as stored in the tag
+// history annotation. A removal of all managed tags has no Hash and no Tags.
+type TagHistoryEntry struct {
+	Time metav1.Time       `json:"time"`
+	Hash string            `json:"hash,omitempty"`
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// ParseTagHistory decodes the tag history annotation, oldest entry first.
+func ParseTagHistory(value string) ([]TagHistoryEntry, error) {
+	var history []TagHistoryEntry
+	if value == "" {
+		return history, nil
+	}
+	// A list of entries, each with a tags object
+	if err := checkAnnotationJSON(value, maxTagHistoryAnnotationBytes, 3); err != nil {
+		return nil, fmt.Errorf("invalid tag history: %w", err)
+	}
+	if err := json.Unmarshal([]byte(value), &history); err != nil {
+		return nil, fmt.Errorf("invalid tag history: %w", err)
+	}
+	return history, nil
+}
+
+// appendTagHistory adds tags to the history annotation value, dropping the oldest
+// entries beyond size. An unparsable value is replaced by a new history, and the
+// parse error is returned alongside it.
+func appendTagHistory(value string, tags map[string]string, hash string, size int) (string, error) {
+	history, parseErr := ParseTagHistory(value)
+	history = append(history, TagHistoryEntry{Time: metav1.Now(), Hash: hash, Tags: tags})
+	if size > MaxTagHistorySize {
+		size = MaxTagHistorySize
+	}
+	if len(history) > size {
+		history = history[len(history)-size:]
+	}
+	encoded, err := json.Marshal(history)
+	if err != nil {
+		return value, err
+	}
+	return string(encoded), parseErr
+}
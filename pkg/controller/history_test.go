@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAppendTagHistory(t *testing.T) {
+	value, err := appendTagHistory("", map[string]string{"team": "a"}, "h1", 2)
+	require.NoError(t, err)
+	value, err = appendTagHistory(value, map[string]string{"team": "b"}, "h2", 2)
+	require.NoError(t, err)
+	value, err = appendTagHistory(value, nil, "", 2)
+	require.NoError(t, err)
+
+	history, err := ParseTagHistory(value)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "h2", history[0].Hash)
+	assert.Equal(t, map[string]string{"team": "b"}, history[0].Tags)
+	assert.Empty(t, history[1].Hash)
+	assert.Empty(t, history[1].Tags)
+	assert.False(t, history[1].Time.IsZero())
+
+	// A corrupted annotation is replaced rather than blocking the update.
+	value, err = appendTagHistory("not-json", map[string]string{"team": "c"}, "h3", 2)
+	assert.Error(t, err)
+	history, err = ParseTagHistory(value)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "h3", history[0].Hash)
+}
+
+func TestUpdatePodAnnotationsTagHistory(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+	r := &PodReconciler{Client: k8sClient, TagHistorySize: 5}
+	ctx := context.Background()
+
+	tags := map[string]string{"team": "a"}
+	require.NoError(t, updatePodAnnotations(ctx, r, pod, tags, computeHash(tags)))
+	// Rewriting the same tags (e.g. after a repair) is not a change.
+	require.NoError(t, updatePodAnnotations(ctx, r, pod, tags, computeHash(tags)))
+	require.NoError(t, updatePodAnnotations(ctx, r, pod, nil, ""))
+
+	updated := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), updated))
+	history, err := ParseTagHistory(updated.Annotations[TagHistoryAnnotationKey])
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, tags, history[0].Tags)
+	assert.Equal(t, computeHash(tags), history[0].Hash)
+	assert.Empty(t, history[1].Tags)
+	assert.NotContains(t, updated.Annotations, LastAppliedAnnotationKey)
+}
+
+func TestUpdatePodAnnotations_SkipsUnchanged(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+	r := &PodReconciler{Client: k8sClient}
+	ctx := context.Background()
+
+	tags := map[string]string{"team": "a"}
+	require.NoError(t, updatePodAnnotations(ctx, r, pod, tags, computeHash(tags)))
+	written := pod.ResourceVersion
+	require.NoError(t, updatePodAnnotations(ctx, r, pod, tags, computeHash(tags)))
+	assert.Equal(t, written, pod.ResourceVersion, "unchanged annotations should not be patched")
+}
@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// usesPrimaryENI reports whether the pod's tags go to its node's primary ENI.
+// See HostNetworkENIPrimary.
+func (r *PodReconciler) usesPrimaryENI(pod *corev1.Pod) bool {
+	return pod.Spec.HostNetwork && r.HostNetworkENI == HostNetworkENIPrimary && r.PrimaryENIs != nil
+}
+
+// getPrimaryENIInfo returns the primary ENI of the instance running the pod,
+// read from AWS rather than the ENI cache, which is keyed by pod IP.
+//
+// All host network pods of a node tag the same ENI, and the hash tag is checked
+// as for a dedicated ENI: pods with the same tags share it, while a pod with
+// other tags reports a hash conflict until the tags agree. The ENI is therefore
+// not treated as shared, even though it carries the node IP and, with the VPC
+// CNI, secondary IPs of other pods.
+func (r *PodReconciler) getPrimaryENIInfo(ctx context.Context, pod *corev1.Pod) (*aws.ENIInfo, error) {
+	instanceID, err := r.podInstanceID(ctx, pod)
+	if err != nil {
+		return nil, err
+	}
+	info, err := r.PrimaryENIs.GetPrimaryENIInfo(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get primary ENI of instance %s: %w", instanceID, err)
+	}
+	owned := *info
+	owned.IsShared = false
+	return &owned, nil
+}
+
+// podInstanceID returns the EC2 instance ID of the node running the pod, from
+// the Node's provider ID.
+func (r *PodReconciler) podInstanceID(ctx context.Context, pod *corev1.Pod) (string, error) {
+	if pod.Spec.NodeName == "" {
+		return "", fmt.Errorf("pod %s is not scheduled to a node", pod.Name)
+	}
+	node := &corev1.Node{}
+	if err := r.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", fmt.Errorf("node %s of pod %s not found", pod.Spec.NodeName, pod.Name)
+		}
+		return "", fmt.Errorf("failed to get node %s: %w", pod.Spec.NodeName, err)
+	}
+	instanceID, err := aws.InstanceIDFromProviderID(node.Spec.ProviderID)
+	if err != nil {
+		return "", fmt.Errorf("node %s: %w", node.Name, err)
+	}
+	return instanceID, nil
+}
+
+// primaryENIHashInUse reports whether another host network pod on the pod's node
+// still has hash applied, in which case the tags on the primary ENI are theirs
+// too and are kept when the pod goes away. Host network pods share the node IP,
+// so they are found through the pod IP index.
+func (r *PodReconciler) primaryENIHashInUse(ctx context.Context, pod *corev1.Pod, hash string) (bool, error) {
+	pods, err := PodsByIP(ctx, r, pod.Status.PodIP)
+	if err != nil {
+		return false, err
+	}
+	hostNetwork := pods[:0]
+	for _, other := range pods {
+		if other.Spec.HostNetwork {
+			hostNetwork = append(hostNetwork, other)
+		}
+	}
+	return r.hashInUse(ctx, pod, hostNetwork, hash)
+}
+
+// hashInUse reports whether a pod in pods, other than pod and not being deleted,
+// has hash applied.
+func (r *PodReconciler) hashInUse(ctx context.Context, pod *corev1.Pod, pods []corev1.Pod, hash string) (bool, error) {
+	keys := r.keys()
+	for i := range pods {
+		other := &pods[i]
+		if other.UID == pod.UID || other.DeletionTimestamp != nil {
+			continue
+		}
+		otherHash := other.Annotations[keys.LastAppliedHash]
+		if r.StateStore != nil {
+			state, ok, err := r.StateStore.get(ctx, client.ObjectKeyFromObject(other))
+			if err != nil {
+				return false, err
+			}
+			if !ok || state.UID != other.UID {
+				continue
+			}
+			otherHash = state.Hash
+		}
+		if otherHash == hash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type stubPrimaryENIFinder map[string]*aws.ENIInfo
+
+func (f stubPrimaryENIFinder) GetPrimaryENIInfo(ctx context.Context, instanceID string) (*aws.ENIInfo, error) {
+	return f[instanceID], nil
+}
+
+func TestHostNetworkPrimaryENI(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	hash := computeHash(map[string]string{"team": "platform"})
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0123456789abcdef0"},
+	}
+	hostPod := func(name string, annotations map[string]string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "kube-system", UID: types.UID("uid-" + name), Annotations: annotations, Finalizers: []string{finalizerName}},
+			Spec:       corev1.PodSpec{HostNetwork: true, NodeName: "node-1"},
+			Status:     corev1.PodStatus{PodIP: "10.0.0.10"},
+		}
+	}
+	agent := hostPod("agent", map[string]string{AnnotationKey: "team=platform"})
+	exporter := hostPod("exporter", map[string]string{
+		AnnotationKey:            "team=platform",
+		LastAppliedAnnotationKey: `{"team":"platform"}`,
+		LastAppliedHashKey:       hash,
+	})
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(node, agent, exporter).
+		WithStatusSubresource(agent, exporter).
+		WithIndex(&corev1.Pod{}, PodIPIndexField, podIPs).
+		Build()
+
+	// The primary ENI carries secondary IPs but is tagged for the node's host network pods
+	primary := &aws.ENIInfo{ID: "eni-primary", IsShared: true, Tags: map[string]string{"team": "platform", HashTagKey: hash}}
+	mockAWS := new(MockAWSClient)
+	r := &PodReconciler{
+		Client:         k8sClient,
+		Scheme:         scheme,
+		Recorder:       record.NewFakeRecorder(10),
+		AWSClient:      mockAWS,
+		AnnotationKey:  AnnotationKey,
+		HostNetworkENI: HostNetworkENIPrimary,
+		PrimaryENIs:    stubPrimaryENIFinder{"i-0123456789abcdef0": primary},
+	}
+
+	// Both pods want the same tags, so the agent's match the exporter's hash
+	mockAWS.On("TagENI", mock.Anything, "eni-primary", map[string]string{"team": "platform", HashTagKey: hash}).Return(nil).Once()
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(agent)})
+	require.NoError(t, err)
+	updated := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(agent), updated))
+	assert.Equal(t, hash, updated.Annotations[LastAppliedHashKey])
+	mockAWS.AssertNotCalled(t, "GetENIInfoByIP", mock.Anything, mock.Anything)
+
+	// The tags stay while the agent still has them applied
+	require.NoError(t, r.cleanupRecordedTags(context.Background(), logr.Discard(), exporter))
+	mockAWS.AssertNotCalled(t, "UntagENI", mock.Anything, mock.Anything, mock.Anything)
+
+	// and are removed with the node's last host network pod
+	require.NoError(t, k8sClient.Delete(context.Background(), updated))
+	mockAWS.On("UntagENI", mock.Anything, "eni-primary", mock.Anything).Return(nil).Once()
+	require.NoError(t, r.cleanupRecordedTags(context.Background(), logr.Discard(), exporter))
+	mockAWS.AssertExpectations(t)
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PodIPIndexField is the informer cache index of pods by IP, for reverse lookups
+// from an ENI or an IP to the pods using it without listing every pod.
+const PodIPIndexField = "status.podIP"
+
+// IndexPodIP registers PodIPIndexField with indexer. Every IP in status.podIPs is
+// indexed, so dual-stack pods are found by either address.
+func IndexPodIP(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &corev1.Pod{}, PodIPIndexField, podIPs)
+}
+
+func podIPs(obj client.Object) []string {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+	ips := make([]string, 0, len(pod.Status.PodIPs)+1)
+	if pod.Status.PodIP != "" {
+		ips = append(ips, pod.Status.PodIP)
+	}
+	for _, ip := range pod.Status.PodIPs {
+		if ip.IP != "" && ip.IP != pod.Status.PodIP {
+			ips = append(ips, ip.IP)
+		}
+	}
+	return ips
+}
+
+// PodsByIP returns the pods using ip. c must have PodIPIndexField registered,
+// which SetupWithManager does for the manager's cache.
+func PodsByIP(ctx context.Context, c client.Reader, ip string) ([]corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.MatchingFields{PodIPIndexField: ip}); err != nil {
+		return nil, fmt.Errorf("failed to look up pods by IP %s: %w", ip, err)
+	}
+	return pods.Items, nil
+}
+
+// PodNodeIndexField is the informer cache index of pods by the node they are
+// scheduled to. SetupWithManager registers it when PodReconciler.Volumes is set.
+const PodNodeIndexField = "spec.nodeName"
+
+// IndexPodNode registers PodNodeIndexField with indexer.
+func IndexPodNode(ctx context.Context, indexer client.FieldIndexer) error {
+	return indexer.IndexField(ctx, &corev1.Pod{}, PodNodeIndexField, podNodeName)
+}
+
+func podNodeName(obj client.Object) []string {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Spec.NodeName == "" {
+		return nil
+	}
+	return []string{pod.Spec.NodeName}
+}
+
+// PodsByNode returns the pods scheduled to node. c must have PodNodeIndexField
+// registered.
+func PodsByNode(ctx context.Context, c client.Reader, node string) ([]corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.MatchingFields{PodNodeIndexField: node}); err != nil {
+		return nil, fmt.Errorf("failed to look up pods on node %s: %w", node, err)
+	}
+	return pods.Items, nil
+}
@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPodsByIP(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	single := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "single", Namespace: "default"},
+		Status:     corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	dualStack := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "dual", Namespace: "other"},
+		Status: corev1.PodStatus{
+			PodIP:  "10.0.0.2",
+			PodIPs: []corev1.PodIP{{IP: "10.0.0.2"}, {IP: "fd00::2"}},
+		},
+	}
+	pending := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"}}
+
+	k8sClient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(single, dualStack, pending).
+		WithIndex(&corev1.Pod{}, PodIPIndexField, podIPs).
+		Build()
+	ctx := context.Background()
+
+	pods, err := PodsByIP(ctx, k8sClient, "10.0.0.1")
+	require.NoError(t, err)
+	require.Len(t, pods, 1)
+	assert.Equal(t, "single", pods[0].Name)
+
+	pods, err = PodsByIP(ctx, k8sClient, "fd00::2")
+	require.NoError(t, err)
+	require.Len(t, pods, 1)
+	assert.Equal(t, "dual", pods[0].Name)
+
+	pods, err = PodsByIP(ctx, k8sClient, "10.0.0.9")
+	require.NoError(t, err)
+	assert.Empty(t, pods)
+
+	assert.Equal(t, []string{"10.0.0.2", "fd00::2"}, podIPs(dualStack))
+	assert.Empty(t, podIPs(pending))
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"maps"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Inspection compares the tags a pod asks for with the live tags of its ENI,
+// as printed by k8s-eni-tagger inspect.
+type Inspection struct {
+	// Plan holds the desired tags, computed as by Reconcile.
+	Plan TagPlan `json:"plan"`
+
+	ENIID    string `json:"eniID,omitempty"`
+	SubnetID string `json:"subnetID,omitempty"`
+	ENIClass string `json:"eniClass,omitempty"`
+	Shared   bool   `json:"shared,omitempty"`
+	// ENITags are the tags on the ENI, read from AWS.
+	ENITags map[string]string `json:"eniTags,omitempty"`
+	// ENIError says why the ENI could not be read; Ineligible why the controller
+	// would not tag it (subnet filter, shared ENI).
+	ENIError   string `json:"eniError,omitempty"`
+	Ineligible string `json:"ineligible,omitempty"`
+
+	// ToAdd and ToRemove are the changes the ENI needs, managed and bookkeeping
+	// tags included: tags missing or with another value, and tags last applied
+	// by the controller that the pod no longer asks for.
+	ToAdd    map[string]string `json:"toAdd,omitempty"`
+	ToRemove []string          `json:"toRemove,omitempty"`
+	// Foreign are the keys on the ENI written by others.
+	Foreign []string `json:"foreign,omitempty"`
+}
+
+// InSync reports whether the ENI was read and needs no changes.
+func (i Inspection) InSync() bool {
+	return i.ENIError == "" && len(i.ToAdd) == 0 && len(i.ToRemove) == 0
+}
+
+// Inspect plans pod as Plan does and diffs the result against the tags of its
+// ENI, looked up as Reconcile does. Nothing is written. Last-applied tags are
+// read from the pod's annotations, so with a StateStore removals are not shown.
+func (r *PodReconciler) Inspect(ctx context.Context, pod *corev1.Pod) Inspection {
+	inspection := Inspection{Plan: r.Plan(ctx, pod)}
+	if pod.Status.PodIP == "" && !r.usesPrimaryENI(pod) {
+		inspection.ENIError = "pod has no IP yet"
+		return inspection
+	}
+
+	eniInfo, err := r.getENIInfo(ctx, pod)
+	if err != nil {
+		inspection.ENIError = err.Error()
+		return inspection
+	}
+	inspection.ENIID = eniInfo.ID
+	inspection.SubnetID = eniInfo.SubnetID
+	inspection.ENIClass = eniInfo.Class
+	inspection.Shared = eniInfo.IsShared
+	inspection.ENITags = eniInfo.Tags
+	if err := r.validateENI(ctx, eniInfo); err != nil {
+		inspection.Ineligible = err.Error()
+	}
+
+	plan := inspection.Plan
+	if plan.Skipped != "" || plan.Error != "" {
+		return inspection
+	}
+	desired := maps.Clone(plan.Tags)
+	maps.Copy(desired, plan.Bookkeeping)
+	lastAppliedTags, err := parseLastApplied(pod.Annotations[r.keys().LastAppliedTags])
+	if err != nil {
+		lastAppliedTags = make(map[string]string)
+	}
+	diff := computeLiveTagDiff(desired, lastAppliedTags, eniInfo.Tags)
+	if len(diff.toAdd) > 0 {
+		inspection.ToAdd = diff.toAdd
+	}
+	if len(diff.toRemove) > 0 {
+		inspection.ToRemove = diff.toRemove
+	}
+	managed := maps.Clone(lastAppliedTags)
+	maps.Copy(managed, desired)
+	inspection.Foreign = eniInfo.ForeignTagKeys(managed)
+	return inspection
+}
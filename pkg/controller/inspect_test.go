@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInspect(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "app",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationKey:            "team=platform,env=prod",
+				LastAppliedAnnotationKey: `{"team":"platform","old":"x"}`,
+			},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.1").Return(&aws.ENIInfo{
+		ID:       "eni-1",
+		SubnetID: "subnet-1",
+		Tags:     map[string]string{"team": "platform", "env": "dev", "old": "x", "Name": "node"},
+	}, nil)
+	r := &PodReconciler{AWSClient: mockAWS, AnnotationKey: AnnotationKey}
+
+	inspection := r.Inspect(context.Background(), pod)
+	assert.Empty(t, inspection.ENIError)
+	assert.Equal(t, "eni-1", inspection.ENIID)
+	assert.Equal(t, "subnet-1", inspection.SubnetID)
+	assert.Equal(t, "prod", inspection.ToAdd["env"])
+	assert.NotContains(t, inspection.ToAdd, "team")
+	assert.Contains(t, inspection.ToAdd, HashTagKey)
+	assert.Equal(t, []string{"old"}, inspection.ToRemove)
+	assert.Equal(t, []string{"Name"}, inspection.Foreign)
+	assert.False(t, inspection.InSync())
+
+	r.SubnetIDs = []string{"subnet-2"}
+	assert.NotEmpty(t, r.Inspect(context.Background(), pod).Ineligible)
+
+	pod.Status.PodIP = ""
+	inspection = r.Inspect(context.Background(), pod)
+	assert.Equal(t, "pod has no IP yet", inspection.ENIError)
+	assert.False(t, inspection.InSync())
+
+	pod.Status.PodIP = "10.0.0.2"
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.2").Return(nil, errors.New("not found"))
+	assert.Contains(t, r.Inspect(context.Background(), pod).ENIError, "not found")
+}
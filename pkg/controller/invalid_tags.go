@@ -0,0 +1,145 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// handleInvalidTags reports a tag annotation that failed validation and applies
+// the InvalidTags policy to the tags previously applied to the pod's ENI. The
+// condition's InvalidTagsPolicy field records what actually happened to them.
+func (r *PodReconciler) handleInvalidTags(ctx context.Context, pod *corev1.Pod, validationErr error) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	r.Recorder.Event(pod, corev1.EventTypeWarning, string(ReasonInvalidTags), validationErr.Error())
+
+	details := ConditionDetails{Message: validationErr.Error(), InvalidTagsPolicy: InvalidTagsKeep}
+	var result ctrl.Result
+	var policyErr error
+	if r.InvalidTags == InvalidTagsRollback || r.InvalidTags == InvalidTagsRemove {
+		if r.paused() {
+			// The policy is applied once tagging resumes
+			details.Message += "; previously applied tags left in place: tagging is paused"
+			result.RequeueAfter = pausedRequeueDelay
+		} else {
+			policyErr = r.applyInvalidTagsPolicy(ctx, pod, &details)
+		}
+	}
+
+	if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, ReasonInvalidTags, details); err != nil {
+		logger.Error(err, "Failed to update status", LogKeyPod, pod.Namespace+"/"+pod.Name)
+	}
+	return result, policyErr
+}
+
+// applyInvalidTagsPolicy rolls back or removes the pod's last applied tags on its
+// ENI and records the outcome in details. Tags are left in place if nothing was
+// applied yet or the ENI no longer carries this controller's hash or owner tag.
+func (r *PodReconciler) applyInvalidTagsPolicy(ctx context.Context, pod *corev1.Pod, details *ConditionDetails) error {
+	logger := log.FromContext(ctx)
+	keys := r.keys()
+
+	lastAppliedTags, err := parseLastApplied(pod.Annotations[keys.LastAppliedTags])
+	if err != nil {
+		logger.Error(err, "Failed to parse last applied tags, leaving ENI tags in place", "value", pod.Annotations[keys.LastAppliedTags])
+		return nil
+	}
+	if len(lastAppliedTags) == 0 {
+		return nil
+	}
+	lastAppliedHash := pod.Annotations[keys.LastAppliedHash]
+
+	eniInfo, err := r.getENIInfo(ctx, pod)
+	if err != nil {
+		details.Message += fmt.Sprintf("; previously applied tags left in place: %v", err)
+		details.ErrorCode = aws.ErrorCode(err)
+		return err
+	}
+	details.ENIID = eniInfo.ID
+	details.SubnetID = eniInfo.SubnetID
+	if err := r.validateENI(ctx, eniInfo); err != nil {
+		details.Message += fmt.Sprintf("; previously applied tags left in place: %v", err)
+		return nil
+	}
+
+	if owner := eniInfo.Tags[keys.OwnerTag]; r.ControllerID != "" && owner != "" && owner != r.ControllerID {
+		details.Message += fmt.Sprintf("; previously applied tags left in place: ENI is owned by %q", owner)
+		return nil
+	}
+	if eniHash := eniInfo.Tags[keys.HashTag]; eniHash != lastAppliedHash && !r.AllowSharedENITagging {
+		details.Message += "; previously applied tags left in place: ENI hash changed since they were applied"
+		return nil
+	}
+
+	switch r.InvalidTags {
+	case InvalidTagsRollback:
+		restore := make(map[string]string)
+		for k, v := range lastAppliedTags {
+			if current, ok := eniInfo.Tags[k]; !ok || current != v {
+				restore[k] = v
+			}
+		}
+		details.InvalidTagsPolicy = InvalidTagsRollback
+		details.Message += "; previously applied tags restored"
+		if len(restore) == 0 {
+			return nil
+		}
+		if r.DryRun {
+			logger.Info("DRY RUN: Would restore last applied tags", "eniID", eniInfo.ID, "tags", restore)
+			r.AuditLog.RecordDryRun(ctx, pod.Namespace+"/"+pod.Name, eniInfo.ID, restore, nil)
+			r.recordDryRunDiff(pod, eniInfo.ID, restore, nil)
+			return nil
+		}
+		unlock := r.ENILocks.lock(eniInfo.ID, false)
+		err := r.AWSClient.TagENI(ctx, eniInfo.ID, restore)
+		unlock()
+		if err != nil {
+			details.Message += fmt.Sprintf(" failed: %v", err)
+			details.ErrorCode = aws.ErrorCode(err)
+			return fmt.Errorf("failed to restore %d tags on ENI %s: %w", len(restore), eniInfo.ID, err)
+		}
+		logger.Info("Restored last applied tags after invalid annotation", "eniID", eniInfo.ID, "restored", len(restore))
+		r.Recorder.Event(pod, corev1.EventTypeNormal, "TagsRolledBack", fmt.Sprintf("Restored %d last applied tags on ENI %s", len(restore), eniInfo.ID))
+
+	case InvalidTagsRemove:
+		tagKeys := make([]string, 0, len(lastAppliedTags)+2)
+		for k := range lastAppliedTags {
+			tagKeys = append(tagKeys, k)
+		}
+		tagKeys = append(tagKeys, keys.HashTag)
+		if r.ControllerID != "" {
+			tagKeys = append(tagKeys, keys.OwnerTag)
+		}
+		if r.removeManagedByTag(eniInfo, lastAppliedTags) {
+			tagKeys = append(tagKeys, ManagedByTagKey)
+		}
+		details.InvalidTagsPolicy = InvalidTagsRemove
+		details.Message += "; previously applied tags removed"
+		if r.DryRun {
+			logger.Info("DRY RUN: Would remove managed tags", "eniID", eniInfo.ID, "tags", tagKeys)
+			r.AuditLog.RecordDryRun(ctx, pod.Namespace+"/"+pod.Name, eniInfo.ID, nil, tagKeys)
+			r.recordDryRunDiff(pod, eniInfo.ID, nil, tagKeys)
+			return nil
+		}
+		unlock := r.ENILocks.lock(eniInfo.ID, true)
+		err := r.retryUntagENI(ctx, eniInfo.ID, tagKeys)
+		unlock()
+		if err != nil {
+			details.Message += fmt.Sprintf(" failed: %v", err)
+			details.ErrorCode = aws.ErrorCode(err)
+			return fmt.Errorf("failed to remove %d tags from ENI %s: %w", len(tagKeys), eniInfo.ID, err)
+		}
+		// Nothing is applied any more; a fixed annotation is tagged from scratch.
+		if err := updatePodAnnotations(ctx, r, pod, nil, ""); err != nil {
+			return fmt.Errorf("failed to clear pod %s annotations after removing tags: %w", pod.Name, err)
+		}
+		logger.Info("Removed managed tags after invalid annotation", "eniID", eniInfo.ID, "removed", len(tagKeys))
+		r.Recorder.Event(pod, corev1.EventTypeNormal, "TagsRemoved", fmt.Sprintf("Removed %d managed tags from ENI %s", len(tagKeys), eniInfo.ID))
+	}
+	return nil
+}
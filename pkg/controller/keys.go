@@ -0,0 +1,61 @@
+package controller
+
+// Keys holds the names the controller uses for its own bookkeeping on pods and ENIs.
+// All of them live under a single domain so that independent installations in the
+// same cluster can use different domains and never touch each other's state.
+type Keys struct {
+	// Finalizer is added to tagged pods so ENI tags are cleaned up on deletion.
+	Finalizer string
+	// ConditionType is the pod condition type reporting tagging status.
+	ConditionType string
+	// HashTag is the ENI tag key holding the optimistic-locking hash.
+	HashTag string
+	// OwnerTag is the ENI tag key holding the identity of the controller that owns the tags.
+	OwnerTag string
+	// SecurityGroupHashTag is the security group tag key holding the hash of the
+	// tags applied to it, kept apart from HashTag so ENIs and groups are owned separately.
+	SecurityGroupHashTag string
+	// VolumeHashTag is the same for the EBS volumes of the pod's node.
+	VolumeHashTag string
+	// LastAppliedTags is the pod annotation storing the last applied tags as JSON.
+	LastAppliedTags string
+	// LastAppliedHash is the pod annotation storing the last applied hash.
+	LastAppliedHash string
+	// TagHistory is the pod annotation storing recently applied tag sets.
+	TagHistory string
+	// PendingTransition is the pod annotation recording a tag change in progress.
+	PendingTransition string
+	// DefaultTagsCanary is the pod annotation marking pods given canary default tags.
+	DefaultTagsCanary string
+	// DefaultTagsCanaryResult is the default tags ConfigMap annotation recording
+	// the outcome of the last canary.
+	DefaultTagsCanaryResult string
+}
+
+// NewKeys returns the bookkeeping keys for the given domain.
+// An empty domain falls back to DefaultKeyDomain, which yields the
+// package-level constants (finalizer, HashTagKey, and so on).
+func NewKeys(domain string) Keys {
+	if domain == "" {
+		domain = DefaultKeyDomain
+	}
+	return Keys{
+		Finalizer:               domain + "/finalizer",
+		ConditionType:           domain + "/tagged",
+		HashTag:                 domain + "/hash",
+		OwnerTag:                domain + "/owner",
+		SecurityGroupHashTag:    domain + "/sg-hash",
+		VolumeHashTag:           domain + "/volume-hash",
+		LastAppliedTags:         domain + "/last-applied-tags",
+		LastAppliedHash:         domain + "/last-applied-hash",
+		TagHistory:              domain + "/tag-history",
+		PendingTransition:       domain + "/pending-tags",
+		DefaultTagsCanary:       domain + "/default-tags-canary",
+		DefaultTagsCanaryResult: domain + "/default-tags-canary-result",
+	}
+}
+
+// keys returns the bookkeeping keys for the reconciler's configured domain.
+func (r *PodReconciler) keys() Keys {
+	return NewKeys(r.KeyDomain)
+}
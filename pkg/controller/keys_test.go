@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKeys(t *testing.T) {
+	t.Run("empty domain yields package defaults", func(t *testing.T) {
+		keys := NewKeys("")
+		assert.Equal(t, finalizerName, keys.Finalizer)
+		assert.Equal(t, ConditionTypeEniTagged, keys.ConditionType)
+		assert.Equal(t, HashTagKey, keys.HashTag)
+		assert.Equal(t, SecurityGroupHashTagKey, keys.SecurityGroupHashTag)
+		assert.Equal(t, VolumeHashTagKey, keys.VolumeHashTag)
+		assert.Equal(t, LastAppliedAnnotationKey, keys.LastAppliedTags)
+		assert.Equal(t, LastAppliedHashKey, keys.LastAppliedHash)
+		assert.Equal(t, TagHistoryAnnotationKey, keys.TagHistory)
+		assert.Equal(t, PendingTransitionAnnotationKey, keys.PendingTransition)
+		assert.Equal(t, DefaultTagsCanaryAnnotationKey, keys.DefaultTagsCanary)
+		assert.Equal(t, DefaultTagsCanaryResultAnnotationKey, keys.DefaultTagsCanaryResult)
+	})
+
+	t.Run("custom domain", func(t *testing.T) {
+		keys := NewKeys("team-b.example.com")
+		assert.Equal(t, Keys{
+			Finalizer:               "team-b.example.com/finalizer",
+			ConditionType:           "team-b.example.com/tagged",
+			HashTag:                 "team-b.example.com/hash",
+			OwnerTag:                "team-b.example.com/owner",
+			SecurityGroupHashTag:    "team-b.example.com/sg-hash",
+			VolumeHashTag:           "team-b.example.com/volume-hash",
+			LastAppliedTags:         "team-b.example.com/last-applied-tags",
+			LastAppliedHash:         "team-b.example.com/last-applied-hash",
+			TagHistory:              "team-b.example.com/tag-history",
+			PendingTransition:       "team-b.example.com/pending-tags",
+			DefaultTagsCanary:       "team-b.example.com/default-tags-canary",
+			DefaultTagsCanaryResult: "team-b.example.com/default-tags-canary-result",
+		}, keys)
+	})
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// labelTags returns the tags projected from the pod's labels by TagFromLabels,
+// or nil when none of the mapped labels is set.
+func (r *PodReconciler) labelTags(pod *corev1.Pod) map[string]string {
+	var tags map[string]string
+	for label, tagKey := range r.TagFromLabels {
+		value, ok := pod.Labels[label]
+		if !ok {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string]string, len(r.TagFromLabels))
+		}
+		tags[tagKey] = value
+	}
+	return tags
+}
+
+// tagAnnotation returns the pod's tag annotation and whether the pod asks to be
+// tagged at all: it has the annotation, or a label mapped by TagFromLabels.
+func (r *PodReconciler) tagAnnotation(pod *corev1.Pod) (string, bool) {
+	value, ok := pod.Annotations[r.annotationKey()]
+	return value, ok || len(r.labelTags(pod)) > 0
+}
+
+// validatePodTags is validateTags for the pod's annotation, with value templates
+// rendered, except that a missing or empty annotation is valid when labels
+// provide tags.
+func (r *PodReconciler) validatePodTags(ctx context.Context, pod *corev1.Pod, annotationValue string) error {
+	if strings.TrimSpace(annotationValue) == "" && len(r.labelTags(pod)) > 0 {
+		return nil
+	}
+	tags, err := r.podTags(ctx, pod, annotationValue)
+	if err != nil {
+		return err
+	}
+	return validateTagSet(tags, r.TagKeyRenames, r.TagKeyCase)
+}
+
+// mergeLabelTags adds the tags projected from pod labels to the annotation's
+// tags, which win on conflicting keys, and checks the result against AWS limits.
+func mergeLabelTags(tags, labelTags map[string]string) (map[string]string, error) {
+	merged := make(map[string]string, len(labelTags)+len(tags))
+	maps.Copy(merged, labelTags)
+	maps.Copy(merged, tags)
+	if _, err := validateParsedTags(merged); err != nil {
+		return nil, fmt.Errorf("invalid tags from pod labels: %w", err)
+	}
+	return merged, nil
+}
+
+// labelTagsChanged reports whether a label mapped by TagFromLabels was added,
+// changed or removed.
+func (r *PodReconciler) labelTagsChanged(oldPod, newPod *corev1.Pod) bool {
+	for label := range r.TagFromLabels {
+		oldValue, oldOK := oldPod.Labels[label]
+		newValue, newOK := newPod.Labels[label]
+		if oldOK != newOK || oldValue != newValue {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTagFromLabels checks that every tag key labels are mapped to is a
+// valid tag key, so a bad mapping fails startup instead of every labeled pod.
+func ValidateTagFromLabels(mapping map[string]string) error {
+	keys := make(map[string]string, len(mapping))
+	for _, tagKey := range mapping {
+		keys[tagKey] = ""
+	}
+	_, err := validateParsedTags(keys)
+	return err
+}
@@ -0,0 +1,82 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileTagFromLabels(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	labelsOnly := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "pod-labels",
+			Namespace:  "default",
+			Labels:     map[string]string{"team": "platform", "cost-center": "1234", "app": "web"},
+			Finalizers: []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.11"},
+	}
+	overridden := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-labels-annotated",
+			Namespace:   "default",
+			Labels:      map[string]string{"team": "platform"},
+			Annotations: map[string]string{AnnotationKey: `{"team":"billing","env":"prod"}`},
+			Finalizers:  []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.12"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(labelsOnly, overridden).Build()
+
+	fromLabels := map[string]string{"team": "team", "cost-center": "CostCenter"}
+	overriddenTags := map[string]string{"team": "billing", "env": "prod"}
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.11").Return(&aws.ENIInfo{ID: "eni-labels"}, nil)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.12").Return(&aws.ENIInfo{ID: "eni-annotated"}, nil)
+	mockAWS.On("TagENI", mock.Anything, "eni-labels", map[string]string{
+		"team":       "platform",
+		"CostCenter": "1234",
+		HashTagKey:   computeHash(map[string]string{"team": "platform", "CostCenter": "1234"}),
+	}).Return(nil).Once()
+	mockAWS.On("TagENI", mock.Anything, "eni-annotated", map[string]string{
+		"team":     "billing",
+		"env":      "prod",
+		HashTagKey: computeHash(overriddenTags),
+	}).Return(nil).Once()
+
+	r := &PodReconciler{
+		Client:        k8sClient,
+		Scheme:        scheme,
+		Recorder:      record.NewFakeRecorder(10),
+		AWSClient:     mockAWS,
+		AnnotationKey: AnnotationKey,
+		TagFromLabels: fromLabels,
+	}
+	for _, name := range []string{"pod-labels", "pod-labels-annotated"} {
+		_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKey{Name: name, Namespace: "default"}})
+		require.NoError(t, err)
+	}
+	mockAWS.AssertExpectations(t)
+
+	updated := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(labelsOnly), updated))
+	assert.JSONEq(t, `{"team":"platform","CostCenter":"1234"}`, updated.Annotations[LastAppliedAnnotationKey])
+
+	require.NoError(t, ValidateTagFromLabels(fromLabels))
+	assert.Error(t, ValidateTagFromLabels(map[string]string{"team": "aws:team"}))
+}
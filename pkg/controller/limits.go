@@ -0,0 +1,69 @@
+package controller
+
+import "fmt"
+
+// Annotations are user-editable, so their size and shape are checked before they
+// are parsed. Kubernetes caps a pod's annotations at 256KiB in total, but the plan
+// endpoint and the state store ConfigMap accept more, and even 256KiB of nested
+// brackets is costly to decode on every reconcile.
+const (
+	// MaxTagAnnotationBytes bounds the tag annotation. It also keeps the
+	// regular-expression free character checks cheap.
+	MaxTagAnnotationBytes = 10000
+
+	// maxBookkeepingAnnotationBytes bounds the last-applied and pending tag
+	// annotations, which hold at most MaxTagsPerENI tags and a few hashes.
+	maxBookkeepingAnnotationBytes = 64 << 10
+
+	// maxTagHistoryAnnotationBytes bounds the tag history annotation: the 256KiB
+	// Kubernetes limit on all of a pod's annotations.
+	maxTagHistoryAnnotationBytes = 256 << 10
+)
+
+// checkAnnotationSize rejects annotation values longer than maxBytes.
+func checkAnnotationSize(value string, maxBytes int) error {
+	if len(value) > maxBytes {
+		return fmt.Errorf("annotation value too long (%d bytes, max %d)", len(value), maxBytes)
+	}
+	return nil
+}
+
+// checkJSONDepth rejects JSON nesting objects and arrays deeper than maxDepth,
+// e.g. 1 for an object of strings, without decoding it. Brackets inside strings
+// are ignored; malformed JSON is left for the decoder to report.
+func checkJSONDepth(value string, maxDepth int) error {
+	depth := 0
+	inString, escaped := false, false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case inString:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+			if depth > maxDepth {
+				return fmt.Errorf("annotation JSON nested deeper than %d levels", maxDepth)
+			}
+		case c == '}' || c == ']':
+			depth--
+		}
+	}
+	return nil
+}
+
+// checkAnnotationJSON applies checkAnnotationSize and checkJSONDepth.
+func checkAnnotationJSON(value string, maxBytes, maxDepth int) error {
+	if err := checkAnnotationSize(value, maxBytes); err != nil {
+		return err
+	}
+	return checkJSONDepth(value, maxDepth)
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckJSONDepth(t *testing.T) {
+	for value, depth := range map[string]int{
+		`{"a":"b"}`:                  1,
+		`{"a":"{[{[{["}`:             1, // brackets in strings do not count
+		`{"a":"\"{"}`:                1,
+		`[{"tags":{"a":"b"}}]`:       3,
+		`{"hashes":["h"],"tags":{}}`: 2,
+		strings.Repeat("[", 100000):  100000,
+		`not json`:                   0,
+	} {
+		assert.NoError(t, checkJSONDepth(value, depth), value)
+		if depth > 0 {
+			assert.Error(t, checkJSONDepth(value, depth-1), value)
+		}
+	}
+}
+
+func TestParseAnnotationGuards(t *testing.T) {
+	nested := strings.Repeat(`{"a":`, 10000) + `"b"` + strings.Repeat("}", 10000)
+
+	_, err := parseTags(`{"team":` + nested[:1000] + `}`)
+	assert.ErrorContains(t, err, "nested")
+	_, err = parseTags("team=" + strings.Repeat("a", MaxTagAnnotationBytes))
+	assert.ErrorContains(t, err, "too long")
+	_, err = parseTags(strings.Repeat(",", 100000)[:MaxTagAnnotationBytes])
+	assert.ErrorContains(t, err, "too many tags")
+
+	_, err = parseLastApplied(nested)
+	assert.ErrorContains(t, err, "nested")
+	_, err = parseLastApplied(`{"team":"` + strings.Repeat("a", maxBookkeepingAnnotationBytes) + `"}`)
+	assert.ErrorContains(t, err, "too long")
+	_, err = parsePendingTransition(nested)
+	assert.ErrorContains(t, err, "nested")
+	_, err = ParseTagHistory(nested)
+	assert.ErrorContains(t, err, "nested")
+
+	// Values written by the controller itself still parse.
+	value, err := appendTagHistory("", map[string]string{"team": "a"}, "h1", MaxTagHistorySize)
+	require.NoError(t, err)
+	_, err = ParseTagHistory(value)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TagValueMacros are the values of the $cluster, $region and $account macros in
+// annotation tag values, e.g. {"Cluster":"$cluster","TaggedAt":"$now"}. $now,
+// the time the tag is first applied, needs no configuration. A macro whose
+// value is empty cannot be used.
+type TagValueMacros struct {
+	Cluster string
+	Region  string
+	// Account is the AWS account. When empty, AccountLookup finds it the first
+	// time a tag uses $account.
+	Account       string
+	AccountLookup *AccountLookup
+}
+
+const (
+	// accountLookupTimeout bounds an account lookup.
+	accountLookupTimeout = 10 * time.Second
+	// accountLookupRetry is how long a failed account lookup is reused before
+	// the next use of $account looks it up again.
+	accountLookupRetry = time.Minute
+)
+
+// AccountLookup looks up the AWS account for the $account macro the first time a
+// tag uses it, so installations that never use the macro make no call for it.
+// The account is kept once found; a failure is returned for accountLookupRetry
+// before another lookup. It is safe for concurrent use.
+type AccountLookup struct {
+	lookup func(ctx context.Context) (string, error)
+
+	mu       sync.Mutex
+	account  string
+	err      error
+	failedAt time.Time
+}
+
+// NewAccountLookup returns an AccountLookup calling lookup, e.g.
+// aws.IdentityFinder.AccountID.
+func NewAccountLookup(lookup func(ctx context.Context) (string, error)) *AccountLookup {
+	return &AccountLookup{lookup: lookup}
+}
+
+// Account returns the AWS account, looking it up on first use.
+func (a *AccountLookup) Account() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.account != "" {
+		return a.account, nil
+	}
+	if a.err != nil && time.Since(a.failedAt) < accountLookupRetry {
+		return "", a.err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), accountLookupTimeout)
+	defer cancel()
+	account, err := a.lookup(ctx)
+	if err == nil && account == "" {
+		err = errors.New("no AWS account returned")
+	}
+	if err != nil {
+		a.err, a.failedAt = err, time.Now()
+		return "", err
+	}
+	a.account, a.err = account, nil
+	return account, nil
+}
+
+// tagMacroPattern matches a macro name. "$" is not allowed in tag values, so no
+// valid value written before macros is read differently.
+var tagMacroPattern = regexp.MustCompile(`\$[a-z]+`)
+
+// macroTimestampPattern matches the values $now expands to.
+const macroTimestampPattern = `\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z`
+
+// expandTags returns tags with the macros in their values replaced, $now by now
+// in RFC 3339 (UTC, seconds). Keys are never expanded.
+func (m TagValueMacros) expandTags(tags map[string]string, now time.Time) (map[string]string, error) {
+	expanded := make(map[string]string, len(tags))
+	for key, value := range tags {
+		if !strings.Contains(value, "$") {
+			expanded[key] = value
+			continue
+		}
+		var err error
+		expanded[key] = tagMacroPattern.ReplaceAllStringFunc(value, func(macro string) string {
+			v, macroErr := m.value(macro, now)
+			if macroErr != nil && err == nil {
+				err = fmt.Errorf("tag %q: %w", key, macroErr)
+			}
+			return v
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return expanded, nil
+}
+
+func (m TagValueMacros) value(macro string, now time.Time) (string, error) {
+	var v, flag string
+	switch macro {
+	case "$now":
+		return now.UTC().Format(time.RFC3339), nil
+	case "$cluster":
+		v, flag = m.Cluster, "--cluster-name"
+	case "$region":
+		v, flag = m.Region, "an AWS region"
+	case "$account":
+		v, flag = m.Account, "the AWS account"
+		if v == "" && m.AccountLookup != nil {
+			account, err := m.AccountLookup.Account()
+			if err != nil {
+				return "", fmt.Errorf("macro %s needs %s: %w", macro, flag, err)
+			}
+			return account, nil
+		}
+	default:
+		return "", fmt.Errorf("unknown macro %s, expected $now, $cluster, $region or $account", macro)
+	}
+	if v == "" {
+		return "", fmt.Errorf("macro %s needs %s", macro, flag)
+	}
+	return v, nil
+}
+
+// keepMacroTimestamps replaces, in place, the values of pod's tags that $now
+// expanded to the current time with their value in previous (the last applied
+// tags) if it was expanded from the same annotation value, so a first-tagged-at
+// tag keeps the time it was first applied instead of changing on every
+// reconcile. Values rendered from templates always take the new time.
+func (r *PodReconciler) keepMacroTimestamps(pod *corev1.Pod, annotationValue string, tags, previous map[string]string) {
+	if !strings.Contains(annotationValue, "$now") || len(previous) == 0 {
+		return
+	}
+	raw, err := splitTags(annotationValue)
+	if err != nil {
+		return
+	}
+	for key, value := range tags {
+		prev, ok := previous[key]
+		if !ok || prev == value {
+			continue
+		}
+		p := r.TagMacros.timestampPattern(raw[r.annotationTagKey(pod, key, raw)])
+		if p != nil && p.MatchString(value) && p.MatchString(prev) {
+			tags[key] = prev
+		}
+	}
+}
+
+// annotationTagKey returns the key in the pod's annotation tags raw that the
+// desired tag key was derived from, undoing the namespace prefix and renames.
+func (r *PodReconciler) annotationTagKey(pod *corev1.Pod, key string, raw map[string]string) string {
+	if r.TagNamespace == "enable" {
+		key = strings.TrimPrefix(key, pod.Namespace+":")
+	}
+	for from, to := range r.TagKeyRenames {
+		if _, ok := raw[from]; ok && to == key {
+			return from
+		}
+	}
+	return key
+}
+
+// timestampPattern returns a pattern matching value with its macros expanded
+// and $now at any time, or nil if value has no $now or other macros that
+// cannot be expanded.
+func (m TagValueMacros) timestampPattern(value string) *regexp.Regexp {
+	if !strings.Contains(value, "$now") {
+		return nil
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	last := 0
+	for _, loc := range tagMacroPattern.FindAllStringIndex(value, -1) {
+		b.WriteString(regexp.QuoteMeta(value[last:loc[0]]))
+		last = loc[1]
+		macro := value[loc[0]:loc[1]]
+		if macro == "$now" {
+			b.WriteString(macroTimestampPattern)
+			continue
+		}
+		v, err := m.value(macro, time.Time{})
+		if err != nil {
+			return nil
+		}
+		b.WriteString(regexp.QuoteMeta(v))
+	}
+	b.WriteString(regexp.QuoteMeta(value[last:]))
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestTagValueMacros(t *testing.T) {
+	m := TagValueMacros{Cluster: "prod-eu", Region: "eu-west-1", Account: "111111111111"}
+	now := time.Date(2026, 3, 1, 12, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	tags, err := m.expandTags(map[string]string{
+		"TaggedAt": "$now",
+		"Where":    "$cluster/$region/$account",
+		"Team":     "platform",
+	}, now)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"TaggedAt": "2026-03-01T11:30:00Z",
+		"Where":    "prod-eu/eu-west-1/111111111111",
+		"Team":     "platform",
+	}, tags)
+
+	_, err = m.expandTags(map[string]string{"x": "$today"}, now)
+	assert.ErrorContains(t, err, "unknown macro $today")
+	_, err = TagValueMacros{}.expandTags(map[string]string{"x": "$cluster"}, now)
+	assert.ErrorContains(t, err, "macro $cluster needs --cluster-name")
+}
+
+func TestKeepMacroTimestamps(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "ns"}}
+	r := &PodReconciler{TagNamespace: "enable", TagKeyRenames: map[string]string{"since": "Since"}, TagMacros: TagValueMacros{Cluster: "prod"}}
+	annotation := `{"FirstTaggedAt":"$now","since":"$now","Stamp":"$cluster@$now","Expires":"2026-01-01T00:00:00Z"}`
+	tags := map[string]string{
+		"ns:FirstTaggedAt": "2026-03-02T00:00:00Z",
+		"ns:Since":         "2026-03-02T00:00:00Z",
+		"ns:Stamp":         "prod@2026-03-02T00:00:00Z",
+		"ns:Expires":       "2026-01-01T00:00:00Z",
+		"ns:New":           "2026-03-02T00:00:00Z",
+	}
+	r.keepMacroTimestamps(pod, annotation, tags, map[string]string{
+		"ns:FirstTaggedAt": "2026-03-01T00:00:00Z",
+		"ns:Since":         "2026-03-01T00:00:00Z",
+		"ns:Stamp":         "staging@2026-03-01T00:00:00Z",
+		"ns:Expires":       "2025-01-01T00:00:00Z",
+	})
+	assert.Equal(t, "2026-03-01T00:00:00Z", tags["ns:FirstTaggedAt"], "kept across reconciles")
+	assert.Equal(t, "2026-03-01T00:00:00Z", tags["ns:Since"], "kept for renamed keys")
+	assert.Equal(t, "prod@2026-03-02T00:00:00Z", tags["ns:Stamp"], "the rest of the value changed")
+	assert.Equal(t, "2026-01-01T00:00:00Z", tags["ns:Expires"], "literal timestamps are not macros")
+	assert.Equal(t, "2026-03-02T00:00:00Z", tags["ns:New"])
+}
+
+func TestReconcileTagValueMacros(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "app",
+		Namespace: "default",
+		Annotations: map[string]string{
+			AnnotationKey:            `{"Cluster":"$cluster","FirstTaggedAt":"$now"}`,
+			LastAppliedAnnotationKey: `{"Cluster":"prod","FirstTaggedAt":"2026-03-01T00:00:00Z"}`,
+		},
+	}}
+	r := &PodReconciler{AnnotationKey: AnnotationKey, TagMacros: TagValueMacros{Cluster: "prod"}}
+
+	current, _, diff, err := r.parseAndCompareTags(context.Background(), pod, pod.Annotations[AnnotationKey], pod.Annotations[LastAppliedAnnotationKey])
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Cluster": "prod", "FirstTaggedAt": "2026-03-01T00:00:00Z"}, current)
+	assert.Empty(t, diff.toAdd)
+
+	plan := r.Plan(context.Background(), pod)
+	assert.Empty(t, plan.ToAdd)
+	assert.Equal(t, computeHash(current), plan.Hash)
+
+	assert.NoError(t, TagAnnotationValidator{TagMacros: r.TagMacros}.Validate(pod.Annotations[AnnotationKey]))
+	assert.ErrorContains(t, TagAnnotationValidator{}.Validate(`{"Region":"$region"}`), "macro $region")
+}
+
+func TestAccountLookup(t *testing.T) {
+	calls := 0
+	var lookupErr error
+	m := TagValueMacros{Region: "eu-west-1", AccountLookup: NewAccountLookup(func(ctx context.Context) (string, error) {
+		calls++
+		if lookupErr != nil {
+			return "", lookupErr
+		}
+		return "111111111111", nil
+	})}
+	now := time.Now()
+
+	_, err := m.expandTags(map[string]string{"Region": "$region", "TaggedAt": "$now"}, now)
+	require.NoError(t, err)
+	assert.Zero(t, calls, "no lookup without $account")
+
+	lookupErr = errors.New("no network")
+	_, err = m.expandTags(map[string]string{"Account": "$account"}, now)
+	assert.ErrorContains(t, err, "macro $account needs the AWS account: no network")
+	_, err = m.expandTags(map[string]string{"Account": "$account"}, now)
+	assert.ErrorContains(t, err, "no network")
+	assert.Equal(t, 1, calls, "a failure is reused until accountLookupRetry")
+
+	lookupErr = nil
+	m.AccountLookup.failedAt = now.Add(-accountLookupRetry)
+	for i := 0; i < 2; i++ {
+		tags, err := m.expandTags(map[string]string{"Account": "$account"}, now)
+		require.NoError(t, err)
+		assert.Equal(t, "111111111111", tags["Account"])
+	}
+	assert.Equal(t, 2, calls, "the account is kept once found")
+
+	static := TagValueMacros{Account: "222222222222", AccountLookup: m.AccountLookup}
+	tags, err := static.expandTags(map[string]string{"Account": "$account"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "222222222222", tags["Account"])
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow is a daily time range in UTC, as offsets from midnight. A
+// window whose End is before its Start runs past midnight.
+type MaintenanceWindow struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// MaintenanceWindows are the times at which deferrable tag changes (drift repair
+// and owner tag rollouts on already tagged ENIs) may be made. Tagging requested
+// by a pod is never deferred. No windows means changes are never deferred.
+type MaintenanceWindows []MaintenanceWindow
+
+// ParseMaintenanceWindows parses comma-separated "HH:MM-HH:MM" UTC ranges,
+// e.g. "22:00-06:00,12:00-13:00".
+func ParseMaintenanceWindows(value string) (MaintenanceWindows, error) {
+	var windows MaintenanceWindows
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid maintenance window %q: expected HH:MM-HH:MM", part)
+		}
+		start, err := parseTimeOfDay(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window %q: %w", part, err)
+		}
+		end, err := parseTimeOfDay(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid maintenance window %q: %w", part, err)
+		}
+		if start == end {
+			return nil, fmt.Errorf("invalid maintenance window %q: start and end are equal", part)
+		}
+		windows = append(windows, MaintenanceWindow{Start: start, End: end})
+	}
+	return windows, nil
+}
+
+// parseTimeOfDay parses "HH:MM" into an offset from midnight.
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("time %q must be HH:MM", strings.TrimSpace(value))
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// contains reports whether offset (from midnight) falls inside w.
+func (w MaintenanceWindow) contains(offset time.Duration) bool {
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+// Open reports whether deferrable changes may be made at t.
+func (ws MaintenanceWindows) Open(t time.Time) bool {
+	if len(ws) == 0 {
+		return true
+	}
+	t = t.UTC()
+	offset := t.Sub(t.Truncate(24 * time.Hour))
+	for _, w := range ws {
+		if w.contains(offset) {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpen returns the earliest time at or after t when a window is open.
+func (ws MaintenanceWindows) NextOpen(t time.Time) time.Time {
+	if ws.Open(t) {
+		return t
+	}
+	t = t.UTC()
+	midnight := t.Truncate(24 * time.Hour)
+	var next time.Time
+	for _, w := range ws {
+		start := midnight.Add(w.Start)
+		if !start.After(t) {
+			start = start.Add(24 * time.Hour)
+		}
+		if next.IsZero() || start.Before(next) {
+			next = start
+		}
+	}
+	return next
+}
+
+// String formats the windows as accepted by ParseMaintenanceWindows.
+func (ws MaintenanceWindows) String() string {
+	parts := make([]string, len(ws))
+	for i, w := range ws {
+		parts[i] = fmt.Sprintf("%s-%s", formatTimeOfDay(w.Start), formatTimeOfDay(w.End))
+	}
+	return strings.Join(parts, ",")
+}
+
+func formatTimeOfDay(offset time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(offset.Hours()), int(offset.Minutes())%60)
+}
+
+// deferredError reports that a deferrable change was held back until the next
+// maintenance window or, with budget set, the next mutation budget window.
+type deferredError struct {
+	eniID  string
+	until  time.Time
+	budget bool
+}
+
+func (e *deferredError) Error() string {
+	if e.budget {
+		return fmt.Sprintf("change of ENI %s tags deferred to the next AWS mutation budget window at %s", e.eniID, e.until.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("repair of ENI %s tags deferred to the maintenance window at %s", e.eniID, e.until.Format(time.RFC3339))
+}
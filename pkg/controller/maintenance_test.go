@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMaintenanceWindows(t *testing.T) {
+	windows, err := ParseMaintenanceWindows("22:00-06:00, 12:30-13:00")
+	require.NoError(t, err)
+	assert.Equal(t, MaintenanceWindows{
+		{Start: 22 * time.Hour, End: 6 * time.Hour},
+		{Start: 12*time.Hour + 30*time.Minute, End: 13 * time.Hour},
+	}, windows)
+	assert.Equal(t, "22:00-06:00,12:30-13:00", windows.String())
+
+	windows, err = ParseMaintenanceWindows("")
+	require.NoError(t, err)
+	assert.Empty(t, windows)
+
+	for _, value := range []string{"22:00", "25:00-06:00", "10:00-10:00", "10-11"} {
+		_, err := ParseMaintenanceWindows(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestMaintenanceWindowsOpen(t *testing.T) {
+	windows, err := ParseMaintenanceWindows("22:00-06:00,12:00-13:00")
+	require.NoError(t, err)
+	day := time.Date(2026, 10, 13, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		at       time.Duration
+		open     bool
+		nextOpen time.Duration
+	}{
+		{at: 23 * time.Hour, open: true},
+		{at: 5*time.Hour + 59*time.Minute, open: true},
+		{at: 6 * time.Hour, nextOpen: 12 * time.Hour},
+		{at: 12*time.Hour + 30*time.Minute, open: true},
+		{at: 13 * time.Hour, nextOpen: 22 * time.Hour},
+	} {
+		at := day.Add(tt.at)
+		assert.Equal(t, tt.open, windows.Open(at), at)
+		if tt.open {
+			assert.Equal(t, at, windows.NextOpen(at))
+		} else {
+			assert.Equal(t, day.Add(tt.nextOpen), windows.NextOpen(at), at)
+		}
+	}
+
+	// Times are compared in UTC.
+	assert.True(t, windows.Open(time.Date(2026, 10, 13, 14, 30, 0, 0, time.FixedZone("CEST", 2*3600))))
+	// Without windows nothing is deferred.
+	assert.True(t, MaintenanceWindows(nil).Open(day.Add(9*time.Hour)))
+}
@@ -0,0 +1,29 @@
+package controller
+
+import "k8s-eni-tagger/pkg/aws"
+
+// ManagedByTagValue returns the managed-by tag value for a cluster:
+// "k8s-eni-tagger/<cluster>", or just ManagedByTagPrefix without a cluster name.
+func ManagedByTagValue(cluster string) string {
+	if cluster == "" {
+		return ManagedByTagPrefix
+	}
+	return ManagedByTagPrefix + "/" + cluster
+}
+
+// managedByTag returns the managed-by tag value to write for a pod with the given
+// tags, or "" when the tag is disabled or the pod sets it itself.
+func (r *PodReconciler) managedByTag(podTags map[string]string) string {
+	if _, own := podTags[ManagedByTagKey]; own {
+		return ""
+	}
+	return r.ManagedByTag
+}
+
+// removeManagedByTag reports whether cleanup should remove the managed-by tag from
+// an ENI: it carries the value this controller writes and is not one of the
+// pod's own tags, which are removed anyway.
+func (r *PodReconciler) removeManagedByTag(eniInfo *aws.ENIInfo, lastAppliedTags map[string]string) bool {
+	value := r.managedByTag(lastAppliedTags)
+	return value != "" && eniInfo.Tags[ManagedByTagKey] == value
+}
@@ -0,0 +1,268 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+const (
+	// PauseConfigMapControllerName names the controller watching the pause ConfigMap.
+	PauseConfigMapControllerName = "pause-configmap"
+
+	// PausedConfigMapKey is the pause ConfigMap data key; "true" pauses tagging.
+	PausedConfigMapKey = "paused"
+
+	// Sources that can pause tagging. Tagging is paused while any of them is set.
+	PauseSourceConfigMap = "configmap"
+	PauseSourceAdmin     = "admin"
+)
+
+// pausedRequeueDelay is how often pods held by a pause are reconciled again, in
+// case the requeue on resume misses them.
+const pausedRequeueDelay = 5 * time.Minute
+
+// PauseSwitch stops the controller from changing ENI tags while set, e.g. during
+// an AWS incident or a change freeze. Pods are still watched and their tags
+// compared; changes that would be made are logged and reported with the Paused
+// condition instead. On resume, held pods are reconciled again.
+type PauseSwitch struct {
+	client client.Reader
+	keys   Keys
+
+	mu      sync.Mutex
+	sources map[string]bool
+	since   time.Time
+
+	// events requeues held pods on resume.
+	events chan event.GenericEvent
+}
+
+// NewPauseSwitch returns an unpaused switch. c lists the pods to requeue on resume.
+func NewPauseSwitch(c client.Reader, keys Keys) *PauseSwitch {
+	return &PauseSwitch{
+		client:  c,
+		keys:    keys,
+		sources: make(map[string]bool),
+		events:  make(chan event.GenericEvent, 100),
+	}
+}
+
+// Paused reports whether tagging is paused by any source.
+func (p *PauseSwitch) Paused() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.pausedLocked()
+}
+
+func (p *PauseSwitch) pausedLocked() bool {
+	for _, paused := range p.sources {
+		if paused {
+			return true
+		}
+	}
+	return false
+}
+
+// Set pauses or unpauses tagging on behalf of source. Tagging resumes once no
+// source holds it paused.
+func (p *PauseSwitch) Set(ctx context.Context, source string, paused bool) {
+	p.mu.Lock()
+	was := p.pausedLocked()
+	p.sources[source] = paused
+	now := p.pausedLocked()
+	if !was && now {
+		p.since = time.Now()
+	}
+	p.mu.Unlock()
+
+	value := 0.0
+	if paused {
+		value = 1
+	}
+	metrics.TaggingPaused.WithLabelValues(source).Set(value)
+
+	logger := log.FromContext(ctx)
+	switch {
+	case !was && now:
+		logger.Info("ENI tagging paused", "source", source)
+	case was && !now:
+		logger.Info("ENI tagging resumed", "source", source)
+		// Only the leader consumes events, so another replica must not block here
+		go p.requeueHeldPods(log.IntoContext(context.Background(), logger))
+	}
+}
+
+// source returns the channel of pods to requeue on resume.
+func (p *PauseSwitch) source() source.Source {
+	return &source.Channel{Source: p.events}
+}
+
+// requeueHeldPods sends pods held by the pause to the pod controller: pods with
+// the Paused condition and terminating pods waiting for cleanup. Pods not sent
+// within pausedRequeueDelay are picked up by their own requeue.
+func (p *PauseSwitch) requeueHeldPods(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, pausedRequeueDelay)
+	defer cancel()
+
+	pods := &corev1.PodList{}
+	if err := p.client.List(ctx, pods); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list pods to requeue after resume")
+		return
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !p.held(pod) {
+			continue
+		}
+		select {
+		case p.events <- event.GenericEvent{Object: pod}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (p *PauseSwitch) held(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil {
+		return controllerutil.ContainsFinalizer(pod, p.keys.Finalizer)
+	}
+	for _, c := range pod.Status.Conditions {
+		if string(c.Type) == p.keys.ConditionType {
+			return c.Reason == string(ReasonPaused)
+		}
+	}
+	return false
+}
+
+// pauseStatus is the JSON body served by PauseSwitch.ServeHTTP.
+type pauseStatus struct {
+	Paused  bool            `json:"paused"`
+	Sources map[string]bool `json:"sources"`
+	Since   *time.Time      `json:"since,omitempty"`
+}
+
+// ServeHTTP reports the pause state as JSON on GET and sets the admin source on
+// PUT or POST with a "paused" query parameter, e.g. PUT /pause?paused=true. The
+// admin source only affects the replica serving the request.
+func (p *PauseSwitch) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		paused, err := strconv.ParseBool(req.URL.Query().Get("paused"))
+		if err != nil {
+			http.Error(w, "paused query parameter must be true or false", http.StatusBadRequest)
+			return
+		}
+		p.Set(req.Context(), PauseSourceAdmin, paused)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	p.mu.Lock()
+	status := pauseStatus{Paused: p.pausedLocked(), Sources: make(map[string]bool, len(p.sources))}
+	for s, paused := range p.sources {
+		status.Sources[s] = paused
+	}
+	if status.Paused {
+		since := p.since
+		status.Since = &since
+	}
+	p.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// pausedError is returned by applyENITags instead of changing tags while paused.
+type pausedError struct {
+	eniID    string
+	toAdd    int
+	toRemove int
+}
+
+func (e *pausedError) Error() string {
+	return fmt.Sprintf("tagging is paused; ENI %s has %d tags to add or update and %d to remove", e.eniID, e.toAdd, e.toRemove)
+}
+
+// PauseConfigMapReconciler keeps the configmap source of a PauseSwitch in sync
+// with a ConfigMap's "paused" key. A missing ConfigMap or key means not paused;
+// an invalid value leaves the switch unchanged. It runs on every replica, so a
+// new leader starts with the current state.
+type PauseConfigMapReconciler struct {
+	client.Client
+
+	// ConfigMap is the watched ConfigMap.
+	ConfigMap types.NamespacedName
+
+	// Switch is updated on every change and shared with the PodReconciler.
+	Switch *PauseSwitch
+}
+
+// Reconcile reloads the pause state from the ConfigMap.
+func (r *PauseConfigMapReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	return ctrl.Result{}, r.Load(ctx, r.Client)
+}
+
+// Load reads the ConfigMap with c and updates the switch. It is also called at
+// startup with an uncached reader, before any pod is reconciled.
+func (r *PauseConfigMapReconciler) Load(ctx context.Context, c client.Reader) error {
+	logger := log.FromContext(ctx).WithValues("configMap", r.ConfigMap)
+
+	cm := &corev1.ConfigMap{}
+	if err := c.Get(ctx, r.ConfigMap, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return err
+		}
+		r.Switch.Set(ctx, PauseSourceConfigMap, false)
+		return nil
+	}
+
+	value := strings.TrimSpace(cm.Data[PausedConfigMapKey])
+	if value == "" {
+		r.Switch.Set(ctx, PauseSourceConfigMap, false)
+		return nil
+	}
+	paused, err := strconv.ParseBool(value)
+	if err != nil {
+		// Retrying cannot help until the ConfigMap is edited, which triggers a new reconcile.
+		logger.Error(err, "Ignoring invalid pause ConfigMap value, keeping the current state", "key", PausedConfigMapKey, "paused", r.Switch.Paused())
+		return nil
+	}
+	r.Switch.Set(ctx, PauseSourceConfigMap, paused)
+	return nil
+}
+
+// SetupWithManager watches the pause ConfigMap on every replica.
+func (r *PauseConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	needLeaderElection := false
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(PauseConfigMapControllerName).
+		For(&corev1.ConfigMap{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return o.GetNamespace() == r.ConfigMap.Namespace && o.GetName() == r.ConfigMap.Name
+		}))).
+		WithOptions(controller.Options{NeedLeaderElection: &needLeaderElection}).
+		Complete(r)
+}
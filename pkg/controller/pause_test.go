@@ -0,0 +1,178 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPauseConfigMapReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	key := types.NamespacedName{Namespace: "kube-system", Name: "eni-tagger-pause"}
+	held := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "held", Namespace: "default"},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+			Type:   corev1.PodConditionType(ConditionTypeEniTagged),
+			Status: corev1.ConditionFalse,
+			Reason: string(ReasonPaused),
+		}}},
+	}
+	synced := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "synced", Namespace: "default"},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+			Type:   corev1.PodConditionType(ConditionTypeEniTagged),
+			Status: corev1.ConditionTrue,
+			Reason: string(ReasonSynced),
+		}}},
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+		Data:       map[string]string{PausedConfigMapKey: "true"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(held, synced, cm).Build()
+
+	pause := NewPauseSwitch(k8sClient, NewKeys(""))
+	r := &PauseConfigMapReconciler{Client: k8sClient, ConfigMap: key, Switch: pause}
+	ctx := context.Background()
+
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	assert.True(t, pause.Paused())
+
+	// An invalid value keeps the current state.
+	cm.Data[PausedConfigMapKey] = "maybe"
+	require.NoError(t, k8sClient.Update(ctx, cm))
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	assert.True(t, pause.Paused())
+
+	// Deleting the ConfigMap resumes tagging and requeues the held pod.
+	require.NoError(t, k8sClient.Delete(ctx, cm))
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	assert.False(t, pause.Paused())
+	select {
+	case ev := <-pause.events:
+		assert.Equal(t, "held", ev.Object.GetName())
+	case <-time.After(5 * time.Second):
+		t.Fatal("held pod was not requeued on resume")
+	}
+	select {
+	case ev := <-pause.events:
+		t.Fatalf("unexpected requeue of %s", ev.Object.GetName())
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPauseSwitchServeHTTP(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+	pause := NewPauseSwitch(k8sClient, NewKeys(""))
+	pause.Set(context.Background(), PauseSourceConfigMap, true)
+
+	rec := httptest.NewRecorder()
+	pause.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/pause?paused=true", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var status pauseStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	assert.True(t, status.Paused)
+	assert.Equal(t, map[string]bool{PauseSourceConfigMap: true, PauseSourceAdmin: true}, status.Sources)
+	assert.NotNil(t, status.Since)
+
+	// Tagging stays paused while the ConfigMap still holds it
+	rec = httptest.NewRecorder()
+	pause.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/pause?paused=false", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, pause.Paused())
+
+	rec = httptest.NewRecorder()
+	pause.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/pause?paused=soon", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	pause.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/pause", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestReconcilePaused(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-paused",
+			Namespace:   "default",
+			UID:         "uid-paused",
+			Annotations: map[string]string{AnnotationKey: `{"team":"platform"}`},
+			Finalizers:  []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.10"},
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKey{Name: "pod-paused", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.10").Return(&aws.ENIInfo{ID: "eni-paused"}, nil)
+
+	pause := NewPauseSwitch(k8sClient, NewKeys(""))
+	pause.Set(context.Background(), PauseSourceAdmin, true)
+	r := &PodReconciler{
+		Client:            k8sClient,
+		Scheme:            scheme,
+		Recorder:          record.NewFakeRecorder(10),
+		AWSClient:         mockAWS,
+		AnnotationKey:     AnnotationKey,
+		Pause:             pause,
+		PauseDeletionHold: time.Hour,
+	}
+
+	res, err := r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, pausedRequeueDelay, res.RequeueAfter)
+	mockAWS.AssertNotCalled(t, "TagENI", mock.Anything, mock.Anything, mock.Anything)
+
+	updated := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+	require.Len(t, updated.Status.Conditions, 1)
+	assert.Equal(t, string(ReasonPaused), updated.Status.Conditions[0].Reason)
+	details, err := ParseConditionDetails(updated.Status.Conditions[0].Message)
+	require.NoError(t, err)
+	assert.Equal(t, "eni-paused", details.ENIID)
+
+	// Deleted pods keep their finalizer until tagging resumes
+	require.NoError(t, k8sClient.Delete(context.Background(), updated))
+	res, err = r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, pausedRequeueDelay, res.RequeueAfter)
+	require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+	assert.Contains(t, updated.Finalizers, finalizerName)
+	mockAWS.AssertNotCalled(t, "UntagENI", mock.Anything, mock.Anything, mock.Anything)
+
+	// Past the hold they are released with their tags left in place
+	r.PauseDeletionHold = 0
+	res, err = r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.Zero(t, res.RequeueAfter)
+	assert.True(t, apierrors.IsNotFound(k8sClient.Get(context.Background(), req.NamespacedName, updated)))
+	mockAWS.AssertNotCalled(t, "UntagENI", mock.Anything, mock.Anything, mock.Anything)
+	assert.Contains(t, <-r.Recorder.(*record.FakeRecorder).Events, "TagCleanupSkipped")
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	enicache "k8s-eni-tagger/pkg/cache"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RBACRequirement is one Kubernetes permission the controller uses.
+type RBACRequirement struct {
+	Verb        string
+	Resource    string
+	Subresource string
+	// Namespace is empty for all namespaces.
+	Namespace string
+	// Name restricts the check to one object.
+	Name string
+	// Optional permissions only degrade a feature when missing, e.g. events.
+	Optional bool
+	// Purpose says what the permission is needed for.
+	Purpose string
+}
+
+// String describes the permission like "patch pods/status in all namespaces".
+func (r RBACRequirement) String() string {
+	resource := r.Resource
+	if r.Subresource != "" {
+		resource += "/" + r.Subresource
+	}
+	if r.Name != "" {
+		resource += " " + r.Name
+	}
+	scope := "all namespaces"
+	if r.Namespace != "" {
+		scope = "namespace " + r.Namespace
+	}
+	return fmt.Sprintf("%s %s in %s", r.Verb, resource, scope)
+}
+
+// RBACOptions describes the features whose permissions RBACRequirements lists.
+type RBACOptions struct {
+	// WatchNamespace limits pod permissions to one namespace. Empty means all.
+	WatchNamespace string
+	// ControllerNamespace holds the leader election lease and the controller's ConfigMaps.
+	ControllerNamespace string
+	LeaderElection      bool
+	// StateStore replaces pod writes with the StateStore ConfigMap.
+	StateStore     bool
+	CacheConfigMap bool
+	// SubnetConfigMap is the watched subnet allow-list ConfigMap, if any.
+	SubnetConfigMap types.NamespacedName
+	// PauseConfigMap is the watched pause ConfigMap, if any.
+	PauseConfigMap types.NamespacedName
+	// DefaultTagsConfigMap is the ConfigMap the admission webhook reads default
+	// tags from, if any.
+	DefaultTagsConfigMap types.NamespacedName
+	// NodeTemplates reads nodes for tag value templates (TagValueTemplatesNode).
+	NodeTemplates bool
+	// ServiceTagging reads and patches Services for load balancer ENI tagging.
+	ServiceTagging bool
+	// HostNetworkPrimaryENI reads nodes to find the instance of host network pods.
+	HostNetworkPrimaryENI bool
+	// NodeVolumes reads nodes to find the instance whose EBS volumes are tagged.
+	NodeVolumes bool
+	// ReadOnly writes neither pods nor Services (see ReadOnlyAudit).
+	ReadOnly bool
+}
+
+// RBACRequirements lists the Kubernetes permissions needed with opts, matching
+// the chart's RBAC templates.
+func RBACRequirements(opts RBACOptions) []RBACRequirement {
+	podNS := opts.WatchNamespace
+	reqs := []RBACRequirement{
+		{Verb: "get", Resource: "pods", Namespace: podNS, Purpose: "reading pods"},
+		{Verb: "list", Resource: "pods", Namespace: podNS, Purpose: "watching pods"},
+		{Verb: "watch", Resource: "pods", Namespace: podNS, Purpose: "watching pods"},
+	}
+	if !opts.StateStore && !opts.ReadOnly {
+		reqs = append(reqs,
+			RBACRequirement{Verb: "patch", Resource: "pods", Namespace: podNS, Purpose: "finalizer and last-applied annotations"},
+			RBACRequirement{Verb: "update", Resource: "pods", Namespace: podNS, Purpose: "finalizer removal"},
+		)
+	}
+	if !opts.ReadOnly {
+		reqs = append(reqs, RBACRequirement{Verb: "patch", Resource: "pods", Subresource: "status", Namespace: podNS, Purpose: "tagging condition"})
+	}
+	reqs = append(reqs, RBACRequirement{Verb: "create", Resource: "events", Namespace: podNS, Optional: true, Purpose: "pod events"})
+
+	ns := opts.ControllerNamespace
+	if opts.LeaderElection {
+		for _, verb := range []string{"get", "create", "update"} {
+			reqs = append(reqs, RBACRequirement{Verb: verb, Resource: "leases", Namespace: ns, Purpose: "leader election"})
+		}
+	}
+	if opts.StateStore {
+		reqs = append(reqs,
+			RBACRequirement{Verb: "get", Resource: "configmaps", Namespace: ns, Name: StateConfigMapName, Purpose: "state store"},
+			RBACRequirement{Verb: "patch", Resource: "configmaps", Namespace: ns, Name: StateConfigMapName, Purpose: "state store"},
+			RBACRequirement{Verb: "create", Resource: "configmaps", Namespace: ns, Purpose: "state store"},
+		)
+	}
+	if opts.CacheConfigMap {
+		reqs = append(reqs,
+			RBACRequirement{Verb: "get", Resource: "configmaps", Namespace: ns, Name: enicache.ConfigMapName, Purpose: "ENI cache persistence"},
+			RBACRequirement{Verb: "update", Resource: "configmaps", Namespace: ns, Name: enicache.ConfigMapName, Purpose: "ENI cache persistence"},
+			RBACRequirement{Verb: "create", Resource: "configmaps", Namespace: ns, Purpose: "ENI cache persistence"},
+		)
+	}
+	if opts.SubnetConfigMap.Name != "" {
+		for _, verb := range []string{"get", "list", "watch"} {
+			reqs = append(reqs, RBACRequirement{Verb: verb, Resource: "configmaps", Namespace: opts.SubnetConfigMap.Namespace, Purpose: "subnet allow-list ConfigMap"})
+		}
+	}
+	if opts.PauseConfigMap.Name != "" {
+		for _, verb := range []string{"get", "list", "watch"} {
+			reqs = append(reqs, RBACRequirement{Verb: verb, Resource: "configmaps", Namespace: opts.PauseConfigMap.Namespace, Purpose: "pause ConfigMap"})
+		}
+	}
+	if opts.DefaultTagsConfigMap.Name != "" {
+		for _, verb := range []string{"get", "list", "watch"} {
+			reqs = append(reqs, RBACRequirement{Verb: verb, Resource: "configmaps", Namespace: opts.DefaultTagsConfigMap.Namespace, Purpose: "default tags ConfigMap"})
+		}
+		if !opts.ReadOnly {
+			reqs = append(reqs, RBACRequirement{Verb: "patch", Resource: "configmaps", Namespace: opts.DefaultTagsConfigMap.Namespace, Name: opts.DefaultTagsConfigMap.Name, Optional: true, Purpose: "default tags canary promotion"})
+		}
+	}
+	if opts.NodeTemplates {
+		for _, verb := range []string{"get", "list", "watch"} {
+			reqs = append(reqs, RBACRequirement{Verb: verb, Resource: "nodes", Purpose: "node labels in tag templates"})
+		}
+	}
+	if opts.HostNetworkPrimaryENI {
+		for _, verb := range []string{"get", "list", "watch"} {
+			reqs = append(reqs, RBACRequirement{Verb: verb, Resource: "nodes", Purpose: "instance IDs of host network pods"})
+		}
+	}
+	if opts.NodeVolumes {
+		for _, verb := range []string{"get", "list", "watch"} {
+			reqs = append(reqs, RBACRequirement{Verb: verb, Resource: "nodes", Purpose: "instance IDs of nodes whose volumes are tagged"})
+		}
+	}
+	if opts.ServiceTagging {
+		for _, verb := range []string{"get", "list", "watch"} {
+			reqs = append(reqs, RBACRequirement{Verb: verb, Resource: "services", Namespace: podNS, Purpose: "watching Services"})
+		}
+		if !opts.ReadOnly {
+			reqs = append(reqs, RBACRequirement{Verb: "patch", Resource: "services", Namespace: podNS, Purpose: "Service last-applied annotations"})
+		}
+	}
+	return reqs
+}
+
+// RBACCheck is the outcome of checking one RBACRequirement.
+type RBACCheck struct {
+	RBACRequirement
+	Allowed bool
+	// Err is set when the check itself failed, leaving the permission unverified.
+	Err error
+}
+
+// CheckRBAC asks the API server, with a SelfSubjectAccessReview per requirement,
+// whether the controller's own identity holds each permission. Every
+// requirement is checked, so all missing permissions are reported at once.
+func CheckRBAC(ctx context.Context, c client.Client, reqs []RBACRequirement) []RBACCheck {
+	checks := make([]RBACCheck, 0, len(reqs))
+	for _, req := range reqs {
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace:   req.Namespace,
+					Verb:        req.Verb,
+					Group:       rbacGroup(req.Resource),
+					Resource:    req.Resource,
+					Subresource: req.Subresource,
+					Name:        req.Name,
+				},
+			},
+		}
+		check := RBACCheck{RBACRequirement: req}
+		if err := c.Create(ctx, review); err != nil {
+			check.Err = fmt.Errorf("access review for %s failed: %w", req, err)
+		} else {
+			check.Allowed = review.Status.Allowed
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+// rbacGroup returns the API group of the resources the controller uses.
+func rbacGroup(resource string) string {
+	if resource == "leases" {
+		return "coordination.k8s.io"
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRBACRequirements(t *testing.T) {
+	has := func(reqs []RBACRequirement, s string) bool {
+		for _, r := range reqs {
+			if r.String() == s {
+				return true
+			}
+		}
+		return false
+	}
+
+	reqs := RBACRequirements(RBACOptions{ControllerNamespace: "kube-system", LeaderElection: true})
+	assert.True(t, has(reqs, "patch pods in all namespaces"))
+	assert.True(t, has(reqs, "patch pods/status in all namespaces"))
+	assert.True(t, has(reqs, "update leases in namespace kube-system"))
+	assert.False(t, has(reqs, "create configmaps in namespace kube-system"))
+	assert.False(t, has(reqs, "get nodes in all namespaces"))
+
+	reqs = RBACRequirements(RBACOptions{ControllerNamespace: "kube-system", NodeVolumes: true})
+	assert.True(t, has(reqs, "watch nodes in all namespaces"))
+
+	reqs = RBACRequirements(RBACOptions{
+		WatchNamespace:        "apps",
+		ControllerNamespace:   "kube-system",
+		StateStore:            true,
+		SubnetConfigMap:       types.NamespacedName{Namespace: "network", Name: "subnets"},
+		PauseConfigMap:        types.NamespacedName{Namespace: "ops", Name: "pause"},
+		DefaultTagsConfigMap:  types.NamespacedName{Namespace: "policy", Name: "default-tags"},
+		ServiceTagging:        true,
+		HostNetworkPrimaryENI: true,
+	})
+	assert.False(t, has(reqs, "patch pods in namespace apps"), "the state store never writes pods")
+	assert.True(t, has(reqs, "watch pods in namespace apps"))
+	assert.True(t, has(reqs, "patch configmaps eni-tagger-state in namespace kube-system"))
+	assert.True(t, has(reqs, "watch configmaps in namespace network"))
+	assert.True(t, has(reqs, "watch configmaps in namespace ops"))
+	assert.True(t, has(reqs, "get configmaps in namespace policy"))
+	assert.True(t, has(reqs, "patch configmaps default-tags in namespace policy"))
+	assert.True(t, has(reqs, "patch services in namespace apps"))
+	assert.True(t, has(reqs, "get nodes in all namespaces"))
+	assert.False(t, has(reqs, "get leases in namespace kube-system"))
+
+	reqs = RBACRequirements(RBACOptions{ControllerNamespace: "kube-system", ServiceTagging: true, ReadOnly: true, DefaultTagsConfigMap: types.NamespacedName{Namespace: "policy", Name: "default-tags"}})
+	assert.False(t, has(reqs, "patch pods in all namespaces"), "read-only mode never writes pods")
+	assert.False(t, has(reqs, "patch pods/status in all namespaces"))
+	assert.False(t, has(reqs, "patch services in all namespaces"))
+	assert.False(t, has(reqs, "patch configmaps default-tags in namespace policy"))
+	assert.True(t, has(reqs, "watch services in all namespaces"))
+}
+
+func TestCheckRBAC(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, authorizationv1.AddToScheme(scheme))
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+			review := obj.(*authorizationv1.SelfSubjectAccessReview)
+			switch review.Spec.ResourceAttributes.Verb {
+			case "update":
+				return errors.New("connection refused")
+			case "patch":
+				review.Status.Allowed = false
+			default:
+				review.Status.Allowed = true
+			}
+			return nil
+		},
+	}).Build()
+
+	checks := CheckRBAC(context.Background(), k8sClient, []RBACRequirement{
+		{Verb: "get", Resource: "pods"},
+		{Verb: "patch", Resource: "pods"},
+		{Verb: "update", Resource: "pods"},
+	})
+	require.Len(t, checks, 3)
+	assert.True(t, checks[0].Allowed)
+	assert.False(t, checks[1].Allowed)
+	assert.NoError(t, checks[1].Err)
+	assert.False(t, checks[2].Allowed)
+	assert.Error(t, checks[2].Err)
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// maxPlanManifestBytes bounds the pod manifest accepted by the plan endpoint.
+const maxPlanManifestBytes = 1 << 20
+
+// TagPlan is what the controller would do with a pod, computed without calling
+// AWS or the API server. Checks that need the ENI (subnet and shared ENI
+// validation, hash conflicts, key case alignment with existing ENI tags) are not
+// part of the plan.
+type TagPlan struct {
+	// Pod is the pod's "namespace/name".
+	Pod string `json:"pod"`
+
+	// Skipped explains why the pod would not be tagged at all.
+	Skipped string `json:"skipped,omitempty"`
+
+	// Reason is the condition reason if tagging would fail; Error says why.
+	Reason ConditionReason `json:"reason,omitempty"`
+	Error  string          `json:"error,omitempty"`
+
+	// Tags are the managed tags after parsing, case resolution and namespacing.
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// Hash is the value of the hash tag for Tags.
+	Hash string `json:"hash,omitempty"`
+
+	// Bookkeeping holds the hash tag and, with a controller ID, the owner tag,
+	// which are written alongside Tags.
+	Bookkeeping map[string]string `json:"bookkeeping,omitempty"`
+
+	// ToAdd and ToRemove are the changes relative to the pod's last-applied
+	// annotation. With TagDiffSourceENI the ENI's tags decide instead.
+	ToAdd    map[string]string `json:"toAdd,omitempty"`
+	ToRemove []string          `json:"toRemove,omitempty"`
+}
+
+// Plan runs pod through the same annotation parsing, external tag source, case
+// resolution, namespacing and validation as Reconcile and returns the tags that
+// would be applied.
+func (r *PodReconciler) Plan(ctx context.Context, pod *corev1.Pod) TagPlan {
+	plan := TagPlan{Pod: pod.Namespace + "/" + pod.Name}
+
+	annotationValue, wantsTags := r.tagAnnotation(pod)
+	switch {
+	case pod.DeletionTimestamp != nil:
+		plan.Skipped = "pod is being deleted; managed tags would be removed"
+		return plan
+	case !wantsTags:
+		plan.Skipped = fmt.Sprintf("pod has no %s annotation", r.annotationKey())
+		return plan
+	case r.isPodExcluded(pod):
+		plan.Skipped = r.exclusionReason(pod)
+		return plan
+	}
+
+	if err := r.validatePodTags(ctx, pod, annotationValue); err != nil {
+		plan.Reason = ReasonInvalidTags
+		plan.Error = err.Error()
+		return plan
+	}
+	// Namespacing errors surface while tagging, as in applyENITags.
+	tags, err := r.desiredTags(ctx, pod, annotationValue)
+	if err != nil {
+		plan.Reason = ReasonTaggingFailed
+		plan.Error = err.Error()
+		return plan
+	}
+
+	keys := r.keys()
+	lastAppliedTags, err := parseLastApplied(pod.Annotations[keys.LastAppliedTags])
+	if err != nil {
+		lastAppliedTags = make(map[string]string)
+	}
+	r.keepMacroTimestamps(pod, annotationValue, tags, lastAppliedTags)
+	plan.Tags = tags
+	plan.Hash = computeHash(tags)
+	plan.Bookkeeping = map[string]string{keys.HashTag: plan.Hash}
+	if r.ControllerID != "" {
+		plan.Bookkeeping[keys.OwnerTag] = r.ControllerID
+	}
+	if managedBy := r.managedByTag(tags); managedBy != "" {
+		plan.Bookkeeping[ManagedByTagKey] = managedBy
+	}
+
+	diff := computeTagDiff(tags, lastAppliedTags)
+	sort.Strings(diff.toRemove)
+	plan.ToAdd = diff.toAdd
+	plan.ToRemove = diff.toRemove
+	return plan
+}
+
+// PlanHandler serves Plan on POST with a pod manifest (JSON or YAML) as the body,
+// e.g. kubectl get pod my-app -o yaml | curl --data-binary @- 127.0.0.1:8082/plan.
+// A manifest without a namespace is planned in "default".
+func (r *PodReconciler) PlanHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		pod := &corev1.Pod{}
+		body := http.MaxBytesReader(w, req.Body, maxPlanManifestBytes)
+		if err := yaml.NewYAMLOrJSONDecoder(body, 4096).Decode(pod); err != nil {
+			http.Error(w, fmt.Sprintf("invalid pod manifest: %v", err), http.StatusBadRequest)
+			return
+		}
+		if pod.Kind != "" && pod.Kind != "Pod" {
+			http.Error(w, fmt.Sprintf("expected a Pod manifest, got %s", pod.Kind), http.StatusBadRequest)
+			return
+		}
+		if pod.Namespace == "" {
+			pod.Namespace = corev1.NamespaceDefault
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(r.Plan(req.Context(), pod))
+	})
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestPlan(t *testing.T) {
+	r := &PodReconciler{TagNamespace: "enable", ControllerID: "kube-system/eni-tagger"}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:      "app",
+		Namespace: "team-a",
+		Annotations: map[string]string{
+			AnnotationKey:            "Team=platform,Env=prod",
+			LastAppliedAnnotationKey: `{"team-a:Team":"platform","team-a:Old":"x"}`,
+		},
+	}}
+
+	plan := r.Plan(context.Background(), pod)
+	want := map[string]string{"team-a:Team": "platform", "team-a:Env": "prod"}
+	assert.Equal(t, "team-a/app", plan.Pod)
+	assert.Empty(t, plan.Error)
+	assert.Equal(t, want, plan.Tags)
+	assert.Equal(t, computeHash(want), plan.Hash)
+	assert.Equal(t, map[string]string{HashTagKey: plan.Hash, OwnerTagKey: "kube-system/eni-tagger"}, plan.Bookkeeping)
+	assert.Equal(t, map[string]string{"team-a:Env": "prod"}, plan.ToAdd)
+	assert.Equal(t, []string{"team-a:Old"}, plan.ToRemove)
+
+	pod.Annotations[AnnotationKey] = `{"aws:Name":"x"}`
+	plan = r.Plan(context.Background(), pod)
+	assert.Equal(t, ReasonInvalidTags, plan.Reason)
+	assert.Contains(t, plan.Error, "reserved prefix")
+	assert.Empty(t, plan.Tags)
+
+	r.ExcludePodSelector = labels.SelectorFromSet(labels.Set{"ci": "true"})
+	pod.Labels = map[string]string{"ci": "true"}
+	assert.Contains(t, r.Plan(context.Background(), pod).Skipped, "exclude selector")
+
+	delete(pod.Annotations, AnnotationKey)
+	assert.Contains(t, r.Plan(context.Background(), pod).Skipped, "no "+AnnotationKey)
+}
+
+func TestPlanHandler(t *testing.T) {
+	h := (&PodReconciler{}).PlanHandler()
+
+	manifest := `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: app
+  annotations:
+    eni-tagger.io/tags: '{"Team":"platform"}'
+`
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/plan", strings.NewReader(manifest)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var plan TagPlan
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &plan))
+	assert.Equal(t, "default/app", plan.Pod)
+	assert.Equal(t, map[string]string{"Team": "platform"}, plan.Tags)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/plan", strings.NewReader(`{"kind":"Deployment"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/plan", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
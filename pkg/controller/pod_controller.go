@@ -2,20 +2,28 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/metrics"
+
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 // Reconcile handles the reconciliation of a Pod resource.
 // It manages ENI tagging based on pod annotations and handles cleanup on deletion.
-func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	logger := log.FromContext(ctx).WithValues(LogKeyPod, req.NamespacedName)
+	// Tag assumed-role sessions (if configured) with the pod behind each AWS call.
+	ctx = aws.WithPodIdentity(ctx, req.Namespace, req.Name)
 
 	// Check per-pod rate limit (if enabled)
 	if r.PodRateLimitQPS > 0 {
@@ -32,6 +40,9 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 			} else {
 				// Try to store, but another goroutine might have stored one already
 				limiterInterface, loaded = r.PodRateLimiters.LoadOrStore(key, entry)
+				if !loaded {
+					r.trackNewRateLimiter(ctx)
+				}
 			}
 		}
 
@@ -46,33 +57,73 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 				if !entry.Allow() {
 					requeueAfter := time.Duration(1.0/r.PodRateLimitQPS) * time.Second
 					logger.V(1).Info("Rate limited, skipping reconciliation", LogKeyRequeueAfter, requeueAfter)
+					if r.ReportRateLimited {
+						r.reportRateLimited(ctx, req.NamespacedName, now.Add(requeueAfter))
+					}
 					return ctrl.Result{RequeueAfter: requeueAfter}, nil
 				}
 			}
 		}
 	}
 
+	// Wait for a reconcile slot if concurrency is tuned below the worker count
+	if r.Concurrency != nil {
+		if err := r.Concurrency.Acquire(ctx); err != nil {
+			return ctrl.Result{}, err
+		}
+		defer r.Concurrency.Release()
+	}
+
+	if r.History != nil {
+		var record func(ctrl.Result, error)
+		ctx, record = r.History.begin(ctx, req.NamespacedName)
+		defer func() { record(result, err) }()
+	}
+
+	if r.ReadOnly != nil {
+		return r.auditPod(ctx, req.NamespacedName)
+	}
+
 	// Fetch the Pod
 	pod := &corev1.Pod{}
-	if err := r.Get(ctx, req.NamespacedName, pod); err != nil {
+	if err := r.getPod(ctx, req.NamespacedName, pod); err != nil {
+		if apierrors.IsNotFound(err) && r.Stats != nil {
+			r.Stats.forget(req.NamespacedName)
+		}
+		if apierrors.IsNotFound(err) && r.StateStore != nil {
+			if r.paused() {
+				return ctrl.Result{RequeueAfter: pausedRequeueDelay}, nil
+			}
+			// Without finalizers, cleanup happens once the pod is gone
+			return ctrl.Result{}, r.cleanupStoredState(ctx, req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	// Handle deletion
 	if pod.DeletionTimestamp != nil {
+		if r.Stats != nil {
+			r.Stats.forget(req.NamespacedName)
+		}
 		return r.handlePodDeletion(ctx, pod)
 	}
 
-	// Get annotation key
-	key := r.AnnotationKey
-	if key == "" {
-		key = AnnotationKey
+	// Check if pod has the annotation, or labels mapped to tags
+	annotationValue, wantsTags := r.tagAnnotation(pod)
+	if !wantsTags {
+		if r.Stats != nil {
+			r.Stats.forget(req.NamespacedName)
+		}
+		// Nothing to do, unless the annotation was removed after tagging
+		if r.hasStaleBookkeeping(pod) {
+			return r.releaseUnannotatedPod(ctx, pod)
+		}
+		return ctrl.Result{}, nil
 	}
 
-	// Check if pod has the annotation
-	annotationValue, hasAnnotation := pod.Annotations[key]
-	if !hasAnnotation {
-		// No annotation, nothing to do
+	// Skip pods explicitly excluded by label selector, and debug copies
+	if reason := r.exclusionReason(pod); reason != "" {
+		logger.V(1).Info("Pod is excluded, skipping", "reason", reason)
 		return ctrl.Result{}, nil
 	}
 
@@ -82,30 +133,30 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 		return ctrl.Result{}, nil
 	}
 
-	// Add finalizer if not present
-	if updated, err := r.ensureFinalizer(ctx, pod); err != nil {
-		return ctrl.Result{}, err
-	} else if updated {
-		// Requeue to continue processing
-		return ctrl.Result{Requeue: true}, nil
+	// Load last-applied state kept outside the pod
+	if r.StateStore != nil {
+		if err := r.loadStoredState(ctx, pod); err != nil {
+			return ctrl.Result{}, err
+		}
 	}
 
 	// Validate tags
-	if err := validateTags(annotationValue); err != nil {
-		logger.Error(err, "Invalid tags in annotation", LogKeyPod, req.NamespacedName, LogKeyTags, annotationValue, LogKeyAnnotationKey, key)
-		r.Recorder.Event(pod, corev1.EventTypeWarning, "InvalidTags", err.Error())
-		if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, "InvalidTags", err.Error()); err != nil {
-			logger.Error(err, "Failed to update status", LogKeyPod, req.NamespacedName)
+	if err := r.validatePodTags(ctx, pod, annotationValue); err != nil {
+		var dataErr *templateDataError
+		if errors.As(err, &dataErr) {
+			return ctrl.Result{}, err
 		}
-		return ctrl.Result{}, nil
+		logger.Error(err, "Invalid tags in annotation", LogKeyPod, req.NamespacedName, LogKeyTags, annotationValue, LogKeyAnnotationKey, r.annotationKey())
+		return r.handleInvalidTags(ctx, pod, err)
 	}
 
 	// Get ENI info
 	eniInfo, err := r.getENIInfo(ctx, pod)
 	if err != nil {
 		logger.Error(err, "Failed to get ENI info", LogKeyPod, req.NamespacedName, LogKeyPodIP, pod.Status.PodIP)
-		r.Recorder.Event(pod, corev1.EventTypeWarning, "ENILookupFailed", err.Error())
-		if statusErr := r.updateStatus(ctx, pod, corev1.ConditionFalse, "ENILookupFailed", err.Error()); statusErr != nil {
+		r.Recorder.Event(pod, corev1.EventTypeWarning, string(ReasonENILookupFailed), err.Error())
+		details := ConditionDetails{Message: err.Error(), ErrorCode: aws.ErrorCode(err)}
+		if statusErr := r.updateStatus(ctx, pod, corev1.ConditionFalse, ReasonENILookupFailed, details); statusErr != nil {
 			logger.Error(statusErr, "Failed to update status", "pod", req.NamespacedName)
 		}
 		// Backoff for transient failures instead of immediate retry
@@ -115,36 +166,174 @@ func (r *PodReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.R
 	// Validate ENI
 	if err := r.validateENI(ctx, eniInfo); err != nil {
 		logger.Error(err, "ENI validation failed", LogKeyPod, req.NamespacedName, LogKeyENIID, eniInfo.ID, LogKeyENISubnet, eniInfo.SubnetID)
-		r.Recorder.Event(pod, corev1.EventTypeWarning, "ENIValidationFailed", err.Error())
-		if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, "ENIValidationFailed", err.Error()); err != nil {
+		r.Recorder.Event(pod, corev1.EventTypeWarning, string(ReasonENIValidationFailed), err.Error())
+		details := ConditionDetails{Message: err.Error(), ENIID: eniInfo.ID, SubnetID: eniInfo.SubnetID}
+		if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, ReasonENIValidationFailed, details); err != nil {
 			logger.Error(err, "Failed to update status", "pod", req.NamespacedName)
 		}
 		return ctrl.Result{}, nil
 	}
 
+	// Tagging an ENI the CNI is still attaching can race with its setup
+	if r.ENIAttachmentRequeueDelay > 0 && !eniInfo.Attached() {
+		return r.waitForENIAttachment(ctx, pod, eniInfo)
+	}
+
 	// Apply tags
 	if err := r.applyENITags(ctx, pod, eniInfo, annotationValue); err != nil {
+		var foreignErr *foreignControllerError
+		if errors.As(err, &foreignErr) {
+			logger.Info("Skipping ENI owned by another controller", LogKeyPod, req.NamespacedName, LogKeyENIID, eniInfo.ID, "owner", foreignErr.owner)
+			r.Recorder.Event(pod, corev1.EventTypeWarning, string(ReasonForeignController), err.Error())
+			details := ConditionDetails{Message: err.Error(), ENIID: eniInfo.ID, SubnetID: eniInfo.SubnetID, Owner: foreignErr.owner}
+			if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, ReasonForeignController, details); err != nil {
+				logger.Error(err, "Failed to update status", "pod", req.NamespacedName)
+			}
+			return ctrl.Result{RequeueAfter: foreignControllerRequeueDelay}, nil
+		}
+		var caseErr *tagKeyCaseConflictError
+		if errors.As(err, &caseErr) {
+			logger.Info("Tag keys clash by case with existing ENI tags", LogKeyPod, req.NamespacedName, LogKeyENIID, eniInfo.ID, LogKeyError, caseErr.Error())
+			r.Recorder.Event(pod, corev1.EventTypeWarning, string(ReasonInvalidTags), caseErr.Error())
+			details := ConditionDetails{Message: caseErr.Error(), ENIID: eniInfo.ID, SubnetID: eniInfo.SubnetID}
+			if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, ReasonInvalidTags, details); err != nil {
+				logger.Error(err, "Failed to update status", "pod", req.NamespacedName)
+			}
+			return ctrl.Result{RequeueAfter: caseConflictRequeueDelay}, nil
+		}
+		var deferErr *deferredError
+		if errors.As(err, &deferErr) {
+			logger.Info("Deferring ENI tag change", LogKeyENIID, eniInfo.ID, "until", deferErr.until, "mutationBudget", deferErr.budget)
+			r.Recorder.Event(pod, corev1.EventTypeNormal, string(ReasonDeferred), deferErr.Error())
+			details := ConditionDetails{Message: deferErr.Error(), ENIID: eniInfo.ID, SubnetID: eniInfo.SubnetID}
+			if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, ReasonDeferred, details); err != nil {
+				logger.Error(err, "Failed to update status", "pod", req.NamespacedName)
+			}
+			return ctrl.Result{RequeueAfter: time.Until(deferErr.until)}, nil
+		}
+		var pausedErr *pausedError
+		if errors.As(err, &pausedErr) {
+			logger.Info("Tagging paused, not changing ENI tags", LogKeyENIID, eniInfo.ID, "toAdd", pausedErr.toAdd, "toRemove", pausedErr.toRemove)
+			details := ConditionDetails{Message: pausedErr.Error(), ENIID: eniInfo.ID, SubnetID: eniInfo.SubnetID}
+			if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, ReasonPaused, details); err != nil {
+				logger.Error(err, "Failed to update status", "pod", req.NamespacedName)
+			}
+			return ctrl.Result{RequeueAfter: pausedRequeueDelay}, nil
+		}
+		var policyErr *aws.TagPolicyViolationError
+		if errors.As(err, &policyErr) {
+			logger.Info("Tags rejected by an AWS Organizations tag policy", LogKeyPod, req.NamespacedName, LogKeyENIID, eniInfo.ID, "policyID", policyErr.PolicyID, "keys", policyErr.Keys)
+			r.Recorder.Event(pod, corev1.EventTypeWarning, string(ReasonTagPolicyViolation), tagPolicyEventMessage(policyErr))
+			details := ConditionDetails{
+				Message:       policyErr.Error(),
+				ENIID:         eniInfo.ID,
+				SubnetID:      eniInfo.SubnetID,
+				ErrorCode:     aws.ErrorCode(err),
+				TagPolicyID:   policyErr.PolicyID,
+				TagPolicyKeys: policyErr.Keys,
+			}
+			if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, ReasonTagPolicyViolation, details); err != nil {
+				logger.Error(err, "Failed to update status", "pod", req.NamespacedName)
+			}
+			// Permanent until the tags change, so no error-driven backoff retries
+			return ctrl.Result{RequeueAfter: tagPolicyRequeueDelay}, nil
+		}
+		var bestEffortErr *bestEffortError
+		if errors.As(err, &bestEffortErr) {
+			metrics.TaggingFailuresTotal.WithLabelValues(TagPriorityBestEffort).Inc()
+			logger.Info("Failed to apply best-effort ENI tags, retrying later", LogKeyENIID, eniInfo.ID, LogKeyError, err.Error(), LogKeyRequeueAfter, bestEffortRequeueDelay)
+			details := ConditionDetails{Message: bestEffortErr.Error(), ENIID: eniInfo.ID, SubnetID: eniInfo.SubnetID, ErrorCode: aws.ErrorCode(err)}
+			if err := r.updateStatus(ctx, pod, corev1.ConditionTrue, ReasonBestEffortTaggingFailed, details); err != nil {
+				logger.Error(err, "Failed to update status", "pod", req.NamespacedName)
+			}
+			return ctrl.Result{RequeueAfter: bestEffortRequeueDelay}, nil
+		}
+		metrics.TaggingFailuresTotal.WithLabelValues(TagPriorityCritical).Inc()
 		logger.Error(err, "Failed to apply ENI tags", LogKeyPod, req.NamespacedName, LogKeyENIID, eniInfo.ID)
-		r.Recorder.Event(pod, corev1.EventTypeWarning, "TaggingFailed", err.Error())
-		if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, "TaggingFailed", err.Error()); err != nil {
+		r.Recorder.Event(pod, corev1.EventTypeWarning, string(ReasonTaggingFailed), err.Error())
+		details := ConditionDetails{Message: err.Error(), ENIID: eniInfo.ID, SubnetID: eniInfo.SubnetID, ErrorCode: aws.ErrorCode(err)}
+		if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, ReasonTaggingFailed, details); err != nil {
 			logger.Error(err, "Failed to update status", "pod", req.NamespacedName)
 		}
 		return ctrl.Result{}, err
 	}
 
+	if r.DriftResync != nil {
+		r.DriftResync.done(req.NamespacedName)
+	}
+	if r.Stats != nil && !r.DryRun {
+		r.Stats.record(req.NamespacedName, eniInfo)
+	}
 	logger.Info("Successfully reconciled pod", LogKeyENIID, eniInfo.ID)
 	return ctrl.Result{}, nil
 }
 
-// ensureFinalizer adds the finalizer to the pod if it's missing.
-// Returns true if the pod was updated, false otherwise.
-func (r *PodReconciler) ensureFinalizer(ctx context.Context, pod *corev1.Pod) (bool, error) {
-	if !controllerutil.ContainsFinalizer(pod, finalizerName) {
-		controllerutil.AddFinalizer(pod, finalizerName)
-		if err := r.Update(ctx, pod); err != nil {
-			return false, err
+// isPodExcluded reports whether the pod matches the configured exclusion selector,
+// or is a debug pod left untagged. An unset or empty selector never matches.
+func (r *PodReconciler) isPodExcluded(pod *corev1.Pod) bool {
+	return r.exclusionReason(pod) != ""
+}
+
+// exclusionReason explains why the pod is never tagged, or returns "" if it may be.
+func (r *PodReconciler) exclusionReason(pod *corev1.Pod) string {
+	if r.skipsDebugPod(pod) {
+		return "pod was created by kubectl debug"
+	}
+	if r.ExcludePodSelector == nil || r.ExcludePodSelector.Empty() {
+		return ""
+	}
+	if r.ExcludePodSelector.Matches(labels.Set(pod.Labels)) {
+		return fmt.Sprintf("pod matches exclude selector %q", r.ExcludePodSelector.String())
+	}
+	return ""
+}
+
+// reportRateLimited sets the RateLimited condition on an annotated pod whose
+// reconcile was deferred until nextAttempt. While the condition already names a
+// future attempt it is left alone, so a pod hitting its limit repeatedly is not
+// patched on every event.
+func (r *PodReconciler) reportRateLimited(ctx context.Context, key client.ObjectKey, nextAttempt time.Time) {
+	logger := log.FromContext(ctx)
+	pod := &corev1.Pod{}
+	if err := r.getPod(ctx, key, pod); err != nil {
+		return
+	}
+	if _, ok := r.tagAnnotation(pod); !ok || pod.DeletionTimestamp != nil {
+		return
+	}
+
+	conditionType := corev1.PodConditionType(r.keys().ConditionType)
+	for _, c := range pod.Status.Conditions {
+		if c.Type != conditionType || c.Reason != string(ReasonRateLimited) {
+			continue
+		}
+		if details, err := ParseConditionDetails(c.Message); err == nil {
+			if pending, err := time.Parse(time.RFC3339, details.NextAttempt); err == nil && pending.After(time.Now()) {
+				return
+			}
 		}
-		return true, nil
 	}
-	return false, nil
+
+	details := ConditionDetails{
+		Message:     fmt.Sprintf("Reconcile deferred by the per-pod rate limit of %g/s", r.PodRateLimitQPS),
+		NextAttempt: nextAttempt.UTC().Format(time.RFC3339),
+	}
+	if err := r.updateStatus(ctx, pod, corev1.ConditionFalse, ReasonRateLimited, details); err != nil {
+		logger.V(1).Info("Failed to report rate limited reconcile", LogKeyError, err.Error())
+	}
+}
+
+// tagPolicyEventMessage explains a tag policy rejection in the pod's event,
+// naming the policy and keys when AWS reported them.
+func tagPolicyEventMessage(e *aws.TagPolicyViolationError) string {
+	var b strings.Builder
+	b.WriteString("Tags rejected by an AWS Organizations tag policy")
+	if e.PolicyID != "" {
+		fmt.Fprintf(&b, " %s", e.PolicyID)
+	}
+	if len(e.Keys) > 0 {
+		fmt.Fprintf(&b, ", non-compliant keys: %s", strings.Join(e.Keys, ", "))
+	}
+	fmt.Fprintf(&b, ". AWS: %s. Retried when the tag annotation changes, or in %s.", e.Message, tagPolicyRequeueDelay)
+	return b.String()
 }
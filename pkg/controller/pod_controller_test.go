@@ -1,10 +1,14 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestValidateTags(t *testing.T) {
@@ -58,6 +62,21 @@ func TestValidateTags(t *testing.T) {
 			annotation:  `{"aws:Name":"test"}`,
 			expectError: true,
 		},
+		{
+			name:        "reserved prefix aws: in upper case",
+			annotation:  `{"AWS:Name":"test"}`,
+			expectError: true,
+		},
+		{
+			name:        "reserved prefix aws: in mixed case",
+			annotation:  `CostCenter=1,Aws:cloudformation=stack`,
+			expectError: true,
+		},
+		{
+			name:        "partition name is not a reserved prefix",
+			annotation:  `{"aws-cn-team":"platform"}`,
+			expectError: false,
+		},
 		{
 			name:        "reserved prefix kubernetes.io/cluster/",
 			annotation:  `{"kubernetes.io/cluster/test":"owned"}`,
@@ -67,7 +86,7 @@ func TestValidateTags(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := validateTags(tt.annotation)
+			err := validateTags(tt.annotation, nil, TagKeyCaseAllow)
 			if tt.expectError {
 				assert.Error(t, err)
 			} else {
@@ -77,6 +96,106 @@ func TestValidateTags(t *testing.T) {
 	}
 }
 
+func TestResolveKeyCaseConflicts(t *testing.T) {
+	tags := map[string]string{"Team": "platform", "team": "platform", "Env": "prod"}
+
+	got, err := resolveKeyCaseConflicts(tags, TagKeyCaseAllow)
+	assert.NoError(t, err)
+	assert.Equal(t, tags, got)
+
+	_, err = resolveKeyCaseConflicts(tags, TagKeyCaseReject)
+	assert.EqualError(t, err, `tag keys differ only by case: ["Team" "team"]`)
+
+	got, err = resolveKeyCaseConflicts(tags, TagKeyCaseNormalize)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"Team": "platform", "Env": "prod"}, got)
+
+	_, err = resolveKeyCaseConflicts(map[string]string{"Team": "a", "TEAM": "b"}, TagKeyCaseNormalize)
+	assert.EqualError(t, err, `tag keys "TEAM" and "Team" differ only by case but have different values`)
+
+	assert.Error(t, validateTags(`Team=a,team=a`, nil, TagKeyCaseReject))
+	assert.NoError(t, validateTags(`Team=a,team=a`, nil, TagKeyCaseNormalize))
+}
+
+func TestAlignKeyCaseWithENI(t *testing.T) {
+	eniTags := map[string]string{"team": "legacy", "Env": "prod", "Owner": "old"}
+	managed := func(k string) bool { return k == "Owner" }
+	desired := map[string]string{"Team": "platform", "Env": "prod", "owner": "new"}
+
+	got, err := alignKeyCaseWithENI(desired, eniTags, managed, TagKeyCaseAllow)
+	assert.NoError(t, err)
+	assert.Equal(t, desired, got)
+
+	_, err = alignKeyCaseWithENI(desired, eniTags, managed, TagKeyCaseReject)
+	assert.EqualError(t, err, `tag key "Team" differs only by case from existing ENI tag "team"`)
+
+	// Managed keys (here the last applied "Owner") are ours to replace, so only
+	// the foreign "team" is adopted.
+	got, err = alignKeyCaseWithENI(desired, eniTags, managed, TagKeyCaseNormalize)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "platform", "Env": "prod", "owner": "new"}, got)
+}
+
+func TestRenameTagKeys(t *testing.T) {
+	renames := map[string]string{"team": "CostTeam", "env": "Environment"}
+
+	got, err := renameTagKeys(map[string]string{"team": "platform", "Owner": "alice"}, renames)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"CostTeam": "platform", "Owner": "alice"}, got)
+
+	_, err = renameTagKeys(map[string]string{"team": "a", "CostTeam": "b"}, renames)
+	assert.EqualError(t, err, `tag keys "CostTeam" and "team" both map to "CostTeam"`)
+
+	tags := map[string]string{"team": "a"}
+	got, err = renameTagKeys(tags, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, tags, got)
+
+	assert.Error(t, validateTags(`team=a,CostTeam=b`, renames, TagKeyCaseAllow))
+	// Renamed keys take part in case conflict checks
+	assert.Error(t, validateTags(`team=a,costteam=a`, renames, TagKeyCaseReject))
+
+	r := &PodReconciler{TagKeyRenames: renames, TagNamespace: "enable"}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "apps"}}
+	got, err = r.desiredTags(context.Background(), pod, "team=platform")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"apps:CostTeam": "platform"}, got, "renames apply before the namespace prefix")
+
+	assert.NoError(t, ValidateTagKeyRenames(renames))
+	assert.Error(t, ValidateTagKeyRenames(map[string]string{"team": "aws:team"}))
+}
+
+// tagSourceFunc adapts a function to TagSource.
+type tagSourceFunc func(ctx context.Context, pod *corev1.Pod) (map[string]string, error)
+
+func (f tagSourceFunc) Tags(ctx context.Context, pod *corev1.Pod) (map[string]string, error) {
+	return f(ctx, pod)
+}
+
+func TestDesiredTagsExternalSource(t *testing.T) {
+	external := map[string]string{"CostCenter": "1234", "team": "finance"}
+	var sourceErr error
+	r := &PodReconciler{
+		TagNamespace: "enable",
+		TagSource: tagSourceFunc(func(ctx context.Context, pod *corev1.Pod) (map[string]string, error) {
+			return external, sourceErr
+		}),
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "apps"}}
+
+	got, err := r.desiredTags(context.Background(), pod, "team=platform")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"apps:CostCenter": "1234", "apps:team": "platform"}, got, "annotation tags win")
+
+	external = map[string]string{"aws:reserved": "x"}
+	_, err = r.desiredTags(context.Background(), pod, "team=platform")
+	assert.ErrorContains(t, err, "invalid tags from external tag source")
+
+	sourceErr = errors.New("connection refused")
+	_, err = r.desiredTags(context.Background(), pod, "team=platform")
+	assert.ErrorContains(t, err, "connection refused")
+}
+
 func TestApplyNamespace(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -0,0 +1,291 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// podWriteFlushTimeout bounds the patches sent when a window closes.
+const podWriteFlushTimeout = 30 * time.Second
+
+// PodWriteBuffer collapses the controller's writes to a pod made within Window
+// of each other into one patch for the pod's metadata and one for its status.
+// When many pods are reconciled per second, a pod often gets its last-applied
+// annotations and condition rewritten several times in quick succession (e.g.
+// RateLimited, then Synced), and these writes dominate API server load.
+//
+// The first write to a pod opens a window; later writes replace what is pending
+// and the patch sent when the window closes goes from the pod as it was before
+// the first write to the pod after the last one. Writes return as soon as they
+// are queued. Pods fetched by the controller are overlaid with their pending
+// writes, so a reconcile within the window sees its predecessor's results.
+//
+// Writes that add a finalizer or record a pending transition are sent at once,
+// together with anything pending, so tags are protected as soon as possible and
+// a pod that went away is noticed while its tags can still be rolled back (see
+// rollbackUnprotectedTags). Status is patched with conditions merged by
+// type, so conditions set by others within the window are kept.
+// Failed patches are logged and the pod is reconciled again. Pending writes are
+// flushed on shutdown.
+type PodWriteBuffer struct {
+	client client.Client
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[types.NamespacedName]*pendingPodWrites
+
+	// events requeues pods whose patch failed.
+	events chan event.GenericEvent
+}
+
+// pendingPodWrites holds the writes to one pod until its window closes. The
+// base fields hold the pod before the first write, the others after the last.
+type pendingPodWrites struct {
+	ctx context.Context
+
+	metadataBase, metadata *corev1.Pod
+	statusBase, status     *corev1.Pod
+	metadataWrites         int
+	statusWrites           int
+}
+
+// NewPodWriteBuffer returns a buffer that holds pod writes for window before
+// sending them through c.
+func NewPodWriteBuffer(c client.Client, window time.Duration) (*PodWriteBuffer, error) {
+	if window <= 0 {
+		return nil, fmt.Errorf("pod write collapse window must be positive, got %s", window)
+	}
+	return &PodWriteBuffer{
+		client:  c,
+		window:  window,
+		pending: make(map[types.NamespacedName]*pendingPodWrites),
+		events:  make(chan event.GenericEvent, 100),
+	}, nil
+}
+
+// patch queues a strategic merge patch of pod's metadata from base, or sends it
+// at once, with any pending metadata write, when it adds a finalizer.
+func (b *PodWriteBuffer) patch(ctx context.Context, base, pod *corev1.Pod) error {
+	if addsFinalizer(base, pod) {
+		return b.patchNow(ctx, base, pod)
+	}
+
+	if changed, err := patchChanges(pod, client.StrategicMergeFrom(base)); err != nil || !changed {
+		return err
+	}
+	b.queue(ctx, pod, func(p *pendingPodWrites) {
+		if p.metadata == nil {
+			p.metadataBase = base.DeepCopy()
+		}
+		p.metadata = pod.DeepCopy()
+		p.metadataWrites++
+	})
+	return nil
+}
+
+// patchNow sends a strategic merge patch of pod's metadata from base at once,
+// together with any pending metadata write. pod must include the pending writes,
+// as pods fetched through getPod do. A later write then starts a new window from
+// pod as sent, so it can undo what this one added.
+func (b *PodWriteBuffer) patchNow(ctx context.Context, base, pod *corev1.Pod) error {
+	key := client.ObjectKeyFromObject(pod)
+	b.mu.Lock()
+	if p, ok := b.pending[key]; ok && p.metadata != nil {
+		base = p.metadataBase
+		p.metadata, p.metadataBase, p.metadataWrites = nil, nil, 0
+	}
+	b.mu.Unlock()
+	return patchIfChanged(ctx, b.client, pod, client.StrategicMergeFrom(base))
+}
+
+// patchStatus queues a strategic merge patch of pod's status from base.
+// Conditions are merged by type, so the kubelet's conditions changed meanwhile
+// are kept.
+func (b *PodWriteBuffer) patchStatus(ctx context.Context, base, pod *corev1.Pod) error {
+	if changed, err := patchChanges(pod, client.StrategicMergeFrom(base)); err != nil || !changed {
+		return err
+	}
+	b.queue(ctx, pod, func(p *pendingPodWrites) {
+		if p.status == nil {
+			p.statusBase = base.DeepCopy()
+		}
+		// Only the status is written; metadata changes go through patch
+		p.status = p.statusBase.DeepCopy()
+		pod.Status.DeepCopyInto(&p.status.Status)
+		p.statusWrites++
+	})
+	return nil
+}
+
+func (b *PodWriteBuffer) queue(ctx context.Context, pod *corev1.Pod, update func(*pendingPodWrites)) {
+	key := client.ObjectKeyFromObject(pod)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, ok := b.pending[key]
+	if !ok {
+		// The reconcile's context carries its logger; the patch outlives the reconcile
+		p = &pendingPodWrites{ctx: context.WithoutCancel(ctx)}
+		b.pending[key] = p
+		time.AfterFunc(b.window, func() { b.flush(key, p) })
+	}
+	update(p)
+}
+
+// overlay applies the writes pending for pod to it: changed annotations and
+// changed conditions.
+func (b *PodWriteBuffer) overlay(pod *corev1.Pod) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, ok := b.pending[client.ObjectKeyFromObject(pod)]
+	if !ok {
+		return
+	}
+	latest := p.metadata
+	if latest == nil {
+		latest = p.status
+	}
+	// Writes pending for an earlier pod of the same name must not leak into this one
+	if latest == nil || latest.UID != pod.UID {
+		return
+	}
+
+	if p.metadata != nil {
+		for k, v := range p.metadata.Annotations {
+			if old, ok := p.metadataBase.Annotations[k]; !ok || old != v {
+				if pod.Annotations == nil {
+					pod.Annotations = make(map[string]string)
+				}
+				pod.Annotations[k] = v
+			}
+		}
+		for k := range p.metadataBase.Annotations {
+			if _, ok := p.metadata.Annotations[k]; !ok {
+				delete(pod.Annotations, k)
+			}
+		}
+	}
+	if p.status != nil {
+		for _, c := range p.status.Status.Conditions {
+			if old := findCondition(p.statusBase.Status.Conditions, c.Type); old != nil && *old == c {
+				continue
+			}
+			if current := findCondition(pod.Status.Conditions, c.Type); current != nil {
+				*current = c
+			} else {
+				pod.Status.Conditions = append(pod.Status.Conditions, c)
+			}
+		}
+	}
+}
+
+// discard drops the writes pending for a pod, e.g. once it is released for deletion.
+func (b *PodWriteBuffer) discard(key types.NamespacedName) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.pending, key)
+}
+
+// flush sends the writes collected for a pod when its window closes.
+func (b *PodWriteBuffer) flush(key types.NamespacedName, p *pendingPodWrites) {
+	b.mu.Lock()
+	if b.pending[key] != p {
+		// Discarded, or flushed on shutdown
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, key)
+	b.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(p.ctx, podWriteFlushTimeout)
+	defer cancel()
+	b.send(ctx, key, p)
+}
+
+func (b *PodWriteBuffer) send(ctx context.Context, key types.NamespacedName, p *pendingPodWrites) {
+	logger := log.FromContext(ctx)
+	var err error
+	if p.metadata != nil {
+		if err = b.client.Patch(ctx, p.metadata, client.StrategicMergeFrom(p.metadataBase)); err == nil {
+			metrics.PodWritesCollapsedTotal.Add(float64(p.metadataWrites - 1))
+		}
+	}
+	if p.status != nil && err == nil {
+		if err = b.client.Status().Patch(ctx, p.status, client.StrategicMergeFrom(p.statusBase)); err == nil {
+			metrics.PodWritesCollapsedTotal.Add(float64(p.statusWrites - 1))
+		}
+	}
+	if err == nil || apierrors.IsNotFound(err) {
+		return
+	}
+
+	logger.Error(err, "Failed to write collapsed pod updates, requeueing pod", LogKeyPod, key)
+	select {
+	case b.events <- event.GenericEvent{Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}}:
+	default:
+		// The pod is reconciled again on its next event or resync
+	}
+}
+
+// Start waits for shutdown, then sends every pending write.
+func (b *PodWriteBuffer) Start(ctx context.Context) error {
+	<-ctx.Done()
+
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = make(map[types.NamespacedName]*pendingPodWrites)
+	b.mu.Unlock()
+
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), podWriteFlushTimeout)
+	defer cancel()
+	for key, p := range pending {
+		b.send(flushCtx, key, p)
+	}
+	return nil
+}
+
+// source returns the channel of pods to requeue after a failed patch.
+func (b *PodWriteBuffer) source() source.Source {
+	return &source.Channel{Source: b.events}
+}
+
+// addsFinalizer reports whether pod has a finalizer base lacks.
+func addsFinalizer(base, pod *corev1.Pod) bool {
+	for _, f := range pod.Finalizers {
+		if !controllerutil.ContainsFinalizer(base, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// patchChanges reports whether patch changes anything in obj.
+func patchChanges(obj client.Object, patch client.Patch) (bool, error) {
+	data, err := patch.Data(obj)
+	if err != nil {
+		return false, err
+	}
+	return string(data) != "{}", nil
+}
+
+func findCondition(conditions []corev1.PodCondition, t corev1.PodConditionType) *corev1.PodCondition {
+	for i := range conditions {
+		if conditions[i].Type == t {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestNewPodWriteBuffer(t *testing.T) {
+	_, err := NewPodWriteBuffer(fake.NewClientBuilder().Build(), 0)
+	assert.Error(t, err)
+}
+
+func TestPodWriteBuffer(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	ctx := context.Background()
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-writes", Namespace: "default", UID: "uid-writes"}}
+	var patches, statusPatches atomic.Int32
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patches.Add(1)
+			return c.Patch(ctx, obj, patch, opts...)
+		},
+		SubResourcePatch: func(ctx context.Context, c client.Client, subResource string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+			statusPatches.Add(1)
+			return c.SubResource(subResource).Patch(ctx, obj, patch, opts...)
+		},
+	}).Build()
+
+	writes, err := NewPodWriteBuffer(k8sClient, 100*time.Millisecond)
+	require.NoError(t, err)
+	r := &PodReconciler{Client: k8sClient, PodWrites: writes}
+	key := client.ObjectKeyFromObject(pod)
+
+	// The first write adds the finalizer and is sent at once
+	current := &corev1.Pod{}
+	require.NoError(t, r.getPod(ctx, key, current))
+	require.NoError(t, updatePodAnnotations(ctx, r, current, map[string]string{"team": "a"}, "hash-a"))
+	assert.Equal(t, int32(1), patches.Load())
+
+	// Later writes within the window are collapsed, and seen by the next reconcile
+	for _, team := range []string{"b", "c"} {
+		current = &corev1.Pod{}
+		require.NoError(t, r.getPod(ctx, key, current))
+		require.NoError(t, updatePodAnnotations(ctx, r, current, map[string]string{"team": team}, "hash-"+team))
+		require.NoError(t, r.updateStatus(ctx, current, corev1.ConditionFalse, ReasonRateLimited, ConditionDetails{Message: team}))
+	}
+	current = &corev1.Pod{}
+	require.NoError(t, r.getPod(ctx, key, current))
+	assert.Equal(t, "hash-c", current.Annotations[LastAppliedHashKey])
+	require.Len(t, current.Status.Conditions, 1)
+	assert.Equal(t, string(ReasonRateLimited), current.Status.Conditions[0].Reason)
+	assert.Equal(t, int32(1), patches.Load())
+	assert.Equal(t, int32(0), statusPatches.Load())
+
+	assert.Eventually(t, func() bool { return statusPatches.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(2), patches.Load())
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, key, stored))
+	assert.Equal(t, "hash-c", stored.Annotations[LastAppliedHashKey])
+	assert.Contains(t, stored.Finalizers, finalizerName)
+	details, err := ParseConditionDetails(stored.Status.Conditions[0].Message)
+	require.NoError(t, err)
+	assert.Equal(t, "c", details.Message)
+
+	// Writes pending at shutdown are sent
+	require.NoError(t, updatePodAnnotations(ctx, r, stored, map[string]string{"team": "d"}, "hash-d"))
+	stopped, stop := context.WithCancel(ctx)
+	stop()
+	require.NoError(t, writes.Start(stopped))
+	require.NoError(t, k8sClient.Get(ctx, key, stored))
+	assert.Equal(t, "hash-d", stored.Annotations[LastAppliedHashKey])
+}
+
+func TestPodWriteBuffer_PendingTransition(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	ctx := context.Background()
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-pending", Namespace: "default", UID: "uid-pending", Finalizers: []string{finalizerName}}}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+	writes, err := NewPodWriteBuffer(k8sClient, time.Hour)
+	require.NoError(t, err)
+	r := &PodReconciler{Client: k8sClient, PodWrites: writes}
+	key := client.ObjectKeyFromObject(pod)
+
+	current := &corev1.Pod{}
+	require.NoError(t, r.getPod(ctx, key, current))
+	require.NoError(t, updatePodAnnotations(ctx, r, current, map[string]string{"team": "a"}, "hash-a"))
+
+	// The transition is sent at once, with the write queued before it
+	current = &corev1.Pod{}
+	require.NoError(t, r.getPod(ctx, key, current))
+	require.NoError(t, r.recordPendingTransition(ctx, current, nil, map[string]string{"team": "b"}, "hash-b"))
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, key, stored))
+	assert.Contains(t, stored.Annotations, NewKeys("").PendingTransition)
+	assert.Equal(t, "hash-a", stored.Annotations[LastAppliedHashKey])
+
+	current = &corev1.Pod{}
+	require.NoError(t, r.getPod(ctx, key, current))
+	require.NoError(t, updatePodAnnotations(ctx, r, current, map[string]string{"team": "b"}, "hash-b"))
+
+	stopped, stop := context.WithCancel(ctx)
+	stop()
+	require.NoError(t, writes.Start(stopped))
+	require.NoError(t, k8sClient.Get(ctx, key, stored))
+	assert.NotContains(t, stored.Annotations, NewKeys("").PendingTransition)
+	assert.Equal(t, "hash-b", stored.Annotations[LastAppliedHashKey])
+}
+
+func TestPodWriteBuffer_KeepsOtherConditions(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	ctx := context.Background()
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod-conditions", Namespace: "default", UID: "uid-conditions"},
+		Status:     corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}}},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+	writes, err := NewPodWriteBuffer(k8sClient, time.Hour)
+	require.NoError(t, err)
+	r := &PodReconciler{Client: k8sClient, PodWrites: writes}
+	key := client.ObjectKeyFromObject(pod)
+
+	current := &corev1.Pod{}
+	require.NoError(t, r.getPod(ctx, key, current))
+	require.NoError(t, r.updateStatus(ctx, current, corev1.ConditionTrue, ReasonSynced, ConditionDetails{}))
+
+	// The kubelet marks the pod ready within the window
+	kubelet := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, key, kubelet))
+	kubelet.Status.Conditions[0].Status = corev1.ConditionTrue
+	require.NoError(t, k8sClient.Status().Update(ctx, kubelet))
+
+	stopped, stop := context.WithCancel(ctx)
+	stop()
+	require.NoError(t, writes.Start(stopped))
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, key, stored))
+	require.Len(t, stored.Status.Conditions, 2)
+	assert.Equal(t, corev1.ConditionTrue, findCondition(stored.Status.Conditions, corev1.PodReady).Status)
+	assert.Equal(t, string(ReasonSynced), findCondition(stored.Status.Conditions, corev1.PodConditionType(ConditionTypeEniTagged)).Reason)
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s-eni-tagger/pkg/aws"
+)
+
+// Tag priorities, used as the priority label of the tagging failures metric.
+const (
+	// TagPriorityCritical tags must be on the ENI: failing to write them sets the
+	// condition to False, which holds pods using it as a readiness gate, and is
+	// retried with error backoff.
+	TagPriorityCritical = "critical"
+	// TagPriorityBestEffort tags are cosmetic: failing to write them is logged and
+	// retried after bestEffortRequeueDelay, but the condition stays True.
+	TagPriorityBestEffort = "best-effort"
+)
+
+// isCriticalTagKey reports whether an ENI tag key is critical. Without
+// CriticalTagKeys every tag is critical. Patterns match a key exactly or, ending
+// in "*", by prefix.
+func (r *PodReconciler) isCriticalTagKey(key string) bool {
+	if len(r.CriticalTagKeys) == 0 {
+		return true
+	}
+	for _, pattern := range r.CriticalTagKeys {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// changePriority returns the priority of a tag change: critical if it adds,
+// updates or removes any critical tag.
+func (r *PodReconciler) changePriority(diff *tagDiff) string {
+	for k := range diff.toAdd {
+		if r.isCriticalTagKey(k) {
+			return TagPriorityCritical
+		}
+	}
+	for _, k := range diff.toRemove {
+		if r.isCriticalTagKey(k) {
+			return TagPriorityCritical
+		}
+	}
+	return TagPriorityBestEffort
+}
+
+// tagChangeError wraps the error of a failed tag change in a bestEffortError when
+// the change touched no critical tag.
+func (r *PodReconciler) tagChangeError(eniInfo *aws.ENIInfo, diff *tagDiff, err error) error {
+	if r.changePriority(diff) == TagPriorityBestEffort {
+		return &bestEffortError{eniID: eniInfo.ID, err: err}
+	}
+	return err
+}
+
+// bestEffortError is returned by applyENITags when a change to best-effort tags
+// only failed. The critical tags on the ENI are already as desired.
+type bestEffortError struct {
+	eniID string
+	err   error
+}
+
+func (e *bestEffortError) Error() string {
+	return fmt.Sprintf("best-effort tags not applied to ENI %s: %v", e.eniID, e.err)
+}
+
+func (e *bestEffortError) Unwrap() error {
+	return e.err
+}
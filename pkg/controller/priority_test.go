@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestChangePriority(t *testing.T) {
+	r := &PodReconciler{}
+	assert.Equal(t, TagPriorityCritical, r.changePriority(&tagDiff{toAdd: map[string]string{"Name": "x"}}))
+
+	r.CriticalTagKeys = []string{"CostCenter", "billing:*"}
+	assert.True(t, r.isCriticalTagKey("billing:project"))
+	assert.False(t, r.isCriticalTagKey("CostCenter2"))
+	assert.Equal(t, TagPriorityBestEffort, r.changePriority(&tagDiff{toAdd: map[string]string{"Name": "x"}, toRemove: []string{"Owner"}}))
+	assert.Equal(t, TagPriorityCritical, r.changePriority(&tagDiff{toAdd: map[string]string{"Name": "x"}, toRemove: []string{"billing:project"}}))
+	assert.Equal(t, TagPriorityCritical, r.changePriority(&tagDiff{toAdd: map[string]string{"CostCenter": "1"}}))
+}
+
+func TestReconcileBestEffortTaggingFailure(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	applied := map[string]string{"CostCenter": "1234"}
+	hash := computeHash(applied)
+	newPod := func(tags string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pod-priority",
+				Namespace: "default",
+				Annotations: map[string]string{
+					AnnotationKey:            tags,
+					LastAppliedAnnotationKey: `{"CostCenter":"1234"}`,
+					LastAppliedHashKey:       hash,
+				},
+				Finalizers: []string{finalizerName},
+			},
+			Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+		}
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKey{Name: "pod-priority", Namespace: "default"}}
+
+	for name, tc := range map[string]struct {
+		tags   string
+		reason ConditionReason
+		status corev1.ConditionStatus
+	}{
+		"best-effort tag only": {`{"CostCenter":"1234","Description":"frontend"}`, ReasonBestEffortTaggingFailed, corev1.ConditionTrue},
+		"critical tag changed": {`{"CostCenter":"5678","Description":"frontend"}`, ReasonTaggingFailed, corev1.ConditionFalse},
+	} {
+		t.Run(name, func(t *testing.T) {
+			k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newPod(tc.tags)).Build()
+			recorder := record.NewFakeRecorder(10)
+			mockAWS := new(MockAWSClient)
+			mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{
+				ID:   "eni-priority",
+				Tags: map[string]string{"CostCenter": "1234", HashTagKey: hash},
+			}, nil)
+			mockAWS.On("TagENI", mock.Anything, "eni-priority", mock.Anything).Return(errors.New("RequestLimitExceeded"))
+
+			r := &PodReconciler{
+				Client:          k8sClient,
+				Scheme:          scheme,
+				Recorder:        recorder,
+				AWSClient:       mockAWS,
+				AnnotationKey:   AnnotationKey,
+				CriticalTagKeys: []string{"CostCenter"},
+			}
+			res, err := r.Reconcile(context.Background(), req)
+			if tc.reason == ReasonBestEffortTaggingFailed {
+				require.NoError(t, err)
+				assert.Equal(t, bestEffortRequeueDelay, res.RequeueAfter)
+				assert.Empty(t, recorder.Events, "best-effort failures must not send Warning events")
+			} else {
+				assert.Error(t, err)
+			}
+
+			pod := &corev1.Pod{}
+			require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, pod))
+			require.Len(t, pod.Status.Conditions, 1)
+			assert.Equal(t, string(tc.reason), pod.Status.Conditions[0].Reason)
+			assert.Equal(t, tc.status, pod.Status.Conditions[0].Status)
+		})
+	}
+}
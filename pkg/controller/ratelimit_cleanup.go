@@ -3,8 +3,11 @@ package controller
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
+	"k8s-eni-tagger/pkg/metrics"
+
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -47,6 +50,7 @@ func (r *PodReconciler) cleanupStaleLimiters(ctx context.Context) {
 	}
 
 	removed := 0
+	live := 0
 
 	r.PodRateLimiters.Range(func(key, value interface{}) bool {
 		podKey, ok := key.(string)
@@ -72,11 +76,68 @@ func (r *PodReconciler) cleanupStaleLimiters(ctx context.Context) {
 			r.PodRateLimiters.Delete(podKey)
 			removed++
 			logger.V(1).Info("Removed stale rate limiter", "pod", podKey, "lastAccess", lastAccess)
+		} else {
+			live++
 		}
 		return true
 	})
+	r.podRateLimiterCount.Store(int64(live))
+	metrics.PodRateLimiters.Set(float64(live))
 
 	if removed > 0 {
 		logger.Info("Cleaned up stale rate limiters", "removed", removed, "threshold", r.RateLimiterCleanupThreshold)
 	}
 }
+
+// trackNewRateLimiter counts a limiter just added to PodRateLimiters and evicts
+// the least recently used ones once PodRateLimitersMax is exceeded, so heavy pod
+// churn cannot grow the map without bound between cleanups.
+func (r *PodReconciler) trackNewRateLimiter(ctx context.Context) {
+	n := r.podRateLimiterCount.Add(1)
+	if r.PodRateLimitersMax > 0 && n > int64(r.PodRateLimitersMax) {
+		r.evictRateLimiters(ctx)
+		return
+	}
+	metrics.PodRateLimiters.Set(float64(n))
+}
+
+// evictRateLimiters removes the least recently used limiters down to 90% of
+// PodRateLimitersMax, so the map is scanned once per tenth of the cap rather
+// than for every new pod. An evicted pod starts over with a full burst.
+func (r *PodReconciler) evictRateLimiters(ctx context.Context) {
+	r.rateLimiterEvictMu.Lock()
+	defer r.rateLimiterEvictMu.Unlock()
+
+	type lruEntry struct {
+		key        interface{}
+		lastAccess time.Time
+	}
+	var entries []lruEntry
+	r.PodRateLimiters.Range(func(key, value interface{}) bool {
+		// Invalid values keep a zero time and are evicted first
+		e := lruEntry{key: key}
+		if entry, ok := value.(*RateLimiterEntry); ok && entry != nil {
+			e.lastAccess = entry.GetLastAccess()
+		}
+		entries = append(entries, e)
+		return true
+	})
+
+	// Another eviction may have run while this one waited for the lock
+	keep := r.PodRateLimitersMax * 9 / 10
+	if len(entries) <= r.PodRateLimitersMax {
+		keep = len(entries)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].lastAccess.Before(entries[j].lastAccess) })
+	evicted := entries[:len(entries)-keep]
+	for _, e := range evicted {
+		r.PodRateLimiters.Delete(e.key)
+	}
+
+	r.podRateLimiterCount.Store(int64(keep))
+	metrics.PodRateLimiters.Set(float64(keep))
+	if len(evicted) > 0 {
+		metrics.PodRateLimiterEvictionsTotal.Add(float64(len(evicted)))
+		log.FromContext(ctx).Info("Evicted least recently used pod rate limiters", "evicted", len(evicted), "max", r.PodRateLimitersMax)
+	}
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -279,3 +280,34 @@ func TestCleanupIntegration(t *testing.T) {
 	_, exists = r.PodRateLimiters.Load("default/new-stale-pod")
 	assert.False(t, exists, "newly added stale entry should be removed")
 }
+
+func TestRateLimiterPoolCap(t *testing.T) {
+	r := &PodReconciler{
+		PodRateLimiters:             &sync.Map{},
+		PodRateLimitersMax:          10,
+		RateLimiterCleanupThreshold: time.Hour,
+	}
+	ctx := context.Background()
+	base := time.Now().Add(-time.Minute)
+	for i := 0; i < 10; i++ {
+		r.PodRateLimiters.Store(fmt.Sprintf("default/pod-%d", i), newTestRateLimiterEntry(base.Add(time.Duration(i)*time.Second)))
+		r.trackNewRateLimiter(ctx)
+	}
+	assert.Equal(t, int64(10), r.podRateLimiterCount.Load())
+
+	// The 11th limiter evicts the least recently used down to 90% of the cap
+	r.PodRateLimiters.Store("default/new", newTestRateLimiterEntry(time.Now()))
+	r.trackNewRateLimiter(ctx)
+	assert.Equal(t, int64(9), r.podRateLimiterCount.Load())
+	for i := 0; i < 2; i++ {
+		_, ok := r.PodRateLimiters.Load(fmt.Sprintf("default/pod-%d", i))
+		assert.False(t, ok, "pod-%d should be evicted", i)
+	}
+	_, ok := r.PodRateLimiters.Load("default/new")
+	assert.True(t, ok)
+
+	// Cleanup corrects the count
+	r.PodRateLimiters.Store("default/untracked", newTestRateLimiterEntry(time.Now()))
+	r.cleanupStaleLimiters(ctx)
+	assert.Equal(t, int64(10), r.podRateLimiterCount.Load())
+}
@@ -0,0 +1,141 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ReadOnlyAudit runs the pod controller as an auditor: each annotated pod's
+// desired tags are compared with the live tags of its ENI and the difference is
+// exported as k8s_eni_tagger_readonly_tag_drift, but nothing is written. Unlike
+// DryRun, pods get no finalizer, annotations or condition, and bookkeeping left
+// by an earlier installation is neither used to clean up nor removed. The AWS
+// client should be read-only too (aws.ClientOptions.ReadOnly).
+//
+// Series are kept per ENI and dropped once no audited pod refers to the ENI.
+type ReadOnlyAudit struct {
+	// interval requeues audited pods so drift made outside Kubernetes is seen;
+	// zero audits pods on their own events only.
+	interval time.Duration
+
+	mu sync.Mutex
+	// pods maps each audited pod to its ENI ID.
+	pods map[types.NamespacedName]string
+	// enis counts the audited pods of each ENI.
+	enis map[string]int
+}
+
+// NewReadOnlyAudit returns an audit requeueing each pod every interval.
+func NewReadOnlyAudit(interval time.Duration) *ReadOnlyAudit {
+	return &ReadOnlyAudit{
+		interval: interval,
+		pods:     make(map[types.NamespacedName]string),
+		enis:     make(map[string]int),
+	}
+}
+
+// record exports the changes the ENI of pod needs. Pods sharing an ENI report
+// the changes of the last one audited.
+func (a *ReadOnlyAudit) record(pod types.NamespacedName, eniID string, toAdd, toRemove int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pods[pod] != eniID {
+		a.releaseLocked(pod)
+		a.pods[pod] = eniID
+		a.enis[eniID]++
+	}
+	metrics.ReadOnlyTagDrift.WithLabelValues(eniID, "add").Set(float64(toAdd))
+	metrics.ReadOnlyTagDrift.WithLabelValues(eniID, "remove").Set(float64(toRemove))
+}
+
+// forget stops auditing the pod, e.g. once it is deleted or its annotation removed.
+func (a *ReadOnlyAudit) forget(pod types.NamespacedName) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.releaseLocked(pod)
+}
+
+// releaseLocked drops pod from its ENI, deleting the ENI's series once no pod
+// refers to it. a.mu must be held.
+func (a *ReadOnlyAudit) releaseLocked(pod types.NamespacedName) {
+	id, ok := a.pods[pod]
+	if !ok {
+		return
+	}
+	delete(a.pods, pod)
+	a.enis[id]--
+	if a.enis[id] == 0 {
+		delete(a.enis, id)
+		metrics.ReadOnlyTagDrift.DeleteLabelValues(id, "add")
+		metrics.ReadOnlyTagDrift.DeleteLabelValues(id, "remove")
+	}
+}
+
+// auditPod is Reconcile in read-only mode. It only reads the pod and its ENI;
+// see ReadOnlyAudit.
+func (r *PodReconciler) auditPod(ctx context.Context, key types.NamespacedName) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	pod := &corev1.Pod{}
+	if err := r.getPod(ctx, key, pod); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.ReadOnly.forget(key)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	annotationValue, wantsTags := r.tagAnnotation(pod)
+	if pod.DeletionTimestamp != nil || !wantsTags || r.isPodExcluded(pod) || pod.Status.PodIP == "" {
+		r.ReadOnly.forget(key)
+		return ctrl.Result{}, nil
+	}
+	requeue := ctrl.Result{RequeueAfter: r.ReadOnly.interval}
+
+	err := r.validatePodTags(ctx, pod, annotationValue)
+	var tags map[string]string
+	if err == nil {
+		tags, err = r.desiredTags(ctx, pod, annotationValue)
+	}
+	if err != nil {
+		metrics.ReadOnlyAuditsTotal.WithLabelValues("invalid").Inc()
+		logger.Info("READ ONLY: Pod tags cannot be applied", LogKeyError, err.Error())
+		r.ReadOnly.forget(key)
+		return requeue, nil
+	}
+
+	eniInfo, err := r.getENIInfo(ctx, pod)
+	if err == nil {
+		err = r.validateENI(ctx, eniInfo)
+	}
+	if err != nil {
+		metrics.ReadOnlyAuditsTotal.WithLabelValues("error").Inc()
+		logger.Info("READ ONLY: Cannot audit the pod's ENI", LogKeyError, err.Error())
+		r.ReadOnly.forget(key)
+		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil
+	}
+
+	// Tags last applied by an earlier installation and since dropped by the pod
+	// count as changes too
+	lastAppliedTags, _ := parseLastApplied(pod.Annotations[r.keys().LastAppliedTags])
+	r.keepMacroTimestamps(pod, annotationValue, tags, eniInfo.Tags)
+	diff := computeLiveTagDiff(tags, lastAppliedTags, eniInfo.Tags)
+	r.ReadOnly.record(key, eniInfo.ID, len(diff.toAdd), len(diff.toRemove))
+	if len(diff.toAdd) == 0 && len(diff.toRemove) == 0 {
+		metrics.ReadOnlyAuditsTotal.WithLabelValues("in_sync").Inc()
+		logger.V(1).Info("READ ONLY: ENI tags match the pod's tags", LogKeyENIID, eniInfo.ID)
+		return requeue, nil
+	}
+	metrics.ReadOnlyAuditsTotal.WithLabelValues("drifted").Inc()
+	logger.Info("READ ONLY: ENI tags differ from the pod's tags", LogKeyENIID, eniInfo.ID, "toAdd", diff.toAdd, "toRemove", diff.toRemove)
+	r.AuditLog.RecordDryRun(ctx, pod.Namespace+"/"+pod.Name, eniInfo.ID, diff.toAdd, diff.toRemove)
+	return requeue, nil
+}
@@ -0,0 +1,79 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReadOnlyAudit(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "audited",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationKey:            `{"team":"platform","env":"prod"}`,
+				LastAppliedAnnotationKey: `{"team":"platform","owner":"alice"}`,
+			},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.1").Return(&aws.ENIInfo{
+		ID:   "eni-audited",
+		Tags: map[string]string{"team": "platform", "owner": "alice"},
+	}, nil)
+
+	r := &PodReconciler{
+		Client:        k8sClient,
+		Scheme:        scheme,
+		Recorder:      record.NewFakeRecorder(10),
+		AWSClient:     mockAWS,
+		AnnotationKey: AnnotationKey,
+		ReadOnly:      NewReadOnlyAudit(time.Minute),
+	}
+	key := client.ObjectKeyFromObject(pod)
+	drifted := testutil.ToFloat64(metrics.ReadOnlyAuditsTotal.WithLabelValues("drifted"))
+
+	result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, result.RequeueAfter)
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ReadOnlyTagDrift.WithLabelValues("eni-audited", "add")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(metrics.ReadOnlyTagDrift.WithLabelValues("eni-audited", "remove")))
+	assert.Equal(t, drifted+1, testutil.ToFloat64(metrics.ReadOnlyAuditsTotal.WithLabelValues("drifted")))
+	mockAWS.AssertNotCalled(t, "TagENI", mock.Anything, mock.Anything, mock.Anything)
+	mockAWS.AssertNotCalled(t, "UntagENI", mock.Anything, mock.Anything, mock.Anything)
+
+	got := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), key, got))
+	assert.Empty(t, got.Finalizers, "read-only mode adds no finalizer")
+	assert.Equal(t, pod.Annotations, got.Annotations, "read-only mode writes no annotations")
+	assert.Empty(t, got.Status.Conditions)
+
+	// Removing the annotation stops the audit and drops the ENI's series
+	delete(got.Annotations, AnnotationKey)
+	require.NoError(t, k8sClient.Update(context.Background(), got))
+	_, err = r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+	assert.Zero(t, testutil.CollectAndCount(metrics.ReadOnlyTagDrift))
+}
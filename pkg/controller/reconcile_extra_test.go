@@ -68,7 +68,7 @@ func TestConflictDetection(t *testing.T) {
 				delete(info.Tags, HashTagKey)
 			}
 
-			conflict := checkHashConflict(info, tt.desiredHash, tt.lastApplied, tt.allowShared)
+			conflict := checkHashConflict(info, HashTagKey, tt.desiredHash, tt.lastApplied, tt.allowShared)
 			assert.Equal(t, tt.expectConflict, conflict)
 		})
 	}
@@ -189,3 +189,68 @@ func TestForeignTagsPreservation(t *testing.T) {
 
 	mockAWS.AssertExpectations(t)
 }
+
+func TestReconcileTagKeyCaseConflictWithENI(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod-case",
+				Namespace:   "default",
+				Annotations: map[string]string{AnnotationKey: `{"Team":"platform"}`},
+				Finalizers:  []string{finalizerName},
+			},
+			Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+		}
+	}
+	eni := &aws.ENIInfo{ID: "eni-case", Tags: map[string]string{"team": "legacy"}}
+
+	t.Run("reject", func(t *testing.T) {
+		pod := newPod()
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(eni, nil)
+		k8s := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+		r := &PodReconciler{Client: k8s, Scheme: scheme, Recorder: record.NewFakeRecorder(10), AWSClient: mockAWS,
+			AnnotationKey: AnnotationKey, TagKeyCase: TagKeyCaseReject, PodRateLimiters: &sync.Map{}}
+
+		res, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+		require.NoError(t, err)
+		assert.Equal(t, caseConflictRequeueDelay, res.RequeueAfter)
+		mockAWS.AssertNotCalled(t, "TagENI", mock.Anything, mock.Anything, mock.Anything)
+
+		var updated corev1.Pod
+		require.NoError(t, k8s.Get(context.Background(), client.ObjectKeyFromObject(pod), &updated))
+		var cond *corev1.PodCondition
+		for i := range updated.Status.Conditions {
+			if updated.Status.Conditions[i].Type == corev1.PodConditionType(ConditionTypeEniTagged) {
+				cond = &updated.Status.Conditions[i]
+			}
+		}
+		require.NotNil(t, cond)
+		assert.Equal(t, string(ReasonInvalidTags), cond.Reason)
+		assert.Contains(t, cond.Message, `existing ENI tag \"team\"`)
+	})
+
+	t.Run("normalize", func(t *testing.T) {
+		pod := newPod()
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(eni, nil)
+		mockAWS.On("TagENI", mock.Anything, "eni-case", mock.MatchedBy(func(tags map[string]string) bool {
+			_, hasTeam := tags["Team"]
+			return tags["team"] == "platform" && !hasTeam
+		})).Return(nil)
+		k8s := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+		r := &PodReconciler{Client: k8s, Scheme: scheme, Recorder: record.NewFakeRecorder(10), AWSClient: mockAWS,
+			AnnotationKey: AnnotationKey, TagKeyCase: TagKeyCaseNormalize, PodRateLimiters: &sync.Map{}}
+
+		_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+		require.NoError(t, err)
+		mockAWS.AssertExpectations(t)
+
+		var updated corev1.Pod
+		require.NoError(t, k8s.Get(context.Background(), client.ObjectKeyFromObject(pod), &updated))
+		assert.JSONEq(t, `{"team":"platform"}`, updated.Annotations[LastAppliedAnnotationKey])
+	})
+}
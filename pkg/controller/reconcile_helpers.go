@@ -3,6 +3,11 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
 
 	"k8s-eni-tagger/pkg/aws"
 
@@ -32,32 +37,77 @@ type tagDiff struct {
 func (r *PodReconciler) parseAndCompareTags(ctx context.Context, pod *corev1.Pod, annotationValue, lastAppliedValue string) (map[string]string, map[string]string, *tagDiff, error) {
 	logger := log.FromContext(ctx)
 
-	// Parse current tags
-	currentTags, err := parseTags(annotationValue)
+	currentTags, err := r.desiredTags(ctx, pod, annotationValue)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
+	// Parse last applied tags
+	lastAppliedTags, err := parseLastApplied(lastAppliedValue)
+	if err != nil {
+		logger.Error(err, "Failed to parse last applied tags, treating as empty", "value", lastAppliedValue)
+		lastAppliedTags = make(map[string]string)
+	}
+	r.keepMacroTimestamps(pod, annotationValue, currentTags, lastAppliedTags)
+
+	return currentTags, lastAppliedTags, computeTagDiff(currentTags, lastAppliedTags), nil
+}
+
+// desiredTags parses the tag annotation, rendering value templates, adds tags from pod labels and the
+// external tag source, renames keys, resolves keys differing only by case and
+// applies the namespace prefix if configured.
+func (r *PodReconciler) desiredTags(ctx context.Context, pod *corev1.Pod, annotationValue string) (map[string]string, error) {
+	tags, err := r.podTags(ctx, pod, annotationValue)
+	if err != nil {
+		return nil, err
+	}
+	if labelTags := r.labelTags(pod); len(labelTags) > 0 {
+		if tags, err = mergeLabelTags(tags, labelTags); err != nil {
+			return nil, err
+		}
+	}
+	if r.TagSource != nil {
+		if tags, err = r.mergeExternalTags(ctx, pod, tags); err != nil {
+			return nil, err
+		}
+	}
+	tags, err = renameTagKeys(tags, r.TagKeyRenames)
+	if err != nil {
+		return nil, err
+	}
+	tags, err = resolveKeyCaseConflicts(tags, r.TagKeyCase)
+	if err != nil {
+		return nil, err
+	}
+
 	// Apply namespace prefix if configured
 	effectiveNamespace := ""
 	if r.TagNamespace == "enable" {
 		effectiveNamespace = pod.Namespace
 	}
-	currentTags, err = applyNamespace(currentTags, effectiveNamespace)
+	return applyNamespace(tags, effectiveNamespace)
+}
+
+// mergeExternalTags adds the tag source's tags for pod to the annotation's and
+// labels' tags, which win on conflicting keys, and checks the result against
+// AWS limits.
+func (r *PodReconciler) mergeExternalTags(ctx context.Context, pod *corev1.Pod, tags map[string]string) (map[string]string, error) {
+	external, err := r.TagSource.Tags(ctx, pod)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, fmt.Errorf("failed to get tags from external tag source: %w", err)
 	}
-
-	// Parse last applied tags
-	lastAppliedTags := make(map[string]string)
-	if lastAppliedValue != "" {
-		if err := json.Unmarshal([]byte(lastAppliedValue), &lastAppliedTags); err != nil {
-			logger.Error(err, "Failed to parse last applied tags, treating as empty", "value", lastAppliedValue)
-			lastAppliedTags = make(map[string]string)
-		}
+	merged := make(map[string]string, len(external)+len(tags))
+	maps.Copy(merged, external)
+	maps.Copy(merged, tags)
+	if _, err := validateParsedTags(merged); err != nil {
+		return nil, fmt.Errorf("invalid tags from external tag source: %w", err)
 	}
+	return merged, nil
+}
 
-	// Calculate Diff
+// computeTagDiff returns the tags to add or update and the keys to remove to go
+// from lastAppliedTags to currentTags.
+func computeTagDiff(currentTags, lastAppliedTags map[string]string) *tagDiff {
 	diff := &tagDiff{
 		toAdd:    make(map[string]string),
 		toRemove: []string{},
@@ -75,7 +125,58 @@ func (r *PodReconciler) parseAndCompareTags(ctx context.Context, pod *corev1.Pod
 		}
 	}
 
-	return currentTags, lastAppliedTags, diff, nil
+	return diff
+}
+
+// computeLiveTagDiff returns the tags to add or update and the keys to remove to
+// go from the tags currently on the ENI to currentTags. Only keys in
+// lastAppliedTags are removed: other ENI tags may belong to someone else.
+func computeLiveTagDiff(currentTags, lastAppliedTags, eniTags map[string]string) *tagDiff {
+	diff := &tagDiff{
+		toAdd:    make(map[string]string),
+		toRemove: []string{},
+	}
+
+	for k, v := range currentTags {
+		if eniVal, ok := eniTags[k]; !ok || eniVal != v {
+			diff.toAdd[k] = v
+		}
+	}
+
+	for k := range lastAppliedTags {
+		if _, ok := currentTags[k]; ok {
+			continue
+		}
+		if _, ok := eniTags[k]; ok {
+			diff.toRemove = append(diff.toRemove, k)
+		}
+	}
+	sort.Strings(diff.toRemove)
+
+	return diff
+}
+
+// adoptENITags rebuilds the last applied tags from the tags on the ENI. Only keys
+// that are desired or were last applied are adopted, so foreign tags are never
+// claimed. It also reports whether the pod's bookkeeping annotations disagree
+// with the ENI, i.e. whether there is anything to repair.
+func adoptENITags(eniInfo *aws.ENIInfo, hashTagKey string, currentTags, lastAppliedTags map[string]string, lastAppliedHash string) (map[string]string, bool) {
+	adopted := make(map[string]string)
+	for _, candidates := range []map[string]string{currentTags, lastAppliedTags} {
+		for k := range candidates {
+			if v, ok := eniInfo.Tags[k]; ok {
+				adopted[k] = v
+			}
+		}
+	}
+
+	stale := eniInfo.Tags[hashTagKey] != lastAppliedHash || len(adopted) != len(lastAppliedTags)
+	for k, v := range adopted {
+		if last, ok := lastAppliedTags[k]; !ok || last != v {
+			stale = true
+		}
+	}
+	return adopted, stale
 }
 
 // checkHashConflict checks if there's a hash conflict indicating another controller modified the ENI.
@@ -87,8 +188,8 @@ func (r *PodReconciler) parseAndCompareTags(ctx context.Context, pod *corev1.Pod
 //
 // If allowSharedENITagging is true, conflicts are ignored (dangerous mode).
 // Returns true if there's a conflict, false otherwise.
-func checkHashConflict(eniInfo *aws.ENIInfo, desiredHash, lastAppliedHash string, allowSharedENITagging bool) bool {
-	eniHash := eniInfo.Tags[HashTagKey]
+func checkHashConflict(eniInfo *aws.ENIInfo, hashTagKey, desiredHash, lastAppliedHash string, allowSharedENITagging bool) bool {
+	eniHash := eniInfo.Tags[hashTagKey]
 
 	// Decision Matrix:
 	// 1. ENI Hash is Empty -> Safe to claim.
@@ -108,3 +209,131 @@ func checkHashConflict(eniInfo *aws.ENIInfo, desiredHash, lastAppliedHash string
 
 	return false
 }
+
+// foreignTagSummary describes tags on the ENI that this controller does not manage.
+// Managed keys are the desired tags, the last applied tags, the bookkeeping tags in
+// keys and, when written, the managed-by tag.
+// It returns an empty string when there are no foreign tags, otherwise a short
+// suffix suitable for appending to a condition message. At most
+// maxForeignKeysInMessage keys are listed to keep conditions readable.
+func foreignTagSummary(eniInfo *aws.ENIInfo, keys Keys, managedBy bool, currentTags, lastAppliedTags map[string]string) string {
+	managed := make(map[string]string, len(currentTags)+len(lastAppliedTags)+2)
+	for k, v := range lastAppliedTags {
+		managed[k] = v
+	}
+	for k, v := range currentTags {
+		managed[k] = v
+	}
+	managed[keys.HashTag] = ""
+	managed[keys.OwnerTag] = ""
+	if managedBy {
+		managed[ManagedByTagKey] = ""
+	}
+
+	foreign := eniInfo.ForeignTagKeys(managed)
+	if len(foreign) == 0 {
+		return ""
+	}
+
+	listed := foreign
+	more := ""
+	if len(listed) > maxForeignKeysInMessage {
+		listed = listed[:maxForeignKeysInMessage]
+		more = fmt.Sprintf(", +%d more", len(foreign)-maxForeignKeysInMessage)
+	}
+	return fmt.Sprintf(" (%d foreign tags: %s%s)", len(foreign), strings.Join(listed, ", "), more)
+}
+
+// tagCountAfter returns the number of tags on an ENI that had current after
+// added are written and removed deleted.
+func tagCountAfter(current, added map[string]string, removed []string) int {
+	n := len(current)
+	for k := range added {
+		if _, ok := current[k]; !ok {
+			n++
+		}
+	}
+	for _, k := range removed {
+		if _, ok := current[k]; ok {
+			if _, readded := added[k]; !readded {
+				n--
+			}
+		}
+	}
+	return n
+}
+
+// observeTagHeadroom updates the tag headroom of an ENI and warns once fewer
+// than TagHeadroomWarning tags can still be added to it, before CreateTags
+// starts failing on the EC2 limit.
+func (r *PodReconciler) observeTagHeadroom(ctx context.Context, pod *corev1.Pod, eniID string, tagCount int) {
+	if r.Stats != nil {
+		r.Stats.observeTags(eniID, tagCount)
+	}
+	headroom := MaxTagsPerENI - tagCount
+	if headroom >= r.TagHeadroomWarning {
+		return
+	}
+	log.FromContext(ctx).Info("ENI is running out of tag slots", "eniID", eniID, "tags", tagCount, "headroom", headroom)
+	r.Recorder.Event(pod, corev1.EventTypeWarning, "TagQuotaLow", fmt.Sprintf("ENI %s has %d of %d tag slots left", eniID, max(headroom, 0), MaxTagsPerENI))
+}
+
+// tagPreview lists tags as sorted key=value pairs for the TagsPlanned event,
+// e.g. "CostCenter=1234, team=platform". Pairs that would take the list past
+// maxTagPreviewLength are counted instead of listed.
+func tagPreview(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, k := range keys {
+		pair := k + "=" + tags[k]
+		if i > 0 {
+			pair = ", " + pair
+		}
+		if b.Len()+len(pair) > maxTagPreviewLength {
+			fmt.Fprintf(&b, ", +%d more", len(keys)-i)
+			break
+		}
+		b.WriteString(pair)
+	}
+	return b.String()
+}
+
+// dryRunDiff is the message of the DryRunDiff event.
+type dryRunDiff struct {
+	ENIID    string            `json:"eniID"`
+	ToAdd    map[string]string `json:"toAdd,omitempty"`
+	ToRemove []string          `json:"toRemove,omitempty"`
+	// Omitted counts the changes left out to keep the message within
+	// maxTagPreviewLength.
+	Omitted int `json:"omitted,omitempty"`
+}
+
+// recordDryRunDiff emits a DryRunDiff event on pod with the changes --dry-run
+// skipped on the ENI, as compact JSON, so they show in kubectl describe pod.
+func (r *PodReconciler) recordDryRunDiff(pod *corev1.Pod, eniID string, toAdd map[string]string, toRemove []string) {
+	r.Recorder.Event(pod, corev1.EventTypeNormal, "DryRunDiff", dryRunDiffMessage(eniID, toAdd, toRemove))
+}
+
+// dryRunDiffMessage renders a dryRunDiff, dropping removals and then the last
+// additions by key until it fits maxTagPreviewLength.
+func dryRunDiffMessage(eniID string, toAdd map[string]string, toRemove []string) string {
+	diff := dryRunDiff{ENIID: eniID, ToAdd: maps.Clone(toAdd), ToRemove: slices.Sorted(slices.Values(toRemove))}
+	addKeys := slices.Sorted(maps.Keys(toAdd))
+	for {
+		msg, _ := json.Marshal(diff)
+		if len(msg) <= maxTagPreviewLength || (len(diff.ToAdd) == 0 && len(diff.ToRemove) == 0) {
+			return string(msg)
+		}
+		if n := len(diff.ToRemove); n > 0 {
+			diff.ToRemove = diff.ToRemove[:n-1]
+		} else {
+			delete(diff.ToAdd, addKeys[len(diff.ToAdd)-1])
+		}
+		diff.Omitted++
+	}
+}
@@ -2,13 +2,19 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
 
+	"k8s-eni-tagger/pkg/aws"
+
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseAndCompareTags_Namespacing(t *testing.T) {
@@ -138,3 +144,92 @@ func TestParseAndCompareTags_Namespacing(t *testing.T) {
 		})
 	}
 }
+
+func TestForeignTagSummary(t *testing.T) {
+	current := map[string]string{"team": "platform"}
+	last := map[string]string{"old": "x"}
+
+	t.Run("no foreign tags", func(t *testing.T) {
+		info := &aws.ENIInfo{Tags: map[string]string{"team": "platform", "old": "x", HashTagKey: "abc", OwnerTagKey: "ns/eni-tagger"}}
+		assert.Equal(t, "", foreignTagSummary(info, NewKeys(""), false, current, last))
+	})
+
+	t.Run("foreign tags listed sorted", func(t *testing.T) {
+		info := &aws.ENIInfo{Tags: map[string]string{"team": "platform", "Name": "n", "CreatedBy": "cni"}}
+		assert.Equal(t, " (2 foreign tags: CreatedBy, Name)", foreignTagSummary(info, NewKeys(""), false, current, last))
+	})
+
+	t.Run("long list truncated", func(t *testing.T) {
+		tags := map[string]string{}
+		for i := 0; i < maxForeignKeysInMessage+3; i++ {
+			tags[fmt.Sprintf("k%02d", i)] = "v"
+		}
+		summary := foreignTagSummary(&aws.ENIInfo{Tags: tags}, NewKeys(""), false, nil, nil)
+		assert.Contains(t, summary, "13 foreign tags")
+		assert.Contains(t, summary, ", +3 more)")
+		assert.NotContains(t, summary, "k10")
+	})
+}
+
+func TestTagCountAfter(t *testing.T) {
+	current := map[string]string{"team": "a", "env": "prod", "foreign": "x"}
+	assert.Equal(t, 3, tagCountAfter(current, map[string]string{"team": "b"}, nil))
+	assert.Equal(t, 3, tagCountAfter(current, map[string]string{"owner": "sre"}, []string{"env", "missing"}))
+	assert.Equal(t, 3, tagCountAfter(current, map[string]string{"env": "dev"}, []string{"env"}))
+	assert.Equal(t, 2, tagCountAfter(nil, map[string]string{"team": "a", HashTagKey: "h"}, []string{"old"}))
+}
+
+func TestTagPreview(t *testing.T) {
+	assert.Equal(t, "CostCenter=1234, eni-tagger.io/hash=abc, team=platform", tagPreview(map[string]string{"team": "platform", "CostCenter": "1234", HashTagKey: "abc"}))
+
+	tags := map[string]string{}
+	for i := 0; i < 20; i++ {
+		tags[fmt.Sprintf("k%02d", i)] = strings.Repeat("v", 100)
+	}
+	preview := tagPreview(tags)
+	assert.LessOrEqual(t, len(preview), maxTagPreviewLength+len(", +99 more"))
+	assert.True(t, strings.HasPrefix(preview, "k00="))
+	assert.Contains(t, preview, " more")
+}
+
+func TestDryRunDiffMessage(t *testing.T) {
+	assert.Equal(t, `{"eniID":"eni-1","toAdd":{"env":"prod"},"toRemove":["a","b"]}`, dryRunDiffMessage("eni-1", map[string]string{"env": "prod"}, []string{"b", "a"}))
+	assert.Equal(t, `{"eniID":"eni-1"}`, dryRunDiffMessage("eni-1", nil, nil))
+
+	tags := map[string]string{}
+	for i := 0; i < 20; i++ {
+		tags[fmt.Sprintf("k%02d", i)] = strings.Repeat("v", 100)
+	}
+	var diff dryRunDiff
+	msg := dryRunDiffMessage("eni-1", tags, []string{"old"})
+	require.NoError(t, json.Unmarshal([]byte(msg), &diff))
+	assert.LessOrEqual(t, len(msg), maxTagPreviewLength)
+	assert.Contains(t, diff.ToAdd, "k00")
+	assert.Empty(t, diff.ToRemove, "removals are dropped first")
+	assert.Equal(t, 21, len(diff.ToAdd)+diff.Omitted)
+}
+
+func TestComputeLiveTagDiff(t *testing.T) {
+	current := map[string]string{"team": "platform", "env": "prod"}
+	last := map[string]string{"team": "platform", "env": "prod", "old": "x", "gone": "y"}
+	eni := map[string]string{"team": "edited", "old": "x", "Name": "foreign"}
+
+	diff := computeLiveTagDiff(current, last, eni)
+	// env was deleted out of band and team edited; both are restored.
+	assert.Equal(t, map[string]string{"team": "platform", "env": "prod"}, diff.toAdd)
+	// old is ours and still on the ENI; gone is already absent; Name was never ours.
+	assert.Equal(t, []string{"old"}, diff.toRemove)
+}
+
+func TestAdoptENITags(t *testing.T) {
+	current := map[string]string{"team": "platform", "env": "prod"}
+	info := &aws.ENIInfo{Tags: map[string]string{"team": "platform", "old": "x", "Name": "foreign", HashTagKey: "h1"}}
+
+	adopted, stale := adoptENITags(info, HashTagKey, current, map[string]string{"old": "x"}, "h0")
+	assert.True(t, stale)
+	assert.Equal(t, map[string]string{"team": "platform", "old": "x"}, adopted)
+
+	adopted, stale = adoptENITags(info, HashTagKey, current, map[string]string{"team": "platform", "old": "x"}, "h1")
+	assert.False(t, stale)
+	assert.Equal(t, map[string]string{"team": "platform", "old": "x"}, adopted)
+}
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+)
+
+// ReconcileRecord is one pod reconcile as kept by ReconcileHistory.
+type ReconcileRecord struct {
+	// ReconcileID is logged as "reconcileID" on every line of the reconcile.
+	ReconcileID string    `json:"reconcileID,omitempty"`
+	Pod         string    `json:"pod"`
+	Start       time.Time `json:"start"`
+	Duration    string    `json:"duration"`
+	// ENIID is the pod's ENI, once the reconcile resolved it.
+	ENIID string `json:"eniID,omitempty"`
+	// Reason is the tagging condition reason the reconcile set, empty when it
+	// ended before setting one (e.g. rate limited or pod deleted).
+	Reason ConditionReason `json:"reason,omitempty"`
+	// Error is the error returned to controller-runtime, which retries with backoff.
+	Error        string `json:"error,omitempty"`
+	RequeueAfter string `json:"requeueAfter,omitempty"`
+	// AWSRequests are the AWS calls made by the reconcile, each retry included.
+	AWSRequests []aws.AWSRequest `json:"awsRequests,omitempty"`
+	// AWSRequestsDropped counts calls made beyond the ones kept.
+	AWSRequestsDropped int `json:"awsRequestsDropped,omitempty"`
+}
+
+// ReconcileHistory keeps the last reconciles of all pods in a ring buffer for
+// support investigations, served by APIHandler by pod and by ENI. Memory is
+// bounded by the size, not by the number of pods, so busy pods push out the
+// history of quiet ones.
+type ReconcileHistory struct {
+	mu      sync.Mutex
+	records []ReconcileRecord
+	// next is the slot the next record is written to.
+	next int
+	full bool
+}
+
+// NewReconcileHistory returns a history keeping the last size reconciles.
+func NewReconcileHistory(size int) *ReconcileHistory {
+	return &ReconcileHistory{records: make([]ReconcileRecord, size)}
+}
+
+// reconcileTraceKey carries the reconcileTrace of a reconcile in its context.
+type reconcileTraceKey struct{}
+
+// reconcileTrace collects what a reconcile learns along the way.
+type reconcileTrace struct {
+	mu     sync.Mutex
+	eniID  string
+	reason ConditionReason
+}
+
+// traceCondition records the condition set by the reconcile running with ctx, if
+// it is traced.
+func traceCondition(ctx context.Context, reason ConditionReason, eniID string) {
+	trace, ok := ctx.Value(reconcileTraceKey{}).(*reconcileTrace)
+	if !ok {
+		return
+	}
+	trace.mu.Lock()
+	defer trace.mu.Unlock()
+	trace.reason = reason
+	if eniID != "" {
+		trace.eniID = eniID
+	}
+}
+
+// begin starts tracing a reconcile of pod. The returned function records it
+// with its result.
+func (h *ReconcileHistory) begin(ctx context.Context, pod types.NamespacedName) (context.Context, func(ctrl.Result, error)) {
+	start := time.Now()
+	trace := &reconcileTrace{}
+	ctx = context.WithValue(ctx, reconcileTraceKey{}, trace)
+	ctx, requestLog := aws.WithRequestLog(ctx)
+	reconcileID := string(controller.ReconcileIDFromContext(ctx))
+
+	return ctx, func(result ctrl.Result, err error) {
+		record := ReconcileRecord{
+			ReconcileID: reconcileID,
+			Pod:         pod.String(),
+			Start:       start,
+			Duration:    time.Since(start).Round(time.Millisecond).String(),
+		}
+		trace.mu.Lock()
+		record.ENIID, record.Reason = trace.eniID, trace.reason
+		trace.mu.Unlock()
+		if err != nil {
+			record.Error = err.Error()
+		}
+		if result.RequeueAfter > 0 {
+			record.RequeueAfter = result.RequeueAfter.String()
+		}
+		record.AWSRequests, record.AWSRequestsDropped = requestLog.Requests()
+		h.add(record)
+	}
+}
+
+func (h *ReconcileHistory) add(record ReconcileRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.records) == 0 {
+		return
+	}
+	h.records[h.next] = record
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// find returns the kept records matching, newest first.
+func (h *ReconcileHistory) find(match func(ReconcileRecord) bool) []ReconcileRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := h.next
+	if h.full {
+		n = len(h.records)
+	}
+	found := []ReconcileRecord{}
+	for i := 1; i <= n; i++ {
+		record := h.records[(h.next-i+len(h.records))%len(h.records)]
+		if match(record) {
+			found = append(found, record)
+		}
+	}
+	return found
+}
+
+// ForPod returns the kept reconciles of pod, newest first.
+func (h *ReconcileHistory) ForPod(pod types.NamespacedName) []ReconcileRecord {
+	key := pod.String()
+	return h.find(func(r ReconcileRecord) bool { return r.Pod == key })
+}
+
+// ForENI returns the kept reconciles that resolved the ENI eniID, newest first.
+func (h *ReconcileHistory) ForENI(eniID string) []ReconcileRecord {
+	return h.find(func(r ReconcileRecord) bool { return r.ENIID == eniID })
+}
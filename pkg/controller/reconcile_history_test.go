@@ -0,0 +1,92 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReconcileHistory(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "app",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationKey: `{"team":"platform"}`},
+			Finalizers:  []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.1").Return(&aws.ENIInfo{ID: "eni-1"}, nil)
+	mockAWS.On("TagENI", mock.Anything, "eni-1", mock.Anything).Return(nil)
+
+	r := &PodReconciler{
+		Client:        k8sClient,
+		Scheme:        scheme,
+		Recorder:      record.NewFakeRecorder(10),
+		AWSClient:     mockAWS,
+		AnnotationKey: AnnotationKey,
+		History:       NewReconcileHistory(10),
+	}
+	key := client.ObjectKeyFromObject(pod)
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+
+	records := r.History.ForPod(key)
+	require.Len(t, records, 1)
+	assert.Equal(t, "default/app", records[0].Pod)
+	assert.Equal(t, "eni-1", records[0].ENIID)
+	assert.Equal(t, ReasonSynced, records[0].Reason)
+	assert.Empty(t, records[0].Error)
+	assert.Equal(t, records, r.History.ForENI("eni-1"))
+	assert.Empty(t, r.History.ForENI("eni-2"))
+
+	rec := httptest.NewRecorder()
+	r.APIHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/enis/eni-1/reconciles", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var served []ReconcileRecord
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	require.Len(t, served, 1)
+	assert.Equal(t, ReasonSynced, served[0].Reason)
+
+	rec = httptest.NewRecorder()
+	r.APIHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/pods/default/other/reconciles", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, "[]", rec.Body.String())
+}
+
+func TestReconcileHistoryRing(t *testing.T) {
+	h := NewReconcileHistory(3)
+	pod := types.NamespacedName{Namespace: "default", Name: "app"}
+	for i := range 5 {
+		h.add(ReconcileRecord{Pod: pod.String(), ENIID: fmt.Sprintf("eni-%d", i)})
+	}
+
+	records := h.ForPod(pod)
+	require.Len(t, records, 3)
+	assert.Equal(t, "eni-4", records[0].ENIID, "newest first")
+	assert.Equal(t, "eni-2", records[2].ENIID, "older reconciles are dropped")
+	assert.Empty(t, h.ForENI("eni-1"))
+}
@@ -1,13 +1,18 @@
 package controller
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
 
+	"k8s-eni-tagger/pkg/audit"
 	"k8s-eni-tagger/pkg/aws"
+	enicache "k8s-eni-tagger/pkg/cache"
 
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/stretchr/testify/assert"
@@ -35,6 +40,14 @@ func (m *MockAWSClient) GetENIInfoByIP(ctx context.Context, ip string) (*aws.ENI
 	return args.Get(0).(*aws.ENIInfo), args.Error(1)
 }
 
+func (m *MockAWSClient) GetENIInfoByIPs(ctx context.Context, ips []string) (map[string]*aws.ENIInfo, error) {
+	args := m.Called(ctx, ips)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]*aws.ENIInfo), args.Error(1)
+}
+
 func (m *MockAWSClient) TagENI(ctx context.Context, eniID string, tags map[string]string) error {
 	args := m.Called(ctx, eniID, tags)
 	return args.Error(0)
@@ -439,6 +452,7 @@ func TestReconcileRateLimiting(t *testing.T) {
 		PodRateLimiters:   &sync.Map{},
 		PodRateLimitQPS:   0.1, // Very low QPS for testing
 		PodRateLimitBurst: 1,
+		ReportRateLimited: true,
 		Recorder:          record.NewFakeRecorder(10),
 	}
 
@@ -464,6 +478,24 @@ func TestReconcileRateLimiting(t *testing.T) {
 	assert.NoError(t, err2)
 	assert.NotZero(t, res2.RequeueAfter) // Should requeue due to rate limiting
 
+	// The deferral is visible on the pod with the next attempt time
+	updated := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+	require.Len(t, updated.Status.Conditions, 1)
+	assert.Equal(t, string(ReasonRateLimited), updated.Status.Conditions[0].Reason)
+	details, err := ParseConditionDetails(updated.Status.Conditions[0].Message)
+	require.NoError(t, err)
+	nextAttempt, err := time.Parse(time.RFC3339, details.NextAttempt)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(res2.RequeueAfter), nextAttempt, 2*time.Second)
+
+	// A pending attempt is not rewritten
+	message := updated.Status.Conditions[0].Message
+	_, err = r.Reconcile(context.Background(), req)
+	assert.NoError(t, err)
+	require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+	assert.Equal(t, message, updated.Status.Conditions[0].Message)
+
 	// Verify that AWS calls were only made once (first reconcile)
 	mockAWS.AssertExpectations(t)
 }
@@ -523,3 +555,748 @@ func TestReconcileRateLimiterInitError(t *testing.T) {
 	// Verify that AWS calls were made (rate limiting was skipped due to init error)
 	mockAWS.AssertExpectations(t)
 }
+
+func TestReconcileForeignController(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod-owner",
+				Namespace:   "default",
+				Annotations: map[string]string{AnnotationKey: `{"team":"platform"}`},
+				Finalizers:  []string{finalizerName},
+			},
+			Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+		}
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "pod-owner", Namespace: "default"}}
+
+	newReconciler := func(k8sClient client.Client, mockAWS *MockAWSClient) *PodReconciler {
+		return &PodReconciler{
+			Client:        k8sClient,
+			Scheme:        scheme,
+			Recorder:      record.NewFakeRecorder(10),
+			AWSClient:     mockAWS,
+			AnnotationKey: AnnotationKey,
+			ControllerID:  "kube-system/eni-tagger-a",
+		}
+	}
+
+	t.Run("ENI owned by another installation is left alone", func(t *testing.T) {
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newPod()).Build()
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{
+			ID:   "eni-owned",
+			Tags: map[string]string{HashTagKey: "other-hash", OwnerTagKey: "team-b/eni-tagger-b"},
+		}, nil)
+
+		res, err := newReconciler(k8sClient, mockAWS).Reconcile(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, foreignControllerRequeueDelay, res.RequeueAfter)
+
+		pod := &corev1.Pod{}
+		require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, pod))
+		require.Len(t, pod.Status.Conditions, 1)
+		assert.Equal(t, corev1.ConditionFalse, pod.Status.Conditions[0].Status)
+		assert.Equal(t, string(ReasonForeignController), pod.Status.Conditions[0].Reason)
+		details, err := ParseConditionDetails(pod.Status.Conditions[0].Message)
+		require.NoError(t, err)
+		assert.Equal(t, "team-b/eni-tagger-b", details.Owner)
+		assert.Equal(t, "eni-owned", details.ENIID)
+
+		mockAWS.AssertExpectations(t)
+		mockAWS.AssertNotCalled(t, "TagENI", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unowned ENI is claimed with the owner tag", func(t *testing.T) {
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(newPod()).Build()
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{ID: "eni-free"}, nil)
+		mockAWS.On("TagENI", mock.Anything, "eni-free", mock.MatchedBy(func(tags map[string]string) bool {
+			return tags[OwnerTagKey] == "kube-system/eni-tagger-a" && tags["team"] == "platform"
+		})).Return(nil)
+
+		_, err := newReconciler(k8sClient, mockAWS).Reconcile(context.Background(), req)
+		require.NoError(t, err)
+		mockAWS.AssertExpectations(t)
+	})
+}
+
+func TestReconcileManagedByTag(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-managed-by",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationKey: `{"team":"platform"}`},
+			Finalizers:  []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "pod-managed-by", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+	value := ManagedByTagValue("prod-eu")
+	assert.Equal(t, "k8s-eni-tagger/prod-eu", value)
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{ID: "eni-managed-by"}, nil).Once()
+	mockAWS.On("TagENI", mock.Anything, "eni-managed-by", map[string]string{
+		"team":          "platform",
+		HashTagKey:      computeHash(map[string]string{"team": "platform"}),
+		ManagedByTagKey: value,
+	}).Return(nil).Once()
+
+	r := &PodReconciler{
+		Client:        k8sClient,
+		Scheme:        scheme,
+		Recorder:      record.NewFakeRecorder(10),
+		AWSClient:     mockAWS,
+		AnnotationKey: AnnotationKey,
+		ManagedByTag:  value,
+	}
+	_, err := r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	mockAWS.AssertExpectations(t)
+
+	// The condition summarizes the change; bookkeeping tags are not counted
+	updated := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+	require.Len(t, updated.Status.Conditions, 1)
+	details, err := ParseConditionDetails(updated.Status.Conditions[0].Message)
+	require.NoError(t, err)
+	assert.Regexp(t, `^Successfully tagged ENI eni-managed-by \(applied 1, removed 0, [0-9.]+m?s\)$`, details.Message)
+
+	// The managed-by tag is removed with the pod's tags
+	require.NoError(t, k8sClient.Delete(context.Background(), updated))
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{ID: "eni-managed-by", Tags: map[string]string{
+		"team":          "platform",
+		HashTagKey:      updated.Annotations[LastAppliedHashKey],
+		ManagedByTagKey: value,
+	}}, nil).Once()
+	mockAWS.On("UntagENI", mock.Anything, "eni-managed-by", mock.MatchedBy(func(keys []string) bool {
+		return assert.ElementsMatch(t, []string{"team", HashTagKey, ManagedByTagKey}, keys)
+	})).Return(nil).Once()
+	_, err = r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	mockAWS.AssertExpectations(t)
+
+	// A pod's own managed-by tag takes precedence
+	own := map[string]string{"team": "platform", ManagedByTagKey: "terraform"}
+	assert.Empty(t, r.managedByTag(own))
+	assert.False(t, r.removeManagedByTag(&aws.ENIInfo{Tags: own}, own))
+}
+
+func TestReconcileTagPolicyViolation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-policy",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationKey: `{"CostCenter":"nope"}`},
+			Finalizers:  []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "pod-policy", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+	recorder := record.NewFakeRecorder(10)
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{ID: "eni-policy"}, nil)
+	mockAWS.On("TagENI", mock.Anything, "eni-policy", mock.Anything).Return(&aws.TagPolicyViolationError{
+		ENIID:    "eni-policy",
+		Keys:     []string{"CostCenter"},
+		PolicyID: "p-95ouootqx1",
+		Message:  "The tag policy does not allow the specified value for the following tag key: 'CostCenter'.",
+		Err:      errors.New("TagPolicyViolation"),
+	})
+
+	r := &PodReconciler{
+		Client:        k8sClient,
+		Scheme:        scheme,
+		Recorder:      recorder,
+		AWSClient:     mockAWS,
+		AnnotationKey: AnnotationKey,
+	}
+	res, err := r.Reconcile(context.Background(), req)
+	require.NoError(t, err, "policy violations must not trigger error backoff retries")
+	assert.Equal(t, tagPolicyRequeueDelay, res.RequeueAfter)
+
+	updated := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+	require.Len(t, updated.Status.Conditions, 1)
+	assert.Equal(t, string(ReasonTagPolicyViolation), updated.Status.Conditions[0].Reason)
+	details, err := ParseConditionDetails(updated.Status.Conditions[0].Message)
+	require.NoError(t, err)
+	assert.Equal(t, "p-95ouootqx1", details.TagPolicyID)
+	assert.Equal(t, []string{"CostCenter"}, details.TagPolicyKeys)
+
+	// The first tagging of the pod is announced before the call
+	assert.Equal(t, "Normal TagsPlanned Applying 2 tags to ENI eni-policy: CostCenter=nope, "+HashTagKey+"="+computeHash(map[string]string{"CostCenter": "nope"}), <-recorder.Events)
+	event := <-recorder.Events
+	assert.Contains(t, event, "TagPolicyViolation")
+	assert.Contains(t, event, "p-95ouootqx1")
+	assert.Contains(t, event, "CostCenter")
+}
+
+func TestReconcileENIAttachmentGating(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-attaching",
+			Namespace:   "default",
+			UID:         "uid-attaching",
+			Annotations: map[string]string{AnnotationKey: `{"team":"platform"}`},
+			Finalizers:  []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "pod-attaching", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{
+		ID:               "eni-attaching",
+		Status:           "attaching",
+		AttachmentStatus: "attaching",
+	}, nil).Once()
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{
+		ID:               "eni-attaching",
+		Status:           "in-use",
+		AttachmentStatus: "attached",
+	}, nil).Once()
+	mockAWS.On("TagENI", mock.Anything, "eni-attaching", mock.Anything).Return(nil).Once()
+
+	r := &PodReconciler{
+		Client:                    k8sClient,
+		Scheme:                    scheme,
+		Recorder:                  record.NewFakeRecorder(10),
+		AWSClient:                 mockAWS,
+		ENICache:                  enicache.NewENICache(mockAWS),
+		AnnotationKey:             AnnotationKey,
+		ENIAttachmentRequeueDelay: 5 * time.Second,
+	}
+
+	res, err := r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, res.RequeueAfter)
+	mockAWS.AssertNotCalled(t, "TagENI", mock.Anything, mock.Anything, mock.Anything)
+
+	updated := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+	require.Len(t, updated.Status.Conditions, 1)
+	assert.Equal(t, string(ReasonENIAttaching), updated.Status.Conditions[0].Reason)
+
+	// The cached lookup was dropped, so the retry sees the attached ENI
+	_, err = r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	mockAWS.AssertExpectations(t)
+}
+
+func TestReconcileDiffSourceENI(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	desired := map[string]string{"team": "platform"}
+	desiredHash := computeHash(desired)
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "pod-live", Namespace: "default"}}
+	newPod := func(annotations map[string]string) *corev1.Pod {
+		annotations[AnnotationKey] = `{"team":"platform"}`
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod-live",
+				Namespace:   "default",
+				Annotations: annotations,
+				Finalizers:  []string{finalizerName},
+			},
+			Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+		}
+	}
+	newReconciler := func(k8sClient client.Client, mockAWS *MockAWSClient) *PodReconciler {
+		return &PodReconciler{
+			Client:        k8sClient,
+			Scheme:        scheme,
+			Recorder:      record.NewFakeRecorder(10),
+			AWSClient:     mockAWS,
+			AnnotationKey: AnnotationKey,
+			DiffSource:    TagDiffSourceENI,
+		}
+	}
+
+	t.Run("tag edited out of band is restored", func(t *testing.T) {
+		pod := newPod(map[string]string{
+			LastAppliedAnnotationKey: `{"team":"platform"}`,
+			LastAppliedHashKey:       desiredHash,
+		})
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{
+			ID:   "eni-live",
+			Tags: map[string]string{"team": "edited", HashTagKey: desiredHash},
+		}, nil)
+		mockAWS.On("TagENI", mock.Anything, "eni-live", map[string]string{"team": "platform", HashTagKey: desiredHash}).Return(nil)
+
+		_, err := newReconciler(k8sClient, mockAWS).Reconcile(context.Background(), req)
+		require.NoError(t, err)
+		mockAWS.AssertExpectations(t)
+	})
+
+	t.Run("lost annotations are restored without AWS writes", func(t *testing.T) {
+		pod := newPod(map[string]string{})
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{
+			ID:   "eni-live",
+			Tags: map[string]string{"team": "platform", HashTagKey: desiredHash},
+		}, nil)
+
+		_, err := newReconciler(k8sClient, mockAWS).Reconcile(context.Background(), req)
+		require.NoError(t, err)
+		mockAWS.AssertNotCalled(t, "TagENI", mock.Anything, mock.Anything, mock.Anything)
+		mockAWS.AssertNotCalled(t, "UntagENI", mock.Anything, mock.Anything, mock.Anything)
+
+		updated := &corev1.Pod{}
+		require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+		assert.Equal(t, desiredHash, updated.Annotations[LastAppliedHashKey])
+		assert.JSONEq(t, `{"team":"platform"}`, updated.Annotations[LastAppliedAnnotationKey])
+	})
+}
+
+func TestReconcileStartupRepair(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	desiredHash := computeHash(map[string]string{"team": "platform"})
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "pod-restored", Namespace: "default"}}
+	// A pod restored from an older backup: its bookkeeping annotations predate the ENI's tags.
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pod-restored",
+				Namespace: "default",
+				Annotations: map[string]string{
+					AnnotationKey:            `{"team":"platform"}`,
+					LastAppliedAnnotationKey: `{"team":"legacy"}`,
+					LastAppliedHashKey:       "stale-hash",
+				},
+				Finalizers: []string{finalizerName},
+			},
+			Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+		}
+	}
+	newReconciler := func(k8sClient client.Client, mockAWS *MockAWSClient, repairUntil time.Time) *PodReconciler {
+		return &PodReconciler{
+			Client:        k8sClient,
+			Scheme:        scheme,
+			Recorder:      record.NewFakeRecorder(10),
+			AWSClient:     mockAWS,
+			AnnotationKey: AnnotationKey,
+			RepairUntil:   repairUntil,
+		}
+	}
+
+	t.Run("outside the window the mismatch is a hash conflict", func(t *testing.T) {
+		pod := newPod()
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{
+			ID:   "eni-restored",
+			Tags: map[string]string{"team": "platform", HashTagKey: "newer-hash"},
+		}, nil)
+
+		_, err := newReconciler(k8sClient, mockAWS, time.Time{}).Reconcile(context.Background(), req)
+		assert.ErrorContains(t, err, "hash conflict")
+	})
+
+	t.Run("matching ENI tags are adopted without AWS writes", func(t *testing.T) {
+		pod := newPod()
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{
+			ID:   "eni-restored",
+			Tags: map[string]string{"team": "platform", HashTagKey: desiredHash},
+		}, nil)
+
+		_, err := newReconciler(k8sClient, mockAWS, time.Now().Add(time.Hour)).Reconcile(context.Background(), req)
+		require.NoError(t, err)
+		mockAWS.AssertNotCalled(t, "TagENI", mock.Anything, mock.Anything, mock.Anything)
+		mockAWS.AssertNotCalled(t, "UntagENI", mock.Anything, mock.Anything, mock.Anything)
+
+		updated := &corev1.Pod{}
+		require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+		assert.Equal(t, desiredHash, updated.Annotations[LastAppliedHashKey])
+		assert.JSONEq(t, `{"team":"platform"}`, updated.Annotations[LastAppliedAnnotationKey])
+	})
+
+	t.Run("missing hash tag is restored without rewriting tags", func(t *testing.T) {
+		pod := newPod()
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{
+			ID:   "eni-restored",
+			Tags: map[string]string{"team": "platform"},
+		}, nil)
+		mockAWS.On("TagENI", mock.Anything, "eni-restored", map[string]string{HashTagKey: desiredHash}).Return(nil)
+
+		_, err := newReconciler(k8sClient, mockAWS, time.Now().Add(time.Hour)).Reconcile(context.Background(), req)
+		require.NoError(t, err)
+		mockAWS.AssertExpectations(t)
+	})
+}
+
+func TestReconcileInvalidTagsPolicy(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	lastHash := computeHash(map[string]string{"team": "platform"})
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "pod-invalid", Namespace: "default"}}
+	// The annotation was valid and applied, then edited to use a reserved prefix.
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "pod-invalid",
+				Namespace: "default",
+				Annotations: map[string]string{
+					AnnotationKey:            `{"aws:team":"platform"}`,
+					LastAppliedAnnotationKey: `{"team":"platform"}`,
+					LastAppliedHashKey:       lastHash,
+				},
+				Finalizers: []string{finalizerName},
+			},
+			Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+		}
+	}
+	eniInfo := func() *aws.ENIInfo {
+		return &aws.ENIInfo{
+			ID:       "eni-invalid",
+			SubnetID: "subnet-1",
+			Tags:     map[string]string{"team": "edited", HashTagKey: lastHash},
+		}
+	}
+	run := func(t *testing.T, policy InvalidTagsPolicy, mockAWS *MockAWSClient) (*corev1.Pod, ConditionDetails) {
+		pod := newPod()
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+		r := &PodReconciler{
+			Client:        k8sClient,
+			Scheme:        scheme,
+			Recorder:      record.NewFakeRecorder(10),
+			AWSClient:     mockAWS,
+			AnnotationKey: AnnotationKey,
+			InvalidTags:   policy,
+		}
+		_, err := r.Reconcile(context.Background(), req)
+		require.NoError(t, err)
+
+		updated := &corev1.Pod{}
+		require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+		for _, c := range updated.Status.Conditions {
+			if c.Reason == string(ReasonInvalidTags) {
+				details, err := ParseConditionDetails(c.Message)
+				require.NoError(t, err)
+				return updated, details
+			}
+		}
+		t.Fatal("InvalidTags condition not set")
+		return nil, ConditionDetails{}
+	}
+
+	t.Run("keep leaves the ENI untouched", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		_, details := run(t, InvalidTagsKeep, mockAWS)
+		mockAWS.AssertNotCalled(t, "GetENIInfoByIP", mock.Anything, mock.Anything)
+		assert.Equal(t, InvalidTagsKeep, details.InvalidTagsPolicy)
+	})
+
+	t.Run("rollback restores tags edited out of band", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(eniInfo(), nil)
+		mockAWS.On("TagENI", mock.Anything, "eni-invalid", map[string]string{"team": "platform"}).Return(nil)
+
+		updated, details := run(t, InvalidTagsRollback, mockAWS)
+		mockAWS.AssertExpectations(t)
+		assert.Equal(t, InvalidTagsRollback, details.InvalidTagsPolicy)
+		assert.Equal(t, "eni-invalid", details.ENIID)
+		assert.Equal(t, lastHash, updated.Annotations[LastAppliedHashKey])
+	})
+
+	t.Run("remove untags the ENI and clears bookkeeping", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(eniInfo(), nil)
+		mockAWS.On("UntagENI", mock.Anything, "eni-invalid", []string{"team", HashTagKey}).Return(nil)
+
+		updated, details := run(t, InvalidTagsRemove, mockAWS)
+		mockAWS.AssertExpectations(t)
+		assert.Equal(t, InvalidTagsRemove, details.InvalidTagsPolicy)
+		assert.NotContains(t, updated.Annotations, LastAppliedAnnotationKey)
+		assert.NotContains(t, updated.Annotations, LastAppliedHashKey)
+	})
+
+	t.Run("tags owned by someone else are kept", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		info := eniInfo()
+		info.Tags[HashTagKey] = "other-hash"
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(info, nil)
+
+		_, details := run(t, InvalidTagsRemove, mockAWS)
+		mockAWS.AssertNotCalled(t, "UntagENI", mock.Anything, mock.Anything, mock.Anything)
+		assert.Equal(t, InvalidTagsKeep, details.InvalidTagsPolicy)
+		assert.Contains(t, details.Message, "left in place")
+	})
+}
+
+func TestReconcileMaintenanceWindow(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	desiredHash := computeHash(map[string]string{"team": "platform"})
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "pod-window", Namespace: "default"}}
+	// A window starting an hour from now, so it is closed during the test.
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Minute)
+	closed := MaintenanceWindows{{
+		Start: start.Sub(start.Truncate(24 * time.Hour)),
+		End:   start.Add(time.Hour).Sub(start.Truncate(24 * time.Hour)),
+	}}
+	run := func(t *testing.T, annotations map[string]string, mockAWS *MockAWSClient) (reconcile.Result, *corev1.Pod) {
+		annotations[AnnotationKey] = `{"team":"platform"}`
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod-window",
+				Namespace:   "default",
+				Annotations: annotations,
+				Finalizers:  []string{finalizerName},
+			},
+			Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+		}
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+		r := &PodReconciler{
+			Client:             k8sClient,
+			Scheme:             scheme,
+			Recorder:           record.NewFakeRecorder(10),
+			AWSClient:          mockAWS,
+			AnnotationKey:      AnnotationKey,
+			DiffSource:         TagDiffSourceENI,
+			MaintenanceWindows: closed,
+		}
+		result, err := r.Reconcile(context.Background(), req)
+		require.NoError(t, err)
+		updated := &corev1.Pod{}
+		require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+		return result, updated
+	}
+
+	t.Run("drift repair waits for the window", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{
+			ID:   "eni-window",
+			Tags: map[string]string{"team": "edited", HashTagKey: desiredHash},
+		}, nil)
+
+		result, updated := run(t, map[string]string{
+			LastAppliedAnnotationKey: `{"team":"platform"}`,
+			LastAppliedHashKey:       desiredHash,
+		}, mockAWS)
+		mockAWS.AssertNotCalled(t, "TagENI", mock.Anything, mock.Anything, mock.Anything)
+		assert.InDelta(t, time.Until(start).Seconds(), result.RequeueAfter.Seconds(), 5)
+		require.Len(t, updated.Status.Conditions, 1)
+		assert.Equal(t, string(ReasonDeferred), updated.Status.Conditions[0].Reason)
+	})
+
+	t.Run("first tagging is not deferred", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{ID: "eni-window", Tags: map[string]string{}}, nil)
+		mockAWS.On("TagENI", mock.Anything, "eni-window", map[string]string{"team": "platform", HashTagKey: desiredHash}).Return(nil)
+
+		_, updated := run(t, map[string]string{}, mockAWS)
+		mockAWS.AssertExpectations(t)
+		assert.Equal(t, desiredHash, updated.Annotations[LastAppliedHashKey])
+	})
+}
+
+func TestReconcileMutationBudget(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	req := reconcile.Request{NamespacedName: client.ObjectKey{Name: "pod-budget", Namespace: "default"}}
+	budget, err := aws.NewMutationBudget(1, time.Hour)
+	require.NoError(t, err)
+	budget.Record()
+	run := func(t *testing.T, criticalTagKeys []string, mockAWS *MockAWSClient) (reconcile.Result, *corev1.Pod) {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "pod-budget",
+				Namespace:   "default",
+				Annotations: map[string]string{AnnotationKey: `{"team":"platform","note":"x"}`},
+				Finalizers:  []string{finalizerName},
+			},
+			Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+		}
+		k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+		r := &PodReconciler{
+			Client:          k8sClient,
+			Scheme:          scheme,
+			Recorder:        record.NewFakeRecorder(10),
+			AWSClient:       mockAWS,
+			AnnotationKey:   AnnotationKey,
+			CriticalTagKeys: criticalTagKeys,
+			MutationBudget:  budget,
+		}
+		result, err := r.Reconcile(context.Background(), req)
+		require.NoError(t, err)
+		updated := &corev1.Pod{}
+		require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+		return result, updated
+	}
+
+	t.Run("best-effort change waits for the next window", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{ID: "eni-budget", Tags: map[string]string{}}, nil)
+
+		result, updated := run(t, []string{"cost-center"}, mockAWS)
+		mockAWS.AssertNotCalled(t, "TagENI", mock.Anything, mock.Anything, mock.Anything)
+		assert.Positive(t, result.RequeueAfter)
+		assert.LessOrEqual(t, result.RequeueAfter, time.Hour)
+		require.Len(t, updated.Status.Conditions, 1)
+		assert.Equal(t, string(ReasonDeferred), updated.Status.Conditions[0].Reason)
+		assert.Contains(t, updated.Status.Conditions[0].Message, "mutation budget")
+	})
+
+	t.Run("critical change is made", func(t *testing.T) {
+		mockAWS := new(MockAWSClient)
+		mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{ID: "eni-budget", Tags: map[string]string{}}, nil)
+		mockAWS.On("TagENI", mock.Anything, "eni-budget", mock.Anything).Return(nil)
+
+		_, updated := run(t, []string{"team"}, mockAWS)
+		mockAWS.AssertExpectations(t)
+		assert.NotEmpty(t, updated.Annotations[LastAppliedHashKey])
+	})
+}
+
+func TestReconcileDryRunAuditLog(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-audit",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationKey: `{"team":"platform"}`},
+			Finalizers:  []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.10"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.10").Return(&aws.ENIInfo{ID: "eni-audit", Tags: map[string]string{}}, nil)
+	var buf bytes.Buffer
+	r := &PodReconciler{
+		Client:        k8sClient,
+		Scheme:        scheme,
+		Recorder:      record.NewFakeRecorder(10),
+		AWSClient:     mockAWS,
+		AnnotationKey: AnnotationKey,
+		DryRun:        true,
+		AuditLog:      audit.NewLog(&buf),
+	}
+
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+	require.NoError(t, err)
+	mockAWS.AssertNotCalled(t, "TagENI", mock.Anything, mock.Anything, mock.Anything)
+
+	var entry audit.Entry
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, audit.OperationCreateTags, entry.Operation)
+	assert.Equal(t, "default/pod-audit", entry.Pod)
+	assert.Equal(t, "eni-audit", entry.ENIID)
+	assert.Equal(t, map[string]string{"team": "platform"}, entry.Tags)
+	assert.True(t, entry.DryRun)
+	assert.Equal(t, audit.ResultSkipped, entry.Result)
+}
+
+func TestReconcileDryRunDiffEvent(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-dry-run",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationKey:            `{"team":"platform"}`,
+				LastAppliedAnnotationKey: `{"team":"infra","old":"x"}`,
+			},
+			Finalizers: []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.12"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.12").Return(&aws.ENIInfo{ID: "eni-dry-run", Tags: map[string]string{}}, nil)
+	recorder := record.NewFakeRecorder(10)
+	r := &PodReconciler{
+		Client:        k8sClient,
+		Scheme:        scheme,
+		Recorder:      recorder,
+		AWSClient:     mockAWS,
+		AnnotationKey: AnnotationKey,
+		DryRun:        true,
+	}
+
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+	require.NoError(t, err)
+
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	assert.Contains(t, events, `Normal DryRunDiff {"eniID":"eni-dry-run","toAdd":{"team":"platform"},"toRemove":["old"]}`)
+}
+
+func TestReconcileTagQuotaLow(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-quota",
+			Namespace:   "default",
+			Annotations: map[string]string{AnnotationKey: `{"team":"platform"}`},
+			Finalizers:  []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.11"},
+	}
+	foreign := make(map[string]string)
+	for i := 0; i < 45; i++ {
+		foreign[fmt.Sprintf("foreign-%02d", i)] = "x"
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.11").Return(&aws.ENIInfo{ID: "eni-quota", Tags: foreign}, nil)
+	mockAWS.On("TagENI", mock.Anything, "eni-quota", mock.Anything).Return(nil)
+	recorder := record.NewFakeRecorder(10)
+	r := &PodReconciler{
+		Client:             k8sClient,
+		Scheme:             scheme,
+		Recorder:           recorder,
+		AWSClient:          mockAWS,
+		AnnotationKey:      AnnotationKey,
+		TagHeadroomWarning: 5,
+	}
+
+	_, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)})
+	require.NoError(t, err)
+
+	// 45 foreign tags, the pod's tag and the hash tag leave 3 slots
+	var events []string
+	for len(recorder.Events) > 0 {
+		events = append(events, <-recorder.Events)
+	}
+	assert.Contains(t, events, "Warning TagQuotaLow ENI eni-quota has 3 of 50 tag slots left")
+}
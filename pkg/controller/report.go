@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// ownershipReportHeader is the header row of the ownership report CSV.
+var ownershipReportHeader = []string{"eni_id", "subnet_id", "namespace", "pod", "tag_key", "tag_value"}
+
+// OwnershipReport periodically writes which pod put which tags on which ENI to
+// S3 as CSV, one row per tag, for FinOps tools to ingest. It only runs on the
+// leader. Objects are named <Prefix>dt=<YYYY-MM-DD>/eni-ownership-<HHMMSS>Z.csv
+// so they can be queried as a date-partitioned table.
+//
+// Rows come from the controller's own bookkeeping: the last applied tags of each
+// pod and the ENI recorded in its tagged condition, so writing a report costs no
+// EC2 calls.
+type OwnershipReport struct {
+	Client client.Reader
+	Writer aws.ObjectWriter
+	Bucket string
+	Prefix string
+	// Interval is the time between reports. A report is also written at start.
+	Interval time.Duration
+	Keys     Keys
+	// StateStore, when set, holds the last applied tags instead of pod annotations.
+	StateStore *StateStore
+}
+
+// Start implements manager.Runnable.
+func (o *OwnershipReport) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("ownership-report")
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
+
+	for {
+		key, rows, err := o.write(ctx, time.Now())
+		if err != nil {
+			metrics.OwnershipReportsTotal.WithLabelValues("error").Inc()
+			logger.Error(err, "Failed to write ENI ownership report", "bucket", o.Bucket)
+		} else {
+			metrics.OwnershipReportsTotal.WithLabelValues("success").Inc()
+			logger.Info("Wrote ENI ownership report", "bucket", o.Bucket, "key", key, "rows", rows)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// write builds the report and uploads it, returning its key and row count.
+func (o *OwnershipReport) write(ctx context.Context, now time.Time) (string, int, error) {
+	rows, err := o.rows(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.WriteAll(append([][]string{ownershipReportHeader}, rows...)); err != nil {
+		return "", 0, err
+	}
+
+	now = now.UTC()
+	key := fmt.Sprintf("%sdt=%s/eni-ownership-%sZ.csv", o.Prefix, now.Format("2006-01-02"), now.Format("150405"))
+	if err := o.Writer.PutObject(ctx, o.Bucket, key, "text/csv", buf.Bytes()); err != nil {
+		return "", 0, err
+	}
+	return key, len(rows), nil
+}
+
+// rows returns one row per tag applied by a pod, sorted by ENI, pod and key.
+func (o *OwnershipReport) rows(ctx context.Context) ([][]string, error) {
+	pods := &corev1.PodList{}
+	if err := o.Client.List(ctx, pods); err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	var rows [][]string
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		details, ok := o.taggedDetails(pod)
+		if !ok {
+			continue
+		}
+		tags, err := o.appliedTags(ctx, pod)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range tags {
+			rows = append(rows, []string{details.ENIID, details.SubnetID, pod.Namespace, pod.Name, k, v})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		for c := range rows[i] {
+			if rows[i][c] != rows[j][c] {
+				return rows[i][c] < rows[j][c]
+			}
+		}
+		return false
+	})
+	return rows, nil
+}
+
+// taggedDetails returns the condition details of a pod whose tags are on an ENI.
+func (o *OwnershipReport) taggedDetails(pod *corev1.Pod) (ConditionDetails, bool) {
+	for _, c := range pod.Status.Conditions {
+		if string(c.Type) != o.Keys.ConditionType {
+			continue
+		}
+		details, err := ParseConditionDetails(c.Message)
+		return details, err == nil && details.ENIID != ""
+	}
+	return ConditionDetails{}, false
+}
+
+// appliedTags returns the tags pod last applied to its ENI.
+func (o *OwnershipReport) appliedTags(ctx context.Context, pod *corev1.Pod) (map[string]string, error) {
+	value := pod.Annotations[o.Keys.LastAppliedTags]
+	if o.StateStore != nil {
+		state, ok, err := o.StateStore.get(ctx, client.ObjectKeyFromObject(pod))
+		if err != nil {
+			return nil, err
+		}
+		value = ""
+		if ok && state.UID == pod.UID {
+			value = state.Tags
+		}
+	}
+	if value == "" {
+		return nil, nil
+	}
+	tags, err := parseLastApplied(value)
+	if err != nil {
+		// One corrupt annotation should not stop the report
+		log.FromContext(ctx).V(1).Info("Skipping pod with unreadable last-applied tags", LogKeyPod, client.ObjectKeyFromObject(pod), LogKeyError, err.Error())
+		return nil, nil
+	}
+	return tags, nil
+}
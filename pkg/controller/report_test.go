@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeObjectWriter records uploaded objects.
+type fakeObjectWriter struct {
+	bucket, key, contentType string
+	body                     string
+}
+
+func (w *fakeObjectWriter) PutObject(_ context.Context, bucket, key, contentType string, body []byte) error {
+	w.bucket, w.key, w.contentType, w.body = bucket, key, contentType, string(body)
+	return nil
+}
+
+func TestOwnershipReport(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	keys := NewKeys("")
+
+	taggedPod := func(name, eniID, tags string) *corev1.Pod {
+		message := ConditionDetails{Message: "ok", ENIID: eniID, SubnetID: "subnet-1"}.String()
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Annotations: map[string]string{keys.LastAppliedTags: tags},
+			},
+			Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+				Type:    corev1.PodConditionType(keys.ConditionType),
+				Status:  corev1.ConditionTrue,
+				Message: message,
+			}}},
+		}
+	}
+	untagged := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: "default"}}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		taggedPod("web", "eni-2", `{"team":"web","cost-center":"42"}`),
+		taggedPod("api", "eni-1", `{"team":"api"}`),
+		untagged,
+	).Build()
+	writer := &fakeObjectWriter{}
+	report := &OwnershipReport{Client: k8sClient, Writer: writer, Bucket: "finops", Prefix: "eni/", Keys: keys}
+
+	key, rows, err := report.write(context.Background(), time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 3, rows)
+	assert.Equal(t, "eni/dt=2024-03-05/eni-ownership-140709Z.csv", key)
+	assert.Equal(t, "finops", writer.bucket)
+	assert.Equal(t, "text/csv", writer.contentType)
+	assert.Equal(t, "eni_id,subnet_id,namespace,pod,tag_key,tag_value\n"+
+		"eni-1,subnet-1,default,api,team,api\n"+
+		"eni-2,subnet-1,default,web,cost-center,42\n"+
+		"eni-2,subnet-1,default,web,team,web\n", writer.body)
+}
@@ -0,0 +1,186 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/metrics"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// syncSecurityGroupTags applies tags, the pod's tags just written to its ENI, to
+// the security groups attached to the ENI. A group is claimed when it carries no
+// security group hash, or the pod's last applied hash, in which case tags the pod
+// no longer has are removed. Groups holding another hash are tagged for pods with
+// other tags and are left alone.
+//
+// Security group tags are best-effort: failures and conflicts are logged and
+// reported as Warning events but do not fail the pod's condition, and are retried
+// with the pod's next tag change.
+func (r *PodReconciler) syncSecurityGroupTags(ctx context.Context, pod *corev1.Pod, eniInfo *aws.ENIInfo, tags, lastAppliedTags map[string]string, hash, lastAppliedHash string) {
+	if r.SecurityGroups == nil || len(eniInfo.SecurityGroupIDs) == 0 {
+		return
+	}
+	logger := log.FromContext(ctx)
+	keys := r.keys()
+
+	groupTags, err := r.SecurityGroups.GetSecurityGroupTags(ctx, eniInfo.SecurityGroupIDs)
+	if err != nil {
+		r.securityGroupTaggingFailed(logger, pod, eniInfo.SecurityGroupIDs, err)
+		return
+	}
+
+	desired, removed := r.sharedResourceTags(tags, lastAppliedTags, keys.SecurityGroupHashTag, hash)
+	toTag, toUntag, conflicts := r.claimSharedResources(eniInfo.SecurityGroupIDs, groupTags, keys.SecurityGroupHashTag, desired, hash, lastAppliedHash)
+
+	if len(conflicts) > 0 {
+		metrics.SecurityGroupTaggingTotal.WithLabelValues("conflict").Add(float64(len(conflicts)))
+		logger.Info("Security groups carry tags of other pods, leaving them untouched", "securityGroups", conflicts)
+		r.Recorder.Event(pod, corev1.EventTypeWarning, "SecurityGroupTagConflict", fmt.Sprintf("Security groups %s of ENI %s carry tags of other pods or installations and were not tagged", strings.Join(conflicts, ", "), eniInfo.ID))
+	}
+	if err := r.SecurityGroups.TagSecurityGroups(ctx, toTag, desired); err != nil {
+		r.securityGroupTaggingFailed(logger, pod, toTag, err)
+		return
+	}
+	if len(removed) > 0 {
+		if err := r.SecurityGroups.UntagSecurityGroups(ctx, toUntag, removed); err != nil {
+			r.securityGroupTaggingFailed(logger, pod, toUntag, err)
+			return
+		}
+	}
+	if len(toTag) > 0 {
+		metrics.SecurityGroupTaggingTotal.WithLabelValues("applied").Add(float64(len(toTag)))
+		logger.Info("Applied tags to security groups", "securityGroups", toTag, "removed", len(removed))
+	}
+}
+
+// cleanupSecurityGroupTags removes the tags of a deleted pod from the security
+// groups of its ENI that carry its hash, unless another ENI in the group still
+// carries the same tags, i.e. another pod with the same tags still uses the group.
+func (r *PodReconciler) cleanupSecurityGroupTags(ctx context.Context, logger logr.Logger, eniInfo *aws.ENIInfo, lastAppliedTags map[string]string, lastAppliedHash string) {
+	if r.SecurityGroups == nil || len(eniInfo.SecurityGroupIDs) == 0 || lastAppliedHash == "" {
+		return
+	}
+	keys := r.keys()
+
+	groupTags, err := r.SecurityGroups.GetSecurityGroupTags(ctx, eniInfo.SecurityGroupIDs)
+	if err != nil {
+		metrics.SecurityGroupTaggingTotal.WithLabelValues("error").Inc()
+		logger.Error(err, "Failed to read security group tags, leaving them in place", "securityGroups", eniInfo.SecurityGroupIDs)
+		return
+	}
+
+	var unused []string
+	for _, id := range eniInfo.SecurityGroupIDs {
+		if groupTags[id][keys.SecurityGroupHashTag] != lastAppliedHash {
+			continue
+		}
+		enis, err := r.SecurityGroups.FindSecurityGroupENIs(ctx, id, keys.HashTag, lastAppliedHash)
+		if err != nil {
+			metrics.SecurityGroupTaggingTotal.WithLabelValues("error").Inc()
+			logger.Error(err, "Failed to find ENIs of security group, leaving its tags in place", "securityGroup", id)
+			continue
+		}
+		if len(enis) > 1 || (len(enis) == 1 && enis[0] != eniInfo.ID) {
+			logger.V(1).Info("Security group still used by ENIs with the same tags, keeping its tags", "securityGroup", id, "enis", enis)
+			continue
+		}
+		unused = append(unused, id)
+	}
+	if len(unused) == 0 {
+		return
+	}
+
+	tagKeys := r.sharedResourceTagKeys(lastAppliedTags, keys.SecurityGroupHashTag)
+	if err := r.SecurityGroups.UntagSecurityGroups(ctx, unused, tagKeys); err != nil {
+		metrics.SecurityGroupTaggingTotal.WithLabelValues("error").Inc()
+		logger.Error(err, "Failed to clean up security group tags", "securityGroups", unused)
+		return
+	}
+	metrics.SecurityGroupTaggingTotal.WithLabelValues("removed").Add(float64(len(unused)))
+	logger.Info("Cleaned up tags on security groups", "securityGroups", unused, "tags", tagKeys)
+}
+
+func (r *PodReconciler) securityGroupTaggingFailed(logger logr.Logger, pod *corev1.Pod, groupIDs []string, err error) {
+	metrics.SecurityGroupTaggingTotal.WithLabelValues("error").Inc()
+	logger.Error(err, "Failed to tag security groups", "securityGroups", groupIDs)
+	r.Recorder.Event(pod, corev1.EventTypeWarning, "SecurityGroupTaggingFailed", err.Error())
+}
+
+// sharedResourceTags returns the tags to write to a resource shared by the pods
+// with the same tags, such as a security group: the pod's tags, its hash under
+// hashKey and the owner tag, and the keys of lastAppliedTags the pod dropped.
+func (r *PodReconciler) sharedResourceTags(tags, lastAppliedTags map[string]string, hashKey, hash string) (map[string]string, []string) {
+	desired := maps.Clone(tags)
+	desired[hashKey] = hash
+	if r.ControllerID != "" {
+		desired[r.keys().OwnerTag] = r.ControllerID
+	}
+	var removed []string
+	for k := range lastAppliedTags {
+		if _, ok := tags[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+	return desired, removed
+}
+
+// claimSharedResources sorts ids, resources with the tags in current, by what
+// the pod may do with them. A resource is claimed when it carries no hash under
+// hashKey, or the pod's last applied hash, in which case the pod's dropped tags
+// are removed (toUntag) too. Resources holding another hash, or owned by another
+// installation, are conflicts and are left alone.
+func (r *PodReconciler) claimSharedResources(ids []string, current map[string]map[string]string, hashKey string, desired map[string]string, hash, lastAppliedHash string) (toTag, toUntag, conflicts []string) {
+	ownerTag := r.keys().OwnerTag
+	for _, id := range ids {
+		tags := current[id]
+		if owner := tags[ownerTag]; r.ControllerID != "" && owner != "" && owner != r.ControllerID {
+			conflicts = append(conflicts, id)
+			continue
+		}
+		switch resourceHash := tags[hashKey]; {
+		case resourceHash == hash:
+			if !hasTags(tags, desired) {
+				toTag = append(toTag, id)
+			}
+		case resourceHash == "":
+			toTag = append(toTag, id)
+		case resourceHash == lastAppliedHash:
+			toTag = append(toTag, id)
+			toUntag = append(toUntag, id)
+		default:
+			conflicts = append(conflicts, id)
+		}
+	}
+	return toTag, toUntag, conflicts
+}
+
+// sharedResourceTagKeys returns the keys to remove from a shared resource whose
+// last pod with lastAppliedTags went away, including its hash and owner tags.
+func (r *PodReconciler) sharedResourceTagKeys(lastAppliedTags map[string]string, hashKey string) []string {
+	tagKeys := make([]string, 0, len(lastAppliedTags)+2)
+	for k := range lastAppliedTags {
+		tagKeys = append(tagKeys, k)
+	}
+	tagKeys = append(tagKeys, hashKey)
+	if r.ControllerID != "" {
+		tagKeys = append(tagKeys, r.keys().OwnerTag)
+	}
+	return tagKeys
+}
+
+// hasTags reports whether current carries every tag in want with the same value.
+func hasTags(current, want map[string]string) bool {
+	for k, v := range want {
+		if cur, ok := current[k]; !ok || cur != v {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// fakeSecurityGroups keeps security group tags in memory. enis maps a group to
+// the ENIs attached to it and their hash tags.
+type fakeSecurityGroups struct {
+	tags map[string]map[string]string
+	enis map[string]map[string]string
+}
+
+func (f *fakeSecurityGroups) GetSecurityGroupTags(ctx context.Context, groupIDs []string) (map[string]map[string]string, error) {
+	out := make(map[string]map[string]string)
+	for _, id := range groupIDs {
+		out[id] = f.tags[id]
+	}
+	return out, nil
+}
+
+func (f *fakeSecurityGroups) TagSecurityGroups(ctx context.Context, groupIDs []string, tags map[string]string) error {
+	for _, id := range groupIDs {
+		if f.tags[id] == nil {
+			f.tags[id] = make(map[string]string)
+		}
+		for k, v := range tags {
+			f.tags[id][k] = v
+		}
+	}
+	return nil
+}
+
+func (f *fakeSecurityGroups) UntagSecurityGroups(ctx context.Context, groupIDs []string, tagKeys []string) error {
+	for _, id := range groupIDs {
+		for _, k := range tagKeys {
+			delete(f.tags[id], k)
+		}
+	}
+	return nil
+}
+
+func (f *fakeSecurityGroups) FindSecurityGroupENIs(ctx context.Context, groupID, key, value string) ([]string, error) {
+	var ids []string
+	for eni, hash := range f.enis[groupID] {
+		if hash == value {
+			ids = append(ids, eni)
+		}
+	}
+	return ids, nil
+}
+
+var _ aws.SecurityGroupTagger = (*fakeSecurityGroups)(nil)
+
+func TestSyncSecurityGroupTags(t *testing.T) {
+	oldTags := map[string]string{"team": "a", "env": "dev"}
+	newTags := map[string]string{"team": "a"}
+	oldHash, newHash := computeHash(oldTags), computeHash(newTags)
+	sgs := &fakeSecurityGroups{tags: map[string]map[string]string{
+		"sg-new":     {},
+		"sg-ours":    {"team": "a", "env": "dev", SecurityGroupHashTagKey: oldHash},
+		"sg-other":   {"team": "b", SecurityGroupHashTagKey: "other"},
+		"sg-foreign": {OwnerTagKey: "cluster-b"},
+	}}
+	recorder := record.NewFakeRecorder(10)
+	r := &PodReconciler{Recorder: recorder, SecurityGroups: sgs, ControllerID: "cluster-a"}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	eni := &aws.ENIInfo{ID: "eni-1", SecurityGroupIDs: []string{"sg-new", "sg-ours", "sg-other", "sg-foreign"}}
+
+	r.syncSecurityGroupTags(context.Background(), pod, eni, newTags, oldTags, newHash, oldHash)
+
+	want := map[string]string{"team": "a", SecurityGroupHashTagKey: newHash, OwnerTagKey: "cluster-a"}
+	assert.Equal(t, want, sgs.tags["sg-new"])
+	assert.Equal(t, want, sgs.tags["sg-ours"], "tags the pod no longer has are removed")
+	assert.Equal(t, map[string]string{"team": "b", SecurityGroupHashTagKey: "other"}, sgs.tags["sg-other"])
+	assert.Equal(t, map[string]string{OwnerTagKey: "cluster-b"}, sgs.tags["sg-foreign"])
+	assert.Contains(t, <-recorder.Events, "SecurityGroupTagConflict")
+}
+
+func TestCleanupSecurityGroupTags(t *testing.T) {
+	tags := map[string]string{"team": "a"}
+	hash := computeHash(tags)
+	sgs := &fakeSecurityGroups{
+		tags: map[string]map[string]string{
+			"sg-unused": {"team": "a", SecurityGroupHashTagKey: hash},
+			"sg-shared": {"team": "a", SecurityGroupHashTagKey: hash},
+			"sg-other":  {"team": "b", SecurityGroupHashTagKey: "other"},
+		},
+		enis: map[string]map[string]string{
+			"sg-shared": {"eni-2": hash},
+		},
+	}
+	r := &PodReconciler{SecurityGroups: sgs}
+	eni := &aws.ENIInfo{ID: "eni-1", SecurityGroupIDs: []string{"sg-unused", "sg-shared", "sg-other"}}
+
+	r.cleanupSecurityGroupTags(context.Background(), logr.Discard(), eni, tags, hash)
+
+	assert.Empty(t, sgs.tags["sg-unused"])
+	assert.Equal(t, map[string]string{"team": "a", SecurityGroupHashTagKey: hash}, sgs.tags["sg-shared"], "another ENI with the same tags still uses the group")
+	assert.Equal(t, map[string]string{"team": "b", SecurityGroupHashTagKey: "other"}, sgs.tags["sg-other"])
+}
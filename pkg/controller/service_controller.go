@@ -0,0 +1,260 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"k8s-eni-tagger/pkg/audit"
+	"k8s-eni-tagger/pkg/aws"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ServiceControllerName is the name of the Service controller.
+const ServiceControllerName = "service"
+
+// serviceENIRequeueDelay is how long a Service waits for its load balancer's
+// ENIs, which ELB creates after the DNS name is published.
+const serviceENIRequeueDelay = time.Minute
+
+// ServiceReconciler tags the ENIs of the Network and Classic Load Balancers
+// created for type LoadBalancer Services from the Service's tag annotation, in
+// the same formats as on pods. The ENIs are found from the DNS names in the
+// Service's status by the descriptions ELB gives them (see
+// aws.LoadBalancerENIDescription).
+//
+// The ENIs of a load balancer belong to it alone, so desired tags are compared
+// with the tags on each ENI rather than tracked by hash. The last applied tags
+// are kept in the Service's last-applied annotation so tags dropped from the
+// annotation, or the whole annotation, are removed again. Nothing is cleaned up
+// on deletion: ELB deletes the ENIs with the load balancer.
+type ServiceReconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+	// AWSClient writes the tags; Finder resolves load balancer ENIs.
+	AWSClient aws.Client
+	Finder    aws.LoadBalancerENIFinder
+
+	// The settings below mean the same as on PodReconciler.
+	AnnotationKey string
+	KeyDomain     string
+	TagNamespace  string
+	TagKeyRenames map[string]string
+	TagKeyCase    TagKeyCasePolicy
+	DryRun        bool
+	Pause         *PauseSwitch
+	AuditLog      *audit.Log
+}
+
+// Reconcile tags the load balancer ENIs of a Service.
+func (r *ServiceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("service", req.NamespacedName)
+	ctx = log.IntoContext(ctx, logger)
+	ctx = audit.WithService(ctx, req.Namespace, req.Name)
+
+	svc := &corev1.Service{}
+	if err := r.Get(ctx, req.NamespacedName, svc); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	keys := NewKeys(r.KeyDomain)
+	annotationValue := svc.Annotations[r.annotationKey()]
+	lastAppliedValue := svc.Annotations[keys.LastAppliedTags]
+	if !r.manages(svc) || !svc.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	desired, err := r.desiredTags(svc, annotationValue)
+	if err != nil {
+		logger.Error(err, "Invalid tags on Service")
+		r.Recorder.Event(svc, corev1.EventTypeWarning, string(ReasonInvalidTags), err.Error())
+		return ctrl.Result{}, nil
+	}
+	lastApplied, err := parseLastApplied(lastAppliedValue)
+	if err != nil {
+		logger.Error(err, "Failed to parse last applied tags, treating as empty", "value", lastAppliedValue)
+		lastApplied = make(map[string]string)
+	}
+
+	hostnames := loadBalancerHostnames(svc)
+	if len(hostnames) == 0 {
+		// The status update publishing the DNS name triggers the next reconcile
+		logger.V(1).Info("Load balancer not provisioned yet")
+		return ctrl.Result{}, nil
+	}
+
+	var enis []*aws.ENIInfo
+	for _, hostname := range hostnames {
+		found, err := r.Finder.FindLoadBalancerENIs(ctx, hostname)
+		if err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to find ENIs of load balancer %s: %w", hostname, err)
+		}
+		enis = append(enis, found...)
+	}
+	if len(enis) == 0 {
+		logger.Info("No ENIs found for load balancer yet, retrying", "hostnames", hostnames, LogKeyRequeueAfter, serviceENIRequeueDelay)
+		return ctrl.Result{RequeueAfter: serviceENIRequeueDelay}, nil
+	}
+
+	removed := computeTagDiff(desired, lastApplied).toRemove
+	changed := 0
+	for _, eni := range enis {
+		toAdd := make(map[string]string)
+		for k, v := range desired {
+			if current, ok := eni.Tags[k]; !ok || current != v {
+				toAdd[k] = v
+			}
+		}
+		var toRemove []string
+		for _, k := range removed {
+			if _, ok := eni.Tags[k]; ok {
+				toRemove = append(toRemove, k)
+			}
+		}
+		if len(toAdd) == 0 && len(toRemove) == 0 {
+			continue
+		}
+		changed++
+		if r.DryRun {
+			logger.Info("DRY RUN: Would apply tags to load balancer ENI", LogKeyENIID, eni.ID, "toAdd", toAdd, "toRemove", toRemove)
+			r.AuditLog.RecordDryRun(ctx, "", eni.ID, toAdd, toRemove)
+			continue
+		}
+		if r.Pause != nil && r.Pause.Paused() {
+			logger.Info("Tagging paused, not applying load balancer tags", LogKeyENIID, eni.ID)
+			return ctrl.Result{RequeueAfter: pausedRequeueDelay}, nil
+		}
+		if len(toAdd) > 0 {
+			if err := r.AWSClient.TagENI(ctx, eni.ID, toAdd); err != nil {
+				r.Recorder.Event(svc, corev1.EventTypeWarning, string(ReasonTaggingFailed), err.Error())
+				return ctrl.Result{}, fmt.Errorf("failed to tag load balancer ENI %s: %w", eni.ID, err)
+			}
+		}
+		if len(toRemove) > 0 {
+			if err := r.AWSClient.UntagENI(ctx, eni.ID, toRemove); err != nil {
+				r.Recorder.Event(svc, corev1.EventTypeWarning, string(ReasonTaggingFailed), err.Error())
+				return ctrl.Result{}, fmt.Errorf("failed to untag load balancer ENI %s: %w", eni.ID, err)
+			}
+		}
+	}
+	if r.DryRun {
+		return ctrl.Result{}, nil
+	}
+	if changed > 0 {
+		logger.Info("Applied tags to load balancer ENIs", "enis", len(enis), "changed", changed, "tags", len(desired), "removed", len(removed))
+		r.Recorder.Event(svc, corev1.EventTypeNormal, "TagsApplied", fmt.Sprintf("Applied %d tags to %d load balancer ENIs", len(desired), len(enis)))
+	}
+
+	if err := r.updateLastApplied(ctx, svc, keys, desired); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to update Service annotations after tagging: %w", err)
+	}
+	return ctrl.Result{}, nil
+}
+
+// desiredTags parses the Service's annotation and applies renames, the key case
+// policy and the namespace prefix as for pods. A missing annotation means no tags.
+func (r *ServiceReconciler) desiredTags(svc *corev1.Service, annotationValue string) (map[string]string, error) {
+	tags, err := parseTags(annotationValue)
+	if err != nil {
+		return nil, err
+	}
+	if tags, err = renameTagKeys(tags, r.TagKeyRenames); err != nil {
+		return nil, err
+	}
+	if tags, err = resolveKeyCaseConflicts(tags, r.TagKeyCase); err != nil {
+		return nil, err
+	}
+	namespace := ""
+	if r.TagNamespace == "enable" {
+		namespace = svc.Namespace
+	}
+	return applyNamespace(tags, namespace)
+}
+
+// updateLastApplied records desired as the Service's last applied tags, or
+// drops the bookkeeping annotations when there are none.
+func (r *ServiceReconciler) updateLastApplied(ctx context.Context, svc *corev1.Service, keys Keys, desired map[string]string) error {
+	base := svc.DeepCopy()
+	if len(desired) == 0 {
+		delete(svc.Annotations, keys.LastAppliedTags)
+		delete(svc.Annotations, keys.LastAppliedHash)
+	} else {
+		lastApplied, err := marshalLastApplied(desired)
+		if err != nil {
+			return err
+		}
+		if svc.Annotations == nil {
+			svc.Annotations = make(map[string]string)
+		}
+		svc.Annotations[keys.LastAppliedTags] = lastApplied
+		svc.Annotations[keys.LastAppliedHash] = computeHash(desired)
+	}
+	if maps.Equal(base.Annotations, svc.Annotations) {
+		return nil
+	}
+	return r.Patch(ctx, svc, client.MergeFrom(base))
+}
+
+// manages reports whether svc is a LoadBalancer Service with tags to apply or
+// to remove.
+func (r *ServiceReconciler) manages(svc *corev1.Service) bool {
+	if svc.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		// A Service changed away from LoadBalancer loses its load balancer
+		return false
+	}
+	_, annotated := svc.Annotations[r.annotationKey()]
+	_, applied := svc.Annotations[NewKeys(r.KeyDomain).LastAppliedTags]
+	return annotated || applied
+}
+
+// loadBalancerHostnames returns the DNS names of the Service's load balancers.
+func loadBalancerHostnames(svc *corev1.Service) []string {
+	var hostnames []string
+	for _, ingress := range svc.Status.LoadBalancer.Ingress {
+		if ingress.Hostname != "" {
+			hostnames = append(hostnames, ingress.Hostname)
+		}
+	}
+	return hostnames
+}
+
+func (r *ServiceReconciler) annotationKey() string {
+	if r.AnnotationKey == "" {
+		return AnnotationKey
+	}
+	return r.AnnotationKey
+}
+
+// SetupWithManager registers the Service controller. Services are reconciled
+// when they become managed, their tag annotation changes or their load balancer
+// DNS names change.
+func (r *ServiceReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(ServiceControllerName).
+		For(&corev1.Service{}).
+		WithEventFilter(predicate.Funcs{
+			CreateFunc: func(e event.CreateEvent) bool {
+				return r.manages(e.Object.(*corev1.Service))
+			},
+			UpdateFunc: func(e event.UpdateEvent) bool {
+				oldSvc, newSvc := e.ObjectOld.(*corev1.Service), e.ObjectNew.(*corev1.Service)
+				if !r.manages(newSvc) {
+					return false
+				}
+				return !r.manages(oldSvc) ||
+					oldSvc.Annotations[r.annotationKey()] != newSvc.Annotations[r.annotationKey()] ||
+					!slices.Equal(loadBalancerHostnames(oldSvc), loadBalancerHostnames(newSvc))
+			},
+			DeleteFunc:  func(event.DeleteEvent) bool { return false },
+			GenericFunc: func(event.GenericEvent) bool { return false },
+		}).
+		Complete(r)
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type stubLoadBalancerENIFinder map[string][]*aws.ENIInfo
+
+func (f stubLoadBalancerENIFinder) FindLoadBalancerENIs(ctx context.Context, hostname string) ([]*aws.ENIInfo, error) {
+	return f[hostname], nil
+}
+
+func TestServiceReconcile(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	const hostname = "web-0123456789abcdef.elb.us-east-1.amazonaws.com"
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "web",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationKey:            "team=platform,env=prod",
+				LastAppliedAnnotationKey: `{"team":"platform","owner":"alice"}`,
+			},
+		},
+		Spec: corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+		Status: corev1.ServiceStatus{LoadBalancer: corev1.LoadBalancerStatus{
+			Ingress: []corev1.LoadBalancerIngress{{Hostname: hostname}},
+		}},
+	}
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(svc)}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(svc).Build()
+	finder := stubLoadBalancerENIFinder{hostname: {
+		{ID: "eni-a", Tags: map[string]string{"team": "platform", "owner": "alice"}},
+		{ID: "eni-b", Tags: map[string]string{"team": "platform", "env": "prod"}},
+	}}
+
+	// Only eni-a differs: it lacks env and still has the dropped owner tag
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("TagENI", mock.Anything, "eni-a", map[string]string{"env": "prod"}).Return(nil).Once()
+	mockAWS.On("UntagENI", mock.Anything, "eni-a", []string{"owner"}).Return(nil).Once()
+
+	r := &ServiceReconciler{
+		Client:    k8sClient,
+		Recorder:  record.NewFakeRecorder(10),
+		AWSClient: mockAWS,
+		Finder:    finder,
+	}
+	res, err := r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.Zero(t, res.RequeueAfter)
+	mockAWS.AssertExpectations(t)
+
+	updated := &corev1.Service{}
+	require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+	lastApplied, err := parseLastApplied(updated.Annotations[LastAppliedAnnotationKey])
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "platform", "env": "prod"}, lastApplied)
+
+	// ELB has not created the ENIs of a new load balancer yet
+	r.Finder = stubLoadBalancerENIFinder{}
+	res, err = r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, serviceENIRequeueDelay, res.RequeueAfter)
+}
+
+func TestServiceReconcileIgnoresOtherServices(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "internal", Namespace: "default", Annotations: map[string]string{AnnotationKey: "team=a"}},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeClusterIP},
+	}
+	r := &ServiceReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(svc).Build(), Finder: stubLoadBalancerENIFinder{}}
+	assert.False(t, r.manages(svc))
+
+	res, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: client.ObjectKeyFromObject(svc)})
+	require.NoError(t, err)
+	assert.Equal(t, reconcile.Result{}, res)
+}
@@ -1,11 +1,16 @@
 package controller
 
 import (
+	"context"
+	"fmt"
+
 	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 )
 
@@ -18,60 +23,193 @@ import (
 //   - Watch Pod resources with the configured annotation key
 //   - Set the maximum number of concurrent reconciliations
 //   - Filter events to only reconcile when:
-//   - A pod is created with the annotation
+//   - A pod is created with the annotation, or without it but with leftover
+//     bookkeeping (see hasStaleBookkeeping)
 //   - The annotation value changes
 //   - A pod gets an IP for the first time (and has the annotation)
 //   - A pod is being deleted and has our finalizer
+//   - A pod with state in StateStore is deleted
+//
+// Pods matching ExcludePodSelector, and debug pods skipped by DebugPods, are
+// filtered out of create and IP-assignment events.
+//
+// Pods rejected by the subnet allow-list are requeued when SubnetAllowList changes,
+// and pods held by a pause when Pause resumes.
+//
+// With TriggerAudit set, the reason each event passed or failed the filter is recorded.
+//
+// With FairQueue set, pod events pass through it so namespaces are served round-robin.
+//
+// With PodWrites set, pods whose collapsed writes failed are requeued.
+//
+// With StateStore set, every stored pod is requeued at startup.
+//
+// With DriftResync set, pods whose ENI tags drifted are requeued.
+//
+// Pods are indexed by IP in the manager's cache (see PodIPIndexField), and by
+// node with Volumes set (see PodNodeIndexField).
 //
 // The concurrentReconciles parameter controls how many pods can be reconciled in parallel.
 func (r *PodReconciler) SetupWithManager(mgr ctrl.Manager, concurrentReconciles int) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&corev1.Pod{}).
+	if err := IndexPodIP(context.Background(), mgr.GetFieldIndexer()); err != nil {
+		return fmt.Errorf("failed to index pods by IP: %w", err)
+	}
+	if r.Volumes != nil {
+		if err := IndexPodNode(context.Background(), mgr.GetFieldIndexer()); err != nil {
+			return fmt.Errorf("failed to index pods by node: %w", err)
+		}
+	}
+	b := ctrl.NewControllerManagedBy(mgr).
+		Named(ControllerName).
 		WithOptions(controller.Options{MaxConcurrentReconciles: concurrentReconciles}).
-		WithEventFilter(r.createPredicate()).
-		Complete(r)
+		WithEventFilter(r.createPredicate())
+	if r.TriggerAudit != nil {
+		if err := mgr.Add(r.TriggerAudit); err != nil {
+			return err
+		}
+	}
+	if r.FairQueue != nil {
+		if err := mgr.Add(r.FairQueue); err != nil {
+			return err
+		}
+		b = b.Watches(&corev1.Pod{}, r.FairQueue)
+	} else {
+		b = b.For(&corev1.Pod{})
+	}
+	if r.SubnetAllowList != nil {
+		b = b.WatchesRawSource(r.SubnetAllowList.source(), &handler.EnqueueRequestForObject{})
+	}
+	if r.Pause != nil {
+		b = b.WatchesRawSource(r.Pause.source(), &handler.EnqueueRequestForObject{})
+	}
+	if r.PodWrites != nil {
+		if err := mgr.Add(r.PodWrites); err != nil {
+			return err
+		}
+		b = b.WatchesRawSource(r.PodWrites.source(), &handler.EnqueueRequestForObject{})
+	}
+	if r.StateStore != nil {
+		if err := mgr.Add(r.StateStore); err != nil {
+			return err
+		}
+		b = b.WatchesRawSource(r.StateStore.source(), &handler.EnqueueRequestForObject{})
+	}
+	if r.DriftResync != nil {
+		r.DriftResync.detect = r.detectDrift
+		if err := mgr.Add(r.DriftResync); err != nil {
+			return err
+		}
+		b = b.WatchesRawSource(r.DriftResync.source(), &handler.EnqueueRequestForObject{})
+	}
+	return b.Complete(r)
 }
 
 func (r *PodReconciler) createPredicate() predicate.Funcs {
-	key := r.AnnotationKey
-	if key == "" {
-		key = AnnotationKey
-	}
-
 	return predicate.Funcs{
 		CreateFunc: func(e event.CreateEvent) bool {
-			pod := e.Object.(*corev1.Pod)
-			_, hasAnnotation := pod.Annotations[key]
-			return hasAnnotation
+			reason, ok := r.createTrigger(e.Object.(*corev1.Pod))
+			r.recordTrigger("create", reason, ok, e.Object)
+			return ok
 		},
 		UpdateFunc: func(e event.UpdateEvent) bool {
-			oldPod := e.ObjectOld.(*corev1.Pod)
-			newPod := e.ObjectNew.(*corev1.Pod)
-
-			oldAnnotation := oldPod.Annotations[key]
-			newAnnotation := newPod.Annotations[key]
-
-			// Reconcile if annotation changed
-			if oldAnnotation != newAnnotation {
-				return true
-			}
-
-			// Reconcile if pod got an IP for the first time
-			if oldPod.Status.PodIP == "" && newPod.Status.PodIP != "" {
-				_, hasAnnotation := newPod.Annotations[key]
-				return hasAnnotation
-			}
-
-			// Reconcile if pod is being deleted and has our finalizer
-			if newPod.DeletionTimestamp != nil && controllerutil.ContainsFinalizer(newPod, finalizerName) {
+			reason, ok := r.updateTrigger(e.ObjectOld.(*corev1.Pod), e.ObjectNew.(*corev1.Pod))
+			r.recordTrigger("update", reason, ok, e.ObjectNew)
+			return ok
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			// We handle deletion via finalizers, unless state is kept in a StateStore
+			if r.StateStore != nil && r.StateStore.has(client.ObjectKeyFromObject(e.Object)) {
+				r.recordTrigger("delete", TriggerDeleted, true, e.Object)
 				return true
 			}
-
+			r.recordTrigger("delete", FilterDeleted, false, e.Object)
 			return false
 		},
-		DeleteFunc: func(e event.DeleteEvent) bool {
-			// We handle deletion via finalizers
-			return false
+		GenericFunc: func(e event.GenericEvent) bool {
+			r.recordTrigger("generic", TriggerRequeued, true, e.Object)
+			return true
 		},
 	}
 }
+
+// createTrigger reports whether a newly seen pod needs a reconcile, and why (see
+// the Trigger and Filter constants).
+func (r *PodReconciler) createTrigger(pod *corev1.Pod) (string, bool) {
+	if _, wantsTags := r.tagAnnotation(pod); !wantsTags {
+		// Every pod is seen as created at startup, which catches annotations
+		// removed while the controller was down
+		if r.hasStaleBookkeeping(pod) {
+			return TriggerStaleBookkeeping, true
+		}
+		return FilterNoAnnotation, false
+	}
+	if r.isPodExcluded(pod) {
+		return FilterExcluded, false
+	}
+	return TriggerCreated, true
+}
+
+// updateTrigger reports whether a pod update needs a reconcile, and why.
+func (r *PodReconciler) updateTrigger(oldPod, newPod *corev1.Pod) (string, bool) {
+	key := r.annotationKey()
+	_, wantsTags := r.tagAnnotation(newPod)
+
+	// Reconcile if annotation changed
+	if oldPod.Annotations[key] != newPod.Annotations[key] {
+		return TriggerAnnotationChanged, true
+	}
+
+	// Reconcile if a label mapped to a tag, or one tag values may be rendered
+	// from, changed
+	if r.labelTagsChanged(oldPod, newPod) || r.templateLabelsChanged(oldPod, newPod) {
+		return TriggerLabelsChanged, true
+	}
+
+	// Reconcile if pod got an IP for the first time
+	if oldPod.Status.PodIP == "" && newPod.Status.PodIP != "" {
+		if !wantsTags {
+			return FilterNoAnnotation, false
+		}
+		if r.isPodExcluded(newPod) {
+			return FilterExcluded, false
+		}
+		return TriggerIPAssigned, true
+	}
+
+	// Reconcile if pod is being deleted and has our finalizer
+	if newPod.DeletionTimestamp != nil && controllerutil.ContainsFinalizer(newPod, r.keys().Finalizer) {
+		return TriggerDeleting, true
+	}
+
+	if oldPod.ResourceVersion != "" && oldPod.ResourceVersion == newPod.ResourceVersion {
+		return FilterResync, false
+	}
+	if !wantsTags {
+		return FilterNoAnnotation, false
+	}
+	return FilterUnchanged, false
+}
+
+func (r *PodReconciler) recordTrigger(event, reason string, triggered bool, obj client.Object) {
+	if r.TriggerAudit != nil {
+		r.TriggerAudit.Record(event, reason, triggered, obj)
+	}
+}
+
+// getPod reads a pod from the cache, with the writes still pending in PodWrites.
+func (r *PodReconciler) getPod(ctx context.Context, key client.ObjectKey, pod *corev1.Pod) error {
+	if err := r.Get(ctx, key, pod); err != nil {
+		return err
+	}
+	if r.PodWrites != nil {
+		r.PodWrites.overlay(pod)
+	}
+	return nil
+}
+
+func (r *PodReconciler) annotationKey() string {
+	if r.AnnotationKey == "" {
+		return AnnotationKey
+	}
+	return r.AnnotationKey
+}
@@ -5,8 +5,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 )
 
@@ -74,3 +76,34 @@ func TestCreatePredicate(t *testing.T) {
 		assert.False(t, p.Delete(event.DeleteEvent{}))
 	})
 }
+
+func TestCreatePredicate_ExcludeSelector(t *testing.T) {
+	selector, err := labels.Parse("ci-runner=true")
+	require.NoError(t, err)
+	r := &PodReconciler{AnnotationKey: AnnotationKey, ExcludePodSelector: selector}
+	p := r.createPredicate()
+
+	excluded := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Labels:      map[string]string{"ci-runner": "true"},
+		Annotations: map[string]string{AnnotationKey: "val"},
+	}}
+	included := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Labels:      map[string]string{"app": "web"},
+		Annotations: map[string]string{AnnotationKey: "val"},
+	}}
+
+	assert.False(t, p.Create(event.CreateEvent{Object: excluded}))
+	assert.True(t, p.Create(event.CreateEvent{Object: included}))
+
+	// IP assignment on an excluded pod -> false
+	withIP := excluded.DeepCopy()
+	withIP.Status.PodIP = "1.2.3.4"
+	assert.False(t, p.Update(event.UpdateEvent{ObjectOld: excluded, ObjectNew: withIP}))
+
+	// Deletion with finalizer is still handled so previously applied tags are cleaned up
+	now := metav1.Now()
+	deleting := excluded.DeepCopy()
+	deleting.Finalizers = []string{finalizerName}
+	deleting.DeletionTimestamp = &now
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: excluded, ObjectNew: deleting}))
+}
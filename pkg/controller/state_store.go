@@ -0,0 +1,316 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// StateConfigMapName is the ConfigMap holding pod state with a StateStore.
+const StateConfigMapName = "eni-tagger-state"
+
+// podState is what a StateStore keeps per pod in place of the last-applied
+// annotations.
+type podState struct {
+	UID types.UID `json:"uid"`
+	// IP locates the ENI for cleanup once the pod is gone.
+	IP string `json:"ip"`
+	// Tags is the last-applied tags JSON, as in the annotation.
+	Tags string `json:"tags"`
+	Hash string `json:"hash"`
+	// Pending is the pending transition JSON, as in the annotation.
+	Pending string `json:"pending,omitempty"`
+}
+
+// StateStore keeps each pod's last applied tags and hash in a ConfigMap in the
+// controller's namespace instead of in pod annotations, and replaces finalizers
+// with pod delete events, so the controller needs no update or patch permission
+// on pods.
+//
+// The trade-off: a pod deleted while the controller is down is only cleaned up
+// at the next start, when every stored pod is requeued; an ENI reused by a new
+// pod in the meantime keeps the old tags unless the hash check lets them be
+// removed. The ConfigMap is limited to 1 MiB, a few thousand pods.
+type StateStore struct {
+	client client.Client
+	// reader loads the ConfigMap without requiring a cluster-wide ConfigMap watch.
+	reader    client.Reader
+	configMap types.NamespacedName
+
+	mu     sync.Mutex
+	loaded bool
+	states map[types.NamespacedName]podState
+
+	// events requeues every stored pod at startup.
+	events chan event.GenericEvent
+}
+
+// NewStateStore returns a store kept in the StateConfigMapName ConfigMap in namespace.
+func NewStateStore(c client.Client, reader client.Reader, namespace string) *StateStore {
+	return &StateStore{
+		client:    c,
+		reader:    reader,
+		configMap: types.NamespacedName{Namespace: namespace, Name: StateConfigMapName},
+		states:    make(map[types.NamespacedName]podState),
+		events:    make(chan event.GenericEvent, 100),
+	}
+}
+
+// stateKey is the ConfigMap data key for pod. Namespaces cannot contain dots,
+// so the first dot separates namespace and name.
+func stateKey(pod types.NamespacedName) string {
+	return pod.Namespace + "." + pod.Name
+}
+
+func parseStateKey(key string) (types.NamespacedName, bool) {
+	namespace, name, ok := strings.Cut(key, ".")
+	return types.NamespacedName{Namespace: namespace, Name: name}, ok && namespace != "" && name != ""
+}
+
+// loadLocked reads the ConfigMap once; failures are retried on the next call.
+// s.mu must be held.
+func (s *StateStore) loadLocked(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+	cm := &corev1.ConfigMap{}
+	if err := s.reader.Get(ctx, s.configMap, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to load pod state ConfigMap %s: %w", s.configMap, err)
+		}
+	}
+	for key, data := range cm.Data {
+		pod, ok := parseStateKey(key)
+		var state podState
+		if !ok || json.Unmarshal([]byte(data), &state) != nil {
+			log.FromContext(ctx).Info("Ignoring corrupt pod state entry", "configMap", s.configMap, "key", key)
+			continue
+		}
+		s.states[pod] = state
+	}
+	s.loaded = true
+	return nil
+}
+
+// get returns the stored state for pod.
+func (s *StateStore) get(ctx context.Context, pod types.NamespacedName) (podState, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(ctx); err != nil {
+		return podState{}, false, err
+	}
+	state, ok := s.states[pod]
+	return state, ok, nil
+}
+
+// has reports whether state may be stored for pod, without loading the store:
+// before the first load every pod may have state.
+func (s *StateStore) has(pod types.NamespacedName) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.states[pod]
+	return ok || !s.loaded
+}
+
+// put stores state for pod, writing only if it changed.
+func (s *StateStore) put(ctx context.Context, pod types.NamespacedName, state podState) error {
+	s.mu.Lock()
+	if err := s.loadLocked(ctx); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if old, ok := s.states[pod]; ok && old == state {
+		s.mu.Unlock()
+		return nil
+	}
+	s.states[pod] = state
+	s.mu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	value := string(data)
+	return s.write(ctx, pod, &value)
+}
+
+// setPending records a pending transition in the state stored for pod. Pods
+// without stored state have nothing to record it against and are skipped.
+func (s *StateStore) setPending(ctx context.Context, pod types.NamespacedName, pending string) error {
+	state, ok, err := s.get(ctx, pod)
+	if err != nil || !ok {
+		return err
+	}
+	state.Pending = pending
+	return s.put(ctx, pod, state)
+}
+
+// remove deletes the state stored for pod.
+func (s *StateStore) remove(ctx context.Context, pod types.NamespacedName) error {
+	s.mu.Lock()
+	if err := s.loadLocked(ctx); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	if _, ok := s.states[pod]; !ok {
+		s.mu.Unlock()
+		return nil
+	}
+	delete(s.states, pod)
+	s.mu.Unlock()
+	return s.write(ctx, pod, nil)
+}
+
+// write sets (or with a nil value deletes) one ConfigMap key with a merge patch,
+// so concurrent writes for different pods never conflict. The ConfigMap is
+// created on first use.
+func (s *StateStore) write(ctx context.Context, pod types.NamespacedName, value *string) error {
+	patch, err := json.Marshal(map[string]any{"data": map[string]*string{stateKey(pod): value}})
+	if err != nil {
+		return err
+	}
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: s.configMap.Namespace, Name: s.configMap.Name}}
+	err = s.client.Patch(ctx, cm, client.RawPatch(types.MergePatchType, patch))
+	if apierrors.IsNotFound(err) {
+		if value == nil {
+			return nil
+		}
+		cm.Data = map[string]string{stateKey(pod): *value}
+		err = s.client.Create(ctx, cm)
+		if apierrors.IsAlreadyExists(err) {
+			// Created concurrently for another pod
+			err = s.client.Patch(ctx, cm, client.RawPatch(types.MergePatchType, patch))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update pod state ConfigMap %s: %w", s.configMap, err)
+	}
+	return nil
+}
+
+// Start loads the stored state and requeues every stored pod, so pods deleted
+// while the controller was down are cleaned up. It implements manager.Runnable.
+func (s *StateStore) Start(ctx context.Context) error {
+	s.mu.Lock()
+	err := s.loadLocked(ctx)
+	pods := make([]types.NamespacedName, 0, len(s.states))
+	for pod := range s.states {
+		pods = append(pods, pod)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range pods {
+		select {
+		case s.events <- event.GenericEvent{Object: &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: pod.Namespace, Name: pod.Name}}}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	<-ctx.Done()
+	return nil
+}
+
+// source returns the channel of stored pods requeued at startup.
+func (s *StateStore) source() source.Source {
+	return &source.Channel{Source: s.events}
+}
+
+// loadStoredState puts the stored last-applied state into pod's in-memory
+// annotations, where the rest of the reconcile reads it. State left by an
+// earlier pod of the same name is cleaned up first.
+func (r *PodReconciler) loadStoredState(ctx context.Context, pod *corev1.Pod) error {
+	key := client.ObjectKeyFromObject(pod)
+	state, ok, err := r.StateStore.get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if ok && state.UID != pod.UID {
+		if err := r.cleanupStoredState(ctx, key); err != nil {
+			return err
+		}
+		ok = false
+	}
+
+	keys := r.keys()
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	delete(pod.Annotations, keys.LastAppliedTags)
+	delete(pod.Annotations, keys.LastAppliedHash)
+	delete(pod.Annotations, keys.PendingTransition)
+	if ok {
+		pod.Annotations[keys.LastAppliedTags] = state.Tags
+		pod.Annotations[keys.LastAppliedHash] = state.Hash
+		if state.Pending != "" {
+			pod.Annotations[keys.PendingTransition] = state.Pending
+		}
+	}
+	return nil
+}
+
+// saveStoredState is updatePodAnnotations for a StateStore.
+func (r *PodReconciler) saveStoredState(ctx context.Context, pod *corev1.Pod, currentTags map[string]string, lastApplied, desiredHash string) error {
+	keys := r.keys()
+	delete(pod.Annotations, keys.PendingTransition)
+	if len(currentTags) == 0 {
+		delete(pod.Annotations, keys.LastAppliedTags)
+		delete(pod.Annotations, keys.LastAppliedHash)
+		return r.StateStore.remove(ctx, client.ObjectKeyFromObject(pod))
+	}
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[keys.LastAppliedTags] = lastApplied
+	pod.Annotations[keys.LastAppliedHash] = desiredHash
+	return r.StateStore.put(ctx, client.ObjectKeyFromObject(pod), podState{
+		UID:  pod.UID,
+		IP:   pod.Status.PodIP,
+		Tags: lastApplied,
+		Hash: desiredHash,
+	})
+}
+
+// cleanupStoredState removes the tags recorded for a pod that no longer exists
+// and forgets it, as handlePodDeletion does for pods with a finalizer.
+func (r *PodReconciler) cleanupStoredState(ctx context.Context, key types.NamespacedName) error {
+	logger := log.FromContext(ctx)
+	state, ok, err := r.StateStore.get(ctx, key)
+	if err != nil || !ok {
+		return err
+	}
+
+	pending, err := parsePendingTransition(state.Pending)
+	if err != nil {
+		logger.Error(err, "Failed to parse stored pending transition, ignoring it")
+	}
+	if lastAppliedTags, err := parseLastApplied(state.Tags); err != nil {
+		logger.Error(err, "Failed to parse stored last applied tags, skipping cleanup")
+	} else if (len(lastAppliedTags) > 0 || pending != nil) && state.IP != "" {
+		eniInfo, err := r.AWSClient.GetENIInfoByIP(ctx, state.IP)
+		if err != nil {
+			logger.Error(err, "Failed to get ENI for cleanup, forgetting pod state")
+		} else {
+			tags, hash := pending.cleanupState(lastAppliedTags, state.Hash, eniInfo.Tags[r.keys().HashTag])
+			r.cleanupTagsForPod(ctx, logger, eniInfo, tags, hash)
+		}
+	}
+
+	if r.ENICache != nil && state.IP != "" {
+		r.ENICache.Invalidate(ctx, state.IP, string(state.UID))
+	}
+	return r.StateStore.remove(ctx, key)
+}
@@ -0,0 +1,132 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestStateStore_RoundTrip(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	ctx := context.Background()
+
+	pod := types.NamespacedName{Namespace: "default", Name: "web.v2"}
+	state := podState{UID: "uid-1", IP: "10.0.0.1", Tags: `{"team":"a"}`, Hash: "h1"}
+	store := NewStateStore(k8sClient, k8sClient, "kube-system")
+	require.NoError(t, store.put(ctx, pod, state))
+	require.NoError(t, store.put(ctx, types.NamespacedName{Namespace: "other", Name: "db"}, state))
+
+	// A fresh store, e.g. after a restart, reads the same state
+	reloaded := NewStateStore(k8sClient, k8sClient, "kube-system")
+	got, ok, err := reloaded.get(ctx, pod)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, state, got)
+
+	require.NoError(t, reloaded.remove(ctx, pod))
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Namespace: "kube-system", Name: StateConfigMapName}, cm))
+	assert.NotContains(t, cm.Data, "default.web.v2")
+	assert.Contains(t, cm.Data, "other.db")
+}
+
+func TestReconcile_StateStoreDoesNotWritePod(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod-1",
+			Namespace:   "default",
+			UID:         "uid-1",
+			Annotations: map[string]string{AnnotationKey: "team=platform"},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.1").Return(&aws.ENIInfo{ID: "eni-1"}, nil)
+	mockAWS.On("TagENI", mock.Anything, "eni-1", mock.Anything).Return(nil).Once()
+
+	store := NewStateStore(k8sClient, k8sClient, "kube-system")
+	r := &PodReconciler{
+		Client:          k8sClient,
+		Scheme:          scheme,
+		Recorder:        record.NewFakeRecorder(10),
+		AWSClient:       mockAWS,
+		AnnotationKey:   AnnotationKey,
+		StateStore:      store,
+		PodRateLimiters: &sync.Map{},
+	}
+	ctx := context.Background()
+	req := reconcile.Request{NamespacedName: client.ObjectKeyFromObject(pod)}
+	_, err := r.Reconcile(ctx, req)
+	require.NoError(t, err)
+
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, req.NamespacedName, stored))
+	assert.Empty(t, stored.Finalizers)
+	assert.NotContains(t, stored.Annotations, LastAppliedAnnotationKey)
+	assert.True(t, isConditionTrue(stored.Status.Conditions, ConditionTypeEniTagged))
+
+	state, ok, err := store.get(ctx, req.NamespacedName)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, `{"team":"platform"}`, state.Tags)
+	assert.Equal(t, "10.0.0.1", state.IP)
+
+	// The stored state is used on the next reconcile, so nothing is retagged
+	_, err = r.Reconcile(ctx, req)
+	require.NoError(t, err)
+	mockAWS.AssertExpectations(t)
+}
+
+func TestReconcile_StateStoreCleansUpDeletedPod(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	ctx := context.Background()
+
+	key := types.NamespacedName{Namespace: "default", Name: "pod-gone"}
+	store := NewStateStore(k8sClient, k8sClient, "kube-system")
+	require.NoError(t, store.put(ctx, key, podState{UID: "uid-1", IP: "10.0.0.1", Tags: `{"team":"platform"}`, Hash: "h1"}))
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.1").Return(&aws.ENIInfo{ID: "eni-1", Tags: map[string]string{"team": "platform", HashTagKey: "h1"}}, nil)
+	mockAWS.On("UntagENI", mock.Anything, "eni-1", mock.MatchedBy(func(keys []string) bool {
+		return assert.ElementsMatch(t, []string{"team", HashTagKey}, keys)
+	})).Return(nil)
+
+	r := &PodReconciler{
+		Client:          k8sClient,
+		Scheme:          scheme,
+		Recorder:        record.NewFakeRecorder(10),
+		AWSClient:       mockAWS,
+		StateStore:      store,
+		PodRateLimiters: &sync.Map{},
+	}
+	_, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+	require.NoError(t, err)
+	mockAWS.AssertExpectations(t)
+
+	_, ok, err := store.get(ctx, key)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, store.has(key))
+}
@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"sync"
+
+	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/metrics"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// unknownZone labels ENIs whose availability zone is not known.
+const unknownZone = "unknown"
+
+// TaggingStats keeps k8s_eni_tagger_tagged_enis current: the ENIs of pods whose
+// tags are in place, counted once however many pods share them, by availability
+// zone and subnet. It shows how tagged capacity, and so cost, is spread across
+// zones. The counts are rebuilt by the reconciles after a restart.
+//
+// It also exports k8s_eni_tagger_eni_tag_headroom for each counted ENI, and
+// drops the series once no pod refers to the ENI.
+type TaggingStats struct {
+	mu sync.Mutex
+	// pods maps each counted pod to its ENI ID.
+	pods map[types.NamespacedName]string
+	enis map[string]*taggedENI
+	// tagCounts holds the number of tags last seen on each ENI.
+	tagCounts map[string]int
+}
+
+// taggedENI is the location of a counted ENI and how many pods refer to it.
+type taggedENI struct {
+	zone, subnet string
+	pods         int
+}
+
+// NewTaggingStats returns empty tagging statistics.
+func NewTaggingStats() *TaggingStats {
+	return &TaggingStats{
+		pods:      make(map[types.NamespacedName]string),
+		enis:      make(map[string]*taggedENI),
+		tagCounts: make(map[string]int),
+	}
+}
+
+// record counts the ENI of a pod whose tags are in place, moving the pod off
+// the ENI it was counted with before, if another.
+func (s *TaggingStats) record(pod types.NamespacedName, info *aws.ENIInfo) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pods[pod] == info.ID {
+		return
+	}
+	s.releaseLocked(pod)
+
+	eni, ok := s.enis[info.ID]
+	if !ok {
+		zone := info.AvailabilityZone
+		if zone == "" {
+			zone = unknownZone
+		}
+		eni = &taggedENI{zone: zone, subnet: info.SubnetID}
+		s.enis[info.ID] = eni
+		metrics.TaggedENIs.WithLabelValues(eni.zone, eni.subnet).Inc()
+		if n, ok := s.tagCounts[info.ID]; ok {
+			metrics.ENITagHeadroom.WithLabelValues(info.ID).Set(float64(MaxTagsPerENI - n))
+		}
+	}
+	eni.pods++
+	s.pods[pod] = info.ID
+}
+
+// observeTags records the number of tags on an ENI after a reconcile. The
+// headroom gauge is exported once a pod is counted with the ENI.
+func (s *TaggingStats) observeTags(eniID string, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tagCounts[eniID] = count
+	if _, ok := s.enis[eniID]; ok {
+		metrics.ENITagHeadroom.WithLabelValues(eniID).Set(float64(MaxTagsPerENI - count))
+	}
+}
+
+// forget stops counting the pod, e.g. once it is deleted or its annotation removed.
+func (s *TaggingStats) forget(pod types.NamespacedName) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.releaseLocked(pod)
+}
+
+// releaseLocked drops pod from the pods of its ENI, uncounting the ENI once no
+// pod refers to it. s.mu must be held.
+func (s *TaggingStats) releaseLocked(pod types.NamespacedName) {
+	id, ok := s.pods[pod]
+	if !ok {
+		return
+	}
+	delete(s.pods, pod)
+	eni := s.enis[id]
+	eni.pods--
+	if eni.pods == 0 {
+		delete(s.enis, id)
+		delete(s.tagCounts, id)
+		metrics.TaggedENIs.WithLabelValues(eni.zone, eni.subnet).Dec()
+		metrics.ENITagHeadroom.DeleteLabelValues(id)
+	}
+}
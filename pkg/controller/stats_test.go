@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+	"k8s-eni-tagger/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestTaggingStats(t *testing.T) {
+	metrics.TaggedENIs.Reset()
+	s := NewTaggingStats()
+	zoneA := &aws.ENIInfo{ID: "eni-shared", SubnetID: "subnet-a", AvailabilityZone: "us-east-1a"}
+	zoneB := &aws.ENIInfo{ID: "eni-b", SubnetID: "subnet-b", AvailabilityZone: "us-east-1b"}
+	legacy := &aws.ENIInfo{ID: "eni-legacy", SubnetID: "subnet-a"}
+	gauge := func(zone, subnet string) float64 {
+		return testutil.ToFloat64(metrics.TaggedENIs.WithLabelValues(zone, subnet))
+	}
+	pod := func(name string) types.NamespacedName {
+		return types.NamespacedName{Namespace: "default", Name: name}
+	}
+
+	// Pods sharing an ENI count it once; repeated reconciles change nothing
+	s.record(pod("a1"), zoneA)
+	s.record(pod("a2"), zoneA)
+	s.record(pod("a2"), zoneA)
+	s.record(pod("b"), zoneB)
+	s.record(pod("old"), legacy)
+	assert.Equal(t, 1.0, gauge("us-east-1a", "subnet-a"))
+	assert.Equal(t, 1.0, gauge("us-east-1b", "subnet-b"))
+	assert.Equal(t, 1.0, gauge(unknownZone, "subnet-a"))
+
+	s.forget(pod("a1"))
+	assert.Equal(t, 1.0, gauge("us-east-1a", "subnet-a"))
+	s.forget(pod("a2"))
+	assert.Equal(t, 0.0, gauge("us-east-1a", "subnet-a"))
+
+	// A pod moved to another ENI is counted there only
+	s.record(pod("b"), zoneA)
+	assert.Equal(t, 0.0, gauge("us-east-1b", "subnet-b"))
+	assert.Equal(t, 1.0, gauge("us-east-1a", "subnet-a"))
+	s.forget(pod("missing"))
+}
+
+func TestTaggingStatsTagHeadroom(t *testing.T) {
+	metrics.ENITagHeadroom.Reset()
+	s := NewTaggingStats()
+	eni := &aws.ENIInfo{ID: "eni-a", SubnetID: "subnet-a", AvailabilityZone: "us-east-1a"}
+	pod := types.NamespacedName{Namespace: "default", Name: "a"}
+
+	// The headroom is exported once a pod is counted with the ENI
+	s.observeTags(eni.ID, 12)
+	assert.Zero(t, testutil.CollectAndCount(metrics.ENITagHeadroom))
+	s.record(pod, eni)
+	assert.Equal(t, 38.0, testutil.ToFloat64(metrics.ENITagHeadroom.WithLabelValues(eni.ID)))
+
+	s.observeTags(eni.ID, 48)
+	assert.Equal(t, 2.0, testutil.ToFloat64(metrics.ENITagHeadroom.WithLabelValues(eni.ID)))
+
+	// The series goes away with the last pod on the ENI
+	s.forget(pod)
+	assert.Zero(t, testutil.CollectAndCount(metrics.ENITagHeadroom))
+}
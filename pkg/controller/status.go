@@ -9,18 +9,33 @@ import (
 )
 
 // updateStatus updates the pod's ENI tagging condition status.
-// It creates or updates a pod condition of type ConditionTypeEniTagged with the given
-// status, reason, and message. The condition's LastTransitionTime is set to the current time.
-func (r *PodReconciler) updateStatus(ctx context.Context, pod *corev1.Pod, status corev1.ConditionStatus, reason, message string) error {
+// It creates or updates a pod condition of the reconciler's condition type (see Keys) with the given
+// status and reason; details are stored as JSON in the message. The condition's LastTransitionTime
+// is set to the current time.
+//
+// Most reconciles of a tagged pod end with the condition it already has, so nothing is
+// written when status, reason and message are unchanged. With PodWrites the write may
+// be collapsed with later ones.
+func (r *PodReconciler) updateStatus(ctx context.Context, pod *corev1.Pod, status corev1.ConditionStatus, reason ConditionReason, details ConditionDetails) error {
+	traceCondition(ctx, reason, details.ENIID)
+	conditionType := corev1.PodConditionType(r.keys().ConditionType)
+	message := details.String()
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type == conditionType && c.Status == status && c.Reason == string(reason) && c.Message == message {
+			return nil
+		}
+	}
+
 	// Create a patch for the status
-	patch := client.MergeFrom(pod.DeepCopy())
+	base := pod.DeepCopy()
 
 	// Helper to find and update condition
 	found := false
 	for i, c := range pod.Status.Conditions {
-		if c.Type == corev1.PodConditionType(ConditionTypeEniTagged) {
+		if c.Type == conditionType {
 			pod.Status.Conditions[i].Status = status
-			pod.Status.Conditions[i].Reason = reason
+			pod.Status.Conditions[i].Reason = string(reason)
 			pod.Status.Conditions[i].Message = message
 			pod.Status.Conditions[i].LastTransitionTime = metav1.Now()
 			found = true
@@ -30,15 +45,18 @@ func (r *PodReconciler) updateStatus(ctx context.Context, pod *corev1.Pod, statu
 
 	if !found {
 		pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{
-			Type:               corev1.PodConditionType(ConditionTypeEniTagged),
+			Type:               conditionType,
 			Status:             status,
-			Reason:             reason,
+			Reason:             string(reason),
 			Message:            message,
 			LastTransitionTime: metav1.Now(),
 		})
 	}
 
-	return r.Status().Patch(ctx, pod, patch)
+	if r.PodWrites != nil {
+		return r.PodWrites.patchStatus(ctx, base, pod)
+	}
+	return r.Status().Patch(ctx, pod, client.StrategicMergeFrom(base))
 }
 
 // isConditionTrue checks if a pod condition of the given type exists and has status True.
@@ -21,8 +21,8 @@ func TestUpdateStatus(t *testing.T) {
 		name         string
 		initialPod   *corev1.Pod
 		status       corev1.ConditionStatus
-		reason       string
-		message      string
+		reason       ConditionReason
+		details      ConditionDetails
 		expectStatus corev1.ConditionStatus
 	}{
 		{
@@ -35,8 +35,8 @@ func TestUpdateStatus(t *testing.T) {
 				Status: corev1.PodStatus{},
 			},
 			status:       corev1.ConditionTrue,
-			reason:       "ENITagged",
-			message:      "Successfully tagged ENI",
+			reason:       ReasonSynced,
+			details:      ConditionDetails{Message: "Successfully tagged ENI", ENIID: "eni-1", SubnetID: "subnet-1"},
 			expectStatus: corev1.ConditionTrue,
 		},
 		{
@@ -58,8 +58,8 @@ func TestUpdateStatus(t *testing.T) {
 				},
 			},
 			status:       corev1.ConditionTrue,
-			reason:       "ENITagged",
-			message:      "Successfully tagged ENI",
+			reason:       ReasonSynced,
+			details:      ConditionDetails{Message: "Successfully tagged ENI", ENIID: "eni-1", SubnetID: "subnet-1"},
 			expectStatus: corev1.ConditionTrue,
 		},
 	}
@@ -74,7 +74,7 @@ func TestUpdateStatus(t *testing.T) {
 			}
 
 			ctx := context.Background()
-			err := r.updateStatus(ctx, tt.initialPod, tt.status, tt.reason, tt.message)
+			err := r.updateStatus(ctx, tt.initialPod, tt.status, tt.reason, tt.details)
 
 			assert.NoError(t, err)
 
@@ -83,8 +83,10 @@ func TestUpdateStatus(t *testing.T) {
 			for _, condition := range tt.initialPod.Status.Conditions {
 				if condition.Type == corev1.PodConditionType(ConditionTypeEniTagged) {
 					assert.Equal(t, tt.expectStatus, condition.Status)
-					assert.Equal(t, tt.reason, condition.Reason)
-					assert.Equal(t, tt.message, condition.Message)
+					assert.Equal(t, string(tt.reason), condition.Reason)
+					details, err := ParseConditionDetails(condition.Message)
+					assert.NoError(t, err)
+					assert.Equal(t, tt.details, details)
 					assert.NotZero(t, condition.LastTransitionTime)
 					found = true
 					break
@@ -150,3 +152,24 @@ func TestIsConditionTrue(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateStatus_SkipsUnchangedCondition(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+	r := &PodReconciler{Client: k8sClient, Scheme: scheme}
+	ctx := context.Background()
+
+	details := ConditionDetails{Message: "Successfully tagged ENI", ENIID: "eni-1"}
+	require.NoError(t, r.updateStatus(ctx, pod, corev1.ConditionTrue, ReasonSynced, details))
+	written := pod.ResourceVersion
+
+	require.NoError(t, r.updateStatus(ctx, pod, corev1.ConditionTrue, ReasonSynced, details))
+	assert.Equal(t, written, pod.ResourceVersion, "unchanged condition should not be patched")
+
+	details.ENIID = "eni-2"
+	require.NoError(t, r.updateStatus(ctx, pod, corev1.ConditionTrue, ReasonSynced, details))
+	assert.NotEqual(t, written, pod.ResourceVersion, "changed details should be patched")
+}
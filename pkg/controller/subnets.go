@@ -0,0 +1,174 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+
+const (
+	// SubnetAllowListControllerName names the controller watching the subnet allow-list ConfigMap.
+	SubnetAllowListControllerName = "subnet-allowlist"
+
+	// SubnetIDsConfigMapKey is the ConfigMap data key holding allowed subnet IDs,
+	// separated by commas or whitespace.
+	SubnetIDsConfigMapKey = "subnet-ids"
+)
+
+// SubnetAllowList is the set of subnets whose ENIs may be tagged. It can be
+// replaced at runtime; an empty list allows every subnet.
+type SubnetAllowList struct {
+	mu  sync.RWMutex
+	ids []string
+
+	// events requeues pods whose ENI was rejected by an earlier list.
+	events chan event.GenericEvent
+}
+
+// NewSubnetAllowList returns an allow-list holding ids.
+func NewSubnetAllowList(ids []string) *SubnetAllowList {
+	l := &SubnetAllowList{events: make(chan event.GenericEvent, 100)}
+	l.Set(ids)
+	return l
+}
+
+// IDs returns the allowed subnet IDs, sorted.
+func (l *SubnetAllowList) IDs() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return append([]string(nil), l.ids...)
+}
+
+// Set replaces the allowed subnet IDs and reports whether they changed.
+func (l *SubnetAllowList) Set(ids []string) bool {
+	normalized := append([]string(nil), ids...)
+	sort.Strings(normalized)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if strings.Join(normalized, ",") == strings.Join(l.ids, ",") {
+		return false
+	}
+	l.ids = normalized
+	return true
+}
+
+// source returns the channel of pods to requeue after a list change.
+func (l *SubnetAllowList) source() source.Source {
+	return &source.Channel{Source: l.events}
+}
+
+// ParseSubnetIDs parses subnet IDs separated by commas or whitespace, dropping duplicates.
+func ParseSubnetIDs(value string) ([]string, error) {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, id := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	}) {
+		if !strings.HasPrefix(id, "subnet-") {
+			return nil, fmt.Errorf("invalid subnet ID format: %s", id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// SubnetAllowListReconciler keeps a SubnetAllowList in sync with a ConfigMap. The
+// effective list is StaticIDs plus the IDs in the ConfigMap; if the ConfigMap is
+// missing only StaticIDs apply, and an invalid ConfigMap leaves the list unchanged.
+type SubnetAllowListReconciler struct {
+	client.Client
+
+	// ConfigMap is the watched ConfigMap.
+	ConfigMap types.NamespacedName
+
+	// StaticIDs are the subnets from --subnet-ids, always allowed.
+	StaticIDs []string
+
+	// AllowList is updated on every change and shared with the PodReconciler.
+	AllowList *SubnetAllowList
+
+	// ConditionType is the pod controller's condition type. When the list changes,
+	// pods whose condition reports ENIValidationFailed are reconciled again.
+	ConditionType string
+}
+
+// Reconcile reloads the allow-list from the ConfigMap.
+func (r *SubnetAllowListReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("configMap", r.ConfigMap)
+
+	ids := append([]string(nil), r.StaticIDs...)
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, r.ConfigMap, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		logger.Info("Subnet allow-list ConfigMap not found, using --subnet-ids only")
+	} else {
+		extra, err := ParseSubnetIDs(cm.Data[SubnetIDsConfigMapKey])
+		if err != nil {
+			// Retrying cannot help until the ConfigMap is edited, which triggers a new reconcile.
+			logger.Error(err, "Ignoring invalid subnet allow-list ConfigMap, keeping the current list", "subnets", r.AllowList.IDs())
+			return ctrl.Result{}, nil
+		}
+		ids = append(ids, extra...)
+	}
+
+	merged, _ := ParseSubnetIDs(strings.Join(ids, ","))
+	if !r.AllowList.Set(merged) {
+		return ctrl.Result{}, nil
+	}
+	logger.Info("Subnet allow-list updated", "subnets", r.AllowList.IDs())
+	return ctrl.Result{}, r.requeueRejectedPods(ctx)
+}
+
+// requeueRejectedPods sends pods whose ENI failed validation to the pod controller,
+// since a wider allow-list may now accept them.
+func (r *SubnetAllowListReconciler) requeueRejectedPods(ctx context.Context) error {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods); err != nil {
+		return fmt.Errorf("failed to list pods to requeue after subnet allow-list change: %w", err)
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		for _, c := range pod.Status.Conditions {
+			if string(c.Type) != r.ConditionType || c.Status != corev1.ConditionFalse || c.Reason != string(ReasonENIValidationFailed) {
+				continue
+			}
+			select {
+			case r.AllowList.events <- event.GenericEvent{Object: pod}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
+
+// SetupWithManager watches the allow-list ConfigMap.
+func (r *SubnetAllowListReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(SubnetAllowListControllerName).
+		For(&corev1.ConfigMap{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return o.GetNamespace() == r.ConfigMap.Namespace && o.GetName() == r.ConfigMap.Name
+		}))).
+		Complete(r)
+}
@@ -0,0 +1,94 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestParseSubnetIDs(t *testing.T) {
+	ids, err := ParseSubnetIDs("subnet-b, subnet-a\nsubnet-b\tsubnet-c,,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"subnet-b", "subnet-a", "subnet-c"}, ids)
+
+	ids, err = ParseSubnetIDs("")
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	_, err = ParseSubnetIDs("subnet-a,sg-123")
+	assert.EqualError(t, err, "invalid subnet ID format: sg-123")
+}
+
+func TestSubnetAllowListReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	key := types.NamespacedName{Namespace: "kube-system", Name: "eni-tagger-subnets"}
+	rejected := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "rejected", Namespace: "default"},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+			Type:   corev1.PodConditionType(ConditionTypeEniTagged),
+			Status: corev1.ConditionFalse,
+			Reason: string(ReasonENIValidationFailed),
+		}}},
+	}
+	synced := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "synced", Namespace: "default"},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{
+			Type:   corev1.PodConditionType(ConditionTypeEniTagged),
+			Status: corev1.ConditionTrue,
+			Reason: string(ReasonSynced),
+		}}},
+	}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+		Data:       map[string]string{SubnetIDsConfigMapKey: "subnet-new, subnet-static"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(rejected, synced, cm).Build()
+
+	allowList := NewSubnetAllowList([]string{"subnet-static"})
+	r := &SubnetAllowListReconciler{
+		Client:        k8sClient,
+		ConfigMap:     key,
+		StaticIDs:     []string{"subnet-static"},
+		AllowList:     allowList,
+		ConditionType: ConditionTypeEniTagged,
+	}
+	ctx := context.Background()
+
+	// The ConfigMap extends the static list and the rejected pod is requeued.
+	_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"subnet-new", "subnet-static"}, allowList.IDs())
+	require.Len(t, allowList.events, 1)
+	assert.Equal(t, "rejected", (<-allowList.events).Object.GetName())
+
+	// An invalid edit keeps the current list.
+	cm.Data[SubnetIDsConfigMapKey] = "subnet-new,vpc-123"
+	require.NoError(t, k8sClient.Update(ctx, cm))
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"subnet-new", "subnet-static"}, allowList.IDs())
+	assert.Empty(t, allowList.events)
+
+	// Deleting the ConfigMap falls back to the static list.
+	require.NoError(t, k8sClient.Delete(ctx, cm))
+	_, err = r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"subnet-static"}, allowList.IDs())
+
+	// The pod reconciler reads the current list.
+	pr := &PodReconciler{SubnetIDs: []string{"subnet-ignored"}, SubnetAllowList: allowList}
+	assert.NoError(t, pr.validateENI(ctx, &aws.ENIInfo{ID: "eni-1", SubnetID: "subnet-static"}))
+	assert.ErrorContains(t, pr.validateENI(ctx, &aws.ENIInfo{ID: "eni-1", SubnetID: "subnet-new"}), "[subnet-static]")
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 )
 
 // parseTags parses tag annotations into a map of key-value pairs.
@@ -17,47 +19,76 @@ import (
 // It validates each tag against AWS constraints:
 //   - Key length must not exceed MaxTagKeyLength (127 characters)
 //   - Value length must not exceed MaxTagValueLength (255 characters)
-//   - Keys cannot use reserved prefixes (aws:, kubernetes.io/cluster/)
+//   - Keys cannot use reserved prefixes (aws: in any case, kubernetes.io/cluster/)
 //   - Keys and values must match AWS allowed character patterns
 //   - Total number of tags must not exceed MaxTagsPerENI (50 tags)
 //
 // Returns an error if any validation fails or if the format is invalid.
 func parseTags(tagStr string) (map[string]string, error) {
+	tags, err := splitTags(tagStr)
+	if err != nil {
+		return nil, err
+	}
+	return validateParsedTags(tags)
+}
+
+// splitTags is parseTags without the checks of individual keys and values, for
+// values that are rendered before they are validated.
+func splitTags(tagStr string) (map[string]string, error) {
 	tagStr = strings.TrimSpace(tagStr)
 	if tagStr == "" {
 		return make(map[string]string), nil
 	}
 
-	// Add length check to prevent catastrophic backtracking in regex validation
-	if len(tagStr) > 10000 {
-		return nil, fmt.Errorf("annotation value too long (max 10000 chars)")
+	if err := checkAnnotationSize(tagStr, MaxTagAnnotationBytes); err != nil {
+		return nil, err
 	}
 
-	var tags map[string]string
-
-	// Try JSON format first (most common for structured data)
-	if err := json.Unmarshal([]byte(tagStr), &tags); err == nil {
-		// JSON parse succeeded
-		return validateParsedTags(tags)
+	// Try JSON format first (most common for structured data). Only an object can
+	// decode into a tag map, so anything else goes straight to the fallback.
+	if tagStr[0] == '{' {
+		if err := checkJSONDepth(tagStr, 1); err != nil {
+			return nil, err
+		}
+		var tags map[string]string
+		if err := json.Unmarshal([]byte(tagStr), &tags); err == nil {
+			if len(tags) > MaxTagsPerENI {
+				return nil, fmt.Errorf("too many tags (%d), AWS limit is %d", len(tags), MaxTagsPerENI)
+			}
+			if tags == nil {
+				tags = make(map[string]string)
+			}
+			return tags, nil
+		}
 	}
 
-	// Fallback to comma-separated format for better UX
-	tags = make(map[string]string)
-	pairs := strings.Split(tagStr, ",")
-	for _, pair := range pairs {
-		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
-		if len(kv) != 2 {
+	// Fallback to comma-separated format for better UX. Values cannot contain
+	// commas, so too many tags are rejected before building the map.
+	if n := strings.Count(tagStr, ",") + 1; n > MaxTagsPerENI {
+		return nil, fmt.Errorf("too many tags (%d), AWS limit is %d", n, MaxTagsPerENI)
+	}
+	tags := make(map[string]string, strings.Count(tagStr, ",")+1)
+	for rest, more := tagStr, true; more; {
+		var pair string
+		pair, rest, more = strings.Cut(rest, ",")
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
 			return nil, fmt.Errorf("invalid tag format: %q (expected JSON or key=value,key=value)", pair)
 		}
-		key := strings.TrimSpace(kv[0])
-		value := strings.TrimSpace(kv[1])
+		key = strings.TrimSpace(key)
 		if key == "" {
 			return nil, fmt.Errorf("empty tag key in: %q", pair)
 		}
-		tags[key] = value
+		tags[key] = strings.TrimSpace(value)
 	}
+	return tags, nil
+}
 
-	return validateParsedTags(tags)
+// ParseTagAnnotation parses a tag annotation value in either format without
+// checking its keys and values, for code that edits annotations outside the
+// reconciler. TagAnnotationValidator checks the result.
+func ParseTagAnnotation(value string) (map[string]string, error) {
+	return splitTags(value)
 }
 
 // validateParsedTags validates a map of tags against AWS constraints.
@@ -86,18 +117,18 @@ func validateParsedTags(tags map[string]string) (map[string]string, error) {
 
 		// Reserved prefixes
 		for _, prefix := range reservedPrefixes {
-			if strings.HasPrefix(key, prefix) {
+			if len(key) >= len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
 				return nil, fmt.Errorf("tag key cannot start with reserved prefix %q: %q", prefix, key)
 			}
 		}
 
-		// Key pattern
-		if !tagKeyPattern.MatchString(key) {
+		// Key characters
+		if !validTagChars(key) {
 			return nil, fmt.Errorf("invalid tag key format: %q", key)
 		}
 
-		// Value pattern
-		if !tagValuePattern.MatchString(value) {
+		// Value characters
+		if !validTagChars(value) {
 			return nil, fmt.Errorf("invalid tag value format: %q", value)
 		}
 	}
@@ -105,6 +136,54 @@ func validateParsedTags(tags map[string]string) (map[string]string, error) {
 	return tags, nil
 }
 
+// validTagChars reports whether s only uses characters AWS allows in tag keys
+// and values. Lengths are checked separately.
+func validTagChars(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !tagCharAllowed[s[i]] {
+			return false
+		}
+	}
+	return true
+}
+
+// renameTagKeys replaces annotation keys with their configured tag keys, e.g.
+// "team" with "CostTeam". Keys without a rename are kept. Two keys that end up
+// with the same name (a renamed key and its target, or two keys renamed to one
+// target) are rejected rather than one value silently winning.
+func renameTagKeys(tags, renames map[string]string) (map[string]string, error) {
+	if len(renames) == 0 {
+		return tags, nil
+	}
+
+	renamed := make(map[string]string, len(tags))
+	source := make(map[string]string, len(tags))
+	for key, value := range tags {
+		target := key
+		if to, ok := renames[key]; ok {
+			target = to
+		}
+		if other, dup := source[target]; dup {
+			a, b := min(key, other), max(key, other)
+			return nil, fmt.Errorf("tag keys %q and %q both map to %q", a, b, target)
+		}
+		source[target] = key
+		renamed[target] = value
+	}
+	return renamed, nil
+}
+
+// ValidateTagKeyRenames checks that every rename target is a valid tag key, so
+// a bad rename fails startup instead of every pod that uses it.
+func ValidateTagKeyRenames(renames map[string]string) error {
+	targets := make(map[string]string, len(renames))
+	for _, to := range renames {
+		targets[to] = ""
+	}
+	_, err := validateParsedTags(targets)
+	return err
+}
+
 // applyNamespace applies a namespace prefix to all tag keys.
 // The namespace comes from either the --tag-namespace flag or the pod's Kubernetes namespace.
 // For example, with namespace "acme-corp", the tag "CostCenter=1234" becomes "acme-corp:CostCenter=1234".
@@ -131,20 +210,159 @@ func applyNamespace(tags map[string]string, namespace string) (map[string]string
 // This ensures the same set of tags always produces the same hash value.
 // The hash is used to detect conflicts when multiple controllers manage the same ENI.
 // The hash is truncated to 16 characters (64 bits) which is sufficient for collision detection.
+//
+// It runs on every reconcile, so the sorted keys and hashed bytes are built in
+// pooled buffers and only the returned string is allocated.
 func computeHash(tags map[string]string) string {
-	keys := make([]string, 0, len(tags))
+	s := hashScratchPool.Get().(*hashScratch)
+	defer func() {
+		clear(s.keys) // don't keep tag keys alive from the pool
+		hashScratchPool.Put(s)
+	}()
+
+	s.keys = s.keys[:0]
+	for k := range tags {
+		s.keys = append(s.keys, k)
+	}
+	slices.Sort(s.keys)
+
+	s.buf = s.buf[:0]
+	for _, k := range s.keys {
+		s.buf = append(s.buf, k...)
+		s.buf = append(s.buf, '=')
+		s.buf = append(s.buf, tags[k]...)
+		s.buf = append(s.buf, ',')
+	}
+	sum := sha256.Sum256(s.buf)
+
+	// 64-bit entropy is sufficient for conflict detection
+	var out [16]byte
+	hex.Encode(out[:], sum[:8])
+	return string(out[:])
+}
+
+// hashScratch holds computeHash's reusable buffers.
+type hashScratch struct {
+	keys []string
+	buf  []byte
+}
+
+var hashScratchPool = sync.Pool{New: func() any { return new(hashScratch) }}
+
+// tagKeyCaseConflictError reports desired tag keys that differ only by case from
+// each other or from a tag already on the ENI.
+type tagKeyCaseConflictError struct {
+	msg string
+}
+
+func (e *tagKeyCaseConflictError) Error() string {
+	return e.msg
+}
+
+// caseFoldedGroups returns the keys of tags that differ only by case, grouped by
+// their lower-case form. Groups and the spellings within them are sorted.
+func caseFoldedGroups(tags map[string]string) [][]string {
+	byFold := make(map[string][]string, len(tags))
 	for k := range tags {
+		fold := strings.ToLower(k)
+		byFold[fold] = append(byFold[fold], k)
+	}
+	var groups [][]string
+	for _, spellings := range byFold {
+		if len(spellings) > 1 {
+			sort.Strings(spellings)
+			groups = append(groups, spellings)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i][0]) < strings.ToLower(groups[j][0])
+	})
+	return groups
+}
+
+// resolveKeyCaseConflicts applies policy to keys in tags that differ only by case.
+// Normalize keeps the first spelling in sort order; the folded keys must agree on
+// the value, since picking one silently would drop the other.
+func resolveKeyCaseConflicts(tags map[string]string, policy TagKeyCasePolicy) (map[string]string, error) {
+	if policy != TagKeyCaseReject && policy != TagKeyCaseNormalize {
+		return tags, nil
+	}
+	groups := caseFoldedGroups(tags)
+	if len(groups) == 0 {
+		return tags, nil
+	}
+
+	if policy == TagKeyCaseReject {
+		described := make([]string, 0, len(groups))
+		for _, spellings := range groups {
+			described = append(described, fmt.Sprintf("%q", spellings))
+		}
+		return nil, &tagKeyCaseConflictError{msg: fmt.Sprintf("tag keys differ only by case: %s", strings.Join(described, ", "))}
+	}
+
+	normalized := make(map[string]string, len(tags))
+	for k, v := range tags {
+		normalized[k] = v
+	}
+	for _, spellings := range groups {
+		canonical := spellings[0]
+		for _, k := range spellings[1:] {
+			if tags[k] != tags[canonical] {
+				return nil, &tagKeyCaseConflictError{msg: fmt.Sprintf("tag keys %q and %q differ only by case but have different values", canonical, k)}
+			}
+			delete(normalized, k)
+		}
+	}
+	return normalized, nil
+}
+
+// alignKeyCaseWithENI applies policy to desired keys that differ only by case
+// from a tag on the ENI that this controller does not manage (managed keys are
+// the desired and last applied tags and the bookkeeping tags). Normalize adopts
+// the ENI's spelling so the ENI does not end up with both.
+func alignKeyCaseWithENI(desired, eniTags map[string]string, managed func(string) bool, policy TagKeyCasePolicy) (map[string]string, error) {
+	if policy != TagKeyCaseReject && policy != TagKeyCaseNormalize {
+		return desired, nil
+	}
+
+	eniByFold := make(map[string]string, len(eniTags))
+	for k := range eniTags {
+		if _, ok := desired[k]; ok || managed(k) {
+			continue
+		}
+		eniByFold[strings.ToLower(k)] = k
+	}
+
+	keys := make([]string, 0, len(desired))
+	for k := range desired {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 
-	h := sha256.New()
+	aligned := make(map[string]string, len(desired))
 	for _, k := range keys {
-		h.Write([]byte(k))
-		h.Write([]byte("="))
-		h.Write([]byte(tags[k]))
-		h.Write([]byte(","))
+		existing, clash := eniByFold[strings.ToLower(k)]
+		if !clash {
+			aligned[k] = desired[k]
+			continue
+		}
+		if policy == TagKeyCaseReject {
+			return nil, &tagKeyCaseConflictError{msg: fmt.Sprintf("tag key %q differs only by case from existing ENI tag %q", k, existing)}
+		}
+		aligned[existing] = desired[k]
+	}
+	return aligned, nil
+}
+
+// sameKeys reports whether a and b have the same key set.
+func sameKeys(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			return false
+		}
 	}
-	fullHash := hex.EncodeToString(h.Sum(nil))
-	return fullHash[:16] // 64-bit entropy is sufficient for conflict detection
+	return true
 }
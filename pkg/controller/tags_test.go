@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestComputeHash_Stable(t *testing.T) {
+	// Hashes are stored on ENIs and in pod annotations, so they must never change
+	// for the same tags: sha256("CostCenter=1234,Team=Platform,")[:16].
+	assert.Equal(t, "adee2f3e0055a9f5", computeHash(map[string]string{"Team": "Platform", "CostCenter": "1234"}))
+	assert.Equal(t, "e3b0c44298fc1c14", computeHash(nil))
+}
+
+func TestParseTags_Formats(t *testing.T) {
+	want := map[string]string{"CostCenter": "1234", "Team": "Platform"}
+	for _, value := range []string{
+		`{"CostCenter":"1234","Team":"Platform"}`,
+		"CostCenter=1234,Team=Platform",
+		" CostCenter = 1234 , Team=Platform ",
+	} {
+		got, err := parseTags(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	for _, value := range []string{"Team=Platform,", "Team", "=x", `{"Team":1}`, "null", "Team=Pl@tform!"} {
+		_, err := parseTags(value)
+		assert.Error(t, err, value)
+	}
+}
+
+// benchTags returns n tags shaped like real cost-allocation tags.
+func benchTags(n int) map[string]string {
+	tags := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		tags[fmt.Sprintf("CostAllocation-%02d", i)] = fmt.Sprintf("team-platform-%d", i)
+	}
+	return tags
+}
+
+func BenchmarkComputeHash(b *testing.B) {
+	for _, n := range []int{3, 10, 50} {
+		tags := benchTags(n)
+		b.Run(fmt.Sprintf("tags=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				computeHash(tags)
+			}
+		})
+	}
+}
+
+func BenchmarkParseTags(b *testing.B) {
+	tags := benchTags(10)
+	jsonValue, _ := json.Marshal(tags)
+	csvValue := ""
+	for k, v := range tags {
+		if csvValue != "" {
+			csvValue += ","
+		}
+		csvValue += k + "=" + v
+	}
+	for name, value := range map[string]string{"json": string(jsonValue), "csv": csvValue} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := parseTags(value); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkParseAndCompareTags(b *testing.B) {
+	current := benchTags(10)
+	last := benchTags(8)
+	last["Retired"] = "yes"
+	currentValue, _ := json.Marshal(current)
+	lastValue, _ := json.Marshal(last)
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "bench", Namespace: "default"}}
+	r := &PodReconciler{}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, _, _, err := r.parseAndCompareTags(ctx, pod, string(currentValue), string(lastValue)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
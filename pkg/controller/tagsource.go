@@ -0,0 +1,14 @@
+package controller
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// TagSource supplies tags for a pod from outside its annotations, such as an
+// organization's central tagging service. An error fails the reconcile and is
+// retried like an AWS error.
+type TagSource interface {
+	Tags(ctx context.Context, pod *corev1.Pod) (map[string]string, error)
+}
@@ -0,0 +1,168 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"text/template"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// TagValueTemplateMode decides whether tag values in the annotation are rendered
+// as Go templates, e.g. {{ .Pod.Namespace }}, and what they can refer to.
+type TagValueTemplateMode string
+
+const (
+	// TagValueTemplatesNone uses tag values as written (the default).
+	TagValueTemplatesNone TagValueTemplateMode = "none"
+	// TagValueTemplatesPod renders values against the pod: .Pod.Name,
+	// .Pod.Namespace, .Pod.UID, .Pod.ServiceAccountName, .Pod.Labels and
+	// .Node.Name, the node the pod is scheduled on.
+	TagValueTemplatesPod TagValueTemplateMode = "pod"
+	// TagValueTemplatesNode adds .Node.Labels, read from the pod's Node, which
+	// needs get, list and watch on nodes.
+	TagValueTemplatesNode TagValueTemplateMode = "node"
+)
+
+// tagTemplateData is what tag value templates are rendered against.
+type tagTemplateData struct {
+	Pod  tagTemplatePod
+	Node tagTemplateNode
+}
+
+type tagTemplatePod struct {
+	Name               string
+	Namespace          string
+	UID                types.UID
+	ServiceAccountName string
+	Labels             map[string]string
+}
+
+type tagTemplateNode struct {
+	Name   string
+	Labels map[string]string
+}
+
+// templateDataError is returned when the data for tag value templates cannot be
+// read. Unlike a bad template, it is not a problem with the annotation.
+type templateDataError struct {
+	err error
+}
+
+func (e *templateDataError) Error() string {
+	return fmt.Sprintf("reading tag template data: %v", e.err)
+}
+
+func (e *templateDataError) Unwrap() error { return e.err }
+
+// podTags parses the pod's tag annotation like parseTags, rendering values that
+// contain templates first when TagValueTemplates is enabled, then expanding
+// value macros.
+func (r *PodReconciler) podTags(ctx context.Context, pod *corev1.Pod, annotationValue string) (map[string]string, error) {
+	templates := r.TagValueTemplates != "" && r.TagValueTemplates != TagValueTemplatesNone
+	if !templates && !strings.Contains(annotationValue, "$") {
+		return parseTags(annotationValue)
+	}
+	tags, err := splitTags(annotationValue)
+	if err != nil {
+		return nil, err
+	}
+	if templates && hasTemplates(tags) {
+		data, err := r.tagTemplateData(ctx, pod)
+		if err != nil {
+			return nil, err
+		}
+		if tags, err = renderTagTemplates(tags, data); err != nil {
+			return nil, err
+		}
+	}
+	if tags, err = r.TagMacros.expandTags(tags, time.Now()); err != nil {
+		return nil, err
+	}
+	return validateParsedTags(tags)
+}
+
+// templateLabelsChanged reports whether the pod's labels changed while its tag
+// annotation has value templates, which may refer to them.
+func (r *PodReconciler) templateLabelsChanged(oldPod, newPod *corev1.Pod) bool {
+	if r.TagValueTemplates == "" || r.TagValueTemplates == TagValueTemplatesNone {
+		return false
+	}
+	return strings.Contains(newPod.Annotations[r.annotationKey()], "{{") && !maps.Equal(oldPod.Labels, newPod.Labels)
+}
+
+func hasTemplates(tags map[string]string) bool {
+	for _, value := range tags {
+		if strings.Contains(value, "{{") {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *PodReconciler) tagTemplateData(ctx context.Context, pod *corev1.Pod) (*tagTemplateData, error) {
+	data := &tagTemplateData{
+		Pod: tagTemplatePod{
+			Name:               pod.Name,
+			Namespace:          pod.Namespace,
+			UID:                pod.UID,
+			ServiceAccountName: pod.Spec.ServiceAccountName,
+			Labels:             maps.Clone(pod.Labels),
+		},
+		Node: tagTemplateNode{Name: pod.Spec.NodeName},
+	}
+	if r.TagValueTemplates != TagValueTemplatesNode || pod.Spec.NodeName == "" {
+		return data, nil
+	}
+	node := &corev1.Node{}
+	if err := r.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, fmt.Errorf("node %s of the pod not found for tag templates", pod.Spec.NodeName)
+		}
+		return nil, &templateDataError{err: err}
+	}
+	data.Node.Labels = maps.Clone(node.Labels)
+	return data, nil
+}
+
+// renderTagTemplates renders the values containing "{{" as Go templates. Keys are
+// never rendered. A missing map key, e.g. an absent label in .Pod.Labels.app, is
+// an error; {{ index .Pod.Labels "app" }} renders it as "" instead.
+func renderTagTemplates(tags map[string]string, data *tagTemplateData) (map[string]string, error) {
+	rendered := make(map[string]string, len(tags))
+	for key, value := range tags {
+		if !strings.Contains(value, "{{") {
+			rendered[key] = value
+			continue
+		}
+		tmpl, err := template.New(key).Option("missingkey=error").Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template in tag %q: %w", key, err)
+		}
+		var out strings.Builder
+		if err := tmpl.Execute(&limitedWriter{w: &out, n: MaxTagValueLength}, data); err != nil {
+			return nil, fmt.Errorf("rendering tag %q: %w", key, err)
+		}
+		rendered[key] = out.String()
+	}
+	return rendered, nil
+}
+
+// limitedWriter fails writes past n bytes, so a template cannot build a value
+// far beyond what a tag can hold.
+type limitedWriter struct {
+	w *strings.Builder
+	n int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.w.Len()+len(p) > l.n {
+		return 0, fmt.Errorf("rendered value exceeds %d characters", l.n)
+	}
+	return l.w.Write(p)
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPodTagsTemplates(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name:   "node-a",
+		Labels: map[string]string{"topology.kubernetes.io/zone": "us-west-2a"},
+	}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "shop", Labels: map[string]string{"app": "web"}},
+		Spec:       corev1.PodSpec{NodeName: "node-a", ServiceAccountName: "web"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(node).Build()
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		mode       TagValueTemplateMode
+		annotation string
+		want       map[string]string
+		wantErr    bool
+	}{
+		{
+			name:       "disabled",
+			mode:       TagValueTemplatesNone,
+			annotation: `{"team":"{{ .Pod.Namespace }}"}`,
+			wantErr:    true,
+		},
+		{
+			name:       "pod fields",
+			mode:       TagValueTemplatesPod,
+			annotation: `{"Workload":"{{ .Pod.Namespace }}/{{ .Pod.Name }}","App":"{{ .Pod.Labels.app }}","Node":"{{ .Node.Name }}","SA":"{{ .Pod.ServiceAccountName }}","env":"prod"}`,
+			want:       map[string]string{"Workload": "shop/web-1", "App": "web", "Node": "node-a", "SA": "web", "env": "prod"},
+		},
+		{
+			name:       "comma format",
+			mode:       TagValueTemplatesPod,
+			annotation: "team={{ .Pod.Namespace }},env=prod",
+			want:       map[string]string{"team": "shop", "env": "prod"},
+		},
+		{
+			name:       "missing label",
+			mode:       TagValueTemplatesPod,
+			annotation: `{"tier":"{{ .Pod.Labels.tier }}"}`,
+			wantErr:    true,
+		},
+		{
+			name:       "missing label with index",
+			mode:       TagValueTemplatesPod,
+			annotation: `{"tier":"{{ index .Pod.Labels \"tier\" }}"}`,
+			want:       map[string]string{"tier": ""},
+		},
+		{
+			name:       "node labels need node mode",
+			mode:       TagValueTemplatesPod,
+			annotation: `{"zone":"{{ index .Node.Labels \"topology.kubernetes.io/zone\" }}"}`,
+			want:       map[string]string{"zone": ""},
+		},
+		{
+			name:       "node labels",
+			mode:       TagValueTemplatesNode,
+			annotation: `{"zone":"{{ index .Node.Labels \"topology.kubernetes.io/zone\" }}"}`,
+			want:       map[string]string{"zone": "us-west-2a"},
+		},
+		{
+			name:       "rendered value validated",
+			mode:       TagValueTemplatesPod,
+			annotation: `{"team":"{{ \"a{b\" }}"}`,
+			wantErr:    true,
+		},
+		{
+			name:       "invalid template",
+			mode:       TagValueTemplatesPod,
+			annotation: `{"team":"{{ .Pod.Name "}`,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &PodReconciler{Client: k8sClient, TagValueTemplates: tt.mode}
+			got, err := r.podTags(ctx, pod, tt.annotation)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	// A node that cannot be found is a problem with the pod, not a transient one
+	r := &PodReconciler{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), TagValueTemplates: TagValueTemplatesNode}
+	_, err := r.podTags(ctx, pod, `{"node":"{{ .Node.Name }}"}`)
+	require.Error(t, err)
+	var dataErr *templateDataError
+	assert.NotErrorAs(t, err, &dataErr)
+}
+
+func TestTagAnnotationValidatorTemplates(t *testing.T) {
+	value := `{"team":"{{ .Pod.Labels.team }}","env":"prod"}`
+	assert.Error(t, TagAnnotationValidator{}.Validate(value), "braces are not valid tag characters")
+
+	v := TagAnnotationValidator{TagValueTemplates: TagValueTemplatesPod}
+	assert.NoError(t, v.Validate(value))
+	assert.Error(t, v.Validate(`{"aws:team":"{{ .Pod.Namespace }}"}`), "keys are checked even with templates")
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// pendingTransition records a tag change that needs both CreateTags and
+// DeleteTags. It is written to the pod before the calls and cleared with the
+// last-applied annotations after them, so a crash in between is detected on the
+// next reconcile: the ENI may then carry a hash and tags that the last-applied
+// annotations do not describe.
+type pendingTransition struct {
+	// Hashes are the hash tag values the ENI may carry, one per attempted change.
+	Hashes []string `json:"hashes"`
+	// Tags are the tags the attempted changes may have applied.
+	Tags map[string]string `json:"tags"`
+}
+
+// parsePendingTransition decodes the pending transition annotation. Empty means
+// no transition is in progress and returns nil.
+func parsePendingTransition(value string) (*pendingTransition, error) {
+	if value == "" {
+		return nil, nil
+	}
+	// An object holding the hash list and the tags object
+	if err := checkAnnotationJSON(value, maxBookkeepingAnnotationBytes, 2); err != nil {
+		return nil, err
+	}
+	pending := &pendingTransition{}
+	if err := json.Unmarshal([]byte(value), pending); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// owns reports whether an ENI hash was written by the interrupted change.
+func (p *pendingTransition) owns(eniHash string) bool {
+	return p != nil && eniHash != "" && slices.Contains(p.Hashes, eniHash)
+}
+
+// include adds the tags an interrupted change may have applied to lastApplied,
+// so they are diffed (and removed if no longer wanted) like applied tags.
+// lastApplied is modified in place; tags already in it keep their value.
+func (p *pendingTransition) include(lastApplied map[string]string) {
+	if p == nil {
+		return
+	}
+	for k, v := range p.Tags {
+		if _, ok := lastApplied[k]; !ok {
+			lastApplied[k] = v
+		}
+	}
+}
+
+// cleanupState returns the tags and hash to clean up for a deleted pod,
+// including those of an interrupted change.
+func (p *pendingTransition) cleanupState(lastApplied map[string]string, lastAppliedHash, eniHash string) (map[string]string, string) {
+	if p == nil {
+		return lastApplied, lastAppliedHash
+	}
+	tags := maps.Clone(lastApplied)
+	if tags == nil {
+		tags = make(map[string]string)
+	}
+	p.include(tags)
+	if p.owns(eniHash) {
+		return tags, eniHash
+	}
+	return tags, lastAppliedHash
+}
+
+// recordPendingTransition records that tags with hash are about to be applied,
+// keeping what an earlier interrupted change (prev) may have left on the ENI. The
+// same write adds the finalizer if the pod lacks it, so the tags are cleaned up
+// on deletion even if the annotation write after tagging never lands.
+func (r *PodReconciler) recordPendingTransition(ctx context.Context, pod *corev1.Pod, prev *pendingTransition, tags map[string]string, hash string) error {
+	pending := pendingTransition{Hashes: []string{hash}, Tags: maps.Clone(tags)}
+	if prev != nil {
+		for _, h := range prev.Hashes {
+			if !slices.Contains(pending.Hashes, h) {
+				pending.Hashes = append(pending.Hashes, h)
+			}
+		}
+		prev.include(pending.Tags)
+	}
+	data, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+
+	keys := r.keys()
+	if r.StateStore != nil {
+		if pod.Annotations == nil {
+			pod.Annotations = make(map[string]string)
+		}
+		pod.Annotations[keys.PendingTransition] = string(data)
+		return r.StateStore.setPending(ctx, client.ObjectKeyFromObject(pod), string(data))
+	}
+
+	base := pod.DeepCopy()
+	controllerutil.AddFinalizer(pod, keys.Finalizer)
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[keys.PendingTransition] = string(data)
+	if r.PodWrites != nil {
+		// The transition must be on the pod before tagging, and later writes
+		// collapsed with earlier ones must still be able to remove it
+		return r.PodWrites.patchNow(ctx, base, pod)
+	}
+	return patchIfChanged(ctx, r.Client, pod, client.StrategicMergeFrom(base))
+}
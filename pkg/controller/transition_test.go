@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestApplyENITags_RecordsPendingTransition(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	oldTags := map[string]string{"team": "a"}
+	oldHash := computeHash(oldTags)
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationKey:            "owner=b",
+				LastAppliedAnnotationKey: `{"team":"a"}`,
+				LastAppliedHashKey:       oldHash,
+			},
+			Finalizers: []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+	ctx := context.Background()
+	newHash := computeHash(map[string]string{"owner": "b"})
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("TagENI", mock.Anything, "eni-1", mock.Anything).Return(nil)
+	mockAWS.On("UntagENI", mock.Anything, "eni-1", []string{"team"}).Run(func(mock.Arguments) {
+		// The change is recorded before the second call
+		stored := &corev1.Pod{}
+		require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+		pending, err := parsePendingTransition(stored.Annotations[PendingTransitionAnnotationKey])
+		require.NoError(t, err)
+		require.NotNil(t, pending)
+		assert.Equal(t, []string{newHash}, pending.Hashes)
+		assert.Equal(t, map[string]string{"owner": "b"}, pending.Tags)
+	}).Return(nil)
+
+	r := &PodReconciler{Client: k8sClient, Scheme: scheme, Recorder: record.NewFakeRecorder(10), AWSClient: mockAWS}
+	eniInfo := &aws.ENIInfo{ID: "eni-1", Tags: map[string]string{"team": "a", HashTagKey: oldHash}}
+	require.NoError(t, r.applyENITags(ctx, pod, eniInfo, "owner=b"))
+	mockAWS.AssertExpectations(t)
+
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+	assert.NotContains(t, stored.Annotations, PendingTransitionAnnotationKey)
+	assert.Equal(t, newHash, stored.Annotations[LastAppliedHashKey])
+}
+
+func TestApplyENITags_ResumesInterruptedTransition(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	// A change from {team=a} to {owner=b} crashed after CreateTags: the ENI has
+	// both tags and the new hash, the annotations still describe {team=a}. The pod
+	// now asks for {env=c}.
+	oldHash := computeHash(map[string]string{"team": "a"})
+	interruptedHash := computeHash(map[string]string{"owner": "b"})
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-1",
+			Namespace: "default",
+			Annotations: map[string]string{
+				AnnotationKey:                  "env=c",
+				LastAppliedAnnotationKey:       `{"team":"a"}`,
+				LastAppliedHashKey:             oldHash,
+				PendingTransitionAnnotationKey: `{"hashes":["` + interruptedHash + `"],"tags":{"owner":"b"}}`,
+			},
+			Finalizers: []string{finalizerName},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.1"},
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).WithStatusSubresource(pod).Build()
+	ctx := context.Background()
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("TagENI", mock.Anything, "eni-1", mock.MatchedBy(func(tags map[string]string) bool {
+		return tags["env"] == "c" && tags[HashTagKey] == computeHash(map[string]string{"env": "c"})
+	})).Return(nil)
+	mockAWS.On("UntagENI", mock.Anything, "eni-1", mock.MatchedBy(func(keys []string) bool {
+		return assert.ElementsMatch(t, []string{"team", "owner"}, keys)
+	})).Return(nil)
+
+	r := &PodReconciler{Client: k8sClient, Scheme: scheme, Recorder: record.NewFakeRecorder(10), AWSClient: mockAWS}
+	eniInfo := &aws.ENIInfo{ID: "eni-1", Tags: map[string]string{"team": "a", "owner": "b", HashTagKey: interruptedHash}}
+	require.NoError(t, r.applyENITags(ctx, pod, eniInfo, "env=c"), "the interrupted change's hash is not a conflict")
+	mockAWS.AssertExpectations(t)
+
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+	assert.NotContains(t, stored.Annotations, PendingTransitionAnnotationKey)
+	assert.Equal(t, `{"env":"c"}`, stored.Annotations[LastAppliedAnnotationKey])
+}
+
+func TestPendingTransition_CleanupState(t *testing.T) {
+	lastApplied := map[string]string{"team": "a"}
+
+	var none *pendingTransition
+	tags, hash := none.cleanupState(lastApplied, "h-old", "h-new")
+	assert.Equal(t, lastApplied, tags)
+	assert.Equal(t, "h-old", hash)
+
+	pending := &pendingTransition{Hashes: []string{"h-new"}, Tags: map[string]string{"owner": "b"}}
+	tags, hash = pending.cleanupState(lastApplied, "h-old", "h-new")
+	assert.Equal(t, map[string]string{"team": "a", "owner": "b"}, tags)
+	assert.Equal(t, "h-new", hash, "the ENI carries the interrupted change's hash")
+	assert.Equal(t, map[string]string{"team": "a"}, lastApplied, "lastApplied is not modified")
+
+	_, hash = pending.cleanupState(lastApplied, "h-old", "h-other")
+	assert.Equal(t, "h-old", hash)
+}
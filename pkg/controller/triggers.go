@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/metrics"
+
+	"github.com/go-logr/logr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Why a pod event did or did not trigger a reconcile, as reported by the trigger audit.
+const (
+	TriggerCreated           = "created"
+	TriggerAnnotationChanged = "annotation-changed"
+	TriggerLabelsChanged     = "labels-changed" // labels mapped to tags, see TagFromLabels
+	TriggerIPAssigned        = "ip-assigned"
+	TriggerDeleting          = "deleting"
+	TriggerDeleted           = "deleted"           // pods with stored state, see StateStore
+	TriggerRequeued          = "requeued"          // generic events, e.g. after a subnet allow-list change
+	TriggerStaleBookkeeping  = "stale-bookkeeping" // unannotated pods still carrying bookkeeping
+
+	FilterNoAnnotation = "no-annotation"
+	FilterExcluded     = "excluded"
+	FilterResync       = "resync" // informer resync: the pod did not change
+	FilterUnchanged    = "unchanged"
+	FilterDeleted      = "deleted" // cleanup is driven by the finalizer, or nothing is stored
+)
+
+// triggerAuditInterval is how often the trigger audit logs its summary.
+const triggerAuditInterval = time.Minute
+
+type triggerKey struct {
+	event, reason string
+	triggered     bool
+}
+
+// TriggerAudit records why each pod event passing through the controller's event
+// filter did or did not trigger a reconcile. Triggering events are logged one by
+// one; all events are counted in k8s_eni_tagger_reconcile_triggers_total and
+// summarized in the log every minute, so noisy event sources can be found and
+// tuned before they turn into AWS calls.
+type TriggerAudit struct {
+	log    logr.Logger
+	mu     sync.Mutex
+	counts map[triggerKey]int
+}
+
+// NewTriggerAudit returns a trigger audit logging to log.
+func NewTriggerAudit(log logr.Logger) *TriggerAudit {
+	return &TriggerAudit{log: log, counts: make(map[triggerKey]int)}
+}
+
+// Record notes that an event for obj did (triggered) or did not trigger a reconcile for reason.
+func (a *TriggerAudit) Record(event, reason string, triggered bool, obj client.Object) {
+	result := "filtered"
+	if triggered {
+		result = "triggered"
+		a.log.Info("Reconcile triggered", LogKeyPod, client.ObjectKeyFromObject(obj), "event", event, "reason", reason)
+	}
+	metrics.ReconcileTriggersTotal.WithLabelValues(event, reason, result).Inc()
+
+	a.mu.Lock()
+	a.counts[triggerKey{event: event, reason: reason, triggered: triggered}]++
+	a.mu.Unlock()
+}
+
+// Start logs a summary of the events recorded in each interval until ctx is done.
+// It implements manager.Runnable.
+func (a *TriggerAudit) Start(ctx context.Context) error {
+	ticker := time.NewTicker(triggerAuditInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if summary := a.summarize(); len(summary) > 0 {
+				a.log.Info("Reconcile trigger summary", "interval", triggerAuditInterval, "events", summary)
+			}
+		}
+	}
+}
+
+// summarize returns the counts recorded since the last call, busiest first, as
+// "event/reason=count" entries (prefixed "filtered:" for events that did not
+// trigger a reconcile), and resets them.
+func (a *TriggerAudit) summarize() []string {
+	a.mu.Lock()
+	counts := a.counts
+	a.counts = make(map[triggerKey]int)
+	a.mu.Unlock()
+
+	keys := make([]triggerKey, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+
+	summary := make([]string, 0, len(keys))
+	for _, k := range keys {
+		prefix := ""
+		if !k.triggered {
+			prefix = "filtered:"
+		}
+		summary = append(summary, fmt.Sprintf("%s%s/%s=%d", prefix, k.event, k.reason, counts[k]))
+	}
+	return summary
+}
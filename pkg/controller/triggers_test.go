@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestTriggerReasons(t *testing.T) {
+	r := &PodReconciler{
+		AnnotationKey:      AnnotationKey,
+		ExcludePodSelector: labels.SelectorFromSet(labels.Set{"skip": "true"}),
+		TagFromLabels:      map[string]string{"team": "Team"},
+	}
+
+	annotated := func(rv string) *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: rv, Annotations: map[string]string{AnnotationKey: "a=b"}}}
+	}
+	withIP := func(p *corev1.Pod) *corev1.Pod {
+		p.Status.PodIP = "10.0.0.1"
+		return p
+	}
+	excluded := withIP(annotated("2"))
+	excluded.Labels = map[string]string{"skip": "true"}
+	deleting := withIP(annotated("2"))
+	deleting.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleting.Finalizers = []string{finalizerName}
+	edited := withIP(annotated("2"))
+	edited.Annotations[AnnotationKey] = "a=c"
+	labeled := func(rv, team string) *corev1.Pod {
+		return withIP(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: rv, Labels: map[string]string{"team": team, "app": rv}}})
+	}
+
+	tests := []struct {
+		name      string
+		old, new  *corev1.Pod
+		reason    string
+		triggered bool
+	}{
+		{"annotation edited", withIP(annotated("1")), edited, TriggerAnnotationChanged, true},
+		{"first IP", annotated("1"), withIP(annotated("2")), TriggerIPAssigned, true},
+		{"first IP, excluded", annotated("1"), excluded, FilterExcluded, false},
+		{"first IP, not annotated", &corev1.Pod{}, withIP(&corev1.Pod{}), FilterNoAnnotation, false},
+		{"mapped label edited", labeled("1", "a"), labeled("2", "b"), TriggerLabelsChanged, true},
+		{"other label edited", labeled("1", "a"), labeled("2", "a"), FilterUnchanged, false},
+		{"deleting", withIP(annotated("1")), deleting, TriggerDeleting, true},
+		{"resync", withIP(annotated("1")), withIP(annotated("1")), FilterResync, false},
+		{"other change", withIP(annotated("1")), withIP(annotated("2")), FilterUnchanged, false},
+		{"other change, not annotated", &corev1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "1"}}, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "2"}}, FilterNoAnnotation, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, triggered := r.updateTrigger(tt.old, tt.new)
+			assert.Equal(t, tt.reason, reason)
+			assert.Equal(t, tt.triggered, triggered)
+		})
+	}
+
+	reason, triggered := r.createTrigger(annotated("1"))
+	assert.Equal(t, TriggerCreated, reason)
+	assert.True(t, triggered)
+	reason, triggered = r.createTrigger(labeled("1", "a"))
+	assert.Equal(t, TriggerCreated, reason)
+	assert.True(t, triggered)
+	reason, triggered = r.createTrigger(&corev1.Pod{})
+	assert.Equal(t, FilterNoAnnotation, reason)
+	assert.False(t, triggered)
+	reason, triggered = r.createTrigger(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Finalizers: []string{finalizerName}}})
+	assert.Equal(t, TriggerStaleBookkeeping, reason)
+	assert.True(t, triggered)
+}
+
+func TestTriggerAudit_Summary(t *testing.T) {
+	audit := NewTriggerAudit(logr.Discard())
+	r := &PodReconciler{AnnotationKey: AnnotationKey, TriggerAudit: audit}
+	p := r.createPredicate()
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p", Namespace: "default", ResourceVersion: "1", Annotations: map[string]string{AnnotationKey: "a=b"}}}
+	assert.True(t, p.Create(event.CreateEvent{Object: pod}))
+	for i := 0; i < 3; i++ {
+		assert.False(t, p.Update(event.UpdateEvent{ObjectOld: pod, ObjectNew: pod}))
+	}
+	assert.False(t, p.Delete(event.DeleteEvent{Object: pod}))
+	assert.True(t, p.Generic(event.GenericEvent{Object: pod}))
+
+	assert.Equal(t, []string{
+		"filtered:update/resync=3",
+		"create/created=1",
+		"filtered:delete/deleted=1",
+		"generic/requeued=1",
+	}, audit.summarize())
+	assert.Empty(t, audit.summarize(), "counts reset after each summary")
+}
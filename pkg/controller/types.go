@@ -3,12 +3,15 @@ package controller
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"k8s-eni-tagger/pkg/audit"
 	"k8s-eni-tagger/pkg/aws"
 	enicache "k8s-eni-tagger/pkg/cache"
 
 	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -204,11 +207,195 @@ type PodReconciler struct {
 	AllowSharedENITagging bool
 	TagNamespace          string
 
+	// SubnetAllowList, when set, replaces SubnetIDs with a list that can change at
+	// runtime (see SubnetAllowListReconciler).
+	SubnetAllowList *SubnetAllowList
+
+	// TagKeyCase decides what happens to tag keys that differ only by case.
+	// Empty means TagKeyCaseAllow.
+	TagKeyCase TagKeyCasePolicy
+
+	// TagKeyRenames maps annotation keys to the tag keys written to the ENI
+	// (e.g. team -> CostTeam). Keys without an entry are written as given.
+	TagKeyRenames map[string]string
+
+	// TagFromLabels maps pod labels to the tag keys their values are written to
+	// (e.g. cost-center -> CostCenter). Pods with a mapped label are tagged even
+	// without the annotation; annotation tags win on conflicting keys.
+	TagFromLabels map[string]string
+
+	// TagValueTemplates decides whether annotation tag values are rendered as Go
+	// templates against the pod and its node. Empty means TagValueTemplatesNone.
+	TagValueTemplates TagValueTemplateMode
+
+	// TagMacros are the values of the $cluster, $region and $account macros in
+	// annotation tag values.
+	TagMacros TagValueMacros
+
+	// InvalidTags decides what happens to previously applied tags when the annotation
+	// becomes invalid. Empty means InvalidTagsKeep.
+	InvalidTags InvalidTagsPolicy
+
+	// DiffSource selects the state desired tags are diffed against. Empty means
+	// TagDiffSourceAnnotation.
+	DiffSource TagDiffSource
+
+	// HostNetworkENI selects the ENI tagged for hostNetwork pods. Empty means
+	// HostNetworkENIPodIP. HostNetworkENIPrimary requires PrimaryENIs.
+	HostNetworkENI HostNetworkENIMode
+	PrimaryENIs    aws.PrimaryENIFinder
+
+	// SecurityGroups, when set, also applies each pod's tags to the security groups
+	// attached to its ENI after the ENI is tagged, and removes them again with the
+	// last pod using them. Groups are owned through their own hash tag, so pods
+	// sharing a group must have the same tags. Nil disables security group tagging.
+	SecurityGroups aws.SecurityGroupTagger
+
+	// Volumes, when set, also applies each pod's tags to the EBS volumes of the
+	// instance running it, for storage cost attribution, and removes them again
+	// with the last pod on the node with the same tags. Volumes are owned through
+	// their own hash tag like security groups, so the first pod's tags win until
+	// the pods with them are gone. Nil disables volume tagging.
+	Volumes aws.VolumeTagger
+
+	// RepairUntil ends the startup repair window. Until then, last-applied and hash
+	// annotations that disagree with the ENI are rebuilt from the ENI's tags instead
+	// of being reported as hash conflicts. Zero disables repair.
+	RepairUntil time.Time
+
+	// Pause, when set and paused, stops all ENI tag changes; pods are reported
+	// with the Paused condition and reconciled again on resume.
+	Pause *PauseSwitch
+	// PauseDeletionHold is how long a deleted pod keeps its finalizer while paused,
+	// so its tags can be removed on resume. Pods held longer are released with
+	// their tags left on the ENI; zero releases them at once.
+	PauseDeletionHold time.Duration
+
+	// MaintenanceWindows restricts when drift repair and owner tag rollouts on
+	// already tagged ENIs are made. Empty means they are never deferred.
+	MaintenanceWindows MaintenanceWindows
+
+	// MutationBudget, when set, caps the EC2 calls changing tags per window. Once it
+	// is used up, drift repair, owner tag rollouts and changes touching only
+	// best-effort tags are deferred to the next window; critical changes the pod
+	// asked for are still made.
+	MutationBudget *aws.MutationBudget
+
+	// ReadOnly, when set, replaces reconciling with an audit that writes neither
+	// to AWS nor to pods and exports the tag changes pods need (see
+	// ReadOnlyAudit).
+	ReadOnly *ReadOnlyAudit
+
+	// History, when set, keeps recent reconciles with their AWS request IDs for
+	// APIHandler.
+	History *ReconcileHistory
+
+	// AuditLog records the tag changes a dry run would make. Applied changes are
+	// recorded by the AWS client. Nil records nothing.
+	AuditLog *audit.Log
+
+	// TagHistorySize is how many applied tag sets are kept in the tag history
+	// annotation, up to MaxTagHistorySize. Zero disables the history.
+	TagHistorySize int
+
+	// ControllerID identifies this installation. When set it is written to the owner
+	// tag on every tagged ENI, and ENIs owned by a different ID are left untouched.
+	// Empty disables owner tracking.
+	ControllerID string
+
+	// CriticalTagKeys are the ENI tag keys, or prefixes ending in "*", whose failure
+	// to apply sets the TaggingFailed condition. Failed changes to other tags only
+	// are logged and retried, with the BestEffortTaggingFailed reason and the
+	// condition left True. Empty makes every tag critical.
+	CriticalTagKeys []string
+
+	// ManagedByTag, when set, is written to the ManagedByTagKey tag on every tagged
+	// ENI and removed with the other tags. A pod's own managed-by tag takes
+	// precedence. Empty disables the tag.
+	ManagedByTag string
+
+	// KeyDomain is the domain used for the finalizer, condition type, hash tag
+	// and bookkeeping annotations. Empty means DefaultKeyDomain.
+	KeyDomain string
+
+	// ExcludePodSelector matches pods that are never tagged, even when annotated.
+	// A nil or empty selector excludes nothing.
+	ExcludePodSelector labels.Selector
+
+	// DebugPods decides whether pods created by kubectl debug are tagged. Empty
+	// means DebugPodsSkip.
+	DebugPods DebugPodPolicy
+
+	// FairQueue, when set, releases pod events to the workqueue round-robin by
+	// namespace so one busy namespace cannot starve the others.
+	FairQueue *FairQueue
+
+	// StateStore, when set, keeps last-applied state in a ConfigMap instead of pod
+	// annotations and cleans up on pod delete events instead of with a finalizer,
+	// so pods are never written (only their status).
+	StateStore *StateStore
+
+	// TriggerAudit, when set, records why each pod event did or did not trigger
+	// a reconcile.
+	TriggerAudit *TriggerAudit
+
+	// ENIAttachmentRequeueDelay, when positive, holds off tagging ENIs that are not
+	// yet attached and in use, since CreateTags can race with CNI setup, and
+	// retries after this delay. Zero tags regardless of attachment state.
+	ENIAttachmentRequeueDelay time.Duration
+
+	// TagSource, when set, supplies tags for annotated pods in addition to the
+	// annotation's. Annotation tags win when both set a key.
+	TagSource TagSource
+
+	// PodWrites, when set, collapses annotation and status writes to the same pod
+	// made within a short window into one patch each.
+	PodWrites *PodWriteBuffer
+
+	// DriftResync, when set, periodically checks the ENIs of tagged pods for tags
+	// changed outside the controller and reconciles drifted pods to repair them.
+	DriftResync *DriftResync
+
+	// Stats, when set, counts tagged ENIs by availability zone and subnet.
+	Stats *TaggingStats
+
+	// TagHeadroomWarning emits a TagQuotaLow Warning event when fewer tags than
+	// this can still be added to a pod's ENI. 0 disables the warning.
+	TagHeadroomWarning int
+
+	// TagBurst, when set, merges CreateTags calls for the same shared ENI made
+	// within a short delay, so pods landing on a new node together cost one call
+	// per ENI. Pod-exclusive ENIs are tagged directly.
+	TagBurst *TagBurstBuffer
+
+	// ENILocks, when set, serializes tag changes per ENI so pods sharing an ENI
+	// cannot interleave CreateTags and DeleteTags. Nil means no locking, which is
+	// safe when every pod has its own ENI.
+	ENILocks *ENILocks
+
+	// Concurrency bounds concurrent reconciles below the controller's worker count and
+	// can be adjusted at runtime. Nil means every worker reconciles.
+	Concurrency *ConcurrencyLimiter
+
 	// Per-pod rate limiters for DoS protection
 	PodRateLimiters   *sync.Map // map[string]*RateLimiterEntry
 	PodRateLimitQPS   float64   // Requests per second per pod
 	PodRateLimitBurst int       // Burst size per pod
 
+	// ReportRateLimited sets the RateLimited condition on annotated pods whose
+	// reconcile the per-pod rate limit deferred, with the next attempt time.
+	ReportRateLimited bool
+
+	// PodRateLimitersMax caps PodRateLimiters between cleanups; past it the least
+	// recently used limiters are evicted. Zero means no cap.
+	PodRateLimitersMax int
+
+	// podRateLimiterCount tracks the size of PodRateLimiters, corrected by every
+	// cleanup and eviction.
+	podRateLimiterCount atomic.Int64
+	// rateLimiterEvictMu keeps evictions from running concurrently.
+	rateLimiterEvictMu sync.Mutex
+
 	// Rate limiter cleanup configuration
 	RateLimiterCleanupThreshold time.Duration // How long before considering a limiter stale
 }
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// hasStaleBookkeeping reports whether a pod without the tag annotation still
+// carries the controller's bookkeeping: the finalizer, the last-applied or pending
+// transition annotations, or stored state. The annotation was removed, possibly
+// while the controller was down, and the pod's tags have not been cleaned up.
+func (r *PodReconciler) hasStaleBookkeeping(pod *corev1.Pod) bool {
+	keys := r.keys()
+	if controllerutil.ContainsFinalizer(pod, keys.Finalizer) {
+		return true
+	}
+	for _, key := range []string{keys.LastAppliedTags, keys.LastAppliedHash, keys.PendingTransition} {
+		if _, ok := pod.Annotations[key]; ok {
+			return true
+		}
+	}
+	return r.StateStore != nil && r.StateStore.has(client.ObjectKeyFromObject(pod))
+}
+
+// releaseUnannotatedPod removes the tags still recorded for a pod whose tag
+// annotation was removed, then strips its bookkeeping annotations and finalizer,
+// as an empty annotation would have. Each step can be retried on its own.
+func (r *PodReconciler) releaseUnannotatedPod(ctx context.Context, pod *corev1.Pod) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	if r.paused() {
+		logger.Info("Tagging paused, delaying cleanup of pod without tag annotation")
+		return ctrl.Result{RequeueAfter: pausedRequeueDelay}, nil
+	}
+	if r.StateStore != nil {
+		if err := r.loadStoredState(ctx, pod); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	logger.Info("Tag annotation removed, cleaning up ENI tags and bookkeeping")
+	if err := r.cleanupRecordedTags(ctx, logger, pod); err != nil {
+		// Unlike deletion nothing is waiting on the pod, so the cleanup is retried
+		return ctrl.Result{}, fmt.Errorf("failed to get ENI for cleanup of pod %s: %w", pod.Name, err)
+	}
+	if r.ENICache != nil && pod.Status.PodIP != "" {
+		r.ENICache.Invalidate(ctx, pod.Status.PodIP, string(pod.UID))
+	}
+
+	if err := updatePodAnnotations(ctx, r, pod, nil, computeHash(nil)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to remove bookkeeping annotations from pod %s: %w", pod.Name, err)
+	}
+	keys := r.keys()
+	if controllerutil.ContainsFinalizer(pod, keys.Finalizer) {
+		patch := client.StrategicMergeFrom(pod.DeepCopy())
+		controllerutil.RemoveFinalizer(pod, keys.Finalizer)
+		if err := r.Patch(ctx, pod, patch); err != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to remove finalizer from pod %s: %w", pod.Name, err)
+		}
+	}
+	r.Recorder.Event(pod, corev1.EventTypeNormal, "TagsRemoved", "Tag annotation removed; cleaned up owned ENI tags and bookkeeping")
+	return ctrl.Result{}, nil
+}
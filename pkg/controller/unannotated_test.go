@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcileReleasesUnannotatedPod(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+
+	hash := computeHash(map[string]string{"team": "platform"})
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod-unannotated",
+			Namespace: "default",
+			Annotations: map[string]string{
+				LastAppliedAnnotationKey: `{"team":"platform"}`,
+				LastAppliedHashKey:       hash,
+				"unrelated":              "kept",
+			},
+			Finalizers: []string{finalizerName, "example.com/other"},
+		},
+		Status: corev1.PodStatus{PodIP: "10.0.0.9"},
+	}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(pod)}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(pod).Build()
+
+	mockAWS := new(MockAWSClient)
+	mockAWS.On("GetENIInfoByIP", mock.Anything, "10.0.0.9").Return(&aws.ENIInfo{
+		ID:   "eni-unannotated",
+		Tags: map[string]string{"team": "platform", HashTagKey: hash},
+	}, nil).Once()
+	mockAWS.On("UntagENI", mock.Anything, "eni-unannotated", mock.MatchedBy(func(keys []string) bool {
+		return assert.ElementsMatch(t, []string{"team", HashTagKey}, keys)
+	})).Return(nil).Once()
+
+	r := &PodReconciler{
+		Client:        k8sClient,
+		Scheme:        scheme,
+		Recorder:      record.NewFakeRecorder(10),
+		AWSClient:     mockAWS,
+		AnnotationKey: AnnotationKey,
+	}
+	_, err := r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	mockAWS.AssertExpectations(t)
+
+	updated := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), req.NamespacedName, updated))
+	assert.Equal(t, map[string]string{"unrelated": "kept"}, updated.Annotations)
+	assert.Equal(t, []string{"example.com/other"}, updated.Finalizers)
+	assert.False(t, r.hasStaleBookkeeping(updated))
+
+	// Nothing left to clean up
+	_, err = r.Reconcile(context.Background(), req)
+	require.NoError(t, err)
+	mockAWS.AssertExpectations(t)
+}
@@ -2,6 +2,8 @@ package controller
 
 import (
 	"fmt"
+	"strings"
+	"time"
 )
 
 // validateTags validates the tag annotation value.
@@ -11,11 +13,25 @@ import (
 // - Tag keys and values meet AWS requirements
 // - No reserved prefixes are used
 // - Tag count doesn't exceed AWS limits
-func validateTags(annotationValue string) error {
+// - Keys differing only by case are acceptable under casePolicy
+// - No two keys end up with the same name after renames
+func validateTags(annotationValue string, renames map[string]string, casePolicy TagKeyCasePolicy) error {
 	tags, err := parseTags(annotationValue)
 	if err != nil {
 		return err
 	}
+	return validateTagSet(tags, renames, casePolicy)
+}
+
+// validateTagSet is validateTags for tags already parsed.
+func validateTagSet(tags map[string]string, renames map[string]string, casePolicy TagKeyCasePolicy) error {
+	tags, err := renameTagKeys(tags, renames)
+	if err != nil {
+		return err
+	}
+	if _, err := resolveKeyCaseConflicts(tags, casePolicy); err != nil {
+		return err
+	}
 
 	if len(tags) == 0 {
 		return fmt.Errorf("no tags specified")
@@ -23,3 +39,39 @@ func validateTags(annotationValue string) error {
 
 	return nil
 }
+
+// TagAnnotationValidator checks tag annotation values the way the pod reconciler
+// does before looking up the ENI, so they can be rejected at admission time. The
+// settings mean the same as on PodReconciler. Template values are rendered per
+// pod when reconciled, so only the annotation's format and keys are checked
+// for them. Value macros are expanded as at reconcile.
+type TagAnnotationValidator struct {
+	TagKeyRenames     map[string]string
+	TagKeyCase        TagKeyCasePolicy
+	TagValueTemplates TagValueTemplateMode
+	TagMacros         TagValueMacros
+}
+
+// Validate returns why annotationValue would be rejected as invalid tags.
+func (v TagAnnotationValidator) Validate(annotationValue string) error {
+	templates := v.TagValueTemplates != "" && v.TagValueTemplates != TagValueTemplatesNone
+	if !templates && !strings.Contains(annotationValue, "$") {
+		return validateTags(annotationValue, v.TagKeyRenames, v.TagKeyCase)
+	}
+	tags, err := splitTags(annotationValue)
+	if err != nil {
+		return err
+	}
+	for key, value := range tags {
+		if templates && strings.Contains(value, "{{") {
+			tags[key] = ""
+		}
+	}
+	if tags, err = v.TagMacros.expandTags(tags, time.Now()); err != nil {
+		return err
+	}
+	if _, err := validateParsedTags(tags); err != nil {
+		return err
+	}
+	return validateTagSet(tags, v.TagKeyRenames, v.TagKeyCase)
+}
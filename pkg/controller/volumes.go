@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"k8s-eni-tagger/pkg/metrics"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// syncVolumeTags applies tags, the pod's tags just written to its ENI, to the EBS
+// volumes of the instance running the pod. Volumes are claimed like security
+// groups (see syncSecurityGroupTags), through their own hash tag: pods on a node
+// with other tags than the pods that claimed its volumes leave them alone, and a
+// pod whose tags change takes its volumes along only when no other pod on the
+// node still has its old tags.
+//
+// Volume tags are best-effort: failures and conflicts are logged and reported as
+// Warning events but do not fail the pod's condition, and are retried with the
+// pod's next tag change.
+func (r *PodReconciler) syncVolumeTags(ctx context.Context, pod *corev1.Pod, tags, lastAppliedTags map[string]string, hash, lastAppliedHash string) {
+	if r.Volumes == nil {
+		return
+	}
+	logger := log.FromContext(ctx)
+	keys := r.keys()
+
+	instanceID, err := r.podInstanceID(ctx, pod)
+	if err != nil {
+		r.volumeTaggingFailed(logger, pod, nil, err)
+		return
+	}
+	volumeTags, err := r.Volumes.GetInstanceVolumeTags(ctx, instanceID)
+	if err != nil {
+		r.volumeTaggingFailed(logger, pod, nil, err)
+		return
+	}
+	volumeIDs := slices.Sorted(maps.Keys(volumeTags))
+
+	desired, removed := r.sharedResourceTags(tags, lastAppliedTags, keys.VolumeHashTag, hash)
+	toTag, toUntag, conflicts := r.claimSharedResources(volumeIDs, volumeTags, keys.VolumeHashTag, desired, hash, lastAppliedHash)
+	if len(toUntag) > 0 {
+		// Pods on a node commonly share tags: volumes carrying the pod's old tags
+		// stay with the other pods still using them.
+		pods, err := PodsByNode(ctx, r, pod.Spec.NodeName)
+		if err != nil {
+			r.volumeTaggingFailed(logger, pod, toUntag, err)
+			return
+		}
+		inUse, err := r.hashInUse(ctx, pod, pods, lastAppliedHash)
+		if err != nil {
+			r.volumeTaggingFailed(logger, pod, toUntag, err)
+			return
+		}
+		if inUse {
+			toTag = slices.DeleteFunc(toTag, func(id string) bool { return slices.Contains(toUntag, id) })
+			conflicts = append(conflicts, toUntag...)
+			toUntag = nil
+		}
+	}
+
+	if len(conflicts) > 0 {
+		metrics.VolumeTaggingTotal.WithLabelValues("conflict").Add(float64(len(conflicts)))
+		logger.Info("Volumes carry tags of other pods, leaving them untouched", "volumes", conflicts, "instanceID", instanceID)
+		r.Recorder.Event(pod, corev1.EventTypeWarning, "VolumeTagConflict", fmt.Sprintf("Volumes %s of instance %s carry tags of other pods or installations and were not tagged", strings.Join(conflicts, ", "), instanceID))
+	}
+	if err := r.Volumes.TagVolumes(ctx, toTag, desired); err != nil {
+		r.volumeTaggingFailed(logger, pod, toTag, err)
+		return
+	}
+	if len(removed) > 0 {
+		if err := r.Volumes.UntagVolumes(ctx, toUntag, removed); err != nil {
+			r.volumeTaggingFailed(logger, pod, toUntag, err)
+			return
+		}
+	}
+	if len(toTag) > 0 {
+		metrics.VolumeTaggingTotal.WithLabelValues("applied").Add(float64(len(toTag)))
+		logger.Info("Applied tags to volumes", "volumes", toTag, "instanceID", instanceID, "removed", len(removed))
+	}
+}
+
+// cleanupVolumeTags removes the tags of a deleted pod from the volumes of its
+// node that carry its hash, unless another pod on the node still has the same
+// tags applied. Volumes of nodes that are already gone are left alone.
+func (r *PodReconciler) cleanupVolumeTags(ctx context.Context, logger logr.Logger, pod *corev1.Pod, lastAppliedTags map[string]string, lastAppliedHash string) {
+	if r.Volumes == nil || lastAppliedHash == "" || pod.Spec.NodeName == "" {
+		return
+	}
+	keys := r.keys()
+
+	pods, err := PodsByNode(ctx, r, pod.Spec.NodeName)
+	if err != nil {
+		metrics.VolumeTaggingTotal.WithLabelValues("error").Inc()
+		logger.Error(err, "Failed to look up pods on node, leaving volume tags in place", "node", pod.Spec.NodeName)
+		return
+	}
+	inUse, err := r.hashInUse(ctx, pod, pods, lastAppliedHash)
+	if err != nil {
+		metrics.VolumeTaggingTotal.WithLabelValues("error").Inc()
+		logger.Error(err, "Failed to check pods on node, leaving volume tags in place", "node", pod.Spec.NodeName)
+		return
+	}
+	if inUse {
+		logger.V(1).Info("Volumes still used by pods with the same tags, keeping their tags", "node", pod.Spec.NodeName)
+		return
+	}
+
+	instanceID, err := r.podInstanceID(ctx, pod)
+	if err != nil {
+		logger.V(1).Info("Cannot find instance of node, leaving volume tags in place", "node", pod.Spec.NodeName, "reason", err.Error())
+		return
+	}
+	volumeTags, err := r.Volumes.GetInstanceVolumeTags(ctx, instanceID)
+	if err != nil {
+		metrics.VolumeTaggingTotal.WithLabelValues("error").Inc()
+		logger.Error(err, "Failed to read volume tags, leaving them in place", "instanceID", instanceID)
+		return
+	}
+	var owned []string
+	for _, id := range slices.Sorted(maps.Keys(volumeTags)) {
+		if volumeTags[id][keys.VolumeHashTag] == lastAppliedHash {
+			owned = append(owned, id)
+		}
+	}
+	if len(owned) == 0 {
+		return
+	}
+
+	tagKeys := r.sharedResourceTagKeys(lastAppliedTags, keys.VolumeHashTag)
+	if err := r.Volumes.UntagVolumes(ctx, owned, tagKeys); err != nil {
+		metrics.VolumeTaggingTotal.WithLabelValues("error").Inc()
+		logger.Error(err, "Failed to clean up volume tags", "volumes", owned)
+		return
+	}
+	metrics.VolumeTaggingTotal.WithLabelValues("removed").Add(float64(len(owned)))
+	logger.Info("Cleaned up tags on volumes", "volumes", owned, "instanceID", instanceID, "tags", tagKeys)
+}
+
+func (r *PodReconciler) volumeTaggingFailed(logger logr.Logger, pod *corev1.Pod, volumeIDs []string, err error) {
+	metrics.VolumeTaggingTotal.WithLabelValues("error").Inc()
+	logger.Error(err, "Failed to tag volumes", "volumes", volumeIDs)
+	r.Recorder.Event(pod, corev1.EventTypeWarning, "VolumeTaggingFailed", err.Error())
+}
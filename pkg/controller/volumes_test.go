@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"k8s-eni-tagger/pkg/aws"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeVolumes keeps the tags of the volumes of one instance in memory.
+type fakeVolumes struct {
+	instanceID string
+	tags       map[string]map[string]string
+}
+
+func (f *fakeVolumes) GetInstanceVolumeTags(ctx context.Context, instanceID string) (map[string]map[string]string, error) {
+	out := make(map[string]map[string]string)
+	if instanceID == f.instanceID {
+		for id, tags := range f.tags {
+			out[id] = tags
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeVolumes) TagVolumes(ctx context.Context, volumeIDs []string, tags map[string]string) error {
+	for _, id := range volumeIDs {
+		for k, v := range tags {
+			f.tags[id][k] = v
+		}
+	}
+	return nil
+}
+
+func (f *fakeVolumes) UntagVolumes(ctx context.Context, volumeIDs []string, tagKeys []string) error {
+	for _, id := range volumeIDs {
+		for _, k := range tagKeys {
+			delete(f.tags[id], k)
+		}
+	}
+	return nil
+}
+
+var _ aws.VolumeTagger = (*fakeVolumes)(nil)
+
+// volumeTestClient returns a client holding node-1, running instance i-1, and pods.
+func volumeTestClient(t *testing.T, pods ...client.Object) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+		Spec:       corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-1"},
+	}
+	return fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(append(pods, node)...).
+		WithIndex(&corev1.Pod{}, PodNodeIndexField, podNodeName).
+		Build()
+}
+
+func volumeTestPod(name, hash string) *corev1.Pod {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+	}
+	if hash != "" {
+		pod.Annotations = map[string]string{LastAppliedHashKey: hash}
+	}
+	return pod
+}
+
+func TestSyncVolumeTags(t *testing.T) {
+	oldTags := map[string]string{"team": "a", "env": "dev"}
+	newTags := map[string]string{"team": "a"}
+	oldHash, newHash := computeHash(oldTags), computeHash(newTags)
+
+	t.Run("claims free volumes and its own", func(t *testing.T) {
+		volumes := &fakeVolumes{instanceID: "i-1", tags: map[string]map[string]string{
+			"vol-root":  {},
+			"vol-ours":  {"team": "a", "env": "dev", VolumeHashTagKey: oldHash},
+			"vol-other": {"team": "b", VolumeHashTagKey: "other"},
+		}}
+		pod := volumeTestPod("web", oldHash)
+		recorder := record.NewFakeRecorder(10)
+		r := &PodReconciler{Client: volumeTestClient(t, pod), Recorder: recorder, Volumes: volumes}
+
+		r.syncVolumeTags(context.Background(), pod, newTags, oldTags, newHash, oldHash)
+
+		want := map[string]string{"team": "a", VolumeHashTagKey: newHash}
+		assert.Equal(t, want, volumes.tags["vol-root"])
+		assert.Equal(t, want, volumes.tags["vol-ours"], "tags the pod no longer has are removed")
+		assert.Equal(t, map[string]string{"team": "b", VolumeHashTagKey: "other"}, volumes.tags["vol-other"])
+		assert.Contains(t, <-recorder.Events, "VolumeTagConflict")
+	})
+
+	t.Run("leaves volumes to pods with its old tags", func(t *testing.T) {
+		volumes := &fakeVolumes{instanceID: "i-1", tags: map[string]map[string]string{
+			"vol-ours": {"team": "a", "env": "dev", VolumeHashTagKey: oldHash},
+		}}
+		pod := volumeTestPod("web", oldHash)
+		r := &PodReconciler{Client: volumeTestClient(t, pod, volumeTestPod("api", oldHash)), Recorder: record.NewFakeRecorder(10), Volumes: volumes}
+
+		r.syncVolumeTags(context.Background(), pod, newTags, oldTags, newHash, oldHash)
+
+		assert.Equal(t, map[string]string{"team": "a", "env": "dev", VolumeHashTagKey: oldHash}, volumes.tags["vol-ours"])
+	})
+}
+
+func TestCleanupVolumeTags(t *testing.T) {
+	tags := map[string]string{"team": "a"}
+	hash := computeHash(tags)
+	newVolumes := func() *fakeVolumes {
+		return &fakeVolumes{instanceID: "i-1", tags: map[string]map[string]string{
+			"vol-ours":  {"team": "a", VolumeHashTagKey: hash},
+			"vol-other": {"team": "b", VolumeHashTagKey: "other"},
+		}}
+	}
+	pod := volumeTestPod("web", hash)
+
+	t.Run("last pod with the tags", func(t *testing.T) {
+		volumes := newVolumes()
+		r := &PodReconciler{Client: volumeTestClient(t, pod, volumeTestPod("api", "other")), Volumes: volumes}
+
+		r.cleanupVolumeTags(context.Background(), logr.Discard(), pod, tags, hash)
+
+		assert.Empty(t, volumes.tags["vol-ours"])
+		assert.Equal(t, map[string]string{"team": "b", VolumeHashTagKey: "other"}, volumes.tags["vol-other"])
+	})
+
+	t.Run("another pod with the same tags", func(t *testing.T) {
+		volumes := newVolumes()
+		r := &PodReconciler{Client: volumeTestClient(t, pod, volumeTestPod("api", hash)), Volumes: volumes}
+
+		r.cleanupVolumeTags(context.Background(), logr.Discard(), pod, tags, hash)
+
+		assert.Equal(t, map[string]string{"team": "a", VolumeHashTagKey: hash}, volumes.tags["vol-ours"])
+	})
+}
@@ -2,6 +2,7 @@ package health
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
@@ -24,43 +25,64 @@ type EC2HealthAPI interface {
 }
 
 // AWSChecker checks connectivity to AWS services via a generic health API.
-// It is designed to be used in health probes (e.g., readiness endpoints).
+// The AWS call runs in a background goroutine (see Start) and probe handlers
+// only read the cached result, so probe latency is independent of AWS latency
+// and probes never trigger AWS API calls themselves.
 //
-// Thread safety: AWSChecker is safe for concurrent use as it does not share mutable state between requests.
-// Idempotency: Each Check invocation is independent and does not affect subsequent calls.
+// Thread safety: AWSChecker is safe for concurrent use; the cached result is protected by a mutex.
 type AWSChecker struct {
 	client         AWSHealthAPI
 	timeoutSeconds int
 	maxRetries     int
 	metrics        AWSCheckerMetrics
-	// latch fields to avoid repeated AWS calls after initial successes
-	mu           sync.Mutex
-	callMu       sync.Mutex
-	successCount int
-	maxSuccesses int
+	// staleAfter is how old the cached result may be before Check reports it as stale.
+	// Zero disables the staleness check.
+	staleAfter time.Duration
+
+	mu          sync.RWMutex
+	lastErr     error
+	lastStatus  string
+	lastCheck   time.Time
+	lastSuccess time.Time
 }
 
+// Classifications of the last AWS health check result.
+const (
+	StatusOK                = "ok"
+	StatusPermissionError   = "permission_error"
+	StatusConnectivityError = "connectivity_error"
+	StatusAPIError          = "api_error"
+)
+
+// Statuses lists every classification an AWS health check can report.
+var Statuses = []string{StatusOK, StatusPermissionError, StatusConnectivityError, StatusAPIError}
+
 // AWSCheckerMetrics defines hooks for metrics collection (e.g., Prometheus)
 type AWSCheckerMetrics interface {
 	IncSuccess()
 	IncFailure()
 	ObserveLatency(seconds float64)
+	// SetStatus records the classification of the most recent check (one of Statuses).
+	SetStatus(status string)
+}
+
+// Snapshot is the last AWS health check result as served by ServeHTTP.
+type Snapshot struct {
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	LastCheck   *time.Time `json:"lastCheck,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
 }
 
 // NewAWSChecker creates a new AWS health checker using a generic AWSHealthAPI client.
 // Usage:
 //
 //	checker := NewAWSChecker(awsClient)
+//	checker.Start(ctx, 30*time.Second)
 //	err := checker.Check(req)
 func NewAWSChecker(client AWSHealthAPI) *AWSChecker {
 	// Default: 5s timeout, 1 retry
-	return &AWSChecker{
-		client:         client,
-		timeoutSeconds: 5,
-		maxRetries:     1,
-		metrics:        nil,
-		maxSuccesses:   3,
-	}
+	return NewAWSCheckerWithConfig(client, 5, 1)
 }
 
 // NewAWSCheckerWithConfig creates a new AWSChecker with custom timeout and retry settings.
@@ -70,51 +92,127 @@ func NewAWSCheckerWithConfig(client AWSHealthAPI, timeoutSeconds, maxRetries int
 		timeoutSeconds: timeoutSeconds,
 		maxRetries:     maxRetries,
 		metrics:        nil,
-		maxSuccesses:   3,
 	}
 }
 
-// SetMaxSuccesses sets the latch threshold for successful checks before
-// skipping further AWS API calls. Thread-safe.
-func (c *AWSChecker) SetMaxSuccesses(n int) {
+// SetMetrics installs metrics hooks. Call before Start.
+func (c *AWSChecker) SetMetrics(m AWSCheckerMetrics) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	if n < 0 {
-		n = 0
+	c.metrics = m
+}
+
+// Start runs an AWS health check immediately and then every interval in a
+// background goroutine until ctx is cancelled. Results older than three
+// intervals are reported as stale by Check, which surfaces a stuck prober.
+func (c *AWSChecker) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		log.Printf("[AWSChecker] Background health checks disabled (interval=%s)", interval)
+		return
 	}
-	c.maxSuccesses = n
+
+	c.mu.Lock()
+	c.staleAfter = 3 * interval
+	c.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		c.Probe(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				log.Printf("[AWSChecker] Stopping background health checks")
+				return
+			case <-ticker.C:
+				c.Probe(ctx)
+			}
+		}
+	}()
 }
 
-// Check performs a lightweight AWS API call to verify connectivity.
-// Returns nil if AWS API is reachable and permissions are sufficient.
-// Returns a wrapped error if connectivity or permissions are insufficient.
-func (c *AWSChecker) Check(req *http.Request) error {
+// Check returns the result of the most recent background AWS health check.
+// It never calls AWS itself. It returns an error if no check has completed yet,
+// the last check failed, or the last result is stale.
+func (c *AWSChecker) Check(_ *http.Request) error {
 	if c == nil || c.client == nil {
 		log.Printf("[AWSChecker] AWS client not configured")
 		return fmt.Errorf("AWS client not configured")
 	}
-	// Latch: if we've already had enough successes, skip AWS call
-	c.mu.Lock()
-	if c.maxSuccesses > 0 && c.successCount >= c.maxSuccesses {
-		c.mu.Unlock()
-		return nil
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.lastCheck.IsZero() {
+		return fmt.Errorf("AWS health check has not completed yet")
+	}
+	if c.staleAfter > 0 && time.Since(c.lastCheck) > c.staleAfter {
+		return fmt.Errorf("AWS health check result is stale (last check %s ago)", time.Since(c.lastCheck).Round(time.Second))
+	}
+	return c.lastErr
+}
+
+// Probe performs a lightweight AWS API call to verify connectivity and caches
+// the result for Check. It returns nil if the AWS API is reachable and
+// permissions are sufficient, or a classified error otherwise.
+func (c *AWSChecker) Probe(ctx context.Context) error {
+	if c == nil || c.client == nil {
+		return fmt.Errorf("AWS client not configured")
 	}
-	c.mu.Unlock()
 
-	// Serialize AWS calls so that concurrent probes do not exceed the latch threshold.
-	c.callMu.Lock()
-	defer c.callMu.Unlock()
+	status, err := c.probe(ctx)
 
-	// Recheck latch after waiting for call lock in case another goroutine already succeeded.
 	c.mu.Lock()
-	if c.maxSuccesses > 0 && c.successCount >= c.maxSuccesses {
-		c.mu.Unlock()
-		return nil
+	now := time.Now()
+	c.lastErr = err
+	c.lastStatus = status
+	c.lastCheck = now
+	if err == nil {
+		c.lastSuccess = now
 	}
 	c.mu.Unlock()
 
+	if c.metrics != nil {
+		c.metrics.SetStatus(status)
+	}
+
+	return err
+}
+
+// Snapshot returns the last cached AWS health check result.
+func (c *AWSChecker) Snapshot() Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snap := Snapshot{Status: c.lastStatus}
+	if c.lastCheck.IsZero() {
+		snap.Status = "pending"
+		return snap
+	}
+	lastCheck := c.lastCheck
+	snap.LastCheck = &lastCheck
+	if !c.lastSuccess.IsZero() {
+		lastSuccess := c.lastSuccess
+		snap.LastSuccess = &lastSuccess
+	}
+	if c.lastErr != nil {
+		snap.Error = c.lastErr.Error()
+	}
+	return snap
+}
+
+// ServeHTTP serves the last AWS health check result as JSON. It never calls AWS.
+func (c *AWSChecker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(c.Snapshot()); err != nil {
+		log.Printf("[AWSChecker] Failed to encode health snapshot: %v", err)
+	}
+}
+
+func (c *AWSChecker) probe(parent context.Context) (string, error) {
 	log.Printf("[AWSChecker] Performing AWS health check via HealthCheck method")
-	ctx, cancel := context.WithTimeout(req.Context(), time.Duration(c.timeoutSeconds)*time.Second)
+	ctx, cancel := context.WithTimeout(parent, time.Duration(c.timeoutSeconds)*time.Second)
 	defer cancel()
 	var err error
 	start := time.Now()
@@ -123,22 +221,13 @@ retryLoop:
 		err = c.client.HealthCheck(ctx)
 		if err == nil {
 			log.Printf("[AWSChecker] AWS health check succeeded (attempt %d)", attempt+1)
-			// Latch success only after a successful call
-			c.mu.Lock()
-			if c.maxSuccesses > 0 && c.successCount < c.maxSuccesses {
-				c.successCount++
-			}
-			c.mu.Unlock()
 			if c.metrics != nil {
 				c.metrics.IncSuccess()
 				c.metrics.ObserveLatency(time.Since(start).Seconds())
 			}
-			return nil
+			return StatusOK, nil
 		}
 		log.Printf("[AWSChecker] AWS health check failed (attempt %d): %v", attempt+1, err)
-		c.mu.Lock()
-		c.successCount = 0
-		c.mu.Unlock()
 		// If context is done, break early
 		if ctx.Err() != nil {
 			break
@@ -164,16 +253,16 @@ retryLoop:
 		errMsg = err.Error()
 		if errMsg != "" && (containsPermissionError(errMsg)) {
 			log.Printf("[AWSChecker] Permission error: %v", err)
-			return fmt.Errorf("AWS permission error: %w", err)
+			return StatusPermissionError, fmt.Errorf("AWS permission error: %w", err)
 		}
 		if errMsg != "" && (containsConnectivityError(errMsg)) {
 			log.Printf("[AWSChecker] Connectivity error: %v", err)
-			return fmt.Errorf("AWS connectivity error: %w", err)
+			return StatusConnectivityError, fmt.Errorf("AWS connectivity error: %w", err)
 		}
 		log.Printf("[AWSChecker] AWS API error: %v", err)
-		return fmt.Errorf("AWS API error: %w", err)
+		return StatusAPIError, fmt.Errorf("AWS API error: %w", err)
 	}
-	return fmt.Errorf("AWS health check failed: unknown error")
+	return StatusAPIError, fmt.Errorf("AWS health check failed: unknown error")
 }
 
 // containsPermissionError checks if error message indicates a permission issue.
@@ -2,8 +2,10 @@ package health
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -62,9 +64,11 @@ func TestCheck(t *testing.T) {
 
 			client := &EC2HealthClient{EC2: m}
 			checker := NewAWSChecker(client)
-			req := httptest.NewRequest("GET", "/healthz", nil)
+			req := httptest.NewRequest("GET", "/readyz", nil)
 
+			probeErr := checker.Probe(context.Background())
 			err := checker.Check(req)
+			assert.Equal(t, probeErr, err)
 			if tt.expectErr {
 				assert.Error(t, err)
 			} else {
@@ -88,8 +92,7 @@ func TestCheck(t *testing.T) {
 		permErr := errors.New("UnauthorizedOperation: You are not authorized to perform this operation.")
 		m.On("DescribeAccountAttributes", mock.Anything, mock.Anything, mock.Anything).Return(nil, permErr)
 		checker := NewAWSChecker(m)
-		req := httptest.NewRequest("GET", "/healthz", nil)
-		err := checker.Check(req)
+		err := checker.Probe(context.Background())
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "UnauthorizedOperation")
 		m.AssertExpectations(t)
@@ -102,8 +105,7 @@ func TestCheck(t *testing.T) {
 		cancel()
 		m.On("DescribeAccountAttributes", mock.Anything, mock.Anything, mock.Anything).Return(nil, context.Canceled)
 		checker := NewAWSChecker(m)
-		req := httptest.NewRequest("GET", "/healthz", nil).WithContext(cancelledCtx)
-		err := checker.Check(req)
+		err := checker.Probe(cancelledCtx)
 		assert.Error(t, err)
 		assert.Equal(t, context.Canceled, errors.Unwrap(err))
 		m.AssertExpectations(t)
@@ -115,67 +117,58 @@ func TestCheck(t *testing.T) {
 	// })
 }
 
-func TestCheck_LatchesAfterSuccesses(t *testing.T) {
+func TestCheck_NoAWSCallFromProbes(t *testing.T) {
 	m := new(mockEC2Health)
-	// Always return success from AWS call
 	m.On("DescribeAccountAttributes", mock.Anything, mock.Anything, mock.Anything).Return(&ec2.DescribeAccountAttributesOutput{}, nil)
 
-	client := &EC2HealthClient{EC2: m}
-	checker := NewAWSChecker(client)
-	// Reduce the threshold for the test
-	checker.maxSuccesses = 2
+	checker := NewAWSChecker(&EC2HealthClient{EC2: m})
+	req := httptest.NewRequest("GET", "/readyz", nil)
 
-	req := httptest.NewRequest("GET", "/healthz", nil)
+	// Before any background check has run, probes fail without calling AWS
+	assert.Error(t, checker.Check(req))
+	m.AssertNumberOfCalls(t, "DescribeAccountAttributes", 0)
 
-	// Perform multiple checks; only the first two should hit AWS
+	assert.NoError(t, checker.Probe(context.Background()))
 	for i := 0; i < 5; i++ {
-		err := checker.Check(req)
-		assert.NoError(t, err)
+		assert.NoError(t, checker.Check(req))
 	}
-
-	m.AssertNumberOfCalls(t, "DescribeAccountAttributes", 2)
-	m.AssertExpectations(t)
+	m.AssertNumberOfCalls(t, "DescribeAccountAttributes", 1)
 }
 
-func TestCheck_LatchesAfterSuccesses_Concurrent(t *testing.T) {
+func TestCheck_StaleResult(t *testing.T) {
 	m := new(mockEC2Health)
-	// Always return success from AWS call
 	m.On("DescribeAccountAttributes", mock.Anything, mock.Anything, mock.Anything).Return(&ec2.DescribeAccountAttributesOutput{}, nil)
 
-	client := &EC2HealthClient{EC2: m}
-	checker := NewAWSChecker(client)
-	// Set threshold to 3 to verify race condition fix
-	checker.maxSuccesses = 3
-
-	// Run concurrent checks to simulate realistic Kubernetes probe behavior
-	// Multiple probes run concurrently and should not all make AWS calls
-	concurrentChecks := 10
-	for i := 0; i < 5; i++ { // Run the concurrent batch 5 times
-		// Create a channel to coordinate goroutines
-		done := make(chan error, concurrentChecks)
-
-		// Launch concurrent checks
-		for j := 0; j < concurrentChecks; j++ {
-			go func() {
-				req := httptest.NewRequest("GET", "/healthz", nil)
-				done <- checker.Check(req)
-			}()
-		}
+	checker := NewAWSChecker(&EC2HealthClient{EC2: m})
+	checker.staleAfter = time.Minute
+	assert.NoError(t, checker.Probe(context.Background()))
+	assert.NoError(t, checker.Check(nil))
 
-		// Wait for all goroutines to complete
-		for j := 0; j < concurrentChecks; j++ {
-			err := <-done
-			assert.NoError(t, err)
-		}
-	}
+	checker.mu.Lock()
+	checker.lastCheck = time.Now().Add(-2 * time.Minute)
+	checker.mu.Unlock()
+
+	err := checker.Check(nil)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "stale")
+}
 
-	// With proper latch implementation, only ~3 calls should hit AWS per batch
-	// Even with 5 batches and 10 concurrent checks per batch, total should be significantly less
-	// Expected: ~3 calls (the threshold), not 50 calls (if no latch) or 15 (if latch fails under concurrency)
-	calls := m.Calls
-	totalCalls := len(calls)
-	t.Logf("Total AWS calls made: %d (expected <= 3 due to latch)", totalCalls)
-	assert.LessOrEqual(t, totalCalls, 3, "Race condition detected: too many AWS calls made despite latch mechanism")
+func TestStart_RunsInBackground(t *testing.T) {
+	var calls atomic.Int32
+	m := new(mockEC2Health)
+	m.On("DescribeAccountAttributes", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { calls.Add(1) }).
+		Return(&ec2.DescribeAccountAttributesOutput{}, nil)
+
+	checker := NewAWSChecker(&EC2HealthClient{EC2: m})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	checker.Start(ctx, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return checker.Check(nil) == nil && calls.Load() >= 2
+	}, time.Second, 5*time.Millisecond)
 }
 
 func TestAWSChecker_computeBackoff(t *testing.T) {
@@ -205,3 +198,41 @@ func TestAWSChecker_computeBackoff(t *testing.T) {
 		last = got
 	}
 }
+
+type recordingMetrics struct {
+	statuses []string
+}
+
+func (r *recordingMetrics) IncSuccess()             {}
+func (r *recordingMetrics) IncFailure()             {}
+func (r *recordingMetrics) ObserveLatency(float64)  {}
+func (r *recordingMetrics) SetStatus(status string) { r.statuses = append(r.statuses, status) }
+
+func TestProbe_ClassifiesAndServesSnapshot(t *testing.T) {
+	m := new(mockEC2Health)
+	m.On("DescribeAccountAttributes", mock.Anything, mock.Anything, mock.Anything).
+		Return(&ec2.DescribeAccountAttributesOutput{}, nil).Once()
+	m.On("DescribeAccountAttributes", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, errors.New("dial tcp: connection refused")).Once()
+
+	rec := &recordingMetrics{}
+	checker := NewAWSChecker(m)
+	checker.SetMetrics(rec)
+
+	assert.Equal(t, "pending", checker.Snapshot().Status)
+
+	assert.NoError(t, checker.Probe(context.Background()))
+	assert.Error(t, checker.Probe(context.Background()))
+	assert.Equal(t, []string{StatusOK, StatusConnectivityError}, rec.statuses)
+
+	w := httptest.NewRecorder()
+	checker.ServeHTTP(w, httptest.NewRequest("GET", "/aws-health", nil))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var snap Snapshot
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &snap))
+	assert.Equal(t, StatusConnectivityError, snap.Status)
+	assert.Contains(t, snap.Error, "connection refused")
+	assert.NotNil(t, snap.LastCheck)
+	assert.NotNil(t, snap.LastSuccess)
+}
@@ -0,0 +1,55 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// Probes collects liveness and readiness checks and serves them on /healthz and
+// /readyz, as the manager's health probe server does. The controller serves
+// them itself so the listener's timeouts can be configured.
+type Probes struct {
+	healthz map[string]healthz.Checker
+	readyz  map[string]healthz.Checker
+}
+
+// NewProbes returns an empty set of probes.
+func NewProbes() *Probes {
+	return &Probes{
+		healthz: make(map[string]healthz.Checker),
+		readyz:  make(map[string]healthz.Checker),
+	}
+}
+
+// AddHealthzCheck adds a liveness check. Names must be unique.
+func (p *Probes) AddHealthzCheck(name string, check healthz.Checker) error {
+	return addCheck(p.healthz, name, check)
+}
+
+// AddReadyzCheck adds a readiness check. Names must be unique.
+func (p *Probes) AddReadyzCheck(name string, check healthz.Checker) error {
+	return addCheck(p.readyz, name, check)
+}
+
+func addCheck(checks map[string]healthz.Checker, name string, check healthz.Checker) error {
+	if _, ok := checks[name]; ok {
+		return fmt.Errorf("check %q already exists", name)
+	}
+	checks[name] = check
+	return nil
+}
+
+// Handler serves the probes. Checks must all be added before it is called.
+// Individual checks are served on sub-paths, e.g. /readyz/aws, and
+// ?verbose lists every check's result.
+func (p *Probes) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for path, checks := range map[string]map[string]healthz.Checker{"/healthz": p.healthz, "/readyz": p.readyz} {
+		h := http.StripPrefix(path, &healthz.Handler{Checks: checks})
+		mux.Handle(path, h)
+		mux.Handle(path+"/", h)
+	}
+	return mux
+}
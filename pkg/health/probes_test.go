@@ -0,0 +1,31 @@
+package health
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbes(t *testing.T) {
+	p := NewProbes()
+	ok := func(*http.Request) error { return nil }
+	require.NoError(t, p.AddHealthzCheck("ping", ok))
+	require.NoError(t, p.AddReadyzCheck("ping", ok))
+	require.NoError(t, p.AddReadyzCheck("aws", func(*http.Request) error { return errors.New("unreachable") }))
+	assert.Error(t, p.AddReadyzCheck("aws", ok), "duplicate names are rejected")
+
+	get := func(path string) int {
+		rec := httptest.NewRecorder()
+		p.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, get("/healthz"))
+	assert.Equal(t, http.StatusInternalServerError, get("/readyz"))
+	assert.Equal(t, http.StatusOK, get("/readyz/ping"))
+	assert.Equal(t, http.StatusInternalServerError, get("/readyz/aws"))
+	assert.Equal(t, http.StatusNotFound, get("/metrics"))
+}
@@ -0,0 +1,87 @@
+// Package httpserver runs the controller's auxiliary HTTP listeners (health
+// probes, pprof and the admin endpoint) with bounded timeouts and graceful
+// shutdown.
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// Options bounds how long clients may hold an auxiliary server's connections.
+// There is deliberately no write timeout: pprof profiles stream for as long as
+// the client asks (30s by default).
+type Options struct {
+	// ReadHeaderTimeout is how long a client may take to send request headers.
+	ReadHeaderTimeout time.Duration
+	// ReadTimeout is how long a client may take to send the whole request.
+	ReadTimeout time.Duration
+	// IdleTimeout is how long a keep-alive connection may wait for its next request.
+	IdleTimeout time.Duration
+	// MaxHeaderBytes caps the size of request headers.
+	MaxHeaderBytes int
+	// ShutdownTimeout is how long in-flight requests may take to finish on shutdown.
+	ShutdownTimeout time.Duration
+}
+
+// Server serves Handler on Addr until its context is cancelled. It implements
+// manager.Runnable and runs on every replica, leader or not.
+type Server struct {
+	// Name identifies the server in logs, e.g. "admin".
+	Name    string
+	Addr    string
+	Handler http.Handler
+	Options Options
+	Log     logr.Logger
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Start listens on Addr and serves until ctx is cancelled, then waits up to
+// ShutdownTimeout for in-flight requests. It fails if Addr cannot be bound.
+func (s *Server) Start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.serve(ctx, ln)
+}
+
+func (s *Server) serve(ctx context.Context, ln net.Listener) error {
+	log := s.Log.WithValues("server", s.Name, "addr", ln.Addr().String())
+	srv := &http.Server{
+		Handler:           s.Handler,
+		ReadHeaderTimeout: s.Options.ReadHeaderTimeout,
+		ReadTimeout:       s.Options.ReadTimeout,
+		IdleTimeout:       s.Options.IdleTimeout,
+		MaxHeaderBytes:    s.Options.MaxHeaderBytes,
+	}
+
+	shutdownDone := make(chan struct{})
+	go func() {
+		defer close(shutdownDone)
+		<-ctx.Done()
+		log.Info("Shutting down server")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), s.Options.ShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Error(err, "Server did not shut down cleanly")
+			_ = srv.Close()
+		}
+	}()
+
+	log.Info("Starting server")
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	<-shutdownDone
+	return nil
+}
@@ -0,0 +1,110 @@
+package httpserver
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startServer(t *testing.T, handler http.Handler, opts Options) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{Name: "test", Handler: handler, Options: opts, Log: logr.Discard()}
+	done := make(chan error, 1)
+	go func() { done <- s.serve(ctx, ln) }()
+	return ln.Addr().String(), cancel, done
+}
+
+func TestServer_ReadHeaderTimeout(t *testing.T) {
+	addr, cancel, _ := startServer(t, http.NotFoundHandler(), Options{
+		ReadHeaderTimeout: 50 * time.Millisecond,
+		ShutdownTimeout:   time.Second,
+	})
+	defer cancel()
+
+	// A client that never finishes its headers is disconnected
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = bufio.NewReader(conn).ReadString('\n')
+	require.Error(t, err)
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		assert.False(t, netErr.Timeout(), "the server closes the connection before the client gives up")
+	}
+}
+
+func TestServer_MaxHeaderBytes(t *testing.T) {
+	addr, cancel, _ := startServer(t, http.NotFoundHandler(), Options{
+		ReadHeaderTimeout: time.Second,
+		MaxHeaderBytes:    1024,
+		ShutdownTimeout:   time.Second,
+	})
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Large", strings.Repeat("a", 8<<10))
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+}
+
+func TestServer_GracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	})
+	addr, cancel, done := startServer(t, handler, Options{ReadHeaderTimeout: time.Second, ShutdownTimeout: 5 * time.Second})
+
+	result := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			result <- 0
+			return
+		}
+		resp.Body.Close()
+		result <- resp.StatusCode
+	}()
+	<-started
+	cancel()
+
+	// The in-flight request finishes before the server stops
+	select {
+	case <-done:
+		t.Fatal("server stopped with a request in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	assert.Equal(t, http.StatusNoContent, <-result)
+	require.NoError(t, <-done)
+}
+
+func TestServer_StartFailsOnBusyAddress(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	s := &Server{Name: "test", Addr: ln.Addr().String(), Handler: http.NotFoundHandler(), Log: logr.Discard()}
+	assert.Error(t, s.Start(context.Background()))
+	assert.False(t, s.NeedLeaderElection())
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// ReconcileIDExemplarLabel is the exemplar label holding the ID of the reconcile
+// that made an AWS call. controller-runtime logs the same ID as "reconcileID" on
+// every line of that reconcile, so an exemplar leads to its logs.
+const ReconcileIDExemplarLabel = "reconcile_id"
+
+// OpenMetricsPath is where metrics are served in the OpenMetrics format, the only
+// one that carries exemplars. The standard /metrics endpoint omits them.
+const OpenMetricsPath = "/metrics/openmetrics"
+
+var exemplarsEnabled atomic.Bool
+
+// reconcileIDFromContext is replaced in tests, as only controller-runtime can
+// put a reconcile ID in a context.
+var reconcileIDFromContext = controller.ReconcileIDFromContext
+
+// EnableExemplars turns on reconcile ID exemplars for AWS API latency.
+func EnableExemplars(enabled bool) {
+	exemplarsEnabled.Store(enabled)
+}
+
+// ObserveAWSAPILatency records the latency of an AWS call. With exemplars
+// enabled, calls made during a reconcile carry its ID as an exemplar.
+func ObserveAWSAPILatency(ctx context.Context, operation, status string, seconds float64) {
+	observer := AWSAPILatency.WithLabelValues(operation, status)
+	if exemplarsEnabled.Load() {
+		if id := reconcileIDFromContext(ctx); id != "" {
+			if eo, ok := observer.(prometheus.ExemplarObserver); ok {
+				eo.ObserveWithExemplar(seconds, prometheus.Labels{ReconcileIDExemplarLabel: string(id)})
+				return
+			}
+		}
+	}
+	observer.Observe(seconds)
+}
+
+// OpenMetricsHandler serves the controller-runtime registry, exemplars included,
+// to scrapers that negotiate the OpenMetrics format.
+func OpenMetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.HTTPErrorOnError,
+		EnableOpenMetrics: true,
+	})
+}
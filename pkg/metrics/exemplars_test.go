@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type reconcileIDKey struct{}
+
+func TestObserveAWSAPILatency_Exemplar(t *testing.T) {
+	orig := reconcileIDFromContext
+	reconcileIDFromContext = func(ctx context.Context) types.UID {
+		id, _ := ctx.Value(reconcileIDKey{}).(types.UID)
+		return id
+	}
+	t.Cleanup(func() {
+		reconcileIDFromContext = orig
+		EnableExemplars(false)
+	})
+	ctx := context.WithValue(context.Background(), reconcileIDKey{}, types.UID("rec-123"))
+
+	scrape := func() string {
+		req := httptest.NewRequest(http.MethodGet, OpenMetricsPath, nil)
+		req.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+		rec := httptest.NewRecorder()
+		OpenMetricsHandler().ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Body.String()
+	}
+
+	ObserveAWSAPILatency(ctx, "ExemplarTestDisabled", "success", 0.002)
+	assert.NotContains(t, scrape(), `reconcile_id="rec-123"`, "exemplars are off by default")
+
+	EnableExemplars(true)
+	ObserveAWSAPILatency(ctx, "ExemplarTest", "success", 0.002)
+	ObserveAWSAPILatency(context.Background(), "ExemplarTestNoReconcile", "success", 0.002)
+	body := scrape()
+	assert.Contains(t, body, `reconcile_id="rec-123"`)
+	assert.Contains(t, body, `operation="ExemplarTestNoReconcile"`, "calls outside a reconcile are still observed")
+}
@@ -0,0 +1,36 @@
+package metrics
+
+import "k8s-eni-tagger/pkg/health"
+
+// AWSHealthMetrics implements health.AWSCheckerMetrics with Prometheus collectors.
+type AWSHealthMetrics struct{}
+
+var _ health.AWSCheckerMetrics = AWSHealthMetrics{}
+
+// IncSuccess counts a successful check and records its timestamp.
+func (AWSHealthMetrics) IncSuccess() {
+	AWSHealthChecksTotal.WithLabelValues("success").Inc()
+	AWSHealthLastSuccess.SetToCurrentTime()
+}
+
+// IncFailure counts a failed check.
+func (AWSHealthMetrics) IncFailure() {
+	AWSHealthChecksTotal.WithLabelValues("failure").Inc()
+}
+
+// ObserveLatency records the duration of a check.
+func (AWSHealthMetrics) ObserveLatency(seconds float64) {
+	AWSHealthCheckLatency.Observe(seconds)
+}
+
+// SetStatus sets the gauge for status to 1 and every other known status to 0,
+// so all series stay present for dashboards.
+func (AWSHealthMetrics) SetStatus(status string) {
+	for _, s := range health.Statuses {
+		value := 0.0
+		if s == status {
+			value = 1
+		}
+		AWSHealthStatus.WithLabelValues(s).Set(value)
+	}
+}
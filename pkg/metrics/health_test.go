@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"testing"
+
+	"k8s-eni-tagger/pkg/health"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAWSHealthMetrics_SetStatus(t *testing.T) {
+	m := AWSHealthMetrics{}
+
+	m.SetStatus(health.StatusPermissionError)
+	if got := testutil.ToFloat64(AWSHealthStatus.WithLabelValues(health.StatusPermissionError)); got != 1 {
+		t.Errorf("expected permission_error=1, got %v", got)
+	}
+	if got := testutil.ToFloat64(AWSHealthStatus.WithLabelValues(health.StatusOK)); got != 0 {
+		t.Errorf("expected ok=0, got %v", got)
+	}
+
+	m.SetStatus(health.StatusOK)
+	if got := testutil.ToFloat64(AWSHealthStatus.WithLabelValues(health.StatusOK)); got != 1 {
+		t.Errorf("expected ok=1, got %v", got)
+	}
+	if got := testutil.ToFloat64(AWSHealthStatus.WithLabelValues(health.StatusPermissionError)); got != 0 {
+		t.Errorf("expected permission_error=0, got %v", got)
+	}
+}
+
+func TestAWSHealthMetrics_IncSuccess(t *testing.T) {
+	before := testutil.ToFloat64(AWSHealthChecksTotal.WithLabelValues("success"))
+	AWSHealthMetrics{}.IncSuccess()
+	if got := testutil.ToFloat64(AWSHealthChecksTotal.WithLabelValues("success")); got != before+1 {
+		t.Errorf("expected success counter to increase by 1, got %v -> %v", before, got)
+	}
+	if testutil.ToFloat64(AWSHealthLastSuccess) == 0 {
+		t.Error("expected last success timestamp to be set")
+	}
+}
@@ -32,6 +32,17 @@ var (
 		},
 	)
 
+	// CacheIPChecksTotal counts checks that a cached ENI still holds the pod's IP,
+	// by result: match, mismatch (entry dropped) or error (cached ENI used).
+	// Only recorded with --eni-cache-ip-check.
+	CacheIPChecksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_cache_ip_checks_total",
+			Help: "Total number of checks that a cached ENI still holds the pod IP, by result",
+		},
+		[]string{"result"},
+	)
+
 	// CachePersistDroppedTotal tracks ConfigMap persistence updates dropped
 	// because the worker queue was full. Drops are safe (the in-memory cache
 	// is updated, and Pod-UID validation catches staleness on restart) but
@@ -42,6 +53,292 @@ var (
 			Help: "Total number of ConfigMap persistence updates dropped due to a full worker queue",
 		},
 	)
+
+	// CachePersistFencedTotal tracks ConfigMap persistence writes refused
+	// because this replica does not own the cache ConfigMap: it is waiting for
+	// the lease, or lost it to a leader that already claimed the ConfigMap.
+	CachePersistFencedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_cache_persist_fenced_total",
+			Help: "Total number of ConfigMap persistence writes refused because this replica does not own the cache",
+		},
+	)
+
+	// AWSHealthStatus is 1 for the classification of the last AWS health check and 0 for the others
+	AWSHealthStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "k8s_eni_tagger_aws_health",
+			Help: "Classification of the last AWS health check (1 for the current status, 0 otherwise)",
+		},
+		[]string{"status"},
+	)
+
+	// AWSHealthLastSuccess records when the AWS health check last succeeded
+	AWSHealthLastSuccess = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "k8s_eni_tagger_aws_health_last_success_timestamp_seconds",
+			Help: "Unix timestamp of the last successful AWS health check",
+		},
+	)
+
+	// AWSHealthChecksTotal counts AWS health checks by result
+	AWSHealthChecksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_aws_health_checks_total",
+			Help: "Total number of AWS health checks by result",
+		},
+		[]string{"result"},
+	)
+
+	// AWSHealthCheckLatency tracks the duration of AWS health checks including retries
+	AWSHealthCheckLatency = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "k8s_eni_tagger_aws_health_check_duration_seconds",
+			Help:    "Duration of AWS health checks in seconds, including retries",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 10), // 10ms to ~5s
+		},
+	)
+
+	// ReconcileConcurrencyLimit is the current runtime limit on concurrent reconciles
+	ReconcileConcurrencyLimit = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "k8s_eni_tagger_reconcile_concurrency_limit",
+			Help: "Current limit on concurrent reconciles (adjustable at runtime, capped by the worker count)",
+		},
+	)
+
+	// FairQueuePending is the number of pods held in the namespace fair queue
+	FairQueuePending = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "k8s_eni_tagger_fair_queue_pending",
+			Help: "Number of pods waiting in the namespace fair queue before entering the workqueue",
+		},
+	)
+
+	// ReconcileTriggersTotal counts pod events by why they did or did not trigger a
+	// reconcile. Only recorded with --trigger-audit.
+	ReconcileTriggersTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_reconcile_triggers_total",
+			Help: "Pod events seen by the controller's event filter, by event type, reason and result (triggered or filtered)",
+		},
+		[]string{"event", "reason", "result"},
+	)
+
+	// PodWritesCollapsedTotal counts pod patches avoided by collapsing writes to
+	// the same pod. Only recorded with --pod-write-collapse-window.
+	PodWritesCollapsedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_pod_writes_collapsed_total",
+			Help: "Total number of pod annotation and status patches avoided by collapsing writes to the same pod within the collapse window",
+		},
+	)
+
+	// TagBurstCallsSavedTotal counts CreateTags calls avoided by merging calls for
+	// the same ENI. Only recorded with --tag-burst-delay.
+	TagBurstCallsSavedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_tag_burst_calls_saved_total",
+			Help: "Total number of CreateTags calls avoided by merging calls for the same ENI within the tag burst delay",
+		},
+	)
+
+	// OwnershipReportsTotal counts ENI ownership reports written to S3 by result.
+	OwnershipReportsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_ownership_reports_total",
+			Help: "Total number of ENI ownership reports written to S3 by result",
+		},
+		[]string{"result"},
+	)
+
+	// TagSourceRequestsTotal counts external tag source lookups by result: cached,
+	// success, stale (webhook failed, last answer used) or error.
+	TagSourceRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_tag_source_requests_total",
+			Help: "Total number of external tag source lookups by result (cached, success, stale, error)",
+		},
+		[]string{"result"},
+	)
+
+	// TaggingPaused is 1 while tagging is paused by the given source (configmap
+	// or admin) and 0 otherwise.
+	TaggingPaused = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "k8s_eni_tagger_tagging_paused",
+			Help: "Whether ENI tagging is paused (1) or not (0), by pause source (configmap, admin)",
+		},
+		[]string{"source"},
+	)
+
+	// PodRateLimiters is the number of live per-pod rate limiters.
+	PodRateLimiters = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "k8s_eni_tagger_pod_rate_limiters",
+			Help: "Number of live per-pod rate limiters",
+		},
+	)
+
+	// PodRateLimiterEvictionsTotal counts per-pod rate limiters evicted because the
+	// pool reached --pod-rate-limiters-max before stale ones were cleaned up.
+	PodRateLimiterEvictionsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_pod_rate_limiter_evictions_total",
+			Help: "Total number of least recently used per-pod rate limiters evicted at the pool size cap",
+		},
+	)
+
+	// TaggingFailuresTotal counts failed tag changes by priority: critical, or
+	// best-effort when the change touched no critical tag. Alert on critical.
+	TaggingFailuresTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_tagging_failures_total",
+			Help: "Total number of failed ENI tag changes by tag priority (critical, best-effort)",
+		},
+		[]string{"priority"},
+	)
+
+	// DriftDetectedTotal counts ENIs found by the drift resync with tags differing
+	// from their pod's last applied tags.
+	DriftDetectedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_drift_detected_total",
+			Help: "Total number of ENIs found with tags drifted from their last applied tags",
+		},
+	)
+
+	// DriftRepairedTotal counts drifted ENIs whose tags were written again.
+	DriftRepairedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_drift_repaired_total",
+			Help: "Total number of ENIs whose drifted tags were repaired",
+		},
+	)
+
+	// ReadOnlyTagDrift is, in read-only mode, the number of tags to add or update
+	// (change "add") and to remove (change "remove") for each audited ENI to
+	// carry its pod's tags.
+	ReadOnlyTagDrift = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "k8s_eni_tagger_readonly_tag_drift",
+			Help: "Number of tag changes an audited ENI needs to carry its pod's tags, in read-only mode",
+		},
+		[]string{"eni_id", "change"},
+	)
+
+	// ReadOnlyAuditsTotal counts the pods audited in read-only mode by result:
+	// in_sync, drifted, invalid (tags that could not be applied) or error.
+	ReadOnlyAuditsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_readonly_audits_total",
+			Help: "Total number of pods audited in read-only mode, by result",
+		},
+		[]string{"result"},
+	)
+
+	// TaggedENIs is the number of ENIs carrying tags of pods the controller
+	// reconciled, by availability zone and subnet. "unknown" stands for a zone
+	// missing from cached ENI data.
+	TaggedENIs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "k8s_eni_tagger_tagged_enis",
+			Help: "Number of ENIs tagged for pods, by availability zone and subnet",
+		},
+		[]string{"availability_zone", "subnet_id"},
+	)
+
+	// ENITagHeadroom is the number of tags that can still be added to each ENI
+	// counted in TaggedENIs before the EC2 limit of 50, as of its last reconcile.
+	ENITagHeadroom = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "k8s_eni_tagger_eni_tag_headroom",
+			Help: "Number of tags that can still be added to a tagged ENI before the EC2 limit",
+		},
+		[]string{"eni_id"},
+	)
+
+	// AdmissionDeniedTotal counts pod create and update requests rejected by the
+	// admission webhook for invalid tag annotations.
+	AdmissionDeniedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_admission_denied_total",
+			Help: "Total number of pod requests denied by the admission webhook for invalid tag annotations",
+		},
+		[]string{"operation"},
+	)
+
+	// AdmissionDefaultedTotal counts pods created with default tags injected into
+	// their tag annotation by the mutating webhook.
+	AdmissionDefaultedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_admission_defaulted_total",
+			Help: "Total number of pods whose tag annotation the admission webhook added default tags to",
+		},
+	)
+
+	// DefaultTagsCanaryTotal counts default tags canaries by outcome, "promoted"
+	// or "rolled_back".
+	DefaultTagsCanaryTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_default_tags_canary_total",
+			Help: "Total number of default tags canaries promoted or rolled back, by result",
+		},
+		[]string{"result"},
+	)
+
+	// AWSMutationBudgetUsed is the number of CreateTags and DeleteTags calls made in
+	// the current budget window, and AWSMutationBudgetLimit the calls allowed per
+	// window. Both are only exported with --aws-mutation-budget.
+	AWSMutationBudgetUsed = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "k8s_eni_tagger_aws_mutation_budget_used",
+			Help: "Number of EC2 CreateTags and DeleteTags calls made in the current budget window",
+		},
+	)
+	AWSMutationBudgetLimit = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "k8s_eni_tagger_aws_mutation_budget_limit",
+			Help: "Number of EC2 CreateTags and DeleteTags calls allowed per budget window",
+		},
+	)
+
+	// AWSMutationBudgetDeferredTotal counts tag changes held back to the next window
+	// because the mutation budget was used up.
+	AWSMutationBudgetDeferredTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_aws_mutation_budget_deferred_total",
+			Help: "Total number of non-critical ENI tag changes deferred because the EC2 mutation budget was used up",
+		},
+	)
+
+	// SecurityGroupTaggingTotal counts security group tag changes by result:
+	// "applied", "removed", "conflict" (the group carries another tag set) or "error".
+	SecurityGroupTaggingTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_security_group_tagging_total",
+			Help: "Total number of security group tag changes by result",
+		},
+		[]string{"result"},
+	)
+
+	// VolumeTaggingTotal counts EBS volume tag changes by result, with the same
+	// results as SecurityGroupTaggingTotal.
+	VolumeTaggingTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_volume_tagging_total",
+			Help: "Total number of EBS volume tag changes by result",
+		},
+		[]string{"result"},
+	)
+
+	// AuditLogErrorsTotal counts audit records that could not be written.
+	AuditLogErrorsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "k8s_eni_tagger_audit_log_errors_total",
+			Help: "Total number of tag change audit records that failed to write",
+		},
+	)
 )
 
 func init() {
@@ -51,5 +348,37 @@ func init() {
 		CacheHitsTotal,
 		CacheMissesTotal,
 		CachePersistDroppedTotal,
+		CachePersistFencedTotal,
+		CacheIPChecksTotal,
+		AWSHealthStatus,
+		AWSHealthLastSuccess,
+		AWSHealthChecksTotal,
+		AWSHealthCheckLatency,
+		ReconcileConcurrencyLimit,
+		FairQueuePending,
+		ReconcileTriggersTotal,
+		TagBurstCallsSavedTotal,
+		PodWritesCollapsedTotal,
+		OwnershipReportsTotal,
+		TagSourceRequestsTotal,
+		TaggingPaused,
+		PodRateLimiters,
+		PodRateLimiterEvictionsTotal,
+		TaggingFailuresTotal,
+		DriftDetectedTotal,
+		DriftRepairedTotal,
+		ReadOnlyTagDrift,
+		ReadOnlyAuditsTotal,
+		TaggedENIs,
+		ENITagHeadroom,
+		AdmissionDeniedTotal,
+		AdmissionDefaultedTotal,
+		DefaultTagsCanaryTotal,
+		AWSMutationBudgetUsed,
+		AWSMutationBudgetLimit,
+		AWSMutationBudgetDeferredTotal,
+		AuditLogErrorsTotal,
+		SecurityGroupTaggingTotal,
+		VolumeTaggingTotal,
 	)
 }
@@ -0,0 +1,30 @@
+package metrics
+
+// Workqueue and reconciler metrics are exported by controller-runtime into the same
+// registry as the k8s_eni_tagger_* metrics. They are labelled with the controller
+// name (controller.ControllerName, "pod"): workqueue_* metrics use the "name" label,
+// controller_runtime_* metrics use the "controller" label.
+//
+// The names below are part of this project's monitoring contract (dashboards and the
+// alert thresholds in the README depend on them). TestWorkqueueMetricNames guards
+// the workqueue_* names against renames in controller-runtime upgrades.
+const (
+	// WorkqueueDepth is the number of pods waiting to be reconciled.
+	WorkqueueDepth = "workqueue_depth"
+	// WorkqueueAdds counts pods added to the queue.
+	WorkqueueAdds = "workqueue_adds_total"
+	// WorkqueueRetries counts rate-limited requeues (failed reconciles).
+	WorkqueueRetries = "workqueue_retries_total"
+	// WorkqueueQueueDuration is how long a pod waits in the queue before a worker picks it up.
+	WorkqueueQueueDuration = "workqueue_queue_duration_seconds"
+	// WorkqueueWorkDuration is how long a single reconcile takes.
+	WorkqueueWorkDuration = "workqueue_work_duration_seconds"
+	// WorkqueueUnfinishedWork is the total age of in-flight reconciles; steady growth means stuck workers.
+	WorkqueueUnfinishedWork = "workqueue_unfinished_work_seconds"
+	// WorkqueueLongestRunningProcessor is the age of the oldest in-flight reconcile.
+	WorkqueueLongestRunningProcessor = "workqueue_longest_running_processor_seconds"
+	// ActiveWorkers is the number of workers currently reconciling.
+	ActiveWorkers = "controller_runtime_active_workers"
+	// MaxConcurrentReconciles is the configured worker count.
+	MaxConcurrentReconciles = "controller_runtime_max_concurrent_reconciles"
+)
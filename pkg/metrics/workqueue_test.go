@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+func TestWorkqueueMetricNames(t *testing.T) {
+	// controller-runtime installs its workqueue metrics provider on import; a named
+	// queue therefore reports into metrics.Registry exactly like the pod controller's.
+	q := workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "metrics-contract-test")
+	defer q.ShutDown()
+	q.Add("item")
+	q.AddRateLimited("retried")
+	item, _ := q.Get()
+	q.Done(item)
+
+	families, err := metrics.Registry.Gather()
+	require.NoError(t, err)
+
+	found := map[string]bool{}
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "name" && l.GetValue() == "metrics-contract-test" {
+					found[mf.GetName()] = true
+				}
+			}
+		}
+	}
+
+	for _, name := range []string{
+		WorkqueueDepth,
+		WorkqueueAdds,
+		WorkqueueRetries,
+		WorkqueueQueueDuration,
+		WorkqueueWorkDuration,
+		WorkqueueUnfinishedWork,
+		WorkqueueLongestRunningProcessor,
+	} {
+		assert.True(t, found[name], "workqueue metric %s not exported", name)
+	}
+}
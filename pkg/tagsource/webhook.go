@@ -0,0 +1,182 @@
+// Package tagsource fetches tags for pods from sources outside the cluster.
+package tagsource
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"k8s-eni-tagger/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// maxResponseBytes caps the webhook response read into memory.
+const maxResponseBytes = 1 << 20
+
+// Request is the JSON body POSTed to the webhook. Annotations are left out:
+// the controller's own bookkeeping annotations change on every sync and would
+// defeat the cache.
+type Request struct {
+	Namespace          string            `json:"namespace"`
+	Name               string            `json:"name"`
+	UID                types.UID         `json:"uid"`
+	Labels             map[string]string `json:"labels,omitempty"`
+	NodeName           string            `json:"nodeName,omitempty"`
+	ServiceAccountName string            `json:"serviceAccountName,omitempty"`
+	Owners             []Owner           `json:"owners,omitempty"`
+}
+
+// Owner is one of the pod's owner references.
+type Owner struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// Response is the JSON body the webhook answers with.
+type Response struct {
+	Tags map[string]string `json:"tags"`
+}
+
+// Webhook asks an HTTP endpoint for a pod's tags. Answers are cached per pod
+// for the cache TTL, or until the pod's metadata sent to the webhook changes. When
+// the webhook fails, the last answer for the pod is used, however old, so an
+// outage does not strip tags from ENIs; pods without one get the error.
+type Webhook struct {
+	url      string
+	client   *http.Client
+	cacheTTL time.Duration
+
+	mu        sync.Mutex
+	cache     map[types.UID]cachedTags
+	lastSweep time.Time
+}
+
+// cachedTags is the last webhook answer for a pod.
+type cachedTags struct {
+	fingerprint [sha256.Size]byte
+	tags        map[string]string
+	fetched     time.Time
+}
+
+// NewWebhook returns a tag source calling url with the given request timeout.
+// A zero cacheTTL calls the webhook on every lookup.
+func NewWebhook(url string, timeout, cacheTTL time.Duration) (*Webhook, error) {
+	if timeout <= 0 {
+		return nil, fmt.Errorf("tag source webhook timeout must be positive, got %s", timeout)
+	}
+	if cacheTTL < 0 {
+		return nil, fmt.Errorf("tag source webhook cache TTL cannot be negative, got %s", cacheTTL)
+	}
+	return &Webhook{
+		url:      url,
+		client:   &http.Client{Timeout: timeout},
+		cacheTTL: cacheTTL,
+		cache:    make(map[types.UID]cachedTags),
+	}, nil
+}
+
+// Tags returns the webhook's tags for pod.
+func (w *Webhook) Tags(ctx context.Context, pod *corev1.Pod) (map[string]string, error) {
+	body, err := json.Marshal(newRequest(pod))
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := sha256.Sum256(body)
+	now := time.Now()
+
+	w.mu.Lock()
+	cached, ok := w.cache[pod.UID]
+	w.mu.Unlock()
+	if ok && cached.fingerprint == fingerprint && now.Sub(cached.fetched) < w.cacheTTL {
+		metrics.TagSourceRequestsTotal.WithLabelValues("cached").Inc()
+		return cached.tags, nil
+	}
+
+	tags, err := w.fetch(ctx, body)
+	if err != nil {
+		if ok {
+			metrics.TagSourceRequestsTotal.WithLabelValues("stale").Inc()
+			log.FromContext(ctx).Error(err, "Tag source webhook failed, using its last answer", "fetched", cached.fetched)
+			return cached.tags, nil
+		}
+		metrics.TagSourceRequestsTotal.WithLabelValues("error").Inc()
+		return nil, err
+	}
+	metrics.TagSourceRequestsTotal.WithLabelValues("success").Inc()
+
+	w.mu.Lock()
+	w.cache[pod.UID] = cachedTags{fingerprint: fingerprint, tags: tags, fetched: now}
+	w.sweepLocked(now)
+	w.mu.Unlock()
+	return tags, nil
+}
+
+// sweepLocked forgets answers not refreshed for an hour or ten TTLs, whichever
+// is longer; their pods are most likely gone. It runs at most once per period.
+// w.mu must be held.
+func (w *Webhook) sweepLocked(now time.Time) {
+	keep := max(10*w.cacheTTL, time.Hour)
+	if now.Sub(w.lastSweep) < keep {
+		return
+	}
+	w.lastSweep = now
+	for uid, cached := range w.cache {
+		if now.Sub(cached.fetched) > keep {
+			delete(w.cache, uid)
+		}
+	}
+}
+
+func (w *Webhook) fetch(ctx context.Context, body []byte) (map[string]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("tag source webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("tag source webhook: reading response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tag source webhook returned %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	var out Response
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("tag source webhook: invalid response: %w", err)
+	}
+	if out.Tags == nil {
+		out.Tags = map[string]string{}
+	}
+	return out.Tags, nil
+}
+
+func newRequest(pod *corev1.Pod) Request {
+	req := Request{
+		Namespace:          pod.Namespace,
+		Name:               pod.Name,
+		UID:                pod.UID,
+		Labels:             pod.Labels,
+		NodeName:           pod.Spec.NodeName,
+		ServiceAccountName: pod.Spec.ServiceAccountName,
+	}
+	for _, ref := range pod.OwnerReferences {
+		req.Owners = append(req.Owners, Owner{Kind: ref.Kind, Name: ref.Name})
+	}
+	return req
+}
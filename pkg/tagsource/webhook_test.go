@@ -0,0 +1,117 @@
+package tagsource
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testPod() *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "api-7d9f",
+			Namespace: "payments",
+			UID:       "uid-1",
+			Labels:    map[string]string{"app": "api"},
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "ReplicaSet", Name: "api-7d9f8"},
+			},
+		},
+		Spec: corev1.PodSpec{NodeName: "node-1", ServiceAccountName: "api"},
+	}
+}
+
+func TestWebhookTags(t *testing.T) {
+	var calls atomic.Int32
+	var failing atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if failing.Load() {
+			http.Error(w, "backend down", http.StatusServiceUnavailable)
+			return
+		}
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(Response{Tags: map[string]string{
+			"CostCenter": req.Labels["app"] + "-1234",
+			"Owner":      req.Owners[0].Kind + "/" + req.Owners[0].Name,
+		}})
+	}))
+	defer srv.Close()
+
+	w, err := NewWebhook(srv.URL, time.Second, time.Minute)
+	require.NoError(t, err)
+	ctx := context.Background()
+	pod := testPod()
+
+	want := map[string]string{"CostCenter": "api-1234", "Owner": "ReplicaSet/api-7d9f8"}
+	tags, err := w.Tags(ctx, pod)
+	require.NoError(t, err)
+	assert.Equal(t, want, tags)
+
+	// Cached within the TTL
+	_, err = w.Tags(ctx, pod)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+
+	// Changed metadata bypasses the cache
+	pod.Labels["app"] = "web"
+	tags, err = w.Tags(ctx, pod)
+	require.NoError(t, err)
+	assert.Equal(t, "web-1234", tags["CostCenter"])
+	assert.Equal(t, int32(2), calls.Load())
+
+	// Failures fall back to the last answer
+	pod.Labels["app"] = "worker"
+	failing.Store(true)
+	tags, err = w.Tags(ctx, pod)
+	require.NoError(t, err)
+	assert.Equal(t, "web-1234", tags["CostCenter"])
+
+	// Pods never answered for get the error
+	other := testPod()
+	other.UID = "uid-2"
+	_, err = w.Tags(ctx, other)
+	assert.ErrorContains(t, err, "503")
+}
+
+func TestWebhookTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	w, err := NewWebhook(srv.URL, 50*time.Millisecond, 0)
+	require.NoError(t, err)
+	_, err = w.Tags(context.Background(), testPod())
+	assert.Error(t, err)
+}
+
+func TestWebhookInvalidResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"tags": ["not", "a", "map"]}`))
+	}))
+	defer srv.Close()
+
+	w, err := NewWebhook(srv.URL, time.Second, 0)
+	require.NoError(t, err)
+	_, err = w.Tags(context.Background(), testPod())
+	assert.ErrorContains(t, err, "invalid response")
+
+	_, err = NewWebhook(srv.URL, 0, 0)
+	assert.Error(t, err)
+}
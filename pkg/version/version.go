@@ -0,0 +1,83 @@
+// Package version describes the running build for --version, in text or as JSON
+// that deployment tooling can check compatibility against.
+package version
+
+import (
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Modules are the dependencies whose versions are reported: the AWS SDK and the
+// Kubernetes client libraries the controller's behavior depends on.
+var Modules = []string{
+	"github.com/aws/aws-sdk-go-v2",
+	"github.com/aws/aws-sdk-go-v2/service/ec2",
+	"k8s.io/client-go",
+	"sigs.k8s.io/controller-runtime",
+}
+
+// Info describes a build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+	// Dependencies maps each of Modules to its version, or "unknown" when the
+	// binary carries no module information.
+	Dependencies map[string]string `json:"dependencies"`
+	// Features lists the flags of the optional features that are switched on.
+	Features []string `json:"features"`
+}
+
+// Get returns the Info of the running binary, with the version, commit and date
+// set at link time and the enabled features.
+func Get(version, commit, date string, features []string) Info {
+	bi, _ := debug.ReadBuildInfo()
+	if features == nil {
+		features = []string{}
+	}
+	return Info{
+		Version:      version,
+		Commit:       commit,
+		Date:         date,
+		GoVersion:    runtime.Version(),
+		Platform:     runtime.GOOS + "/" + runtime.GOARCH,
+		Dependencies: dependencies(bi),
+		Features:     features,
+	}
+}
+
+// dependencies looks up the versions of Modules in bi, which may be nil.
+func dependencies(bi *debug.BuildInfo) map[string]string {
+	deps := make(map[string]string, len(Modules))
+	for _, m := range Modules {
+		deps[m] = "unknown"
+	}
+	if bi == nil {
+		return deps
+	}
+	for _, dep := range bi.Deps {
+		if _, ok := deps[dep.Path]; !ok {
+			continue
+		}
+		version := dep.Version
+		if dep.Replace != nil {
+			version = dep.Replace.Version
+		}
+		deps[dep.Path] = version
+	}
+	return deps
+}
+
+// String returns the one-line text form.
+func (i Info) String() string {
+	return fmt.Sprintf("k8s-eni-tagger version=%s commit=%s date=%s", i.Version, i.Commit, i.Date)
+}
+
+// JSON returns the indented JSON form.
+func (i Info) JSON() ([]byte, error) {
+	return json.MarshalIndent(i, "", "  ")
+}
@@ -0,0 +1,42 @@
+package version
+
+import (
+	"encoding/json"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDependencies(t *testing.T) {
+	deps := dependencies(nil)
+	assert.Len(t, deps, len(Modules))
+	assert.Equal(t, "unknown", deps["k8s.io/client-go"])
+
+	deps = dependencies(&debug.BuildInfo{Deps: []*debug.Module{
+		{Path: "github.com/aws/aws-sdk-go-v2", Version: "v1.40.0"},
+		{Path: "k8s.io/client-go", Version: "v0.28.4", Replace: &debug.Module{Path: "example.com/client-go", Version: "v0.28.5"}},
+		{Path: "github.com/spf13/viper", Version: "v1.17.0"},
+	}})
+	assert.Equal(t, map[string]string{
+		"github.com/aws/aws-sdk-go-v2":             "v1.40.0",
+		"github.com/aws/aws-sdk-go-v2/service/ec2": "unknown",
+		"k8s.io/client-go":                         "v0.28.5",
+		"sigs.k8s.io/controller-runtime":           "unknown",
+	}, deps)
+}
+
+func TestInfo(t *testing.T) {
+	info := Get("v1.2.3", "abc123", "2026-10-16T00:00:00Z", nil)
+	assert.Equal(t, "k8s-eni-tagger version=v1.2.3 commit=abc123 date=2026-10-16T00:00:00Z", info.String())
+
+	data, err := info.JSON()
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "v1.2.3", decoded["version"])
+	assert.NotEmpty(t, decoded["goVersion"])
+	assert.Equal(t, []any{}, decoded["features"], "no features is an empty list, not null")
+	assert.Len(t, decoded["dependencies"], len(Modules))
+}
@@ -0,0 +1,171 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"k8s-eni-tagger/pkg/controller"
+	"k8s-eni-tagger/pkg/metrics"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// DefaultTagsCanaryControllerName names the controller deciding default tags canaries.
+const DefaultTagsCanaryControllerName = "default-tags-canary"
+
+// canaryCheckInterval is how often a running canary's pods are checked.
+const canaryCheckInterval = 30 * time.Second
+
+// Canary results, recorded on the ConfigMap and in DefaultTagsCanaryTotal.
+const (
+	CanaryPromoted   = "Promoted"
+	CanaryRolledBack = "RolledBack"
+)
+
+// CanaryResult is the JSON recorded in the DefaultTagsCanaryResult annotation of
+// the default tags ConfigMap when a canary ends.
+type CanaryResult struct {
+	Revision  string `json:"revision"`
+	Result    string `json:"result"`
+	Tags      string `json:"tags"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	Time      string `json:"time"`
+}
+
+// DefaultTagsCanaryReconciler decides the canary in the default tags ConfigMap
+// from the tagging condition of the pods given its tags at admission. Pods whose
+// tags were applied count as successes; pods whose tags were invalid, or that AWS
+// or a tag policy refused, as failures. Other conditions (lookups, pauses,
+// deferrals) do not count. Once more than MaxFailureRatio of Pods have failed,
+// the canary is rolled back by removing it from the ConfigMap; once Pods have a
+// result without that, its tags are promoted to the "tags" key. It runs on the
+// leader only, and all of its state is in the ConfigMap and on the pods.
+type DefaultTagsCanaryReconciler struct {
+	client.Client
+
+	// ConfigMap is the default tags ConfigMap.
+	ConfigMap types.NamespacedName
+	// Keys names the canary pod annotation, result annotation and tagged condition.
+	Keys controller.Keys
+	// Validator parses the canary tags as the defaulter does.
+	Validator controller.TagAnnotationValidator
+	// Pods is the number of canary pods with a result needed to promote.
+	Pods int
+	// MaxFailureRatio is the share of Pods allowed to fail.
+	MaxFailureRatio float64
+}
+
+// Reconcile checks the canary pods and promotes or rolls back the canary once
+// they decide it, or checks again after canaryCheckInterval.
+func (r *DefaultTagsCanaryReconciler) Reconcile(ctx context.Context, _ ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx).WithValues("configMap", r.ConfigMap)
+
+	cm := &corev1.ConfigMap{}
+	if err := r.Get(ctx, r.ConfigMap, cm); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	raw := cm.Data[DefaultTagsCanaryConfigMapKey]
+	parsed, err := ParseDefaultTags(raw, "", r.Validator)
+	if err != nil || len(parsed.Tags) == 0 {
+		// The defaulter ignores it too; editing the ConfigMap triggers a new reconcile
+		return ctrl.Result{}, nil
+	}
+	revision := canaryRevision(parsed.Tags)
+
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods); err != nil {
+		return ctrl.Result{}, err
+	}
+	succeeded, failed := 0, 0
+	for i := range pods.Items {
+		if pods.Items[i].Annotations[r.Keys.DefaultTagsCanary] != revision {
+			continue
+		}
+		if decided, ok := r.podOutcome(&pods.Items[i]); decided && ok {
+			succeeded++
+		} else if decided {
+			failed++
+		}
+	}
+
+	result := ""
+	switch {
+	case float64(failed) > r.MaxFailureRatio*float64(r.Pods):
+		result = CanaryRolledBack
+	case succeeded+failed >= r.Pods:
+		result = CanaryPromoted
+	default:
+		logger.V(1).Info("Default tags canary running", "revision", revision, "succeeded", succeeded, "failed", failed, "pods", r.Pods)
+		return ctrl.Result{RequeueAfter: canaryCheckInterval}, nil
+	}
+
+	patch := client.MergeFromWithOptions(cm.DeepCopy(), client.MergeFromWithOptimisticLock{})
+	if result == CanaryPromoted {
+		cm.Data[DefaultTagsConfigMapKey] = raw
+	}
+	delete(cm.Data, DefaultTagsCanaryConfigMapKey)
+	delete(cm.Data, DefaultTagsCanaryPercentConfigMapKey)
+	record, err := json.Marshal(CanaryResult{
+		Revision:  revision,
+		Result:    result,
+		Tags:      raw,
+		Succeeded: succeeded,
+		Failed:    failed,
+		Time:      time.Now().UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if cm.Annotations == nil {
+		cm.Annotations = make(map[string]string)
+	}
+	cm.Annotations[r.Keys.DefaultTagsCanaryResult] = string(record)
+	if err := r.Patch(ctx, cm, patch); err != nil {
+		// On a conflict the ConfigMap was edited meanwhile; the retry decides again
+		return ctrl.Result{}, err
+	}
+
+	if result == CanaryPromoted {
+		logger.Info("Promoted default tags canary", "revision", revision, "succeeded", succeeded, "failed", failed)
+		metrics.DefaultTagsCanaryTotal.WithLabelValues("promoted").Inc()
+	} else {
+		logger.Info("Rolled back default tags canary", "revision", revision, "succeeded", succeeded, "failed", failed)
+		metrics.DefaultTagsCanaryTotal.WithLabelValues("rolled_back").Inc()
+	}
+	return ctrl.Result{}, nil
+}
+
+// podOutcome reports whether the pod's tags were applied or refused (decided),
+// and which of the two (ok).
+func (r *DefaultTagsCanaryReconciler) podOutcome(pod *corev1.Pod) (decided, ok bool) {
+	for _, c := range pod.Status.Conditions {
+		if string(c.Type) != r.Keys.ConditionType {
+			continue
+		}
+		switch controller.ConditionReason(c.Reason) {
+		case controller.ReasonSynced, controller.ReasonBestEffortTaggingFailed:
+			return true, true
+		case controller.ReasonInvalidTags, controller.ReasonTaggingFailed, controller.ReasonTagPolicyViolation:
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// SetupWithManager watches the default tags ConfigMap on the leader.
+func (r *DefaultTagsCanaryReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(DefaultTagsCanaryControllerName).
+		For(&corev1.ConfigMap{}, builder.WithPredicates(predicate.NewPredicateFuncs(func(o client.Object) bool {
+			return o.GetNamespace() == r.ConfigMap.Namespace && o.GetName() == r.ConfigMap.Name
+		}))).
+		Complete(r)
+}
@@ -0,0 +1,141 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"k8s-eni-tagger/pkg/controller"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestDefaultTagsCanaryReconciler(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	key := types.NamespacedName{Namespace: "kube-system", Name: "default-tags"}
+	keys := controller.NewKeys("")
+	const canaryTags = "team=platform,cost-center=42"
+	revision := canaryRevision(map[string]string{"team": "platform", "cost-center": "42"})
+
+	// pods returns n canary pods of revision rev whose tagged condition has reason.
+	pods := func(rev string, reason controller.ConditionReason, n int) []client.Object {
+		var objs []client.Object
+		for i := 0; i < n; i++ {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "default",
+				Name:        fmt.Sprintf("%s-%s-%d", rev, reason, i),
+				Annotations: map[string]string{keys.DefaultTagsCanary: rev},
+			}}
+			if reason != "" {
+				pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodConditionType(keys.ConditionType), Reason: string(reason)}}
+			}
+			objs = append(objs, pod)
+		}
+		return objs
+	}
+	concat := func(groups ...[]client.Object) []client.Object {
+		var objs []client.Object
+		for _, g := range groups {
+			objs = append(objs, g...)
+		}
+		return objs
+	}
+
+	tests := []struct {
+		name       string
+		data       map[string]string
+		pods       []client.Object
+		wantResult string
+		wantTags   string
+		wantWait   bool
+	}{
+		{
+			name:     "waits for enough pods",
+			data:     map[string]string{"tags": "team=platform", "canaryTags": canaryTags},
+			pods:     concat(pods(revision, controller.ReasonSynced, 4), pods(revision, controller.ReasonENILookupFailed, 3), pods(revision, "", 2)),
+			wantTags: "team=platform",
+			wantWait: true,
+		},
+		{
+			name:     "pods of another canary do not count",
+			data:     map[string]string{"tags": "team=platform", "canaryTags": canaryTags},
+			pods:     concat(pods(revision, controller.ReasonSynced, 2), pods("0123456789abcdef", controller.ReasonSynced, 5)),
+			wantTags: "team=platform",
+			wantWait: true,
+		},
+		{
+			name:       "promotes once enough pods are tagged",
+			data:       map[string]string{"tags": "team=platform", "canaryTags": canaryTags, "canaryPercent": "50"},
+			pods:       concat(pods(revision, controller.ReasonSynced, 3), pods(revision, controller.ReasonBestEffortTaggingFailed, 1), pods(revision, controller.ReasonTaggingFailed, 1)),
+			wantResult: CanaryPromoted,
+			wantTags:   canaryTags,
+		},
+		{
+			name:       "rolls back once too many pods fail",
+			data:       map[string]string{"tags": "team=platform", "canaryTags": canaryTags},
+			pods:       concat(pods(revision, controller.ReasonTagPolicyViolation, 1), pods(revision, controller.ReasonInvalidTags, 1)),
+			wantResult: CanaryRolledBack,
+			wantTags:   "team=platform",
+		},
+		{
+			name:     "no canary",
+			data:     map[string]string{"tags": "team=platform"},
+			pods:     pods(revision, controller.ReasonTaggingFailed, 5),
+			wantTags: "team=platform",
+		},
+		{
+			name:     "invalid canary tags are left alone",
+			data:     map[string]string{"tags": "team=platform", "canaryTags": "aws:x=y"},
+			pods:     pods(canaryRevision(map[string]string{"aws:x": "y"}), controller.ReasonSynced, 5),
+			wantTags: "team=platform",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := append([]client.Object{&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+				Data:       tt.data,
+			}}, tt.pods...)
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+			r := &DefaultTagsCanaryReconciler{Client: c, ConfigMap: key, Keys: keys, Pods: 5, MaxFailureRatio: 0.2}
+
+			res, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+			require.NoError(t, err)
+			if tt.wantWait {
+				assert.Equal(t, canaryCheckInterval, res.RequeueAfter)
+			} else {
+				assert.Zero(t, res.RequeueAfter)
+			}
+
+			cm := &corev1.ConfigMap{}
+			require.NoError(t, c.Get(context.Background(), key, cm))
+			assert.Equal(t, tt.wantTags, cm.Data["tags"])
+			record, recorded := cm.Annotations[keys.DefaultTagsCanaryResult]
+			if tt.wantResult == "" {
+				assert.False(t, recorded)
+				assert.Equal(t, tt.data["canaryTags"], cm.Data["canaryTags"])
+				return
+			}
+			assert.NotContains(t, cm.Data, "canaryTags")
+			assert.NotContains(t, cm.Data, "canaryPercent")
+			var result CanaryResult
+			require.NoError(t, json.Unmarshal([]byte(record), &result))
+			assert.Equal(t, revision, result.Revision)
+			assert.Equal(t, tt.wantResult, result.Result)
+			assert.Equal(t, canaryTags, result.Tags)
+			_, err = time.Parse(time.RFC3339, result.Time)
+			assert.NoError(t, err)
+		})
+	}
+}
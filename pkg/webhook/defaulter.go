@@ -0,0 +1,283 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"k8s-eni-tagger/pkg/controller"
+	"k8s-eni-tagger/pkg/metrics"
+
+	"github.com/go-logr/logr"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// DefaultPath is the path the pod mutating webhook is served on.
+const DefaultPath = "/default-pod-tags"
+
+const (
+	// DefaultTagsConfigMapKey is the ConfigMap data key holding default tags, in
+	// either tag annotation format.
+	DefaultTagsConfigMapKey = "tags"
+	// DefaultTagsSelectorConfigMapKey is the ConfigMap data key holding the label
+	// selector of the pods default tags are added to.
+	DefaultTagsSelectorConfigMapKey = "selector"
+	// DefaultTagsCanaryConfigMapKey is the ConfigMap data key holding new default
+	// tags tried on a share of new pods before they replace the "tags" key.
+	DefaultTagsCanaryConfigMapKey = "canaryTags"
+	// DefaultTagsCanaryPercentConfigMapKey is the ConfigMap data key holding the
+	// percentage of new pods given the canary tags.
+	DefaultTagsCanaryPercentConfigMapKey = "canaryPercent"
+)
+
+// DefaultTags are tags added to the annotation of new pods matching Selector.
+// The zero value adds nothing.
+type DefaultTags struct {
+	Tags     map[string]string
+	Selector labels.Selector
+	// Canary, when set, replaces Tags for a share of the pods.
+	Canary *DefaultTagsCanary
+}
+
+// DefaultTagsCanary are default tags given to Percent of the new pods matching
+// the selector instead of the current ones, until DefaultTagsCanaryReconciler
+// promotes or rolls them back. Revision identifies the tags on the pods given them.
+type DefaultTagsCanary struct {
+	Tags     map[string]string
+	Revision string
+	Percent  int
+}
+
+// canaryRevision identifies a set of canary tags. json.Marshal sorts map keys,
+// so equal tags give the same revision on every replica.
+func canaryRevision(tags map[string]string) string {
+	// Marshalling a map of strings cannot fail
+	data, _ := json.Marshal(tags)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// inCanary reports whether the admission request uid falls in the percent of
+// requests given canary tags.
+func inCanary(uid types.UID, percent int) bool {
+	h := fnv.New32a()
+	h.Write([]byte(uid))
+	return int(h.Sum32()%100) < percent
+}
+
+// ParseDefaultTags parses tags in either tag annotation format and a label
+// selector, where an empty selector matches every pod. The tags must pass
+// validator; empty tags add nothing.
+func ParseDefaultTags(tags, selector string, validator controller.TagAnnotationValidator) (DefaultTags, error) {
+	parsed, err := controller.ParseTagAnnotation(tags)
+	if err != nil {
+		return DefaultTags{}, err
+	}
+	if len(parsed) == 0 {
+		return DefaultTags{}, nil
+	}
+	if err := validator.Validate(tags); err != nil {
+		return DefaultTags{}, err
+	}
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return DefaultTags{}, fmt.Errorf("invalid selector %q: %w", selector, err)
+	}
+	return DefaultTags{Tags: parsed, Selector: sel}, nil
+}
+
+// PodDefaulter adds default tags to the tag annotation of pods matching a
+// selector when they are created, so workloads are tagged without every
+// Deployment setting the annotation. Tags the pod sets itself win, and pods whose
+// annotation cannot be parsed, or would be invalid with the defaults, are left
+// for the validating webhook and the reconciler to report. Updates are never
+// changed, so removing a default tag from a running pod sticks.
+type PodDefaulter struct {
+	annotationKey string
+	defaults      DefaultTags
+	validator     controller.TagAnnotationValidator
+	decoder       *admission.Decoder
+
+	// reader and configMap, when set, supply defaults overriding the static ones
+	reader    client.Reader
+	configMap types.NamespacedName
+
+	// canaryKey, when set, enables canaries from the ConfigMap and marks the
+	// pods given canary tags; canaryPercent is used without a canaryPercent key.
+	canaryKey     string
+	canaryPercent int
+}
+
+var _ admission.Handler = (*PodDefaulter)(nil)
+
+// NewPodDefaulter returns a defaulter adding defaults to the annotationKey
+// annotation, checking the result with validator's settings. scheme must know
+// corev1 pods.
+func NewPodDefaulter(scheme *runtime.Scheme, annotationKey string, defaults DefaultTags, validator controller.TagAnnotationValidator) *PodDefaulter {
+	return &PodDefaulter{
+		annotationKey: annotationKey,
+		defaults:      defaults,
+		validator:     validator,
+		decoder:       admission.NewDecoder(scheme),
+	}
+}
+
+// WithConfigMap reads the defaults from the "tags" and "selector" keys of the
+// named ConfigMap on every request, so they can be changed without a restart.
+// Each key present overrides the static default; while the ConfigMap is missing
+// or invalid, the static defaults apply.
+func (d *PodDefaulter) WithConfigMap(reader client.Reader, key types.NamespacedName) *PodDefaulter {
+	d.reader = reader
+	d.configMap = key
+	return d
+}
+
+// WithCanary enables canaries from the ConfigMap's "canaryTags" key: percent of
+// the new pods (unless its "canaryPercent" key says otherwise) get those tags
+// instead of the current defaults, and the annotationKey annotation with the
+// canary's revision. Pods are picked by admission request UID.
+func (d *PodDefaulter) WithCanary(annotationKey string, percent int) *PodDefaulter {
+	d.canaryKey = annotationKey
+	d.canaryPercent = percent
+	return d
+}
+
+// Handle implements admission.Handler.
+func (d *PodDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create {
+		return admission.Allowed("")
+	}
+	pod := &corev1.Pod{}
+	if err := d.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	logger := log.FromContext(ctx).WithValues("pod", req.Namespace+"/"+podName(req, pod))
+
+	defaults := d.currentDefaults(ctx)
+	add, canary := defaults.Tags, ""
+	if c := defaults.Canary; c != nil && inCanary(req.UID, c.Percent) {
+		add, canary = c.Tags, c.Revision
+	}
+	if len(add) == 0 || (defaults.Selector != nil && !defaults.Selector.Matches(labels.Set(pod.Labels))) {
+		return admission.Allowed("")
+	}
+
+	tags, err := controller.ParseTagAnnotation(pod.Annotations[d.annotationKey])
+	if err != nil {
+		return admission.Allowed("")
+	}
+	added := 0
+	for key, value := range add {
+		if _, ok := tags[key]; !ok {
+			tags[key] = value
+			added++
+		}
+	}
+	if added == 0 {
+		return admission.Allowed("")
+	}
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if err := d.validator.Validate(string(data)); err != nil {
+		logger.Info("Not adding default tags that would make the tag annotation invalid", "error", err.Error())
+		return admission.Allowed("")
+	}
+
+	if pod.Annotations == nil {
+		pod.Annotations = make(map[string]string)
+	}
+	pod.Annotations[d.annotationKey] = string(data)
+	if canary != "" {
+		pod.Annotations[d.canaryKey] = canary
+	}
+	marshaled, err := json.Marshal(pod)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	logger.V(1).Info("Added default tags", "added", added, "canary", canary)
+	metrics.AdmissionDefaultedTotal.Inc()
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// currentDefaults returns the static defaults, overridden by the ConfigMap's
+// keys when one is configured and readable.
+func (d *PodDefaulter) currentDefaults(ctx context.Context) DefaultTags {
+	if d.reader == nil {
+		return d.defaults
+	}
+	logger := log.FromContext(ctx).WithValues("configMap", d.configMap)
+	cm := &corev1.ConfigMap{}
+	if err := d.reader.Get(ctx, d.configMap, cm); err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to read default tags ConfigMap, using static defaults")
+		}
+		return d.defaults
+	}
+
+	tags, hasTags := cm.Data[DefaultTagsConfigMapKey]
+	selector, hasSelector := cm.Data[DefaultTagsSelectorConfigMapKey]
+	if !hasTags && !hasSelector {
+		return d.defaults
+	}
+	defaults := d.defaults
+	if hasTags {
+		parsed, err := ParseDefaultTags(tags, "", d.validator)
+		if err != nil {
+			logger.Error(err, "Invalid default tags in ConfigMap, using static defaults")
+			return d.defaults
+		}
+		defaults.Tags = parsed.Tags
+	}
+	if hasSelector {
+		sel, err := labels.Parse(strings.TrimSpace(selector))
+		if err != nil {
+			logger.Error(err, "Invalid default tags selector in ConfigMap, using static defaults")
+			return d.defaults
+		}
+		defaults.Selector = sel
+	}
+	if d.canaryKey != "" {
+		defaults.Canary = d.currentCanary(logger, cm)
+	}
+	return defaults
+}
+
+// currentCanary returns the canary in the ConfigMap, or nil when there is none
+// or its tags are invalid. An invalid percentage falls back to the default one.
+func (d *PodDefaulter) currentCanary(logger logr.Logger, cm *corev1.ConfigMap) *DefaultTagsCanary {
+	parsed, err := ParseDefaultTags(cm.Data[DefaultTagsCanaryConfigMapKey], "", d.validator)
+	if err != nil {
+		logger.Error(err, "Invalid canary default tags in ConfigMap, ignoring them")
+		return nil
+	}
+	if len(parsed.Tags) == 0 {
+		return nil
+	}
+	canary := &DefaultTagsCanary{Tags: parsed.Tags, Revision: canaryRevision(parsed.Tags), Percent: d.canaryPercent}
+	if value, ok := cm.Data[DefaultTagsCanaryPercentConfigMapKey]; ok {
+		percent, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || percent < 1 || percent > 100 {
+			logger.Error(fmt.Errorf("invalid %s %q: must be between 1 and 100", DefaultTagsCanaryPercentConfigMapKey, value), "Using the default canary percentage", "percent", d.canaryPercent)
+		} else {
+			canary.Percent = percent
+		}
+	}
+	return canary
+}
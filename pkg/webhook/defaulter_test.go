@@ -0,0 +1,233 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s-eni-tagger/pkg/controller"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func createRequest(t *testing.T, podLabels map[string]string, annotation *string) admission.Request {
+	t.Helper()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{GenerateName: "web-", Namespace: "default", Labels: podLabels}}
+	if annotation != nil {
+		pod.Annotations = map[string]string{controller.AnnotationKey: *annotation}
+	}
+	data, err := json.Marshal(pod)
+	require.NoError(t, err)
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Namespace: "default",
+		Object:    runtime.RawExtension{Raw: data},
+	}}
+}
+
+// patchedTags returns the tag annotation set by resp's patch, or nil if it does
+// not set one.
+func patchedTags(t *testing.T, resp admission.Response) map[string]string {
+	t.Helper()
+	for _, op := range resp.Patches {
+		var value string
+		switch {
+		case op.Path == "/metadata/annotations":
+			value = op.Value.(map[string]any)[controller.AnnotationKey].(string)
+		case op.Path == "/metadata/annotations/"+strings.ReplaceAll(controller.AnnotationKey, "/", "~1"):
+			value = op.Value.(string)
+		default:
+			continue
+		}
+		tags := make(map[string]string)
+		require.NoError(t, json.Unmarshal([]byte(value), &tags))
+		return tags
+	}
+	return nil
+}
+
+func TestPodDefaulter(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	defaults, err := ParseDefaultTags("team=platform,env=prod", "tier=web", controller.TagAnnotationValidator{})
+	require.NoError(t, err)
+	d := NewPodDefaulter(scheme, controller.AnnotationKey, defaults, controller.TagAnnotationValidator{})
+	web := map[string]string{"tier": "web"}
+	own, malformed := `{"team":"payments"}`, `{"team":`
+	overridden := `{"team":"payments","env":"staging"}`
+
+	tests := []struct {
+		name       string
+		labels     map[string]string
+		annotation *string
+		want       map[string]string
+	}{
+		{name: "no annotation", labels: web, want: map[string]string{"team": "platform", "env": "prod"}},
+		{name: "pod tags win", labels: web, annotation: &own, want: map[string]string{"team": "payments", "env": "prod"}},
+		{name: "selector does not match", labels: map[string]string{"tier": "db"}},
+		{name: "malformed annotation left alone", labels: web, annotation: &malformed},
+		{name: "all defaults already set", labels: web, annotation: &overridden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := d.Handle(context.Background(), createRequest(t, tt.labels, tt.annotation))
+			require.True(t, resp.Allowed, resp.Result)
+			assert.Equal(t, tt.want, patchedTags(t, resp))
+		})
+	}
+
+	t.Run("updates are not changed", func(t *testing.T) {
+		req := createRequest(t, web, nil)
+		req.Operation = admissionv1.Update
+		resp := d.Handle(context.Background(), req)
+		assert.True(t, resp.Allowed)
+		assert.Empty(t, resp.Patches)
+	})
+}
+
+func TestPodDefaulterInvalidResult(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	defaults, err := ParseDefaultTags("Team=platform", "", controller.TagAnnotationValidator{})
+	require.NoError(t, err)
+	validator := controller.TagAnnotationValidator{TagKeyCase: controller.TagKeyCaseReject}
+	d := NewPodDefaulter(scheme, controller.AnnotationKey, defaults, validator)
+
+	// Adding Team next to team would be refused as a case conflict
+	own := `{"team":"payments"}`
+	resp := d.Handle(context.Background(), createRequest(t, nil, &own))
+	assert.True(t, resp.Allowed)
+	assert.Empty(t, resp.Patches)
+}
+
+func TestPodDefaulterConfigMap(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	defaults, err := ParseDefaultTags("team=platform", "", controller.TagAnnotationValidator{})
+	require.NoError(t, err)
+	key := types.NamespacedName{Namespace: "kube-system", Name: "default-tags"}
+
+	tests := []struct {
+		name string
+		data map[string]string
+		want map[string]string
+	}{
+		{name: "missing ConfigMap keeps static defaults", want: map[string]string{"team": "platform"}},
+		{name: "tags override", data: map[string]string{"tags": `{"owner":"sre"}`}, want: map[string]string{"owner": "sre"}},
+		{name: "selector overrides", data: map[string]string{"selector": "tier=db"}},
+		{name: "invalid tags keep static defaults", data: map[string]string{"tags": "aws:x=y"}, want: map[string]string{"team": "platform"}},
+		{name: "empty tags disable defaults", data: map[string]string{"tags": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := fake.NewClientBuilder().WithScheme(scheme)
+			if tt.data != nil {
+				builder = builder.WithObjects(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+					Data:       tt.data,
+				})
+			}
+			d := NewPodDefaulter(scheme, controller.AnnotationKey, defaults, controller.TagAnnotationValidator{}).
+				WithConfigMap(builder.Build(), key)
+
+			resp := d.Handle(context.Background(), createRequest(t, map[string]string{"tier": "web"}, nil))
+			require.True(t, resp.Allowed, resp.Result)
+			assert.Equal(t, tt.want, patchedTags(t, resp))
+		})
+	}
+}
+
+func TestParseDefaultTags(t *testing.T) {
+	defaults, err := ParseDefaultTags("", "tier=web", controller.TagAnnotationValidator{})
+	require.NoError(t, err)
+	assert.Empty(t, defaults.Tags)
+
+	_, err = ParseDefaultTags("aws:Name=web", "", controller.TagAnnotationValidator{})
+	assert.Error(t, err)
+
+	_, err = ParseDefaultTags("team=platform", "tier in (web", controller.TagAnnotationValidator{})
+	assert.Error(t, err)
+}
+
+func TestPodDefaulterCanary(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	key := types.NamespacedName{Namespace: "kube-system", Name: "default-tags"}
+	canaryTags := map[string]string{"team": "platform", "cost-center": "42"}
+
+	// canaryAnnotation returns the canary annotation set by resp's patch, if any.
+	canaryAnnotation := func(resp admission.Response) string {
+		for _, op := range resp.Patches {
+			switch op.Path {
+			case "/metadata/annotations":
+				value, _ := op.Value.(map[string]any)[controller.DefaultTagsCanaryAnnotationKey].(string)
+				return value
+			case "/metadata/annotations/" + strings.ReplaceAll(controller.DefaultTagsCanaryAnnotationKey, "/", "~1"):
+				return op.Value.(string)
+			}
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name       string
+		data       map[string]string
+		percent    int
+		wantCanary int
+	}{
+		{name: "default percentage", data: map[string]string{"tags": "team=platform", "canaryTags": "team=platform,cost-center=42"}, percent: 30, wantCanary: 30},
+		{name: "percentage from ConfigMap", data: map[string]string{"tags": "team=platform", "canaryTags": "team=platform,cost-center=42", "canaryPercent": "100"}, percent: 30, wantCanary: 100},
+		{name: "invalid percentage uses the default", data: map[string]string{"tags": "team=platform", "canaryTags": "team=platform,cost-center=42", "canaryPercent": "0"}, percent: 30, wantCanary: 30},
+		{name: "invalid canary tags are ignored", data: map[string]string{"tags": "team=platform", "canaryTags": "aws:x=y", "canaryPercent": "100"}, percent: 30},
+		{name: "no canary", data: map[string]string{"tags": "team=platform"}, percent: 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+				Data:       tt.data,
+			}).Build()
+			d := NewPodDefaulter(scheme, controller.AnnotationKey, DefaultTags{}, controller.TagAnnotationValidator{}).
+				WithConfigMap(c, key).
+				WithCanary(controller.DefaultTagsCanaryAnnotationKey, tt.percent)
+
+			const requests = 1000
+			canaries := 0
+			for i := 0; i < requests; i++ {
+				req := createRequest(t, nil, nil)
+				req.UID = types.UID(fmt.Sprintf("request-%d", i))
+				resp := d.Handle(context.Background(), req)
+				require.True(t, resp.Allowed, resp.Result)
+				if revision := canaryAnnotation(resp); revision != "" {
+					assert.Equal(t, canaryRevision(canaryTags), revision)
+					assert.Equal(t, canaryTags, patchedTags(t, resp))
+					canaries++
+				} else {
+					assert.Equal(t, map[string]string{"team": "platform"}, patchedTags(t, resp))
+				}
+			}
+			assert.InDelta(t, tt.wantCanary, canaries*100/requests, 5)
+		})
+	}
+
+	t.Run("disabled without WithCanary", func(t *testing.T) {
+		c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			Data:       map[string]string{"tags": "team=platform", "canaryTags": "owner=sre", "canaryPercent": "100"},
+		}).Build()
+		d := NewPodDefaulter(scheme, controller.AnnotationKey, DefaultTags{}, controller.TagAnnotationValidator{}).WithConfigMap(c, key)
+		resp := d.Handle(context.Background(), createRequest(t, nil, nil))
+		assert.Equal(t, map[string]string{"team": "platform"}, patchedTags(t, resp))
+		assert.Empty(t, canaryAnnotation(resp))
+	})
+}
@@ -0,0 +1,90 @@
+// Package webhook serves the admission webhooks for pod tag annotations: one
+// rejects pods with invalid annotations when they are created or updated, instead
+// of leaving the reconciler to report them on the pod's condition afterwards, and
+// one adds default tags to the annotation of new pods.
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"k8s-eni-tagger/pkg/controller"
+	"k8s-eni-tagger/pkg/metrics"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ValidatePath is the path the pod validating webhook is served on.
+const ValidatePath = "/validate-pod-tags"
+
+// PodValidator denies pod create and update requests whose tag annotation the
+// reconciler would reject: malformed JSON or key=value pairs, reserved key
+// prefixes, too many tags, keys or values too long or with invalid characters.
+//
+// Updates that leave the annotation unchanged are always allowed, so pods
+// created before the webhook, or while it was unavailable, can still have their
+// finalizer removed and their status updated. An empty annotation is allowed too,
+// since pods may be tagged from their labels alone.
+type PodValidator struct {
+	annotationKey string
+	validator     controller.TagAnnotationValidator
+	decoder       *admission.Decoder
+}
+
+var _ admission.Handler = (*PodValidator)(nil)
+
+// NewPodValidator returns a validator of the annotationKey annotation, checked
+// with validator's settings. scheme must know corev1 pods.
+func NewPodValidator(scheme *runtime.Scheme, annotationKey string, validator controller.TagAnnotationValidator) *PodValidator {
+	return &PodValidator{
+		annotationKey: annotationKey,
+		validator:     validator,
+		decoder:       admission.NewDecoder(scheme),
+	}
+}
+
+// Handle implements admission.Handler.
+func (v *PodValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	pod := &corev1.Pod{}
+	if err := v.decoder.Decode(req, pod); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	value, ok := pod.Annotations[v.annotationKey]
+	if !ok || strings.TrimSpace(value) == "" {
+		return admission.Allowed("")
+	}
+	if req.Operation == admissionv1.Update {
+		oldPod := &corev1.Pod{}
+		if err := v.decoder.DecodeRaw(req.OldObject, oldPod); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if oldValue, ok := oldPod.Annotations[v.annotationKey]; ok && oldValue == value {
+			return admission.Allowed("")
+		}
+	}
+
+	if err := v.validator.Validate(value); err != nil {
+		log.FromContext(ctx).Info("Denied pod with invalid tag annotation", "pod", req.Namespace+"/"+podName(req, pod), "operation", req.Operation, "error", err.Error())
+		metrics.AdmissionDeniedTotal.WithLabelValues(string(req.Operation)).Inc()
+		return admission.Denied(fmt.Sprintf("invalid %s annotation: %v", v.annotationKey, err))
+	}
+	return admission.Allowed("")
+}
+
+// podName returns the pod's name, which pods created from a generateName only
+// get after admission.
+func podName(req admission.Request, pod *corev1.Pod) string {
+	if req.Name != "" {
+		return req.Name
+	}
+	if pod.Name != "" {
+		return pod.Name
+	}
+	return pod.GenerateName + "*"
+}
@@ -0,0 +1,89 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"k8s-eni-tagger/pkg/controller"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func podRequest(t *testing.T, op admissionv1.Operation, annotation string, oldAnnotation *string) admission.Request {
+	t.Helper()
+	raw := func(value *string) runtime.RawExtension {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+		if value != nil {
+			pod.Annotations = map[string]string{controller.AnnotationKey: *value}
+		}
+		data, err := json.Marshal(pod)
+		require.NoError(t, err)
+		return runtime.RawExtension{Raw: data}
+	}
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: op,
+		Name:      "web",
+		Namespace: "default",
+		Object:    raw(&annotation),
+	}}
+	if op == admissionv1.Update {
+		req.OldObject = raw(oldAnnotation)
+	}
+	return req
+}
+
+func TestPodValidator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	v := NewPodValidator(scheme, controller.AnnotationKey, controller.TagAnnotationValidator{})
+	valid, invalid := `{"team":"platform"}`, `{"aws:Name":"web"}`
+
+	tests := []struct {
+		name          string
+		op            admissionv1.Operation
+		annotation    string
+		oldAnnotation *string
+		allowed       bool
+	}{
+		{name: "valid JSON", op: admissionv1.Create, annotation: valid, allowed: true},
+		{name: "valid key=value", op: admissionv1.Create, annotation: "team=platform,env=prod", allowed: true},
+		{name: "empty", op: admissionv1.Create, annotation: " ", allowed: true},
+		{name: "malformed JSON", op: admissionv1.Create, annotation: `{"team":`, allowed: false},
+		{name: "reserved prefix", op: admissionv1.Create, annotation: invalid, allowed: false},
+		{name: "edited into an invalid value", op: admissionv1.Update, annotation: invalid, oldAnnotation: &valid, allowed: false},
+		{name: "invalid value added on update", op: admissionv1.Update, annotation: invalid, allowed: false},
+		{name: "unchanged invalid value", op: admissionv1.Update, annotation: invalid, oldAnnotation: &invalid, allowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := v.Handle(context.Background(), podRequest(t, tt.op, tt.annotation, tt.oldAnnotation))
+			assert.Equal(t, tt.allowed, resp.Allowed, resp.Result)
+			if !tt.allowed {
+				assert.Contains(t, resp.Result.Message, controller.AnnotationKey)
+			}
+		})
+	}
+}
+
+func TestPodValidatorTooManyTags(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	v := NewPodValidator(scheme, controller.AnnotationKey, controller.TagAnnotationValidator{})
+
+	tags := make(map[string]string)
+	for i := range controller.MaxTagsPerENI + 1 {
+		tags[string(rune('a'+i%26))+string(rune('a'+i/26))] = "x"
+	}
+	data, err := json.Marshal(tags)
+	require.NoError(t, err)
+	resp := v.Handle(context.Background(), podRequest(t, admissionv1.Create, string(data), nil))
+	assert.False(t, resp.Allowed)
+	assert.Contains(t, resp.Result.Message, "too many tags")
+}